@@ -0,0 +1,2 @@
+// Package adminsync reconciles desired channel admins against actual ones.
+package adminsync
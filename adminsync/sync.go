@@ -0,0 +1,336 @@
+package adminsync
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-faster/errors"
+	"go.uber.org/zap"
+	"golang.org/x/time/rate"
+
+	"github.com/gotd/td/telegram/query/dialogs"
+	"github.com/gotd/td/tg"
+
+	"github.com/gotd/contrib/chatdrift"
+	"github.com/gotd/contrib/storage"
+)
+
+// Admin is a desired or actual channel admin.
+type Admin struct {
+	UserID int64
+	Rights tg.ChatAdminRights
+	Rank   string
+}
+
+func (a Admin) equal(b Admin) bool {
+	// Ignore flags, they are set by decoding.
+	a.Rights.Flags, b.Rights.Flags = 0, 0
+	return a.UserID == b.UserID && a.Rights == b.Rights && a.Rank == b.Rank
+}
+
+func (a Admin) String() string {
+	s := "rights=" + strings.Join(chatdrift.AdminRights(a.Rights), ",")
+	if a.Rank != "" {
+		s += " rank=" + a.Rank
+	}
+	return s
+}
+
+// ActionKind is a kind of sync action.
+type ActionKind int
+
+const (
+	// Promote promotes user to admin.
+	Promote ActionKind = iota
+	// Update changes rights or rank of admin.
+	Update
+	// Demote removes admin rights.
+	Demote
+)
+
+// String implements fmt.Stringer.
+func (k ActionKind) String() string {
+	switch k {
+	case Promote:
+		return "promote"
+	case Update:
+		return "update"
+	case Demote:
+		return "demote"
+	default:
+		return fmt.Sprintf("ActionKind(%d)", int(k))
+	}
+}
+
+// Action is a single sync action.
+type Action struct {
+	Kind ActionKind
+	// Old is an actual admin, zero for Promote.
+	Old Admin
+	// New is a desired admin, zero for Demote.
+	New Admin
+}
+
+// UserID returns ID of affected user.
+func (a Action) UserID() int64 {
+	if a.Kind == Demote {
+		return a.Old.UserID
+	}
+	return a.New.UserID
+}
+
+// String implements fmt.Stringer.
+func (a Action) String() string {
+	switch a.Kind {
+	case Promote:
+		return fmt.Sprintf("promote %d: %s", a.New.UserID, a.New)
+	case Update:
+		return fmt.Sprintf("update %d: %s -> %s", a.New.UserID, a.Old, a.New)
+	default:
+		return fmt.Sprintf("demote %d: %s", a.Old.UserID, a.Old)
+	}
+}
+
+// Syncer reconciles channel admins.
+//
+// Channel creator, current user and protected users are never changed.
+type Syncer struct {
+	api   *tg.Client
+	peers storage.PeerStorage
+	log   *zap.Logger
+
+	limiter   *rate.Limiter
+	dryRun    bool
+	protected map[int64]struct{}
+
+	self    int64
+	selfMux sync.Mutex
+}
+
+// NewSyncer creates new Syncer. Peer storage is used to find access hashes
+// of users which are not admins yet.
+//
+// By default, at most one change per second is made, with burst of 5.
+func NewSyncer(api *tg.Client, peers storage.PeerStorage) *Syncer {
+	return &Syncer{
+		api:     api,
+		peers:   peers,
+		log:     zap.NewNop(),
+		limiter: rate.NewLimiter(rate.Every(time.Second), 5),
+
+		protected: map[int64]struct{}{},
+	}
+}
+
+// WithLog sets logger to log made changes.
+func (s *Syncer) WithLog(log *zap.Logger) *Syncer {
+	s.log = log
+	return s
+}
+
+// WithRateLimit sets rate limit of made changes.
+func (s *Syncer) WithRateLimit(r rate.Limit, burst int) *Syncer {
+	s.limiter = rate.NewLimiter(r, burst)
+	return s
+}
+
+// WithSelf sets ID of current user, so Syncer does not request it.
+// By default, it is requested once using users.getUsers.
+func (s *Syncer) WithSelf(userID int64) *Syncer {
+	s.self = userID
+	return s
+}
+
+// WithProtected adds users which are never changed, e.g. other bots
+// which are admins of synced channels.
+func (s *Syncer) WithProtected(userIDs ...int64) *Syncer {
+	for _, id := range userIDs {
+		s.protected[id] = struct{}{}
+	}
+	return s
+}
+
+// WithDryRun sets whether Sync only plans changes without making them.
+func (s *Syncer) WithDryRun(dryRun bool) *Syncer {
+	s.dryRun = dryRun
+	return s
+}
+
+// maxAdmins is a participants limit of admins request, the maximum
+// allowed by API.
+//
+// It is above Telegram limit of channel admins, so single page is enough.
+const maxAdmins = 200
+
+type actual struct {
+	admins  map[int64]Admin
+	creator int64
+	users   map[int64]*tg.User
+	// protected are users which are never changed, including creator.
+	protected map[int64]struct{}
+}
+
+func (s *Syncer) selfID(ctx context.Context) (int64, error) {
+	s.selfMux.Lock()
+	defer s.selfMux.Unlock()
+
+	if s.self != 0 {
+		return s.self, nil
+	}
+	users, err := s.api.UsersGetUsers(ctx, []tg.InputUserClass{&tg.InputUserSelf{}})
+	if err != nil {
+		return 0, errors.Errorf("get self: %w", err)
+	}
+	for _, u := range users {
+		if user, ok := u.(*tg.User); ok {
+			s.self = user.ID
+			return s.self, nil
+		}
+	}
+	return 0, errors.New("get self: user not returned")
+}
+
+func (s *Syncer) fetch(ctx context.Context, channel tg.InputChannelClass) (actual, error) {
+	self, err := s.selfID(ctx)
+	if err != nil {
+		return actual{}, err
+	}
+
+	r, err := s.api.ChannelsGetParticipants(ctx, &tg.ChannelsGetParticipantsRequest{
+		Channel: channel,
+		Filter:  &tg.ChannelParticipantsAdmins{},
+		Limit:   maxAdmins,
+	})
+	if err != nil {
+		return actual{}, errors.Errorf("get admins: %w", err)
+	}
+
+	a := actual{
+		admins:    map[int64]Admin{},
+		users:     map[int64]*tg.User{},
+		protected: map[int64]struct{}{self: {}},
+	}
+	for id := range s.protected {
+		a.protected[id] = struct{}{}
+	}
+	p, ok := r.(*tg.ChannelsChannelParticipants)
+	if !ok {
+		return a, nil
+	}
+	for _, u := range p.Users {
+		if user, ok := u.(*tg.User); ok {
+			a.users[user.ID] = user
+		}
+	}
+	for _, participant := range p.Participants {
+		switch v := participant.(type) {
+		case *tg.ChannelParticipantCreator:
+			a.creator = v.UserID
+			a.protected[v.UserID] = struct{}{}
+		case *tg.ChannelParticipantAdmin:
+			a.admins[v.UserID] = Admin{
+				UserID: v.UserID,
+				Rights: v.AdminRights,
+				Rank:   v.Rank,
+			}
+		}
+	}
+	return a, nil
+}
+
+func plan(a actual, desired []Admin) []Action {
+	var actions []Action
+	want := make(map[int64]struct{}, len(desired))
+	for _, d := range desired {
+		want[d.UserID] = struct{}{}
+		if _, ok := a.protected[d.UserID]; ok {
+			continue
+		}
+		old, ok := a.admins[d.UserID]
+		switch {
+		case !ok:
+			actions = append(actions, Action{Kind: Promote, New: d})
+		case !old.equal(d):
+			actions = append(actions, Action{Kind: Update, Old: old, New: d})
+		}
+	}
+
+	var demote []Action
+	for id, old := range a.admins {
+		if _, ok := a.protected[id]; ok {
+			continue
+		}
+		if _, ok := want[id]; !ok {
+			demote = append(demote, Action{Kind: Demote, Old: old})
+		}
+	}
+	sort.Slice(demote, func(i, j int) bool {
+		return demote[i].Old.UserID < demote[j].Old.UserID
+	})
+	return append(actions, demote...)
+}
+
+// Plan returns actions required to make channel admins match desired ones.
+func (s *Syncer) Plan(ctx context.Context, channel tg.InputChannelClass, desired []Admin) ([]Action, error) {
+	a, err := s.fetch(ctx, channel)
+	if err != nil {
+		return nil, err
+	}
+	return plan(a, desired), nil
+}
+
+func (s *Syncer) inputUser(ctx context.Context, a actual, id int64) (tg.InputUserClass, error) {
+	if u, ok := a.users[id]; ok {
+		return u.AsInput(), nil
+	}
+
+	p, err := s.peers.Find(ctx, storage.PeerKey{Kind: dialogs.User, ID: id})
+	if err != nil {
+		return nil, errors.Errorf("find user %d: %w", id, err)
+	}
+	u, ok := p.AsInputUser()
+	if !ok {
+		return nil, errors.Errorf("peer %d is not a user", id)
+	}
+	return u, nil
+}
+
+// Sync makes channel admins match desired ones and returns applied
+// actions. In dry-run mode, actions are only returned.
+//
+// If some action fails, Sync returns actions applied so far and error.
+func (s *Syncer) Sync(ctx context.Context, channel tg.InputChannelClass, desired []Admin) ([]Action, error) {
+	a, err := s.fetch(ctx, channel)
+	if err != nil {
+		return nil, err
+	}
+	actions := plan(a, desired)
+	if s.dryRun {
+		return actions, nil
+	}
+
+	for i, action := range actions {
+		user, err := s.inputUser(ctx, a, action.UserID())
+		if err != nil {
+			return actions[:i], err
+		}
+		if err := s.limiter.Wait(ctx); err != nil {
+			return actions[:i], err
+		}
+
+		if _, err := s.api.ChannelsEditAdmin(ctx, &tg.ChannelsEditAdminRequest{
+			Channel:     channel,
+			UserID:      user,
+			AdminRights: action.New.Rights,
+			Rank:        action.New.Rank,
+		}); err != nil {
+			return actions[:i], errors.Errorf("%s: %w", action, err)
+		}
+		s.log.Info("Admin synced", zap.Stringer("action", action))
+	}
+	return actions, nil
+}
@@ -0,0 +1,136 @@
+package adminsync
+
+import (
+	"context"
+	"testing"
+
+	pebbledb "github.com/cockroachdb/pebble"
+	"github.com/cockroachdb/pebble/vfs"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/time/rate"
+
+	"github.com/gotd/td/tg"
+	"github.com/gotd/td/tgmock"
+
+	"github.com/gotd/contrib/pebble"
+	"github.com/gotd/contrib/storage"
+)
+
+func TestSyncer(t *testing.T) {
+	a := require.New(t)
+	ctx := context.Background()
+	mock := tgmock.New(t)
+
+	db, err := pebbledb.Open("pebble.db", &pebbledb.Options{
+		FS: vfs.NewMem(),
+	})
+	a.NoError(err)
+	t.Cleanup(func() {
+		_ = db.Close()
+	})
+	peers := pebble.NewPeerStorage(db)
+
+	var p storage.Peer
+	a.True(p.FromUser(&tg.User{ID: 4, AccessHash: 40}))
+	a.NoError(peers.Add(ctx, p))
+
+	channel := &tg.InputChannel{ChannelID: 10, AccessHash: 10}
+	expectAdmins := func() {
+		mock.ExpectCall(&tg.ChannelsGetParticipantsRequest{
+			Channel: channel,
+			Filter:  &tg.ChannelParticipantsAdmins{},
+			Limit:   maxAdmins,
+		}).ThenResult(&tg.ChannelsChannelParticipants{
+			Count: 5,
+			Participants: []tg.ChannelParticipantClass{
+				&tg.ChannelParticipantCreator{UserID: 1},
+				&tg.ChannelParticipantAdmin{UserID: 2, AdminRights: tg.ChatAdminRights{BanUsers: true}},
+				&tg.ChannelParticipantAdmin{UserID: 3, AdminRights: tg.ChatAdminRights{PinMessages: true}},
+				// Syncing bot and other protected bot.
+				&tg.ChannelParticipantAdmin{UserID: 6, AdminRights: tg.ChatAdminRights{BanUsers: true}},
+				&tg.ChannelParticipantAdmin{UserID: 7, AdminRights: tg.ChatAdminRights{BanUsers: true}},
+			},
+			Users: []tg.UserClass{
+				&tg.User{ID: 1, AccessHash: 10},
+				&tg.User{ID: 2, AccessHash: 20},
+				&tg.User{ID: 3, AccessHash: 30},
+				&tg.User{ID: 6, AccessHash: 60, Self: true, Bot: true},
+				&tg.User{ID: 7, AccessHash: 70, Bot: true},
+			},
+		})
+	}
+	desired := []Admin{
+		{UserID: 1},
+		{UserID: 2, Rights: tg.ChatAdminRights{BanUsers: true}, Rank: "mod"},
+		{UserID: 4, Rights: tg.ChatAdminRights{DeleteMessages: true}},
+	}
+	expected := []string{
+		"update 2: rights=ban_users -> rights=ban_users rank=mod",
+		"promote 4: rights=delete_messages",
+		"demote 3: rights=pin_messages",
+	}
+	format := func(actions []Action) (r []string) {
+		for _, action := range actions {
+			r = append(r, action.String())
+		}
+		return r
+	}
+
+	s := NewSyncer(tg.NewClient(mock), peers).
+		WithRateLimit(rate.Inf, 1).
+		WithProtected(7)
+
+	// Current user is requested once.
+	mock.ExpectCall(&tg.UsersGetUsersRequest{
+		ID: []tg.InputUserClass{&tg.InputUserSelf{}},
+	}).ThenResult(&tg.UserClassVector{
+		Elems: []tg.UserClass{&tg.User{ID: 6, AccessHash: 60, Self: true, Bot: true}},
+	})
+	expectAdmins()
+	actions, err := s.Plan(ctx, channel, desired)
+	a.NoError(err)
+	a.Equal(expected, format(actions))
+
+	// Dry run makes no changes.
+	expectAdmins()
+	actions, err = s.WithDryRun(true).Sync(ctx, channel, desired)
+	a.NoError(err)
+	a.Equal(expected, format(actions))
+
+	expectAdmins()
+	mock.ExpectCall(&tg.ChannelsEditAdminRequest{
+		Channel:     channel,
+		UserID:      &tg.InputUser{UserID: 2, AccessHash: 20},
+		AdminRights: tg.ChatAdminRights{BanUsers: true},
+		Rank:        "mod",
+	}).ThenResult(&tg.Updates{})
+	mock.ExpectCall(&tg.ChannelsEditAdminRequest{
+		Channel:     channel,
+		UserID:      &tg.InputUser{UserID: 4, AccessHash: 40},
+		AdminRights: tg.ChatAdminRights{DeleteMessages: true},
+	}).ThenResult(&tg.Updates{})
+	mock.ExpectCall(&tg.ChannelsEditAdminRequest{
+		Channel: channel,
+		UserID:  &tg.InputUser{UserID: 3, AccessHash: 30},
+	}).ThenResult(&tg.Updates{})
+	actions, err = s.WithDryRun(false).Sync(ctx, channel, desired)
+	a.NoError(err)
+	a.Equal(expected, format(actions))
+
+	// Unknown user cannot be promoted.
+	expectAdmins()
+	actions, err = s.Sync(ctx, channel, []Admin{{UserID: 5}})
+	a.ErrorIs(err, storage.ErrPeerNotFound)
+	a.Empty(actions)
+
+	// Current user is not changed even if desired.
+	expectAdmins()
+	actions, err = s.WithDryRun(true).Plan(ctx, channel, []Admin{
+		{UserID: 1},
+		{UserID: 2, Rights: tg.ChatAdminRights{BanUsers: true}},
+		{UserID: 3, Rights: tg.ChatAdminRights{PinMessages: true}},
+		{UserID: 6},
+	})
+	a.NoError(err)
+	a.Empty(actions)
+}
@@ -0,0 +1,201 @@
+package alert
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"golang.org/x/time/rate"
+
+	"github.com/gotd/td/telegram/message"
+	"github.com/gotd/td/tg"
+
+	"github.com/gotd/contrib/middleware/floodwait"
+	"github.com/gotd/contrib/recovery"
+)
+
+// Severity is an alert severity.
+type Severity int
+
+const (
+	// Info is an informational alert.
+	Info Severity = iota
+	// Warning is an alert about degraded operation.
+	Warning
+	// Critical is an alert which requires immediate attention.
+	Critical
+)
+
+// String implements fmt.Stringer.
+func (s Severity) String() string {
+	switch s {
+	case Info:
+		return "INFO"
+	case Warning:
+		return "WARNING"
+	case Critical:
+		return "CRITICAL"
+	default:
+		return fmt.Sprintf("Severity(%d)", int(s))
+	}
+}
+
+// Alert is an operational event.
+type Alert struct {
+	Severity Severity
+	// Title is a short description, e.g. "Session invalidated".
+	Title string
+	// Text is an optional details.
+	Text string
+}
+
+// String returns alert text as sent to chat.
+func (a Alert) String() string {
+	var b strings.Builder
+	b.WriteString("[")
+	b.WriteString(a.Severity.String())
+	b.WriteString("] ")
+	b.WriteString(a.Title)
+	if a.Text != "" {
+		b.WriteString("\n\n")
+		b.WriteString(a.Text)
+	}
+	return b.String()
+}
+
+// Notifier sends alerts to admin chat.
+//
+// Alerts below minimum severity are ignored, alerts above rate limit are
+// dropped and reported by the next sent alert.
+type Notifier struct {
+	sender *message.Sender
+	peer   tg.InputPeerClass
+	log    *zap.Logger
+
+	minSeverity Severity
+	limiter     *rate.Limiter
+	timeout     time.Duration
+
+	mux     sync.Mutex
+	dropped int
+}
+
+// NewNotifier creates new Notifier which sends alerts to given peer.
+//
+// By default, at most one alert per 10 seconds is sent, with burst of 5.
+func NewNotifier(api *tg.Client, peer tg.InputPeerClass) *Notifier {
+	return &Notifier{
+		sender:  message.NewSender(api),
+		peer:    peer,
+		log:     zap.NewNop(),
+		limiter: rate.NewLimiter(rate.Every(10*time.Second), 5),
+		timeout: 30 * time.Second,
+	}
+}
+
+// WithLog sets logger to log dropped alerts and send errors.
+func (n *Notifier) WithLog(log *zap.Logger) *Notifier {
+	n.log = log
+	return n
+}
+
+// WithMinSeverity sets minimum severity of sent alerts. Default is Info.
+func (n *Notifier) WithMinSeverity(s Severity) *Notifier {
+	n.minSeverity = s
+	return n
+}
+
+// WithRateLimit sets rate limit of sent alerts.
+func (n *Notifier) WithRateLimit(r rate.Limit, burst int) *Notifier {
+	n.limiter = rate.NewLimiter(r, burst)
+	return n
+}
+
+// WithTimeout sets send timeout of alerts from background hooks.
+// Default is 30 seconds.
+func (n *Notifier) WithTimeout(timeout time.Duration) *Notifier {
+	n.timeout = timeout
+	return n
+}
+
+// Notify sends alert to admin chat.
+func (n *Notifier) Notify(ctx context.Context, a Alert) error {
+	if a.Severity < n.minSeverity {
+		return nil
+	}
+
+	n.mux.Lock()
+	if !n.limiter.Allow() {
+		n.dropped++
+		n.mux.Unlock()
+		n.log.Warn("Alert dropped by rate limit",
+			zap.Stringer("severity", a.Severity),
+			zap.String("title", a.Title),
+		)
+		return nil
+	}
+	dropped := n.dropped
+	n.dropped = 0
+	n.mux.Unlock()
+
+	text := a.String()
+	if dropped > 0 {
+		text += fmt.Sprintf("\n\n(%d alerts dropped by rate limit)", dropped)
+	}
+	if _, err := n.sender.To(n.peer).Text(ctx, text); err != nil {
+		return err
+	}
+	return nil
+}
+
+// notifyAsync sends alert in background, so hooks do not block callers.
+func (n *Notifier) notifyAsync(a Alert) {
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), n.timeout)
+		defer cancel()
+
+		if err := n.Notify(ctx, a); err != nil {
+			n.log.Warn("Send alert", zap.Error(err))
+		}
+	}()
+}
+
+// Error sends alert about given error.
+//
+// It can be used as error hook, e.g. for storage failures.
+func (n *Notifier) Error(ctx context.Context, s Severity, title string, err error) error {
+	return n.Notify(ctx, Alert{
+		Severity: s,
+		Title:    title,
+		Text:     err.Error(),
+	})
+}
+
+// FloodWait returns floodwait.Waiter callback which sends Warning alert
+// when flood wait duration reaches given threshold.
+func (n *Notifier) FloodWait(threshold time.Duration) func(ctx context.Context, wait floodwait.FloodWait) {
+	return func(ctx context.Context, wait floodwait.FloodWait) {
+		if wait.Duration < threshold {
+			return
+		}
+		n.notifyAsync(Alert{
+			Severity: Warning,
+			Title:    "Flood wait threshold reached",
+			Text:     fmt.Sprintf("Waiting %s (threshold %s)", wait.Duration, threshold),
+		})
+	}
+}
+
+// Sink returns recovery.Sink which sends Critical alert on handler panics.
+func (n *Notifier) Sink() recovery.Sink {
+	return recovery.SinkFunc(func(ctx context.Context, p *recovery.Panic) {
+		n.notifyAsync(Alert{
+			Severity: Critical,
+			Title:    "Update handler panic",
+			Text:     fmt.Sprint(p.Value),
+		})
+	})
+}
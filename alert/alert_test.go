@@ -0,0 +1,69 @@
+package alert
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-faster/errors"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/time/rate"
+
+	"github.com/gotd/td/bin"
+	"github.com/gotd/td/tg"
+	"github.com/gotd/td/tgmock"
+
+	"github.com/gotd/contrib/middleware/floodwait"
+	"github.com/gotd/contrib/recovery"
+)
+
+func newTestNotifier(t *testing.T, sent chan<- string) *Notifier {
+	api := tg.NewClient(tgmock.Invoker(func(request bin.Encoder) (bin.Encoder, error) {
+		req, ok := request.(*tg.MessagesSendMessageRequest)
+		require.True(t, ok)
+		sent <- req.Message
+		return &tg.Updates{}, nil
+	}))
+	return NewNotifier(api, &tg.InputPeerSelf{})
+}
+
+func TestNotifier(t *testing.T) {
+	a := require.New(t)
+	ctx := context.Background()
+
+	sent := make(chan string, 10)
+	n := newTestNotifier(t, sent).
+		WithMinSeverity(Warning).
+		WithRateLimit(rate.Every(time.Hour), 1)
+
+	a.NoError(n.Notify(ctx, Alert{Severity: Info, Title: "ignored"}))
+	a.Empty(sent)
+
+	a.NoError(n.Error(ctx, Critical, "Storage failure", errors.New("disk full")))
+	a.Equal("[CRITICAL] Storage failure\n\ndisk full", <-sent)
+
+	// Rate limited.
+	a.NoError(n.Notify(ctx, Alert{Severity: Warning, Title: "dropped"}))
+	a.Empty(sent)
+
+	n.limiter = rate.NewLimiter(rate.Inf, 1)
+	a.NoError(n.Notify(ctx, Alert{Severity: Warning, Title: "next"}))
+	a.Equal("[WARNING] next\n\n(1 alerts dropped by rate limit)", <-sent)
+}
+
+func TestNotifier_Hooks(t *testing.T) {
+	a := require.New(t)
+	ctx := context.Background()
+
+	sent := make(chan string, 10)
+	n := newTestNotifier(t, sent)
+
+	cb := n.FloodWait(time.Minute)
+	cb(ctx, floodwait.FloodWait{Duration: time.Second})
+	cb(ctx, floodwait.FloodWait{Duration: time.Hour})
+	a.Contains(<-sent, "Flood wait threshold reached")
+
+	n.Sink().Report(ctx, &recovery.Panic{Value: "boom"})
+	a.Equal("[CRITICAL] Update handler panic\n\nboom", <-sent)
+	a.Empty(sent)
+}
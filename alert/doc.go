@@ -0,0 +1,2 @@
+// Package alert implements operational alerts sent to admin chat.
+package alert
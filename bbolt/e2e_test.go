@@ -1,13 +1,20 @@
 package bbolt_test
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"os"
 	"testing"
 
 	bboltdb "go.etcd.io/bbolt"
 
+	"github.com/gotd/td/tg"
+
 	"github.com/gotd/contrib/bbolt"
 	"github.com/gotd/contrib/internal/tests"
+	"github.com/gotd/contrib/storage"
+	"github.com/gotd/contrib/storage/storagetest"
 )
 
 func TestE2E(t *testing.T) {
@@ -24,4 +31,107 @@ func TestE2E(t *testing.T) {
 	tests.TestSessionStorage(t, bbolt.NewSessionStorage(db, "testsession", bucket))
 	tests.TestCredentials(t, bbolt.NewCredentials(db, bucket))
 	tests.TestPeerStorage(t, bbolt.NewPeerStorage(db, bucket))
+
+	t.Run("Conformance", func(t *testing.T) {
+		var n int
+		storagetest.TestPeerStorage(t, func() storage.PeerStorage {
+			n++
+			bucket := []byte(fmt.Sprintf("conformance%d", n))
+			if err := db.Update(func(tx *bboltdb.Tx) error {
+				_, err := tx.CreateBucket(bucket)
+				return err
+			}); err != nil {
+				t.Fatal(err)
+			}
+			return bbolt.NewPeerStorage(db, bucket)
+		})
+	})
+	t.Run("Namespace", func(t *testing.T) {
+		bucket := []byte("namespace")
+		storagetest.TestIsolation(t,
+			bbolt.NewPeerStorage(db, bucket).WithNamespace("first"),
+			bbolt.NewPeerStorage(db, bucket).WithNamespace("second"),
+		)
+		storagetest.TestIsolation(t,
+			bbolt.NewPeerStorage(db, bucket),
+			bbolt.NewPeerStorage(db, bucket).WithNamespace("third"),
+		)
+
+		// Namespace bucket is created on first write.
+		s := bbolt.NewPeerStorage(db, bucket).WithNamespace("fourth")
+		if err := s.SetSchemaVersion(context.Background(), 1); err != nil {
+			t.Fatal(err)
+		}
+		tests.TestPeerStorage(t, s)
+	})
+	t.Run("CodecSwitch", func(t *testing.T) {
+		bucket := []byte("codec")
+		tests.TestPeerStorageCodecSwitch(t,
+			bbolt.NewPeerStorage(db, bucket),
+			bbolt.NewPeerStorage(db, bucket).WithCodec(storage.BinaryCodec{}),
+		)
+	})
+	t.Run("ReadOnly", func(t *testing.T) {
+		ctx := context.Background()
+		f, err := os.CreateTemp("", "*readonly.db")
+		if err != nil {
+			t.Fatal(err)
+		}
+		name := f.Name()
+		_ = f.Close()
+		defer os.Remove(name)
+
+		rw, err := bboltdb.Open(name, 0o600, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var p storage.Peer
+		if !p.FromUser(&tg.User{ID: 1, AccessHash: 1, Username: "user"}) {
+			t.Fatal("invalid user")
+		}
+		if err := bbolt.NewPeerStorage(rw, bucket).Add(ctx, p); err != nil {
+			t.Fatal(err)
+		}
+		if err := rw.Close(); err != nil {
+			t.Fatal(err)
+		}
+
+		// Multiple readers share the lock.
+		first, err := bboltdb.Open(name, 0o600, bbolt.ReadOnlyOptions())
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer first.Close()
+		second, err := bboltdb.Open(name, 0o600, bbolt.ReadOnlyOptions())
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer second.Close()
+
+		s := bbolt.NewPeerStorage(second, bucket)
+		if _, err := s.Resolve(ctx, "user"); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.Add(ctx, p); !errors.Is(err, bboltdb.ErrDatabaseReadOnly) {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+	t.Run("Ephemeral", func(t *testing.T) {
+		opts := bbolt.EphemeralOptions()
+		opts.OpenFile = func(s string, flag int, mode os.FileMode) (*os.File, error) {
+			return os.CreateTemp("", "*"+s)
+		}
+		db, err := bboltdb.Open("bbolt.db", 0, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := db.Update(func(tx *bboltdb.Tx) error {
+			_, err := tx.CreateBucket(bucket)
+			return err
+		}); err != nil {
+			t.Fatal(err)
+		}
+
+		tests.TestPeerStorage(t, bbolt.NewPeerStorage(db, bucket))
+	})
 }
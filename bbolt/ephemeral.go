@@ -0,0 +1,23 @@
+package bbolt
+
+import (
+	"go.etcd.io/bbolt"
+)
+
+// EphemeralOptions returns bbolt options tuned for ephemeral deployments,
+// e.g. CI bots or short-lived scrapers, where speed matters more than
+// durability.
+//
+// Fsync is disabled for data, freelist and file growth. Database may be
+// corrupted on OS crash or power loss, so these options MUST NOT be used
+// for data which should survive restart, like sessions of long-running
+// clients. Process crash alone does not lose committed data.
+func EphemeralOptions() *bbolt.Options {
+	return &bbolt.Options{
+		Timeout:        bbolt.DefaultOptions.Timeout,
+		NoGrowSync:     true,
+		NoFreelistSync: true,
+		FreelistType:   bbolt.FreelistMapType,
+		NoSync:         true,
+	}
+}
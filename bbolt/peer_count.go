@@ -0,0 +1,37 @@
+package bbolt
+
+import (
+	"bytes"
+	"context"
+
+	"go.etcd.io/bbolt"
+
+	"github.com/gotd/td/telegram/query/dialogs"
+
+	"github.com/gotd/contrib/storage"
+)
+
+var _ storage.PeerCounter = PeerStorage{}
+
+// Count returns count of stored peers of given kinds.
+// If no kinds given, peers of all kinds are counted.
+//
+// Count scans keys only and does not decode values, so expired peers
+// which are not removed by DeleteExpired yet are counted too.
+func (s PeerStorage) Count(ctx context.Context, kinds ...dialogs.PeerKind) (count int, rerr error) {
+	rerr = s.bbolt.View(func(tx *bbolt.Tx) error {
+		bucket := s.getBucket(tx)
+		if bucket == nil {
+			return nil
+		}
+
+		cur := bucket.Cursor()
+		for _, prefix := range storage.KindPrefixes(kinds...) {
+			for k, _ := cur.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, _ = cur.Next() {
+				count++
+			}
+		}
+		return nil
+	})
+	return count, rerr
+}
@@ -0,0 +1,53 @@
+package bbolt
+
+import (
+	"context"
+
+	"github.com/go-faster/errors"
+	"go.etcd.io/bbolt"
+
+	"github.com/gotd/contrib/storage"
+)
+
+var _ storage.PeerDeleter = PeerStorage{}
+
+// Delete removes peer with given key and its associated keys.
+func (s PeerStorage) Delete(ctx context.Context, key storage.PeerKey) error {
+	if err := s.bbolt.Update(func(tx *bbolt.Tx) error {
+		bucket := s.getBucket(tx)
+		if bucket == nil {
+			return storage.ErrPeerNotFound
+		}
+
+		id := key.Bytes(nil)
+		data := bucket.Get(id)
+		if data == nil {
+			return storage.ErrPeerNotFound
+		}
+
+		var value storage.Peer
+		if err := s.codec.Unmarshal(data, &value); err != nil &&
+			!errors.Is(err, storage.ErrPeerUnmarshalMustInvalidate) {
+			return errors.Errorf("unmarshal: %w", err)
+		}
+
+		if err := bucket.Delete(id); err != nil {
+			return errors.Errorf("delete %q: %w", id, err)
+		}
+		for _, k := range value.Keys() {
+			if err := deleteAssociated(bucket, []byte(k), id); err != nil {
+				return err
+			}
+		}
+
+		if err := deleteUsername(bucket, id, value); err != nil {
+			return err
+		}
+		return deleteRecent(bucket, id)
+	}); err != nil {
+		return err
+	}
+	s.watch.Publish(storage.Event{Type: storage.EventDelete, Key: key})
+
+	return nil
+}
@@ -0,0 +1,68 @@
+package bbolt
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"time"
+
+	"github.com/go-faster/errors"
+	"go.etcd.io/bbolt"
+
+	"github.com/gotd/contrib/storage"
+)
+
+var _ storage.PeerEvicter = PeerStorage{}
+
+// Evict removes peers which were not added, assigned or touched during
+// given duration and returns count of removed peers.
+func (s PeerStorage) Evict(ctx context.Context, olderThan time.Duration) (int, error) {
+	until := binary.BigEndian.AppendUint64(nil, uint64(time.Now().Add(-olderThan).UnixNano()))
+
+	var keys []storage.PeerKey
+	if err := s.bbolt.View(func(tx *bbolt.Tx) error {
+		bucket := s.getBucket(tx)
+		if bucket == nil {
+			return nil
+		}
+
+		cur := bucket.Cursor()
+		for k, _ := cur.Seek(recentPrefix); k != nil && bytes.HasPrefix(k, recentPrefix); k, _ = cur.Next() {
+			k = k[len(recentPrefix):]
+			if len(k) < 8 {
+				continue
+			}
+			ts, id := k[:8], k[8:]
+			if bytes.Compare(ts, until) >= 0 {
+				break
+			}
+			if !bytes.Equal(ts, bucket.Get(touchedKey(id))) {
+				// Stale entry.
+				continue
+			}
+			var key storage.PeerKey
+			if err := key.Parse(id); err != nil {
+				continue
+			}
+			keys = append(keys, key)
+		}
+		return nil
+	}); err != nil {
+		return 0, err
+	}
+
+	var n int
+	for _, key := range keys {
+		if err := ctx.Err(); err != nil {
+			return n, err
+		}
+		if err := s.Delete(ctx, key); err != nil {
+			if errors.Is(err, storage.ErrPeerNotFound) {
+				continue
+			}
+			return n, errors.Errorf("delete %s: %w", key, err)
+		}
+		n++
+	}
+	return n, nil
+}
@@ -0,0 +1,47 @@
+package bbolt
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-faster/errors"
+	"go.etcd.io/bbolt"
+
+	"github.com/gotd/contrib/storage"
+)
+
+var _ storage.PeerIDFinder = PeerStorage{}
+
+// FindByID finds peer with given ID of any kind.
+//
+// Peer keys are prefixed by kind, so FindByID does one point lookup per
+// kind in the same transaction.
+func (s PeerStorage) FindByID(ctx context.Context, id int64) (p storage.Peer, rerr error) {
+	rerr = s.bbolt.View(func(tx *bbolt.Tx) error {
+		bucket := s.getBucket(tx)
+		if bucket == nil {
+			return errors.Errorf("bucket %q does not exist", s.bucket)
+		}
+
+		now := time.Now()
+		for _, kind := range storage.IDKinds {
+			data := bucket.Get(storage.PeerKey{Kind: kind, ID: id}.Bytes(nil))
+			if data == nil {
+				continue
+			}
+			if err := s.codec.Unmarshal(data, &p); err != nil {
+				if errors.Is(err, storage.ErrPeerUnmarshalMustInvalidate) {
+					continue
+				}
+				return errors.Errorf("unmarshal: %w", err)
+			}
+			if p.Expired(now) {
+				continue
+			}
+			return nil
+		}
+		p = storage.Peer{}
+		return storage.ErrPeerNotFound
+	})
+	return p, rerr
+}
@@ -0,0 +1,72 @@
+package bbolt
+
+import (
+	"context"
+
+	"github.com/go-faster/errors"
+	"go.etcd.io/bbolt"
+
+	"github.com/gotd/contrib/storage"
+)
+
+var _ storage.PeerGarbageCollector = PeerStorage{}
+
+// CollectGarbage removes associated keys which point to missing peers
+// and returns count of removed keys.
+func (s PeerStorage) CollectGarbage(ctx context.Context, opts storage.GCOptions) (count int, rerr error) {
+	fn := s.bbolt.Update
+	if opts.DryRun {
+		fn = s.bbolt.View
+	}
+	rerr = fn(func(tx *bbolt.Tx) error {
+		count = 0
+		bucket := s.getBucket(tx)
+		if bucket == nil {
+			return nil
+		}
+
+		var orphaned [][]byte
+		cur := bucket.Cursor()
+		for k, v := cur.First(); k != nil; k, v = cur.Next() {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+
+			target, ok := storage.AssociatedKey(k, v)
+			if !ok {
+				continue
+			}
+
+			var (
+				p     storage.Peer
+				found bool
+			)
+			if data := bucket.Get(target.Bytes(nil)); data != nil {
+				switch err := s.codec.Unmarshal(data, &p); {
+				case err == nil:
+					found = true
+				case !errors.Is(err, storage.ErrPeerUnmarshalMustInvalidate):
+					return errors.Errorf("unmarshal: %w", err)
+				}
+			}
+			if storage.Orphaned(string(k), p, found, opts) {
+				orphaned = append(orphaned, append([]byte(nil), k...))
+			}
+		}
+
+		count = len(orphaned)
+		if opts.DryRun {
+			return nil
+		}
+		for _, k := range orphaned {
+			if err := bucket.Delete(k); err != nil {
+				return errors.Errorf("delete %q: %w", k, err)
+			}
+		}
+		return nil
+	})
+	if rerr != nil {
+		return 0, rerr
+	}
+	return count, nil
+}
@@ -0,0 +1,43 @@
+package bbolt
+
+import (
+	"go.etcd.io/bbolt"
+)
+
+// namespacePrefix is a prefix of nested namespace bucket names.
+//
+// Prefix starts with underscore, so namespace buckets are never considered
+// peer or associated keys of storage without namespace.
+const namespacePrefix = "_ns:"
+
+// WithNamespace sets namespace of stored peers, e.g. account ID, so multiple
+// PeerStorage instances can share one bucket without collisions.
+//
+// Peers of namespace are stored in a nested bucket. Empty namespace means
+// no nested bucket, which is the default. Note that storages using
+// different buckets of one database are isolated too.
+func (s *PeerStorage) WithNamespace(ns string) *PeerStorage {
+	s.ns = nil
+	if ns != "" {
+		s.ns = []byte(namespacePrefix + ns)
+	}
+	return s
+}
+
+// getBucket returns bucket of the storage, or nil if it does not exist.
+func (s PeerStorage) getBucket(tx *bbolt.Tx) *bbolt.Bucket {
+	bucket := tx.Bucket(s.bucket)
+	if bucket == nil || s.ns == nil {
+		return bucket
+	}
+	return bucket.Bucket(s.ns)
+}
+
+// createBucket returns bucket of the storage, creating it if needed.
+func (s PeerStorage) createBucket(tx *bbolt.Tx) (*bbolt.Bucket, error) {
+	bucket, err := tx.CreateBucketIfNotExists(s.bucket)
+	if err != nil || s.ns == nil {
+		return bucket, err
+	}
+	return bucket.CreateBucketIfNotExists(s.ns)
+}
@@ -0,0 +1,62 @@
+package bbolt
+
+import (
+	"bytes"
+	"context"
+	"time"
+
+	"github.com/go-faster/errors"
+	"go.etcd.io/bbolt"
+
+	"github.com/gotd/contrib/storage"
+)
+
+var _ storage.PeerPager = PeerStorage{}
+
+// IteratePage returns page of peers following given cursor.
+// Empty cursor means the first page.
+//
+// Peers are returned in key order, cursor is the last returned key,
+// so pagination is stable even if storage is modified between calls.
+func (s PeerStorage) IteratePage(ctx context.Context, cursor string, limit int) (page storage.PeerPage, rerr error) {
+	if limit <= 0 {
+		return storage.PeerPage{}, errors.Errorf("invalid limit %d", limit)
+	}
+
+	rerr = s.bbolt.View(func(tx *bbolt.Tx) error {
+		bucket := s.getBucket(tx)
+		if bucket == nil {
+			return nil
+		}
+
+		cur := bucket.Cursor()
+		var k, v []byte
+		if cursor == "" {
+			k, v = cur.Seek(storage.PeerKeyPrefix)
+		} else if k, v = cur.Seek([]byte(cursor)); string(k) == cursor {
+			k, v = cur.Next()
+		}
+
+		now := time.Now()
+		for ; k != nil && bytes.HasPrefix(k, storage.PeerKeyPrefix); k, v = cur.Next() {
+			if len(page.Peers) == limit {
+				page.Next = string(storage.KeyFromPeer(page.Peers[len(page.Peers)-1]).Bytes(nil))
+				break
+			}
+
+			var value storage.Peer
+			if err := s.codec.Unmarshal(v, &value); err != nil {
+				if errors.Is(err, storage.ErrPeerUnmarshalMustInvalidate) {
+					continue
+				}
+				return errors.Errorf("unmarshal: %w", err)
+			}
+			if value.Expired(now) {
+				continue
+			}
+			page.Peers = append(page.Peers, value)
+		}
+		return nil
+	})
+	return page, rerr
+}
@@ -0,0 +1,173 @@
+package bbolt
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"time"
+
+	"github.com/go-faster/errors"
+	"go.etcd.io/bbolt"
+
+	"github.com/gotd/contrib/storage"
+)
+
+var _ storage.PeerToucher = PeerStorage{}
+
+var (
+	// recentPrefix is a prefix of recency index keys.
+	// Key is recentPrefix + big-endian touch time + peer key.
+	recentPrefix = []byte("_recent:")
+	// touchedPrefix is a prefix of last touch time keys.
+	// Key is touchedPrefix + peer key, value is big-endian touch time.
+	touchedPrefix = []byte("_touched:")
+)
+
+func recentKey(ts, id []byte) []byte {
+	k := make([]byte, 0, len(recentPrefix)+len(ts)+len(id))
+	k = append(k, recentPrefix...)
+	k = append(k, ts...)
+	return append(k, id...)
+}
+
+func touchedKey(id []byte) []byte {
+	return append(append([]byte(nil), touchedPrefix...), id...)
+}
+
+// touch updates recency index entries for given peer.
+func touch(bucket *bbolt.Bucket, id []byte, now time.Time) error {
+	if err := deleteRecent(bucket, id); err != nil {
+		return err
+	}
+
+	ts := binary.BigEndian.AppendUint64(nil, uint64(now.UnixNano()))
+	if err := bucket.Put(recentKey(ts, id), []byte{}); err != nil {
+		return errors.Errorf("set recent: %w", err)
+	}
+	if err := bucket.Put(touchedKey(id), ts); err != nil {
+		return errors.Errorf("set touched: %w", err)
+	}
+	return nil
+}
+
+// deleteRecent deletes recency index entries for given peer.
+func deleteRecent(bucket *bbolt.Bucket, id []byte) error {
+	ts := bucket.Get(touchedKey(id))
+	if ts == nil {
+		return nil
+	}
+	if err := bucket.Delete(recentKey(ts, id)); err != nil {
+		return errors.Errorf("delete recent: %w", err)
+	}
+	if err := bucket.Delete(touchedKey(id)); err != nil {
+		return errors.Errorf("delete touched: %w", err)
+	}
+	return nil
+}
+
+// Touch marks peer with given key as recently used.
+func (s PeerStorage) Touch(ctx context.Context, key storage.PeerKey) error {
+	return s.bbolt.Update(func(tx *bbolt.Tx) error {
+		bucket := s.getBucket(tx)
+		if bucket == nil {
+			return storage.ErrPeerNotFound
+		}
+
+		id := key.Bytes(nil)
+		if bucket.Get(id) == nil {
+			return storage.ErrPeerNotFound
+		}
+		return touch(bucket, id, time.Now())
+	})
+}
+
+type recentIterator struct {
+	codec   storage.Codec
+	tx      *bbolt.Tx
+	bucket  *bbolt.Bucket
+	iter    *bbolt.Cursor
+	reverse bool
+	started bool
+	lastErr error
+	value   storage.Peer
+	opts    storage.IterateOptions
+}
+
+func (p *recentIterator) Close() error {
+	return p.tx.Rollback()
+}
+
+func (p *recentIterator) advance() []byte {
+	var k []byte
+	switch {
+	case p.started && p.reverse:
+		k, _ = p.iter.Next()
+	case p.started:
+		k, _ = p.iter.Prev()
+	case p.reverse:
+		k, _ = p.iter.Seek(recentPrefix)
+	default:
+		// Position at the last index entry.
+		if k, _ = p.iter.Seek(keyUpperBound(recentPrefix)); k == nil {
+			k, _ = p.iter.Last()
+		} else {
+			k, _ = p.iter.Prev()
+		}
+	}
+	p.started = true
+
+	if !bytes.HasPrefix(k, recentPrefix) {
+		return nil
+	}
+	return k
+}
+
+func (p *recentIterator) Next(ctx context.Context) bool {
+	now := time.Now()
+	for k := p.advance(); k != nil; k = p.advance() {
+		k = k[len(recentPrefix):]
+		if len(k) < 8 {
+			continue
+		}
+		id := k[8:]
+
+		data := p.bucket.Get(id)
+		if data == nil {
+			continue
+		}
+		p.value = storage.Peer{}
+		if err := p.codec.Unmarshal(data, &p.value); err != nil {
+			if errors.Is(err, storage.ErrPeerUnmarshalMustInvalidate) {
+				continue // skip
+			}
+			p.lastErr = errors.Wrap(err, "unmarshal")
+			return false
+		}
+		if p.value.Expired(now) || !p.opts.Match(p.value) {
+			continue // skip
+		}
+
+		return true
+	}
+	return false
+}
+
+func (p *recentIterator) Err() error {
+	return p.lastErr
+}
+
+func (p *recentIterator) Value() storage.Peer {
+	return p.value
+}
+
+func keyUpperBound(b []byte) []byte {
+	end := make([]byte, len(b))
+	copy(end, b)
+	for i := len(end) - 1; i >= 0; i-- {
+		end[i]++
+		if end[i] != 0 {
+			return end[:i+1]
+		}
+	}
+	return nil // no upper-bound
+}
@@ -0,0 +1,60 @@
+package bbolt
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-faster/errors"
+	"go.etcd.io/bbolt"
+
+	"github.com/gotd/contrib/storage"
+)
+
+var _ storage.PeerBatchResolver = PeerStorage{}
+
+// ResolveBatch finds peers using given associated keys.
+// Keys not found are omitted from result.
+//
+// All keys are resolved in the same transaction.
+func (s PeerStorage) ResolveBatch(ctx context.Context, keys []string) (r map[string]storage.Peer, rerr error) {
+	rerr = s.bbolt.View(func(tx *bbolt.Tx) error {
+		bucket := s.getBucket(tx)
+		if bucket == nil {
+			return errors.Errorf("bucket %q does not exist", s.bucket)
+		}
+
+		now := time.Now()
+		r = make(map[string]storage.Peer, len(keys))
+		for _, key := range keys {
+			if _, ok := r[key]; ok {
+				continue
+			}
+
+			id := bucket.Get([]byte(key))
+			if id == nil {
+				continue
+			}
+			data := bucket.Get(id)
+			if data == nil {
+				continue
+			}
+
+			var p storage.Peer
+			if err := s.codec.Unmarshal(data, &p); err != nil {
+				if errors.Is(err, storage.ErrPeerUnmarshalMustInvalidate) {
+					continue
+				}
+				return errors.Errorf("unmarshal: %w", err)
+			}
+			if p.Expired(now) {
+				continue
+			}
+			r[key] = p
+		}
+		return nil
+	})
+	if rerr != nil {
+		return nil, rerr
+	}
+	return r, nil
+}
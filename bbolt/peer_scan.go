@@ -0,0 +1,39 @@
+package bbolt
+
+import (
+	"context"
+
+	"go.etcd.io/bbolt"
+
+	"github.com/gotd/contrib/storage"
+)
+
+var _ storage.RawScanner = PeerStorage{}
+
+// ScanRaw calls f for every key-value pair of the bucket, including
+// associated keys, indexes and data of other storages in the bucket.
+//
+// Nested buckets are skipped.
+func (s PeerStorage) ScanRaw(ctx context.Context, f func(key, value []byte) error) error {
+	return s.bbolt.View(func(tx *bbolt.Tx) error {
+		bucket := s.getBucket(tx)
+		if bucket == nil {
+			return nil
+		}
+
+		cur := bucket.Cursor()
+		for k, v := cur.First(); k != nil; k, v = cur.Next() {
+			if v == nil {
+				// Nested bucket.
+				continue
+			}
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			if err := f(k, v); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
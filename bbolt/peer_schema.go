@@ -0,0 +1,49 @@
+package bbolt
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/go-faster/errors"
+	"go.etcd.io/bbolt"
+
+	"github.com/gotd/contrib/storage"
+)
+
+var _ storage.SchemaVersioner = PeerStorage{}
+
+// SchemaVersion returns stored schema version.
+func (s PeerStorage) SchemaVersion(ctx context.Context) (v int, err error) {
+	err = s.bbolt.View(func(tx *bbolt.Tx) error {
+		bucket := s.getBucket(tx)
+		if bucket == nil {
+			return nil
+		}
+		data := bucket.Get([]byte(storage.SchemaKey))
+		if data == nil {
+			return nil
+		}
+
+		parsed, err := strconv.Atoi(string(data))
+		if err != nil {
+			return errors.Errorf("parse %q: %w", data, err)
+		}
+		v = parsed
+		return nil
+	})
+	return v, err
+}
+
+// SetSchemaVersion stores schema version.
+func (s PeerStorage) SetSchemaVersion(ctx context.Context, version int) error {
+	return s.bbolt.Update(func(tx *bbolt.Tx) error {
+		bucket, err := s.createBucket(tx)
+		if err != nil {
+			return errors.Errorf("create bucket: %w", err)
+		}
+		if err := bucket.Put([]byte(storage.SchemaKey), strconv.AppendInt(nil, int64(version), 10)); err != nil {
+			return errors.Errorf("put: %w", err)
+		}
+		return nil
+	})
+}
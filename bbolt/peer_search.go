@@ -0,0 +1,146 @@
+package bbolt
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"time"
+
+	"github.com/go-faster/errors"
+	"go.etcd.io/bbolt"
+
+	"github.com/gotd/contrib/storage"
+)
+
+var _ storage.PeerSearcher = PeerStorage{}
+
+// usernamePrefix is a prefix of username index keys.
+// Key is usernamePrefix + lowercase username + 0x00 + peer key.
+var usernamePrefix = []byte("_username:")
+
+func usernameKey(username string, id []byte) []byte {
+	k := make([]byte, 0, len(usernamePrefix)+len(username)+1+len(id))
+	k = append(k, usernamePrefix...)
+	k = append(k, username...)
+	k = append(k, 0)
+	return append(k, id...)
+}
+
+// indexUsername puts username index entry for given peer.
+func indexUsername(bucket *bbolt.Bucket, id []byte, value storage.Peer) error {
+	username := storage.UsernameIndex(value)
+	if username == "" {
+		return nil
+	}
+	if err := bucket.Put(usernameKey(username, id), []byte{}); err != nil {
+		return errors.Errorf("set username: %w", err)
+	}
+	return nil
+}
+
+// deleteUsername deletes username index entry for given peer.
+func deleteUsername(bucket *bbolt.Bucket, id []byte, value storage.Peer) error {
+	username := storage.UsernameIndex(value)
+	if username == "" {
+		return nil
+	}
+	if err := bucket.Delete(usernameKey(username, id)); err != nil {
+		return errors.Errorf("delete username: %w", err)
+	}
+	return nil
+}
+
+type searchIterator struct {
+	codec   storage.Codec
+	tx      *bbolt.Tx
+	bucket  *bbolt.Bucket
+	iter    *bbolt.Cursor
+	prefix  []byte
+	started bool
+	lastErr error
+	value   storage.Peer
+}
+
+func (p *searchIterator) Close() error {
+	return p.tx.Rollback()
+}
+
+func (p *searchIterator) advance() []byte {
+	var k []byte
+	if !p.started {
+		p.started = true
+		k, _ = p.iter.Seek(p.prefix)
+	} else {
+		k, _ = p.iter.Next()
+	}
+	if !bytes.HasPrefix(k, p.prefix) {
+		return nil
+	}
+	return k
+}
+
+func (p *searchIterator) Next(ctx context.Context) bool {
+	now := time.Now()
+	for k := p.advance(); k != nil; k = p.advance() {
+		k = k[len(usernamePrefix):]
+		idx := bytes.IndexByte(k, 0)
+		if idx < 0 {
+			continue
+		}
+		username, id := k[:idx], k[idx+1:]
+
+		data := p.bucket.Get(id)
+		if data == nil {
+			continue
+		}
+		p.value = storage.Peer{}
+		if err := p.codec.Unmarshal(data, &p.value); err != nil {
+			if errors.Is(err, storage.ErrPeerUnmarshalMustInvalidate) {
+				continue // skip
+			}
+			p.lastErr = errors.Wrap(err, "unmarshal")
+			return false
+		}
+		// Peer may change username after indexing.
+		if p.value.Expired(now) || storage.UsernameIndex(p.value) != string(username) {
+			continue // skip
+		}
+
+		return true
+	}
+	return false
+}
+
+func (p *searchIterator) Err() error {
+	return p.lastErr
+}
+
+func (p *searchIterator) Value() storage.Peer {
+	return p.value
+}
+
+// SearchPrefix creates and returns new PeerIterator which returns only
+// peers whose username starts with given prefix.
+//
+// Peers are returned in username order. Search uses username index
+// maintained by Add and Assign.
+func (s PeerStorage) SearchPrefix(ctx context.Context, prefix string) (storage.PeerIterator, error) {
+	tx, err := s.bbolt.Begin(false)
+	if err != nil {
+		return nil, errors.Errorf("create tx: %w", err)
+	}
+
+	bucket := s.getBucket(tx)
+	if bucket == nil {
+		_ = tx.Rollback()
+		return nil, errors.Errorf("bucket %q does not exist", s.bucket)
+	}
+
+	return &searchIterator{
+		codec:  s.codec,
+		tx:     tx,
+		bucket: bucket,
+		iter:   bucket.Cursor(),
+		prefix: append(append([]byte(nil), usernamePrefix...), strings.ToLower(prefix)...),
+	}, nil
+}
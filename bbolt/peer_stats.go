@@ -0,0 +1,27 @@
+package bbolt
+
+import (
+	"context"
+
+	"go.etcd.io/bbolt"
+
+	"github.com/gotd/contrib/storage"
+)
+
+var _ storage.PeerStatsReporter = PeerStorage{}
+
+// Stats returns storage statistics. Bytes is a size of the whole database
+// file, including data of other buckets.
+func (s PeerStorage) Stats(ctx context.Context) (storage.Stats, error) {
+	r, err := storage.RawStats(ctx, s)
+	if err != nil {
+		return storage.Stats{}, err
+	}
+	if err := s.bbolt.View(func(tx *bbolt.Tx) error {
+		r.Bytes = tx.Size()
+		return nil
+	}); err != nil {
+		return storage.Stats{}, err
+	}
+	return r, nil
+}
@@ -3,7 +3,7 @@ package bbolt
 import (
 	"bytes"
 	"context"
-	"encoding/json"
+	"time"
 
 	"github.com/go-faster/errors"
 	"go.etcd.io/bbolt"
@@ -11,57 +11,105 @@ import (
 	"github.com/gotd/contrib/storage"
 )
 
-var _ storage.PeerStorage = PeerStorage{}
+var (
+	_ storage.PeerStorage         = PeerStorage{}
+	_ storage.FilteredPeerStorage = PeerStorage{}
+	_ storage.PeerWatcher         = PeerStorage{}
+)
 
 // PeerStorage is a peer storage based on pebble.
 type PeerStorage struct {
 	bbolt  *bbolt.DB
+	codec  storage.Codec
 	bucket []byte
+	watch  *storage.Broadcaster
+	// ns is a name of nested namespace bucket, if any.
+	ns []byte
 }
 
 // NewPeerStorage creates new peer storage using bbolt.
 func NewPeerStorage(db *bbolt.DB, bucket []byte) *PeerStorage {
-	return &PeerStorage{bbolt: db, bucket: bucket}
+	return &PeerStorage{
+		bbolt:  db,
+		bucket: bucket,
+		codec:  storage.JSONCodec{},
+		watch:  storage.NewBroadcaster(),
+	}
+}
+
+// WithCodec sets codec of persisted peers. Default is storage.JSONCodec.
+//
+// Peers stored using another codec are considered outdated.
+func (s *PeerStorage) WithCodec(c storage.Codec) *PeerStorage {
+	s.codec = c
+	return s
+}
+
+// Watch returns channel of changes made using this PeerStorage.
+//
+// Changes made by other PeerStorage instances of the same database are
+// not observed.
+func (s PeerStorage) Watch(ctx context.Context) (<-chan storage.Event, error) {
+	return s.watch.Watch(ctx)
 }
 
 type bboltIterator struct {
+	codec   storage.Codec
 	tx      *bbolt.Tx
 	iter    *bbolt.Cursor
 	lastErr error
 	value   storage.Peer
+
+	// prefix is a current key prefix to scan.
+	prefix []byte
+	// prefixes are remaining key prefixes to scan after current one.
+	prefixes [][]byte
+	started  bool
+	opts     storage.IterateOptions
 }
 
 func (p *bboltIterator) Close() error {
 	return p.tx.Rollback()
 }
 
-func (p *bboltIterator) Next(ctx context.Context) bool {
-Next:
-	k, v := p.iter.Next()
-	if v == nil {
-		return false
+func (p *bboltIterator) advance() (k, v []byte) {
+	if !p.started {
+		p.started = true
+		return p.iter.Seek(p.prefix)
 	}
+	return p.iter.Next()
+}
 
+func (p *bboltIterator) Next(ctx context.Context) bool {
+	now := time.Now()
 	for {
-		if bytes.HasPrefix(k, storage.PeerKeyPrefix) {
-			break
+		k, v := p.advance()
+		if k == nil || !bytes.HasPrefix(k, p.prefix) {
+			if len(p.prefixes) == 0 {
+				return false
+			}
+			p.prefix, p.prefixes = p.prefixes[0], p.prefixes[1:]
+			p.started = false
+			continue
 		}
-
-		k, v = p.iter.Next()
 		if v == nil {
-			return false
+			// Nested bucket.
+			continue
 		}
-	}
 
-	if err := json.Unmarshal(v, &p.value); err != nil {
-		if errors.Is(err, storage.ErrPeerUnmarshalMustInvalidate) {
-			goto Next // skip
+		if err := p.codec.Unmarshal(v, &p.value); err != nil {
+			if errors.Is(err, storage.ErrPeerUnmarshalMustInvalidate) {
+				continue // skip
+			}
+			p.lastErr = errors.Wrap(err, "unmarshal")
+			return false
+		}
+		if p.value.Expired(now) || !p.opts.Match(p.value) {
+			continue // skip
 		}
-		p.lastErr = errors.Wrap(err, "unmarshal")
-		return false
-	}
 
-	return true
+		return true
+	}
 }
 
 func (p *bboltIterator) Err() error {
@@ -74,37 +122,84 @@ func (p *bboltIterator) Value() storage.Peer {
 
 // Iterate creates and returns new PeerIterator.
 func (s PeerStorage) Iterate(ctx context.Context) (storage.PeerIterator, error) {
+	return s.IterateFiltered(ctx, storage.IterateOptions{})
+}
+
+// IterateFiltered creates and returns new PeerIterator which returns only
+// peers matching given options.
+//
+// Peer kinds are filtered by key prefix without decoding other peers.
+// Recency order uses last-touched index maintained by Add, Assign and Touch.
+func (s PeerStorage) IterateFiltered(ctx context.Context, opts storage.IterateOptions) (storage.PeerIterator, error) {
+	prefixes := opts.Prefixes()
+	if len(prefixes) == 0 {
+		return storage.EmptyIterator(), nil
+	}
+
 	tx, err := s.bbolt.Begin(false)
 	if err != nil {
 		return nil, errors.Errorf("create tx: %w", err)
 	}
 
-	bucket := tx.Bucket(s.bucket)
+	bucket := s.getBucket(tx)
 	if bucket == nil {
+		_ = tx.Rollback()
 		return nil, errors.Errorf("bucket %q does not exist", s.bucket)
 	}
 
-	cur := bucket.Cursor()
-	cur.Seek(storage.PeerKeyPrefix)
-	cur.Prev()
+	if opts.Order != storage.OrderKey {
+		return &recentIterator{
+			codec:   s.codec,
+			tx:      tx,
+			bucket:  bucket,
+			iter:    bucket.Cursor(),
+			reverse: opts.Order == storage.OrderRecentReverse,
+			opts:    opts,
+		}, nil
+	}
+
 	return &bboltIterator{
-		tx:   tx,
-		iter: cur,
+		codec:    s.codec,
+		tx:       tx,
+		iter:     bucket.Cursor(),
+		prefix:   prefixes[0],
+		prefixes: prefixes[1:],
+		opts:     opts,
 	}, nil
 }
 
 func (s PeerStorage) add(associated []string, value storage.Peer) (err error) {
+	var event storage.Event
 	err = s.bbolt.Batch(func(tx *bbolt.Tx) error {
-		bucket, err := tx.CreateBucketIfNotExists(s.bucket)
+		bucket, err := s.createBucket(tx)
 		if err != nil {
 			return errors.Errorf("create bucket: %w", err)
 		}
 
-		data, err := json.Marshal(value)
+		// Batch may call this function multiple times, so do not modify value.
+		event = storage.Event{
+			Type: storage.EventAdd,
+			Key:  storage.KeyFromPeer(value),
+			Peer: value,
+		}
+		id := event.Key.Bytes(nil)
+		var stored *storage.Peer
+		if data := bucket.Get(id); data != nil {
+			var p storage.Peer
+			switch err := s.codec.Unmarshal(data, &p); {
+			case err == nil:
+				stored = &p
+				event.Peer = storage.Merge(p, value)
+				event.Type = storage.EventUpdate
+			case !errors.Is(err, storage.ErrPeerUnmarshalMustInvalidate):
+				return errors.Errorf("unmarshal: %w", err)
+			}
+		}
+
+		data, err := s.codec.Marshal(event.Peer)
 		if err != nil {
 			return errors.Errorf("marshal: %w", err)
 		}
-		id := storage.KeyFromPeer(value).Bytes(nil)
 
 		if err := bucket.Put(id, data); err != nil {
 			return errors.Errorf("set id <-> data: %w", err)
@@ -116,8 +211,25 @@ func (s PeerStorage) add(associated []string, value storage.Peer) (err error) {
 			}
 		}
 
-		return nil
+		if stored != nil {
+			// Remove keys of previous value, like old username.
+			for _, key := range storage.StaleKeys(*stored, append(event.Peer.Keys(), associated...)) {
+				if err := deleteAssociated(bucket, []byte(key), id); err != nil {
+					return err
+				}
+			}
+			if err := deleteUsername(bucket, id, *stored); err != nil {
+				return err
+			}
+		}
+		if err := indexUsername(bucket, id, event.Peer); err != nil {
+			return err
+		}
+		return touch(bucket, id, time.Now())
 	})
+	if err == nil {
+		s.watch.Publish(event)
+	}
 	return
 }
 
@@ -129,7 +241,7 @@ func (s PeerStorage) Add(ctx context.Context, value storage.Peer) error {
 // Find finds peer using given key.
 func (s PeerStorage) Find(ctx context.Context, key storage.PeerKey) (p storage.Peer, rerr error) {
 	rerr = s.bbolt.View(func(tx *bbolt.Tx) error {
-		bucket := tx.Bucket(s.bucket)
+		bucket := s.getBucket(tx)
 		if bucket == nil {
 			return errors.Errorf("bucket %q does not exist", s.bucket)
 		}
@@ -139,12 +251,15 @@ func (s PeerStorage) Find(ctx context.Context, key storage.PeerKey) (p storage.P
 			return storage.ErrPeerNotFound
 		}
 
-		if err := json.Unmarshal(data, &p); err != nil {
+		if err := s.codec.Unmarshal(data, &p); err != nil {
 			if errors.Is(err, storage.ErrPeerUnmarshalMustInvalidate) {
 				return storage.ErrPeerNotFound
 			}
 			return errors.Errorf("unmarshal: %w", err)
 		}
+		if p.Expired(time.Now()) {
+			return storage.ErrPeerNotFound
+		}
 		return nil
 	})
 	return
@@ -158,7 +273,7 @@ func (s PeerStorage) Assign(ctx context.Context, key string, value storage.Peer)
 // Resolve finds peer using associated key.
 func (s PeerStorage) Resolve(ctx context.Context, key string) (p storage.Peer, rerr error) {
 	rerr = s.bbolt.View(func(tx *bbolt.Tx) error {
-		bucket := tx.Bucket(s.bucket)
+		bucket := s.getBucket(tx)
 		if bucket == nil {
 			return errors.Errorf("bucket %q does not exist", s.bucket)
 		}
@@ -173,12 +288,15 @@ func (s PeerStorage) Resolve(ctx context.Context, key string) (p storage.Peer, r
 			return storage.ErrPeerNotFound
 		}
 
-		if err := json.Unmarshal(data, &p); err != nil {
+		if err := s.codec.Unmarshal(data, &p); err != nil {
 			if errors.Is(err, storage.ErrPeerUnmarshalMustInvalidate) {
 				return storage.ErrPeerNotFound
 			}
 			return errors.Errorf("unmarshal: %w", err)
 		}
+		if p.Expired(time.Now()) {
+			return storage.ErrPeerNotFound
+		}
 		return nil
 	})
 	return
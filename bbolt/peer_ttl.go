@@ -0,0 +1,100 @@
+package bbolt
+
+import (
+	"bytes"
+	"context"
+	"time"
+
+	"github.com/go-faster/errors"
+	"go.etcd.io/bbolt"
+
+	"github.com/gotd/contrib/storage"
+)
+
+var _ storage.ExpiringPeerStorage = PeerStorage{}
+
+// AddTTL adds given peer to the storage. Peer expires after given ttl.
+//
+// Bbolt has no native expiration, so expired peers are skipped on read
+// and removed by DeleteExpired.
+func (s PeerStorage) AddTTL(ctx context.Context, value storage.Peer, ttl time.Duration) error {
+	return s.add(value.Keys(), storage.WithTTL(value, time.Now(), ttl))
+}
+
+// DeleteExpired removes expired peers and their associated keys.
+// It returns count of removed peers.
+//
+// It is intended to be called periodically to bound storage growth.
+func (s PeerStorage) DeleteExpired(ctx context.Context) (_ int, rerr error) {
+	var keys []storage.PeerKey
+	rerr = s.bbolt.Update(func(tx *bbolt.Tx) error {
+		bucket := s.getBucket(tx)
+		if bucket == nil {
+			return nil
+		}
+
+		type expired struct {
+			id    []byte
+			value storage.Peer
+		}
+		var (
+			now   = time.Now()
+			value storage.Peer
+			toDel []expired
+		)
+		cur := bucket.Cursor()
+		for k, v := cur.Seek(storage.PeerKeyPrefix); k != nil && bytes.HasPrefix(k, storage.PeerKeyPrefix); k, v = cur.Next() {
+			if err := s.codec.Unmarshal(v, &value); err != nil {
+				if errors.Is(err, storage.ErrPeerUnmarshalMustInvalidate) {
+					continue
+				}
+				return errors.Errorf("unmarshal: %w", err)
+			}
+			if !value.Expired(now) {
+				continue
+			}
+			toDel = append(toDel, expired{
+				id:    append([]byte(nil), k...),
+				value: value,
+			})
+		}
+
+		for _, e := range toDel {
+			if err := bucket.Delete(e.id); err != nil {
+				return errors.Errorf("delete %q: %w", e.id, err)
+			}
+			for _, key := range e.value.Keys() {
+				if err := deleteAssociated(bucket, []byte(key), e.id); err != nil {
+					return err
+				}
+			}
+			if err := deleteRecent(bucket, e.id); err != nil {
+				return err
+			}
+			if err := deleteUsername(bucket, e.id, e.value); err != nil {
+				return err
+			}
+			keys = append(keys, storage.KeyFromPeer(e.value))
+		}
+
+		return nil
+	})
+	if rerr != nil {
+		return 0, rerr
+	}
+	for _, key := range keys {
+		s.watch.Publish(storage.Event{Type: storage.EventDelete, Key: key})
+	}
+	return len(keys), nil
+}
+
+// deleteAssociated deletes associated key if it still points to given id.
+func deleteAssociated(bucket *bbolt.Bucket, key, id []byte) error {
+	if !bytes.Equal(bucket.Get(key), id) {
+		return nil
+	}
+	if err := bucket.Delete(key); err != nil {
+		return errors.Errorf("delete %q: %w", key, err)
+	}
+	return nil
+}
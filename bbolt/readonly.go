@@ -0,0 +1,20 @@
+package bbolt
+
+import (
+	"go.etcd.io/bbolt"
+)
+
+// ReadOnlyOptions returns bbolt options to open existing database in
+// read-only mode, e.g. to inspect stored peers by external tools.
+//
+// Read-only database is locked using shared lock, so it can be opened by
+// multiple readers at once. Database opened for writing by another process
+// holds exclusive lock, so Open waits for it until Timeout. Writes to
+// storages of read-only database fail with bbolt.ErrDatabaseReadOnly, use
+// storage.NewReadOnly to reject them early.
+func ReadOnlyOptions() *bbolt.Options {
+	return &bbolt.Options{
+		Timeout:  bbolt.DefaultOptions.Timeout,
+		ReadOnly: true,
+	}
+}
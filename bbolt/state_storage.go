@@ -232,3 +232,13 @@ func (s *State) ForEachChannels(ctx context.Context, userID int64, f func(ctx co
 		})
 	})
 }
+
+// DeleteState removes all updates state of given user.
+func (s *State) DeleteState(_ context.Context, userID int64) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		if tx.Bucket(i642b(userID)) == nil {
+			return nil
+		}
+		return tx.DeleteBucket(i642b(userID))
+	})
+}
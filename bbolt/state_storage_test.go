@@ -7,6 +7,8 @@ import (
 
 	"github.com/stretchr/testify/require"
 	bboltdb "go.etcd.io/bbolt"
+
+	"github.com/gotd/td/telegram/updates"
 )
 
 func TestState(t *testing.T) {
@@ -20,5 +22,16 @@ func TestState(t *testing.T) {
 		return nil
 	}
 	require.NoError(t, state.ForEachChannels(ctx, 0, cb))
+
+	require.NoError(t, state.SetState(ctx, 1, updates.State{Pts: 1}))
+	_, found, err := state.GetState(ctx, 1)
+	require.NoError(t, err)
+	require.True(t, found)
+	require.NoError(t, state.DeleteState(ctx, 1))
+	_, found, err = state.GetState(ctx, 1)
+	require.NoError(t, err)
+	require.False(t, found)
+	require.NoError(t, state.DeleteState(ctx, 1))
+
 	require.NoError(t, db.Close())
 }
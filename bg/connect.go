@@ -4,6 +4,11 @@ package bg
 import (
 	"context"
 	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/cenkalti/backoff/v4"
 )
 
 // Client abstracts telegram client.
@@ -14,8 +19,52 @@ type Client interface {
 // StopFunc closes Client and waits until Run returns.
 type StopFunc func() error
 
+// State is a state of background client.
+type State int
+
+const (
+	// StateConnecting means that client is starting.
+	StateConnecting State = iota
+	// StateConnected means that client is connected.
+	StateConnected
+	// StateReconnecting means that client failed and waits for backoff
+	// before restart.
+	StateReconnecting
+	// StateStopped means that client is stopped and will not be restarted.
+	StateStopped
+	// StateReady means that client is connected and ready check is passed,
+	// see WithReadyCheck.
+	StateReady
+)
+
+// String implements fmt.Stringer.
+func (s State) String() string {
+	switch s {
+	case StateConnecting:
+		return "connecting"
+	case StateConnected:
+		return "connected"
+	case StateReconnecting:
+		return "reconnecting"
+	case StateStopped:
+		return "stopped"
+	case StateReady:
+		return "ready"
+	default:
+		return "State(" + strconv.Itoa(int(s)) + ")"
+	}
+}
+
 type connectOptions struct {
-	ctx context.Context
+	ctx     context.Context
+	backoff backoff.BackOff
+	onState func(s State, err error)
+	ready   func(ctx context.Context) error
+	onReady func()
+
+	drainer      *Drainer
+	drainTimeout time.Duration
+	flush        func(ctx context.Context) error
 }
 
 // Option for Connect.
@@ -36,11 +85,79 @@ func WithContext(ctx context.Context) Option {
 	})
 }
 
-// Connect blocks until client is connected, calling Run internally in
-// background.
+// WithReconnect enables restart of client, if Run fails after client is
+// ready, using given backoff, e.g. backoff.NewExponentialBackOff,
+// which is jittered by default.
+//
+// Backoff is reset every time client becomes ready. Client is stopped when
+// backoff returns backoff.Stop, and StopFunc returns last error.
+func WithReconnect(b backoff.BackOff) Option {
+	return fnOption(func(o *connectOptions) {
+		o.backoff = b
+	})
+}
+
+// OnStateChange sets callback called on every state change. Error is set
+// for StateReconnecting and StateStopped, if client failed.
+//
+// Callback is called synchronously and should not block.
+func OnStateChange(f func(s State, err error)) Option {
+	return fnOption(func(o *connectOptions) {
+		o.onState = f
+	})
+}
+
+// WithReadyCheck sets check which is called after every connect, e.g.
+// health.Authorized(client.Auth()). Client is ready only if check passes,
+// otherwise Run fails with check error.
+//
+// Check may also do initialization, e.g. authenticate using
+// client.Auth().IfNecessary.
+func WithReadyCheck(f func(ctx context.Context) error) Option {
+	return fnOption(func(o *connectOptions) {
+		o.ready = f
+	})
+}
+
+// OnReady sets callback called every time client becomes ready, i.e. after
+// connect and reconnects. First call happens before Connect returns.
+//
+// Callback is called synchronously and should not block.
+func OnReady(f func()) Option {
+	return fnOption(func(o *connectOptions) {
+		o.onReady = f
+	})
+}
+
+// WithDrain enables graceful stop: StopFunc rejects new calls and updates
+// using given Drainer, waits for in-flight ones at most timeout, flushes
+// session (see WithFlush) and only then closes client. Zero timeout means
+// no limit.
+//
+// Drainer should be used as middleware and update handler of client.
+func WithDrain(d *Drainer, timeout time.Duration) Option {
+	return fnOption(func(o *connectOptions) {
+		o.drainer = d
+		o.drainTimeout = timeout
+	})
+}
+
+// WithFlush sets function to flush session storage on stop, before client
+// is closed, e.g. of coalesce.Session.
+func WithFlush(f func(ctx context.Context) error) Option {
+	return fnOption(func(o *connectOptions) {
+		o.flush = f
+	})
+}
+
+// Connect blocks until client is connected and ready, calling Run
+// internally in background.
 func Connect(client Client, options ...Option) (StopFunc, error) {
 	opt := &connectOptions{
-		ctx: context.Background(),
+		ctx:     context.Background(),
+		onState: func(s State, err error) {},
+		ready:   func(ctx context.Context) error { return nil },
+		onReady: func() {},
 	}
 	for _, o := range options {
 		o.apply(opt)
@@ -50,16 +167,39 @@ func Connect(client Client, options ...Option) (StopFunc, error) {
 
 	errC := make(chan error, 1)
 	initDone := make(chan struct{})
-	go func() {
-		defer close(errC)
-		errC <- client.Run(ctx, func(ctx context.Context) error {
-			close(initDone)
+	run := func() error {
+		opt.onState(StateConnecting, nil)
+		return client.Run(ctx, func(ctx context.Context) error {
+			opt.onState(StateConnected, nil)
+			if err := opt.ready(ctx); err != nil {
+				return fmt.Errorf("ready check: %w", err)
+			}
+			if opt.backoff != nil {
+				opt.backoff.Reset()
+			}
+			opt.onState(StateReady, nil)
+			opt.onReady()
+			select {
+			case <-initDone:
+			default:
+				close(initDone)
+			}
+
 			<-ctx.Done()
 			if errors.Is(ctx.Err(), context.Canceled) {
 				return nil
 			}
 			return ctx.Err()
 		})
+	}
+	go func() {
+		defer close(errC)
+		err := run()
+		if opt.backoff != nil {
+			err = reconnect(ctx, opt, initDone, err, run)
+		}
+		opt.onState(StateStopped, err)
+		errC <- err
 	}()
 
 	select {
@@ -73,8 +213,67 @@ func Connect(client Client, options ...Option) (StopFunc, error) {
 	}
 
 	stopFn := func() error {
+		shutdownErr := shutdown(opt)
 		cancel()
-		return <-errC
+		if err := <-errC; err != nil {
+			return err
+		}
+		return shutdownErr
 	}
 	return stopFn, nil
 }
+
+// shutdown drains and flushes client before close.
+func shutdown(opt *connectOptions) error {
+	ctx := context.WithoutCancel(opt.ctx)
+
+	var errs []error
+	if opt.drainer != nil {
+		drainCtx := ctx
+		if opt.drainTimeout > 0 {
+			var cancel context.CancelFunc
+			drainCtx, cancel = context.WithTimeout(ctx, opt.drainTimeout)
+			defer cancel()
+		}
+		if err := opt.drainer.Drain(drainCtx); err != nil {
+			errs = append(errs, fmt.Errorf("drain: %w", err))
+		}
+	}
+	if opt.flush != nil {
+		if err := opt.flush(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("flush: %w", err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// reconnect restarts failed client until it is stopped or backoff gives up.
+func reconnect(ctx context.Context, opt *connectOptions, initDone <-chan struct{}, err error, run func() error) error {
+	select {
+	case <-initDone:
+	default:
+		// Startup errors are returned by Connect.
+		return err
+	}
+
+	for err != nil && ctx.Err() == nil {
+		d := opt.backoff.NextBackOff()
+		if d == backoff.Stop {
+			return err
+		}
+		opt.onState(StateReconnecting, err)
+
+		t := time.NewTimer(d)
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			t.Stop()
+			if errors.Is(ctx.Err(), context.Canceled) {
+				return nil
+			}
+			return ctx.Err()
+		}
+		err = run()
+	}
+	return err
+}
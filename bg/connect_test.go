@@ -2,10 +2,23 @@ package bg
 
 import (
 	"context"
+	"crypto/rand"
+	"errors"
+	"sync"
 	"testing"
+	"time"
 
+	"github.com/cenkalti/backoff/v4"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+
+	"github.com/gotd/td/bin"
+	"github.com/gotd/td/telegram"
+	"github.com/gotd/td/telegram/auth"
+	"github.com/gotd/td/tg"
+	"github.com/gotd/td/tgerr"
+
+	"github.com/gotd/contrib/health"
 )
 
 type testKey string
@@ -26,3 +39,122 @@ func TestConnect(t *testing.T) {
 	require.NoError(t, err)
 	require.NoError(t, stop())
 }
+
+type flakyClient struct {
+	failures int
+	// connects is a count of failed runs which connect before failure.
+	connects int
+	runs     int
+}
+
+func (c *flakyClient) Run(ctx context.Context, f func(ctx context.Context) error) error {
+	c.runs++
+	if c.runs > c.failures {
+		return f(ctx)
+	}
+	if c.runs > c.connects {
+		return errors.New("connect failed")
+	}
+
+	// Connect, then disconnect.
+	connCtx, cancel := context.WithCancel(ctx)
+	cancel()
+	if err := f(connCtx); err != nil {
+		return err
+	}
+	return errors.New("disconnected")
+}
+
+func TestConnectReconnect(t *testing.T) {
+	a := require.New(t)
+	client := &flakyClient{failures: 2, connects: 2}
+
+	var (
+		states []State
+		mux    sync.Mutex
+	)
+	connected := make(chan struct{}, 10)
+	stop, err := Connect(client,
+		WithReconnect(backoff.NewConstantBackOff(time.Millisecond)),
+		OnStateChange(func(s State, err error) {
+			mux.Lock()
+			defer mux.Unlock()
+			states = append(states, s)
+			if s == StateConnected {
+				connected <- struct{}{}
+			}
+		}),
+	)
+	a.NoError(err)
+	for i := 0; i < 3; i++ {
+		<-connected
+	}
+	a.NoError(stop())
+	a.Equal(3, client.runs)
+
+	mux.Lock()
+	defer mux.Unlock()
+	a.Equal([]State{
+		StateConnecting, StateConnected, StateReady, StateReconnecting,
+		StateConnecting, StateConnected, StateReady, StateReconnecting,
+		StateConnecting, StateConnected, StateReady,
+		StateStopped,
+	}, states)
+}
+
+func TestConnectReconnectStop(t *testing.T) {
+	client := &flakyClient{failures: 10, connects: 1}
+	stopped := make(chan struct{})
+	stop, err := Connect(client,
+		WithReconnect(backoff.WithMaxRetries(backoff.NewConstantBackOff(time.Millisecond), 1)),
+		OnStateChange(func(s State, err error) {
+			if s == StateStopped {
+				close(stopped)
+			}
+		}),
+	)
+	require.NoError(t, err)
+	<-stopped
+	require.EqualError(t, stop(), "connect failed")
+	require.Equal(t, 2, client.runs)
+}
+
+func TestConnectReadyCheck(t *testing.T) {
+	a := require.New(t)
+	testErr := errors.New("test")
+
+	_, err := Connect(testClient{tt: t},
+		WithContext(context.WithValue(context.Background(), testKey("foo"), "bar")),
+		WithReadyCheck(func(ctx context.Context) error {
+			return testErr
+		}),
+	)
+	a.ErrorIs(err, testErr)
+
+	var checked, ready int
+	stop, err := Connect(testClient{tt: t},
+		WithContext(context.WithValue(context.Background(), testKey("foo"), "bar")),
+		WithReadyCheck(func(ctx context.Context) error {
+			checked++
+			return nil
+		}),
+		OnReady(func() {
+			ready++
+		}),
+	)
+	a.NoError(err)
+	a.Equal(1, checked)
+	a.Equal(1, ready)
+	a.NoError(stop())
+}
+
+func TestConnectAuthorized(t *testing.T) {
+	api := tg.NewClient(telegram.InvokeFunc(func(ctx context.Context, input bin.Encoder, output bin.Decoder) error {
+		return tgerr.New(401, "AUTH_KEY_UNREGISTERED")
+	}))
+	_, err := Connect(testClient{tt: t},
+		WithContext(context.WithValue(context.Background(), testKey("foo"), "bar")),
+		WithReadyCheck(health.Authorized(auth.NewClient(api, rand.Reader, 1, "hash"))),
+	)
+	require.ErrorIs(t, err, health.ErrNotAuthorized)
+}
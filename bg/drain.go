@@ -0,0 +1,86 @@
+package bg
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/gotd/td/bin"
+	"github.com/gotd/td/telegram"
+	"github.com/gotd/td/tg"
+)
+
+// ErrDraining is returned by Drainer for calls and updates started after
+// draining.
+var ErrDraining = errors.New("client is draining")
+
+// Drainer tracks in-flight calls and update handlers to wait for them
+// before shutdown, see WithDrain.
+//
+// Use Handle as telegram.Middleware and UpdateHandler to wrap handler of
+// updates.
+type Drainer struct {
+	mux      sync.Mutex
+	draining bool
+	wg       sync.WaitGroup
+}
+
+// NewDrainer creates new Drainer.
+func NewDrainer() *Drainer {
+	return &Drainer{}
+}
+
+func (d *Drainer) acquire() error {
+	d.mux.Lock()
+	defer d.mux.Unlock()
+
+	if d.draining {
+		return ErrDraining
+	}
+	d.wg.Add(1)
+	return nil
+}
+
+// Handle implements telegram.Middleware.
+func (d *Drainer) Handle(next tg.Invoker) telegram.InvokeFunc {
+	return func(ctx context.Context, input bin.Encoder, output bin.Decoder) error {
+		if err := d.acquire(); err != nil {
+			return err
+		}
+		defer d.wg.Done()
+
+		return next.Invoke(ctx, input, output)
+	}
+}
+
+// UpdateHandler returns telegram.UpdateHandler which tracks next one.
+func (d *Drainer) UpdateHandler(next telegram.UpdateHandler) telegram.UpdateHandler {
+	return telegram.UpdateHandlerFunc(func(ctx context.Context, u tg.UpdatesClass) error {
+		if err := d.acquire(); err != nil {
+			return err
+		}
+		defer d.wg.Done()
+
+		return next.Handle(ctx, u)
+	})
+}
+
+// Drain rejects new calls and updates, and waits until in-flight ones are
+// finished or given context is done.
+func (d *Drainer) Drain(ctx context.Context) error {
+	d.mux.Lock()
+	d.draining = true
+	d.mux.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		d.wg.Wait()
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,74 @@
+package bg
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/gotd/td/bin"
+	"github.com/gotd/td/telegram"
+	"github.com/gotd/td/tg"
+)
+
+func TestDrainer(t *testing.T) {
+	a := require.New(t)
+	ctx := context.Background()
+	d := NewDrainer()
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	invoker := d.Handle(telegram.InvokeFunc(func(ctx context.Context, input bin.Encoder, output bin.Decoder) error {
+		close(started)
+		<-release
+		return nil
+	}))
+	done := make(chan error, 1)
+	go func() {
+		done <- invoker.Invoke(ctx, &tg.HelpGetConfigRequest{}, nil)
+	}()
+	<-started
+
+	timeoutCtx, cancel := context.WithTimeout(ctx, time.Millisecond)
+	defer cancel()
+	a.ErrorIs(d.Drain(timeoutCtx), context.DeadlineExceeded)
+	a.ErrorIs(invoker.Invoke(ctx, &tg.HelpGetConfigRequest{}, nil), ErrDraining)
+
+	h := d.UpdateHandler(telegram.UpdateHandlerFunc(func(ctx context.Context, u tg.UpdatesClass) error {
+		return nil
+	}))
+	a.ErrorIs(h.Handle(ctx, &tg.Updates{}), ErrDraining)
+
+	close(release)
+	a.NoError(<-done)
+	a.NoError(d.Drain(ctx))
+}
+
+func TestConnectDrain(t *testing.T) {
+	a := require.New(t)
+	d := NewDrainer()
+
+	var steps []string
+	stop, err := Connect(testClient{tt: t},
+		WithContext(context.WithValue(context.Background(), testKey("foo"), "bar")),
+		WithDrain(d, time.Second),
+		WithFlush(func(ctx context.Context) error {
+			steps = append(steps, "flush")
+			return nil
+		}),
+		OnStateChange(func(s State, err error) {
+			if s == StateStopped {
+				steps = append(steps, "stopped")
+			}
+		}),
+	)
+	a.NoError(err)
+	a.NoError(stop())
+	a.Equal([]string{"flush", "stopped"}, steps)
+
+	invoker := d.Handle(telegram.InvokeFunc(func(ctx context.Context, input bin.Encoder, output bin.Decoder) error {
+		return nil
+	}))
+	a.ErrorIs(invoker.Invoke(context.Background(), &tg.HelpGetConfigRequest{}, nil), ErrDraining)
+}
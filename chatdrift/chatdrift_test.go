@@ -0,0 +1,141 @@
+package chatdrift
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	pebbledb "github.com/cockroachdb/pebble"
+	"github.com/cockroachdb/pebble/vfs"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gotd/td/tg"
+	"github.com/gotd/td/tgmock"
+
+	"github.com/gotd/contrib/pebble"
+)
+
+func TestDiff(t *testing.T) {
+	old := Settings{
+		Title:        "old",
+		BannedRights: []string{"send_media"},
+		Slowmode:     10,
+		Admins: []Admin{
+			{UserID: 1, Creator: true},
+			{UserID: 2, Rights: []string{"ban_users"}},
+			{UserID: 3, Rank: "mod"},
+		},
+	}
+	new := Settings{
+		Title:        "new",
+		BannedRights: []string{"send_media"},
+		LinkedChatID: 100,
+		Admins: []Admin{
+			{UserID: 1, Creator: true},
+			{UserID: 2, Rights: []string{"ban_users", "add_admins"}},
+			{UserID: 4},
+		},
+	}
+
+	require.Equal(t, []Change{
+		{Field: "title", Old: "old", New: "new"},
+		{Field: "slowmode", Old: "10"},
+		{Field: "linked_chat", New: "100"},
+		{Field: "admin 2", Old: "rights=ban_users", New: "rights=ban_users,add_admins"},
+		{Field: "admin 4", New: "rights="},
+		{Field: "admin 3", Old: "rights= rank=mod"},
+	}, Diff(old, new))
+	require.Empty(t, Diff(old, old))
+}
+
+func newMock(t *testing.T) (*tgmock.Mock, *pebble.PeerStorage) {
+	db, err := pebbledb.Open("pebble.db", &pebbledb.Options{
+		FS: vfs.NewMem(),
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = db.Close()
+	})
+	return tgmock.New(t), pebble.NewPeerStorage(db)
+}
+
+func expectFetch(mock *tgmock.Mock, input *tg.InputChannel, title string, admins ...tg.ChannelParticipantClass) {
+	ch := &tg.Channel{
+		ID:         input.ChannelID,
+		AccessHash: input.AccessHash,
+		Title:      title,
+		Photo:      &tg.ChatPhotoEmpty{},
+		Megagroup:  true,
+	}
+	ch.SetDefaultBannedRights(tg.ChatBannedRights{SendPolls: true})
+
+	mock.ExpectCall(&tg.ChannelsGetFullChannelRequest{Channel: input}).ThenResult(&tg.MessagesChatFull{
+		FullChat: &tg.ChannelFull{
+			ID:        input.ChannelID,
+			ChatPhoto: &tg.PhotoEmpty{},
+		},
+		Chats: []tg.ChatClass{ch},
+	})
+	mock.ExpectCall(&tg.ChannelsGetParticipantsRequest{
+		Channel: input,
+		Filter:  &tg.ChannelParticipantsAdmins{},
+		Limit:   maxAdmins,
+	}).ThenResult(&tg.ChannelsChannelParticipants{
+		Count:        len(admins),
+		Participants: admins,
+	})
+}
+
+func TestMonitor(t *testing.T) {
+	a := require.New(t)
+	ctx := context.Background()
+	mock, peers := newMock(t)
+	input := &tg.InputChannel{ChannelID: 10, AccessHash: 20}
+
+	var drift []Change
+	m := NewMonitor(tg.NewClient(mock), peers).OnDrift(
+		func(ctx context.Context, channel *tg.Channel, old Snapshot, changes []Change) error {
+			a.Equal("title", old.Settings.Title)
+			drift = changes
+			return nil
+		},
+	)
+
+	// No snapshot yet, current settings are stored.
+	expectFetch(mock, input, "title", &tg.ChannelParticipantCreator{UserID: 1})
+	changes, err := m.Check(ctx, input)
+	a.NoError(err)
+	a.Empty(changes)
+
+	s, ok, err := m.Load(ctx, input.ChannelID)
+	a.NoError(err)
+	a.True(ok)
+	a.Equal(Settings{
+		Title:        "title",
+		BannedRights: []string{"send_polls"},
+		Admins:       []Admin{{UserID: 1, Creator: true}},
+	}, s.Settings)
+
+	// No changes.
+	expectFetch(mock, input, "title", &tg.ChannelParticipantCreator{UserID: 1})
+	changes, err = m.Check(ctx, input)
+	a.NoError(err)
+	a.Empty(changes)
+	a.Nil(drift)
+
+	// Drift is reported and stored.
+	expectFetch(mock, input, "title",
+		&tg.ChannelParticipantCreator{UserID: 1},
+		&tg.ChannelParticipantAdmin{UserID: 2, AdminRights: tg.ChatAdminRights{BanUsers: true}},
+	)
+	changes, err = m.Check(ctx, input)
+	a.NoError(err)
+	a.Equal([]Change{{Field: "admin 2", New: "rights=ban_users"}}, changes)
+	a.Equal(changes, drift)
+
+	s, ok, err = m.Load(ctx, input.ChannelID)
+	a.NoError(err)
+	a.True(ok)
+	a.Len(s.Settings.Admins, 2)
+	a.WithinDuration(time.Now(), s.At, time.Minute)
+}
@@ -0,0 +1,81 @@
+package chatdrift
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Change is a single settings change.
+type Change struct {
+	// Field is a changed setting, e.g. "title" or "admin 123".
+	Field string
+	// Old is an old value, empty if setting was not set.
+	Old string
+	// New is a new value, empty if setting was removed.
+	New string
+}
+
+// String implements fmt.Stringer.
+func (c Change) String() string {
+	return fmt.Sprintf("%s: %q -> %q", c.Field, c.Old, c.New)
+}
+
+// Diff returns changes from old settings to new.
+func Diff(old, new Settings) []Change {
+	var r []Change
+	add := func(field, o, n string) {
+		if o != n {
+			r = append(r, Change{Field: field, Old: o, New: n})
+		}
+	}
+
+	add("title", old.Title, new.Title)
+	add("banned_rights", strings.Join(old.BannedRights, ","), strings.Join(new.BannedRights, ","))
+	add("slowmode", formatInt(int64(old.Slowmode)), formatInt(int64(new.Slowmode)))
+	add("linked_chat", formatInt(old.LinkedChatID), formatInt(new.LinkedChatID))
+
+	admins := make(map[int64]Admin, len(old.Admins))
+	for _, a := range old.Admins {
+		admins[a.UserID] = a
+	}
+	for _, a := range new.Admins {
+		field := "admin " + strconv.FormatInt(a.UserID, 10)
+		o, ok := admins[a.UserID]
+		delete(admins, a.UserID)
+		if !ok {
+			add(field, "", formatAdmin(a))
+			continue
+		}
+		add(field, formatAdmin(o), formatAdmin(a))
+	}
+	// Removed admins, in old order.
+	for _, a := range old.Admins {
+		if _, ok := admins[a.UserID]; ok {
+			add("admin "+strconv.FormatInt(a.UserID, 10), formatAdmin(a), "")
+		}
+	}
+
+	return r
+}
+
+func formatInt(v int64) string {
+	if v == 0 {
+		return ""
+	}
+	return strconv.FormatInt(v, 10)
+}
+
+func formatAdmin(a Admin) string {
+	var b strings.Builder
+	if a.Creator {
+		b.WriteString("creator ")
+	}
+	b.WriteString("rights=")
+	b.WriteString(strings.Join(a.Rights, ","))
+	if a.Rank != "" {
+		b.WriteString(" rank=")
+		b.WriteString(a.Rank)
+	}
+	return b.String()
+}
@@ -0,0 +1,6 @@
+// Package chatdrift snapshots channel settings into peer storage and
+// detects their drift over time.
+//
+// Tracked settings are title, default banned rights, slow mode, linked
+// chat and admin list with admin rights and ranks.
+package chatdrift
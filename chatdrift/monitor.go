@@ -0,0 +1,196 @@
+package chatdrift
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/go-faster/errors"
+	"go.uber.org/zap"
+
+	"github.com/gotd/td/clock"
+	"github.com/gotd/td/telegram/query/dialogs"
+	"github.com/gotd/td/tg"
+
+	"github.com/gotd/contrib/storage"
+)
+
+// MetadataKey is a peer metadata key of stored snapshot.
+const MetadataKey = "chatdrift"
+
+// Snapshot is a stored settings snapshot.
+type Snapshot struct {
+	At       time.Time `json:"at"`
+	Settings Settings  `json:"settings"`
+}
+
+// Handler is called when settings drift is detected.
+//
+// If Handler returns error, stored snapshot is not updated, so the same
+// drift is reported again on next check.
+type Handler func(ctx context.Context, channel *tg.Channel, old Snapshot, changes []Change) error
+
+// Monitor periodically checks channel settings and reports drift from
+// stored snapshot.
+type Monitor struct {
+	api   *tg.Client
+	peers storage.PeerStorage
+
+	clock    clock.Clock
+	log      *zap.Logger
+	interval time.Duration
+	onDrift  Handler
+}
+
+// NewMonitor creates new Monitor which stores snapshots in given peer storage.
+func NewMonitor(api *tg.Client, peers storage.PeerStorage) *Monitor {
+	return &Monitor{
+		api:      api,
+		peers:    peers,
+		clock:    clock.System,
+		log:      zap.NewNop(),
+		interval: time.Hour,
+		onDrift: func(ctx context.Context, channel *tg.Channel, old Snapshot, changes []Change) error {
+			return nil
+		},
+	}
+}
+
+// WithClock sets clock to use. Default is to use system clock.
+func (m *Monitor) WithClock(c clock.Clock) *Monitor {
+	m.clock = c
+	return m
+}
+
+// WithLog sets logger to use. Default is no-op logger.
+func (m *Monitor) WithLog(log *zap.Logger) *Monitor {
+	m.log = log
+	return m
+}
+
+// WithInterval sets interval between checks of Run. Default is 1 hour.
+func (m *Monitor) WithInterval(interval time.Duration) *Monitor {
+	m.interval = interval
+	return m
+}
+
+// OnDrift sets drift handler.
+func (m *Monitor) OnDrift(h Handler) *Monitor {
+	m.onDrift = h
+	return m
+}
+
+// Load loads stored snapshot of channel with given ID.
+func (m *Monitor) Load(ctx context.Context, channelID int64) (Snapshot, bool, error) {
+	v, ok, err := storage.GetMetadata(ctx, m.peers, storage.PeerKey{
+		Kind: dialogs.Channel,
+		ID:   channelID,
+	}, MetadataKey)
+	if err != nil {
+		if errors.Is(err, storage.ErrPeerNotFound) {
+			return Snapshot{}, false, nil
+		}
+		return Snapshot{}, false, errors.Errorf("get metadata: %w", err)
+	}
+	if !ok {
+		return Snapshot{}, false, nil
+	}
+
+	data, ok := v.(string)
+	if !ok {
+		return Snapshot{}, false, errors.Errorf("unexpected metadata type %T", v)
+	}
+	var s Snapshot
+	if err := json.Unmarshal([]byte(data), &s); err != nil {
+		return Snapshot{}, false, errors.Errorf("unmarshal: %w", err)
+	}
+	return s, true, nil
+}
+
+func (m *Monitor) save(ctx context.Context, ch *tg.Channel, s Snapshot) error {
+	data, err := json.Marshal(s)
+	if err != nil {
+		return errors.Errorf("marshal: %w", err)
+	}
+
+	var p storage.Peer
+	if !p.FromChat(ch) {
+		return errors.Errorf("unexpected channel %d", ch.ID)
+	}
+	// Add merges metadata, so other entries are kept.
+	p.Metadata = map[string]any{MetadataKey: string(data)}
+	if err := m.peers.Add(ctx, p); err != nil {
+		return errors.Errorf("add: %w", err)
+	}
+	return nil
+}
+
+// Record fetches current settings of given channel and stores them as
+// a new snapshot.
+func (m *Monitor) Record(ctx context.Context, channel tg.InputChannelClass) (Snapshot, error) {
+	ch, settings, err := Fetch(ctx, m.api, channel)
+	if err != nil {
+		return Snapshot{}, err
+	}
+	s := Snapshot{At: m.clock.Now(), Settings: settings}
+	if err := m.save(ctx, ch, s); err != nil {
+		return Snapshot{}, err
+	}
+	return s, nil
+}
+
+// Check fetches current settings of given channel and compares them with
+// stored snapshot. If drift is detected, drift handler is called and
+// snapshot is updated.
+//
+// If there is no stored snapshot, current settings are stored and no
+// changes are reported.
+func (m *Monitor) Check(ctx context.Context, channel tg.InputChannelClass) ([]Change, error) {
+	ch, settings, err := Fetch(ctx, m.api, channel)
+	if err != nil {
+		return nil, err
+	}
+	current := Snapshot{At: m.clock.Now(), Settings: settings}
+
+	old, ok, err := m.Load(ctx, ch.ID)
+	if err != nil {
+		return nil, errors.Errorf("load: %w", err)
+	}
+	if !ok {
+		return nil, m.save(ctx, ch, current)
+	}
+
+	changes := Diff(old.Settings, settings)
+	if len(changes) == 0 {
+		return nil, nil
+	}
+	if err := m.onDrift(ctx, ch, old, changes); err != nil {
+		return changes, errors.Errorf("handle drift: %w", err)
+	}
+	return changes, m.save(ctx, ch, current)
+}
+
+// Run checks given channels every interval until context is done.
+//
+// Check errors are logged and do not stop Run.
+func (m *Monitor) Run(ctx context.Context, channels ...tg.InputChannelClass) error {
+	ticker := m.clock.Ticker(m.interval)
+	defer ticker.Stop()
+
+	for {
+		for _, channel := range channels {
+			if _, err := m.Check(ctx, channel); err != nil {
+				if ctx.Err() != nil {
+					return ctx.Err()
+				}
+				m.log.Warn("Check channel settings", zap.Error(err))
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C():
+		}
+	}
+}
@@ -0,0 +1,162 @@
+package chatdrift
+
+import (
+	"context"
+	"sort"
+
+	"github.com/go-faster/errors"
+
+	"github.com/gotd/td/tg"
+)
+
+// Admin is a channel admin.
+type Admin struct {
+	UserID  int64    `json:"user_id"`
+	Creator bool     `json:"creator,omitempty"`
+	Rank    string   `json:"rank,omitempty"`
+	Rights  []string `json:"rights,omitempty"`
+}
+
+// Settings is a snapshot of channel settings.
+type Settings struct {
+	Title string `json:"title"`
+	// BannedRights are names of default banned rights, e.g. "send_media".
+	BannedRights []string `json:"banned_rights,omitempty"`
+	// Slowmode is a slow mode delay in seconds, zero if disabled.
+	Slowmode     int   `json:"slowmode,omitempty"`
+	LinkedChatID int64 `json:"linked_chat_id,omitempty"`
+	// Admins are channel admins sorted by user ID.
+	Admins []Admin `json:"admins,omitempty"`
+}
+
+// AdminRights returns names of set admin rights.
+func AdminRights(r tg.ChatAdminRights) []string {
+	return names([]right{
+		{"change_info", r.ChangeInfo},
+		{"post_messages", r.PostMessages},
+		{"edit_messages", r.EditMessages},
+		{"delete_messages", r.DeleteMessages},
+		{"ban_users", r.BanUsers},
+		{"invite_users", r.InviteUsers},
+		{"pin_messages", r.PinMessages},
+		{"add_admins", r.AddAdmins},
+		{"anonymous", r.Anonymous},
+		{"manage_call", r.ManageCall},
+		{"other", r.Other},
+		{"manage_topics", r.ManageTopics},
+		{"post_stories", r.PostStories},
+		{"edit_stories", r.EditStories},
+		{"delete_stories", r.DeleteStories},
+	})
+}
+
+// BannedRights returns names of set banned rights.
+func BannedRights(r tg.ChatBannedRights) []string {
+	return names([]right{
+		{"view_messages", r.ViewMessages},
+		{"send_messages", r.SendMessages},
+		{"send_media", r.SendMedia},
+		{"send_stickers", r.SendStickers},
+		{"send_gifs", r.SendGifs},
+		{"send_games", r.SendGames},
+		{"send_inline", r.SendInline},
+		{"embed_links", r.EmbedLinks},
+		{"send_polls", r.SendPolls},
+		{"change_info", r.ChangeInfo},
+		{"invite_users", r.InviteUsers},
+		{"pin_messages", r.PinMessages},
+		{"manage_topics", r.ManageTopics},
+		{"send_photos", r.SendPhotos},
+		{"send_videos", r.SendVideos},
+		{"send_roundvideos", r.SendRoundvideos},
+		{"send_audios", r.SendAudios},
+		{"send_voices", r.SendVoices},
+		{"send_docs", r.SendDocs},
+		{"send_plain", r.SendPlain},
+	})
+}
+
+type right struct {
+	name string
+	set  bool
+}
+
+func names(rights []right) []string {
+	var r []string
+	for _, v := range rights {
+		if v.set {
+			r = append(r, v.name)
+		}
+	}
+	return r
+}
+
+// maxAdmins is a participants limit of admins request.
+//
+// Telegram limits count of channel admins to 50, so single page is enough.
+const maxAdmins = 200
+
+// Fetch fetches current settings of given channel.
+func Fetch(ctx context.Context, api *tg.Client, channel tg.InputChannelClass) (*tg.Channel, Settings, error) {
+	full, err := api.ChannelsGetFullChannel(ctx, channel)
+	if err != nil {
+		return nil, Settings{}, errors.Errorf("get full channel: %w", err)
+	}
+	channelFull, ok := full.FullChat.(*tg.ChannelFull)
+	if !ok {
+		return nil, Settings{}, errors.Errorf("unexpected full chat type %T", full.FullChat)
+	}
+
+	var ch *tg.Channel
+	for _, c := range full.Chats {
+		if v, ok := c.(*tg.Channel); ok && v.ID == channelFull.ID {
+			ch = v
+			break
+		}
+	}
+	if ch == nil {
+		return nil, Settings{}, errors.Errorf("channel %d not found in result", channelFull.ID)
+	}
+
+	s := Settings{
+		Title:        ch.Title,
+		LinkedChatID: channelFull.LinkedChatID,
+		Slowmode:     channelFull.SlowmodeSeconds,
+	}
+	if rights, ok := ch.GetDefaultBannedRights(); ok {
+		s.BannedRights = BannedRights(rights)
+	}
+
+	participants, err := api.ChannelsGetParticipants(ctx, &tg.ChannelsGetParticipantsRequest{
+		Channel: channel,
+		Filter:  &tg.ChannelParticipantsAdmins{},
+		Limit:   maxAdmins,
+	})
+	if err != nil {
+		return nil, Settings{}, errors.Errorf("get admins: %w", err)
+	}
+	if p, ok := participants.(*tg.ChannelsChannelParticipants); ok {
+		for _, participant := range p.Participants {
+			switch v := participant.(type) {
+			case *tg.ChannelParticipantCreator:
+				s.Admins = append(s.Admins, Admin{
+					UserID:  v.UserID,
+					Creator: true,
+					Rank:    v.Rank,
+					Rights:  AdminRights(v.AdminRights),
+				})
+			case *tg.ChannelParticipantAdmin:
+				s.Admins = append(s.Admins, Admin{
+					UserID: v.UserID,
+					Rank:   v.Rank,
+					Rights: AdminRights(v.AdminRights),
+				})
+			}
+		}
+	}
+	sort.Slice(s.Admins, func(i, j int) bool {
+		return s.Admins[i].UserID < s.Admins[j].UserID
+	})
+
+	return ch, s, nil
+}
@@ -0,0 +1,224 @@
+package cleanup
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-faster/errors"
+
+	"github.com/gotd/td/clock"
+	"github.com/gotd/td/tg"
+	"github.com/gotd/td/tgerr"
+)
+
+// MaxChunkSize is a maximum count of message IDs per delete request.
+const MaxChunkSize = 100
+
+// Progress describes deletion progress.
+type Progress struct {
+	// Done is a count of processed message IDs.
+	Done int
+	// Total is a total count of message IDs to process.
+	Total int
+	// PtsCount is a total count of events generated by deletion.
+	PtsCount int
+}
+
+// Deleter deletes messages in chunks.
+type Deleter struct {
+	api   *tg.Client
+	clock clock.Clock
+
+	chunkSize int
+	interval  time.Duration
+	revoke    bool
+	maxWait   time.Duration
+	progress  func(Progress)
+}
+
+// NewDeleter creates new Deleter.
+func NewDeleter(api *tg.Client) *Deleter {
+	return &Deleter{
+		api:       api,
+		clock:     clock.System,
+		chunkSize: MaxChunkSize,
+	}
+}
+
+// WithClock sets clock to use. Default is to use system clock.
+func (d *Deleter) WithClock(c clock.Clock) *Deleter {
+	d.clock = c
+	return d
+}
+
+// WithChunkSize sets count of message IDs per request.
+// Size is clamped to [1, MaxChunkSize], default is MaxChunkSize.
+func (d *Deleter) WithChunkSize(size int) *Deleter {
+	switch {
+	case size < 1:
+		size = 1
+	case size > MaxChunkSize:
+		size = MaxChunkSize
+	}
+	d.chunkSize = size
+	return d
+}
+
+// WithInterval sets delay between requests. Default is no delay.
+func (d *Deleter) WithInterval(interval time.Duration) *Deleter {
+	d.interval = interval
+	return d
+}
+
+// WithRevoke sets whether to delete messages for all participants.
+//
+// Applies only to private chats and basic groups, channel messages are
+// always deleted for everyone.
+func (d *Deleter) WithRevoke(revoke bool) *Deleter {
+	d.revoke = revoke
+	return d
+}
+
+// WithMaxWait limits flood wait time per request. Deleter will return an error if
+// flood wait time exceeds that limit. Default is to wait without time limit.
+func (d *Deleter) WithMaxWait(m time.Duration) *Deleter {
+	d.maxWait = m
+	return d
+}
+
+// WithProgress sets callback which is called after every processed chunk.
+func (d *Deleter) WithProgress(cb func(Progress)) *Deleter {
+	d.progress = cb
+	return d
+}
+
+// Delete deletes messages with given IDs from given peer.
+func (d *Deleter) Delete(ctx context.Context, peer tg.InputPeerClass, ids []int) error {
+	return d.run(ctx, peer, len(ids), func(offset, n int) []int {
+		return ids[offset : offset+n]
+	})
+}
+
+// DeleteRange deletes messages with IDs in range [from, to] from given peer.
+func (d *Deleter) DeleteRange(ctx context.Context, peer tg.InputPeerClass, from, to int) error {
+	if from > to {
+		return errors.Errorf("invalid range [%d, %d]", from, to)
+	}
+	buf := make([]int, 0, d.chunkSize)
+	return d.run(ctx, peer, to-from+1, func(offset, n int) []int {
+		buf = buf[:0]
+		for id := from + offset; id < from+offset+n; id++ {
+			buf = append(buf, id)
+		}
+		return buf
+	})
+}
+
+func (d *Deleter) run(ctx context.Context, peer tg.InputPeerClass, total int, chunk func(offset, n int) []int) error {
+	del, err := d.deleter(peer)
+	if err != nil {
+		return err
+	}
+
+	p := Progress{Total: total}
+	for p.Done < total {
+		if p.Done > 0 && d.interval > 0 {
+			if err := d.sleep(ctx, d.interval); err != nil {
+				return err
+			}
+		}
+
+		n := d.chunkSize
+		if rest := total - p.Done; n > rest {
+			n = rest
+		}
+		ids := chunk(p.Done, n)
+
+		affected, err := d.invoke(ctx, func() (*tg.MessagesAffectedMessages, error) {
+			return del(ctx, ids)
+		})
+		if err != nil {
+			return errors.Errorf("delete [%d, %d): %w", p.Done, p.Done+n, err)
+		}
+
+		p.Done += n
+		p.PtsCount += affected.PtsCount
+		if d.progress != nil {
+			d.progress(p)
+		}
+	}
+
+	return nil
+}
+
+type deleteFunc func(ctx context.Context, ids []int) (*tg.MessagesAffectedMessages, error)
+
+func (d *Deleter) deleter(peer tg.InputPeerClass) (deleteFunc, error) {
+	var channel tg.InputChannelClass
+	switch p := peer.(type) {
+	case *tg.InputPeerChannel:
+		channel = &tg.InputChannel{
+			ChannelID:  p.ChannelID,
+			AccessHash: p.AccessHash,
+		}
+	case *tg.InputPeerChannelFromMessage:
+		channel = &tg.InputChannelFromMessage{
+			Peer:      p.Peer,
+			MsgID:     p.MsgID,
+			ChannelID: p.ChannelID,
+		}
+	case *tg.InputPeerEmpty:
+		return nil, errors.New("empty peer")
+	default:
+		return func(ctx context.Context, ids []int) (*tg.MessagesAffectedMessages, error) {
+			return d.api.MessagesDeleteMessages(ctx, &tg.MessagesDeleteMessagesRequest{
+				Revoke: d.revoke,
+				ID:     ids,
+			})
+		}, nil
+	}
+
+	return func(ctx context.Context, ids []int) (*tg.MessagesAffectedMessages, error) {
+		return d.api.ChannelsDeleteMessages(ctx, &tg.ChannelsDeleteMessagesRequest{
+			Channel: channel,
+			ID:      ids,
+		})
+	}, nil
+}
+
+// invoke calls f, retrying on flood wait errors.
+func (d *Deleter) invoke(ctx context.Context, f func() (*tg.MessagesAffectedMessages, error)) (*tg.MessagesAffectedMessages, error) {
+	for {
+		r, err := f()
+		if err == nil {
+			return r, nil
+		}
+
+		wait, ok := tgerr.AsFloodWait(err)
+		if !ok {
+			return nil, err
+		}
+		if wait == 0 {
+			wait = time.Second
+		}
+		if v := d.maxWait; v != 0 && wait > v {
+			return nil, errors.Errorf("flood wait argument is too big (%v > %v): %w", wait, v, err)
+		}
+
+		if err := d.sleep(ctx, wait); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (d *Deleter) sleep(ctx context.Context, dur time.Duration) error {
+	t := d.clock.Timer(dur)
+	defer clock.StopTimer(t)
+
+	select {
+	case <-t.C():
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,87 @@
+package cleanup
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/gotd/neo"
+	"github.com/gotd/td/tg"
+	"github.com/gotd/td/tgmock"
+)
+
+func TestDeleter_DeleteRange(t *testing.T) {
+	a := require.New(t)
+	mock := tgmock.New(t)
+	channel := &tg.InputPeerChannel{ChannelID: 10, AccessHash: 10}
+
+	ids := func(from, to int) (r []int) {
+		for id := from; id <= to; id++ {
+			r = append(r, id)
+		}
+		return r
+	}
+	for _, chunk := range [][2]int{{1, 100}, {101, 200}, {201, 250}} {
+		mock.ExpectCall(&tg.ChannelsDeleteMessagesRequest{
+			Channel: &tg.InputChannel{ChannelID: 10, AccessHash: 10},
+			ID:      ids(chunk[0], chunk[1]),
+		}).ThenResult(&tg.MessagesAffectedMessages{PtsCount: chunk[1] - chunk[0] + 1})
+	}
+
+	var progress []Progress
+	a.NoError(NewDeleter(tg.NewClient(mock)).
+		WithProgress(func(p Progress) {
+			progress = append(progress, p)
+		}).
+		DeleteRange(context.Background(), channel, 1, 250),
+	)
+	a.True(mock.AllWereMet())
+	a.Equal([]Progress{
+		{Done: 100, Total: 250, PtsCount: 100},
+		{Done: 200, Total: 250, PtsCount: 200},
+		{Done: 250, Total: 250, PtsCount: 250},
+	}, progress)
+
+	a.Error(NewDeleter(tg.NewClient(mock)).DeleteRange(context.Background(), channel, 2, 1))
+}
+
+func TestDeleter_Delete(t *testing.T) {
+	a := require.New(t)
+	mock := tgmock.New(t)
+	clock := neo.NewTime(time.Now())
+
+	req := &tg.MessagesDeleteMessagesRequest{Revoke: true, ID: []int{1, 2}}
+	mock.ExpectCall(req).ThenFlood(5)
+	mock.ExpectCall(req).ThenResult(&tg.MessagesAffectedMessages{PtsCount: 2})
+	mock.ExpectCall(&tg.MessagesDeleteMessagesRequest{Revoke: true, ID: []int{3}}).
+		ThenResult(&tg.MessagesAffectedMessages{PtsCount: 1})
+
+	observe := clock.Observe()
+	done := make(chan error, 1)
+	go func() {
+		done <- NewDeleter(tg.NewClient(mock)).
+			WithClock(clock).
+			WithChunkSize(2).
+			WithRevoke(true).
+			Delete(context.Background(), &tg.InputPeerUser{UserID: 1}, []int{1, 2, 3})
+	}()
+
+	<-observe
+	clock.Travel(5 * time.Second)
+	a.NoError(<-done)
+	a.True(mock.AllWereMet())
+}
+
+func TestDeleter_MaxWait(t *testing.T) {
+	a := require.New(t)
+	mock := tgmock.New(t)
+
+	mock.ExpectCall(&tg.MessagesDeleteMessagesRequest{ID: []int{1}}).ThenFlood(60)
+
+	a.Error(NewDeleter(tg.NewClient(mock)).
+		WithMaxWait(time.Second).
+		Delete(context.Background(), &tg.InputPeerSelf{}, []int{1}),
+	)
+}
@@ -0,0 +1,2 @@
+// Package cleanup contains helpers for bulk removal of messages.
+package cleanup
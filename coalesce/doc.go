@@ -0,0 +1,3 @@
+// Package coalesce contains session and peer storage decorators which
+// coalesce rapid writes.
+package coalesce
@@ -0,0 +1,362 @@
+package coalesce
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/go-faster/errors"
+	"go.uber.org/zap"
+
+	"github.com/gotd/td/clock"
+
+	"github.com/gotd/contrib/storage"
+)
+
+var _ storage.PeerStorage = (*Peers)(nil)
+
+// Peers is a storage.PeerStorage decorator which buffers writes in memory
+// and writes them to underlying storage in background.
+//
+// Update handlers add every seen peer, which is costly for backends which
+// sync every write, like pebble or bbolt. Add and Assign only queue peer,
+// merging it with queued value of the same peer, queued peers are written
+// by Run. Find and Resolve return queued peers, Iterate flushes queue first.
+//
+// Queue size is bounded, Add and Assign block while queue is full.
+type Peers struct {
+	next storage.PeerStorage
+
+	clock        clock.Clock
+	log          *zap.Logger
+	interval     time.Duration
+	size         int
+	flushTimeout time.Duration
+
+	mux sync.Mutex
+	// pending are queued writes, writing are writes made by current flush.
+	pending peerQueue
+	writing peerQueue
+	// flushed is closed and replaced by every flush.
+	flushed chan struct{}
+	notify  chan struct{}
+	full    chan struct{}
+
+	// flushMux serializes flushes.
+	flushMux sync.Mutex
+}
+
+type pendingPeer struct {
+	value storage.Peer
+	// assigned are keys passed to Assign.
+	assigned []string
+}
+
+type peerQueue struct {
+	peers map[storage.PeerKey]*pendingPeer
+	// keys are associated keys of queued peers.
+	keys map[string]storage.PeerKey
+}
+
+func newPeerQueue() peerQueue {
+	return peerQueue{
+		peers: map[storage.PeerKey]*pendingPeer{},
+		keys:  map[string]storage.PeerKey{},
+	}
+}
+
+func (q peerQueue) put(value storage.Peer, assigned ...string) {
+	k := storage.KeyFromPeer(value)
+	e, ok := q.peers[k]
+	var old storage.Peer
+	if !ok {
+		e = &pendingPeer{value: value}
+		q.peers[k] = e
+	} else {
+		old = e.value
+		e.value = storage.Merge(e.value, value)
+	}
+
+Assign:
+	for _, key := range assigned {
+		for _, existing := range e.assigned {
+			if existing == key {
+				continue Assign
+			}
+		}
+		e.assigned = append(e.assigned, key)
+	}
+	keys := append(e.value.Keys(), e.assigned...)
+	if ok {
+		// Remove keys of previous value, like old username.
+		for _, key := range storage.StaleKeys(old, keys) {
+			delete(q.keys, key)
+		}
+	}
+	for _, key := range keys {
+		q.keys[key] = k
+	}
+}
+
+func (q peerQueue) resolve(key string) (storage.Peer, bool) {
+	k, ok := q.keys[key]
+	if !ok {
+		return storage.Peer{}, false
+	}
+	e, ok := q.peers[k]
+	if !ok {
+		return storage.Peer{}, false
+	}
+	return e.value, true
+}
+
+// NewPeers creates new Peers.
+func NewPeers(next storage.PeerStorage) *Peers {
+	return &Peers{
+		next:         next,
+		clock:        clock.System,
+		log:          zap.NewNop(),
+		interval:     time.Second,
+		size:         1024,
+		flushTimeout: 10 * time.Second,
+		pending:      newPeerQueue(),
+		writing:      newPeerQueue(),
+		flushed:      make(chan struct{}),
+		notify:       make(chan struct{}, 1),
+		full:         make(chan struct{}, 1),
+	}
+}
+
+// WithClock sets clock to use. Default is to use system clock.
+func (p *Peers) WithClock(c clock.Clock) *Peers {
+	p.clock = c
+	return p
+}
+
+// WithLog sets logger to log write errors.
+func (p *Peers) WithLog(log *zap.Logger) *Peers {
+	p.log = log
+	return p
+}
+
+// WithInterval sets maximum time peer is queued before write, if queue is
+// not full. Default is 1 second.
+func (p *Peers) WithInterval(interval time.Duration) *Peers {
+	p.interval = interval
+	return p
+}
+
+// WithSize sets maximum count of queued peers. Default is 1024.
+func (p *Peers) WithSize(size int) *Peers {
+	if size < 1 {
+		size = 1
+	}
+	p.size = size
+	return p
+}
+
+// WithFlushTimeout sets timeout of final flush made by Run.
+// Default is 10 seconds.
+func (p *Peers) WithFlushTimeout(timeout time.Duration) *Peers {
+	p.flushTimeout = timeout
+	return p
+}
+
+// Len returns count of queued peers.
+func (p *Peers) Len() int {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+
+	return len(p.pending.peers)
+}
+
+func signal(ch chan struct{}) {
+	select {
+	case ch <- struct{}{}:
+	default:
+	}
+}
+
+func (p *Peers) queue(ctx context.Context, value storage.Peer, assigned ...string) error {
+	k := storage.KeyFromPeer(value)
+	for {
+		p.mux.Lock()
+		if _, ok := p.pending.peers[k]; ok || len(p.pending.peers) < p.size {
+			p.pending.put(value, assigned...)
+			full := len(p.pending.peers) >= p.size
+			p.mux.Unlock()
+
+			signal(p.notify)
+			if full {
+				signal(p.full)
+			}
+			return nil
+		}
+		flushed := p.flushed
+		p.mux.Unlock()
+
+		signal(p.full)
+		select {
+		case <-flushed:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// Add implements storage.PeerStorage. It blocks only if queue is full,
+// peer is written by Run.
+func (p *Peers) Add(ctx context.Context, value storage.Peer) error {
+	return p.queue(ctx, value)
+}
+
+// Find implements storage.PeerStorage.
+func (p *Peers) Find(ctx context.Context, key storage.PeerKey) (storage.Peer, error) {
+	p.mux.Lock()
+	e, ok := p.pending.peers[key]
+	if !ok {
+		e, ok = p.writing.peers[key]
+	}
+	p.mux.Unlock()
+	if ok {
+		return e.value, nil
+	}
+	return p.next.Find(ctx, key)
+}
+
+// Assign implements storage.PeerStorage. It blocks only if queue is full,
+// peer is written by Run.
+func (p *Peers) Assign(ctx context.Context, key string, value storage.Peer) error {
+	return p.queue(ctx, value, key)
+}
+
+// Resolve implements storage.PeerStorage.
+func (p *Peers) Resolve(ctx context.Context, key string) (storage.Peer, error) {
+	p.mux.Lock()
+	v, ok := p.pending.resolve(key)
+	if !ok {
+		v, ok = p.writing.resolve(key)
+	}
+	p.mux.Unlock()
+	if ok {
+		return v, nil
+	}
+	return p.next.Resolve(ctx, key)
+}
+
+// Iterate implements storage.PeerStorage. It flushes queued peers first.
+func (p *Peers) Iterate(ctx context.Context) (storage.PeerIterator, error) {
+	if err := p.Flush(ctx); err != nil {
+		return nil, err
+	}
+	return p.next.Iterate(ctx)
+}
+
+func (p *Peers) write(ctx context.Context, e *pendingPeer) error {
+	if len(e.assigned) == 0 {
+		return p.next.Add(ctx, e.value)
+	}
+	for _, key := range e.assigned {
+		if err := p.next.Assign(ctx, key, e.value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Flush writes queued peers immediately.
+//
+// Peers which are failed to write are queued again.
+func (p *Peers) Flush(ctx context.Context) error {
+	p.flushMux.Lock()
+	defer p.flushMux.Unlock()
+
+	p.mux.Lock()
+	batch := p.pending
+	p.pending, p.writing = newPeerQueue(), batch
+	flushed := p.flushed
+	p.flushed = make(chan struct{})
+	p.mux.Unlock()
+
+	var (
+		failed  []*pendingPeer
+		lastErr error
+	)
+	for _, e := range batch.peers {
+		if err := p.write(ctx, e); err != nil {
+			failed = append(failed, e)
+			lastErr = err
+		}
+	}
+
+	p.mux.Lock()
+	p.writing = newPeerQueue()
+	for _, e := range failed {
+		// Keep newer value queued during flush.
+		var newer *storage.Peer
+		if n, ok := p.pending.peers[storage.KeyFromPeer(e.value)]; ok {
+			v := n.value
+			newer = &v
+		}
+		p.pending.put(e.value, e.assigned...)
+		if newer != nil {
+			p.pending.put(*newer)
+		}
+	}
+	p.mux.Unlock()
+	close(flushed)
+
+	if lastErr != nil {
+		return errors.Errorf("write %d of %d peers: %w", len(failed), len(batch.peers), lastErr)
+	}
+	return nil
+}
+
+// Run writes queued peers until given context is done, then flushes
+// queued peers.
+//
+// Write errors are logged and retried on next interval.
+func (p *Peers) Run(ctx context.Context) error {
+	defer func() {
+		flushCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), p.flushTimeout)
+		defer cancel()
+		if err := p.Flush(flushCtx); err != nil {
+			p.log.Error("Flush peers", zap.Error(err))
+		}
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-p.notify:
+		}
+
+		// Wait for more writes unless queue is full.
+		if err := p.wait(ctx); err != nil {
+			return err
+		}
+		if err := p.Flush(ctx); err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			p.log.Warn("Write peers", zap.Error(err))
+			// Retry after interval.
+			signal(p.notify)
+		}
+	}
+}
+
+func (p *Peers) wait(ctx context.Context) error {
+	t := p.clock.Timer(p.interval)
+	defer clock.StopTimer(t)
+
+	select {
+	case <-t.C():
+		return nil
+	case <-p.full:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
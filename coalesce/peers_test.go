@@ -0,0 +1,112 @@
+package coalesce
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-faster/errors"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gotd/neo"
+	"github.com/gotd/td/tg"
+
+	"github.com/gotd/contrib/storage"
+	"github.com/gotd/contrib/storage/storagetest"
+)
+
+func testUser(id int64, username string) storage.Peer {
+	var p storage.Peer
+	p.FromUser(&tg.User{ID: id, AccessHash: id, Username: username})
+	return p
+}
+
+func TestPeers(t *testing.T) {
+	a := require.New(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	clock := neo.NewTime(time.Now())
+	next := storagetest.NewFaulty(nil)
+	s := NewPeers(next).WithClock(clock).WithInterval(time.Second)
+
+	p := testUser(10, "user")
+	a.NoError(s.Add(ctx, testUser(10, "old")))
+	a.NoError(s.Add(ctx, p))
+	a.NoError(s.Assign(ctx, "key", p))
+	a.Equal(1, s.Len(), "writes must be coalesced")
+	a.Empty(next.Calls())
+
+	// Queued peer is observed.
+	v, err := s.Find(ctx, storage.KeyFromPeer(p))
+	a.NoError(err)
+	a.Equal("user", v.User.Username)
+	_, err = s.Resolve(ctx, "user")
+	a.NoError(err)
+	_, err = s.Resolve(ctx, "key")
+	a.NoError(err)
+	_, err = s.Resolve(ctx, "old")
+	a.ErrorIs(err, storage.ErrPeerNotFound)
+	next.Reset()
+
+	done := make(chan error, 1)
+	observe := clock.Observe()
+	go func() {
+		done <- s.Run(ctx)
+	}()
+	<-observe
+	clock.Travel(time.Second)
+	a.Eventually(func() bool {
+		return s.Len() == 0 && len(next.Calls()) == 1
+	}, time.Second, time.Millisecond)
+	a.Equal(storagetest.MethodAssign, next.Calls()[0].Method)
+
+	// Failed writes are queued again.
+	testErr := errors.New("unavailable")
+	next.FailNext(storagetest.MethodAdd, testErr)
+	other := testUser(11, "")
+	a.NoError(s.Add(ctx, other))
+	a.ErrorIs(s.Flush(ctx), testErr)
+	a.Equal(1, s.Len())
+
+	// Queued peers are flushed on shutdown.
+	cancel()
+	a.ErrorIs(<-done, context.Canceled)
+	a.Equal(0, s.Len())
+	_, err = next.Find(context.Background(), storage.KeyFromPeer(other))
+	a.NoError(err)
+}
+
+func TestPeersFull(t *testing.T) {
+	a := require.New(t)
+	ctx := context.Background()
+
+	next := storagetest.NewFaulty(nil)
+	s := NewPeers(next).WithSize(1)
+	a.NoError(s.Add(ctx, testUser(10, "")))
+	// Queued peer may be updated.
+	a.NoError(s.Add(ctx, testUser(10, "user")))
+
+	timeoutCtx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	a.ErrorIs(s.Add(timeoutCtx, testUser(11, "")), context.DeadlineExceeded)
+
+	added := make(chan error, 1)
+	go func() {
+		added <- s.Add(ctx, testUser(11, ""))
+	}()
+	a.NoError(s.Flush(ctx))
+	a.NoError(<-added)
+	a.Equal(1, s.Len())
+
+	// Iterate flushes queue.
+	iter, err := s.Iterate(ctx)
+	a.NoError(err)
+	var n int
+	for iter.Next(ctx) {
+		n++
+	}
+	a.NoError(iter.Err())
+	a.NoError(iter.Close())
+	a.Equal(2, n)
+}
@@ -0,0 +1,184 @@
+package coalesce
+
+import (
+	"bytes"
+	"context"
+	"sync"
+	"time"
+
+	"github.com/go-faster/errors"
+	"go.uber.org/zap"
+
+	"github.com/gotd/td/clock"
+	"github.com/gotd/td/session"
+)
+
+var _ session.Storage = (*Session)(nil)
+
+// Session is a session.Storage decorator which coalesces rapid session
+// writes into at most one write per interval.
+//
+// Client stores session on every salt or state change, which is costly for
+// slow backends like Vault or S3. StoreSession only records pending data,
+// it is written by Run. LoadSession returns pending data, if any.
+type Session struct {
+	next session.Storage
+
+	clock        clock.Clock
+	log          *zap.Logger
+	interval     time.Duration
+	flushTimeout time.Duration
+
+	mux     sync.Mutex
+	pending []byte
+	written []byte
+	notify  chan struct{}
+}
+
+// NewSession creates new Session.
+func NewSession(next session.Storage) *Session {
+	return &Session{
+		next:         next,
+		clock:        clock.System,
+		log:          zap.NewNop(),
+		interval:     10 * time.Second,
+		flushTimeout: 10 * time.Second,
+		notify:       make(chan struct{}, 1),
+	}
+}
+
+// WithClock sets clock to use. Default is to use system clock.
+func (s *Session) WithClock(c clock.Clock) *Session {
+	s.clock = c
+	return s
+}
+
+// WithLog sets logger to log write errors.
+func (s *Session) WithLog(log *zap.Logger) *Session {
+	s.log = log
+	return s
+}
+
+// WithInterval sets minimum interval between writes. Default is 10 seconds.
+func (s *Session) WithInterval(interval time.Duration) *Session {
+	s.interval = interval
+	return s
+}
+
+// WithFlushTimeout sets timeout of final flush made by Run.
+// Default is 10 seconds.
+func (s *Session) WithFlushTimeout(timeout time.Duration) *Session {
+	s.flushTimeout = timeout
+	return s
+}
+
+// LoadSession implements session.Storage.
+func (s *Session) LoadSession(ctx context.Context) ([]byte, error) {
+	s.mux.Lock()
+	pending := s.pending
+	s.mux.Unlock()
+	if pending != nil {
+		return append([]byte(nil), pending...), nil
+	}
+	return s.next.LoadSession(ctx)
+}
+
+// StoreSession implements session.Storage. It does not block, data is
+// written by Run.
+func (s *Session) StoreSession(ctx context.Context, data []byte) error {
+	s.mux.Lock()
+	s.pending = append([]byte(nil), data...)
+	s.mux.Unlock()
+
+	select {
+	case s.notify <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// take returns pending data if it differs from written one.
+func (s *Session) take() ([]byte, bool) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	return s.pending, s.pending != nil && !bytes.Equal(s.pending, s.written)
+}
+
+func (s *Session) write(ctx context.Context, data []byte) error {
+	if err := s.next.StoreSession(ctx, data); err != nil {
+		return errors.Errorf("store: %w", err)
+	}
+
+	s.mux.Lock()
+	s.written = data
+	s.mux.Unlock()
+	return nil
+}
+
+// Flush writes pending data immediately.
+func (s *Session) Flush(ctx context.Context) error {
+	data, ok := s.take()
+	if !ok {
+		return nil
+	}
+	return s.write(ctx, data)
+}
+
+// Run writes pending data until given context is done, then flushes
+// pending data.
+//
+// Write errors are logged and retried on next interval.
+func (s *Session) Run(ctx context.Context) error {
+	defer func() {
+		flushCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), s.flushTimeout)
+		defer cancel()
+		if err := s.Flush(flushCtx); err != nil {
+			s.log.Error("Flush session", zap.Error(err))
+		}
+	}()
+
+	var next time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-s.notify:
+		}
+
+		if wait := next.Sub(s.clock.Now()); wait > 0 {
+			if err := s.sleep(ctx, wait); err != nil {
+				return err
+			}
+		}
+
+		data, ok := s.take()
+		if !ok {
+			continue
+		}
+		next = s.clock.Now().Add(s.interval)
+		if err := s.write(ctx, data); err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			s.log.Warn("Store session", zap.Error(err))
+			// Retry after interval.
+			select {
+			case s.notify <- struct{}{}:
+			default:
+			}
+		}
+	}
+}
+
+func (s *Session) sleep(ctx context.Context, d time.Duration) error {
+	t := s.clock.Timer(d)
+	defer clock.StopTimer(t)
+
+	select {
+	case <-t.C():
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
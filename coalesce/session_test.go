@@ -0,0 +1,87 @@
+package coalesce
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-faster/errors"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gotd/neo"
+	"github.com/gotd/td/session"
+)
+
+type testStorage struct {
+	writes chan string
+	data   []byte
+	errs   []error
+}
+
+func (t *testStorage) LoadSession(ctx context.Context) ([]byte, error) {
+	if t.data == nil {
+		return nil, session.ErrNotFound
+	}
+	return t.data, nil
+}
+
+func (t *testStorage) StoreSession(ctx context.Context, data []byte) error {
+	t.writes <- string(data)
+	if len(t.errs) > 0 {
+		err := t.errs[0]
+		t.errs = t.errs[1:]
+		return err
+	}
+	t.data = data
+	return nil
+}
+
+func TestSession(t *testing.T) {
+	a := require.New(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	clock := neo.NewTime(time.Now())
+	next := &testStorage{
+		writes: make(chan string, 10),
+		errs:   []error{nil, errors.New("unavailable")},
+	}
+	s := NewSession(next).WithClock(clock).WithInterval(time.Second)
+
+	_, err := s.LoadSession(ctx)
+	a.ErrorIs(err, session.ErrNotFound)
+
+	a.NoError(s.StoreSession(ctx, []byte("1")))
+	a.NoError(s.StoreSession(ctx, []byte("2")))
+	data, err := s.LoadSession(ctx)
+	a.NoError(err)
+	a.Equal("2", string(data), "pending data should be loaded")
+
+	done := make(chan error, 1)
+	go func() {
+		done <- s.Run(ctx)
+	}()
+	a.Equal("2", <-next.writes, "writes must be coalesced")
+
+	observe := clock.Observe()
+	a.NoError(s.StoreSession(ctx, []byte("3")))
+	<-observe
+	a.NoError(s.StoreSession(ctx, []byte("4")))
+	observe = clock.Observe()
+	clock.Travel(time.Second)
+	a.Equal("4", <-next.writes)
+
+	// Failed write is retried after interval.
+	<-observe
+	clock.Travel(time.Second)
+	a.Equal("4", <-next.writes)
+
+	// Pending data is flushed on shutdown.
+	observe = clock.Observe()
+	a.NoError(s.StoreSession(ctx, []byte("5")))
+	<-observe
+	cancel()
+	a.ErrorIs(<-done, context.Canceled)
+	a.Equal("5", <-next.writes)
+	a.Equal("5", string(next.data))
+}
@@ -0,0 +1,3 @@
+// Package dcpool implements pool of invokers to non-primary DCs, e.g. to
+// download files from media DCs.
+package dcpool
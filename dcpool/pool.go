@@ -0,0 +1,168 @@
+package dcpool
+
+import (
+	"context"
+	"strconv"
+	"sync"
+
+	"github.com/go-faster/errors"
+	"go.uber.org/multierr"
+	"golang.org/x/sync/singleflight"
+
+	"github.com/gotd/td/bin"
+	"github.com/gotd/td/telegram"
+	"github.com/gotd/td/tg"
+)
+
+// Dialer creates invoker to given DC with at most max connections, e.g.
+// (*telegram.Client).DC or (*telegram.Client).MediaOnly.
+//
+// Dialer is expected to transfer authorization to created invoker.
+type Dialer func(ctx context.Context, dc int, max int64) (telegram.CloseInvoker, error)
+
+// ErrClosed is returned by Pool after Close.
+var ErrClosed = errors.New("pool closed")
+
+// Pool maintains invoker per DC, created by Dialer on first use and reused
+// afterwards, so authorization is exported to every DC once.
+type Pool struct {
+	dial Dialer
+	max  int64
+
+	mux      sync.Mutex
+	invokers map[int]telegram.CloseInvoker
+	closed   bool
+	group    singleflight.Group
+}
+
+// New creates new Pool.
+//
+// Example:
+//
+//	pool := dcpool.New(client.MediaOnly)
+//	defer func() { _ = pool.Close() }()
+func New(dial Dialer) *Pool {
+	return &Pool{
+		dial:     dial,
+		max:      1,
+		invokers: map[int]telegram.CloseInvoker{},
+	}
+}
+
+// WithMax sets max count of connections to every DC. Default is 1.
+func (p *Pool) WithMax(max int64) *Pool {
+	p.max = max
+	return p
+}
+
+func (p *Pool) get(dc int) (telegram.CloseInvoker, bool, error) {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+
+	if p.closed {
+		return nil, false, ErrClosed
+	}
+	inv, ok := p.invokers[dc]
+	return inv, ok, nil
+}
+
+// DC returns invoker to given DC.
+//
+// Concurrent calls share single dial, which uses context of one of them.
+// If that caller is canceled, other callers dial again.
+func (p *Pool) DC(ctx context.Context, dc int) (tg.Invoker, error) {
+	for {
+		if inv, ok, err := p.get(dc); err != nil || ok {
+			return inv, err
+		}
+
+		ch := p.group.DoChan(strconv.Itoa(dc), func() (any, error) {
+			if inv, ok, err := p.get(dc); err != nil || ok {
+				return inv, err
+			}
+
+			inv, err := p.dial(ctx, dc, p.max)
+			if err != nil {
+				return nil, errors.Wrapf(err, "dial DC %d", dc)
+			}
+
+			p.mux.Lock()
+			defer p.mux.Unlock()
+			if p.closed {
+				return nil, multierr.Append(ErrClosed, inv.Close())
+			}
+			p.invokers[dc] = inv
+			return inv, nil
+		})
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case res := <-ch:
+			if res.Err != nil {
+				if canceled(res.Err) && ctx.Err() == nil {
+					// Dial was canceled by context of another caller.
+					continue
+				}
+				return nil, res.Err
+			}
+			return res.Val.(tg.Invoker), nil
+		}
+	}
+}
+
+func canceled(err error) bool {
+	return errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)
+}
+
+// Close closes all invokers.
+func (p *Pool) Close() error {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+
+	p.closed = true
+	var err error
+	for dc, inv := range p.invokers {
+		if closeErr := inv.Close(); closeErr != nil {
+			err = multierr.Append(err, errors.Wrapf(closeErr, "close DC %d", dc))
+		}
+		delete(p.invokers, dc)
+	}
+	return err
+}
+
+type dcKey struct{}
+
+// WithDC returns new context, so requests made with it are routed by
+// Pool.Handle to given DC. Zero DC means primary one.
+func WithDC(ctx context.Context, dc int) context.Context {
+	return context.WithValue(ctx, dcKey{}, dc)
+}
+
+// DCFromContext returns DC set by WithDC, if any.
+func DCFromContext(ctx context.Context) (int, bool) {
+	dc, ok := ctx.Value(dcKey{}).(int)
+	return dc, ok && dc != 0
+}
+
+// Handle implements telegram.Middleware. Requests with DC set by WithDC
+// are routed to invoker of DC, other requests are passed to next.
+//
+// Example:
+//
+//	raw := tg.NewClient(pool.Handle(client))
+//	ctx = dcpool.WithDC(ctx, doc.DCID)
+//	_, err := downloader.NewDownloader().Download(raw, loc).Stream(ctx, w)
+func (p *Pool) Handle(next tg.Invoker) telegram.InvokeFunc {
+	return func(ctx context.Context, input bin.Encoder, output bin.Decoder) error {
+		dc, ok := DCFromContext(ctx)
+		if !ok {
+			return next.Invoke(ctx, input, output)
+		}
+		inv, err := p.DC(ctx, dc)
+		if err != nil {
+			return err
+		}
+		return inv.Invoke(ctx, input, output)
+	}
+}
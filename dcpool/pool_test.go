@@ -0,0 +1,104 @@
+package dcpool
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+
+	"github.com/go-faster/errors"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gotd/td/bin"
+	"github.com/gotd/td/telegram"
+	"github.com/gotd/td/tg"
+)
+
+type dcInvoker struct {
+	dc     int
+	closed bool
+}
+
+func (i *dcInvoker) Invoke(ctx context.Context, input bin.Encoder, output bin.Decoder) error {
+	*output.(*tg.Config) = tg.Config{ThisDC: i.dc}
+	return nil
+}
+
+func (i *dcInvoker) Close() error {
+	i.closed = true
+	return nil
+}
+
+func TestPool(t *testing.T) {
+	a := require.New(t)
+	ctx := context.Background()
+
+	var dialed []*dcInvoker
+	pool := New(func(ctx context.Context, dc int, max int64) (telegram.CloseInvoker, error) {
+		if dc == 5 {
+			return nil, errors.New("unavailable")
+		}
+		a.Equal(int64(4), max)
+		inv := &dcInvoker{dc: dc}
+		dialed = append(dialed, inv)
+		return inv, nil
+	}).WithMax(4)
+
+	raw := tg.NewClient(pool.Handle(&dcInvoker{dc: 2}))
+	dc := func(ctx context.Context) int {
+		cfg, err := raw.HelpGetConfig(ctx)
+		a.NoError(err)
+		return cfg.ThisDC
+	}
+
+	a.Equal(2, dc(ctx))
+	a.Empty(dialed)
+	a.Equal(4, dc(WithDC(ctx, 4)))
+	a.Equal(4, dc(WithDC(ctx, 4)))
+	a.Equal(1, dc(WithDC(ctx, 1)))
+	a.Equal(2, dc(WithDC(ctx, 0)))
+	a.Len(dialed, 2)
+
+	_, err := raw.HelpGetConfig(WithDC(ctx, 5))
+	a.ErrorContains(err, "unavailable")
+
+	a.NoError(pool.Close())
+	for _, inv := range dialed {
+		a.True(inv.closed)
+	}
+	_, err = pool.DC(ctx, 4)
+	a.ErrorIs(err, ErrClosed)
+}
+
+func TestPoolCanceledDial(t *testing.T) {
+	a := require.New(t)
+
+	started := make(chan struct{})
+	var calls atomic.Int32
+	pool := New(func(ctx context.Context, dc int, max int64) (telegram.CloseInvoker, error) {
+		if calls.Add(1) == 1 {
+			close(started)
+			<-ctx.Done()
+			return nil, ctx.Err()
+		}
+		return &dcInvoker{dc: dc}, nil
+	})
+
+	leaderCtx, cancel := context.WithCancel(context.Background())
+	leaderErr := make(chan error, 1)
+	go func() {
+		_, err := pool.DC(leaderCtx, 4)
+		leaderErr <- err
+	}()
+	<-started
+
+	followerErr := make(chan error, 1)
+	go func() {
+		_, err := pool.DC(context.Background(), 4)
+		followerErr <- err
+	}()
+	cancel()
+
+	a.ErrorIs(<-leaderErr, context.Canceled)
+	a.NoError(<-followerErr)
+	a.Equal(int32(2), calls.Load())
+}
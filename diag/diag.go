@@ -0,0 +1,229 @@
+package diag
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"runtime"
+	"runtime/debug"
+	"runtime/pprof"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-faster/errors"
+
+	"github.com/gotd/td/clock"
+	"github.com/gotd/td/tg"
+
+	"github.com/gotd/contrib/middleware/floodwait"
+	"github.com/gotd/contrib/storage"
+)
+
+// Client is a client to inspect, e.g. *telegram.Client.
+type Client interface {
+	Config() tg.Config
+	Ping(ctx context.Context) error
+}
+
+// DC is a DC option known by client.
+type DC struct {
+	ID    int    `json:"id"`
+	Addr  string `json:"addr"`
+	IPv6  bool   `json:"ipv6,omitempty"`
+	Media bool   `json:"media,omitempty"`
+	CDN   bool   `json:"cdn,omitempty"`
+}
+
+// Connection is a connection state of client.
+type Connection struct {
+	// DC is an ID of current DC.
+	DC int `json:"dc"`
+	// Ping is a ping latency, zero if ping failed.
+	Ping time.Duration `json:"ping,omitempty"`
+	DCs  []DC          `json:"dcs"`
+}
+
+// Runtime is an information about process.
+type Runtime struct {
+	GoVersion  string `json:"go_version"`
+	OS         string `json:"os"`
+	Arch       string `json:"arch"`
+	CPU        int    `json:"cpu"`
+	Goroutines int    `json:"goroutines"`
+	// Modules is versions of gotd modules.
+	Modules map[string]string `json:"modules,omitempty"`
+}
+
+// Bundle is a diagnostics snapshot.
+type Bundle struct {
+	Time       time.Time           `json:"time"`
+	Runtime    Runtime             `json:"runtime"`
+	Connection *Connection         `json:"connection,omitempty"`
+	Pending    []Call              `json:"pending,omitempty"`
+	FloodWait  *floodwait.Snapshot `json:"flood_wait,omitempty"`
+	Storage    *storage.Stats      `json:"storage,omitempty"`
+	// Errors is errors of parts which could not be captured.
+	Errors map[string]string `json:"errors,omitempty"`
+	// Goroutines is a dump of goroutine stacks.
+	Goroutines string `json:"goroutines,omitempty"`
+}
+
+// Collector captures Bundle. Only parts which are set are captured.
+type Collector struct {
+	clock      clock.Clock
+	client     Client
+	pending    *Pending
+	waiter     *floodwait.Waiter
+	storage    storage.PeerStorage
+	goroutines bool
+}
+
+// New creates new Collector.
+func New() *Collector {
+	return &Collector{
+		clock:      clock.System,
+		goroutines: true,
+	}
+}
+
+// WithClock sets clock to use. Default is to use system clock.
+func (c *Collector) WithClock(clk clock.Clock) *Collector {
+	c.clock = clk
+	return c
+}
+
+// WithClient sets client to capture connection state of.
+func (c *Collector) WithClient(client Client) *Collector {
+	c.client = client
+	return c
+}
+
+// WithPending sets middleware to capture pending calls of.
+func (c *Collector) WithPending(p *Pending) *Collector {
+	c.pending = p
+	return c
+}
+
+// WithWaiter sets flood wait middleware to capture state of.
+func (c *Collector) WithWaiter(w *floodwait.Waiter) *Collector {
+	c.waiter = w
+	return c
+}
+
+// WithStorage sets peer storage to capture statistics of, see
+// storage.PeerStats.
+func (c *Collector) WithStorage(s storage.PeerStorage) *Collector {
+	c.storage = s
+	return c
+}
+
+// WithGoroutines sets whether to capture goroutine dump. Default is true.
+func (c *Collector) WithGoroutines(enabled bool) *Collector {
+	c.goroutines = enabled
+	return c
+}
+
+func runtimeInfo() Runtime {
+	r := Runtime{
+		GoVersion:  runtime.Version(),
+		OS:         runtime.GOOS,
+		Arch:       runtime.GOARCH,
+		CPU:        runtime.NumCPU(),
+		Goroutines: runtime.NumGoroutine(),
+	}
+	if info, ok := debug.ReadBuildInfo(); ok {
+		r.Modules = map[string]string{}
+		if strings.HasPrefix(info.Main.Path, "github.com/gotd/") {
+			r.Modules[info.Main.Path] = info.Main.Version
+		}
+		for _, m := range info.Deps {
+			if strings.HasPrefix(m.Path, "github.com/gotd/") {
+				r.Modules[m.Path] = m.Version
+			}
+		}
+	}
+	return r
+}
+
+func (c *Collector) connection(ctx context.Context) (*Connection, error) {
+	cfg := c.client.Config()
+	conn := &Connection{
+		DC:  cfg.ThisDC,
+		DCs: make([]DC, 0, len(cfg.DCOptions)),
+	}
+	for _, o := range cfg.DCOptions {
+		conn.DCs = append(conn.DCs, DC{
+			ID:    o.ID,
+			Addr:  o.IPAddress + ":" + strconv.Itoa(o.Port),
+			IPv6:  o.Ipv6,
+			Media: o.MediaOnly,
+			CDN:   o.CDN,
+		})
+	}
+
+	start := c.clock.Now()
+	if err := c.client.Ping(ctx); err != nil {
+		return conn, errors.Errorf("ping: %w", err)
+	}
+	conn.Ping = c.clock.Now().Sub(start)
+	return conn, nil
+}
+
+// Collect captures Bundle. Errors of parts are reported in Bundle.Errors,
+// so partial snapshot is returned anyway.
+func (c *Collector) Collect(ctx context.Context) Bundle {
+	b := Bundle{
+		Time:    c.clock.Now(),
+		Runtime: runtimeInfo(),
+	}
+	fail := func(part string, err error) {
+		if b.Errors == nil {
+			b.Errors = map[string]string{}
+		}
+		b.Errors[part] = err.Error()
+	}
+
+	if c.client != nil {
+		conn, err := c.connection(ctx)
+		if err != nil {
+			fail("connection", err)
+		}
+		b.Connection = conn
+	}
+	if c.pending != nil {
+		b.Pending = c.pending.Calls()
+	}
+	if c.waiter != nil {
+		s := c.waiter.Snapshot()
+		b.FloodWait = &s
+	}
+	if c.storage != nil {
+		stats, err := storage.PeerStats(ctx, c.storage)
+		if err != nil {
+			fail("storage", err)
+		} else {
+			b.Storage = &stats
+		}
+	}
+	if c.goroutines {
+		var buf bytes.Buffer
+		if err := pprof.Lookup("goroutine").WriteTo(&buf, 2); err != nil {
+			fail("goroutines", err)
+		}
+		b.Goroutines = buf.String()
+	}
+
+	return b
+}
+
+// Write captures Bundle and writes it to w as indented JSON.
+func (c *Collector) Write(ctx context.Context, w io.Writer) error {
+	e := json.NewEncoder(w)
+	e.SetIndent("", "  ")
+	if err := e.Encode(c.Collect(ctx)); err != nil {
+		return errors.Errorf("encode: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+package diag
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+
+	pebbledb "github.com/cockroachdb/pebble"
+	"github.com/cockroachdb/pebble/vfs"
+	"github.com/go-faster/errors"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gotd/td/tg"
+
+	"github.com/gotd/contrib/middleware/floodwait"
+	"github.com/gotd/contrib/pebble"
+	"github.com/gotd/contrib/storage"
+)
+
+type testClient struct {
+	cfg  tg.Config
+	ping error
+}
+
+func (c testClient) Config() tg.Config {
+	return c.cfg
+}
+
+func (c testClient) Ping(ctx context.Context) error {
+	return c.ping
+}
+
+func TestCollector(t *testing.T) {
+	a := require.New(t)
+	ctx := context.Background()
+
+	db, err := pebbledb.Open("pebble", &pebbledb.Options{FS: vfs.NewMem()})
+	a.NoError(err)
+	t.Cleanup(func() {
+		_ = db.Close()
+	})
+	peers := pebble.NewPeerStorage(db)
+	var p storage.Peer
+	a.True(p.FromUser(&tg.User{ID: 10, AccessHash: 10}))
+	a.NoError(peers.Add(ctx, p))
+
+	client := testClient{cfg: tg.Config{
+		ThisDC: 2,
+		DCOptions: []tg.DCOption{
+			{ID: 2, IPAddress: "149.154.167.50", Port: 443},
+		},
+	}}
+	c := New().
+		WithClient(client).
+		WithPending(NewPending()).
+		WithWaiter(floodwait.NewWaiter()).
+		WithStorage(peers)
+
+	b := c.Collect(ctx)
+	a.Empty(b.Errors)
+	a.Equal(2, b.Connection.DC)
+	a.Equal([]DC{{ID: 2, Addr: "149.154.167.50:443"}}, b.Connection.DCs)
+	a.NotNil(b.FloodWait)
+	a.Equal(1, b.Storage.Users)
+	a.Contains(b.Goroutines, "TestCollector")
+	a.NotEmpty(b.Runtime.GoVersion)
+
+	var buf bytes.Buffer
+	a.NoError(c.WithGoroutines(false).Write(ctx, &buf))
+	var decoded Bundle
+	a.NoError(json.Unmarshal(buf.Bytes(), &decoded))
+	a.Empty(decoded.Goroutines)
+	a.Equal(1, decoded.Storage.Users)
+}
+
+func TestCollectorErrors(t *testing.T) {
+	client := testClient{ping: errors.New("test")}
+	b := New().WithClient(client).Collect(context.Background())
+
+	require.Contains(t, b.Errors["connection"], "test")
+	require.NotNil(t, b.Connection)
+	require.Zero(t, b.Connection.Ping)
+}
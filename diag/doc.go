@@ -0,0 +1,3 @@
+// Package diag implements diagnostics snapshot of client state, intended
+// for attaching to bug reports.
+package diag
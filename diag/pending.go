@@ -0,0 +1,91 @@
+package diag
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gotd/td/bin"
+	"github.com/gotd/td/clock"
+	"github.com/gotd/td/telegram"
+	"github.com/gotd/td/tg"
+)
+
+// Call is a pending call.
+type Call struct {
+	Method  string        `json:"method"`
+	Started time.Time     `json:"started"`
+	Elapsed time.Duration `json:"elapsed"`
+}
+
+// Pending is a tg.Invoker that tracks pending calls of underlying invoker.
+type Pending struct {
+	clock clock.Clock
+
+	mux   sync.Mutex
+	next  uint64
+	calls map[uint64]Call
+}
+
+// NewPending creates new Pending.
+func NewPending() *Pending {
+	return &Pending{
+		clock: clock.System,
+		calls: map[uint64]Call{},
+	}
+}
+
+// WithClock sets clock to use. Default is to use system clock.
+func (p *Pending) WithClock(c clock.Clock) *Pending {
+	p.clock = c
+	return p
+}
+
+func (p *Pending) add(method string) uint64 {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+
+	id := p.next
+	p.next++
+	p.calls[id] = Call{Method: method, Started: p.clock.Now()}
+	return id
+}
+
+func (p *Pending) remove(id uint64) {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	delete(p.calls, id)
+}
+
+// Calls returns pending calls, oldest first.
+func (p *Pending) Calls() []Call {
+	p.mux.Lock()
+	now := p.clock.Now()
+	calls := make([]Call, 0, len(p.calls))
+	for _, c := range p.calls {
+		c.Elapsed = now.Sub(c.Started)
+		calls = append(calls, c)
+	}
+	p.mux.Unlock()
+
+	sort.SliceStable(calls, func(i, j int) bool {
+		return calls[i].Started.Before(calls[j].Started)
+	})
+	return calls
+}
+
+// Handle implements telegram.Middleware.
+func (p *Pending) Handle(next tg.Invoker) telegram.InvokeFunc {
+	return func(ctx context.Context, input bin.Encoder, output bin.Decoder) error {
+		var method string
+		if obj, ok := input.(interface{ TypeName() string }); ok {
+			method = obj.TypeName()
+		}
+
+		id := p.add(method)
+		defer p.remove(id)
+
+		return next.Invoke(ctx, input, output)
+	}
+}
@@ -0,0 +1,45 @@
+package diag
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/gotd/neo"
+	"github.com/gotd/td/bin"
+	"github.com/gotd/td/telegram"
+	"github.com/gotd/td/tg"
+)
+
+func TestPending(t *testing.T) {
+	a := require.New(t)
+	clock := neo.NewTime(time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC))
+	p := NewPending().WithClock(clock)
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	invoker := p.Handle(telegram.InvokeFunc(func(ctx context.Context, input bin.Encoder, output bin.Decoder) error {
+		close(started)
+		<-release
+		return nil
+	}))
+
+	done := make(chan error, 1)
+	go func() {
+		done <- invoker.Invoke(context.Background(), &tg.HelpGetConfigRequest{}, nil)
+	}()
+	<-started
+	clock.Travel(time.Second)
+
+	a.Equal([]Call{{
+		Method:  "help.getConfig",
+		Started: time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC),
+		Elapsed: time.Second,
+	}}, p.Calls())
+
+	close(release)
+	a.NoError(<-done)
+	a.Empty(p.Calls())
+}
@@ -0,0 +1,130 @@
+package health
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/go-faster/errors"
+
+	"github.com/gotd/td/clock"
+	"github.com/gotd/td/session"
+	"github.com/gotd/td/telegram"
+	"github.com/gotd/td/telegram/auth"
+	"github.com/gotd/td/tg"
+
+	"github.com/gotd/contrib/auth/kv"
+)
+
+// ErrNotAuthorized is returned by Authorized check, if client is not
+// authorized.
+var ErrNotAuthorized = errors.New("not authorized")
+
+// Pinger pings server, e.g. *telegram.Client.
+type Pinger interface {
+	Ping(ctx context.Context) error
+}
+
+// Connected returns check which pings server.
+func Connected(p Pinger) Check {
+	return func(ctx context.Context) error {
+		if err := p.Ping(ctx); err != nil {
+			return errors.Errorf("ping: %w", err)
+		}
+		return nil
+	}
+}
+
+// Authorized returns check which requests auth status, e.g. of
+// client.Auth().
+func Authorized(a *auth.Client) Check {
+	return func(ctx context.Context) error {
+		status, err := a.Status(ctx)
+		if err != nil {
+			return errors.Errorf("auth status: %w", err)
+		}
+		if !status.Authorized {
+			return ErrNotAuthorized
+		}
+		return nil
+	}
+}
+
+// Session returns check which loads session from storage. Missing session
+// is not an error.
+func Session(s telegram.SessionStorage) Check {
+	return func(ctx context.Context) error {
+		if _, err := s.LoadSession(ctx); err != nil && !errors.Is(err, session.ErrNotFound) {
+			return errors.Errorf("load session: %w", err)
+		}
+		return nil
+	}
+}
+
+// KV returns check which gets given key from storage. Missing key is not
+// an error.
+func KV(s kv.Storage, key string) Check {
+	return func(ctx context.Context) error {
+		if _, err := s.Get(ctx, key); err != nil && !errors.Is(err, kv.ErrKeyNotFound) {
+			return errors.Errorf("get %q: %w", key, err)
+		}
+		return nil
+	}
+}
+
+// Updates tracks time of last update to check that updates are flowing.
+type Updates struct {
+	clock  clock.Clock
+	maxAge time.Duration
+
+	mux  sync.Mutex
+	last time.Time
+}
+
+// NewUpdates creates new Updates which fails check, if there were no
+// updates for maxAge.
+func NewUpdates(maxAge time.Duration) *Updates {
+	return &Updates{
+		clock:  clock.System,
+		maxAge: maxAge,
+		last:   clock.System.Now(),
+	}
+}
+
+// WithClock sets clock to use. Default is to use system clock.
+func (u *Updates) WithClock(c clock.Clock) *Updates {
+	u.mux.Lock()
+	defer u.mux.Unlock()
+
+	u.clock = c
+	u.last = c.Now()
+	return u
+}
+
+// Last returns time of last update. Before first update, it returns
+// creation time.
+func (u *Updates) Last() time.Time {
+	u.mux.Lock()
+	defer u.mux.Unlock()
+	return u.last
+}
+
+// Handle returns telegram.UpdateHandler which tracks updates passed to next.
+func (u *Updates) Handle(next telegram.UpdateHandler) telegram.UpdateHandler {
+	return telegram.UpdateHandlerFunc(func(ctx context.Context, updates tg.UpdatesClass) error {
+		u.mux.Lock()
+		u.last = u.clock.Now()
+		u.mux.Unlock()
+
+		return next.Handle(ctx, updates)
+	})
+}
+
+// Check implements Check.
+func (u *Updates) Check(ctx context.Context) error {
+	since := u.clock.Now().Sub(u.Last())
+	if since > u.maxAge {
+		return errors.Errorf("no updates for %s", since.Round(time.Second))
+	}
+	return nil
+}
@@ -0,0 +1,114 @@
+package health
+
+import (
+	"context"
+	"crypto/rand"
+	"testing"
+	"time"
+
+	"github.com/go-faster/errors"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gotd/neo"
+	"github.com/gotd/td/bin"
+	"github.com/gotd/td/session"
+	"github.com/gotd/td/telegram"
+	"github.com/gotd/td/telegram/auth"
+	"github.com/gotd/td/tg"
+	"github.com/gotd/td/tgerr"
+
+	"github.com/gotd/contrib/auth/kv"
+)
+
+type pingFunc func(ctx context.Context) error
+
+func (f pingFunc) Ping(ctx context.Context) error {
+	return f(ctx)
+}
+
+func TestConnected(t *testing.T) {
+	a := require.New(t)
+	ctx := context.Background()
+	testErr := errors.New("test")
+
+	a.NoError(Connected(pingFunc(func(ctx context.Context) error { return nil }))(ctx))
+	a.ErrorIs(Connected(pingFunc(func(ctx context.Context) error { return testErr }))(ctx), testErr)
+}
+
+func TestAuthorized(t *testing.T) {
+	a := require.New(t)
+	ctx := context.Background()
+
+	authorized := false
+	api := tg.NewClient(telegram.InvokeFunc(func(ctx context.Context, input bin.Encoder, output bin.Decoder) error {
+		if !authorized {
+			return tgerr.New(401, "AUTH_KEY_UNREGISTERED")
+		}
+		users := &tg.UserClassVector{Elems: []tg.UserClass{&tg.User{ID: 1, Self: true}}}
+		b := &bin.Buffer{}
+		if err := users.Encode(b); err != nil {
+			return err
+		}
+		return output.Decode(b)
+	}))
+	check := Authorized(auth.NewClient(api, rand.Reader, 1, "hash"))
+
+	a.ErrorIs(check(ctx), ErrNotAuthorized)
+	authorized = true
+	a.NoError(check(ctx))
+}
+
+type memKV map[string]string
+
+func (m memKV) Set(ctx context.Context, k, v string) error {
+	m[k] = v
+	return nil
+}
+
+func (m memKV) Get(ctx context.Context, k string) (string, error) {
+	v, ok := m[k]
+	if !ok {
+		return "", kv.ErrKeyNotFound
+	}
+	return v, nil
+}
+
+type failingKV struct{}
+
+func (failingKV) Set(ctx context.Context, k, v string) error {
+	return errors.New("unavailable")
+}
+
+func (failingKV) Get(ctx context.Context, k string) (string, error) {
+	return "", errors.New("unavailable")
+}
+
+func TestStorage(t *testing.T) {
+	a := require.New(t)
+	ctx := context.Background()
+
+	a.NoError(KV(memKV{}, "health")(ctx))
+	a.Error(KV(failingKV{}, "health")(ctx))
+	a.NoError(Session(&session.StorageMemory{})(ctx))
+	a.NoError(Session(kv.NewSession(memKV{}, "session"))(ctx))
+	a.Error(Session(kv.NewSession(failingKV{}, "session"))(ctx))
+}
+
+func TestUpdates(t *testing.T) {
+	a := require.New(t)
+	ctx := context.Background()
+	clock := neo.NewTime(time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC))
+
+	u := NewUpdates(time.Minute).WithClock(clock)
+	h := u.Handle(telegram.UpdateHandlerFunc(func(ctx context.Context, u tg.UpdatesClass) error {
+		return nil
+	}))
+	a.NoError(u.Check(ctx))
+
+	clock.Travel(2 * time.Minute)
+	a.Error(u.Check(ctx))
+
+	a.NoError(h.Handle(ctx, &tg.Updates{}))
+	a.Equal(clock.Now(), u.Last())
+	a.NoError(u.Check(ctx))
+}
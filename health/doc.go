@@ -0,0 +1,3 @@
+// Package health implements liveness and readiness probes of client, e.g.
+// for Kubernetes deployments.
+package health
@@ -0,0 +1,152 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Check is a health check. It returns nil if check is passed.
+type Check func(ctx context.Context) error
+
+type namedCheck struct {
+	name  string
+	check Check
+}
+
+// Result is a result of single check.
+type Result struct {
+	Name string `json:"name"`
+	// Error is an error of failed check, empty if check is passed.
+	Error string `json:"error,omitempty"`
+}
+
+// Report is a result of probe.
+type Report struct {
+	OK     bool     `json:"ok"`
+	Checks []Result `json:"checks"`
+}
+
+// Checker runs liveness and readiness probes.
+//
+// Checker is a http.Handler which serves "GET /livez" and "GET /readyz"
+// with JSON Report, responding with 503 if probe is failed.
+type Checker struct {
+	timeout   time.Duration
+	liveness  []namedCheck
+	readiness []namedCheck
+	mux       *http.ServeMux
+}
+
+// New creates new Checker without checks.
+func New() *Checker {
+	c := &Checker{
+		timeout: 5 * time.Second,
+		mux:     http.NewServeMux(),
+	}
+	c.mux.Handle("GET /livez", c.LiveHandler())
+	c.mux.Handle("GET /readyz", c.ReadyHandler())
+	return c
+}
+
+// WithTimeout sets timeout of every check. Default is 5s.
+func (c *Checker) WithTimeout(d time.Duration) *Checker {
+	c.timeout = d
+	return c
+}
+
+// WithLiveness adds liveness check, e.g. Connected. Failed liveness means
+// that process should be restarted.
+func (c *Checker) WithLiveness(name string, check Check) *Checker {
+	c.liveness = append(c.liveness, namedCheck{name: name, check: check})
+	return c
+}
+
+// WithReadiness adds readiness check, e.g. Authorized or Updates.Check.
+// Failed readiness means that process should not get traffic.
+func (c *Checker) WithReadiness(name string, check Check) *Checker {
+	c.readiness = append(c.readiness, namedCheck{name: name, check: check})
+	return c
+}
+
+func (c *Checker) run(ctx context.Context, checks []namedCheck) Report {
+	r := Report{
+		OK:     true,
+		Checks: make([]Result, len(checks)),
+	}
+	errs := make([]error, len(checks))
+
+	var wg sync.WaitGroup
+	for i, nc := range checks {
+		wg.Add(1)
+		go func(i int, nc namedCheck) {
+			defer wg.Done()
+
+			ctx := ctx
+			if c.timeout > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, c.timeout)
+				defer cancel()
+			}
+			errs[i] = nc.check(ctx)
+		}(i, nc)
+	}
+	wg.Wait()
+
+	for i, nc := range checks {
+		r.Checks[i] = Result{Name: nc.name}
+		if err := errs[i]; err != nil {
+			r.OK = false
+			r.Checks[i].Error = err.Error()
+		}
+	}
+	return r
+}
+
+// Live runs liveness checks.
+func (c *Checker) Live(ctx context.Context) Report {
+	return c.run(ctx, c.liveness)
+}
+
+// Ready runs readiness checks. Liveness checks are also included, because
+// dead process is not ready.
+func (c *Checker) Ready(ctx context.Context) Report {
+	checks := make([]namedCheck, 0, len(c.liveness)+len(c.readiness))
+	checks = append(checks, c.liveness...)
+	checks = append(checks, c.readiness...)
+	return c.run(ctx, checks)
+}
+
+func serve(probe func(ctx context.Context) Report) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		report := probe(r.Context())
+		data, err := json.Marshal(report)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if !report.OK {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		_, _ = w.Write(data)
+	})
+}
+
+// LiveHandler returns http.Handler of liveness probe.
+func (c *Checker) LiveHandler() http.Handler {
+	return serve(c.Live)
+}
+
+// ReadyHandler returns http.Handler of readiness probe.
+func (c *Checker) ReadyHandler() http.Handler {
+	return serve(c.Ready)
+}
+
+// ServeHTTP implements http.Handler.
+func (c *Checker) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	c.mux.ServeHTTP(w, r)
+}
@@ -0,0 +1,64 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-faster/errors"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChecker(t *testing.T) {
+	a := require.New(t)
+	ok := func(ctx context.Context) error { return nil }
+	fail := func(ctx context.Context) error { return errors.New("test") }
+
+	c := New().
+		WithLiveness("connected", ok).
+		WithReadiness("authorized", fail)
+
+	live := c.Live(context.Background())
+	a.True(live.OK)
+	a.Equal([]Result{{Name: "connected"}}, live.Checks)
+
+	ready := c.Ready(context.Background())
+	a.False(ready.OK)
+	a.Equal([]Result{
+		{Name: "connected"},
+		{Name: "authorized", Error: "test"},
+	}, ready.Checks)
+
+	for _, tt := range []struct {
+		path string
+		code int
+		ok   bool
+	}{
+		{"/livez", http.StatusOK, true},
+		{"/readyz", http.StatusServiceUnavailable, false},
+	} {
+		w := httptest.NewRecorder()
+		c.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+		a.Equal(tt.code, w.Code, tt.path)
+		a.Equal("application/json", w.Header().Get("Content-Type"))
+
+		var r Report
+		a.NoError(json.Unmarshal(w.Body.Bytes(), &r))
+		a.Equal(tt.ok, r.OK)
+	}
+}
+
+func TestCheckerTimeout(t *testing.T) {
+	c := New().
+		WithTimeout(1).
+		WithLiveness("blocked", func(ctx context.Context) error {
+			<-ctx.Done()
+			return ctx.Err()
+		})
+
+	r := c.Live(context.Background())
+	require.False(t, r.OK)
+	require.Equal(t, context.DeadlineExceeded.Error(), r.Checks[0].Error)
+}
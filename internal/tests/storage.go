@@ -5,9 +5,11 @@ import (
 	"testing"
 	"time"
 
+	"github.com/go-faster/errors"
 	"github.com/stretchr/testify/require"
 
 	"github.com/gotd/td/session"
+	"github.com/gotd/td/telegram/query/dialogs"
 	"github.com/gotd/td/tg"
 
 	"github.com/gotd/contrib/auth"
@@ -123,5 +125,547 @@ func TestPeerStorage(t *testing.T, st storage.PeerStorage) {
 			break
 		}
 		a.True(found, "should contain")
+
+		if c, ok := st.(storage.PeerCounter); ok {
+			count, err := c.Count(ctx)
+			a.NoError(err)
+			a.GreaterOrEqual(count, 6)
+
+			users, err := c.Count(ctx, dialogs.User)
+			a.NoError(err)
+			a.Equal(count, users)
+
+			channels, err := c.Count(ctx, dialogs.Channel)
+			a.NoError(err)
+			a.Zero(channels)
+		}
+	})
+	if f, ok := st.(storage.FilteredPeerStorage); ok {
+		t.Run("IterateFiltered", func(t *testing.T) {
+			a := require.New(t)
+
+			var bot, channel storage.Peer
+			a.True(bot.FromUser(&tg.User{
+				ID:         300,
+				AccessHash: 300,
+				Bot:        true,
+			}))
+			a.True(channel.FromChat(&tg.Channel{
+				ID:         300,
+				AccessHash: 300,
+				Photo:      &tg.ChatPhotoEmpty{},
+			}))
+			a.NoError(st.Add(ctx, bot))
+			a.NoError(st.Add(ctx, channel))
+
+			collect := func(opts storage.IterateOptions) (r []storage.Peer) {
+				iter, err := f.IterateFiltered(ctx, opts)
+				a.NoError(err)
+				defer func() {
+					a.NoError(iter.Close())
+				}()
+				a.NoError(storage.ForEach(ctx, iter, func(p storage.Peer) error {
+					r = append(r, p)
+					return nil
+				}))
+				return r
+			}
+
+			channels := collect(storage.IterateOptions{Kinds: []dialogs.PeerKind{dialogs.Channel}})
+			a.Len(channels, 1)
+			a.Equal(channel.Key, channels[0].Key)
+
+			bots := collect(storage.IterateOptions{Bots: true})
+			a.Len(bots, 1)
+			a.Equal(bot.Key, bots[0].Key)
+
+			a.Empty(collect(storage.IterateOptions{Kinds: []dialogs.PeerKind{dialogs.Chat}}))
+			a.Empty(collect(storage.IterateOptions{Kinds: []dialogs.PeerKind{dialogs.Channel}, Bots: true}))
+
+			all := collect(storage.IterateOptions{Kinds: []dialogs.PeerKind{dialogs.User, dialogs.Channel}})
+			a.Len(all, len(collect(storage.IterateOptions{})))
+		})
+	}
+	if touch, ok := st.(storage.PeerToucher); ok {
+		t.Run("Recent", func(t *testing.T) {
+			testPeerStorageRecent(ctx, t, st, touch)
+		})
+	}
+	t.Run("Merge", func(t *testing.T) {
+		a := require.New(t)
+
+		var full, minPeer storage.Peer
+		a.True(full.FromUser(&tg.User{
+			ID:         600,
+			AccessHash: 600,
+			Username:   "merged",
+		}))
+		full.Metadata = map[string]any{"lang": "en"}
+		a.True(minPeer.FromUser(&tg.User{
+			ID:         600,
+			AccessHash: 601,
+			Min:        true,
+		}))
+		a.NoError(st.Add(ctx, full))
+		a.NoError(st.Add(ctx, minPeer))
+
+		p, err := st.Find(ctx, storage.KeyFromPeer(full))
+		a.NoError(err)
+		a.Equal(int64(600), p.Key.AccessHash, "min peer must not overwrite full one")
+		a.False(p.Min())
+		a.Equal("en", p.Metadata["lang"])
+	})
+	t.Run("Metadata", func(t *testing.T) {
+		a := require.New(t)
+
+		var p storage.Peer
+		a.True(p.FromUser(&tg.User{
+			ID:         700,
+			AccessHash: 700,
+		}))
+		key := storage.KeyFromPeer(p)
+		a.ErrorIs(storage.SetMetadata(ctx, st, key, "lang", "en"), storage.ErrPeerNotFound)
+		a.NoError(st.Add(ctx, p))
+
+		a.NoError(storage.SetMetadata(ctx, st, key, "lang", "en"))
+		a.NoError(storage.SetMetadata(ctx, st, key, "banned", true))
+		v, ok, err := storage.GetMetadata(ctx, st, key, "lang")
+		a.NoError(err)
+		a.True(ok)
+		a.Equal("en", v)
+
+		a.NoError(storage.SetMetadata(ctx, st, key, "banned", nil))
+		_, ok, err = storage.GetMetadata(ctx, st, key, "banned")
+		a.NoError(err)
+		a.False(ok)
+
+		// Metadata is kept when peer is updated without it.
+		a.NoError(st.Add(ctx, p))
+		v, ok, err = storage.GetMetadata(ctx, st, key, "lang")
+		a.NoError(err)
+		a.True(ok)
+		a.Equal("en", v)
+	})
+	t.Run("SearchPrefix", func(t *testing.T) {
+		testPeerStorageSearch(ctx, t, st)
+	})
+	t.Run("ResolveBatch", func(t *testing.T) {
+		a := require.New(t)
+
+		var first, second storage.Peer
+		a.NoError(first.FromInputPeer(&tg.InputPeerUser{UserID: 5151, AccessHash: 1}))
+		a.NoError(second.FromInputPeer(&tg.InputPeerChannel{ChannelID: 5152, AccessHash: 2}))
+		a.NoError(st.Assign(ctx, "batch_first", first))
+		a.NoError(st.Assign(ctx, "batch_second", second))
+
+		r, err := storage.ResolveBatch(ctx, st, []string{
+			"batch_first", "batch_missing", "batch_second", "batch_first",
+		})
+		a.NoError(err)
+		a.Len(r, 2)
+		a.Equal(storage.KeyFromPeer(first), storage.KeyFromPeer(r["batch_first"]))
+		a.Equal(storage.KeyFromPeer(second), storage.KeyFromPeer(r["batch_second"]))
+
+		r, err = storage.ResolveBatch(ctx, st, nil)
+		a.NoError(err)
+		a.Empty(r)
 	})
+	t.Run("FindByID", func(t *testing.T) {
+		a := require.New(t)
+
+		var user, channel storage.Peer
+		a.NoError(user.FromInputPeer(&tg.InputPeerUser{UserID: 4242, AccessHash: 1}))
+		a.NoError(channel.FromInputPeer(&tg.InputPeerChannel{ChannelID: 4242, AccessHash: 2}))
+		a.NoError(st.Add(ctx, channel))
+
+		p, err := storage.FindByID(ctx, st, 4242)
+		a.NoError(err)
+		a.Equal(dialogs.Channel, p.Key.Kind)
+
+		// User is preferred for ambiguous ID.
+		a.NoError(st.Add(ctx, user))
+		p, err = storage.FindByID(ctx, st, 4242)
+		a.NoError(err)
+		a.Equal(dialogs.User, p.Key.Kind)
+
+		// Bot API ID of channel.
+		p, err = storage.FindByID(ctx, st, -1000000004242)
+		a.NoError(err)
+		a.Equal(dialogs.Channel, p.Key.Kind)
+
+		_, err = storage.FindByID(ctx, st, 4243)
+		a.ErrorIs(err, storage.ErrPeerNotFound)
+	})
+	if pager, ok := st.(storage.PeerPager); ok {
+		t.Run("IteratePage", func(t *testing.T) {
+			a := require.New(t)
+
+			total, err := storage.CountPeers(ctx, st)
+			a.NoError(err)
+
+			var (
+				cursor string
+				seen   = map[storage.PeerKey]struct{}{}
+			)
+			for {
+				page, err := pager.IteratePage(ctx, cursor, 2)
+				a.NoError(err)
+				for _, p := range page.Peers {
+					key := storage.KeyFromPeer(p)
+					a.NotContains(seen, key, "duplicate peer")
+					seen[key] = struct{}{}
+				}
+				if page.Next == "" {
+					break
+				}
+				cursor = page.Next
+			}
+			a.Len(seen, total)
+		})
+	}
+	if _, ok := st.(storage.RawScanner); ok {
+		t.Run("Analyze", func(t *testing.T) {
+			a := require.New(t)
+
+			total, err := storage.CountPeers(ctx, st)
+			a.NoError(err)
+
+			r, err := storage.Analyze(ctx, st, 3)
+			a.NoError(err)
+			var peers int
+			for _, kind := range []dialogs.PeerKind{dialogs.User, dialogs.Chat, dialogs.Channel} {
+				peers += r.Prefixes[string(storage.KindPrefix(kind))]
+			}
+			a.Equal(total, peers)
+			a.Greater(r.Count, peers, "associated keys and indexes are scanned too")
+			a.Len(r.Largest, 3)
+			a.GreaterOrEqual(r.Largest[0].Size, r.Largest[2].Size)
+		})
+	}
+	if d, ok := st.(storage.PeerDeleter); ok {
+		t.Run("Delete", func(t *testing.T) {
+			a := require.New(t)
+
+			var p storage.Peer
+			a.True(p.FromUser(&tg.User{
+				ID:         200,
+				AccessHash: 200,
+				Username:   "deleted",
+			}))
+			key := storage.KeyFromPeer(p)
+			a.NoError(st.Add(ctx, p))
+
+			a.NoError(d.Delete(ctx, key))
+			_, err := st.Find(ctx, key)
+			a.ErrorIs(err, storage.ErrPeerNotFound)
+			_, err = st.Resolve(ctx, "deleted")
+			a.ErrorIs(err, storage.ErrPeerNotFound)
+
+			a.ErrorIs(d.Delete(ctx, key), storage.ErrPeerNotFound)
+		})
+	}
+	if w, ok := st.(storage.PeerWatcher); ok {
+		t.Run("Watch", func(t *testing.T) {
+			testPeerStorageWatch(ctx, t, st, w)
+		})
+	}
+	if ttl, ok := st.(storage.ExpiringPeerStorage); ok {
+		t.Run("TTL", func(t *testing.T) {
+			testPeerStorageTTL(ctx, t, ttl)
+		})
+	}
+	if v, ok := st.(storage.SchemaVersioner); ok {
+		t.Run("Schema", func(t *testing.T) {
+			testPeerStorageSchema(ctx, t, st, v)
+		})
+	}
+	if gc, ok := st.(storage.PeerGarbageCollector); ok {
+		// Mismatched collection removes keys assigned by previous tests,
+		// so it must be the last one.
+		t.Run("GC", func(t *testing.T) {
+			testPeerStorageGC(ctx, t, st, gc)
+		})
+	}
+}
+
+func testPeerStorageGC(ctx context.Context, t *testing.T, st storage.PeerStorage, gc storage.PeerGarbageCollector) {
+	a := require.New(t)
+
+	// Old username is removed on update.
+	user := &tg.User{ID: 600, AccessHash: 600, Username: "gc_old"}
+	var p storage.Peer
+	a.True(p.FromUser(user))
+	a.NoError(st.Add(ctx, p))
+	user.Username = "gc_new"
+	a.True(p.FromUser(user))
+	a.NoError(st.Add(ctx, p))
+	_, err := st.Resolve(ctx, "gc_old")
+	a.ErrorIs(err, storage.ErrPeerNotFound)
+	_, err = st.Resolve(ctx, "gc_new")
+	a.NoError(err)
+
+	// Keys of missing peers are orphaned.
+	var deleted storage.Peer
+	a.True(deleted.FromUser(&tg.User{ID: 601, AccessHash: 601}))
+	a.NoError(st.Assign(ctx, "gc_deleted", deleted))
+	d, ok := st.(storage.PeerDeleter)
+	a.True(ok, "garbage collector should support deletion")
+	a.NoError(d.Delete(ctx, storage.KeyFromPeer(deleted)))
+	a.NoError(st.Assign(ctx, "gc_custom", p))
+
+	n, err := gc.CollectGarbage(ctx, storage.GCOptions{DryRun: true})
+	a.NoError(err)
+	a.GreaterOrEqual(n, 1)
+	removed, err := gc.CollectGarbage(ctx, storage.GCOptions{})
+	a.NoError(err)
+	a.Equal(n, removed)
+	n, err = gc.CollectGarbage(ctx, storage.GCOptions{})
+	a.NoError(err)
+	a.Zero(n)
+
+	// Custom key is kept unless mismatched keys are collected.
+	_, err = st.Resolve(ctx, "gc_custom")
+	a.NoError(err)
+	n, err = gc.CollectGarbage(ctx, storage.GCOptions{Mismatched: true})
+	a.NoError(err)
+	a.GreaterOrEqual(n, 1)
+	_, err = st.Resolve(ctx, "gc_custom")
+	a.ErrorIs(err, storage.ErrPeerNotFound)
+	_, err = st.Resolve(ctx, "gc_new")
+	a.NoError(err)
+}
+
+func testPeerStorageSchema(ctx context.Context, t *testing.T, st storage.PeerStorage, v storage.SchemaVersioner) {
+	a := require.New(t)
+
+	total, err := storage.CountPeers(ctx, st)
+	a.NoError(err)
+
+	a.NoError(storage.UpgradeSchema(ctx, st))
+	version, err := v.SchemaVersion(ctx)
+	a.NoError(err)
+	a.Equal(storage.SchemaVersion, version)
+
+	// Migration keeps peers.
+	count, err := storage.CountPeers(ctx, st)
+	a.NoError(err)
+	a.Equal(total, count)
+
+	// Already applied migrations are skipped.
+	a.NoError(storage.UpgradeSchema(ctx, st, storage.Migration{
+		Version: storage.SchemaVersion,
+		Migrate: func(ctx context.Context, s storage.PeerStorage) error {
+			return errors.New("must not be called")
+		},
+	}))
+
+	a.NoError(v.SetSchemaVersion(ctx, storage.SchemaVersion+1))
+	a.ErrorIs(storage.UpgradeSchema(ctx, st), storage.ErrSchemaTooNew)
+	a.NoError(v.SetSchemaVersion(ctx, storage.SchemaVersion))
+}
+
+func testPeerStorageTTL(ctx context.Context, t *testing.T, st storage.ExpiringPeerStorage) {
+	a := require.New(t)
+
+	var p storage.Peer
+	a.NoError(p.FromInputPeer(&tg.InputPeerUser{
+		UserID:     100,
+		AccessHash: 100,
+	}))
+	key := storage.KeyFromPeer(p)
+
+	a.NoError(st.AddTTL(ctx, p, time.Hour))
+	found, err := st.Find(ctx, key)
+	a.NoError(err)
+	a.False(found.ExpiresAt.IsZero())
+
+	a.NoError(st.AddTTL(ctx, p, 10*time.Millisecond))
+	time.Sleep(50 * time.Millisecond)
+	_, err = st.Find(ctx, key)
+	a.ErrorIs(err, storage.ErrPeerNotFound)
+
+	if gc, ok := st.(interface {
+		DeleteExpired(ctx context.Context) (int, error)
+	}); ok {
+		deleted, err := gc.DeleteExpired(ctx)
+		a.NoError(err)
+		a.Equal(1, deleted)
+	}
+
+	iter, err := st.Iterate(ctx)
+	a.NoError(err)
+	defer func() {
+		a.NoError(iter.Close())
+	}()
+	for iter.Next(ctx) {
+		a.NotEqual(p.Key, iter.Value().Key, "expired peer should be skipped")
+	}
+	a.NoError(iter.Err())
+}
+
+func testPeerStorageRecent(ctx context.Context, t *testing.T, st storage.PeerStorage, touch storage.PeerToucher) {
+	a := require.New(t)
+
+	var keys []storage.PeerKey
+	for i := range [3]struct{}{} {
+		var p storage.Peer
+		a.True(p.FromUser(&tg.User{
+			ID:         int64(i) + 400,
+			AccessHash: int64(i) + 400,
+		}))
+		a.NoError(st.Add(ctx, p))
+		keys = append(keys, storage.KeyFromPeer(p))
+		// Some backends store touch time with millisecond precision.
+		time.Sleep(5 * time.Millisecond)
+	}
+	a.NoError(touch.Touch(ctx, keys[0]))
+	a.ErrorIs(touch.Touch(ctx, storage.PeerKey{Kind: dialogs.User, ID: 499}), storage.ErrPeerNotFound)
+
+	collect := func(order storage.IterateOrder) (r []storage.PeerKey) {
+		iter, err := storage.IterateFiltered(ctx, st, storage.IterateOptions{Order: order})
+		a.NoError(err)
+		defer func() {
+			a.NoError(iter.Close())
+		}()
+		a.NoError(storage.ForEach(ctx, iter, func(p storage.Peer) error {
+			key := storage.KeyFromPeer(p)
+			for _, k := range keys {
+				if k == key {
+					r = append(r, key)
+				}
+			}
+			return nil
+		}))
+		return r
+	}
+
+	a.Equal([]storage.PeerKey{keys[0], keys[2], keys[1]}, collect(storage.OrderRecent))
+	a.Equal([]storage.PeerKey{keys[1], keys[2], keys[0]}, collect(storage.OrderRecentReverse))
+}
+
+func testPeerStorageSearch(ctx context.Context, t *testing.T, st storage.PeerStorage) {
+	a := require.New(t)
+
+	for i, username := range []string{"search_b", "Search_A", "searcher", "other"} {
+		var p storage.Peer
+		a.True(p.FromUser(&tg.User{
+			ID:         int64(i) + 500,
+			AccessHash: int64(i) + 500,
+			Username:   username,
+		}))
+		a.NoError(st.Add(ctx, p))
+	}
+
+	// Peer changed username, old index entry must be ignored.
+	var renamed storage.Peer
+	a.True(renamed.FromUser(&tg.User{
+		ID:         510,
+		AccessHash: 510,
+		Username:   "search_old",
+	}))
+	a.NoError(st.Add(ctx, renamed))
+	renamed.User.Username = "renamed"
+	a.NoError(st.Add(ctx, renamed))
+
+	search := func(prefix string) (r []string) {
+		iter, err := storage.SearchPrefix(ctx, st, prefix)
+		a.NoError(err)
+		defer func() {
+			a.NoError(iter.Close())
+		}()
+		a.NoError(storage.ForEach(ctx, iter, func(p storage.Peer) error {
+			r = append(r, p.Username())
+			return nil
+		}))
+		return r
+	}
+
+	a.ElementsMatch([]string{"Search_A", "search_b", "searcher"}, search("SEARCH"))
+	a.ElementsMatch([]string{"Search_A", "search_b"}, search("search_"))
+	a.Equal([]string{"renamed"}, search("ren"))
+	a.Empty(search("nobody"))
+}
+
+func testPeerStorageWatch(ctx context.Context, t *testing.T, st storage.PeerStorage, w storage.PeerWatcher) {
+	a := require.New(t)
+
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	events, err := w.Watch(ctx)
+	a.NoError(err)
+
+	var p storage.Peer
+	a.True(p.FromUser(&tg.User{
+		ID:         800,
+		AccessHash: 800,
+	}))
+	key := storage.KeyFromPeer(p)
+
+	next := func() storage.Event {
+		for {
+			select {
+			case e := <-events:
+				if e.Key == key {
+					return e
+				}
+			case <-ctx.Done():
+				t.Fatal("event not received")
+			}
+		}
+	}
+
+	a.NoError(st.Add(ctx, p))
+	e := next()
+	a.Contains([]storage.EventType{storage.EventAdd, storage.EventUpdate}, e.Type)
+	a.Equal(p.Key, e.Peer.Key)
+
+	a.NoError(st.Add(ctx, p))
+	a.Equal(storage.EventUpdate, next().Type)
+
+	if d, ok := st.(storage.PeerDeleter); ok {
+		a.NoError(d.Delete(ctx, key))
+		a.Equal(storage.EventDelete, next().Type)
+	}
+
+	cancel()
+	for range events {
+		// Channel must be closed.
+	}
+}
+
+// TestPeerStorageCodecSwitch checks that peers written by old codec are
+// cache misses for storage using another codec over the same data.
+func TestPeerStorageCodecSwitch(t *testing.T, old, switched storage.PeerStorage) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	a := require.New(t)
+
+	var p storage.Peer
+	a.True(p.FromUser(&tg.User{ID: 700, AccessHash: 700, Username: "codec"}))
+	a.NoError(old.Add(ctx, p))
+	a.NoError(old.Assign(ctx, "codec_key", p))
+
+	_, err := switched.Find(ctx, storage.KeyFromPeer(p))
+	a.ErrorIs(err, storage.ErrPeerNotFound)
+	_, err = switched.Resolve(ctx, "codec_key")
+	a.ErrorIs(err, storage.ErrPeerNotFound)
+
+	var fresh storage.Peer
+	a.True(fresh.FromUser(&tg.User{ID: 701, AccessHash: 701}))
+	a.NoError(switched.Add(ctx, fresh))
+
+	iter, err := switched.Iterate(ctx)
+	a.NoError(err)
+	var keys []storage.PeerKey
+	a.NoError(storage.ForEach(ctx, iter, func(p storage.Peer) error {
+		keys = append(keys, storage.KeyFromPeer(p))
+		return nil
+	}))
+	a.NoError(iter.Close())
+	a.Equal([]storage.PeerKey{storage.KeyFromPeer(fresh)}, keys, "outdated peers are skipped")
+
+	// Switched storage overwrites outdated peer.
+	a.NoError(switched.Add(ctx, p))
+	_, err = switched.Find(ctx, storage.KeyFromPeer(p))
+	a.NoError(err)
 }
@@ -0,0 +1,86 @@
+package invoker
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"sync"
+
+	"github.com/go-faster/errors"
+
+	"github.com/gotd/td/bin"
+	"github.com/gotd/td/tg"
+	"github.com/gotd/td/tgerr"
+)
+
+// Record is a recorded call, encoded as single JSON line.
+type Record struct {
+	// Method is a TL name of method, e.g. "messages.sendMessage".
+	Method string `json:"method"`
+	// Request is an encoded request.
+	Request []byte `json:"request"`
+	// Response is an encoded response, if call succeeded.
+	Response []byte `json:"response,omitempty"`
+	// Error is an RPC error, if call failed.
+	Error *RecordError `json:"error,omitempty"`
+}
+
+// RecordError is a recorded RPC error.
+type RecordError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// Recorder is invoker middleware which records calls to writer, so they
+// can be served back by Replay.
+//
+// Only successful calls and calls failed with RPC error are recorded.
+type Recorder struct {
+	next tg.Invoker
+
+	mux sync.Mutex
+	enc *json.Encoder
+}
+
+// NewRecorder creates new Recorder middleware.
+func NewRecorder(next tg.Invoker, out io.Writer) *Recorder {
+	return &Recorder{next: next, enc: json.NewEncoder(out)}
+}
+
+// Invoke implements tg.Invoker.
+func (r *Recorder) Invoke(ctx context.Context, input bin.Encoder, output bin.Decoder) error {
+	var b bin.Buffer
+	if err := input.Encode(&b); err != nil {
+		return errors.Wrap(err, "encode request")
+	}
+	rec := Record{Request: b.Copy()}
+	if obj, ok := input.(interface{ TypeName() string }); ok {
+		rec.Method = obj.TypeName()
+	}
+
+	invokeErr := r.next.Invoke(ctx, input, output)
+	if invokeErr != nil {
+		rpcErr, ok := tgerr.As(invokeErr)
+		if !ok {
+			return invokeErr
+		}
+		rec.Error = &RecordError{Code: rpcErr.Code, Message: rpcErr.Message}
+	} else {
+		result, ok := output.(bin.Encoder)
+		if !ok {
+			return errors.Errorf("can't record output %T", output)
+		}
+		b.Reset()
+		if err := result.Encode(&b); err != nil {
+			return errors.Wrap(err, "encode response")
+		}
+		rec.Response = b.Copy()
+	}
+
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	if err := r.enc.Encode(rec); err != nil {
+		return errors.Wrap(err, "write record")
+	}
+	return invokeErr
+}
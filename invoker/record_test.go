@@ -0,0 +1,87 @@
+package invoker
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/gotd/td/bin"
+	"github.com/gotd/td/telegram"
+	"github.com/gotd/td/tg"
+	"github.com/gotd/td/tgerr"
+)
+
+func TestRecordReplay(t *testing.T) {
+	a := require.New(t)
+	ctx := context.Background()
+
+	var out bytes.Buffer
+	recorder := NewRecorder(telegram.InvokeFunc(func(ctx context.Context, input bin.Encoder, output bin.Decoder) error {
+		switch req := input.(type) {
+		case *tg.HelpGetConfigRequest:
+			*output.(*tg.Config) = tg.Config{ThisDC: 2}
+		case *tg.ContactsResolveUsernameRequest:
+			if req.Username != "gotd" {
+				return tgerr.New(400, "USERNAME_NOT_OCCUPIED")
+			}
+			*output.(*tg.ContactsResolvedPeer) = tg.ContactsResolvedPeer{
+				Peer:  &tg.PeerUser{UserID: 10},
+				Chats: []tg.ChatClass{},
+				Users: []tg.UserClass{&tg.User{ID: 10, Username: "gotd"}},
+			}
+		}
+		return nil
+	}), &out)
+
+	raw := tg.NewClient(recorder)
+	_, err := raw.HelpGetConfig(ctx)
+	a.NoError(err)
+	_, err = raw.ContactsResolveUsername(ctx, "gotd")
+	a.NoError(err)
+	_, err = raw.ContactsResolveUsername(ctx, "unknown")
+	a.True(tgerr.Is(err, "USERNAME_NOT_OCCUPIED"))
+
+	replay, err := NewReplay(&out)
+	a.NoError(err)
+	a.Len(replay.Unused(), 3)
+	raw = tg.NewClient(replay)
+
+	// Calls are matched by request, not by order.
+	_, err = raw.ContactsResolveUsername(ctx, "unknown")
+	a.True(tgerr.Is(err, "USERNAME_NOT_OCCUPIED"))
+	resolved, err := raw.ContactsResolveUsername(ctx, "gotd")
+	a.NoError(err)
+	a.Equal("gotd", resolved.Users[0].(*tg.User).Username)
+	cfg, err := raw.HelpGetConfig(ctx)
+	a.NoError(err)
+	a.Equal(2, cfg.ThisDC)
+	a.Empty(replay.Unused())
+
+	_, err = raw.HelpGetConfig(ctx)
+	a.ErrorIs(err, ErrNotRecorded)
+}
+
+func TestReplayMethodFallback(t *testing.T) {
+	a := require.New(t)
+	ctx := context.Background()
+
+	var out bytes.Buffer
+	raw := tg.NewClient(NewRecorder(telegram.InvokeFunc(func(ctx context.Context, input bin.Encoder, output bin.Decoder) error {
+		*output.(*tg.UpdatesBox) = tg.UpdatesBox{Updates: &tg.UpdateShortSentMessage{ID: 1}}
+		return nil
+	}), &out))
+	_, err := raw.MessagesSendMessage(ctx, &tg.MessagesSendMessageRequest{
+		Peer: &tg.InputPeerSelf{}, Message: "hi", RandomID: 1,
+	})
+	a.NoError(err)
+
+	replay, err := NewReplay(&out)
+	a.NoError(err)
+	u, err := tg.NewClient(replay).MessagesSendMessage(ctx, &tg.MessagesSendMessageRequest{
+		Peer: &tg.InputPeerSelf{}, Message: "hi", RandomID: 2,
+	})
+	a.NoError(err)
+	a.Equal(1, u.(*tg.UpdateShortSentMessage).ID)
+}
@@ -0,0 +1,111 @@
+package invoker
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"sync"
+
+	"github.com/go-faster/errors"
+
+	"github.com/gotd/td/bin"
+	"github.com/gotd/td/tg"
+	"github.com/gotd/td/tgerr"
+)
+
+// ErrNotRecorded is returned by Replay, if there is no record of call.
+var ErrNotRecorded = errors.New("call is not recorded")
+
+// Replay is invoker which serves calls recorded by Recorder.
+//
+// Call is matched to record with equal request. If there is no such
+// record, e.g. request contains random ID, call is matched to record of
+// the same method. Records are matched in recorded order and every record
+// is served once.
+type Replay struct {
+	mux     sync.Mutex
+	records []*Record
+	used    []bool
+}
+
+var _ tg.Invoker = (*Replay)(nil)
+
+// NewReplay reads records from given reader and creates new Replay.
+func NewReplay(in io.Reader) (*Replay, error) {
+	r := &Replay{}
+	dec := json.NewDecoder(in)
+	for {
+		var rec Record
+		if err := dec.Decode(&rec); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, errors.Wrapf(err, "decode record %d", len(r.records))
+		}
+		r.records = append(r.records, &rec)
+	}
+	r.used = make([]bool, len(r.records))
+	return r, nil
+}
+
+// Unused returns records which are not served yet.
+func (r *Replay) Unused() []Record {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	var unused []Record
+	for i, rec := range r.records {
+		if !r.used[i] {
+			unused = append(unused, *rec)
+		}
+	}
+	return unused
+}
+
+func (r *Replay) find(method string, request []byte) (*Record, bool) {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	match := -1
+	for i, rec := range r.records {
+		if r.used[i] || rec.Method != method {
+			continue
+		}
+		if string(rec.Request) == string(request) {
+			match = i
+			break
+		}
+		if match < 0 {
+			match = i
+		}
+	}
+	if match < 0 {
+		return nil, false
+	}
+	r.used[match] = true
+	return r.records[match], true
+}
+
+// Invoke implements tg.Invoker.
+func (r *Replay) Invoke(ctx context.Context, input bin.Encoder, output bin.Decoder) error {
+	var b bin.Buffer
+	if err := input.Encode(&b); err != nil {
+		return errors.Wrap(err, "encode request")
+	}
+	var method string
+	if obj, ok := input.(interface{ TypeName() string }); ok {
+		method = obj.TypeName()
+	}
+
+	rec, ok := r.find(method, b.Buf)
+	if !ok {
+		return errors.Wrapf(ErrNotRecorded, "replay %s", method)
+	}
+	if rec.Error != nil {
+		return tgerr.New(rec.Error.Code, rec.Error.Message)
+	}
+	if err := output.Decode(&bin.Buffer{Buf: rec.Response}); err != nil {
+		return errors.Wrapf(err, "decode %s response", method)
+	}
+	return nil
+}
@@ -0,0 +1,3 @@
+// Package joinreq queues chat join requests and approves or declines them
+// using pluggable policies.
+package joinreq
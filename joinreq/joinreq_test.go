@@ -0,0 +1,196 @@
+package joinreq
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	pebbledb "github.com/cockroachdb/pebble"
+	"github.com/cockroachdb/pebble/vfs"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/time/rate"
+
+	"github.com/gotd/td/telegram/query/dialogs"
+	"github.com/gotd/td/tg"
+	"github.com/gotd/td/tgerr"
+	"github.com/gotd/td/tgmock"
+)
+
+func TestQueue(t *testing.T) {
+	db, err := pebbledb.Open("pebble.db", &pebbledb.Options{
+		FS: vfs.NewMem(),
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = db.Close()
+	})
+
+	for name, q := range map[string]Queue{
+		"Mem":    NewMemQueue(),
+		"Pebble": NewPebbleQueue(db),
+	} {
+		t.Run(name, func(t *testing.T) {
+			a := require.New(t)
+			ctx := context.Background()
+			chat := dialogs.DialogKey{Kind: dialogs.Channel, ID: 10, AccessHash: 10}
+			now := time.Unix(1700000000, 0).UTC()
+
+			a.NoError(q.Push(ctx, Request{Chat: chat, UserID: 2, Date: now.Add(time.Second)}))
+			a.NoError(q.Push(ctx, Request{Chat: chat, UserID: 1, Date: now.Add(2 * time.Second)}))
+			// Duplicate replaces queued request.
+			a.NoError(q.Push(ctx, Request{Chat: chat, UserID: 2, Date: now, About: "again"}))
+
+			r, err := q.Pending(ctx, nil, 10)
+			a.NoError(err)
+			a.Len(r, 2)
+			a.Equal(int64(2), r[0].UserID)
+			a.Equal("again", r[0].About)
+			a.True(now.Equal(r[0].Date))
+
+			r, err = q.Pending(ctx, nil, 1)
+			a.NoError(err)
+			a.Len(r, 1)
+
+			after, err := q.Pending(ctx, &r[0], 10)
+			a.NoError(err)
+			a.Len(after, 1)
+			a.Equal(int64(1), after[0].UserID)
+
+			a.NoError(q.Remove(ctx, r[0]))
+			r, err = q.Pending(ctx, nil, 10)
+			a.NoError(err)
+			a.Len(r, 1)
+			a.Equal(int64(1), r[0].UserID)
+		})
+	}
+}
+
+func TestPolicies(t *testing.T) {
+	a := require.New(t)
+	ctx := context.Background()
+	now := time.Unix(1700000000, 0)
+
+	d, err := Allowlist(1).Decide(ctx, Request{UserID: 1})
+	a.NoError(err)
+	a.Equal(Approve, d)
+	d, err = Allowlist(1).Decide(ctx, Request{UserID: 2})
+	a.NoError(err)
+	a.Equal(Undecided, d)
+
+	age := AccountAge(func(userID int64) (time.Time, bool) {
+		return now.Add(-time.Duration(userID) * time.Hour), userID > 0
+	}, 24*time.Hour)
+	for userID, expected := range map[int64]Decision{
+		0:  Undecided,
+		1:  Decline,
+		48: Undecided,
+	} {
+		d, err := age.Decide(ctx, Request{UserID: userID, Date: now})
+		a.NoError(err)
+		a.Equal(expected, d, userID)
+	}
+}
+
+func TestProcessor(t *testing.T) {
+	a := require.New(t)
+	ctx := context.Background()
+	mock := tgmock.New(t)
+	queue := NewMemQueue()
+
+	captcha := Captcha(func(ctx context.Context, r Request) (bool, bool, error) {
+		return false, r.UserID == 2, nil
+	})
+	p := NewProcessor(tg.NewClient(mock), queue, Allowlist(1), captcha).
+		WithRateLimit(rate.Inf, 1)
+
+	e := tg.Entities{
+		Users: map[int64]*tg.User{
+			1: {ID: 1, AccessHash: 11},
+			2: {ID: 2, AccessHash: 22},
+			3: {ID: 3, AccessHash: 33},
+		},
+		Channels: map[int64]*tg.Channel{
+			10: {ID: 10, AccessHash: 100},
+		},
+	}
+	for i, userID := range []int64{1, 2, 3} {
+		a.NoError(p.Handle(ctx, e, &tg.UpdateBotChatInviteRequester{
+			Peer:   &tg.PeerChannel{ChannelID: 10},
+			Date:   1700000000 + i,
+			UserID: userID,
+			Invite: &tg.ChatInvitePublicJoinRequests{},
+		}))
+	}
+
+	peer := &tg.InputPeerChannel{ChannelID: 10, AccessHash: 100}
+	mock.ExpectCall(&tg.MessagesHideChatJoinRequestRequest{
+		Approved: true,
+		Peer:     peer,
+		UserID:   &tg.InputUser{UserID: 1, AccessHash: 11},
+	}).ThenResult(&tg.Updates{})
+	mock.ExpectCall(&tg.MessagesHideChatJoinRequestRequest{
+		Peer:   peer,
+		UserID: &tg.InputUser{UserID: 2, AccessHash: 22},
+	}).ThenResult(&tg.Updates{})
+
+	r, err := p.Process(ctx)
+	a.NoError(err)
+	a.Equal(Result{Approved: 1, Declined: 1, Pending: 1}, r)
+
+	// Request already handled by other admin is removed.
+	mock.ExpectCall(&tg.MessagesHideChatJoinRequestRequest{
+		Peer:   peer,
+		UserID: &tg.InputUser{UserID: 3, AccessHash: 33},
+	}).ThenRPCErr(tgerr.New(400, "HIDE_REQUESTER_MISSING"))
+	r, err = p.WithFallback(Decline).Process(ctx)
+	a.NoError(err)
+	a.Equal(Result{Declined: 1}, r)
+
+	pending, err := queue.Pending(ctx, nil, 10)
+	a.NoError(err)
+	a.Empty(pending)
+}
+
+func TestProcessorCursor(t *testing.T) {
+	a := require.New(t)
+	ctx := context.Background()
+	mock := tgmock.New(t)
+	queue := NewMemQueue()
+
+	// Only the newest request is decidable.
+	p := NewProcessor(tg.NewClient(mock), queue, Allowlist(5)).
+		WithRateLimit(rate.Inf, 1).
+		WithBatchSize(2)
+
+	chat := dialogs.DialogKey{Kind: dialogs.Channel, ID: 10, AccessHash: 100}
+	now := time.Unix(1700000000, 0)
+	for i := int64(1); i <= 5; i++ {
+		a.NoError(queue.Push(ctx, Request{
+			Chat:           chat,
+			UserID:         i,
+			UserAccessHash: i,
+			Date:           now.Add(time.Duration(i) * time.Second),
+		}))
+	}
+
+	r, err := p.Process(ctx)
+	a.NoError(err)
+	a.Equal(Result{Pending: 2}, r)
+	r, err = p.Process(ctx)
+	a.NoError(err)
+	a.Equal(Result{Pending: 2}, r)
+
+	mock.ExpectCall(&tg.MessagesHideChatJoinRequestRequest{
+		Approved: true,
+		Peer:     &tg.InputPeerChannel{ChannelID: 10, AccessHash: 100},
+		UserID:   &tg.InputUser{UserID: 5, AccessHash: 5},
+	}).ThenResult(&tg.Updates{})
+	r, err = p.Process(ctx)
+	a.NoError(err)
+	a.Equal(Result{Approved: 1}, r)
+
+	// Queue is processed from the beginning again.
+	r, err = p.Process(ctx)
+	a.NoError(err)
+	a.Equal(Result{Pending: 2}, r)
+}
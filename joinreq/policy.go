@@ -0,0 +1,99 @@
+package joinreq
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Decision is a policy decision about join request.
+type Decision int
+
+const (
+	// Undecided leaves decision to the next policy.
+	Undecided Decision = iota
+	// Approve approves join request.
+	Approve
+	// Decline declines join request.
+	Decline
+)
+
+// String implements fmt.Stringer.
+func (d Decision) String() string {
+	switch d {
+	case Undecided:
+		return "undecided"
+	case Approve:
+		return "approve"
+	case Decline:
+		return "decline"
+	default:
+		return fmt.Sprintf("Decision(%d)", int(d))
+	}
+}
+
+// Policy decides whether to approve join request.
+type Policy interface {
+	Decide(ctx context.Context, r Request) (Decision, error)
+}
+
+// PolicyFunc is a functional Policy.
+type PolicyFunc func(ctx context.Context, r Request) (Decision, error)
+
+// Decide implements Policy.
+func (f PolicyFunc) Decide(ctx context.Context, r Request) (Decision, error) {
+	return f(ctx, r)
+}
+
+// Allowlist approves requests of given users.
+func Allowlist(userIDs ...int64) Policy {
+	allowed := make(map[int64]struct{}, len(userIDs))
+	for _, id := range userIDs {
+		allowed[id] = struct{}{}
+	}
+	return PolicyFunc(func(ctx context.Context, r Request) (Decision, error) {
+		if _, ok := allowed[r.UserID]; ok {
+			return Approve, nil
+		}
+		return Undecided, nil
+	})
+}
+
+// AccountAge declines requests of accounts younger than given age at
+// the moment of request.
+//
+// Telegram does not expose account creation date, so it is estimated by
+// given function, e.g. by interpolating known user ID ranges. Estimate
+// returns false if creation date is unknown, leaving request undecided.
+func AccountAge(estimate func(userID int64) (time.Time, bool), minAge time.Duration) Policy {
+	return PolicyFunc(func(ctx context.Context, r Request) (Decision, error) {
+		created, ok := estimate(r.UserID)
+		if !ok {
+			return Undecided, nil
+		}
+		if r.Date.Sub(created) < minAge {
+			return Decline, nil
+		}
+		return Undecided, nil
+	})
+}
+
+// Captcha decides using captcha result of requester.
+//
+// Result returns false if captcha is not solved or failed yet, leaving
+// request undecided and queued.
+func Captcha(result func(ctx context.Context, r Request) (solved, ok bool, err error)) Policy {
+	return PolicyFunc(func(ctx context.Context, r Request) (Decision, error) {
+		solved, ok, err := result(ctx, r)
+		switch {
+		case err != nil:
+			return Undecided, err
+		case !ok:
+			return Undecided, nil
+		case solved:
+			return Approve, nil
+		default:
+			return Decline, nil
+		}
+	})
+}
@@ -0,0 +1,239 @@
+package joinreq
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/go-faster/errors"
+	"go.uber.org/zap"
+	"golang.org/x/time/rate"
+
+	"github.com/gotd/td/clock"
+	"github.com/gotd/td/telegram/query/dialogs"
+	"github.com/gotd/td/tg"
+	"github.com/gotd/td/tgerr"
+)
+
+// Result is a result of single Process call.
+type Result struct {
+	Approved int
+	Declined int
+	// Pending is a count of processed requests left undecided.
+	Pending int
+}
+
+// Processor queues join requests and processes them in batches.
+//
+// Policies are applied in order, the first decision other than Undecided
+// wins. Requests left undecided by all policies stay queued until the
+// fallback decision is set. Every batch continues after the last request
+// of previous one, so undecided requests do not block newer ones.
+type Processor struct {
+	api      *tg.Client
+	queue    Queue
+	policies []Policy
+
+	clock     clock.Clock
+	log       *zap.Logger
+	limiter   *rate.Limiter
+	batchSize int
+	interval  time.Duration
+	fallback  Decision
+
+	// cursor is a last request of previous batch, nil if queue is
+	// processed from the beginning.
+	cursor    *Request
+	cursorMux sync.Mutex
+}
+
+// NewProcessor creates new Processor.
+//
+// By default, at most 5 requests per second are approved or declined,
+// at most 100 requests are processed per batch.
+func NewProcessor(api *tg.Client, queue Queue, policies ...Policy) *Processor {
+	return &Processor{
+		api:       api,
+		queue:     queue,
+		policies:  policies,
+		clock:     clock.System,
+		log:       zap.NewNop(),
+		limiter:   rate.NewLimiter(5, 5),
+		batchSize: 100,
+		interval:  10 * time.Second,
+	}
+}
+
+// WithClock sets clock to use. Default is to use system clock.
+func (p *Processor) WithClock(c clock.Clock) *Processor {
+	p.clock = c
+	return p
+}
+
+// WithLog sets logger to use. Default is no-op logger.
+func (p *Processor) WithLog(log *zap.Logger) *Processor {
+	p.log = log
+	return p
+}
+
+// WithRateLimit sets rate limit of approve and decline requests.
+func (p *Processor) WithRateLimit(r rate.Limit, burst int) *Processor {
+	p.limiter = rate.NewLimiter(r, burst)
+	return p
+}
+
+// WithBatchSize sets maximum count of requests processed per batch.
+// Default is 100.
+func (p *Processor) WithBatchSize(size int) *Processor {
+	if size < 1 {
+		size = 1
+	}
+	p.batchSize = size
+	return p
+}
+
+// WithInterval sets interval between batches of Run. Default is 10 seconds.
+func (p *Processor) WithInterval(interval time.Duration) *Processor {
+	p.interval = interval
+	return p
+}
+
+// WithFallback sets decision for requests left undecided by all policies.
+// Default is Undecided, so such requests stay queued.
+func (p *Processor) WithFallback(d Decision) *Processor {
+	p.fallback = d
+	return p
+}
+
+// Handle queues join request from update.
+func (p *Processor) Handle(ctx context.Context, e tg.Entities, u *tg.UpdateBotChatInviteRequester) error {
+	r := Request{
+		UserID: u.UserID,
+		Date:   time.Unix(int64(u.Date), 0),
+		About:  u.About,
+	}
+	if user, ok := e.Users[u.UserID]; ok {
+		r.UserAccessHash = user.AccessHash
+	}
+	if invite, ok := u.Invite.(*tg.ChatInviteExported); ok {
+		r.Link = invite.Link
+	}
+
+	switch peer := u.Peer.(type) {
+	case *tg.PeerChat:
+		r.Chat = dialogs.DialogKey{Kind: dialogs.Chat, ID: peer.ChatID}
+	case *tg.PeerChannel:
+		r.Chat = dialogs.DialogKey{Kind: dialogs.Channel, ID: peer.ChannelID}
+		if ch, ok := e.Channels[peer.ChannelID]; ok {
+			r.Chat.AccessHash = ch.AccessHash
+		}
+	default:
+		return errors.Errorf("unexpected peer type %T", u.Peer)
+	}
+
+	if err := p.queue.Push(ctx, r); err != nil {
+		return errors.Errorf("push: %w", err)
+	}
+	return nil
+}
+
+// Register registers join request handler in given dispatcher.
+func (p *Processor) Register(d tg.UpdateDispatcher) {
+	d.OnBotChatInviteRequester(p.Handle)
+}
+
+func (p *Processor) decide(ctx context.Context, r Request) (Decision, error) {
+	for _, policy := range p.policies {
+		d, err := policy.Decide(ctx, r)
+		if err != nil {
+			return Undecided, err
+		}
+		if d != Undecided {
+			return d, nil
+		}
+	}
+	return p.fallback, nil
+}
+
+// Process processes single batch of queued requests, starting after the
+// last request of previous batch. Queue is processed from the beginning
+// again after the last batch.
+//
+// Failed policies leave request queued and are logged. Process stops on
+// first API error and returns result so far.
+func (p *Processor) Process(ctx context.Context) (Result, error) {
+	var result Result
+
+	p.cursorMux.Lock()
+	defer p.cursorMux.Unlock()
+
+	requests, err := p.queue.Pending(ctx, p.cursor, p.batchSize)
+	if err != nil {
+		return result, errors.Errorf("pending: %w", err)
+	}
+	if len(requests) < p.batchSize {
+		p.cursor = nil
+	} else {
+		last := requests[len(requests)-1]
+		p.cursor = &last
+	}
+
+	for _, r := range requests {
+		d, err := p.decide(ctx, r)
+		if err != nil {
+			p.log.Warn("Decide join request",
+				zap.Int64("user_id", r.UserID),
+				zap.Error(err),
+			)
+		}
+		if d == Undecided {
+			result.Pending++
+			continue
+		}
+
+		if err := p.limiter.Wait(ctx); err != nil {
+			return result, err
+		}
+		if _, err := p.api.MessagesHideChatJoinRequest(ctx, &tg.MessagesHideChatJoinRequestRequest{
+			Approved: d == Approve,
+			Peer:     r.InputPeer(),
+			UserID:   r.InputUser(),
+		}); err != nil && !tgerr.Is(err, "HIDE_REQUESTER_MISSING") {
+			return result, errors.Errorf("%s %d: %w", d, r.UserID, err)
+		}
+		if err := p.queue.Remove(ctx, r); err != nil {
+			return result, errors.Errorf("remove: %w", err)
+		}
+
+		if d == Approve {
+			result.Approved++
+		} else {
+			result.Declined++
+		}
+	}
+
+	return result, nil
+}
+
+// Run processes queued requests every interval until context is done.
+//
+// Process errors are logged and do not stop Run.
+func (p *Processor) Run(ctx context.Context) error {
+	ticker := p.clock.Ticker(p.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C():
+		}
+
+		if _, err := p.Process(ctx); err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			p.log.Warn("Process join requests", zap.Error(err))
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package joinreq
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gotd/td/telegram/query/dialogs"
+	"github.com/gotd/td/tg"
+)
+
+// Request is a queued chat join request.
+type Request struct {
+	// Chat is a key of requested chat.
+	Chat           dialogs.DialogKey `json:"chat"`
+	UserID         int64             `json:"user_id"`
+	UserAccessHash int64             `json:"user_access_hash"`
+	Date           time.Time         `json:"date"`
+	// About is a user bio.
+	About string `json:"about,omitempty"`
+	// Link is an invite link used by user, if any.
+	Link string `json:"link,omitempty"`
+}
+
+// InputPeer returns input peer of requested chat.
+func (r Request) InputPeer() tg.InputPeerClass {
+	switch r.Chat.Kind {
+	case dialogs.Chat:
+		return &tg.InputPeerChat{ChatID: r.Chat.ID}
+	case dialogs.Channel:
+		return &tg.InputPeerChannel{ChannelID: r.Chat.ID, AccessHash: r.Chat.AccessHash}
+	default:
+		return &tg.InputPeerUser{UserID: r.Chat.ID, AccessHash: r.Chat.AccessHash}
+	}
+}
+
+// InputUser returns input user of requester.
+func (r Request) InputUser() tg.InputUserClass {
+	return &tg.InputUser{UserID: r.UserID, AccessHash: r.UserAccessHash}
+}
+
+type requestKey struct {
+	kind   dialogs.PeerKind
+	chatID int64
+	userID int64
+}
+
+func (r Request) key() requestKey {
+	return requestKey{kind: r.Chat.Kind, chatID: r.Chat.ID, userID: r.UserID}
+}
+
+// Queue is a join request queue.
+//
+// Queue should deduplicate requests: request of the same user to the same
+// chat replaces queued one.
+//
+// Requests are ordered by date, then by chat and user, see Request.Before.
+type Queue interface {
+	// Push adds request to the queue.
+	Push(ctx context.Context, r Request) error
+	// Pending returns at most n oldest queued requests. If after is not
+	// nil, only requests ordered after it are returned.
+	Pending(ctx context.Context, after *Request, n int) ([]Request, error)
+	// Remove removes request from the queue.
+	Remove(ctx context.Context, r Request) error
+}
+
+var _ Queue = (*MemQueue)(nil)
+
+// MemQueue is an in-memory Queue. It is not durable and intended for
+// tests and single-process bots which can lose pending requests on restart.
+type MemQueue struct {
+	mux      sync.Mutex
+	requests map[requestKey]Request
+}
+
+// NewMemQueue creates new MemQueue.
+func NewMemQueue() *MemQueue {
+	return &MemQueue{
+		requests: map[requestKey]Request{},
+	}
+}
+
+// Push implements Queue.
+func (q *MemQueue) Push(ctx context.Context, r Request) error {
+	q.mux.Lock()
+	defer q.mux.Unlock()
+
+	q.requests[r.key()] = r
+	return nil
+}
+
+// Pending implements Queue.
+func (q *MemQueue) Pending(ctx context.Context, after *Request, n int) ([]Request, error) {
+	q.mux.Lock()
+	r := make([]Request, 0, len(q.requests))
+	for _, req := range q.requests {
+		r = append(r, req)
+	}
+	q.mux.Unlock()
+
+	return pendingAfter(r, after, n), nil
+}
+
+// Remove implements Queue.
+func (q *MemQueue) Remove(ctx context.Context, r Request) error {
+	q.mux.Lock()
+	defer q.mux.Unlock()
+
+	delete(q.requests, r.key())
+	return nil
+}
+
+// Before reports whether r is ordered before other in Queue.
+func (r Request) Before(other Request) bool {
+	if !r.Date.Equal(other.Date) {
+		return r.Date.Before(other.Date)
+	}
+	a, b := r.key(), other.key()
+	if a.kind != b.kind {
+		return a.kind < b.kind
+	}
+	if a.chatID != b.chatID {
+		return a.chatID < b.chatID
+	}
+	return a.userID < b.userID
+}
+
+// pendingAfter sorts given requests and returns at most n of them, ordered
+// after given request, if any.
+func pendingAfter(r []Request, after *Request, n int) []Request {
+	sort.Slice(r, func(i, j int) bool {
+		return r[i].Before(r[j])
+	})
+	if after != nil {
+		i := sort.Search(len(r), func(i int) bool {
+			return after.Before(r[i])
+		})
+		r = r[i:]
+	}
+	if len(r) > n {
+		r = r[:n]
+	}
+	return r
+}
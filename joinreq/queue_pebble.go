@@ -0,0 +1,98 @@
+package joinreq
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+
+	"github.com/cockroachdb/pebble"
+	"github.com/go-faster/errors"
+	"go.uber.org/multierr"
+)
+
+// pebblePrefix is a key prefix of queued requests.
+//
+// Keys prefixed by "_" are skipped by peer storage, so queue can share
+// database with it.
+const pebblePrefix = "_joinreq:"
+
+var _ Queue = PebbleQueue{}
+
+// PebbleQueue is a durable Queue stored in pebble database.
+type PebbleQueue struct {
+	db        *pebble.DB
+	writeOpts *pebble.WriteOptions
+}
+
+// NewPebbleQueue creates new PebbleQueue.
+func NewPebbleQueue(db *pebble.DB) PebbleQueue {
+	return PebbleQueue{
+		db:        db,
+		writeOpts: pebble.Sync,
+	}
+}
+
+func pebbleKey(r Request) []byte {
+	k := []byte(pebblePrefix)
+	k = strconv.AppendInt(k, int64(r.Chat.Kind), 10)
+	k = append(k, '_')
+	k = strconv.AppendInt(k, r.Chat.ID, 10)
+	k = append(k, ':')
+	k = strconv.AppendInt(k, r.UserID, 10)
+	return k
+}
+
+// Push implements Queue.
+func (q PebbleQueue) Push(ctx context.Context, r Request) error {
+	data, err := json.Marshal(r)
+	if err != nil {
+		return errors.Errorf("marshal: %w", err)
+	}
+	if err := q.db.Set(pebbleKey(r), data, q.writeOpts); err != nil {
+		return errors.Errorf("set: %w", err)
+	}
+	return nil
+}
+
+// Pending implements Queue.
+//
+// Pending reads all queued requests to return the oldest ones.
+func (q PebbleQueue) Pending(ctx context.Context, after *Request, n int) (_ []Request, rerr error) {
+	upper := []byte(pebblePrefix)
+	upper[len(upper)-1]++
+	iter, err := q.db.NewIter(&pebble.IterOptions{
+		LowerBound: []byte(pebblePrefix),
+		UpperBound: upper,
+	})
+	if err != nil {
+		return nil, errors.Errorf("new iter: %w", err)
+	}
+	defer func() {
+		multierr.AppendInto(&rerr, iter.Close())
+	}()
+
+	var r []Request
+	for iter.First(); iter.Valid(); iter.Next() {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		var req Request
+		if err := json.Unmarshal(iter.Value(), &req); err != nil {
+			return nil, errors.Errorf("unmarshal %q: %w", iter.Key(), err)
+		}
+		r = append(r, req)
+	}
+	if err := iter.Error(); err != nil {
+		return nil, errors.Errorf("iterate: %w", err)
+	}
+
+	return pendingAfter(r, after, n), nil
+}
+
+// Remove implements Queue.
+func (q PebbleQueue) Remove(ctx context.Context, r Request) error {
+	if err := q.db.Delete(pebbleKey(r), q.writeOpts); err != nil {
+		return errors.Errorf("delete: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,3 @@
+// Package linkpreview implements helpers to control and generate link
+// previews of messages.
+package linkpreview
@@ -0,0 +1,111 @@
+package linkpreview
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/gotd/td/telegram/message/styling"
+	"github.com/gotd/td/tg"
+	"github.com/gotd/td/tgmock"
+)
+
+func TestBuilder(t *testing.T) {
+	a := require.New(t)
+	ctx := context.Background()
+	mock := tgmock.New(t)
+	peer := &tg.InputPeerSelf{}
+	s := NewSender(tg.NewClient(mock)).WithRand(bytes.NewReader(make([]byte, 1024)))
+
+	mock.ExpectCall(&tg.MessagesSendMessageRequest{
+		NoWebpage: true,
+		Peer:      peer,
+		Message:   "https://gotd.dev",
+	}).ThenResult(&tg.Updates{})
+	_, err := s.To(peer).Disable().AboveText().Text(ctx, "https://gotd.dev")
+	a.NoError(err)
+
+	mock.ExpectCall(&tg.MessagesSendMessageRequest{
+		InvertMedia: true,
+		Peer:        peer,
+		Message:     "https://gotd.dev",
+	}).ThenResult(&tg.Updates{})
+	_, err = s.To(peer).AboveText().Text(ctx, "https://gotd.dev")
+	a.NoError(err)
+
+	mock.ExpectCall(&tg.MessagesSendMediaRequest{
+		InvertMedia: true,
+		Peer:        peer,
+		Media: &tg.InputMediaWebPage{
+			ForceLargeMedia: true,
+			Optional:        true,
+			URL:             "https://gotd.dev",
+		},
+		Message: "see https://gotd.dev",
+	}).ThenResult(&tg.Updates{})
+	_, err = s.To(peer).SmallMedia().LargeMedia().AboveText().Text(ctx, "see https://gotd.dev")
+	a.NoError(err)
+
+	mock.ExpectCall(&tg.MessagesSendMediaRequest{
+		Peer: peer,
+		Media: &tg.InputMediaWebPage{
+			ForceSmallMedia: true,
+			Optional:        true,
+			URL:             "https://example.com",
+		},
+		Message: "no link",
+	}).ThenResult(&tg.Updates{})
+	_, err = s.To(peer).SmallMedia().URL("https://example.com").Text(ctx, "no link")
+	a.NoError(err)
+
+	_, err = s.To(peer).SmallMedia().Text(ctx, "no link")
+	a.ErrorIs(err, ErrNoPreview)
+	a.True(mock.AllWereMet())
+}
+
+func TestFirstURL(t *testing.T) {
+	a := require.New(t)
+	a.Equal("https://gotd.dev", FirstURL("ссылка https://gotd.dev", nil))
+	a.Equal("", FirstURL("no link", nil))
+	a.Equal("https://text.url", FirstURL("link", []tg.MessageEntityClass{
+		&tg.MessageEntityBold{Offset: 0, Length: 4},
+		&tg.MessageEntityTextURL{Offset: 0, Length: 4, URL: "https://text.url"},
+	}))
+	a.Equal("t.me/gotd", FirstURL("🙂 t.me/gotd", []tg.MessageEntityClass{
+		&tg.MessageEntityURL{Offset: 3, Length: 9},
+	}))
+}
+
+func TestGenerate(t *testing.T) {
+	a := require.New(t)
+	ctx := context.Background()
+	mock := tgmock.New(t)
+	api := tg.NewClient(mock)
+
+	mock.ExpectCall(&tg.MessagesGetWebPagePreviewRequest{Message: "https://gotd.dev"}).
+		ThenResult(&tg.MessageMediaWebPage{Webpage: &tg.WebPage{
+			URL:           "https://gotd.dev",
+			Title:         "gotd",
+			HasLargeMedia: true,
+			Photo:         &tg.Photo{ID: 1},
+		}})
+	p, err := Generate(ctx, api, "https://gotd.dev")
+	a.NoError(err)
+	a.Equal("gotd", p.Title)
+	a.True(p.HasLargeMedia)
+	a.Equal(int64(1), p.Photo.ID)
+
+	mock.ExpectCall(&tg.MessagesGetWebPagePreviewRequest{Message: "https://slow.dev"}).
+		ThenResult(&tg.MessageMediaWebPage{Webpage: &tg.WebPagePending{URL: "https://slow.dev"}})
+	p, err = Generate(ctx, api, "https://slow.dev")
+	a.NoError(err)
+	a.True(p.Pending)
+
+	req := &tg.MessagesGetWebPagePreviewRequest{Message: "nothing"}
+	req.SetEntities([]tg.MessageEntityClass{&tg.MessageEntityBold{Offset: 0, Length: 7}})
+	mock.ExpectCall(req).ThenResult(&tg.MessageMediaEmpty{})
+	_, err = GenerateStyled(ctx, api, styling.Bold("nothing"))
+	a.ErrorIs(err, ErrNoPreview)
+}
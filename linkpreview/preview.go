@@ -0,0 +1,87 @@
+package linkpreview
+
+import (
+	"context"
+
+	"github.com/go-faster/errors"
+
+	"github.com/gotd/td/telegram/message/entity"
+	"github.com/gotd/td/telegram/message/styling"
+	"github.com/gotd/td/tg"
+)
+
+// ErrNoPreview means that link preview could not be generated for message.
+var ErrNoPreview = errors.New("no link preview")
+
+// Preview is a generated link preview.
+type Preview struct {
+	URL         string
+	DisplayURL  string
+	Type        string
+	SiteName    string
+	Title       string
+	Description string
+	// Photo is a preview photo, may be nil.
+	Photo *tg.Photo
+	// HasLargeMedia is true if preview could be shown with large media.
+	HasLargeMedia bool
+	// Pending is true if preview is still being generated by server.
+	// Only URL is set for pending previews.
+	Pending bool
+	// WebPage is a raw preview, nil if Pending.
+	WebPage *tg.WebPage
+}
+
+// Generate returns link preview which would be generated for given text.
+//
+// If text has no link or preview is empty, it returns ErrNoPreview error.
+func Generate(ctx context.Context, api *tg.Client, text string) (Preview, error) {
+	return generate(ctx, api, &tg.MessagesGetWebPagePreviewRequest{Message: text})
+}
+
+// GenerateStyled returns link preview which would be generated for given
+// styled text.
+func GenerateStyled(ctx context.Context, api *tg.Client, texts ...styling.StyledTextOption) (Preview, error) {
+	var b entity.Builder
+	if err := styling.Perform(&b, texts...); err != nil {
+		return Preview{}, errors.Wrap(err, "perform styling")
+	}
+	msg, entities := b.Complete()
+
+	req := &tg.MessagesGetWebPagePreviewRequest{Message: msg}
+	if len(entities) > 0 {
+		req.SetEntities(entities)
+	}
+	return generate(ctx, api, req)
+}
+
+func generate(ctx context.Context, api *tg.Client, req *tg.MessagesGetWebPagePreviewRequest) (Preview, error) {
+	media, err := api.MessagesGetWebPagePreview(ctx, req)
+	if err != nil {
+		return Preview{}, errors.Wrap(err, "get preview")
+	}
+	m, ok := media.(*tg.MessageMediaWebPage)
+	if !ok {
+		return Preview{}, ErrNoPreview
+	}
+
+	switch w := m.Webpage.(type) {
+	case *tg.WebPage:
+		p := Preview{
+			URL:           w.URL,
+			DisplayURL:    w.DisplayURL,
+			Type:          w.Type,
+			SiteName:      w.SiteName,
+			Title:         w.Title,
+			Description:   w.Description,
+			HasLargeMedia: w.HasLargeMedia,
+			WebPage:       w,
+		}
+		p.Photo, _ = w.Photo.(*tg.Photo)
+		return p, nil
+	case *tg.WebPagePending:
+		return Preview{URL: w.URL, Pending: true}, nil
+	default:
+		return Preview{}, ErrNoPreview
+	}
+}
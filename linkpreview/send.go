@@ -0,0 +1,173 @@
+package linkpreview
+
+import (
+	"context"
+	"crypto/rand"
+	"io"
+	"regexp"
+	"unicode/utf16"
+
+	"github.com/go-faster/errors"
+
+	"github.com/gotd/td/crypto"
+	"github.com/gotd/td/telegram/message/entity"
+	"github.com/gotd/td/telegram/message/styling"
+	"github.com/gotd/td/tg"
+)
+
+// Sender sends messages with link preview options.
+type Sender struct {
+	api  *tg.Client
+	rand io.Reader
+}
+
+// NewSender creates new Sender.
+func NewSender(api *tg.Client) *Sender {
+	return &Sender{
+		api:  api,
+		rand: rand.Reader,
+	}
+}
+
+// WithRand sets random source of message random IDs.
+func (s *Sender) WithRand(r io.Reader) *Sender {
+	s.rand = r
+	return s
+}
+
+// To creates new Builder of message to given peer.
+func (s *Sender) To(peer tg.InputPeerClass) *Builder {
+	return &Builder{
+		sender: s,
+		peer:   peer,
+	}
+}
+
+// Builder is a message builder with link preview options.
+type Builder struct {
+	sender *Sender
+	peer   tg.InputPeerClass
+
+	disable    bool
+	url        string
+	largeMedia bool
+	smallMedia bool
+	aboveText  bool
+}
+
+// Disable disables link preview.
+func (b *Builder) Disable() *Builder {
+	b.disable = true
+	return b
+}
+
+// URL sets URL to generate preview for, instead of first link of message.
+func (b *Builder) URL(url string) *Builder {
+	b.url = url
+	return b
+}
+
+// LargeMedia forces large media of preview.
+func (b *Builder) LargeMedia() *Builder {
+	b.largeMedia = true
+	b.smallMedia = false
+	return b
+}
+
+// SmallMedia forces small media of preview.
+func (b *Builder) SmallMedia() *Builder {
+	b.smallMedia = true
+	b.largeMedia = false
+	return b
+}
+
+// AboveText shows preview above message text.
+func (b *Builder) AboveText() *Builder {
+	b.aboveText = true
+	return b
+}
+
+// Text sends plain text message.
+func (b *Builder) Text(ctx context.Context, msg string) (tg.UpdatesClass, error) {
+	return b.send(ctx, msg, nil)
+}
+
+// StyledText sends styled text message.
+func (b *Builder) StyledText(ctx context.Context, texts ...styling.StyledTextOption) (tg.UpdatesClass, error) {
+	var eb entity.Builder
+	if err := styling.Perform(&eb, texts...); err != nil {
+		return nil, errors.Wrap(err, "perform styling")
+	}
+	msg, entities := eb.Complete()
+	return b.send(ctx, msg, entities)
+}
+
+func (b *Builder) send(ctx context.Context, msg string, entities []tg.MessageEntityClass) (tg.UpdatesClass, error) {
+	id, err := crypto.RandInt64(b.sender.rand)
+	if err != nil {
+		return nil, errors.Wrap(err, "generate random id")
+	}
+
+	// Preview options other than position require explicit web page media.
+	if b.disable || (b.url == "" && !b.largeMedia && !b.smallMedia) {
+		req := &tg.MessagesSendMessageRequest{
+			NoWebpage:   b.disable,
+			InvertMedia: b.aboveText && !b.disable,
+			Peer:        b.peer,
+			Message:     msg,
+			RandomID:    id,
+		}
+		if len(entities) > 0 {
+			req.SetEntities(entities)
+		}
+		return b.sender.api.MessagesSendMessage(ctx, req)
+	}
+
+	url := b.url
+	if url == "" {
+		url = FirstURL(msg, entities)
+	}
+	if url == "" {
+		return nil, errors.Wrap(ErrNoPreview, "no link in message")
+	}
+
+	req := &tg.MessagesSendMediaRequest{
+		InvertMedia: b.aboveText,
+		Peer:        b.peer,
+		Media: &tg.InputMediaWebPage{
+			ForceLargeMedia: b.largeMedia,
+			ForceSmallMedia: b.smallMedia,
+			Optional:        true,
+			URL:             url,
+		},
+		Message:  msg,
+		RandomID: id,
+	}
+	if len(entities) > 0 {
+		req.SetEntities(entities)
+	}
+	return b.sender.api.MessagesSendMedia(ctx, req)
+}
+
+var urlRegexp = regexp.MustCompile(`(?i)\bhttps?://\S+`)
+
+// FirstURL returns first link of message, using given entities, if any,
+// or searching for http(s) links in text otherwise.
+func FirstURL(msg string, entities []tg.MessageEntityClass) string {
+	var text []uint16
+	for _, e := range entities {
+		switch e := e.(type) {
+		case *tg.MessageEntityTextURL:
+			return e.URL
+		case *tg.MessageEntityURL:
+			if text == nil {
+				text = utf16.Encode([]rune(msg))
+			}
+			if e.Offset < 0 || e.Length <= 0 || e.Offset+e.Length > len(text) {
+				continue
+			}
+			return string(utf16.Decode(text[e.Offset : e.Offset+e.Length]))
+		}
+	}
+	return urlRegexp.FindString(msg)
+}
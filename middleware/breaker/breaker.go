@@ -0,0 +1,343 @@
+package breaker
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-faster/errors"
+
+	"github.com/gotd/td/bin"
+	"github.com/gotd/td/clock"
+	"github.com/gotd/td/telegram"
+	"github.com/gotd/td/tg"
+	"github.com/gotd/td/tgerr"
+
+	"github.com/gotd/contrib/middleware/retry"
+)
+
+// State is a circuit state.
+type State int
+
+const (
+	// StateClosed means that requests are passed.
+	StateClosed State = iota
+	// StateOpen means that requests fail fast.
+	StateOpen
+	// StateHalfOpen means that single probe request is passed.
+	StateHalfOpen
+)
+
+// String implements fmt.Stringer.
+func (s State) String() string {
+	switch s {
+	case StateClosed:
+		return "closed"
+	case StateOpen:
+		return "open"
+	case StateHalfOpen:
+		return "half-open"
+	default:
+		return "State(" + strconv.Itoa(int(s)) + ")"
+	}
+}
+
+// OpenError is returned by Breaker while circuit is open.
+type OpenError struct {
+	// Key is a circuit key, see WithKey.
+	Key string
+	// Until is a time when probe request is allowed.
+	Until time.Time
+}
+
+// Error implements error.
+func (e *OpenError) Error() string {
+	if e.Key == "" {
+		return fmt.Sprintf("circuit breaker is open until %s", e.Until.Format(time.RFC3339))
+	}
+	return fmt.Sprintf("circuit breaker %q is open until %s", e.Key, e.Until.Format(time.RFC3339))
+}
+
+// IsOpen reports whether given error is returned due to open circuit.
+func IsOpen(err error) bool {
+	var openErr *OpenError
+	return errors.As(err, &openErr)
+}
+
+// ByMethod is a key function which uses separate circuit per method.
+func ByMethod(ctx context.Context, input bin.Encoder) string {
+	if obj, ok := input.(interface{ TypeName() string }); ok {
+		return obj.TypeName()
+	}
+	return ""
+}
+
+// Failure is a default failure classifier. Context cancellation and
+// RPC errors, except transient ones, are not failures, because they do
+// not indicate server unavailability.
+func Failure(err error) bool {
+	if errors.Is(err, context.Canceled) {
+		return false
+	}
+	if _, ok := tgerr.As(err); ok {
+		return retry.Transient(err)
+	}
+	return true
+}
+
+type circuit struct {
+	state       State
+	windowStart time.Time
+	total       int
+	failures    int
+	openedAt    time.Time
+	probing     bool
+}
+
+type transition struct {
+	key      string
+	from, to State
+}
+
+// Breaker is a tg.Invoker that stops calling underlying invoker, if failure
+// ratio or latency exceeds threshold.
+//
+// After cooldown, single probe request is passed. If probe succeeds,
+// circuit is closed, otherwise it is opened again.
+//
+// Breaker uses single circuit by default. Use WithKey and ByMethod to
+// break per method. To break per DC, use separate Breaker for invoker of
+// every DC.
+type Breaker struct {
+	clock       clock.Clock
+	key         func(ctx context.Context, input bin.Encoder) string
+	ratio       float64
+	minRequests int
+	window      time.Duration
+	cooldown    time.Duration
+	slow        time.Duration
+	failureIf   func(err error) bool
+	onChange    func(key string, from, to State)
+
+	mux      sync.Mutex
+	circuits map[string]*circuit
+}
+
+// New creates new Breaker.
+func New() *Breaker {
+	return &Breaker{
+		clock:       clock.System,
+		key:         func(ctx context.Context, input bin.Encoder) string { return "" },
+		ratio:       0.5,
+		minRequests: 10,
+		window:      10 * time.Second,
+		cooldown:    30 * time.Second,
+		failureIf:   Failure,
+		onChange:    func(key string, from, to State) {},
+		circuits:    map[string]*circuit{},
+	}
+}
+
+// clone returns a copy of the Breaker.
+//
+// Circuits are counted with configured thresholds, so the copy starts with
+// its own closed circuits.
+func (b *Breaker) clone() *Breaker {
+	return &Breaker{
+		clock:       b.clock,
+		key:         b.key,
+		ratio:       b.ratio,
+		minRequests: b.minRequests,
+		window:      b.window,
+		cooldown:    b.cooldown,
+		slow:        b.slow,
+		failureIf:   b.failureIf,
+		onChange:    b.onChange,
+		circuits:    map[string]*circuit{},
+	}
+}
+
+// WithClock sets clock to use. Default is to use system clock.
+func (b *Breaker) WithClock(c clock.Clock) *Breaker {
+	b = b.clone()
+	b.clock = c
+	return b
+}
+
+// WithKey sets function which returns circuit key of request, e.g. ByMethod.
+func (b *Breaker) WithKey(f func(ctx context.Context, input bin.Encoder) string) *Breaker {
+	b = b.clone()
+	b.key = f
+	return b
+}
+
+// WithThreshold sets failure ratio which opens circuit, if at least
+// minRequests requests are made during window. Default is 0.5 and 10.
+func (b *Breaker) WithThreshold(ratio float64, minRequests int) *Breaker {
+	b = b.clone()
+	b.ratio = ratio
+	b.minRequests = minRequests
+	return b
+}
+
+// WithWindow sets duration of window to count failures. Default is 10s.
+func (b *Breaker) WithWindow(d time.Duration) *Breaker {
+	b = b.clone()
+	b.window = d
+	return b
+}
+
+// WithCooldown sets duration of open state before probe. Default is 30s.
+func (b *Breaker) WithCooldown(d time.Duration) *Breaker {
+	b = b.clone()
+	b.cooldown = d
+	return b
+}
+
+// WithSlowCall sets latency threshold, so slower successful requests are
+// counted as failures. Default is zero, which disables latency check.
+func (b *Breaker) WithSlowCall(d time.Duration) *Breaker {
+	b = b.clone()
+	b.slow = d
+	return b
+}
+
+// WithFailureIf sets function to decide whether error is a failure.
+// Default is Failure.
+func (b *Breaker) WithFailureIf(f func(err error) bool) *Breaker {
+	b = b.clone()
+	b.failureIf = f
+	return b
+}
+
+// OnStateChange sets callback called on every state change, e.g. to log
+// or alert.
+func (b *Breaker) OnStateChange(f func(key string, from, to State)) *Breaker {
+	b = b.clone()
+	b.onChange = f
+	return b
+}
+
+// State returns current state of circuit with given key.
+func (b *Breaker) State(key string) State {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+
+	c, ok := b.circuits[key]
+	if !ok {
+		return StateClosed
+	}
+	return c.state
+}
+
+func (b *Breaker) set(c *circuit, key string, to State, now time.Time) transition {
+	t := transition{key: key, from: c.state, to: to}
+	c.state = to
+	c.total, c.failures = 0, 0
+	c.windowStart = now
+	if to == StateOpen {
+		c.openedAt = now
+	}
+	return t
+}
+
+// allow reports whether request is allowed and whether it is a probe.
+func (b *Breaker) allow(key string, now time.Time) (probe bool, _ []transition, _ error) {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+
+	c, ok := b.circuits[key]
+	if !ok {
+		c = &circuit{windowStart: now}
+		b.circuits[key] = c
+	}
+
+	var transitions []transition
+	switch c.state {
+	case StateOpen:
+		until := c.openedAt.Add(b.cooldown)
+		if now.Before(until) {
+			return false, nil, &OpenError{Key: key, Until: until}
+		}
+		transitions = append(transitions, b.set(c, key, StateHalfOpen, now))
+		fallthrough
+	case StateHalfOpen:
+		if c.probing {
+			return false, transitions, &OpenError{Key: key, Until: now.Add(b.cooldown)}
+		}
+		c.probing = true
+		return true, transitions, nil
+	default:
+		if now.Sub(c.windowStart) >= b.window {
+			c.total, c.failures = 0, 0
+			c.windowStart = now
+		}
+		return false, nil, nil
+	}
+}
+
+func (b *Breaker) done(key string, probe, failed, canceled bool, now time.Time) []transition {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+
+	c := b.circuits[key]
+	if probe && canceled {
+		// Canceled probe tells nothing, allow next one.
+		c.probing = false
+		return nil
+	}
+	if probe {
+		c.probing = false
+		if failed {
+			return []transition{b.set(c, key, StateOpen, now)}
+		}
+		return []transition{b.set(c, key, StateClosed, now)}
+	}
+	if c.state != StateClosed {
+		// Request started before circuit was opened.
+		return nil
+	}
+
+	c.total++
+	if failed {
+		c.failures++
+	}
+	if c.total >= b.minRequests && float64(c.failures)/float64(c.total) >= b.ratio {
+		return []transition{b.set(c, key, StateOpen, now)}
+	}
+	return nil
+}
+
+func (b *Breaker) notify(transitions []transition) {
+	for _, t := range transitions {
+		b.onChange(t.key, t.from, t.to)
+	}
+}
+
+// Handle implements telegram.Middleware.
+func (b *Breaker) Handle(next tg.Invoker) telegram.InvokeFunc {
+	return func(ctx context.Context, input bin.Encoder, output bin.Decoder) error {
+		key := b.key(ctx, input)
+		start := b.clock.Now()
+
+		probe, transitions, err := b.allow(key, start)
+		b.notify(transitions)
+		if err != nil {
+			return err
+		}
+
+		err = next.Invoke(ctx, input, output)
+
+		now := b.clock.Now()
+		failed := b.slow > 0 && now.Sub(start) > b.slow
+		if err != nil {
+			failed = b.failureIf(err)
+		}
+		canceled := errors.Is(err, context.Canceled)
+		b.notify(b.done(key, probe, failed, canceled, now))
+
+		return err
+	}
+}
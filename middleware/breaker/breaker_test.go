@@ -0,0 +1,135 @@
+package breaker
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-faster/errors"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gotd/neo"
+	"github.com/gotd/td/bin"
+	"github.com/gotd/td/telegram"
+	"github.com/gotd/td/tg"
+	"github.com/gotd/td/tgerr"
+)
+
+func TestBreaker(t *testing.T) {
+	a := require.New(t)
+	ctx := context.Background()
+	clock := neo.NewTime(time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC))
+
+	var (
+		calls       int
+		invokeErr   error
+		transitions []State
+	)
+	next := telegram.InvokeFunc(func(ctx context.Context, input bin.Encoder, output bin.Decoder) error {
+		calls++
+		return invokeErr
+	})
+	b := New().
+		WithClock(clock).
+		WithThreshold(0.5, 4).
+		WithCooldown(time.Minute).
+		OnStateChange(func(key string, from, to State) {
+			transitions = append(transitions, to)
+		})
+	invoker := b.Handle(next)
+	req := &tg.HelpGetConfigRequest{}
+
+	// Client errors are not failures.
+	invokeErr = tgerr.New(400, "PEER_ID_INVALID")
+	for i := 0; i < 4; i++ {
+		a.Error(invoker.Invoke(ctx, req, nil))
+	}
+	a.Equal(StateClosed, b.State(""))
+
+	clock.Travel(10 * time.Second)
+	invokeErr = tgerr.New(500, "INTERNAL")
+	a.Error(invoker.Invoke(ctx, req, nil))
+	a.Error(invoker.Invoke(ctx, req, nil))
+	invokeErr = nil
+	a.NoError(invoker.Invoke(ctx, req, nil))
+	a.Equal(StateClosed, b.State(""))
+	invokeErr = errors.New("connection reset")
+	a.Error(invoker.Invoke(ctx, req, nil))
+	a.Equal(StateOpen, b.State(""))
+	a.Equal(8, calls)
+
+	// Fail fast.
+	err := invoker.Invoke(ctx, req, nil)
+	a.True(IsOpen(err))
+	var openErr *OpenError
+	a.ErrorAs(err, &openErr)
+	a.Equal(clock.Now().Add(time.Minute), openErr.Until)
+	a.Equal(8, calls)
+
+	// Failed probe.
+	clock.Travel(time.Minute)
+	a.Error(invoker.Invoke(ctx, req, nil))
+	a.Equal(9, calls)
+	a.Equal(StateOpen, b.State(""))
+	a.True(IsOpen(invoker.Invoke(ctx, req, nil)))
+
+	// Successful probe.
+	clock.Travel(time.Minute)
+	invokeErr = nil
+	a.NoError(invoker.Invoke(ctx, req, nil))
+	a.Equal(StateClosed, b.State(""))
+	a.NoError(invoker.Invoke(ctx, req, nil))
+	a.Equal(11, calls)
+
+	a.Equal([]State{StateOpen, StateHalfOpen, StateOpen, StateHalfOpen, StateClosed}, transitions)
+}
+
+func TestBreakerByMethod(t *testing.T) {
+	a := require.New(t)
+	ctx := context.Background()
+	b := New().WithKey(ByMethod).WithThreshold(1, 1)
+	invoker := b.Handle(telegram.InvokeFunc(func(ctx context.Context, input bin.Encoder, output bin.Decoder) error {
+		if _, ok := input.(*tg.HelpGetConfigRequest); ok {
+			return tgerr.New(-503, "Timeout")
+		}
+		return nil
+	}))
+
+	a.Error(invoker.Invoke(ctx, &tg.HelpGetConfigRequest{}, nil))
+	a.True(IsOpen(invoker.Invoke(ctx, &tg.HelpGetConfigRequest{}, nil)))
+	a.Equal(StateOpen, b.State("help.getConfig"))
+	a.NoError(invoker.Invoke(ctx, &tg.HelpGetNearestDCRequest{}, nil))
+}
+
+func TestBreakerSlowCall(t *testing.T) {
+	a := require.New(t)
+	ctx := context.Background()
+	clock := neo.NewTime(time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC))
+	b := New().WithClock(clock).WithThreshold(1, 1).WithSlowCall(time.Second)
+	invoker := b.Handle(telegram.InvokeFunc(func(ctx context.Context, input bin.Encoder, output bin.Decoder) error {
+		clock.Travel(2 * time.Second)
+		return nil
+	}))
+
+	a.NoError(invoker.Invoke(ctx, &tg.HelpGetConfigRequest{}, nil))
+	a.Equal(StateOpen, b.State(""))
+}
+
+func TestBreakerWith(t *testing.T) {
+	a := require.New(t)
+	ctx := context.Background()
+	base := New()
+	derived := base.WithThreshold(1, 1)
+	a.NotSame(base, derived)
+
+	fail := telegram.InvokeFunc(func(ctx context.Context, input bin.Encoder, output bin.Decoder) error {
+		return tgerr.New(-503, "Timeout")
+	})
+	a.Error(derived.Handle(fail).Invoke(ctx, &tg.HelpGetConfigRequest{}, nil))
+	a.Equal(StateOpen, derived.State(""))
+
+	// Base breaker keeps default threshold and own circuits.
+	a.Equal(StateClosed, base.State(""))
+	a.Error(base.Handle(fail).Invoke(ctx, &tg.HelpGetConfigRequest{}, nil))
+	a.Equal(StateClosed, base.State(""))
+}
@@ -0,0 +1,3 @@
+// Package breaker implements a tg.Invoker circuit breaker, which fails
+// fast while Telegram is unavailable.
+package breaker
@@ -0,0 +1,163 @@
+package cache
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+
+	"github.com/go-faster/errors"
+
+	"github.com/gotd/td/bin"
+	"github.com/gotd/td/telegram"
+	"github.com/gotd/td/tg"
+
+	"github.com/gotd/contrib/auth/kv"
+)
+
+// DefaultMethods is a list of methods cached by default.
+var DefaultMethods = []string{
+	"users.getUsers",
+	"users.getFullUser",
+	"channels.getChannels",
+	"channels.getFullChannel",
+	"messages.getChats",
+	"messages.getFullChat",
+}
+
+// Cache is a tg.Invoker that caches results of idempotent requests in
+// Storage.
+//
+// Cache key is a scope and a hash of encoded request, so requests with
+// equal parameters of one scope share result. Results depend on account
+// which sends request, e.g. access hashes are per account, so every
+// account must use its own scope. Only outputs which implement bin.Encoder are
+// cached, this is true for all result types of tg package.
+//
+// Storage errors do not fail requests, underlying invoker is called
+// instead. Use OnError to report them.
+type Cache struct {
+	storage Storage
+	scope   string
+	prefix  string
+	ttl     time.Duration
+	methods map[string]time.Duration
+	onError func(ctx context.Context, err error)
+}
+
+// New creates new Cache of DefaultMethods with given scope, e.g. ID of
+// bot or user account.
+func New(s Storage, scope string) *Cache {
+	c := &Cache{
+		storage: s,
+		scope:   scope,
+		prefix:  "tg:cache:",
+		ttl:     5 * time.Minute,
+		methods: map[string]time.Duration{},
+		onError: func(ctx context.Context, err error) {},
+	}
+	for _, m := range DefaultMethods {
+		c.methods[m] = 0
+	}
+	return c
+}
+
+// WithTTL sets default TTL of cached results. Default is 5 minutes.
+func (c *Cache) WithTTL(ttl time.Duration) *Cache {
+	c.ttl = ttl
+	return c
+}
+
+// WithMethod enables caching of given method, e.g. "messages.getHistory",
+// with given TTL. Zero TTL means default one.
+func (c *Cache) WithMethod(method string, ttl time.Duration) *Cache {
+	c.methods[method] = ttl
+	return c
+}
+
+// WithoutMethods disables caching of given methods.
+func (c *Cache) WithoutMethods(methods ...string) *Cache {
+	for _, m := range methods {
+		delete(c.methods, m)
+	}
+	return c
+}
+
+// WithPrefix sets prefix of storage keys. Default is "tg:cache:".
+func (c *Cache) WithPrefix(prefix string) *Cache {
+	c.prefix = prefix
+	return c
+}
+
+// OnError sets callback called on storage errors.
+func (c *Cache) OnError(f func(ctx context.Context, err error)) *Cache {
+	c.onError = f
+	return c
+}
+
+// cacheTTL returns TTL of given request, if it is cached.
+func (c *Cache) cacheTTL(input bin.Encoder) (time.Duration, bool) {
+	obj, ok := input.(interface{ TypeName() string })
+	if !ok {
+		return 0, false
+	}
+	ttl, ok := c.methods[obj.TypeName()]
+	if ttl == 0 {
+		ttl = c.ttl
+	}
+	return ttl, ok
+}
+
+func (c *Cache) key(input bin.Encoder) (string, error) {
+	var b bin.Buffer
+	if err := input.Encode(&b); err != nil {
+		return "", err
+	}
+	h := sha256.Sum256(b.Buf)
+	return c.prefix + c.scope + ":" + hex.EncodeToString(h[:]), nil
+}
+
+// Handle implements telegram.Middleware.
+func (c *Cache) Handle(next tg.Invoker) telegram.InvokeFunc {
+	return func(ctx context.Context, input bin.Encoder, output bin.Decoder) error {
+		result, ok := output.(bin.Encoder)
+		if !ok {
+			return next.Invoke(ctx, input, output)
+		}
+		ttl, ok := c.cacheTTL(input)
+		if !ok {
+			return next.Invoke(ctx, input, output)
+		}
+
+		key, err := c.key(input)
+		if err != nil {
+			return errors.Wrap(err, "encode request")
+		}
+
+		v, err := c.storage.Get(ctx, key)
+		switch {
+		case err == nil:
+			if err := output.Decode(&bin.Buffer{Buf: v}); err == nil {
+				return nil
+			}
+			// Cached value is corrupted or encoded by another layer.
+			c.onError(ctx, errors.Errorf("decode %q", key))
+		case !errors.Is(err, kv.ErrKeyNotFound):
+			c.onError(ctx, errors.Wrapf(err, "get %q", key))
+		}
+
+		if err := next.Invoke(ctx, input, output); err != nil {
+			return err
+		}
+
+		var b bin.Buffer
+		if err := result.Encode(&b); err != nil {
+			c.onError(ctx, errors.Wrapf(err, "encode %q", key))
+			return nil
+		}
+		if err := c.storage.Set(ctx, key, b.Buf, ttl); err != nil {
+			c.onError(ctx, errors.Wrapf(err, "set %q", key))
+		}
+		return nil
+	}
+}
@@ -0,0 +1,155 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/atomic"
+
+	"github.com/gotd/neo"
+	"github.com/gotd/td/bin"
+	"github.com/gotd/td/telegram"
+	"github.com/gotd/td/tg"
+
+	"github.com/gotd/contrib/auth/kv"
+)
+
+func TestCache(t *testing.T) {
+	a := require.New(t)
+	ctx := context.Background()
+	clock := neo.NewTime(time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC))
+
+	var calls atomic.Int64
+	storage := NewMemory(10).WithClock(clock)
+	raw := tg.NewClient(New(storage, "1").
+		WithMethod("users.getFullUser", time.Hour).
+		Handle(telegram.InvokeFunc(func(ctx context.Context, input bin.Encoder, output bin.Decoder) error {
+			calls.Inc()
+			switch req := input.(type) {
+			case *tg.UsersGetUsersRequest:
+				id := req.ID[0].(*tg.InputUser).UserID
+				*output.(*tg.UserClassVector) = tg.UserClassVector{
+					Elems: []tg.UserClass{&tg.User{ID: id, FirstName: "user"}},
+				}
+			case *tg.UsersGetFullUserRequest:
+				*output.(*tg.UsersUserFull) = tg.UsersUserFull{
+					FullUser: tg.UserFull{ID: 1, About: "about"},
+					Chats:    []tg.ChatClass{},
+					Users:    []tg.UserClass{},
+				}
+			case *tg.HelpGetConfigRequest:
+				*output.(*tg.Config) = tg.Config{ThisDC: 2}
+			}
+			return nil
+		})))
+
+	getUser := func(id int64) *tg.User {
+		u, err := raw.UsersGetUsers(ctx, []tg.InputUserClass{&tg.InputUser{UserID: id}})
+		a.NoError(err)
+		a.Len(u, 1)
+		return u[0].(*tg.User)
+	}
+
+	a.Equal("user", getUser(1).FirstName)
+	a.Equal(int64(1), getUser(1).ID)
+	a.Equal(int64(1), calls.Load())
+	a.Equal(int64(2), getUser(2).ID)
+	a.Equal(int64(2), calls.Load())
+
+	full, err := raw.UsersGetFullUser(ctx, &tg.InputUserSelf{})
+	a.NoError(err)
+	a.Equal("about", full.FullUser.About)
+	a.Equal(int64(3), calls.Load())
+
+	// Default TTL is expired, method one is not.
+	clock.Travel(10 * time.Minute)
+	getUser(1)
+	a.Equal(int64(4), calls.Load())
+	full, err = raw.UsersGetFullUser(ctx, &tg.InputUserSelf{})
+	a.NoError(err)
+	a.Equal("about", full.FullUser.About)
+	a.Equal(int64(4), calls.Load())
+
+	// Other methods are not cached.
+	for i := 0; i < 2; i++ {
+		cfg, err := raw.HelpGetConfig(ctx)
+		a.NoError(err)
+		a.Equal(2, cfg.ThisDC)
+	}
+	a.Equal(int64(6), calls.Load())
+
+	// Other accounts do not share results.
+	other := tg.NewClient(New(storage, "2").
+		Handle(telegram.InvokeFunc(func(ctx context.Context, input bin.Encoder, output bin.Decoder) error {
+			calls.Inc()
+			*output.(*tg.UserClassVector) = tg.UserClassVector{
+				Elems: []tg.UserClass{&tg.User{ID: 1, FirstName: "other"}},
+			}
+			return nil
+		})))
+	u, err := other.UsersGetUsers(ctx, []tg.InputUserClass{&tg.InputUser{UserID: 1}})
+	a.NoError(err)
+	a.Equal("other", u[0].(*tg.User).FirstName)
+	a.Equal(int64(7), calls.Load())
+}
+
+func TestMemory(t *testing.T) {
+	a := require.New(t)
+	ctx := context.Background()
+	clock := neo.NewTime(time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC))
+	m := NewMemory(2).WithClock(clock)
+
+	a.NoError(m.Set(ctx, "a", []byte("1"), time.Minute))
+	a.NoError(m.Set(ctx, "b", []byte("2"), time.Hour))
+	v, err := m.Get(ctx, "a")
+	a.NoError(err)
+	a.Equal([]byte("1"), v)
+
+	// "b" is least recently used.
+	a.NoError(m.Set(ctx, "c", []byte("3"), time.Hour))
+	_, err = m.Get(ctx, "b")
+	a.ErrorIs(err, kv.ErrKeyNotFound)
+
+	clock.Travel(time.Minute)
+	_, err = m.Get(ctx, "a")
+	a.ErrorIs(err, kv.ErrKeyNotFound)
+	v, err = m.Get(ctx, "c")
+	a.NoError(err)
+	a.Equal([]byte("3"), v)
+}
+
+type mapKV map[string]string
+
+func (m mapKV) Set(ctx context.Context, k, v string) error {
+	m[k] = v
+	return nil
+}
+
+func (m mapKV) Get(ctx context.Context, k string) (string, error) {
+	v, ok := m[k]
+	if !ok {
+		return "", kv.ErrKeyNotFound
+	}
+	return v, nil
+}
+
+func TestKV(t *testing.T) {
+	a := require.New(t)
+	ctx := context.Background()
+	clock := neo.NewTime(time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC))
+	s := NewKV(mapKV{}).WithClock(clock)
+
+	_, err := s.Get(ctx, "a")
+	a.ErrorIs(err, kv.ErrKeyNotFound)
+
+	a.NoError(s.Set(ctx, "a", []byte("1"), time.Minute))
+	v, err := s.Get(ctx, "a")
+	a.NoError(err)
+	a.Equal([]byte("1"), v)
+
+	clock.Travel(time.Minute)
+	_, err = s.Get(ctx, "a")
+	a.ErrorIs(err, kv.ErrKeyNotFound)
+}
@@ -0,0 +1,6 @@
+// Package cache implements a tg.Invoker that caches results of idempotent
+// requests.
+//
+// Cached results are scoped, see New, because they depend on account
+// which sends request.
+package cache
@@ -0,0 +1,149 @@
+package cache
+
+import (
+	"container/list"
+	"context"
+	"encoding/binary"
+	"sync"
+	"time"
+
+	"github.com/go-faster/errors"
+
+	"github.com/gotd/td/clock"
+
+	"github.com/gotd/contrib/auth/kv"
+)
+
+// Storage is a cache storage.
+type Storage interface {
+	// Get returns value of given key. If key is not found or expired,
+	// kv.ErrKeyNotFound is returned.
+	Get(ctx context.Context, k string) ([]byte, error)
+	// Set sets value of given key, which expires after ttl.
+	Set(ctx context.Context, k string, v []byte, ttl time.Duration) error
+}
+
+type memoryEntry struct {
+	key       string
+	value     []byte
+	expiresAt time.Time
+}
+
+// Memory is an in-memory LRU Storage.
+type Memory struct {
+	clock clock.Clock
+	size  int
+
+	mux     sync.Mutex
+	entries map[string]*list.Element
+	lru     *list.List
+}
+
+var _ Storage = (*Memory)(nil)
+
+// NewMemory creates new Memory storage with given max count of entries.
+func NewMemory(size int) *Memory {
+	return &Memory{
+		clock:   clock.System,
+		size:    size,
+		entries: map[string]*list.Element{},
+		lru:     list.New(),
+	}
+}
+
+// WithClock sets clock to use. Default is to use system clock.
+func (m *Memory) WithClock(c clock.Clock) *Memory {
+	m.clock = c
+	return m
+}
+
+// Get implements Storage.
+func (m *Memory) Get(ctx context.Context, k string) ([]byte, error) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	elem, ok := m.entries[k]
+	if !ok {
+		return nil, kv.ErrKeyNotFound
+	}
+	e := elem.Value.(*memoryEntry)
+	if !m.clock.Now().Before(e.expiresAt) {
+		m.lru.Remove(elem)
+		delete(m.entries, k)
+		return nil, kv.ErrKeyNotFound
+	}
+	m.lru.MoveToFront(elem)
+	return e.value, nil
+}
+
+// Set implements Storage.
+func (m *Memory) Set(ctx context.Context, k string, v []byte, ttl time.Duration) error {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	expiresAt := m.clock.Now().Add(ttl)
+	if elem, ok := m.entries[k]; ok {
+		e := elem.Value.(*memoryEntry)
+		e.value, e.expiresAt = v, expiresAt
+		m.lru.MoveToFront(elem)
+		return nil
+	}
+
+	m.entries[k] = m.lru.PushFront(&memoryEntry{key: k, value: v, expiresAt: expiresAt})
+	for m.lru.Len() > m.size {
+		oldest := m.lru.Back()
+		m.lru.Remove(oldest)
+		delete(m.entries, oldest.Value.(*memoryEntry).key)
+	}
+	return nil
+}
+
+// KV is a Storage over generic key-value storage, e.g. one used for
+// sessions.
+//
+// Expiration time is stored along with value and checked on Get.
+//
+// NB: expired values are never removed from underlying storage, so it
+// grows with every distinct request. Use KV only with storage which
+// expires or evicts keys itself, otherwise use Memory.
+type KV struct {
+	storage kv.Storage
+	clock   clock.Clock
+}
+
+var _ Storage = KV{}
+
+// NewKV creates new KV storage.
+func NewKV(s kv.Storage) KV {
+	return KV{storage: s, clock: clock.System}
+}
+
+// WithClock sets clock to use. Default is to use system clock.
+func (s KV) WithClock(c clock.Clock) KV {
+	s.clock = c
+	return s
+}
+
+// Get implements Storage.
+func (s KV) Get(ctx context.Context, k string) ([]byte, error) {
+	v, err := s.storage.Get(ctx, k)
+	if err != nil {
+		return nil, err
+	}
+	if len(v) < 8 {
+		return nil, errors.Errorf("invalid value of %q", k)
+	}
+
+	expiresAt := time.Unix(0, int64(binary.LittleEndian.Uint64([]byte(v[:8]))))
+	if !s.clock.Now().Before(expiresAt) {
+		return nil, kv.ErrKeyNotFound
+	}
+	return []byte(v[8:]), nil
+}
+
+// Set implements Storage.
+func (s KV) Set(ctx context.Context, k string, v []byte, ttl time.Duration) error {
+	b := make([]byte, 8, 8+len(v))
+	binary.LittleEndian.PutUint64(b, uint64(s.clock.Now().Add(ttl).UnixNano()))
+	return s.storage.Set(ctx, k, string(append(b, v...)))
+}
@@ -0,0 +1,120 @@
+package middleware
+
+import (
+	"context"
+	"strings"
+
+	"github.com/gotd/td/bin"
+	"github.com/gotd/td/telegram"
+	"github.com/gotd/td/tg"
+)
+
+// Matcher reports whether middleware should be applied to method with
+// given TL name, e.g. "messages.sendMessage".
+type Matcher func(method string) bool
+
+// Methods returns Matcher of given methods.
+func Methods(methods ...string) Matcher {
+	set := make(map[string]struct{}, len(methods))
+	for _, m := range methods {
+		set[m] = struct{}{}
+	}
+	return func(method string) bool {
+		_, ok := set[method]
+		return ok
+	}
+}
+
+// Prefix returns Matcher of methods with any of given prefixes, e.g.
+// "messages.get" or "upload.".
+func Prefix(prefixes ...string) Matcher {
+	return func(method string) bool {
+		for _, p := range prefixes {
+			if strings.HasPrefix(method, p) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// Not returns Matcher of methods not matched by m.
+func Not(m Matcher) Matcher {
+	return func(method string) bool {
+		return !m(method)
+	}
+}
+
+type link struct {
+	middleware telegram.Middleware
+	// match is nil if middleware is applied to all methods.
+	match Matcher
+}
+
+// Chain composes middlewares. Middlewares are called in order in which they
+// are added, like telegram.Options.Middlewares, i.e. first one is the
+// outermost.
+//
+// Chain implements telegram.Middleware itself, so it can be passed to
+// telegram.Options or nested into another Chain.
+type Chain struct {
+	links []link
+}
+
+// NewChain creates new Chain of given middlewares.
+func NewChain(middlewares ...telegram.Middleware) *Chain {
+	c := &Chain{}
+	for _, m := range middlewares {
+		c.Use(m)
+	}
+	return c
+}
+
+// Use adds middleware which is applied to all methods.
+func (c *Chain) Use(m telegram.Middleware) *Chain {
+	c.links = append(c.links, link{middleware: m})
+	return c
+}
+
+// UseIf adds middleware which is applied only to methods matched by
+// match. Other methods skip it.
+func (c *Chain) UseIf(match Matcher, m telegram.Middleware) *Chain {
+	c.links = append(c.links, link{middleware: m, match: match})
+	return c
+}
+
+// UseExcept adds middleware which is applied to all methods except ones
+// matched by match.
+func (c *Chain) UseExcept(match Matcher, m telegram.Middleware) *Chain {
+	return c.UseIf(Not(match), m)
+}
+
+func methodOf(input bin.Encoder) string {
+	obj, ok := input.(interface{ TypeName() string })
+	if !ok {
+		return ""
+	}
+	return obj.TypeName()
+}
+
+// Handle implements telegram.Middleware.
+func (c *Chain) Handle(next tg.Invoker) telegram.InvokeFunc {
+	invoker := next
+	for i := len(c.links) - 1; i >= 0; i-- {
+		l := c.links[i]
+		wrapped := l.middleware.Handle(invoker)
+		if l.match == nil {
+			invoker = wrapped
+			continue
+		}
+
+		skip := invoker
+		invoker = telegram.InvokeFunc(func(ctx context.Context, input bin.Encoder, output bin.Decoder) error {
+			if l.match(methodOf(input)) {
+				return wrapped.Invoke(ctx, input, output)
+			}
+			return skip.Invoke(ctx, input, output)
+		})
+	}
+	return invoker.Invoke
+}
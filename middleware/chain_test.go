@@ -0,0 +1,56 @@
+package middleware
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/gotd/td/bin"
+	"github.com/gotd/td/telegram"
+	"github.com/gotd/td/tg"
+)
+
+type recordMiddleware struct {
+	name  string
+	calls *[]string
+}
+
+func (m recordMiddleware) Handle(next tg.Invoker) telegram.InvokeFunc {
+	return func(ctx context.Context, input bin.Encoder, output bin.Decoder) error {
+		*m.calls = append(*m.calls, m.name)
+		return next.Invoke(ctx, input, output)
+	}
+}
+
+func TestChain(t *testing.T) {
+	a := require.New(t)
+	ctx := context.Background()
+
+	var calls []string
+	mw := func(name string) telegram.Middleware {
+		return recordMiddleware{name: name, calls: &calls}
+	}
+	invoker := NewChain(mw("first")).
+		UseIf(Prefix("messages.get", "users."), mw("reads")).
+		UseExcept(Methods("help.getConfig"), mw("except")).
+		Use(NewChain(mw("nested"))).
+		Handle(telegram.InvokeFunc(func(ctx context.Context, input bin.Encoder, output bin.Decoder) error {
+			calls = append(calls, "invoker")
+			return nil
+		}))
+
+	for _, tt := range []struct {
+		input bin.Encoder
+		calls []string
+	}{
+		{&tg.MessagesGetHistoryRequest{}, []string{"first", "reads", "except", "nested", "invoker"}},
+		{&tg.UsersGetUsersRequest{}, []string{"first", "reads", "except", "nested", "invoker"}},
+		{&tg.MessagesSendMessageRequest{}, []string{"first", "except", "nested", "invoker"}},
+		{&tg.HelpGetConfigRequest{}, []string{"first", "nested", "invoker"}},
+	} {
+		calls = nil
+		a.NoError(invoker.Invoke(ctx, tt.input, nil))
+		a.Equal(tt.calls, calls, "%T", tt.input)
+	}
+}
@@ -0,0 +1,160 @@
+package coalesce
+
+import (
+	"context"
+	"sync"
+
+	"github.com/go-faster/errors"
+
+	"github.com/gotd/td/bin"
+	"github.com/gotd/td/telegram"
+	"github.com/gotd/td/tg"
+)
+
+// DefaultMethods is a list of read-only methods coalesced by default.
+var DefaultMethods = []string{
+	"users.getUsers",
+	"users.getFullUser",
+	"channels.getChannels",
+	"channels.getFullChannel",
+	"channels.getParticipant",
+	"messages.getChats",
+	"messages.getFullChat",
+	"contacts.resolveUsername",
+	"help.getConfig",
+	"help.getNearestDc",
+}
+
+// Coalescer is a tg.Invoker that detects concurrent identical requests of
+// read-only methods and calls underlying invoker once. Result is decoded
+// to output of every caller.
+//
+// Requests are identical if their encoded bodies are equal. Only outputs
+// which implement bin.Encoder are coalesced, this is true for all
+// result types of tg package.
+type Coalescer struct {
+	methods map[string]struct{}
+
+	mux   sync.Mutex
+	calls map[string]*call
+}
+
+// New creates new Coalescer of DefaultMethods.
+func New() *Coalescer {
+	c := &Coalescer{
+		methods: map[string]struct{}{},
+		calls:   map[string]*call{},
+	}
+	return c.WithMethods(DefaultMethods...)
+}
+
+// WithMethods adds given methods, e.g. "messages.getHistory", to the list
+// of coalesced ones. Methods must not have side effects.
+func (c *Coalescer) WithMethods(methods ...string) *Coalescer {
+	for _, m := range methods {
+		c.methods[m] = struct{}{}
+	}
+	return c
+}
+
+// WithoutMethods removes given methods from the list of coalesced ones.
+func (c *Coalescer) WithoutMethods(methods ...string) *Coalescer {
+	for _, m := range methods {
+		delete(c.methods, m)
+	}
+	return c
+}
+
+func (c *Coalescer) coalesced(input bin.Encoder) bool {
+	obj, ok := input.(interface{ TypeName() string })
+	if !ok {
+		return false
+	}
+	_, ok = c.methods[obj.TypeName()]
+	return ok
+}
+
+// call is an in-flight request.
+type call struct {
+	done   chan struct{}
+	result []byte
+	err    error
+}
+
+// join returns in-flight call of given key, or registers new one.
+func (c *Coalescer) join(key string) (_ *call, leader bool) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	if cl, ok := c.calls[key]; ok {
+		return cl, false
+	}
+	cl := &call{done: make(chan struct{})}
+	c.calls[key] = cl
+	return cl, true
+}
+
+func (c *Coalescer) lead(
+	ctx context.Context, next tg.Invoker,
+	key string, cl *call,
+	input bin.Encoder, output bin.Decoder, result bin.Encoder,
+) error {
+	defer func() {
+		c.mux.Lock()
+		delete(c.calls, key)
+		c.mux.Unlock()
+		close(cl.done)
+	}()
+
+	if err := next.Invoke(ctx, input, output); err != nil {
+		cl.err = err
+		return err
+	}
+	var b bin.Buffer
+	if err := result.Encode(&b); err != nil {
+		cl.err = errors.Wrap(err, "encode result")
+		return nil
+	}
+	cl.result = b.Buf
+	return nil
+}
+
+// Handle implements telegram.Middleware.
+func (c *Coalescer) Handle(next tg.Invoker) telegram.InvokeFunc {
+	return func(ctx context.Context, input bin.Encoder, output bin.Decoder) error {
+		result, ok := output.(bin.Encoder)
+		if !ok || !c.coalesced(input) {
+			return next.Invoke(ctx, input, output)
+		}
+
+		var b bin.Buffer
+		if err := input.Encode(&b); err != nil {
+			return errors.Wrap(err, "encode request")
+		}
+		key := string(b.Buf)
+
+		cl, leader := c.join(key)
+		if leader {
+			return c.lead(ctx, next, key, cl, input, output, result)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-cl.done:
+		}
+		if cl.err != nil {
+			if errors.Is(cl.err, context.Canceled) || errors.Is(cl.err, context.DeadlineExceeded) {
+				// Leader is canceled, but this caller is not.
+				return next.Invoke(ctx, input, output)
+			}
+			return cl.err
+		}
+
+		b.ResetTo(cl.result)
+		if err := output.Decode(&b); err != nil {
+			return errors.Wrap(err, "decode result")
+		}
+		return nil
+	}
+}
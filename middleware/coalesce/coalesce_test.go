@@ -0,0 +1,133 @@
+package coalesce
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/atomic"
+
+	"github.com/gotd/td/bin"
+	"github.com/gotd/td/telegram"
+	"github.com/gotd/td/tg"
+)
+
+// joinContext reports waiting of follower, which selects on Done after
+// joining in-flight call.
+type joinContext struct {
+	context.Context
+	once   sync.Once
+	joined chan<- struct{}
+}
+
+func (c *joinContext) Done() <-chan struct{} {
+	c.once.Do(func() {
+		c.joined <- struct{}{}
+	})
+	return c.Context.Done()
+}
+
+func TestCoalescer(t *testing.T) {
+	a := require.New(t)
+	const n = 10
+
+	// Leaders are released when all followers joined.
+	joined, release := make(chan struct{}), make(chan struct{})
+	go func() {
+		for i := 0; i < n-2; i++ {
+			<-joined
+		}
+		close(release)
+	}()
+
+	var calls atomic.Int64
+	c := New()
+	raw := tg.NewClient(c.Handle(telegram.InvokeFunc(func(ctx context.Context, input bin.Encoder, output bin.Decoder) error {
+		calls.Inc()
+		<-release
+		switch req := input.(type) {
+		case *tg.UsersGetUsersRequest:
+			id := req.ID[0].(*tg.InputUser).UserID
+			*output.(*tg.UserClassVector) = tg.UserClassVector{
+				Elems: []tg.UserClass{&tg.User{ID: id, FirstName: "user"}},
+			}
+		case *tg.MessagesSendMessageRequest:
+			*output.(*tg.UpdatesBox) = tg.UpdatesBox{Updates: &tg.UpdatesTooLong{}}
+		}
+		return nil
+	})))
+	var wg sync.WaitGroup
+	users := make([][]tg.UserClass, n)
+	for i := 0; i < n; i++ {
+		i := i
+		id := int64(1 + i%2)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ctx := &joinContext{Context: context.Background(), joined: joined}
+			u, err := raw.UsersGetUsers(ctx, []tg.InputUserClass{&tg.InputUser{UserID: id}})
+			a.NoError(err)
+			users[i] = u
+		}()
+	}
+	wg.Wait()
+
+	a.Equal(int64(2), calls.Load())
+	for i, u := range users {
+		a.Len(u, 1)
+		user := u[0].(*tg.User)
+		a.Equal(int64(1+i%2), user.ID)
+		a.Equal("user", user.FirstName)
+	}
+
+	// Methods with side effects are not coalesced.
+	ctx := context.Background()
+	calls.Store(0)
+	for i := 0; i < 2; i++ {
+		_, err := raw.MessagesSendMessage(ctx, &tg.MessagesSendMessageRequest{
+			Peer:    &tg.InputPeerSelf{},
+			Message: "hi",
+		})
+		a.NoError(err)
+	}
+	a.Equal(int64(2), calls.Load())
+}
+
+func TestCoalescerLeaderCanceled(t *testing.T) {
+	a := require.New(t)
+	var calls atomic.Int64
+	started := make(chan struct{}, 2)
+	c := New()
+	raw := tg.NewClient(c.Handle(telegram.InvokeFunc(func(ctx context.Context, input bin.Encoder, output bin.Decoder) error {
+		if calls.Inc() == 1 {
+			started <- struct{}{}
+			<-ctx.Done()
+			return ctx.Err()
+		}
+		*output.(*tg.Config) = tg.Config{ThisDC: 2}
+		return nil
+	})))
+
+	leaderCtx, cancel := context.WithCancel(context.Background())
+	errs := make(chan error, 1)
+	go func() {
+		_, err := raw.HelpGetConfig(leaderCtx)
+		errs <- err
+	}()
+	<-started
+
+	joined := make(chan struct{})
+	result := make(chan *tg.Config, 1)
+	go func() {
+		ctx := &joinContext{Context: context.Background(), joined: joined}
+		cfg, err := raw.HelpGetConfig(ctx)
+		a.NoError(err)
+		result <- cfg
+	}()
+	<-joined
+	cancel()
+
+	a.ErrorIs(<-errs, context.Canceled)
+	a.Equal(2, (<-result).ThisDC)
+}
@@ -0,0 +1,3 @@
+// Package coalesce implements a tg.Invoker that merges concurrent identical
+// requests.
+package coalesce
@@ -0,0 +1,171 @@
+package concurrency
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/go-faster/errors"
+	"go.uber.org/atomic"
+
+	"github.com/gotd/td/bin"
+	"github.com/gotd/td/telegram"
+	"github.com/gotd/td/tg"
+)
+
+// QueueFullError is returned by Limiter if request can't be queued.
+type QueueFullError struct {
+	// Method is a TL name of method, if limit of method is reached.
+	// Empty if global limit is reached.
+	Method string
+	// Limit is a reached limit of in-flight requests.
+	Limit int
+}
+
+// Error implements error.
+func (e *QueueFullError) Error() string {
+	if e.Method == "" {
+		return fmt.Sprintf("queue is full (limit %d)", e.Limit)
+	}
+	return fmt.Sprintf("queue of %q is full (limit %d)", e.Method, e.Limit)
+}
+
+// IsQueueFull reports whether given error is returned due to full queue.
+func IsQueueFull(err error) bool {
+	var queueErr *QueueFullError
+	return errors.As(err, &queueErr)
+}
+
+type semaphore struct {
+	method  string
+	slots   chan struct{}
+	waiting *atomic.Int64
+}
+
+func newSemaphore(method string, limit int) *semaphore {
+	return &semaphore{
+		method:  method,
+		slots:   make(chan struct{}, limit),
+		waiting: atomic.NewInt64(0),
+	}
+}
+
+func (s *semaphore) acquire(ctx context.Context, queue int) error {
+	select {
+	case s.slots <- struct{}{}:
+		return nil
+	default:
+	}
+
+	if w := s.waiting.Inc(); queue >= 0 && w > int64(queue) {
+		s.waiting.Dec()
+		return &QueueFullError{Method: s.method, Limit: cap(s.slots)}
+	}
+	defer s.waiting.Dec()
+
+	select {
+	case s.slots <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (s *semaphore) release() {
+	<-s.slots
+}
+
+// Limiter is a tg.Invoker that limits count of in-flight requests of
+// underlying invoker, globally and per method.
+//
+// Requests over limit wait in queue. If queue is full, *QueueFullError
+// is returned.
+type Limiter struct {
+	global  *semaphore
+	methods map[string]*semaphore
+	queue   int
+}
+
+// New creates new Limiter with given global limit of in-flight requests.
+// Zero limit disables global limit.
+func New(limit int) *Limiter {
+	l := &Limiter{
+		methods: map[string]*semaphore{},
+		queue:   -1,
+	}
+	if limit > 0 {
+		l.global = newSemaphore("", limit)
+	}
+	return l
+}
+
+// WithMethod sets limit of in-flight requests of given method, e.g.
+// "upload.getFile". Method requests are also counted by global limit.
+func (l *Limiter) WithMethod(method string, limit int) *Limiter {
+	l.methods[method] = newSemaphore(method, limit)
+	return l
+}
+
+// WithQueue sets max count of requests waiting for every limit, i.e.
+// global one and every method one. Zero queue means that requests over
+// limit fail immediately. Default is unbounded queue.
+func (l *Limiter) WithQueue(size int) *Limiter {
+	l.queue = size
+	return l
+}
+
+// Waiting returns count of requests waiting in queues.
+func (l *Limiter) Waiting() int64 {
+	var n int64
+	if l.global != nil {
+		n += l.global.waiting.Load()
+	}
+	for _, s := range l.methods {
+		n += s.waiting.Load()
+	}
+	return n
+}
+
+func (l *Limiter) acquire(ctx context.Context, input bin.Encoder) (release func(), err error) {
+	var method *semaphore
+	if obj, ok := input.(interface{ TypeName() string }); ok {
+		method = l.methods[obj.TypeName()]
+	}
+
+	// Acquire method slot first, so requests waiting for method slot
+	// do not hold global one.
+	if method != nil {
+		if err := method.acquire(ctx, l.queue); err != nil {
+			return nil, err
+		}
+	}
+	if l.global != nil {
+		if err := l.global.acquire(ctx, l.queue); err != nil {
+			if method != nil {
+				method.release()
+			}
+			return nil, err
+		}
+	}
+
+	return func() {
+		if l.global != nil {
+			l.global.release()
+		}
+		if method != nil {
+			method.release()
+		}
+	}, nil
+}
+
+// Handle implements telegram.Middleware.
+func (l *Limiter) Handle(next tg.Invoker) telegram.InvokeFunc {
+	return func(ctx context.Context, input bin.Encoder, output bin.Decoder) error {
+		release, err := l.acquire(ctx, input)
+		if err != nil {
+			return err
+		}
+		defer release()
+
+		return next.Invoke(ctx, input, output)
+	}
+}
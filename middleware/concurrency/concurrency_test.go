@@ -0,0 +1,116 @@
+package concurrency
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/gotd/td/bin"
+	"github.com/gotd/td/tg"
+)
+
+type blockingInvoker struct {
+	started chan string
+	release chan struct{}
+}
+
+func newBlockingInvoker() *blockingInvoker {
+	return &blockingInvoker{
+		started: make(chan string, 10),
+		release: make(chan struct{}),
+	}
+}
+
+func (b *blockingInvoker) Invoke(ctx context.Context, input bin.Encoder, output bin.Decoder) error {
+	b.started <- input.(interface{ TypeName() string }).TypeName()
+	select {
+	case <-b.release:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func waitFor(t *testing.T, f func() bool) {
+	t.Helper()
+	require.Eventually(t, f, time.Second, time.Millisecond)
+}
+
+func TestLimiter(t *testing.T) {
+	a := require.New(t)
+	next := newBlockingInvoker()
+	l := New(1).WithQueue(1)
+	invoker := l.Handle(next)
+	ctx := context.Background()
+
+	errs := make(chan error, 2)
+	go func() { errs <- invoker.Invoke(ctx, &tg.HelpGetConfigRequest{}, nil) }()
+	a.Equal("help.getConfig", <-next.started)
+
+	go func() { errs <- invoker.Invoke(ctx, &tg.HelpGetNearestDCRequest{}, nil) }()
+	waitFor(t, func() bool { return l.Waiting() == 1 })
+
+	err := invoker.Invoke(ctx, &tg.HelpGetConfigRequest{}, nil)
+	a.True(IsQueueFull(err))
+	var queueErr *QueueFullError
+	a.ErrorAs(err, &queueErr)
+	a.Equal(&QueueFullError{Limit: 1}, queueErr)
+
+	next.release <- struct{}{}
+	a.NoError(<-errs)
+	a.Equal("help.getNearestDc", <-next.started)
+	next.release <- struct{}{}
+	a.NoError(<-errs)
+	a.Zero(l.Waiting())
+}
+
+func TestLimiterMethod(t *testing.T) {
+	a := require.New(t)
+	next := newBlockingInvoker()
+	l := New(2).WithMethod("upload.getFile", 1).WithQueue(0)
+	invoker := l.Handle(next)
+	ctx := context.Background()
+
+	errs := make(chan error, 2)
+	go func() { errs <- invoker.Invoke(ctx, &tg.UploadGetFileRequest{}, nil) }()
+	a.Equal("upload.getFile", <-next.started)
+
+	err := invoker.Invoke(ctx, &tg.UploadGetFileRequest{}, nil)
+	var queueErr *QueueFullError
+	a.ErrorAs(err, &queueErr)
+	a.Equal(&QueueFullError{Method: "upload.getFile", Limit: 1}, queueErr)
+
+	// Other methods use remaining global slot.
+	go func() { errs <- invoker.Invoke(ctx, &tg.HelpGetConfigRequest{}, nil) }()
+	a.Equal("help.getConfig", <-next.started)
+	a.True(IsQueueFull(invoker.Invoke(ctx, &tg.HelpGetConfigRequest{}, nil)))
+
+	next.release <- struct{}{}
+	next.release <- struct{}{}
+	a.NoError(<-errs)
+	a.NoError(<-errs)
+}
+
+func TestLimiterCanceled(t *testing.T) {
+	a := require.New(t)
+	next := newBlockingInvoker()
+	l := New(1)
+	invoker := l.Handle(next)
+
+	errs := make(chan error, 1)
+	go func() { errs <- invoker.Invoke(context.Background(), &tg.HelpGetConfigRequest{}, nil) }()
+	<-next.started
+
+	ctx, cancel := context.WithCancel(context.Background())
+	canceled := make(chan error, 1)
+	go func() { canceled <- invoker.Invoke(ctx, &tg.HelpGetConfigRequest{}, nil) }()
+	waitFor(t, func() bool { return l.Waiting() == 1 })
+	cancel()
+	a.ErrorIs(<-canceled, context.Canceled)
+	a.Zero(l.Waiting())
+
+	next.release <- struct{}{}
+	a.NoError(<-errs)
+}
@@ -0,0 +1,3 @@
+// Package concurrency implements a tg.Invoker that limits count of
+// in-flight requests.
+package concurrency
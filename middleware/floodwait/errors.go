@@ -0,0 +1,62 @@
+package floodwait
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/go-faster/errors"
+)
+
+// WaitError is returned instead of waiting, if flood wait exceeds limit
+// set by WithMaxWait or WithMaxTotalWait.
+//
+// WaitError wraps original FLOOD_WAIT error, so tgerr.AsFloodWait can be
+// used too.
+type WaitError struct {
+	// Method is a TL name of method, e.g. "messages.sendMessage".
+	Method string
+	// Wait is a requested wait duration.
+	Wait time.Duration
+	// Waited is a total duration already waited by call.
+	Waited time.Duration
+	// Limit is an exceeded limit.
+	Limit time.Duration
+	// Total reports whether total wait limit is exceeded, see
+	// WithMaxTotalWait.
+	Total bool
+
+	Err error
+}
+
+// Error implements error.
+func (e *WaitError) Error() string {
+	if e.Total {
+		return fmt.Sprintf("flood wait budget exceeded (%v + %v > %v): %v", e.Waited, e.Wait, e.Limit, e.Err)
+	}
+	return fmt.Sprintf("flood wait argument is too big (%v > %v): %v", e.Wait, e.Limit, e.Err)
+}
+
+// Unwrap returns original error.
+func (e *WaitError) Unwrap() error {
+	return e.Err
+}
+
+// AsWaitError extracts *WaitError from error chain.
+func AsWaitError(err error) (*WaitError, bool) {
+	var waitErr *WaitError
+	if errors.As(err, &waitErr) {
+		return waitErr, true
+	}
+	return nil, false
+}
+
+// checkWait returns *WaitError, if wait d exceeds limits.
+func checkWait(method string, d, waited, maxWait, maxTotal time.Duration, err error) error {
+	if maxWait != 0 && d > maxWait {
+		return &WaitError{Method: method, Wait: d, Waited: waited, Limit: maxWait, Err: err}
+	}
+	if maxTotal != 0 && waited+d > maxTotal {
+		return &WaitError{Method: method, Wait: d, Waited: waited, Limit: maxTotal, Total: true, Err: err}
+	}
+	return nil
+}
@@ -0,0 +1,133 @@
+package floodwait
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"time"
+
+	"github.com/go-faster/errors"
+
+	"github.com/gotd/contrib/auth/kv"
+)
+
+// ActiveWait is an active flood wait of method, stored by Waiter.
+type ActiveWait struct {
+	TypeID uint32    `json:"type_id"`
+	Method string    `json:"method,omitempty"`
+	Until  time.Time `json:"until"`
+}
+
+// WithStorage enables persistence of active flood waits using given key of
+// storage, so restarted Waiter does not trigger the same flood wait again.
+//
+// Waits are loaded on Run and saved in background after flood waits, so
+// slow storage does not delay requests. Pending waits are saved once more
+// on shutdown. Persistence is best-effort, use WithStorageError to report
+// save errors.
+func (w *Waiter) WithStorage(s kv.Storage, key string) *Waiter {
+	w = w.clone()
+	w.storage = s
+	w.storageKey = key
+	return w
+}
+
+// WithStorageError sets callback for errors of saving flood waits to
+// storage, e.g. to log them. Default is to ignore errors.
+func (w *Waiter) WithStorageError(f func(ctx context.Context, err error)) *Waiter {
+	w = w.clone()
+	w.onSaveError = f
+	return w
+}
+
+func (w *Waiter) load(ctx context.Context) error {
+	if w.storage == nil {
+		return nil
+	}
+
+	data, err := w.storage.Get(ctx, w.storageKey)
+	if err != nil {
+		if errors.Is(err, kv.ErrKeyNotFound) {
+			return nil
+		}
+		return errors.Wrap(err, "get")
+	}
+
+	var waits []ActiveWait
+	if err := json.Unmarshal([]byte(data), &waits); err != nil {
+		return errors.Wrap(err, "decode")
+	}
+	deadlines := make(map[key]time.Time, len(waits))
+	for _, wait := range waits {
+		deadlines[key(wait.TypeID)] = wait.Until
+	}
+	w.sch.restore(deadlines)
+	return nil
+}
+
+// requestSave schedules save of flood waits by persist loop.
+func (w *Waiter) requestSave() {
+	if w.storage == nil {
+		return
+	}
+	select {
+	case w.dirty <- struct{}{}:
+	default:
+		// Save is already pending.
+	}
+}
+
+// persist saves flood waits on every requestSave until context is done.
+func (w *Waiter) persist(ctx context.Context) {
+	for {
+		select {
+		case <-w.dirty:
+			w.trySave(ctx)
+		case <-ctx.Done():
+			select {
+			case <-w.dirty:
+				w.trySave(context.WithoutCancel(ctx))
+			default:
+			}
+			return
+		}
+	}
+}
+
+func (w *Waiter) trySave(ctx context.Context) {
+	if err := w.save(ctx); err != nil {
+		w.onSaveError(ctx, err)
+	}
+}
+
+func (w *Waiter) save(ctx context.Context) error {
+	if w.storage == nil {
+		return nil
+	}
+
+	data, err := json.Marshal(w.active(typeNames()))
+	if err != nil {
+		return errors.Wrap(err, "encode")
+	}
+	if err := w.storage.Set(ctx, w.storageKey, string(data)); err != nil {
+		return errors.Wrap(err, "set")
+	}
+	return nil
+}
+
+// active returns active flood waits sorted by type ID.
+func (w *Waiter) active(names map[uint32]string) []ActiveWait {
+	deadlines := w.sch.deadlines()
+	waits := make([]ActiveWait, 0, len(deadlines))
+	for k, until := range deadlines {
+		waits = append(waits, ActiveWait{
+			TypeID: uint32(k),
+			Method: methodName(names, uint32(k)),
+			Until:  until,
+		})
+	}
+	sort.Slice(waits, func(i, j int) bool {
+		return waits[i].TypeID < waits[j].TypeID
+	})
+	return waits
+}
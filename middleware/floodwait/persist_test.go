@@ -0,0 +1,119 @@
+package floodwait
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/go-faster/errors"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gotd/neo"
+	"github.com/gotd/td/tg"
+
+	"github.com/gotd/contrib/auth/kv"
+)
+
+type memKV map[string]string
+
+type failingKV struct{}
+
+func (failingKV) Set(ctx context.Context, k, v string) error {
+	return errors.New("failed")
+}
+
+func (failingKV) Get(ctx context.Context, k string) (string, error) {
+	return "", kv.ErrKeyNotFound
+}
+
+func (m memKV) Set(ctx context.Context, k, v string) error {
+	m[k] = v
+	return nil
+}
+
+func (m memKV) Get(ctx context.Context, k string) (string, error) {
+	v, ok := m[k]
+	if !ok {
+		return "", kv.ErrKeyNotFound
+	}
+	return v, nil
+}
+
+func TestWaiter_Storage(t *testing.T) {
+	a := require.New(t)
+	ctx := context.Background()
+	n := neo.NewTime(time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC))
+	storage := memKV{}
+
+	w := NewWaiter().WithClock(n).WithStorage(storage, "floodwait")
+	w.sch = newScheduler(n, time.Second)
+	a.NoError(w.load(ctx))
+
+	r := request{key: tg.MessagesSendMessageRequestTypeID}
+	w.sch.schedule(r)
+	a.Len(w.sch.gather(nil), 1)
+	w.sch.flood(r, 10*time.Second)
+	a.NoError(w.save(ctx))
+
+	var waits []ActiveWait
+	a.NoError(json.Unmarshal([]byte(storage["floodwait"]), &waits))
+	a.Equal([]ActiveWait{{
+		TypeID: tg.MessagesSendMessageRequestTypeID,
+		Method: "messages.sendMessage",
+		Until:  n.Now().Add(10 * time.Second),
+	}}, waits)
+
+	// Restart after 3 seconds.
+	n.Travel(3 * time.Second)
+	restarted := NewWaiter().WithClock(n).WithStorage(storage, "floodwait")
+	restarted.sch = newScheduler(n, time.Second)
+	a.NoError(restarted.load(ctx))
+
+	restarted.sch.schedule(request{key: tg.MessagesSendMessageRequestTypeID})
+	n.Travel(6 * time.Second)
+	a.Empty(restarted.sch.gather(nil))
+	n.Travel(time.Second)
+	a.Len(restarted.sch.gather(nil), 1)
+
+	// Expired waits are removed.
+	a.NoError(restarted.save(ctx))
+	a.JSONEq(`[]`, storage["floodwait"])
+}
+
+func TestWaiter_StoragePersist(t *testing.T) {
+	a := require.New(t)
+	storage := memKV{}
+
+	w := NewWaiter().WithStorage(storage, "floodwait")
+	w.requestSave()
+	w.requestSave()
+	a.Len(w.dirty, 1, "saves must be coalesced")
+
+	// Pending save is done on shutdown.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	w.persist(ctx)
+	a.JSONEq(`[]`, storage["floodwait"])
+}
+
+func TestWaiter_StorageError(t *testing.T) {
+	a := require.New(t)
+
+	errs := make(chan error, 1)
+	w := NewWaiter().WithStorage(failingKV{}, "floodwait").
+		WithStorageError(func(ctx context.Context, err error) {
+			errs <- err
+		})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		w.persist(ctx)
+	}()
+	w.requestSave()
+	a.Error(<-errs)
+	cancel()
+	<-done
+}
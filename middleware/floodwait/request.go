@@ -2,6 +2,7 @@ package floodwait
 
 import (
 	"context"
+	"time"
 
 	"github.com/gotd/td/bin"
 	"github.com/gotd/td/tg"
@@ -14,6 +15,14 @@ type object interface {
 
 type key uint64
 
+func methodOf(encoder bin.Encoder) string {
+	obj, ok := encoder.(interface{ TypeName() string })
+	if !ok {
+		return ""
+	}
+	return obj.TypeName()
+}
+
 func (k *key) fromEncoder(encoder bin.Encoder) {
 	obj, ok := encoder.(object)
 	if !ok {
@@ -29,6 +38,8 @@ type request struct {
 	next   tg.Invoker
 	key    key
 
-	retry  int
+	retry int
+	// waited is a total duration of flood waits.
+	waited time.Duration
 	result chan error
 }
@@ -12,6 +12,8 @@ import (
 
 type scheduler struct {
 	state map[key]time.Duration
+	// until is a deadline of last flood wait of every key.
+	until map[key]time.Time
 	mux   sync.Mutex
 	queue *queue
 
@@ -24,6 +26,7 @@ func newScheduler(c clock.Clock, dec time.Duration) *scheduler {
 
 	return &scheduler{
 		state: make(map[key]time.Duration, initialCapacity),
+		until: make(map[key]time.Time, initialCapacity),
 		queue: newQueue(initialCapacity),
 		clock: c,
 		dec:   dec,
@@ -59,6 +62,9 @@ func (s *scheduler) schedule(r request) {
 	} else {
 		t = s.clock.Now()
 	}
+	if until, ok := s.until[k]; ok && until.After(t) {
+		t = until
+	}
 	s.queue.add(r, t)
 }
 
@@ -84,8 +90,45 @@ func (s *scheduler) flood(req request, d time.Duration) {
 	if state, ok := s.state[k]; !ok || state < d {
 		s.state[k] = d
 	}
+	if until := now.Add(d); s.until[k].Before(until) {
+		s.until[k] = until
+	}
 	s.queue.add(req, now.Add(d))
 	s.mux.Unlock()
 
 	s.queue.move(k, now, d)
 }
+
+// deadlines returns active flood wait deadlines and removes expired ones.
+func (s *scheduler) deadlines() map[key]time.Time {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	now := s.clock.Now()
+	r := make(map[key]time.Time, len(s.until))
+	for k, until := range s.until {
+		if !until.After(now) {
+			delete(s.until, k)
+			continue
+		}
+		r[k] = until
+	}
+	return r
+}
+
+// restore sets flood wait deadlines, e.g. loaded from persistent storage.
+func (s *scheduler) restore(deadlines map[key]time.Time) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	now := s.clock.Now()
+	for k, until := range deadlines {
+		if !until.After(now) || s.until[k].After(until) {
+			continue
+		}
+		s.until[k] = until
+		if d := until.Sub(now); s.state[k] < d {
+			s.state[k] = d
+		}
+	}
+}
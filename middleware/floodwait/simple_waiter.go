@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"github.com/go-faster/errors"
+	"go.uber.org/atomic"
 
 	"github.com/gotd/td/bin"
 	"github.com/gotd/td/clock"
@@ -24,12 +25,18 @@ type SimpleWaiter struct {
 
 	maxRetries uint
 	maxWait    time.Duration
+	maxTotal   time.Duration
+	onWait     func(ctx context.Context, wait FloodWait)
+	// waiting is shared between copies.
+	waiting *atomic.Int64
 }
 
 // NewSimpleWaiter returns a new invoker that waits on the flood wait errors.
 func NewSimpleWaiter() *SimpleWaiter {
 	return &SimpleWaiter{
-		clock: clock.System,
+		clock:   clock.System,
+		onWait:  func(ctx context.Context, wait FloodWait) {},
+		waiting: atomic.NewInt64(0),
 	}
 }
 
@@ -38,7 +45,10 @@ func (w *SimpleWaiter) clone() *SimpleWaiter {
 	return &SimpleWaiter{
 		clock:      w.clock,
 		maxWait:    w.maxWait,
+		maxTotal:   w.maxTotal,
 		maxRetries: w.maxRetries,
+		onWait:     w.onWait,
+		waiting:    w.waiting,
 	}
 }
 
@@ -57,22 +67,43 @@ func (w *SimpleWaiter) WithMaxRetries(m uint) *SimpleWaiter {
 	return w
 }
 
-// WithMaxWait limits wait time per attempt. SimpleWaiter will return an error if flood wait
+// WithMaxWait limits wait time per attempt. SimpleWaiter will return *WaitError if flood wait
 // time exceeds that limit. Default is to wait without time limit.
 //
-// To limit total wait time use a context.Context with timeout or deadline set.
+// To limit total wait time use WithMaxTotalWait or a context.Context with timeout or
+// deadline set.
 func (w *SimpleWaiter) WithMaxWait(m time.Duration) *SimpleWaiter {
 	w = w.clone()
 	w.maxWait = m
 	return w
 }
 
+// WithMaxTotalWait limits total wait time per call. SimpleWaiter will return *WaitError
+// instead of waiting, if total flood wait time of call would exceed that limit. Default
+// is no limit.
+func (w *SimpleWaiter) WithMaxTotalWait(m time.Duration) *SimpleWaiter {
+	w = w.clone()
+	w.maxTotal = m
+	return w
+}
+
+// WithCallback sets callback for every flood wait event, e.g. to log or
+// count them.
+func (w *SimpleWaiter) WithCallback(f func(ctx context.Context, wait FloodWait)) *SimpleWaiter {
+	w = w.clone()
+	w.onWait = f
+	return w
+}
+
 // Handle implements telegram.Middleware.
 func (w *SimpleWaiter) Handle(next tg.Invoker) telegram.InvokeFunc {
 	return func(ctx context.Context, input bin.Encoder, output bin.Decoder) error {
 		var t clock.Timer
 
-		var retries uint
+		var (
+			retries uint
+			waited  time.Duration
+		)
 		for {
 			err := next.Invoke(ctx, input, output)
 			if err == nil {
@@ -84,6 +115,12 @@ func (w *SimpleWaiter) Handle(next tg.Invoker) telegram.InvokeFunc {
 				return err
 			}
 
+			method := methodOf(input)
+			w.onWait(ctx, FloodWait{
+				Method:   method,
+				Duration: d,
+			})
+
 			retries++
 
 			if v := w.maxRetries; v != 0 && retries > v {
@@ -94,9 +131,10 @@ func (w *SimpleWaiter) Handle(next tg.Invoker) telegram.InvokeFunc {
 				d = time.Second
 			}
 
-			if v := w.maxWait; v != 0 && d > v {
-				return errors.Errorf("flood wait argument is too big (%v > %v): %w", d, v, err)
+			if err := checkWait(method, d, waited, w.maxWait, w.maxTotal, err); err != nil {
+				return err
 			}
+			waited += d
 
 			if t == nil {
 				t = w.clock.Timer(d)
@@ -104,10 +142,13 @@ func (w *SimpleWaiter) Handle(next tg.Invoker) telegram.InvokeFunc {
 				clock.StopTimer(t)
 				t.Reset(d)
 			}
+			w.waiting.Inc()
 			select {
 			case <-t.C():
+				w.waiting.Dec()
 				continue
 			case <-ctx.Done():
+				w.waiting.Dec()
 				clock.StopTimer(t)
 				return ctx.Err()
 			}
@@ -0,0 +1,70 @@
+package floodwait
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/gotd/neo"
+	"github.com/gotd/td/bin"
+	"github.com/gotd/td/telegram"
+	"github.com/gotd/td/tg"
+	"github.com/gotd/td/tgerr"
+)
+
+func TestSimpleWaiter_Budget(t *testing.T) {
+	a := require.New(t)
+	clock := neo.NewTime(time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC))
+
+	var waits []FloodWait
+	invoker := NewSimpleWaiter().
+		WithClock(clock).
+		WithMaxWait(time.Minute).
+		WithMaxTotalWait(5 * time.Second).
+		WithCallback(func(ctx context.Context, wait FloodWait) {
+			waits = append(waits, wait)
+		}).
+		Handle(telegram.InvokeFunc(func(ctx context.Context, input bin.Encoder, output bin.Decoder) error {
+			return tgerr.New(420, "FLOOD_WAIT_3")
+		}))
+
+	observe := clock.Observe()
+	done := make(chan error, 1)
+	go func() { done <- invoker.Invoke(context.Background(), &tg.HelpGetConfigRequest{}, nil) }()
+	<-observe
+	clock.Travel(3 * time.Second)
+
+	err := <-done
+	waitErr, ok := AsWaitError(err)
+	a.True(ok)
+	a.Equal("help.getConfig", waitErr.Method)
+	a.Equal(3*time.Second, waitErr.Wait)
+	a.Equal(3*time.Second, waitErr.Waited)
+	a.True(waitErr.Total)
+	d, ok := tgerr.AsFloodWait(err)
+	a.True(ok)
+	a.Equal(3*time.Second, d)
+
+	a.Equal([]FloodWait{
+		{Method: "help.getConfig", Duration: 3 * time.Second},
+		{Method: "help.getConfig", Duration: 3 * time.Second},
+	}, waits)
+}
+
+func TestSimpleWaiter_MaxWait(t *testing.T) {
+	a := require.New(t)
+	invoker := NewSimpleWaiter().
+		WithMaxWait(time.Second).
+		Handle(telegram.InvokeFunc(func(ctx context.Context, input bin.Encoder, output bin.Decoder) error {
+			return tgerr.New(420, "FLOOD_WAIT_300")
+		}))
+
+	err := invoker.Invoke(context.Background(), &tg.HelpGetConfigRequest{}, nil)
+	waitErr, ok := AsWaitError(err)
+	a.True(ok)
+	a.False(waitErr.Total)
+	a.Equal(300*time.Second, waitErr.Wait)
+	a.Equal(time.Second, waitErr.Limit)
+}
@@ -0,0 +1,94 @@
+package floodwait
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gotd/td/tg"
+)
+
+// LearnedWait is a flood wait learned for method.
+type LearnedWait struct {
+	TypeID uint32        `json:"type_id"`
+	Method string        `json:"method,omitempty"`
+	Wait   time.Duration `json:"wait"`
+}
+
+// QueuedRequest is a request waiting to be sent.
+type QueuedRequest struct {
+	TypeID uint32    `json:"type_id"`
+	Method string    `json:"method,omitempty"`
+	SendAt time.Time `json:"send_at"`
+	Retry  int       `json:"retry"`
+}
+
+// Snapshot is a state of Waiter, intended for debugging.
+type Snapshot struct {
+	Running bool            `json:"running"`
+	Learned []LearnedWait   `json:"learned"`
+	Queued  []QueuedRequest `json:"queued"`
+	// Active is flood waits which are not expired yet.
+	Active []ActiveWait `json:"active"`
+}
+
+// typeNames returns TL names of types, computed once.
+var typeNames = sync.OnceValue(tg.TypesMap) // nolint:gochecknoglobals
+
+func methodName(names map[uint32]string, id uint32) string {
+	name, _, _ := strings.Cut(names[id], "#")
+	return name
+}
+
+// Snapshot returns current state of Waiter: learned flood waits per method
+// and requests waiting to be sent.
+func (w *Waiter) Snapshot() Snapshot {
+	names := typeNames()
+	r := Snapshot{
+		Running: w.running.Load(),
+		Active:  w.active(names),
+	}
+
+	w.sch.mux.Lock()
+	for k, d := range w.sch.state {
+		r.Learned = append(r.Learned, LearnedWait{
+			TypeID: uint32(k),
+			Method: methodName(names, uint32(k)),
+			Wait:   d,
+		})
+	}
+	w.sch.mux.Unlock()
+	sort.Slice(r.Learned, func(i, j int) bool {
+		return r.Learned[i].TypeID < r.Learned[j].TypeID
+	})
+
+	w.sch.queue.requestsMux.Lock()
+	for _, s := range w.sch.queue.requests {
+		r.Queued = append(r.Queued, QueuedRequest{
+			TypeID: uint32(s.request.key),
+			Method: methodName(names, uint32(s.request.key)),
+			SendAt: s.sendTime,
+			Retry:  s.request.retry,
+		})
+	}
+	w.sch.queue.requestsMux.Unlock()
+	sort.SliceStable(r.Queued, func(i, j int) bool {
+		return r.Queued[i].SendAt.Before(r.Queued[j].SendAt)
+	})
+
+	return r
+}
+
+// SimpleSnapshot is a state of SimpleWaiter, intended for debugging.
+type SimpleSnapshot struct {
+	// Waiting is count of requests waiting after flood wait error.
+	Waiting int64 `json:"waiting"`
+}
+
+// Snapshot returns current state of SimpleWaiter.
+func (w *SimpleWaiter) Snapshot() SimpleSnapshot {
+	return SimpleSnapshot{
+		Waiting: w.waiting.Load(),
+	}
+}
@@ -0,0 +1,43 @@
+package floodwait
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/gotd/neo"
+	"github.com/gotd/td/tg"
+)
+
+func TestWaiter_Snapshot(t *testing.T) {
+	a := require.New(t)
+	n := neo.NewTime(time.Now())
+	w := NewWaiter().WithClock(n)
+	w.sch = newScheduler(n, time.Second)
+
+	r := request{key: tg.MessagesSendMessageRequestTypeID}
+	w.sch.schedule(r)
+	w.sch.gather(nil)
+	r.retry++
+	w.sch.flood(r, 5*time.Second)
+
+	s := w.Snapshot()
+	a.False(s.Running)
+	a.Equal([]LearnedWait{{
+		TypeID: tg.MessagesSendMessageRequestTypeID,
+		Method: "messages.sendMessage",
+		Wait:   5 * time.Second,
+	}}, s.Learned)
+	a.Len(s.Queued, 1)
+	q := s.Queued[0]
+	a.Equal("messages.sendMessage", q.Method)
+	a.Equal(1, q.Retry)
+	a.False(q.SendAt.Before(n.Now().Add(5 * time.Second)))
+	a.Len(s.Active, 1)
+	a.Equal("messages.sendMessage", s.Active[0].Method)
+
+	_, err := json.Marshal(s)
+	a.NoError(err)
+}
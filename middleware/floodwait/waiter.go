@@ -14,6 +14,8 @@ import (
 	"github.com/gotd/td/bin"
 	"github.com/gotd/td/clock"
 	"github.com/gotd/td/tgerr"
+
+	"github.com/gotd/contrib/auth/kv"
 )
 
 const (
@@ -37,12 +39,20 @@ type Waiter struct {
 	running    atomic.Bool
 	tick       time.Duration
 	maxWait    time.Duration
+	maxTotal   time.Duration
 	maxRetries int
 	onWait     func(ctx context.Context, wait FloodWait)
+
+	storage     kv.Storage
+	storageKey  string
+	onSaveError func(ctx context.Context, err error)
+	dirty       chan struct{}
 }
 
 // FloodWait event.
 type FloodWait struct {
+	// Method is a TL name of method, e.g. "messages.sendMessage".
+	Method   string
 	Duration time.Duration
 }
 
@@ -64,10 +74,14 @@ func NewWaiter() *Waiter {
 		maxWait:    defaultMaxWait,
 		maxRetries: defaultMaxRetries,
 		onWait:     func(ctx context.Context, wait FloodWait) {},
+
+		onSaveError: func(ctx context.Context, err error) {},
+		dirty:       make(chan struct{}, 1),
 	}
 }
 
-// WithCallback sets callback for flood wait event.
+// WithCallback sets callback for every flood wait event, e.g. to log or
+// count them.
 func (w *Waiter) WithCallback(f func(ctx context.Context, wait FloodWait)) *Waiter {
 	w = w.clone()
 	w.onWait = f
@@ -81,7 +95,14 @@ func (w *Waiter) clone() *Waiter {
 		sch:        w.sch,
 		tick:       w.tick,
 		maxWait:    w.maxWait,
+		maxTotal:   w.maxTotal,
 		maxRetries: w.maxRetries,
+		onWait:     w.onWait,
+		storage:    w.storage,
+		storageKey: w.storageKey,
+
+		onSaveError: w.onSaveError,
+		dirty:       w.dirty,
 	}
 }
 
@@ -92,16 +113,25 @@ func (w *Waiter) WithClock(c clock.Clock) *Waiter {
 	return w
 }
 
-// WithMaxWait limits wait time per attempt. Waiter will return an error if flood wait
+// WithMaxWait limits wait time per attempt. Waiter will return *WaitError if flood wait
 // time exceeds that limit. Default is to wait at most a minute.
 //
-// To limit total wait time use a context.Context with timeout or deadline set.
+// To limit total wait time use WithMaxTotalWait or a context.Context with timeout or
+// deadline set.
 func (w *Waiter) WithMaxWait(m time.Duration) *Waiter {
 	w = w.clone()
 	w.maxWait = m
 	return w
 }
 
+// WithMaxTotalWait limits total wait time per call. Waiter will return *WaitError instead
+// of waiting, if total flood wait time of call would exceed that limit. Default is no limit.
+func (w *Waiter) WithMaxTotalWait(m time.Duration) *Waiter {
+	w = w.clone()
+	w.maxTotal = m
+	return w
+}
+
 // WithMaxRetries sets max number of retries before giving up. Default is to retry at most 5 times.
 func (w *Waiter) WithMaxRetries(m int) *Waiter {
 	w = w.clone()
@@ -130,6 +160,10 @@ func (w *Waiter) WithTick(t time.Duration) *Waiter {
 //		return errors.Wrap(err, "run client")
 //	}
 func (w *Waiter) Run(ctx context.Context, f func(ctx context.Context) error) (err error) {
+	if err := w.load(ctx); err != nil {
+		return errors.Wrap(err, "load flood waits")
+	}
+
 	w.running.Store(true)
 	defer w.running.Store(false)
 
@@ -139,6 +173,12 @@ func (w *Waiter) Run(ctx context.Context, f func(ctx context.Context) error) (er
 		defer cancel()
 		return f(ctx)
 	})
+	if w.storage != nil {
+		wg.Go(func() error {
+			w.persist(ctx)
+			return nil
+		})
+	}
 	wg.Go(func() error {
 		ticker := w.clock.Ticker(w.tick)
 		defer ticker.Stop()
@@ -179,8 +219,10 @@ func (w *Waiter) send(s scheduled) (bool, error) {
 		return true, err
 	}
 
+	method := methodOf(s.request.input)
 	// Notify about flood wait.
 	w.onWait(s.request.ctx, FloodWait{
+		Method:   method,
 		Duration: d,
 	})
 
@@ -193,11 +235,13 @@ func (w *Waiter) send(s scheduled) (bool, error) {
 	if d < time.Second {
 		d = time.Second
 	}
-	if v := w.maxWait; v != 0 && d > v {
-		return true, errors.Errorf("flood wait argument is too big (%v > %v): %w", d, v, err)
+	if err := checkWait(method, d, s.request.waited, w.maxWait, w.maxTotal, err); err != nil {
+		return true, err
 	}
 
+	s.request.waited += d
 	w.sch.flood(s.request, d)
+	w.requestSave()
 	return false, nil
 }
 
@@ -0,0 +1,3 @@
+// Package hedge implements a tg.Invoker that sends hedged requests to cut
+// tail latency.
+package hedge
@@ -0,0 +1,245 @@
+package hedge
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gotd/td/bin"
+	"github.com/gotd/td/clock"
+	"github.com/gotd/td/telegram"
+	"github.com/gotd/td/tg"
+)
+
+// DefaultMethods is a list of read-only methods hedged by default.
+var DefaultMethods = []string{
+	"users.getUsers",
+	"users.getFullUser",
+	"channels.getChannels",
+	"channels.getFullChannel",
+	"channels.getParticipant",
+	"messages.getChats",
+	"messages.getFullChat",
+	"messages.getHistory",
+	"messages.getMessages",
+	"contacts.resolveUsername",
+}
+
+// minSamples is a minimum count of latency samples to compute delay.
+const minSamples = 10
+
+// latencies is a ring buffer of latency samples.
+type latencies struct {
+	samples []time.Duration
+	next    int
+}
+
+func (l *latencies) add(d time.Duration, size int) {
+	if len(l.samples) < size {
+		l.samples = append(l.samples, d)
+		return
+	}
+	l.samples[l.next] = d
+	l.next = (l.next + 1) % len(l.samples)
+}
+
+func (l *latencies) percentile(p float64) (time.Duration, bool) {
+	if len(l.samples) < minSamples {
+		return 0, false
+	}
+	sorted := append([]time.Duration(nil), l.samples...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	return sorted[int(p*float64(len(sorted)-1))], true
+}
+
+// Hedger is a tg.Invoker that sends second attempt of read-only request,
+// if first one is not completed within delay, and uses the first
+// successful result.
+//
+// Delay is a percentile of latencies of recent successful requests of
+// method, bounded by WithDelay. Until enough requests are made, max
+// delay is used.
+//
+// Output of request must be a pointer, this is true for all result types
+// of tg package. Requests with other outputs are not hedged.
+type Hedger struct {
+	clock      clock.Clock
+	methods    map[string]struct{}
+	percentile float64
+	minDelay   time.Duration
+	maxDelay   time.Duration
+	window     int
+
+	mux       sync.Mutex
+	latencies map[string]*latencies
+}
+
+// New creates new Hedger of DefaultMethods.
+func New() *Hedger {
+	h := &Hedger{
+		clock:      clock.System,
+		methods:    map[string]struct{}{},
+		percentile: 0.95,
+		minDelay:   50 * time.Millisecond,
+		maxDelay:   2 * time.Second,
+		window:     100,
+		latencies:  map[string]*latencies{},
+	}
+	return h.WithMethods(DefaultMethods...)
+}
+
+// WithClock sets clock to use. Default is to use system clock.
+func (h *Hedger) WithClock(c clock.Clock) *Hedger {
+	h.clock = c
+	return h
+}
+
+// WithMethods adds given methods to the list of hedged ones. Methods
+// must not have side effects.
+func (h *Hedger) WithMethods(methods ...string) *Hedger {
+	for _, m := range methods {
+		h.methods[m] = struct{}{}
+	}
+	return h
+}
+
+// WithoutMethods removes given methods from the list of hedged ones.
+func (h *Hedger) WithoutMethods(methods ...string) *Hedger {
+	for _, m := range methods {
+		delete(h.methods, m)
+	}
+	return h
+}
+
+// WithPercentile sets latency percentile used as delay, from 0 to 1.
+// Default is 0.95.
+func (h *Hedger) WithPercentile(p float64) *Hedger {
+	h.percentile = p
+	return h
+}
+
+// WithDelay sets delay bounds. Default is 50ms and 2s.
+func (h *Hedger) WithDelay(minDelay, maxDelay time.Duration) *Hedger {
+	h.minDelay = minDelay
+	h.maxDelay = maxDelay
+	return h
+}
+
+// WithWindow sets count of recent requests of every method used to compute
+// delay. Default is 100, values less than 10 are treated as 10.
+func (h *Hedger) WithWindow(n int) *Hedger {
+	if n < minSamples {
+		n = minSamples
+	}
+	h.window = n
+	return h
+}
+
+// Delay returns current hedging delay of given method.
+func (h *Hedger) Delay(method string) time.Duration {
+	h.mux.Lock()
+	defer h.mux.Unlock()
+
+	l, ok := h.latencies[method]
+	if !ok {
+		return h.maxDelay
+	}
+	d, ok := l.percentile(h.percentile)
+	switch {
+	case !ok, d > h.maxDelay:
+		return h.maxDelay
+	case d < h.minDelay:
+		return h.minDelay
+	default:
+		return d
+	}
+}
+
+func (h *Hedger) observe(method string, d time.Duration) {
+	h.mux.Lock()
+	defer h.mux.Unlock()
+
+	l, ok := h.latencies[method]
+	if !ok {
+		l = &latencies{}
+		h.latencies[method] = l
+	}
+	l.add(d, h.window)
+}
+
+type attempt struct {
+	output bin.Decoder
+	err    error
+}
+
+// Handle implements telegram.Middleware.
+func (h *Hedger) Handle(next tg.Invoker) telegram.InvokeFunc {
+	return func(ctx context.Context, input bin.Encoder, output bin.Decoder) error {
+		obj, ok := input.(interface{ TypeName() string })
+		if !ok {
+			return next.Invoke(ctx, input, output)
+		}
+		method := obj.TypeName()
+		if _, ok := h.methods[method]; !ok {
+			return next.Invoke(ctx, input, output)
+		}
+		typ := reflect.TypeOf(output)
+		if typ == nil || typ.Kind() != reflect.Ptr {
+			return next.Invoke(ctx, input, output)
+		}
+
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		// Every attempt decodes to its own output, because loser is
+		// still running when winner is returned.
+		results := make(chan attempt, 2)
+		send := func() {
+			start := h.clock.Now()
+			out := reflect.New(typ.Elem()).Interface().(bin.Decoder)
+			err := next.Invoke(ctx, input, out)
+			if err == nil {
+				h.observe(method, h.clock.Now().Sub(start))
+			}
+			results <- attempt{output: out, err: err}
+		}
+		go send()
+
+		t := h.clock.Timer(h.Delay(method))
+		defer clock.StopTimer(t)
+
+		var (
+			pending  = 1
+			hedged   bool
+			firstErr error
+		)
+		for pending > 0 {
+			select {
+			case <-t.C():
+				if !hedged {
+					hedged = true
+					pending++
+					go send()
+				}
+			case res := <-results:
+				pending--
+				if res.err == nil {
+					reflect.ValueOf(output).Elem().Set(reflect.ValueOf(res.output).Elem())
+					return nil
+				}
+				if firstErr == nil {
+					firstErr = res.err
+				}
+				if !hedged {
+					// Errors are not hedged, use retry middleware.
+					return firstErr
+				}
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+		return firstErr
+	}
+}
@@ -0,0 +1,95 @@
+package hedge
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-faster/errors"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/atomic"
+
+	"github.com/gotd/neo"
+	"github.com/gotd/td/bin"
+	"github.com/gotd/td/telegram"
+	"github.com/gotd/td/tg"
+)
+
+func TestHedger(t *testing.T) {
+	a := require.New(t)
+	clock := neo.NewTime(time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC))
+
+	var calls atomic.Int64
+	stuck := make(chan struct{})
+	defer close(stuck)
+	h := New().WithClock(clock).WithDelay(time.Second, 5*time.Second)
+	raw := tg.NewClient(h.Handle(telegram.InvokeFunc(func(ctx context.Context, input bin.Encoder, output bin.Decoder) error {
+		if calls.Inc() == 1 {
+			// First attempt is stuck.
+			select {
+			case <-stuck:
+			case <-ctx.Done():
+			}
+			return ctx.Err()
+		}
+		*output.(*tg.MessagesChatFull) = tg.MessagesChatFull{
+			FullChat: &tg.ChatFull{ID: 10, About: "about"},
+		}
+		return nil
+	})))
+
+	observe := clock.Observe()
+	result := make(chan *tg.MessagesChatFull, 1)
+	go func() {
+		full, err := raw.MessagesGetFullChat(context.Background(), 10)
+		a.NoError(err)
+		result <- full
+	}()
+	<-observe
+	// Max delay is used until enough samples.
+	clock.Travel(5 * time.Second)
+
+	full := <-result
+	a.Equal("about", full.FullChat.(*tg.ChatFull).About)
+	a.Equal(int64(2), calls.Load())
+}
+
+func TestHedgerError(t *testing.T) {
+	a := require.New(t)
+	testErr := errors.New("test")
+	var calls atomic.Int64
+	raw := tg.NewClient(New().Handle(telegram.InvokeFunc(func(ctx context.Context, input bin.Encoder, output bin.Decoder) error {
+		calls.Inc()
+		return testErr
+	})))
+
+	_, err := raw.MessagesGetFullChat(context.Background(), 10)
+	a.ErrorIs(err, testErr)
+	a.Equal(int64(1), calls.Load())
+}
+
+func TestHedgerDelay(t *testing.T) {
+	a := require.New(t)
+	h := New().WithDelay(10*time.Millisecond, time.Second).WithPercentile(0.9)
+	a.Equal(time.Second, h.Delay("users.getUsers"))
+
+	for i := 1; i <= 10; i++ {
+		h.observe("users.getUsers", time.Duration(i)*100*time.Millisecond)
+	}
+	a.Equal(900*time.Millisecond, h.Delay("users.getUsers"))
+
+	h.WithWindow(10)
+	for i := 0; i < 10; i++ {
+		h.observe("users.getUsers", time.Millisecond)
+	}
+	a.Equal(10*time.Millisecond, h.Delay("users.getUsers"))
+
+	// Window is clamped to count of samples required to compute delay.
+	for _, n := range []int{-1, 0, 5} {
+		h := New().WithDelay(10*time.Millisecond, time.Second).WithWindow(n)
+		for i := 0; i < minSamples; i++ {
+			h.observe("users.getUsers", time.Millisecond)
+		}
+		a.Equal(10*time.Millisecond, h.Delay("users.getUsers"), n)
+	}
+}
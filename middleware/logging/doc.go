@@ -0,0 +1,3 @@
+// Package logging implements a tg.Invoker that logs RPC calls using zap
+// or log/slog.
+package logging
@@ -0,0 +1,192 @@
+package logging
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/go-faster/errors"
+
+	"github.com/gotd/td/bin"
+	"github.com/gotd/td/clock"
+	"github.com/gotd/td/telegram"
+	"github.com/gotd/td/tg"
+	"github.com/gotd/td/tgerr"
+)
+
+// Result is a class of call result.
+type Result string
+
+const (
+	// ResultOK is a successful call.
+	ResultOK Result = "ok"
+	// ResultFloodWait is a call failed with FLOOD_WAIT error.
+	ResultFloodWait Result = "flood_wait"
+	// ResultRPCError is a call failed with other RPC error.
+	ResultRPCError Result = "rpc_error"
+	// ResultCanceled is a call canceled by context.
+	ResultCanceled Result = "canceled"
+	// ResultError is a call failed with non-RPC error, e.g. network error.
+	ResultError Result = "error"
+)
+
+// ResultOf returns class of given call error.
+func ResultOf(err error) Result {
+	switch {
+	case err == nil:
+		return ResultOK
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return ResultCanceled
+	}
+	if _, ok := tgerr.AsFloodWait(err); ok {
+		return ResultFloodWait
+	}
+	if _, ok := tgerr.As(err); ok {
+		return ResultRPCError
+	}
+	return ResultError
+}
+
+// Entry is a log entry of call.
+type Entry struct {
+	// Method is a TL name of method, e.g. "messages.sendMessage".
+	Method   string
+	Duration time.Duration
+	Result   Result
+	// Err is a call error, nil if call succeeded.
+	Err error
+	// Request is a call request, set only if enabled by WithRequest.
+	Request fmt.Stringer
+	// DC is an ID of DC, set only if enabled by WithDC.
+	DC int
+	// Slow reports whether call is logged by Slow middleware.
+	Slow bool
+}
+
+// Logger logs call entries. See Zap and Slog.
+type Logger interface {
+	Log(ctx context.Context, e Entry)
+}
+
+// Middleware is a tg.Invoker that logs calls of underlying invoker.
+type Middleware struct {
+	log     Logger
+	clock   clock.Clock
+	sample  *sampler
+	request bool
+	dc      func() int
+}
+
+// New creates new logging middleware.
+func New(log Logger) *Middleware {
+	return &Middleware{
+		log:    log,
+		clock:  clock.System,
+		sample: newSampler(1),
+	}
+}
+
+// clone returns a copy of the Middleware.
+func (m *Middleware) clone() *Middleware {
+	return &Middleware{
+		log:     m.log,
+		clock:   m.clock,
+		sample:  m.sample,
+		request: m.request,
+		dc:      m.dc,
+	}
+}
+
+// WithClock sets clock to use. Default is to use system clock.
+func (m *Middleware) WithClock(c clock.Clock) *Middleware {
+	m = m.clone()
+	m.clock = c
+	return m
+}
+
+// WithSampling sets ratio of successful calls to log, from 0 to 1.
+// Failed calls are always logged. Default is 1, i.e. log all calls.
+func (m *Middleware) WithSampling(ratio float64) *Middleware {
+	m = m.clone()
+	m.sample = newSampler(ratio)
+	return m
+}
+
+// WithRequest enables logging of request body. Request may contain
+// sensitive data, e.g. message text or auth codes.
+func (m *Middleware) WithRequest(enabled bool) *Middleware {
+	m = m.clone()
+	m.request = enabled
+	return m
+}
+
+// WithDC sets function which returns ID of DC, e.g. from client config.
+func (m *Middleware) WithDC(f func() int) *Middleware {
+	m = m.clone()
+	m.dc = f
+	return m
+}
+
+// sampler samples events with given ratio.
+type sampler struct {
+	ratio float64
+
+	mux  sync.Mutex
+	rand *rand.Rand
+}
+
+func newSampler(ratio float64) *sampler {
+	return &sampler{
+		ratio: ratio,
+		rand:  rand.New(rand.NewSource(time.Now().UnixNano())), // #nosec G404
+	}
+}
+
+func (s *sampler) sampled() bool {
+	switch {
+	case s.ratio >= 1:
+		return true
+	case s.ratio <= 0:
+		return false
+	}
+
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	return s.rand.Float64() < s.ratio
+}
+
+// entry returns entry of call.
+func entry(input bin.Encoder, d time.Duration, err error, request bool, dc func() int) Entry {
+	e := Entry{
+		Duration: d,
+		Result:   ResultOf(err),
+		Err:      err,
+	}
+	if obj, ok := input.(interface{ TypeName() string }); ok {
+		e.Method = obj.TypeName()
+	}
+	if s, ok := input.(fmt.Stringer); ok && request {
+		e.Request = s
+	}
+	if dc != nil {
+		e.DC = dc()
+	}
+	return e
+}
+
+// Handle implements telegram.Middleware.
+func (m *Middleware) Handle(next tg.Invoker) telegram.InvokeFunc {
+	return func(ctx context.Context, input bin.Encoder, output bin.Decoder) error {
+		start := m.clock.Now()
+		err := next.Invoke(ctx, input, output)
+
+		if err == nil && !m.sample.sampled() {
+			return nil
+		}
+		m.log.Log(ctx, entry(input, m.clock.Now().Sub(start), err, m.request, m.dc))
+
+		return err
+	}
+}
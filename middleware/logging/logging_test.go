@@ -0,0 +1,122 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/go-faster/errors"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+
+	"github.com/gotd/neo"
+	"github.com/gotd/td/bin"
+	"github.com/gotd/td/telegram"
+	"github.com/gotd/td/tg"
+	"github.com/gotd/td/tgerr"
+)
+
+func TestResultOf(t *testing.T) {
+	a := require.New(t)
+	a.Equal(ResultOK, ResultOf(nil))
+	a.Equal(ResultFloodWait, ResultOf(tgerr.New(420, "FLOOD_WAIT_10")))
+	a.Equal(ResultRPCError, ResultOf(tgerr.New(400, "PEER_ID_INVALID")))
+	a.Equal(ResultCanceled, ResultOf(errors.Wrap(context.Canceled, "invoke")))
+	a.Equal(ResultError, ResultOf(errors.New("connection reset")))
+}
+
+func invoker(m *Middleware, clock *neo.Time, err error) tg.Invoker {
+	return m.Handle(telegram.InvokeFunc(func(ctx context.Context, input bin.Encoder, output bin.Decoder) error {
+		clock.Travel(time.Second)
+		return err
+	}))
+}
+
+func TestZap(t *testing.T) {
+	a := require.New(t)
+	ctx := context.Background()
+	clock := neo.NewTime(time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC))
+	core, logs := observer.New(zap.DebugLevel)
+	m := New(Zap(zap.New(core))).WithClock(clock).WithRequest(true)
+
+	req := &tg.HelpGetConfigRequest{}
+	a.NoError(invoker(m, clock, nil).Invoke(ctx, req, nil))
+	a.Error(invoker(m, clock, tgerr.New(420, "FLOOD_WAIT_10")).Invoke(ctx, req, nil))
+
+	entries := logs.AllUntimed()
+	a.Len(entries, 2)
+	a.Equal(zap.DebugLevel, entries[0].Level)
+	fields := entries[0].ContextMap()
+	a.Equal("help.getConfig", fields["method"])
+	a.Equal(time.Second, fields["duration"])
+	a.Equal("ok", fields["result"])
+	a.Contains(fields, "request")
+
+	a.Equal(zap.WarnLevel, entries[1].Level)
+	fields = entries[1].ContextMap()
+	a.Equal("flood_wait", fields["result"])
+	a.Contains(fields, "error")
+}
+
+func TestMiddlewareWith(t *testing.T) {
+	a := require.New(t)
+	ctx := context.Background()
+	clock := neo.NewTime(time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC))
+	core, logs := observer.New(zap.DebugLevel)
+	base := New(Zap(zap.New(core))).WithClock(clock)
+	derived := base.WithRequest(true)
+	a.NotSame(base, derived)
+
+	req := &tg.HelpGetConfigRequest{}
+	a.NoError(invoker(derived, clock, nil).Invoke(ctx, req, nil))
+	a.NoError(invoker(base, clock, nil).Invoke(ctx, req, nil))
+
+	entries := logs.AllUntimed()
+	a.Len(entries, 2)
+	a.Contains(entries[0].ContextMap(), "request")
+	// Base middleware does not log requests.
+	a.NotContains(entries[1].ContextMap(), "request")
+}
+
+func TestSlog(t *testing.T) {
+	a := require.New(t)
+	ctx := context.Background()
+	clock := neo.NewTime(time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC))
+	var buf bytes.Buffer
+	log := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelWarn}))
+	m := New(Slog(log)).WithClock(clock)
+
+	// Debug level is disabled.
+	a.NoError(invoker(m, clock, nil).Invoke(ctx, &tg.HelpGetConfigRequest{}, nil))
+	a.Zero(buf.Len())
+
+	a.Error(invoker(m, clock, tgerr.New(400, "PEER_ID_INVALID")).Invoke(ctx, &tg.HelpGetConfigRequest{}, nil))
+	var record map[string]any
+	a.NoError(json.Unmarshal(buf.Bytes(), &record))
+	a.Equal("WARN", record["level"])
+	a.Equal("help.getConfig", record["method"])
+	a.Equal("rpc_error", record["result"])
+	a.Contains(record["error"], "PEER_ID_INVALID")
+	a.NotContains(record, "request")
+}
+
+func TestSampling(t *testing.T) {
+	a := require.New(t)
+	ctx := context.Background()
+	clock := neo.NewTime(time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC))
+	core, logs := observer.New(zap.DebugLevel)
+	m := New(Zap(zap.New(core))).WithClock(clock).WithSampling(0)
+
+	for i := 0; i < 10; i++ {
+		a.NoError(invoker(m, clock, nil).Invoke(ctx, &tg.HelpGetConfigRequest{}, nil))
+	}
+	a.Zero(logs.Len())
+
+	// Failures are always logged.
+	a.Error(invoker(m, clock, errors.New("connection reset")).Invoke(ctx, &tg.HelpGetConfigRequest{}, nil))
+	a.Equal(1, logs.Len())
+}
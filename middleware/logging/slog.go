@@ -0,0 +1,46 @@
+package logging
+
+import (
+	"context"
+	"log/slog"
+)
+
+type slogLogger struct {
+	log *slog.Logger
+}
+
+// Slog returns Logger which logs successful calls with debug level and
+// failed or slow calls with warn level.
+func Slog(log *slog.Logger) Logger {
+	return slogLogger{log: log}
+}
+
+func (s slogLogger) Log(ctx context.Context, e Entry) {
+	level := slog.LevelDebug
+	if e.Err != nil || e.Slow {
+		level = slog.LevelWarn
+	}
+	if !s.log.Enabled(ctx, level) {
+		return
+	}
+
+	attrs := []slog.Attr{
+		slog.String("method", e.Method),
+		slog.Duration("duration", e.Duration),
+		slog.String("result", string(e.Result)),
+	}
+	if e.DC != 0 {
+		attrs = append(attrs, slog.Int("dc", e.DC))
+	}
+	if e.Err != nil {
+		attrs = append(attrs, slog.String("error", e.Err.Error()))
+	}
+	if e.Request != nil {
+		attrs = append(attrs, slog.String("request", e.Request.String()))
+	}
+	msg := "RPC call"
+	if e.Slow {
+		msg = "Slow RPC call"
+	}
+	s.log.LogAttrs(ctx, level, msg, attrs...)
+}
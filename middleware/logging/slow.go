@@ -0,0 +1,86 @@
+package logging
+
+import (
+	"context"
+	"time"
+
+	"github.com/gotd/td/bin"
+	"github.com/gotd/td/clock"
+	"github.com/gotd/td/telegram"
+	"github.com/gotd/td/tg"
+)
+
+// Slow is a tg.Invoker that logs calls of underlying invoker which take
+// longer than threshold, to diagnose tail latency.
+//
+// Entries of slow calls have Slow flag set, so adapters log them with warn
+// level.
+type Slow struct {
+	log       Logger
+	clock     clock.Clock
+	threshold time.Duration
+	dump      *sampler
+	dc        func() int
+}
+
+// NewSlow creates new slow call logging middleware.
+func NewSlow(log Logger, threshold time.Duration) *Slow {
+	return &Slow{
+		log:       log,
+		clock:     clock.System,
+		threshold: threshold,
+		dump:      newSampler(0),
+	}
+}
+
+// clone returns a copy of the Slow.
+func (s *Slow) clone() *Slow {
+	return &Slow{
+		log:       s.log,
+		clock:     s.clock,
+		threshold: s.threshold,
+		dump:      s.dump,
+		dc:        s.dc,
+	}
+}
+
+// WithClock sets clock to use. Default is to use system clock.
+func (s *Slow) WithClock(c clock.Clock) *Slow {
+	s = s.clone()
+	s.clock = c
+	return s
+}
+
+// WithRequestSampling sets ratio of slow calls to log with request body,
+// from 0 to 1. Request may contain sensitive data, e.g. message text or
+// auth codes. Default is 0, i.e. do not log requests.
+func (s *Slow) WithRequestSampling(ratio float64) *Slow {
+	s = s.clone()
+	s.dump = newSampler(ratio)
+	return s
+}
+
+// WithDC sets function which returns ID of DC, e.g. from client config.
+func (s *Slow) WithDC(f func() int) *Slow {
+	s = s.clone()
+	s.dc = f
+	return s
+}
+
+// Handle implements telegram.Middleware.
+func (s *Slow) Handle(next tg.Invoker) telegram.InvokeFunc {
+	return func(ctx context.Context, input bin.Encoder, output bin.Decoder) error {
+		start := s.clock.Now()
+		err := next.Invoke(ctx, input, output)
+
+		d := s.clock.Now().Sub(start)
+		if d < s.threshold {
+			return err
+		}
+		e := entry(input, d, err, s.dump.sampled(), s.dc)
+		e.Slow = true
+		s.log.Log(ctx, e)
+
+		return err
+	}
+}
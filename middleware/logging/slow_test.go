@@ -0,0 +1,49 @@
+package logging
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+
+	"github.com/gotd/neo"
+	"github.com/gotd/td/bin"
+	"github.com/gotd/td/telegram"
+	"github.com/gotd/td/tg"
+)
+
+func TestSlow(t *testing.T) {
+	a := require.New(t)
+	ctx := context.Background()
+	clock := neo.NewTime(time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC))
+	core, logs := observer.New(zap.DebugLevel)
+
+	var took time.Duration
+	invoker := NewSlow(Zap(zap.New(core)), 2*time.Second).
+		WithClock(clock).
+		WithRequestSampling(1).
+		WithDC(func() int { return 4 }).
+		Handle(telegram.InvokeFunc(func(ctx context.Context, input bin.Encoder, output bin.Decoder) error {
+			clock.Travel(took)
+			return nil
+		}))
+
+	took = time.Second
+	a.NoError(invoker.Invoke(ctx, &tg.HelpGetConfigRequest{}, nil))
+	a.Zero(logs.Len())
+
+	took = 3 * time.Second
+	a.NoError(invoker.Invoke(ctx, &tg.HelpGetConfigRequest{}, nil))
+	entries := logs.AllUntimed()
+	a.Len(entries, 1)
+	a.Equal(zap.WarnLevel, entries[0].Level)
+	a.Equal("Slow RPC call", entries[0].Message)
+	fields := entries[0].ContextMap()
+	a.Equal("help.getConfig", fields["method"])
+	a.Equal(3*time.Second, fields["duration"])
+	a.Equal(int64(4), fields["dc"])
+	a.Contains(fields, "request")
+}
@@ -0,0 +1,49 @@
+package logging
+
+import (
+	"context"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+type zapLogger struct {
+	log *zap.Logger
+}
+
+// Zap returns Logger which logs successful calls with debug level and
+// failed or slow calls with warn level.
+func Zap(log *zap.Logger) Logger {
+	return zapLogger{log: log}
+}
+
+func (z zapLogger) Log(ctx context.Context, e Entry) {
+	level := zapcore.DebugLevel
+	if e.Err != nil || e.Slow {
+		level = zapcore.WarnLevel
+	}
+	msg := "RPC call"
+	if e.Slow {
+		msg = "Slow RPC call"
+	}
+	ce := z.log.Check(level, msg)
+	if ce == nil {
+		return
+	}
+
+	fields := []zap.Field{
+		zap.String("method", e.Method),
+		zap.Duration("duration", e.Duration),
+		zap.String("result", string(e.Result)),
+	}
+	if e.DC != 0 {
+		fields = append(fields, zap.Int("dc", e.DC))
+	}
+	if e.Err != nil {
+		fields = append(fields, zap.Error(e.Err))
+	}
+	if e.Request != nil {
+		fields = append(fields, zap.Stringer("request", e.Request))
+	}
+	ce.Write(fields...)
+}
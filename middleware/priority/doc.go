@@ -0,0 +1,3 @@
+// Package priority implements a tg.Invoker that schedules requests by
+// priority class.
+package priority
@@ -0,0 +1,51 @@
+package priority
+
+import (
+	"context"
+	"strconv"
+)
+
+// Priority is a request priority class.
+type Priority int
+
+const (
+	// Interactive is a priority of user-facing requests, e.g. replies.
+	Interactive Priority = iota
+	// Background is a priority of background requests, e.g. sync.
+	Background
+	// Bulk is a priority of bulk requests, e.g. history export.
+	Bulk
+
+	classes = iota
+)
+
+// String implements fmt.Stringer.
+func (p Priority) String() string {
+	switch p {
+	case Interactive:
+		return "interactive"
+	case Background:
+		return "background"
+	case Bulk:
+		return "bulk"
+	default:
+		return "Priority(" + strconv.Itoa(int(p)) + ")"
+	}
+}
+
+func (p Priority) valid() bool {
+	return p >= 0 && p < classes
+}
+
+type priorityKey struct{}
+
+// With returns new context with given request priority.
+func With(ctx context.Context, p Priority) context.Context {
+	return context.WithValue(ctx, priorityKey{}, p)
+}
+
+// FromContext returns request priority from context, if any.
+func FromContext(ctx context.Context) (Priority, bool) {
+	p, ok := ctx.Value(priorityKey{}).(Priority)
+	return p, ok
+}
@@ -0,0 +1,165 @@
+package priority
+
+import (
+	"context"
+	"sync"
+
+	"github.com/gotd/td/bin"
+	"github.com/gotd/td/telegram"
+	"github.com/gotd/td/tg"
+)
+
+type waiter struct {
+	ready chan struct{}
+}
+
+// Scheduler is a tg.Invoker that limits count of in-flight requests and
+// schedules waiting ones by priority using weighted fair queuing.
+//
+// Every class gets share of slots proportional to its weight while it has
+// waiting requests, so bulk requests do not starve interactive ones, and
+// are not starved themselves. Default weights are 16, 4 and 1 for
+// Interactive, Background and Bulk.
+//
+// Priority of request is set by With. Requests without priority are
+// Interactive, see WithDefault.
+type Scheduler struct {
+	limit   int
+	def     Priority
+	weights [classes]int
+
+	mux      sync.Mutex
+	inFlight int
+	queues   [classes][]*waiter
+	// pass is a virtual finish time of every class, class with least
+	// pass is scheduled first.
+	pass [classes]float64
+	// now is a virtual time, i.e. pass of last scheduled class.
+	now float64
+}
+
+// New creates new Scheduler with given limit of in-flight requests.
+func New(limit int) *Scheduler {
+	return &Scheduler{
+		limit:   limit,
+		def:     Interactive,
+		weights: [classes]int{16, 4, 1},
+	}
+}
+
+// WithWeight sets weight of given priority class.
+func (s *Scheduler) WithWeight(p Priority, weight int) *Scheduler {
+	if p.valid() && weight > 0 {
+		s.weights[p] = weight
+	}
+	return s
+}
+
+// WithDefault sets priority of requests without one. Default is Interactive.
+func (s *Scheduler) WithDefault(p Priority) *Scheduler {
+	if p.valid() {
+		s.def = p
+	}
+	return s
+}
+
+// Waiting returns count of requests waiting with given priority.
+func (s *Scheduler) Waiting(p Priority) int {
+	if !p.valid() {
+		return 0
+	}
+
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	return len(s.queues[p])
+}
+
+func (s *Scheduler) priority(ctx context.Context) Priority {
+	if p, ok := FromContext(ctx); ok && p.valid() {
+		return p
+	}
+	return s.def
+}
+
+func (s *Scheduler) queued() bool {
+	for _, q := range s.queues {
+		if len(q) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Scheduler) acquire(ctx context.Context, p Priority) error {
+	s.mux.Lock()
+	if s.inFlight < s.limit && !s.queued() {
+		s.inFlight++
+		s.mux.Unlock()
+		return nil
+	}
+
+	w := &waiter{ready: make(chan struct{})}
+	if len(s.queues[p]) == 0 && s.pass[p] < s.now {
+		// Idle class does not accumulate credit.
+		s.pass[p] = s.now
+	}
+	s.queues[p] = append(s.queues[p], w)
+	s.mux.Unlock()
+
+	select {
+	case <-w.ready:
+		return nil
+	case <-ctx.Done():
+	}
+
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	for i, q := range s.queues[p] {
+		if q == w {
+			s.queues[p] = append(s.queues[p][:i], s.queues[p][i+1:]...)
+			return ctx.Err()
+		}
+	}
+	// Slot is already handed to this request.
+	s.releaseLocked()
+	return ctx.Err()
+}
+
+func (s *Scheduler) release() {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	s.releaseLocked()
+}
+
+// releaseLocked hands slot to the next waiting request, if any.
+func (s *Scheduler) releaseLocked() {
+	next := -1
+	for p, q := range s.queues {
+		if len(q) > 0 && (next < 0 || s.pass[p] < s.pass[next]) {
+			next = p
+		}
+	}
+	if next < 0 {
+		s.inFlight--
+		return
+	}
+
+	w := s.queues[next][0]
+	s.queues[next][0] = nil
+	s.queues[next] = s.queues[next][1:]
+	s.now = s.pass[next]
+	s.pass[next] += 1 / float64(s.weights[next])
+	close(w.ready)
+}
+
+// Handle implements telegram.Middleware.
+func (s *Scheduler) Handle(next tg.Invoker) telegram.InvokeFunc {
+	return func(ctx context.Context, input bin.Encoder, output bin.Decoder) error {
+		if err := s.acquire(ctx, s.priority(ctx)); err != nil {
+			return err
+		}
+		defer s.release()
+
+		return next.Invoke(ctx, input, output)
+	}
+}
@@ -0,0 +1,97 @@
+package priority
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/gotd/td/bin"
+	"github.com/gotd/td/telegram"
+	"github.com/gotd/td/tg"
+)
+
+func TestScheduler(t *testing.T) {
+	a := require.New(t)
+	s := New(1).WithWeight(Interactive, 2).WithWeight(Bulk, 1)
+
+	var (
+		mux   sync.Mutex
+		order []Priority
+	)
+	block := make(chan struct{})
+	invoker := s.Handle(telegram.InvokeFunc(func(ctx context.Context, input bin.Encoder, output bin.Decoder) error {
+		p, ok := FromContext(ctx)
+		if !ok {
+			<-block
+			return nil
+		}
+		mux.Lock()
+		order = append(order, p)
+		mux.Unlock()
+		return nil
+	}))
+
+	var wg sync.WaitGroup
+	invoke := func(ctx context.Context) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			a.NoError(invoker.Invoke(ctx, &tg.HelpGetConfigRequest{}, nil))
+		}()
+	}
+	invoke(context.Background())
+	a.Eventually(func() bool {
+		s.mux.Lock()
+		defer s.mux.Unlock()
+		return s.inFlight == 1
+	}, time.Second, time.Millisecond)
+
+	for i := 0; i < 4; i++ {
+		invoke(With(context.Background(), Bulk))
+		invoke(With(context.Background(), Interactive))
+	}
+	a.Eventually(func() bool {
+		return s.Waiting(Bulk) == 4 && s.Waiting(Interactive) == 4
+	}, time.Second, time.Millisecond)
+
+	close(block)
+	wg.Wait()
+	a.Equal([]Priority{
+		Interactive, Bulk, Interactive, Interactive,
+		Bulk, Interactive, Bulk, Bulk,
+	}, order)
+	a.Zero(s.inFlight)
+}
+
+func TestSchedulerCanceled(t *testing.T) {
+	a := require.New(t)
+	s := New(1)
+	block := make(chan struct{})
+	invoker := s.Handle(telegram.InvokeFunc(func(ctx context.Context, input bin.Encoder, output bin.Decoder) error {
+		<-block
+		return nil
+	}))
+
+	done := make(chan error, 1)
+	go func() { done <- invoker.Invoke(context.Background(), &tg.HelpGetConfigRequest{}, nil) }()
+	a.Eventually(func() bool {
+		s.mux.Lock()
+		defer s.mux.Unlock()
+		return s.inFlight == 1
+	}, time.Second, time.Millisecond)
+
+	ctx, cancel := context.WithCancel(With(context.Background(), Bulk))
+	canceled := make(chan error, 1)
+	go func() { canceled <- invoker.Invoke(ctx, &tg.HelpGetConfigRequest{}, nil) }()
+	a.Eventually(func() bool { return s.Waiting(Bulk) == 1 }, time.Second, time.Millisecond)
+	cancel()
+	a.ErrorIs(<-canceled, context.Canceled)
+	a.Zero(s.Waiting(Bulk))
+
+	close(block)
+	a.NoError(<-done)
+	a.Zero(s.inFlight)
+}
@@ -0,0 +1,124 @@
+package ratelimit
+
+import (
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+
+	"github.com/gotd/td/tgerr"
+)
+
+// Adaptive is a configuration of adaptive rate limiting, see
+// RateLimiter.WithAdaptive.
+type Adaptive struct {
+	// Min is a minimum rate limit. Default is one request per minute.
+	Min rate.Limit
+	// Decrease is a factor applied to rate limit on flood wait. Default
+	// is 0.5.
+	Decrease float64
+	// Increase is a fraction of configured rate limit which is restored
+	// every Interval without flood waits. Default is 0.1.
+	Increase float64
+	// Interval is a recovery interval. Default is 10s.
+	Interval time.Duration
+}
+
+func (a *Adaptive) setDefaults() {
+	if a.Min <= 0 {
+		a.Min = rate.Every(time.Minute)
+	}
+	if a.Decrease <= 0 || a.Decrease >= 1 {
+		a.Decrease = 0.5
+	}
+	if a.Increase <= 0 {
+		a.Increase = 0.1
+	}
+	if a.Interval <= 0 {
+		a.Interval = 10 * time.Second
+	}
+}
+
+type adaptiveLimit struct {
+	// max is a configured rate limit.
+	max     rate.Limit
+	changed time.Time
+}
+
+// adaptive tunes rate limits AIMD-style: limit is decreased
+// multiplicatively on flood wait and increased additively otherwise.
+type adaptive struct {
+	cfg Adaptive
+
+	mux    sync.Mutex
+	limits map[*rate.Limiter]*adaptiveLimit
+}
+
+func newAdaptive(cfg Adaptive) *adaptive {
+	cfg.setDefaults()
+	return &adaptive{
+		cfg:    cfg,
+		limits: map[*rate.Limiter]*adaptiveLimit{},
+	}
+}
+
+// get returns state of given limiter. Assumes the mutex is locked.
+func (a *adaptive) get(lim *rate.Limiter, now time.Time) *adaptiveLimit {
+	s, ok := a.limits[lim]
+	if !ok {
+		s = &adaptiveLimit{max: lim.Limit(), changed: now}
+		a.limits[lim] = s
+	}
+	return s
+}
+
+// configure sets configured rate limit of given limiter.
+func (a *adaptive) configure(lim *rate.Limiter, r rate.Limit, now time.Time) {
+	a.mux.Lock()
+	defer a.mux.Unlock()
+
+	s := a.get(lim, now)
+	s.max = r
+	s.changed = now
+}
+
+// observe tunes limiter using result of request.
+func (a *adaptive) observe(lim *rate.Limiter, err error, now time.Time) {
+	flood := false
+	if rpcErr, ok := tgerr.As(err); ok && rpcErr.Code == 420 {
+		flood = true
+	} else if err != nil {
+		// Other errors tell nothing about rate.
+		return
+	}
+
+	a.mux.Lock()
+	defer a.mux.Unlock()
+
+	s := a.get(lim, now)
+	if s.max == rate.Inf {
+		return
+	}
+	current := lim.Limit()
+	if flood {
+		r := rate.Limit(float64(current) * a.cfg.Decrease)
+		if r < a.cfg.Min {
+			r = a.cfg.Min
+		}
+		if r < current {
+			lim.SetLimitAt(now, r)
+		}
+		s.changed = now
+		return
+	}
+
+	if current >= s.max || now.Sub(s.changed) < a.cfg.Interval {
+		return
+	}
+	r := current + rate.Limit(float64(s.max)*a.cfg.Increase)
+	if r > s.max {
+		r = s.max
+	}
+	lim.SetLimitAt(now, r)
+	s.changed = now
+}
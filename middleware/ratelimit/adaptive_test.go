@@ -0,0 +1,59 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/time/rate"
+
+	"github.com/gotd/neo"
+	"github.com/gotd/td/bin"
+	"github.com/gotd/td/telegram"
+	"github.com/gotd/td/tg"
+	"github.com/gotd/td/tgerr"
+)
+
+func TestRateLimiterAdaptive(t *testing.T) {
+	a := require.New(t)
+	ctx := context.Background()
+	clock := neo.NewTime(time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC))
+
+	var flood bool
+	l := New(rate.Inf, 1).
+		WithClock(clock).
+		WithMethod("messages.sendMessage", 10, 100).
+		WithAdaptive(Adaptive{Min: 2, Interval: time.Second})
+	invoker := l.Handle(telegram.InvokeFunc(func(ctx context.Context, input bin.Encoder, output bin.Decoder) error {
+		if flood {
+			return tgerr.New(420, "FLOOD_WAIT_1")
+		}
+		return nil
+	}))
+	send := &tg.MessagesSendMessageRequest{}
+	limit := func() float64 {
+		return l.Snapshot().Methods["messages.sendMessage"].Limit
+	}
+
+	flood = true
+	a.Error(invoker.Invoke(ctx, send, nil))
+	a.Equal(5.0, limit())
+	a.Error(invoker.Invoke(ctx, send, nil))
+	a.Equal(2.5, limit())
+	a.Error(invoker.Invoke(ctx, send, nil))
+	a.Equal(2.0, limit())
+
+	// Other methods are not affected.
+	a.Error(invoker.Invoke(ctx, &tg.HelpGetConfigRequest{}, nil))
+	a.Equal(float64(rate.Inf), l.Snapshot().Limit)
+
+	flood = false
+	a.NoError(invoker.Invoke(ctx, send, nil))
+	a.Equal(2.0, limit(), "recovered before interval")
+	for i := 0; i < 10; i++ {
+		clock.Travel(time.Second)
+		a.NoError(invoker.Invoke(ctx, send, nil))
+	}
+	a.Equal(10.0, limit())
+}
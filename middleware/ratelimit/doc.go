@@ -1,2 +1,3 @@
-// Package ratelimit implements a tg.Invoker that limits request rate.
+// Package ratelimit implements a tg.Invoker that limits request rate,
+// globally, per method or per target chat.
 package ratelimit
@@ -0,0 +1,131 @@
+package ratelimit
+
+import (
+	"context"
+
+	"go.uber.org/atomic"
+	"golang.org/x/time/rate"
+
+	"github.com/gotd/td/bin"
+	"github.com/gotd/td/clock"
+	"github.com/gotd/td/telegram"
+	"github.com/gotd/td/tg"
+)
+
+// Limit is a rate limit with burst size. Zero Limit means no limit.
+type Limit struct {
+	Rate  rate.Limit `json:"rate"`
+	Burst int        `json:"burst"`
+}
+
+func (l Limit) zero() bool {
+	return l.Rate == 0 && l.Burst == 0
+}
+
+func (l Limit) limiter() *rate.Limiter {
+	return rate.NewLimiter(l.Rate, l.Burst)
+}
+
+// Config is a configuration of Hierarchy.
+type Config struct {
+	// Global limits all requests.
+	Global Limit `json:"global"`
+	// Methods limit requests of method by TL name, e.g.
+	// "messages.sendMessage".
+	Methods map[string]Limit `json:"methods"`
+	// User limits sending of messages to every user, see PeerLimiter.
+	User Limit `json:"user"`
+	// Chat limits sending of messages to every group or channel, see
+	// PeerLimiter.
+	Chat Limit `json:"chat"`
+}
+
+// Hierarchy is a tg.Invoker that combines global, per-method and per-peer
+// rate limits. Request is sent when all applicable limits allow it.
+//
+// Unlike RateLimiter.WithMethod, method limits are applied in addition to
+// global one, not instead of it.
+type Hierarchy struct {
+	clock   clock.Clock
+	global  *rate.Limiter
+	methods map[string]*rate.Limiter
+	peers   *PeerLimiter
+
+	waiting *atomic.Int64
+}
+
+// NewHierarchy creates new Hierarchy from given config.
+func NewHierarchy(cfg Config) *Hierarchy {
+	h := &Hierarchy{
+		clock:   clock.System,
+		methods: make(map[string]*rate.Limiter, len(cfg.Methods)),
+		waiting: atomic.NewInt64(0),
+	}
+	if !cfg.Global.zero() {
+		h.global = cfg.Global.limiter()
+	}
+	for method, limit := range cfg.Methods {
+		if !limit.zero() {
+			h.methods[method] = limit.limiter()
+		}
+	}
+	if !cfg.User.zero() || !cfg.Chat.zero() {
+		h.peers = NewPeerLimiter().
+			WithUserLimit(rate.Inf, 0).
+			WithChatLimit(rate.Inf, 0)
+		if !cfg.User.zero() {
+			h.peers = h.peers.WithUserLimit(cfg.User.Rate, cfg.User.Burst)
+		}
+		if !cfg.Chat.zero() {
+			h.peers = h.peers.WithChatLimit(cfg.Chat.Rate, cfg.Chat.Burst)
+		}
+	}
+	return h
+}
+
+// WithClock sets clock to use. Default is to use system clock.
+func (h *Hierarchy) WithClock(c clock.Clock) *Hierarchy {
+	h.clock = c
+	if h.peers != nil {
+		h.peers = h.peers.WithClock(c)
+	}
+	return h
+}
+
+// Waiting returns count of requests waiting for permission.
+func (h *Hierarchy) Waiting() int64 {
+	return h.waiting.Load()
+}
+
+// limiters returns all limiters applicable to given request.
+func (h *Hierarchy) limiters(input bin.Encoder) []*rate.Limiter {
+	lims := make([]*rate.Limiter, 0, 3)
+	if h.global != nil {
+		lims = append(lims, h.global)
+	}
+	if obj, ok := input.(object); ok {
+		if lim, ok := h.methods[obj.TypeName()]; ok {
+			lims = append(lims, lim)
+		}
+	}
+	if h.peers != nil {
+		if peer, ok := targetPeer(input); ok {
+			if lim, ok := h.peers.limiter(peer); ok {
+				lims = append(lims, lim)
+			}
+		}
+	}
+	return lims
+}
+
+// Handle implements telegram.Middleware.
+func (h *Hierarchy) Handle(next tg.Invoker) telegram.InvokeFunc {
+	return func(ctx context.Context, input bin.Encoder, output bin.Decoder) error {
+		if lims := h.limiters(input); len(lims) > 0 {
+			if err := wait(ctx, h.clock, h.waiting, lims...); err != nil {
+				return err
+			}
+		}
+		return next.Invoke(ctx, input, output)
+	}
+}
@@ -0,0 +1,78 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/time/rate"
+
+	"github.com/gotd/neo"
+	"github.com/gotd/td/bin"
+	"github.com/gotd/td/telegram"
+	"github.com/gotd/td/tg"
+)
+
+func TestHierarchy(t *testing.T) {
+	a := require.New(t)
+	ctx := context.Background()
+	clock := neo.NewTime(time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC))
+
+	h := NewHierarchy(Config{
+		Global: Limit{Rate: rate.Every(time.Second), Burst: 2},
+		Methods: map[string]Limit{
+			"messages.sendMessage": {Rate: rate.Every(10 * time.Second), Burst: 1},
+		},
+		User: Limit{Rate: rate.Every(time.Second), Burst: 1},
+	}).WithClock(clock)
+	invoker := h.Handle(telegram.InvokeFunc(func(ctx context.Context, input bin.Encoder, output bin.Decoder) error {
+		return nil
+	}))
+
+	a.NoError(invoker.Invoke(ctx, &tg.MessagesSendMessageRequest{Peer: &tg.InputPeerUser{UserID: 1}}, nil))
+	a.NoError(invoker.Invoke(ctx, &tg.HelpGetConfigRequest{}, nil))
+
+	// Global limit allows request after 1s, but method limit after 10s.
+	observe := clock.Observe()
+	done := make(chan error, 1)
+	go func() {
+		done <- invoker.Invoke(ctx, &tg.MessagesSendMessageRequest{Peer: &tg.InputPeerUser{UserID: 2}}, nil)
+	}()
+	<-observe
+	a.Equal(int64(1), h.Waiting())
+	a.Len(done, 0)
+	clock.Travel(10 * time.Second)
+	a.NoError(<-done)
+	a.Zero(h.Waiting())
+}
+
+func TestHierarchyPeer(t *testing.T) {
+	a := require.New(t)
+	ctx := context.Background()
+	clock := neo.NewTime(time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC))
+
+	h := NewHierarchy(Config{
+		Chat: Limit{Rate: rate.Every(3 * time.Second), Burst: 1},
+	}).WithClock(clock)
+	invoker := h.Handle(telegram.InvokeFunc(func(ctx context.Context, input bin.Encoder, output bin.Decoder) error {
+		return nil
+	}))
+
+	// Users are not limited.
+	user := &tg.InputPeerUser{UserID: 1}
+	for i := 0; i < 3; i++ {
+		a.NoError(invoker.Invoke(ctx, &tg.MessagesSendMessageRequest{Peer: user}, nil))
+	}
+
+	chat := &tg.InputPeerChat{ChatID: 1}
+	a.NoError(invoker.Invoke(ctx, &tg.MessagesSendMessageRequest{Peer: chat}, nil))
+	observe := clock.Observe()
+	done := make(chan error, 1)
+	go func() {
+		done <- invoker.Invoke(ctx, &tg.MessagesSendMediaRequest{Peer: chat}, nil)
+	}()
+	<-observe
+	clock.Travel(3 * time.Second)
+	a.NoError(<-done)
+}
@@ -0,0 +1,184 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go.uber.org/atomic"
+	"golang.org/x/time/rate"
+
+	"github.com/gotd/td/bin"
+	"github.com/gotd/td/clock"
+	"github.com/gotd/td/telegram"
+	"github.com/gotd/td/telegram/query/dialogs"
+	"github.com/gotd/td/tg"
+)
+
+type peerKey struct {
+	Kind dialogs.PeerKind
+	ID   int64
+}
+
+type peerLimit struct {
+	r rate.Limit
+	b int
+}
+
+// PeerLimiter is a tg.Invoker that throttles sending of messages per chat.
+//
+// It inspects target peer of messages.sendMessage, messages.sendMedia,
+// messages.sendMultiMedia, messages.forwardMessages and
+// messages.sendInlineBotResult, and maintains token bucket per peer.
+// Other calls are passed as is.
+//
+// Default limits are Telegram's practical limits: one message per second
+// to user and 20 messages per minute to group or channel.
+type PeerLimiter struct {
+	clock clock.Clock
+	user  peerLimit
+	chat  peerLimit
+
+	mux      sync.Mutex
+	limiters map[peerKey]*rate.Limiter
+	// sweepAt is a count of limiters which triggers removal of idle ones.
+	sweepAt int
+	waiting *atomic.Int64
+}
+
+// NewPeerLimiter creates new PeerLimiter.
+func NewPeerLimiter() *PeerLimiter {
+	return &PeerLimiter{
+		clock:    clock.System,
+		user:     peerLimit{r: rate.Every(time.Second), b: 1},
+		chat:     peerLimit{r: rate.Every(3 * time.Second), b: 1},
+		limiters: map[peerKey]*rate.Limiter{},
+		sweepAt:  minSweep,
+		waiting:  atomic.NewInt64(0),
+	}
+}
+
+// minSweep is a minimum count of peer limiters to sweep idle ones.
+const minSweep = 1024
+
+// clone returns a copy of the PeerLimiter.
+//
+// Per-peer limiters are created from configured limits, so the copy starts
+// with its own empty set of them.
+func (l *PeerLimiter) clone() *PeerLimiter {
+	return &PeerLimiter{
+		clock:    l.clock,
+		user:     l.user,
+		chat:     l.chat,
+		limiters: map[peerKey]*rate.Limiter{},
+		sweepAt:  minSweep,
+		waiting:  atomic.NewInt64(0),
+	}
+}
+
+// WithClock sets clock to use. Default is to use system clock.
+func (l *PeerLimiter) WithClock(c clock.Clock) *PeerLimiter {
+	l = l.clone()
+	l.clock = c
+	return l
+}
+
+// WithUserLimit sets rate limit and burst size of messages to every user.
+// Use rate.Inf to disable limit.
+func (l *PeerLimiter) WithUserLimit(r rate.Limit, b int) *PeerLimiter {
+	l = l.clone()
+	l.user = peerLimit{r: r, b: b}
+	return l
+}
+
+// WithChatLimit sets rate limit and burst size of messages to every group
+// or channel. Use rate.Inf to disable limit.
+func (l *PeerLimiter) WithChatLimit(r rate.Limit, b int) *PeerLimiter {
+	l = l.clone()
+	l.chat = peerLimit{r: r, b: b}
+	return l
+}
+
+// Waiting returns count of requests waiting for permission.
+func (l *PeerLimiter) Waiting() int64 {
+	return l.waiting.Load()
+}
+
+// targetPeer returns target peer of sending request.
+func targetPeer(input bin.Encoder) (tg.InputPeerClass, bool) {
+	switch r := input.(type) {
+	case *tg.MessagesSendMessageRequest:
+		return r.Peer, true
+	case *tg.MessagesSendMediaRequest:
+		return r.Peer, true
+	case *tg.MessagesSendMultiMediaRequest:
+		return r.Peer, true
+	case *tg.MessagesForwardMessagesRequest:
+		return r.ToPeer, true
+	case *tg.MessagesSendInlineBotResultRequest:
+		return r.Peer, true
+	default:
+		return nil, false
+	}
+}
+
+// limiter returns limiter of given peer.
+func (l *PeerLimiter) limiter(peer tg.InputPeerClass) (*rate.Limiter, bool) {
+	var k dialogs.DialogKey
+	if err := k.FromInputPeer(peer); err != nil {
+		// E.g. inputPeerSelf.
+		return nil, false
+	}
+	key := peerKey{Kind: k.Kind, ID: k.ID}
+
+	limit := l.chat
+	if key.Kind == dialogs.User {
+		limit = l.user
+	}
+	if limit.r == rate.Inf {
+		return nil, false
+	}
+
+	l.mux.Lock()
+	defer l.mux.Unlock()
+
+	if lim, ok := l.limiters[key]; ok {
+		return lim, true
+	}
+	if len(l.limiters) >= l.sweepAt {
+		l.sweep()
+	}
+
+	lim := rate.NewLimiter(limit.r, limit.b)
+	l.limiters[key] = lim
+	return lim, true
+}
+
+// sweep removes limiters with full bucket, because they do not limit
+// anything.
+func (l *PeerLimiter) sweep() {
+	now := l.clock.Now()
+	for key, lim := range l.limiters {
+		if lim.TokensAt(now) >= float64(lim.Burst()) {
+			delete(l.limiters, key)
+		}
+	}
+	l.sweepAt = 2 * len(l.limiters)
+	if l.sweepAt < minSweep {
+		l.sweepAt = minSweep
+	}
+}
+
+// Handle implements telegram.Middleware.
+func (l *PeerLimiter) Handle(next tg.Invoker) telegram.InvokeFunc {
+	return func(ctx context.Context, input bin.Encoder, output bin.Decoder) error {
+		if peer, ok := targetPeer(input); ok {
+			if lim, ok := l.limiter(peer); ok {
+				if err := wait(ctx, l.clock, l.waiting, lim); err != nil {
+					return err
+				}
+			}
+		}
+		return next.Invoke(ctx, input, output)
+	}
+}
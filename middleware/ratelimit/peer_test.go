@@ -0,0 +1,98 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/time/rate"
+
+	"github.com/gotd/neo"
+	"github.com/gotd/td/bin"
+	"github.com/gotd/td/telegram"
+	"github.com/gotd/td/tg"
+)
+
+func TestPeerLimiter(t *testing.T) {
+	a := require.New(t)
+	ctx := context.Background()
+	clock := neo.NewTime(time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC))
+
+	l := NewPeerLimiter().WithClock(clock)
+	invoker := l.Handle(telegram.InvokeFunc(func(ctx context.Context, input bin.Encoder, output bin.Decoder) error {
+		return nil
+	}))
+
+	user := &tg.InputPeerUser{UserID: 1, AccessHash: 1}
+	a.NoError(invoker.Invoke(ctx, &tg.MessagesSendMessageRequest{Peer: user}, nil))
+	// Other peers and methods are not limited.
+	a.NoError(invoker.Invoke(ctx, &tg.MessagesSendMessageRequest{Peer: &tg.InputPeerChat{ChatID: 1}}, nil))
+	a.NoError(invoker.Invoke(ctx, &tg.MessagesSendMessageRequest{Peer: &tg.InputPeerSelf{}}, nil))
+	a.NoError(invoker.Invoke(ctx, &tg.MessagesGetHistoryRequest{Peer: user}, nil))
+
+	observe := clock.Observe()
+	done := make(chan error, 1)
+	go func() {
+		done <- invoker.Invoke(ctx, &tg.MessagesForwardMessagesRequest{ToPeer: user}, nil)
+	}()
+	<-observe
+	a.Equal(int64(1), l.Waiting())
+	clock.Travel(time.Second)
+	a.NoError(<-done)
+
+	// Chat limit.
+	observe = clock.Observe()
+	go func() {
+		done <- invoker.Invoke(ctx, &tg.MessagesSendMediaRequest{Peer: &tg.InputPeerChat{ChatID: 1}}, nil)
+	}()
+	<-observe
+	clock.Travel(time.Second)
+	select {
+	case <-done:
+		t.Fatal("unexpected send")
+	default:
+	}
+	clock.Travel(2 * time.Second)
+	a.NoError(<-done)
+}
+
+func TestPeerLimiterSweep(t *testing.T) {
+	a := require.New(t)
+	clock := neo.NewTime(time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC))
+	l := NewPeerLimiter().WithClock(clock)
+
+	for i := 0; i < minSweep; i++ {
+		lim, ok := l.limiter(&tg.InputPeerUser{UserID: int64(i)})
+		a.True(ok)
+		a.True(lim.AllowN(clock.Now(), 1))
+	}
+	a.Len(l.limiters, minSweep)
+
+	clock.Travel(time.Second)
+	_, ok := l.limiter(&tg.InputPeerUser{UserID: -1})
+	a.True(ok)
+	a.Len(l.limiters, 1)
+}
+
+func TestPeerLimiterWith(t *testing.T) {
+	a := require.New(t)
+
+	base := NewPeerLimiter()
+	derived := base.WithUserLimit(rate.Inf, 0).WithChatLimit(rate.Inf, 0)
+	a.NotSame(base, derived)
+
+	user := &tg.InputPeerUser{UserID: 1, AccessHash: 1}
+	chat := &tg.InputPeerChat{ChatID: 1}
+
+	_, ok := derived.limiter(user)
+	a.False(ok)
+	_, ok = derived.limiter(chat)
+	a.False(ok)
+
+	// Base limiter keeps default limits.
+	_, ok = base.limiter(user)
+	a.True(ok)
+	_, ok = base.limiter(chat)
+	a.True(ok)
+}
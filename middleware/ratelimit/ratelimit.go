@@ -5,6 +5,7 @@ import (
 	"errors"
 	"time"
 
+	"go.uber.org/atomic"
 	"golang.org/x/time/rate"
 
 	"github.com/gotd/td/bin"
@@ -14,24 +15,47 @@ import (
 )
 
 // RateLimiter is a tg.Invoker that throttles RPC calls on underlying invoker.
+//
+// Calls of methods with limits set by WithMethod use their own limiters,
+// other calls use default limiter.
 type RateLimiter struct {
 	clock clock.Clock
 	lim   *rate.Limiter
+	// methods are limiters of methods by TL name.
+	methods map[string]*rate.Limiter
+	// waiting is shared between copies.
+	waiting *atomic.Int64
+	// adaptive is nil if adaptive limiting is disabled.
+	adaptive *adaptive
+}
+
+// object is an abstraction for Telegram API object with TypeName.
+type object interface {
+	TypeName() string
 }
 
 // New returns a new invoker rate limiter using lim.
 func New(r rate.Limit, b int) *RateLimiter {
 	return &RateLimiter{
-		clock: clock.System,
-		lim:   rate.NewLimiter(r, b),
+		clock:   clock.System,
+		lim:     rate.NewLimiter(r, b),
+		methods: map[string]*rate.Limiter{},
+		waiting: atomic.NewInt64(0),
 	}
 }
 
 // clone returns a copy of the RateLimiter.
 func (l *RateLimiter) clone() *RateLimiter {
+	methods := make(map[string]*rate.Limiter, len(l.methods))
+	for k, v := range l.methods {
+		methods[k] = v
+	}
 	return &RateLimiter{
-		clock: l.clock,
-		lim:   l.lim,
+		clock:    l.clock,
+		lim:      l.lim,
+		methods:  methods,
+		waiting:  l.waiting,
+		adaptive: l.adaptive,
 	}
 }
 
@@ -42,10 +66,47 @@ func (l *RateLimiter) WithClock(c clock.Clock) *RateLimiter {
 	return l
 }
 
-// wait blocks until rate limiter permits an event to happen. It returns an error if
+// WithMethod sets rate limit and burst size of given method, e.g.
+// "messages.sendMessage", instead of default limit.
+func (l *RateLimiter) WithMethod(method string, r rate.Limit, b int) *RateLimiter {
+	l = l.clone()
+	l.methods[method] = rate.NewLimiter(r, b)
+	return l
+}
+
+// WithAdaptive enables adaptive rate limiting: limit of method is decreased
+// when FLOOD_WAIT or other 420 error is returned, and then slowly restored
+// up to configured one.
+//
+// Limiter of method is used, if set by WithMethod, otherwise default one
+// is tuned.
+func (l *RateLimiter) WithAdaptive(cfg Adaptive) *RateLimiter {
+	l = l.clone()
+	l.adaptive = newAdaptive(cfg)
+	return l
+}
+
+// SetLimit sets new default rate limit and burst size.
+//
+// It is safe to call SetLimit concurrently with requests, e.g. on
+// configuration reload. Change is applied to all copies made by
+// With* methods.
+func (l *RateLimiter) SetLimit(r rate.Limit, b int) {
+	now := l.clock.Now()
+	l.lim.SetLimitAt(now, r)
+	l.lim.SetBurstAt(now, b)
+	if l.adaptive != nil {
+		l.adaptive.configure(l.lim, r, now)
+	}
+}
+
+// wait blocks until all rate limiters permit an event to happen. It returns an error if
 // limiter’s burst size is misconfigured, the Context is canceled, or the expected
 // wait time exceeds the Context’s Deadline.
-func (l *RateLimiter) wait(ctx context.Context) error {
+//
+// Tokens are reserved from all limiters at once, so waiting for one limiter does not
+// waste tokens of others.
+func wait(ctx context.Context, c clock.Clock, waiting *atomic.Int64, lims ...*rate.Limiter) error {
 	// Check if ctx is already canceled.
 	select {
 	case <-ctx.Done():
@@ -53,15 +114,30 @@ func (l *RateLimiter) wait(ctx context.Context) error {
 	default:
 	}
 
-	now := l.clock.Now()
+	now := c.Now()
 
-	r := l.lim.ReserveN(now, 1)
-	if !r.OK() {
-		// Limiter requires n <= lim.burst for each reservation.
-		return errors.New("limiter's burst size must be greater than zero")
+	var (
+		reservations = make([]*rate.Reservation, 0, len(lims))
+		delay        time.Duration
+	)
+	cancel := func() {
+		now := c.Now()
+		for _, r := range reservations {
+			r.CancelAt(now)
+		}
+	}
+	for _, lim := range lims {
+		r := lim.ReserveN(now, 1)
+		if !r.OK() {
+			cancel()
+			// Limiter requires n <= lim.burst for each reservation.
+			return errors.New("limiter's burst size must be greater than zero")
+		}
+		reservations = append(reservations, r)
+		if d := r.DelayFrom(now); d > delay {
+			delay = d
+		}
 	}
-
-	delay := r.DelayFrom(now)
 	if delay == 0 {
 		return nil
 	}
@@ -70,26 +146,45 @@ func (l *RateLimiter) wait(ctx context.Context) error {
 	// contexts use system time instead of mockable clock.
 	deadline, ok := ctx.Deadline()
 	if ok && delay > time.Until(deadline) {
+		cancel()
 		return context.DeadlineExceeded
 	}
 
-	t := l.clock.Timer(delay)
+	waiting.Inc()
+	defer waiting.Dec()
+
+	t := c.Timer(delay)
 	defer clock.StopTimer(t)
 	select {
 	case <-t.C():
 		return nil
 	case <-ctx.Done():
-		r.CancelAt(l.clock.Now())
+		cancel()
 		return ctx.Err()
 	}
 }
 
+// limiter returns limiter of given request.
+func (l *RateLimiter) limiter(input bin.Encoder) *rate.Limiter {
+	if obj, ok := input.(object); ok {
+		if lim, ok := l.methods[obj.TypeName()]; ok {
+			return lim
+		}
+	}
+	return l.lim
+}
+
 // Handle implements telegram.Middleware.
 func (l *RateLimiter) Handle(next tg.Invoker) telegram.InvokeFunc {
 	return func(ctx context.Context, input bin.Encoder, output bin.Decoder) error {
-		if err := l.wait(ctx); err != nil {
+		lim := l.limiter(input)
+		if err := wait(ctx, l.clock, l.waiting, lim); err != nil {
 			return err
 		}
-		return next.Invoke(ctx, input, output)
+		err := next.Invoke(ctx, input, output)
+		if l.adaptive != nil {
+			l.adaptive.observe(lim, err, l.clock.Now())
+		}
+		return err
 	}
 }
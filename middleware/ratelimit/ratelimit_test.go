@@ -0,0 +1,41 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/time/rate"
+
+	"github.com/gotd/td/bin"
+	"github.com/gotd/td/telegram"
+	"github.com/gotd/td/tg"
+)
+
+func TestRateLimiterMethod(t *testing.T) {
+	a := require.New(t)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	l := New(rate.Inf, 1).
+		WithMethod("messages.sendMessage", rate.Every(time.Hour), 1)
+	invoker := l.Handle(telegram.InvokeFunc(func(ctx context.Context, input bin.Encoder, output bin.Decoder) error {
+		return nil
+	}))
+
+	send := &tg.MessagesSendMessageRequest{}
+	a.NoError(invoker.Invoke(ctx, send, nil))
+	// Wait exceeds deadline.
+	a.ErrorIs(invoker.Invoke(ctx, send, nil), context.DeadlineExceeded)
+
+	// Other methods use default limit.
+	for i := 0; i < 10; i++ {
+		a.NoError(invoker.Invoke(ctx, &tg.HelpGetConfigRequest{}, nil))
+	}
+
+	s := l.Snapshot()
+	a.Equal(float64(rate.Inf), s.Limit)
+	a.Contains(s.Methods, "messages.sendMessage")
+	a.Equal(1, s.Methods["messages.sendMessage"].Burst)
+}
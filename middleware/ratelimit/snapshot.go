@@ -0,0 +1,37 @@
+package ratelimit
+
+// Snapshot is a state of RateLimiter, intended for debugging.
+type Snapshot struct {
+	// Limit is events per second.
+	Limit float64 `json:"limit"`
+	Burst int     `json:"burst"`
+	// Tokens is count of available tokens, negative if requests wait.
+	Tokens float64 `json:"tokens"`
+	// Waiting is count of requests waiting for permission.
+	Waiting int64 `json:"waiting"`
+	// Methods are states of method limiters, see WithMethod. Waiting
+	// is not tracked per method.
+	Methods map[string]Snapshot `json:"methods,omitempty"`
+}
+
+// Snapshot returns current state of RateLimiter.
+func (l *RateLimiter) Snapshot() Snapshot {
+	now := l.clock.Now()
+	s := Snapshot{
+		Limit:   float64(l.lim.Limit()),
+		Burst:   l.lim.Burst(),
+		Tokens:  l.lim.TokensAt(now),
+		Waiting: l.waiting.Load(),
+	}
+	if len(l.methods) > 0 {
+		s.Methods = make(map[string]Snapshot, len(l.methods))
+		for method, lim := range l.methods {
+			s.Methods[method] = Snapshot{
+				Limit:  float64(lim.Limit()),
+				Burst:  lim.Burst(),
+				Tokens: lim.TokensAt(now),
+			}
+		}
+	}
+	return s
+}
@@ -0,0 +1,3 @@
+// Package retry implements a tg.Invoker that retries transient RPC errors
+// with exponential backoff.
+package retry
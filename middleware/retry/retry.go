@@ -0,0 +1,217 @@
+package retry
+
+import (
+	"context"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/go-faster/errors"
+	"golang.org/x/time/rate"
+
+	"github.com/gotd/td/bin"
+	"github.com/gotd/td/clock"
+	"github.com/gotd/td/telegram"
+	"github.com/gotd/td/tg"
+	"github.com/gotd/td/tgerr"
+)
+
+// Transient reports whether given error is a transient server error,
+// i.e. -503 timeout, internal server error or "try again later" error.
+func Transient(err error) bool {
+	rpcErr, ok := tgerr.As(err)
+	if !ok {
+		return false
+	}
+	switch {
+	case rpcErr.Code == -503, rpcErr.Code >= 500:
+		return true
+	case rpcErr.IsOneOf(
+		"Timeout",
+		"RPC_CALL_FAIL",
+		"RPC_MCGET_FAIL",
+		"WORKER_BUSY_TOO_LONG_RETRY",
+		"HISTORY_GET_FAILED",
+		"MSG_WAIT_FAILED",
+	):
+		return true
+	default:
+		return false
+	}
+}
+
+// object is an abstraction for Telegram API object with TypeName.
+type object interface {
+	TypeName() string
+}
+
+// Retrier is a tg.Invoker that retries transient RPC errors on underlying
+// invoker with exponential backoff and full jitter.
+//
+// FLOOD_WAIT errors are not transient, use floodwait middleware to
+// handle them.
+type Retrier struct {
+	clock clock.Clock
+
+	maxRetries uint
+	minDelay   time.Duration
+	maxDelay   time.Duration
+	retryIf    func(err error) bool
+	skip       map[string]struct{}
+	// budget is shared between copies.
+	budget *rate.Limiter
+
+	// rand is shared between copies.
+	randMux *sync.Mutex
+	rand    *rand.Rand
+}
+
+// New returns a new invoker that retries transient errors.
+func New() *Retrier {
+	return &Retrier{
+		clock:      clock.System,
+		maxRetries: 5,
+		minDelay:   100 * time.Millisecond,
+		maxDelay:   10 * time.Second,
+		retryIf:    Transient,
+		skip:       map[string]struct{}{},
+		randMux:    &sync.Mutex{},
+		rand:       rand.New(rand.NewSource(time.Now().UnixNano())), // #nosec G404
+	}
+}
+
+// clone returns a copy of the Retrier.
+func (r *Retrier) clone() *Retrier {
+	skip := make(map[string]struct{}, len(r.skip))
+	for k := range r.skip {
+		skip[k] = struct{}{}
+	}
+	return &Retrier{
+		clock:      r.clock,
+		maxRetries: r.maxRetries,
+		minDelay:   r.minDelay,
+		maxDelay:   r.maxDelay,
+		retryIf:    r.retryIf,
+		skip:       skip,
+		budget:     r.budget,
+		randMux:    r.randMux,
+		rand:       r.rand,
+	}
+}
+
+// WithClock sets clock to use. Default is to use system clock.
+func (r *Retrier) WithClock(c clock.Clock) *Retrier {
+	r = r.clone()
+	r.clock = c
+	return r
+}
+
+// WithMaxRetries sets max number of retries per request. Default is 5.
+func (r *Retrier) WithMaxRetries(m uint) *Retrier {
+	r = r.clone()
+	r.maxRetries = m
+	return r
+}
+
+// WithBackoff sets delay bounds. Delay before n-th retry is chosen randomly
+// from [0, min(maxDelay, minDelay * 2^n)). Default is 100ms and 10s.
+func (r *Retrier) WithBackoff(minDelay, maxDelay time.Duration) *Retrier {
+	r = r.clone()
+	r.minDelay = minDelay
+	r.maxDelay = maxDelay
+	return r
+}
+
+// WithRetryIf sets function to decide whether error should be retried.
+// Default is Transient.
+func (r *Retrier) WithRetryIf(f func(err error) bool) *Retrier {
+	r = r.clone()
+	r.retryIf = f
+	return r
+}
+
+// WithSkip disables retries of given methods, e.g. "messages.sendMessage".
+func (r *Retrier) WithSkip(methods ...string) *Retrier {
+	r = r.clone()
+	for _, m := range methods {
+		r.skip[m] = struct{}{}
+	}
+	return r
+}
+
+// WithBudget limits rate of retries of all requests, so retries do not
+// multiply load during outage. Default is no limit.
+//
+// Budget is shared between copies made by With* methods.
+func (r *Retrier) WithBudget(limit rate.Limit, burst int) *Retrier {
+	r = r.clone()
+	r.budget = rate.NewLimiter(limit, burst)
+	return r
+}
+
+func (r *Retrier) delay(retry uint) time.Duration {
+	d := r.maxDelay
+	if shift := retry - 1; shift < 32 {
+		if v := r.minDelay << shift; v > 0 && v < r.maxDelay {
+			d = v
+		}
+	}
+	if d <= 0 {
+		return 0
+	}
+
+	r.randMux.Lock()
+	defer r.randMux.Unlock()
+	return time.Duration(r.rand.Int63n(int64(d)))
+}
+
+func (r *Retrier) skipped(input bin.Encoder) bool {
+	obj, ok := input.(object)
+	if !ok {
+		return false
+	}
+	_, ok = r.skip[obj.TypeName()]
+	return ok
+}
+
+// Handle implements telegram.Middleware.
+func (r *Retrier) Handle(next tg.Invoker) telegram.InvokeFunc {
+	return func(ctx context.Context, input bin.Encoder, output bin.Decoder) error {
+		if r.skipped(input) {
+			return next.Invoke(ctx, input, output)
+		}
+
+		var (
+			t       clock.Timer
+			retries uint
+		)
+		for {
+			err := next.Invoke(ctx, input, output)
+			if err == nil || !r.retryIf(err) || ctx.Err() != nil {
+				return err
+			}
+
+			retries++
+			if retries > r.maxRetries {
+				return errors.Errorf("retry limit exceeded (%d): %w", r.maxRetries, err)
+			}
+			if r.budget != nil && !r.budget.AllowN(r.clock.Now(), 1) {
+				return errors.Errorf("retry budget exceeded: %w", err)
+			}
+
+			d := r.delay(retries)
+			if t == nil {
+				t = r.clock.Timer(d)
+			} else {
+				clock.StopTimer(t)
+				t.Reset(d)
+			}
+			select {
+			case <-t.C():
+			case <-ctx.Done():
+				clock.StopTimer(t)
+				return ctx.Err()
+			}
+		}
+	}
+}
@@ -0,0 +1,126 @@
+package retry
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-faster/errors"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/time/rate"
+
+	"github.com/gotd/td/bin"
+	"github.com/gotd/td/telegram"
+	"github.com/gotd/td/tg"
+	"github.com/gotd/td/tgerr"
+)
+
+func failing(calls *int, errs ...error) telegram.InvokeFunc {
+	return func(ctx context.Context, input bin.Encoder, output bin.Decoder) error {
+		*calls++
+		if len(errs) == 0 {
+			return nil
+		}
+		err := errs[0]
+		errs = errs[1:]
+		return err
+	}
+}
+
+func TestTransient(t *testing.T) {
+	a := require.New(t)
+	a.True(Transient(tgerr.New(-503, "Timeout")))
+	a.True(Transient(tgerr.New(500, "INTERNAL")))
+	a.True(Transient(tgerr.New(400, "RPC_CALL_FAIL")))
+	a.False(Transient(tgerr.New(420, "FLOOD_WAIT_10")))
+	a.False(Transient(tgerr.New(400, "PEER_ID_INVALID")))
+	a.False(Transient(errors.New("other")))
+}
+
+func TestRetrier(t *testing.T) {
+	ctx := context.Background()
+	transient := tgerr.New(500, "INTERNAL")
+	r := New().WithBackoff(time.Millisecond, time.Millisecond)
+	req := &tg.MessagesSendMessageRequest{}
+
+	t.Run("Retry", func(t *testing.T) {
+		a := require.New(t)
+		var calls int
+		err := r.Handle(failing(&calls, transient, transient)).Invoke(ctx, req, nil)
+		a.NoError(err)
+		a.Equal(3, calls)
+	})
+	t.Run("Permanent", func(t *testing.T) {
+		a := require.New(t)
+		var calls int
+		err := r.Handle(failing(&calls, tgerr.New(400, "PEER_ID_INVALID"))).Invoke(ctx, req, nil)
+		a.True(tgerr.Is(err, "PEER_ID_INVALID"))
+		a.Equal(1, calls)
+	})
+	t.Run("MaxRetries", func(t *testing.T) {
+		a := require.New(t)
+		var calls int
+		err := r.WithMaxRetries(1).
+			Handle(failing(&calls, transient, transient, transient)).
+			Invoke(ctx, req, nil)
+		a.ErrorIs(err, transient)
+		a.Equal(2, calls)
+	})
+	t.Run("Skip", func(t *testing.T) {
+		a := require.New(t)
+		var calls int
+		err := r.WithSkip(req.TypeName()).Handle(failing(&calls, transient)).Invoke(ctx, req, nil)
+		a.ErrorIs(err, transient)
+		a.Equal(1, calls)
+
+		// Other methods are retried.
+		calls = 0
+		err = r.WithSkip(req.TypeName()).
+			Handle(failing(&calls, transient)).
+			Invoke(ctx, &tg.HelpGetConfigRequest{}, nil)
+		a.NoError(err)
+		a.Equal(2, calls)
+	})
+	t.Run("Budget", func(t *testing.T) {
+		a := require.New(t)
+		b := r.WithBudget(rate.Every(time.Hour), 1)
+		var calls int
+		a.NoError(b.Handle(failing(&calls, transient)).Invoke(ctx, req, nil))
+		err := b.Handle(failing(&calls, transient)).Invoke(ctx, req, nil)
+		a.ErrorIs(err, transient)
+		a.Equal(3, calls)
+	})
+	t.Run("Canceled", func(t *testing.T) {
+		a := require.New(t)
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		var calls int
+		err := r.WithBackoff(time.Hour, time.Hour).
+			Handle(telegram.InvokeFunc(func(ctx context.Context, input bin.Encoder, output bin.Decoder) error {
+				calls++
+				cancel()
+				return transient
+			})).
+			Invoke(ctx, req, nil)
+		a.ErrorIs(err, transient)
+		a.Equal(1, calls)
+	})
+}
+
+func TestRetrierDelay(t *testing.T) {
+	a := require.New(t)
+	r := New().WithBackoff(time.Second, 4*time.Second)
+	for retry, limit := range map[uint]time.Duration{
+		1:   time.Second,
+		2:   2 * time.Second,
+		3:   4 * time.Second,
+		4:   4 * time.Second,
+		100: 4 * time.Second,
+	} {
+		for i := 0; i < 10; i++ {
+			d := r.delay(retry)
+			a.GreaterOrEqual(d, time.Duration(0))
+			a.Less(d, limit)
+		}
+	}
+}
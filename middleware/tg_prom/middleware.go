@@ -1,4 +1,5 @@
-// Package tg_prom implements middleware for prometheus metrics.
+// Package tg_prom implements middleware and peer storage decorator for
+// prometheus metrics.
 package tg_prom
 
 import (
@@ -0,0 +1,160 @@
+package tg_prom
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-faster/errors"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/gotd/contrib/storage"
+)
+
+const (
+	labelBackend   = "backend"
+	labelOperation = "operation"
+	labelResult    = "result"
+)
+
+// StorageMetrics is prometheus metrics of peer storages.
+type StorageMetrics struct {
+	duration *prometheus.HistogramVec
+	failures *prometheus.CounterVec
+	lookups  *prometheus.CounterVec
+}
+
+// NewStorageMetrics initializes and returns new peer storage metrics.
+func NewStorageMetrics() *StorageMetrics {
+	return &StorageMetrics{
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name: "tg_peer_storage_duration_seconds",
+			Help: "Peer storage operations duration histogram.",
+		}, []string{labelBackend, labelOperation}),
+		failures: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "tg_peer_storage_failures_total",
+			Help: "Peer storage failed operations total count.",
+		}, []string{labelBackend, labelOperation}),
+		lookups: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "tg_peer_storage_lookups_total",
+			Help: "Peer storage lookups total count by result, hit or miss.",
+		}, []string{labelBackend, labelOperation, labelResult}),
+	}
+}
+
+// Metrics returns slice of provided prometheus metrics.
+func (m *StorageMetrics) Metrics() []prometheus.Collector {
+	return []prometheus.Collector{
+		m.duration,
+		m.failures,
+		m.lookups,
+	}
+}
+
+// Wrap returns storage.PeerStorage decorator which observes calls of given
+// storage, labeled by given backend name.
+func (m *StorageMetrics) Wrap(next storage.PeerStorage, backend string) storage.PeerStorage {
+	return peerStorage{
+		next:    next,
+		metrics: m,
+		backend: backend,
+	}
+}
+
+type peerStorage struct {
+	next    storage.PeerStorage
+	metrics *StorageMetrics
+	backend string
+}
+
+func (s peerStorage) observe(operation string, start time.Time, err error) {
+	labels := prometheus.Labels{
+		labelBackend:   s.backend,
+		labelOperation: operation,
+	}
+	s.metrics.duration.With(labels).Observe(time.Since(start).Seconds())
+	if err != nil && !errors.Is(err, storage.ErrPeerNotFound) {
+		s.metrics.failures.With(labels).Inc()
+	}
+}
+
+func (s peerStorage) lookup(operation string, start time.Time, err error) {
+	s.observe(operation, start, err)
+
+	result := "hit"
+	switch {
+	case err == nil:
+	case errors.Is(err, storage.ErrPeerNotFound):
+		result = "miss"
+	default:
+		return
+	}
+	s.metrics.lookups.With(prometheus.Labels{
+		labelBackend:   s.backend,
+		labelOperation: operation,
+		labelResult:    result,
+	}).Inc()
+}
+
+func (s peerStorage) Add(ctx context.Context, value storage.Peer) error {
+	start := time.Now()
+	err := s.next.Add(ctx, value)
+	s.observe("add", start, err)
+	return err
+}
+
+func (s peerStorage) Find(ctx context.Context, key storage.PeerKey) (storage.Peer, error) {
+	start := time.Now()
+	p, err := s.next.Find(ctx, key)
+	s.lookup("find", start, err)
+	return p, err
+}
+
+func (s peerStorage) Assign(ctx context.Context, key string, value storage.Peer) error {
+	start := time.Now()
+	err := s.next.Assign(ctx, key, value)
+	s.observe("assign", start, err)
+	return err
+}
+
+func (s peerStorage) Resolve(ctx context.Context, key string) (storage.Peer, error) {
+	start := time.Now()
+	p, err := s.next.Resolve(ctx, key)
+	s.lookup("resolve", start, err)
+	return p, err
+}
+
+// Iterate observes duration of whole iteration, from Iterate call to
+// iterator Close.
+func (s peerStorage) Iterate(ctx context.Context) (storage.PeerIterator, error) {
+	start := time.Now()
+	iter, err := s.next.Iterate(ctx)
+	if err != nil {
+		s.observe("iterate", start, err)
+		return nil, err
+	}
+	return &peerIterator{PeerIterator: iter, storage: s, start: start}, nil
+}
+
+type peerIterator struct {
+	storage.PeerIterator
+	storage peerStorage
+	start   time.Time
+	closed  bool
+}
+
+// Close observes iteration and closes iterator. Iteration error, if any,
+// is counted as failure.
+func (i *peerIterator) Close() error {
+	closeErr := i.PeerIterator.Close()
+	if i.closed {
+		return closeErr
+	}
+	i.closed = true
+
+	err := i.PeerIterator.Err()
+	if err == nil {
+		err = closeErr
+	}
+	i.storage.observe("iterate", i.start, err)
+	return closeErr
+}
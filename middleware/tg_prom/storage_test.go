@@ -0,0 +1,117 @@
+package tg_prom
+
+import (
+	"context"
+	"testing"
+
+	pebbledb "github.com/cockroachdb/pebble"
+	"github.com/cockroachdb/pebble/vfs"
+	"github.com/go-faster/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gotd/td/tg"
+
+	"github.com/gotd/contrib/pebble"
+	"github.com/gotd/contrib/storage"
+)
+
+type failingIterator struct{}
+
+func (failingIterator) Next(ctx context.Context) bool { return false }
+func (failingIterator) Err() error                    { return errors.New("failed") }
+func (failingIterator) Value() storage.Peer           { return storage.Peer{} }
+func (failingIterator) Close() error                  { return nil }
+
+type failingStorage struct {
+	storage.PeerStorage
+}
+
+func (failingStorage) Iterate(ctx context.Context) (storage.PeerIterator, error) {
+	return failingIterator{}, nil
+}
+
+func labelValue(m *dto.Metric, name string) string {
+	for _, l := range m.GetLabel() {
+		if l.GetName() == name {
+			return l.GetValue()
+		}
+	}
+	return ""
+}
+
+func TestStorageMetrics(t *testing.T) {
+	a := require.New(t)
+	ctx := context.Background()
+
+	r := prometheus.NewPedanticRegistry()
+	m := NewStorageMetrics()
+	for _, c := range m.Metrics() {
+		a.NoError(r.Register(c))
+	}
+
+	db, err := pebbledb.Open("pebble.db", &pebbledb.Options{FS: vfs.NewMem()})
+	a.NoError(err)
+	t.Cleanup(func() {
+		_ = db.Close()
+	})
+	s := m.Wrap(pebble.NewPeerStorage(db), "pebble")
+
+	var p storage.Peer
+	a.True(p.FromUser(&tg.User{ID: 10, AccessHash: 10, Username: "user"}))
+	a.NoError(s.Add(ctx, p))
+	_, err = s.Find(ctx, storage.KeyFromPeer(p))
+	a.NoError(err)
+	_, err = s.Resolve(ctx, "user")
+	a.NoError(err)
+	_, err = s.Resolve(ctx, "missing")
+	a.ErrorIs(err, storage.ErrPeerNotFound)
+
+	iter, err := s.Iterate(ctx)
+	a.NoError(err)
+	a.NoError(storage.ForEach(ctx, iter, func(storage.Peer) error { return nil }))
+	a.NoError(iter.Close())
+
+	// Failed iteration is observed on Close.
+	failing, err := m.Wrap(failingStorage{}, "failing").Iterate(ctx)
+	a.NoError(err)
+	a.False(failing.Next(ctx))
+	a.NoError(failing.Close())
+
+	families, err := r.Gather()
+	a.NoError(err)
+	lookups := map[string]float64{}
+	for _, f := range families {
+		switch f.GetName() {
+		case "tg_peer_storage_duration_seconds":
+			for _, metric := range f.GetMetric() {
+				if labelValue(metric, labelOperation) == "iterate" {
+					a.Equal(uint64(1), metric.GetHistogram().GetSampleCount())
+				}
+			}
+			continue
+		case "tg_peer_storage_failures_total":
+			a.Len(f.GetMetric(), 1)
+			a.Equal("failing", labelValue(f.GetMetric()[0], labelBackend))
+			a.Equal("iterate", labelValue(f.GetMetric()[0], labelOperation))
+			continue
+		case "tg_peer_storage_lookups_total":
+		default:
+			continue
+		}
+		for _, metric := range f.GetMetric() {
+			labels := map[string]string{}
+			for _, l := range metric.GetLabel() {
+				labels[l.GetName()] = l.GetValue()
+			}
+			a.Equal("pebble", labels[labelBackend])
+			lookups[labels[labelOperation]+":"+labels[labelResult]] = metric.GetCounter().GetValue()
+		}
+	}
+	a.Equal(map[string]float64{
+		"find:hit":     1,
+		"resolve:hit":  1,
+		"resolve:miss": 1,
+	}, lookups)
+}
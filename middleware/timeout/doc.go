@@ -0,0 +1,3 @@
+// Package timeout implements a tg.Invoker that sets default deadline of
+// requests.
+package timeout
@@ -0,0 +1,60 @@
+package timeout
+
+import (
+	"context"
+	"time"
+
+	"github.com/gotd/td/bin"
+	"github.com/gotd/td/telegram"
+	"github.com/gotd/td/tg"
+)
+
+// Timeout is a tg.Invoker that sets deadline of requests without one,
+// so a stuck request does not block caller indefinitely.
+//
+// Deadline set by caller is kept as is.
+type Timeout struct {
+	timeout time.Duration
+	methods map[string]time.Duration
+}
+
+// New creates new Timeout with given default timeout.
+func New(timeout time.Duration) *Timeout {
+	return &Timeout{
+		timeout: timeout,
+		methods: map[string]time.Duration{},
+	}
+}
+
+// WithMethod sets timeout of given method, e.g. "upload.getFile", instead
+// of default one. Zero timeout disables deadline of method.
+func (t *Timeout) WithMethod(method string, timeout time.Duration) *Timeout {
+	t.methods[method] = timeout
+	return t
+}
+
+func (t *Timeout) get(input bin.Encoder) time.Duration {
+	if obj, ok := input.(interface{ TypeName() string }); ok {
+		if d, ok := t.methods[obj.TypeName()]; ok {
+			return d
+		}
+	}
+	return t.timeout
+}
+
+// Handle implements telegram.Middleware.
+func (t *Timeout) Handle(next tg.Invoker) telegram.InvokeFunc {
+	return func(ctx context.Context, input bin.Encoder, output bin.Decoder) error {
+		if _, ok := ctx.Deadline(); ok {
+			return next.Invoke(ctx, input, output)
+		}
+		d := t.get(input)
+		if d <= 0 {
+			return next.Invoke(ctx, input, output)
+		}
+
+		ctx, cancel := context.WithTimeout(ctx, d)
+		defer cancel()
+		return next.Invoke(ctx, input, output)
+	}
+}
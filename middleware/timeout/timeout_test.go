@@ -0,0 +1,49 @@
+package timeout
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/gotd/td/bin"
+	"github.com/gotd/td/telegram"
+	"github.com/gotd/td/tg"
+)
+
+func TestTimeout(t *testing.T) {
+	a := require.New(t)
+	var (
+		deadline time.Time
+		ok       bool
+	)
+	invoker := New(time.Minute).
+		WithMethod("upload.getFile", time.Hour).
+		WithMethod("updates.getDifference", 0).
+		Handle(telegram.InvokeFunc(func(ctx context.Context, input bin.Encoder, output bin.Decoder) error {
+			deadline, ok = ctx.Deadline()
+			return nil
+		}))
+	ctx := context.Background()
+
+	start := time.Now()
+	a.NoError(invoker.Invoke(ctx, &tg.HelpGetConfigRequest{}, nil))
+	a.True(ok)
+	a.WithinDuration(start.Add(time.Minute), deadline, time.Second)
+
+	a.NoError(invoker.Invoke(ctx, &tg.UploadGetFileRequest{}, nil))
+	a.True(ok)
+	a.WithinDuration(start.Add(time.Hour), deadline, time.Second)
+
+	a.NoError(invoker.Invoke(ctx, &tg.UpdatesGetDifferenceRequest{}, nil))
+	a.False(ok)
+
+	// Caller deadline is kept.
+	callerCtx, cancel := context.WithTimeout(ctx, 2*time.Hour)
+	defer cancel()
+	expected, _ := callerCtx.Deadline()
+	a.NoError(invoker.Invoke(callerCtx, &tg.HelpGetConfigRequest{}, nil))
+	a.True(ok)
+	a.Equal(expected, deadline)
+}
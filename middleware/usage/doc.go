@@ -0,0 +1,3 @@
+// Package usage implements a tg.Invoker that accounts API usage and
+// enforces quotas.
+package usage
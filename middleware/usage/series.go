@@ -0,0 +1,88 @@
+package usage
+
+import "time"
+
+const (
+	// bucketSize is a resolution of usage series.
+	bucketSize = time.Minute
+	// buckets is a count of buckets in series, i.e. retention is a day.
+	buckets = int64(24 * time.Hour / bucketSize)
+)
+
+// Stats is an accounted usage.
+type Stats struct {
+	// Calls is a count of calls, including failed ones.
+	Calls int64 `json:"calls"`
+	// Errors is a count of failed calls.
+	Errors int64 `json:"errors"`
+	// RequestBytes is a total size of encoded requests.
+	RequestBytes int64 `json:"request_bytes"`
+	// ResponseBytes is a total size of encoded responses.
+	ResponseBytes int64 `json:"response_bytes"`
+}
+
+func (s *Stats) add(o Stats) {
+	s.Calls += o.Calls
+	s.Errors += o.Errors
+	s.RequestBytes += o.RequestBytes
+	s.ResponseBytes += o.ResponseBytes
+}
+
+func (s *Stats) sub(o Stats) {
+	s.Calls -= o.Calls
+	s.Errors -= o.Errors
+	s.RequestBytes -= o.RequestBytes
+	s.ResponseBytes -= o.ResponseBytes
+}
+
+// series is a ring of per-minute buckets of the last day.
+type series struct {
+	buckets [buckets]Stats
+	// total is a sum of buckets.
+	total Stats
+	// last is an index of the last bucket, i.e. minutes since epoch.
+	last int64
+}
+
+func bucketOf(t time.Time) int64 {
+	return t.UnixNano() / int64(bucketSize)
+}
+
+// advance clears buckets older than a day.
+func (s *series) advance(now time.Time) {
+	cur := bucketOf(now)
+	if cur <= s.last {
+		return
+	}
+	from := s.last + 1
+	if cur-from >= buckets {
+		from = cur - buckets + 1
+	}
+	for b := from; b <= cur; b++ {
+		i := b % buckets
+		s.total.sub(s.buckets[i])
+		s.buckets[i] = Stats{}
+	}
+	s.last = cur
+}
+
+func (s *series) add(now time.Time, st Stats) {
+	s.advance(now)
+	s.buckets[s.last%buckets].add(st)
+	s.total.add(st)
+}
+
+// sum returns usage during given window, rounded up to bucket size.
+func (s *series) sum(now time.Time, window time.Duration) Stats {
+	s.advance(now)
+	n := int64((window + bucketSize - 1) / bucketSize)
+	if n >= buckets {
+		return s.total
+	}
+
+	var r Stats
+	for b := s.last - n + 1; b <= s.last; b++ {
+		r.add(s.buckets[b%buckets])
+	}
+	return r
+}
@@ -0,0 +1,195 @@
+package usage
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/go-faster/errors"
+
+	"github.com/gotd/td/bin"
+	"github.com/gotd/td/clock"
+	"github.com/gotd/td/telegram"
+	"github.com/gotd/td/tg"
+)
+
+// QuotaError is returned by Meter instead of calling method, if daily quota
+// of method is exceeded.
+type QuotaError struct {
+	// Key is a usage key, see WithKey.
+	Key string
+	// Method is a TL name of method, e.g. "messages.sendMessage".
+	Method string
+	// Limit is a daily quota.
+	Limit int64
+}
+
+// Error implements error.
+func (e *QuotaError) Error() string {
+	if e.Key == "" {
+		return fmt.Sprintf("daily quota of %s exceeded (%d calls)", e.Method, e.Limit)
+	}
+	return fmt.Sprintf("daily quota of %s exceeded by %q (%d calls)", e.Method, e.Key, e.Limit)
+}
+
+// IsQuotaExceeded reports whether given error is returned due to exceeded
+// quota.
+func IsQuotaExceeded(err error) bool {
+	var quotaErr *QuotaError
+	return errors.As(err, &quotaErr)
+}
+
+// Usage is an usage of method by key.
+type Usage struct {
+	Key    string `json:"key,omitempty"`
+	Method string `json:"method"`
+	Stats
+}
+
+type usageKey struct {
+	key    string
+	method string
+}
+
+// Meter is a tg.Invoker that accounts calls of underlying invoker per
+// method over the last day, and enforces daily quotas.
+//
+// Usage can be attributed to callers, e.g. teams sharing API ID, using
+// WithKey.
+type Meter struct {
+	clock  clock.Clock
+	key    func(ctx context.Context) string
+	sizes  bool
+	quotas map[string]int64
+
+	mux    sync.Mutex
+	series map[usageKey]*series
+}
+
+// New creates new Meter.
+func New() *Meter {
+	return &Meter{
+		clock:  clock.System,
+		key:    func(ctx context.Context) string { return "" },
+		sizes:  true,
+		quotas: map[string]int64{},
+		series: map[usageKey]*series{},
+	}
+}
+
+// WithClock sets clock to use. Default is to use system clock.
+func (m *Meter) WithClock(c clock.Clock) *Meter {
+	m.clock = c
+	return m
+}
+
+// WithKey sets function which returns usage key of call, e.g. team name
+// from context. Usage and quotas are accounted per key.
+func (m *Meter) WithKey(f func(ctx context.Context) string) *Meter {
+	m.key = f
+	return m
+}
+
+// WithSizes sets whether to account sizes of requests and responses.
+// Sizes are computed by encoding, which is not free. Default is true.
+func (m *Meter) WithSizes(enabled bool) *Meter {
+	m.sizes = enabled
+	return m
+}
+
+// WithQuota sets limit of calls of given method per key during the last
+// day. Calls over limit fail with *QuotaError.
+func (m *Meter) WithQuota(method string, limit int64) *Meter {
+	m.quotas[method] = limit
+	return m
+}
+
+// Report returns usage during given window, up to a day, sorted by key
+// and method.
+func (m *Meter) Report(window time.Duration) []Usage {
+	now := m.clock.Now()
+
+	m.mux.Lock()
+	r := make([]Usage, 0, len(m.series))
+	for k, s := range m.series {
+		st := s.sum(now, window)
+		if st.Calls == 0 {
+			continue
+		}
+		r = append(r, Usage{Key: k.key, Method: k.method, Stats: st})
+	}
+	m.mux.Unlock()
+
+	sort.Slice(r, func(i, j int) bool {
+		if r[i].Key != r[j].Key {
+			return r[i].Key < r[j].Key
+		}
+		return r[i].Method < r[j].Method
+	})
+	return r
+}
+
+// start checks quota of method and accounts call.
+func (m *Meter) start(k usageKey, now time.Time) error {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	s, ok := m.series[k]
+	if !ok {
+		s = &series{last: bucketOf(now)}
+		m.series[k] = s
+	}
+	s.advance(now)
+	if limit, ok := m.quotas[k.method]; ok && s.total.Calls >= limit {
+		return &QuotaError{Key: k.key, Method: k.method, Limit: limit}
+	}
+	s.add(now, Stats{Calls: 1})
+	return nil
+}
+
+// finish accounts result of call.
+func (m *Meter) finish(k usageKey, now time.Time, st Stats) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	m.series[k].add(now, st)
+}
+
+func size(e bin.Encoder) int64 {
+	var b bin.Buffer
+	if err := e.Encode(&b); err != nil {
+		return 0
+	}
+	return int64(b.Len())
+}
+
+// Handle implements telegram.Middleware.
+func (m *Meter) Handle(next tg.Invoker) telegram.InvokeFunc {
+	return func(ctx context.Context, input bin.Encoder, output bin.Decoder) error {
+		k := usageKey{key: m.key(ctx)}
+		if obj, ok := input.(interface{ TypeName() string }); ok {
+			k.method = obj.TypeName()
+		}
+		if err := m.start(k, m.clock.Now()); err != nil {
+			return err
+		}
+
+		err := next.Invoke(ctx, input, output)
+
+		var st Stats
+		if err != nil {
+			st.Errors = 1
+		}
+		if m.sizes {
+			st.RequestBytes = size(input)
+			if result, ok := output.(bin.Encoder); ok && err == nil {
+				st.ResponseBytes = size(result)
+			}
+		}
+		m.finish(k, m.clock.Now(), st)
+
+		return err
+	}
+}
@@ -0,0 +1,86 @@
+package usage
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-faster/errors"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gotd/neo"
+	"github.com/gotd/td/bin"
+	"github.com/gotd/td/telegram"
+	"github.com/gotd/td/tg"
+)
+
+type teamKey struct{}
+
+func TestMeter(t *testing.T) {
+	a := require.New(t)
+	clock := neo.NewTime(time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC))
+
+	m := New().
+		WithClock(clock).
+		WithKey(func(ctx context.Context) string {
+			team, _ := ctx.Value(teamKey{}).(string)
+			return team
+		}).
+		WithQuota("messages.sendMessage", 2)
+	testErr := errors.New("test")
+	raw := tg.NewClient(m.Handle(telegram.InvokeFunc(func(ctx context.Context, input bin.Encoder, output bin.Decoder) error {
+		switch input.(type) {
+		case *tg.HelpGetConfigRequest:
+			*output.(*tg.Config) = tg.Config{ThisDC: 2}
+			return nil
+		default:
+			return testErr
+		}
+	})))
+
+	alpha := context.WithValue(context.Background(), teamKey{}, "alpha")
+	beta := context.WithValue(context.Background(), teamKey{}, "beta")
+	send := func(ctx context.Context) error {
+		_, err := raw.MessagesSendMessage(ctx, &tg.MessagesSendMessageRequest{
+			Peer:    &tg.InputPeerSelf{},
+			Message: "hi",
+		})
+		return err
+	}
+
+	_, err := raw.HelpGetConfig(alpha)
+	a.NoError(err)
+	a.ErrorIs(send(alpha), testErr)
+	a.ErrorIs(send(alpha), testErr)
+	err = send(alpha)
+	a.True(IsQuotaExceeded(err))
+	var quotaErr *QuotaError
+	a.ErrorAs(err, &quotaErr)
+	a.Equal(&QuotaError{Key: "alpha", Method: "messages.sendMessage", Limit: 2}, quotaErr)
+	// Quota is per key.
+	a.ErrorIs(send(beta), testErr)
+
+	report := m.Report(time.Hour)
+	a.Len(report, 3)
+	a.Equal("alpha", report[0].Key)
+	a.Equal("help.getConfig", report[0].Method)
+	a.Equal(int64(1), report[0].Calls)
+	a.Positive(report[0].RequestBytes)
+	a.Positive(report[0].ResponseBytes)
+	a.Equal(Usage{Key: "alpha", Method: "messages.sendMessage", Stats: Stats{
+		Calls:        2,
+		Errors:       2,
+		RequestBytes: 2 * report[2].RequestBytes,
+	}}, report[1])
+	a.Equal("beta", report[2].Key)
+
+	// Window is sliding.
+	clock.Travel(2 * time.Hour)
+	a.Empty(m.Report(time.Hour))
+	a.Len(m.Report(24*time.Hour), 3)
+	a.True(IsQuotaExceeded(send(alpha)))
+
+	clock.Travel(22 * time.Hour)
+	a.Empty(m.Report(24 * time.Hour))
+	a.ErrorIs(send(alpha), testErr)
+}
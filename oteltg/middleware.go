@@ -5,6 +5,7 @@ import (
 	"context"
 	"fmt"
 	"strconv"
+	"strings"
 	"time"
 
 	"go.opentelemetry.io/otel/attribute"
@@ -16,21 +17,26 @@ import (
 	"github.com/gotd/td/telegram"
 	"github.com/gotd/td/tg"
 	"github.com/gotd/td/tgerr"
+
+	"github.com/gotd/contrib/tenant"
 )
 
 // Middleware is prometheus metrics middleware for Telegram.
 type Middleware struct {
-	count    metric.Int64Counter
-	failures metric.Int64Counter
-	duration metric.Float64Histogram
-	tracer   trace.Tracer
+	count     metric.Int64Counter
+	failures  metric.Int64Counter
+	duration  metric.Float64Histogram
+	floodWait metric.Float64Histogram
+	migrate   metric.Int64Counter
+	authErr   metric.Int64Counter
+	tracer    trace.Tracer
 }
 
 // Handle implements telegram.Middleware.
 func (m Middleware) Handle(next tg.Invoker) telegram.InvokeFunc {
 	return func(ctx context.Context, input bin.Encoder, output bin.Decoder) error {
 		// Prepare.
-		attrs := m.attributes(input)
+		attrs := m.attributes(ctx, input)
 
 		spanName := "tg.rpc"
 		for _, attr := range attrs {
@@ -57,6 +63,7 @@ func (m Middleware) Handle(next tg.Invoker) telegram.InvokeFunc {
 					attribute.String("tg.rpc.err", rpcErr.Type),
 					attribute.String("tg.rpc.code", strconv.Itoa(rpcErr.Code)),
 				)
+				m.observeRPCError(ctx, span, rpcErr, attrs)
 			} else {
 				span.SetStatus(codes.Error, "Internal error")
 				errAttrs = append(errAttrs,
@@ -74,19 +81,46 @@ func (m Middleware) Handle(next tg.Invoker) telegram.InvokeFunc {
 	}
 }
 
+// observeRPCError records errors which need dedicated alerting: flood
+// waits, DC migrations and authorization key errors.
+func (m Middleware) observeRPCError(ctx context.Context, span trace.Span, rpcErr *tgerr.Error, attrs []attribute.KeyValue) {
+	switch {
+	case rpcErr.IsOneOf(tgerr.ErrFloodWait, "FLOOD_PREMIUM_WAIT", "SLOWMODE_WAIT"):
+		span.AddEvent("tg.rpc.flood_wait", trace.WithAttributes(
+			attribute.String("tg.rpc.err", rpcErr.Type),
+			attribute.Int("tg.rpc.flood_wait.seconds", rpcErr.Argument),
+		))
+		m.floodWait.Record(ctx, float64(rpcErr.Argument), metric.WithAttributes(attrs...))
+	case strings.HasSuffix(rpcErr.Type, "_MIGRATE"):
+		migrateAttrs := []attribute.KeyValue{
+			attribute.String("tg.rpc.err", rpcErr.Type),
+			attribute.Int("tg.rpc.migrate.dc", rpcErr.Argument),
+		}
+		span.AddEvent("tg.rpc.migrate", trace.WithAttributes(migrateAttrs...))
+		m.migrate.Add(ctx, 1, metric.WithAttributes(append(attrs, migrateAttrs...)...))
+	case rpcErr.Code == 401 || strings.HasPrefix(rpcErr.Type, "AUTH_KEY_"):
+		authAttrs := []attribute.KeyValue{
+			attribute.String("tg.rpc.err", rpcErr.Type),
+		}
+		span.AddEvent("tg.rpc.auth_error", trace.WithAttributes(authAttrs...))
+		m.authErr.Add(ctx, 1, metric.WithAttributes(append(attrs, authAttrs...)...))
+	}
+}
+
 // object is a abstraction for Telegram API object with TypeName.
 type object interface {
 	TypeName() string
 }
 
-func (m Middleware) attributes(input bin.Encoder) []attribute.KeyValue {
-	obj, ok := input.(object)
-	if !ok {
-		return []attribute.KeyValue{}
+func (m Middleware) attributes(ctx context.Context, input bin.Encoder) []attribute.KeyValue {
+	var attrs []attribute.KeyValue
+	if obj, ok := input.(object); ok {
+		attrs = append(attrs, attribute.String("tg.method", obj.TypeName()))
 	}
-	return []attribute.KeyValue{
-		attribute.String("tg.method", obj.TypeName()),
+	if id, ok := tenant.FromContext(ctx); ok {
+		attrs = append(attrs, attribute.String("tg.tenant", id))
 	}
+	return attrs
 }
 
 // New initializes and returns new prometheus middleware.
@@ -106,5 +140,14 @@ func New(meterProvider metric.MeterProvider, tracerProvider trace.TracerProvider
 	if m.duration, err = meter.Float64Histogram("tg.rpc.duration"); err != nil {
 		return nil, err
 	}
+	if m.floodWait, err = meter.Float64Histogram("tg.rpc.flood_wait"); err != nil {
+		return nil, err
+	}
+	if m.migrate, err = meter.Int64Counter("tg.rpc.migrate"); err != nil {
+		return nil, err
+	}
+	if m.authErr, err = meter.Int64Counter("tg.rpc.auth_errors"); err != nil {
+		return nil, err
+	}
 	return m, nil
 }
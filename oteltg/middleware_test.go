@@ -5,12 +5,15 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/metric/noop"
 	"go.opentelemetry.io/otel/trace"
 
 	"github.com/gotd/td/bin"
 	"github.com/gotd/td/tg"
 	"github.com/gotd/td/tgerr"
+
+	"github.com/gotd/contrib/tenant"
 )
 
 type invoker func(ctx context.Context, input bin.Encoder, output bin.Decoder) error
@@ -35,4 +38,26 @@ func TestMiddleware_Handle(t *testing.T) {
 	require.NoError(t, m.Handle(okInvoker).Invoke(ctx, input, nil))
 	require.NoError(t, m.Handle(okInvoker).Invoke(ctx, nil, nil))
 	require.True(t, tgerr.Is(m.Handle(errInvoker).Invoke(ctx, input, nil), tgerr.ErrFloodWait))
+
+	for _, rpcErr := range []*tgerr.Error{
+		tgerr.New(420, "FLOOD_WAIT_10"),
+		tgerr.New(303, "FILE_MIGRATE_4"),
+		tgerr.New(401, "AUTH_KEY_UNREGISTERED"),
+	} {
+		rpcErr := rpcErr
+		h := m.Handle(invoker(func(ctx context.Context, input bin.Encoder, output bin.Decoder) error {
+			return rpcErr
+		}))
+		require.ErrorIs(t, h.Invoke(ctx, input, nil), rpcErr)
+	}
+}
+
+func TestMiddleware_attributes(t *testing.T) {
+	var m Middleware
+	ctx := tenant.With(context.Background(), "foo")
+	require.Equal(t, []attribute.KeyValue{
+		attribute.String("tg.method", "users.getUsers"),
+		attribute.String("tg.tenant", "foo"),
+	}, m.attributes(ctx, &tg.UsersGetUsersRequest{}))
+	require.Empty(t, m.attributes(context.Background(), nil))
 }
@@ -0,0 +1,129 @@
+package oteltg
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/go-faster/errors"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/gotd/td/telegram/query/dialogs"
+
+	"github.com/gotd/contrib/storage"
+)
+
+var _ storage.PeerStorage = (*Storage)(nil)
+
+// Storage is a storage.PeerStorage decorator which creates spans for
+// every storage call.
+type Storage struct {
+	next   storage.PeerStorage
+	tracer trace.Tracer
+	attrs  []attribute.KeyValue
+}
+
+// NewStorage initializes and returns new tracing peer storage.
+func NewStorage(next storage.PeerStorage, tracerProvider trace.TracerProvider) *Storage {
+	return &Storage{
+		next:   next,
+		tracer: tracerProvider.Tracer("github.com/gotd/contrib/oteltg"),
+	}
+}
+
+// WithBackend sets backend name attribute of spans.
+func (s *Storage) WithBackend(backend string) *Storage {
+	s.attrs = append(s.attrs, attribute.String("tg.storage.backend", backend))
+	return s
+}
+
+func (s *Storage) start(ctx context.Context, op string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return s.tracer.Start(ctx, "tg.storage: "+op,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(s.attrs...),
+		trace.WithAttributes(attrs...),
+	)
+}
+
+func kindName(kind dialogs.PeerKind) string {
+	switch kind {
+	case dialogs.User:
+		return "user"
+	case dialogs.Chat:
+		return "chat"
+	case dialogs.Channel:
+		return "channel"
+	default:
+		return strconv.Itoa(int(kind))
+	}
+}
+
+func peerAttrs(key storage.PeerKey) []attribute.KeyValue {
+	return []attribute.KeyValue{
+		attribute.String("tg.peer.kind", kindName(key.Kind)),
+		attribute.Int64("tg.peer.id", key.ID),
+	}
+}
+
+// end records result of storage call. ErrPeerNotFound is not an error.
+func end(span trace.Span, err error) {
+	defer span.End()
+
+	switch {
+	case err == nil:
+		span.SetStatus(codes.Ok, "")
+	case errors.Is(err, storage.ErrPeerNotFound):
+		span.SetAttributes(attribute.Bool("tg.storage.not_found", true))
+		span.SetStatus(codes.Ok, "")
+	default:
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Storage error")
+	}
+}
+
+// Add implements storage.PeerStorage.
+func (s *Storage) Add(ctx context.Context, value storage.Peer) (err error) {
+	ctx, span := s.start(ctx, "add", peerAttrs(storage.KeyFromPeer(value))...)
+	defer func() { end(span, err) }()
+
+	return s.next.Add(ctx, value)
+}
+
+// Find implements storage.PeerStorage.
+func (s *Storage) Find(ctx context.Context, key storage.PeerKey) (_ storage.Peer, err error) {
+	ctx, span := s.start(ctx, "find", peerAttrs(key)...)
+	defer func() { end(span, err) }()
+
+	return s.next.Find(ctx, key)
+}
+
+// Assign implements storage.PeerStorage.
+func (s *Storage) Assign(ctx context.Context, key string, value storage.Peer) (err error) {
+	ctx, span := s.start(ctx, "assign", peerAttrs(storage.KeyFromPeer(value))...)
+	defer func() { end(span, err) }()
+
+	return s.next.Assign(ctx, key, value)
+}
+
+// Resolve implements storage.PeerStorage.
+//
+// Resolved key is not recorded, as it may be phone or username.
+func (s *Storage) Resolve(ctx context.Context, key string) (_ storage.Peer, err error) {
+	ctx, span := s.start(ctx, "resolve")
+	defer func() { end(span, err) }()
+
+	p, err := s.next.Resolve(ctx, key)
+	if err == nil {
+		span.SetAttributes(peerAttrs(storage.KeyFromPeer(p))...)
+	}
+	return p, err
+}
+
+// Iterate implements storage.PeerStorage.
+func (s *Storage) Iterate(ctx context.Context) (_ storage.PeerIterator, err error) {
+	ctx, span := s.start(ctx, "iterate")
+	defer func() { end(span, err) }()
+
+	return s.next.Iterate(ctx)
+}
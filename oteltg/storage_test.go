@@ -0,0 +1,90 @@
+package oteltg
+
+import (
+	"context"
+	"testing"
+
+	pebbledb "github.com/cockroachdb/pebble"
+	"github.com/cockroachdb/pebble/vfs"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/embedded"
+	"go.opentelemetry.io/otel/trace/noop"
+
+	"github.com/gotd/td/tg"
+
+	"github.com/gotd/contrib/pebble"
+	"github.com/gotd/contrib/storage"
+)
+
+type testSpan struct {
+	noop.Span
+	name   string
+	status codes.Code
+	errors int
+	ended  bool
+}
+
+func (s *testSpan) SetStatus(code codes.Code, _ string)     { s.status = code }
+func (s *testSpan) RecordError(error, ...trace.EventOption) { s.errors++ }
+func (s *testSpan) End(...trace.SpanEndOption)              { s.ended = true }
+
+type testProvider struct {
+	embedded.TracerProvider
+	tracer *testTracer
+}
+
+func (p testProvider) Tracer(string, ...trace.TracerOption) trace.Tracer { return p.tracer }
+
+type testTracer struct {
+	embedded.Tracer
+	spans []*testSpan
+}
+
+func (t *testTracer) Start(ctx context.Context, name string, _ ...trace.SpanStartOption) (context.Context, trace.Span) {
+	s := &testSpan{name: name}
+	t.spans = append(t.spans, s)
+	return trace.ContextWithSpan(ctx, s), s
+}
+
+func TestStorage(t *testing.T) {
+	a := require.New(t)
+	ctx := context.Background()
+
+	db, err := pebbledb.Open("pebble.db", &pebbledb.Options{FS: vfs.NewMem()})
+	a.NoError(err)
+	t.Cleanup(func() {
+		_ = db.Close()
+	})
+	tracer := &testTracer{}
+	s := NewStorage(pebble.NewPeerStorage(db), testProvider{tracer: tracer}).WithBackend("pebble")
+
+	var p storage.Peer
+	a.True(p.FromUser(&tg.User{ID: 10, AccessHash: 10, Username: "user"}))
+	a.NoError(s.Add(ctx, p))
+	_, err = s.Find(ctx, storage.KeyFromPeer(p))
+	a.NoError(err)
+	_, err = s.Resolve(ctx, "missing")
+	a.ErrorIs(err, storage.ErrPeerNotFound)
+
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	_, err = s.Find(canceled, storage.KeyFromPeer(p))
+	a.ErrorIs(err, context.Canceled)
+
+	a.Len(tracer.spans, 4)
+	for i, name := range []string{
+		"tg.storage: add",
+		"tg.storage: find",
+		"tg.storage: resolve",
+		"tg.storage: find",
+	} {
+		span := tracer.spans[i]
+		a.Equal(name, span.name)
+		a.True(span.ended)
+	}
+	a.Equal(codes.Ok, tracer.spans[2].status, "not found is not an error")
+	a.Equal(codes.Error, tracer.spans[3].status)
+	a.Equal(1, tracer.spans[3].errors)
+}
@@ -0,0 +1,135 @@
+package oteltg
+
+import (
+	"context"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/gotd/td/telegram"
+	"github.com/gotd/td/tg"
+
+	"github.com/gotd/contrib/tenant"
+)
+
+// Updates is OpenTelemetry instrumentation of updates path.
+type Updates struct {
+	meter    metric.Meter
+	received metric.Int64Counter
+	failures metric.Int64Counter
+	gaps     metric.Int64Counter
+	inFlight metric.Int64UpDownCounter
+	duration metric.Float64Histogram
+	tracer   trace.Tracer
+}
+
+// NewUpdates initializes and returns new updates instrumentation.
+func NewUpdates(meterProvider metric.MeterProvider, tracerProvider trace.TracerProvider) (*Updates, error) {
+	const name = "github.com/gotd/contrib/oteltg"
+	u := &Updates{
+		meter:  meterProvider.Meter(name),
+		tracer: tracerProvider.Tracer(name),
+	}
+	var err error
+	if u.received, err = u.meter.Int64Counter("tg.updates.received"); err != nil {
+		return nil, err
+	}
+	if u.failures, err = u.meter.Int64Counter("tg.updates.failures"); err != nil {
+		return nil, err
+	}
+	if u.gaps, err = u.meter.Int64Counter("tg.updates.gaps"); err != nil {
+		return nil, err
+	}
+	if u.inFlight, err = u.meter.Int64UpDownCounter("tg.updates.in_flight"); err != nil {
+		return nil, err
+	}
+	if u.duration, err = u.meter.Float64Histogram("tg.updates.duration"); err != nil {
+		return nil, err
+	}
+	return u, nil
+}
+
+// updateTypes returns TL names of updates in container.
+func updateTypes(u tg.UpdatesClass) []string {
+	var updates []tg.UpdateClass
+	switch u := u.(type) {
+	case *tg.Updates:
+		updates = u.Updates
+	case *tg.UpdatesCombined:
+		updates = u.Updates
+	case *tg.UpdateShort:
+		updates = []tg.UpdateClass{u.Update}
+	case *tg.UpdatesTooLong:
+		return nil
+	default:
+		// Short messages.
+		return []string{u.TypeName()}
+	}
+
+	types := make([]string, 0, len(updates))
+	for _, update := range updates {
+		types = append(types, update.TypeName())
+	}
+	return types
+}
+
+// Handle returns telegram.UpdateHandler which instruments next one.
+func (u *Updates) Handle(next telegram.UpdateHandler) telegram.UpdateHandler {
+	return telegram.UpdateHandlerFunc(func(ctx context.Context, updates tg.UpdatesClass) error {
+		var attrs []attribute.KeyValue
+		if id, ok := tenant.FromContext(ctx); ok {
+			attrs = append(attrs, attribute.String("tg.tenant", id))
+		}
+		if _, ok := updates.(*tg.UpdatesTooLong); ok {
+			u.gaps.Add(ctx, 1, metric.WithAttributes(
+				append(attrs, attribute.String("tg.updates.gap", "too_long"))...,
+			))
+		}
+		for _, typ := range updateTypes(updates) {
+			u.received.Add(ctx, 1, metric.WithAttributes(
+				append(attrs, attribute.String("tg.update", typ))...,
+			))
+		}
+
+		ctx, span := u.tracer.Start(ctx, "tg.updates: "+updates.TypeName(), trace.WithAttributes(attrs...))
+		defer span.End()
+		u.inFlight.Add(ctx, 1, metric.WithAttributes(attrs...))
+		defer u.inFlight.Add(ctx, -1, metric.WithAttributes(attrs...))
+		start := time.Now()
+
+		err := next.Handle(ctx, updates)
+
+		u.duration.Record(ctx, time.Since(start).Seconds(), metric.WithAttributes(attrs...))
+		if err != nil {
+			span.SetStatus(codes.Error, "Handler error")
+			span.RecordError(err)
+			u.failures.Add(ctx, 1, metric.WithAttributes(attrs...))
+		} else {
+			span.SetStatus(codes.Ok, "")
+		}
+		return err
+	})
+}
+
+// OnChannelTooLong records gap of channel which is too long to recover
+// by difference. It can be used as updates.Config.OnChannelTooLong.
+func (u *Updates) OnChannelTooLong(channelID int64) {
+	u.gaps.Add(context.Background(), 1, metric.WithAttributes(
+		attribute.String("tg.updates.gap", "channel_too_long"),
+	))
+}
+
+// ObserveQueue registers gauge of updates queue depth, e.g. length of
+// channel of handler worker pool.
+func (u *Updates) ObserveQueue(queue string, depth func() int64) error {
+	_, err := u.meter.Int64ObservableGauge("tg.updates.queue",
+		metric.WithInt64Callback(func(ctx context.Context, o metric.Int64Observer) error {
+			o.Observe(depth(), metric.WithAttributes(attribute.String("tg.updates.queue", queue)))
+			return nil
+		}),
+	)
+	return err
+}
@@ -0,0 +1,44 @@
+package oteltg
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-faster/errors"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/metric/noop"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/gotd/td/telegram"
+	"github.com/gotd/td/tg"
+)
+
+func TestUpdateTypes(t *testing.T) {
+	require.Equal(t, []string{"updateNewMessage", "updateDeleteMessages"}, updateTypes(&tg.Updates{
+		Updates: []tg.UpdateClass{&tg.UpdateNewMessage{}, &tg.UpdateDeleteMessages{}},
+	}))
+	require.Equal(t, []string{"updateUserTyping"}, updateTypes(&tg.UpdateShort{
+		Update: &tg.UpdateUserTyping{},
+	}))
+	require.Equal(t, []string{"updateShortMessage"}, updateTypes(&tg.UpdateShortMessage{}))
+	require.Empty(t, updateTypes(&tg.UpdatesTooLong{}))
+}
+
+func TestUpdates_Handle(t *testing.T) {
+	u, err := NewUpdates(noop.NewMeterProvider(), trace.NewNoopTracerProvider())
+	require.NoError(t, err)
+	require.NoError(t, u.ObserveQueue("handler", func() int64 { return 0 }))
+
+	testErr := errors.New("test")
+	h := u.Handle(telegram.UpdateHandlerFunc(func(ctx context.Context, updates tg.UpdatesClass) error {
+		if _, ok := updates.(*tg.UpdatesTooLong); ok {
+			return testErr
+		}
+		return nil
+	}))
+
+	ctx := context.Background()
+	require.NoError(t, h.Handle(ctx, &tg.UpdateShort{Update: &tg.UpdateUserTyping{}}))
+	require.ErrorIs(t, h.Handle(ctx, &tg.UpdatesTooLong{}), testErr)
+	u.OnChannelTooLong(10)
+}
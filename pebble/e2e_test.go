@@ -1,13 +1,20 @@
 package pebble_test
 
 import (
+	"context"
 	"testing"
 
 	pebbledb "github.com/cockroachdb/pebble"
 	"github.com/cockroachdb/pebble/vfs"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gotd/td/tg"
 
 	"github.com/gotd/contrib/internal/tests"
 	"github.com/gotd/contrib/pebble"
+	"github.com/gotd/contrib/storage"
+	"github.com/gotd/contrib/storage/storagetest"
 )
 
 func TestE2E(t *testing.T) {
@@ -21,4 +28,192 @@ func TestE2E(t *testing.T) {
 	tests.TestSessionStorage(t, pebble.NewSessionStorage(db, "testsession"))
 	tests.TestCredentials(t, pebble.NewCredentials(db))
 	tests.TestPeerStorage(t, pebble.NewPeerStorage(db))
+
+	t.Run("Conformance", func(t *testing.T) {
+		storagetest.TestPeerStorage(t, func() storage.PeerStorage {
+			db, err := pebbledb.Open("pebble.db", &pebbledb.Options{
+				FS: vfs.NewMem(),
+			})
+			require.NoError(t, err)
+			t.Cleanup(func() {
+				_ = db.Close()
+			})
+			return pebble.NewPeerStorage(db)
+		})
+	})
+	t.Run("Namespace", func(t *testing.T) {
+		db, err := pebbledb.Open("pebble.db", &pebbledb.Options{
+			FS: vfs.NewMem(),
+		})
+		require.NoError(t, err)
+		defer db.Close()
+
+		storagetest.TestIsolation(t,
+			pebble.NewPeerStorage(db).WithNamespace("first"),
+			pebble.NewPeerStorage(db).WithNamespace("second"),
+		)
+		storagetest.TestIsolation(t,
+			pebble.NewPeerStorage(db),
+			pebble.NewPeerStorage(db).WithNamespace("third"),
+		)
+		tests.TestPeerStorage(t, pebble.NewPeerStorage(db).WithNamespace("fourth"))
+	})
+	t.Run("BinaryCodec", func(t *testing.T) {
+		db, err := pebbledb.Open("pebble.db", &pebbledb.Options{
+			FS: vfs.NewMem(),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		tests.TestPeerStorage(t, pebble.NewPeerStorage(db).WithCodec(storage.BinaryCodec{}))
+	})
+	t.Run("CodecSwitch", func(t *testing.T) {
+		db, err := pebbledb.Open("pebble.db", &pebbledb.Options{
+			FS: vfs.NewMem(),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		tests.TestPeerStorageCodecSwitch(t,
+			pebble.NewPeerStorage(db),
+			pebble.NewPeerStorage(db).WithCodec(storage.BinaryCodec{}),
+		)
+	})
+	t.Run("Ephemeral", func(t *testing.T) {
+		opts := pebble.EphemeralOptions()
+		opts.FS = vfs.NewMem()
+		db, err := pebbledb.Open("pebble.db", opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		tests.TestPeerStorage(t, pebble.NewPeerStorage(db).WithWriteOptions(pebbledb.NoSync))
+	})
+	t.Run("Backup", func(t *testing.T) {
+		a := require.New(t)
+		ctx := context.Background()
+		fs := vfs.NewMem()
+
+		db, err := pebbledb.Open("pebble.db", &pebbledb.Options{FS: fs})
+		a.NoError(err)
+		defer db.Close()
+		s := pebble.NewPeerStorage(db)
+
+		var before, after storage.Peer
+		a.True(before.FromUser(&tg.User{ID: 1, AccessHash: 1}))
+		a.True(after.FromUser(&tg.User{ID: 2, AccessHash: 2}))
+
+		a.NoError(s.Add(ctx, before))
+		a.NoError(s.Backup(ctx, "backup"))
+		a.Error(s.Backup(ctx, "backup"), "backup directory exists")
+		a.NoError(s.Add(ctx, after))
+
+		a.NoError(pebble.Restore(ctx, fs, "backup", "restored"))
+		a.Error(pebble.Restore(ctx, fs, "backup", "restored"), "directory is not empty")
+
+		restored, err := pebbledb.Open("restored", &pebbledb.Options{FS: fs})
+		a.NoError(err)
+		defer restored.Close()
+		r := pebble.NewPeerStorage(restored)
+
+		_, err = r.Find(ctx, storage.KeyFromPeer(before))
+		a.NoError(err)
+		_, err = r.Find(ctx, storage.KeyFromPeer(after))
+		a.ErrorIs(err, storage.ErrPeerNotFound)
+	})
+	t.Run("ReadOnly", func(t *testing.T) {
+		a := require.New(t)
+		ctx := context.Background()
+		fs := vfs.NewMem()
+
+		db, err := pebbledb.Open("pebble.db", &pebbledb.Options{FS: fs})
+		a.NoError(err)
+		defer db.Close()
+
+		var p storage.Peer
+		a.True(p.FromUser(&tg.User{ID: 1, AccessHash: 1, Username: "user"}))
+		a.NoError(pebble.NewPeerStorage(db).Add(ctx, p))
+		a.NoError(pebble.NewPeerStorage(db).Backup(ctx, "backup"))
+
+		opts := pebble.ReadOnlyOptions()
+		opts.FS = fs
+		_, err = pebbledb.Open("missing", opts)
+		a.Error(err)
+
+		backup, err := pebbledb.Open("backup", opts)
+		a.NoError(err)
+		defer backup.Close()
+		r := pebble.NewPeerStorage(backup)
+
+		_, err = r.Resolve(ctx, "user")
+		a.NoError(err)
+		a.Error(r.Add(ctx, p))
+		a.ErrorIs(storage.NewReadOnly(r).Add(ctx, p), storage.ErrReadOnly)
+	})
+	t.Run("Metrics", func(t *testing.T) {
+		a := require.New(t)
+		ctx := context.Background()
+		db, err := pebbledb.Open("pebble.db", &pebbledb.Options{
+			FS: vfs.NewMem(),
+		})
+		a.NoError(err)
+		defer db.Close()
+		s := pebble.NewPeerStorage(db)
+
+		var p storage.Peer
+		a.True(p.FromUser(&tg.User{ID: 1, AccessHash: 1}))
+		a.NoError(s.Add(ctx, p))
+		a.NoError(db.Flush())
+		a.Positive(s.Metrics().Flush.Count)
+
+		r := prometheus.NewPedanticRegistry()
+		a.NoError(r.Register(s.Collector()))
+		families, err := r.Gather()
+		a.NoError(err)
+
+		values := map[string]float64{}
+		for _, f := range families {
+			for _, m := range f.GetMetric() {
+				if len(m.GetLabel()) > 0 {
+					continue
+				}
+				values[f.GetName()] = m.GetCounter().GetValue() + m.GetGauge().GetValue()
+			}
+		}
+		a.Positive(values["tg_pebble_flushes_total"])
+		a.Positive(values["tg_pebble_disk_usage_bytes"])
+		a.Contains(values, "tg_pebble_block_cache_hits_total")
+	})
+	t.Run("Context", func(t *testing.T) {
+		a := require.New(t)
+		db, err := pebbledb.Open("pebble.db", &pebbledb.Options{
+			FS: vfs.NewMem(),
+		})
+		a.NoError(err)
+		defer db.Close()
+		s := pebble.NewPeerStorage(db)
+
+		var p storage.Peer
+		a.True(p.FromUser(&tg.User{ID: 1, AccessHash: 1, Username: "user"}))
+		a.NoError(s.Add(context.Background(), p))
+		iter, err := s.Iterate(context.Background())
+		a.NoError(err)
+		defer iter.Close()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		a.ErrorIs(s.Add(ctx, p), context.Canceled)
+		_, err = s.Find(ctx, storage.KeyFromPeer(p))
+		a.ErrorIs(err, context.Canceled)
+		_, err = s.Resolve(ctx, "user")
+		a.ErrorIs(err, context.Canceled)
+		_, err = s.Iterate(ctx)
+		a.ErrorIs(err, context.Canceled)
+
+		a.False(iter.Next(ctx))
+		a.ErrorIs(iter.Err(), context.Canceled)
+	})
 }
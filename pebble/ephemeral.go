@@ -0,0 +1,27 @@
+package pebble
+
+import (
+	"github.com/cockroachdb/pebble"
+)
+
+// EphemeralOptions returns pebble options tuned for ephemeral deployments,
+// e.g. CI bots or short-lived scrapers, where speed matters more than
+// durability.
+//
+// WAL is disabled, memtables are larger and compression is turned off.
+// Writes which are not flushed from memtable are lost on process crash
+// or restart, so these options MUST NOT be used for data which should
+// survive restart, like sessions of long-running clients.
+//
+// Use pebble.NoSync as write options of storages opened with these options.
+func EphemeralOptions() *pebble.Options {
+	opts := &pebble.Options{
+		DisableWAL:   true,
+		MemTableSize: 64 << 20,
+	}
+	opts.Levels = make([]pebble.LevelOptions, 7)
+	for i := range opts.Levels {
+		opts.Levels[i].Compression = pebble.NoCompression
+	}
+	return opts.EnsureDefaults()
+}
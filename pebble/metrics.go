@@ -0,0 +1,138 @@
+package pebble
+
+import (
+	"strconv"
+
+	"github.com/cockroachdb/pebble"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Metrics returns metrics of the database, e.g. of levels, compactions
+// and block cache.
+func (s PeerStorage) Metrics() *pebble.Metrics {
+	return s.pebble.Metrics()
+}
+
+// Collector returns prometheus collector of database metrics.
+//
+// Metrics are read from database on every scrape. Database metrics are
+// shared by all storages of the database, so register collector of one
+// storage per database, using prometheus.WrapRegistererWith to label them.
+func (s PeerStorage) Collector() prometheus.Collector {
+	return newCollector(s.pebble)
+}
+
+type collector struct {
+	db *pebble.DB
+
+	diskUsage     *prometheus.Desc
+	readAmp       *prometheus.Desc
+	levelFiles    *prometheus.Desc
+	levelBytes    *prometheus.Desc
+	levelScore    *prometheus.Desc
+	sublevels     *prometheus.Desc
+	compactions   *prometheus.Desc
+	compacting    *prometheus.Desc
+	compactTime   *prometheus.Desc
+	flushes       *prometheus.Desc
+	memtableBytes *prometheus.Desc
+	walBytes      *prometheus.Desc
+	cacheBytes    *prometheus.Desc
+	cacheHits     *prometheus.Desc
+	cacheMisses   *prometheus.Desc
+}
+
+func newCollector(db *pebble.DB) *collector {
+	level := []string{"level"}
+	return &collector{
+		db: db,
+
+		diskUsage: prometheus.NewDesc("tg_pebble_disk_usage_bytes",
+			"Disk space used by the database.", nil, nil),
+		readAmp: prometheus.NewDesc("tg_pebble_read_amplification",
+			"Current read amplification of the database.", nil, nil),
+		levelFiles: prometheus.NewDesc("tg_pebble_level_files",
+			"Count of sstables in LSM level.", level, nil),
+		levelBytes: prometheus.NewDesc("tg_pebble_level_bytes",
+			"Size of sstables in LSM level.", level, nil),
+		levelScore: prometheus.NewDesc("tg_pebble_level_compaction_score",
+			"Compaction score of LSM level.", level, nil),
+		sublevels: prometheus.NewDesc("tg_pebble_l0_sublevels",
+			"Count of L0 sublevels, writes are stalled if it is too high.", nil, nil),
+		compactions: prometheus.NewDesc("tg_pebble_compactions_total",
+			"Total count of compactions.", nil, nil),
+		compacting: prometheus.NewDesc("tg_pebble_compactions_in_progress",
+			"Count of compactions in progress.", nil, nil),
+		compactTime: prometheus.NewDesc("tg_pebble_compaction_duration_seconds_total",
+			"Total duration of compactions.", nil, nil),
+		flushes: prometheus.NewDesc("tg_pebble_flushes_total",
+			"Total count of memtable flushes.", nil, nil),
+		memtableBytes: prometheus.NewDesc("tg_pebble_memtable_bytes",
+			"Size of memtables.", nil, nil),
+		walBytes: prometheus.NewDesc("tg_pebble_wal_bytes",
+			"Physical size of WAL files.", nil, nil),
+		cacheBytes: prometheus.NewDesc("tg_pebble_block_cache_bytes",
+			"Size of block cache.", nil, nil),
+		cacheHits: prometheus.NewDesc("tg_pebble_block_cache_hits_total",
+			"Total count of block cache hits.", nil, nil),
+		cacheMisses: prometheus.NewDesc("tg_pebble_block_cache_misses_total",
+			"Total count of block cache misses.", nil, nil),
+	}
+}
+
+func (c *collector) descs() []*prometheus.Desc {
+	return []*prometheus.Desc{
+		c.diskUsage,
+		c.readAmp,
+		c.levelFiles,
+		c.levelBytes,
+		c.levelScore,
+		c.sublevels,
+		c.compactions,
+		c.compacting,
+		c.compactTime,
+		c.flushes,
+		c.memtableBytes,
+		c.walBytes,
+		c.cacheBytes,
+		c.cacheHits,
+		c.cacheMisses,
+	}
+}
+
+// Describe implements prometheus.Collector.
+func (c *collector) Describe(ch chan<- *prometheus.Desc) {
+	for _, d := range c.descs() {
+		ch <- d
+	}
+}
+
+// Collect implements prometheus.Collector.
+func (c *collector) Collect(ch chan<- prometheus.Metric) {
+	m := c.db.Metrics()
+	gauge := func(d *prometheus.Desc, v float64, labels ...string) {
+		ch <- prometheus.MustNewConstMetric(d, prometheus.GaugeValue, v, labels...)
+	}
+	counter := func(d *prometheus.Desc, v float64) {
+		ch <- prometheus.MustNewConstMetric(d, prometheus.CounterValue, v)
+	}
+
+	gauge(c.diskUsage, float64(m.DiskSpaceUsage()))
+	gauge(c.readAmp, float64(m.ReadAmp()))
+	for i, l := range m.Levels {
+		level := strconv.Itoa(i)
+		gauge(c.levelFiles, float64(l.NumFiles), level)
+		gauge(c.levelBytes, float64(l.Size), level)
+		gauge(c.levelScore, l.Score, level)
+	}
+	gauge(c.sublevels, float64(m.Levels[0].Sublevels))
+	counter(c.compactions, float64(m.Compact.Count))
+	gauge(c.compacting, float64(m.Compact.NumInProgress))
+	counter(c.compactTime, m.Compact.Duration.Seconds())
+	counter(c.flushes, float64(m.Flush.Count))
+	gauge(c.memtableBytes, float64(m.MemTable.Size))
+	gauge(c.walBytes, float64(m.WAL.PhysicalSize))
+	gauge(c.cacheBytes, float64(m.BlockCache.Size))
+	counter(c.cacheHits, float64(m.BlockCache.Hits))
+	counter(c.cacheMisses, float64(m.BlockCache.Misses))
+}
@@ -0,0 +1,70 @@
+package pebble
+
+import (
+	"context"
+
+	"github.com/cockroachdb/pebble"
+	"github.com/cockroachdb/pebble/vfs"
+	"github.com/go-faster/errors"
+)
+
+// Backup creates consistent hot backup of the whole database in given
+// directory using pebble checkpoint. Directory must not exist.
+//
+// Memtables are flushed first, so backup includes writes which are not in
+// WAL, e.g. of databases opened with EphemeralOptions.
+//
+// Backup files are hard-linked when possible, so backup directory should be
+// on the same file system as database to be cheap. Backup is a valid pebble
+// database and can be opened directly or copied back by Restore.
+func (s PeerStorage) Backup(ctx context.Context, dir string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := s.pebble.Flush(); err != nil {
+		return errors.Errorf("flush: %w", err)
+	}
+	if err := s.pebble.Checkpoint(dir, pebble.WithFlushedWAL()); err != nil {
+		return errors.Errorf("checkpoint: %w", err)
+	}
+	return nil
+}
+
+// Restore copies backup created by Backup to given database directory.
+// Directory must not exist or be empty, and database must not be open.
+//
+// fs is a file system of both directories, usually vfs.Default.
+func Restore(ctx context.Context, fs vfs.FS, backupDir, dir string) error {
+	files, err := fs.List(backupDir)
+	if err != nil {
+		return errors.Errorf("list backup: %w", err)
+	}
+	if existing, err := fs.List(dir); err == nil && len(existing) > 0 {
+		return errors.Errorf("directory %q is not empty", dir)
+	}
+	if err := fs.MkdirAll(dir, 0o755); err != nil {
+		return errors.Errorf("create directory: %w", err)
+	}
+
+	for _, name := range files {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := vfs.Copy(fs, fs.PathJoin(backupDir, name), fs.PathJoin(dir, name)); err != nil {
+			return errors.Errorf("copy %q: %w", name, err)
+		}
+	}
+
+	d, err := fs.OpenDir(dir)
+	if err != nil {
+		return errors.Errorf("open directory: %w", err)
+	}
+	if err := d.Sync(); err != nil {
+		_ = d.Close()
+		return errors.Errorf("sync directory: %w", err)
+	}
+	if err := d.Close(); err != nil {
+		return errors.Errorf("close directory: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,42 @@
+package pebble
+
+import (
+	"context"
+
+	"github.com/go-faster/errors"
+	"go.uber.org/multierr"
+
+	"github.com/gotd/td/telegram/query/dialogs"
+
+	"github.com/gotd/contrib/storage"
+)
+
+var _ storage.PeerCounter = PeerStorage{}
+
+// Count returns count of stored peers of given kinds.
+// If no kinds given, peers of all kinds are counted.
+//
+// Count scans keys only and does not decode values, so expired peers
+// which are not removed by DeleteExpired yet are counted too.
+func (s PeerStorage) Count(ctx context.Context, kinds ...dialogs.PeerKind) (_ int, rerr error) {
+	snap := s.pebble.NewSnapshot()
+	defer func() {
+		multierr.AppendInto(&rerr, snap.Close())
+	}()
+
+	var count int
+	for _, prefix := range storage.KindPrefixes(kinds...) {
+		iter, err := snap.NewIter(s.iterOptions(prefix))
+		if err != nil {
+			return 0, errors.Errorf("new iter: %w", err)
+		}
+		for iter.First(); iter.Valid(); iter.Next() {
+			count++
+		}
+		if err := multierr.Append(iter.Error(), iter.Close()); err != nil {
+			return 0, errors.Errorf("iterate: %w", err)
+		}
+	}
+
+	return count, nil
+}
@@ -0,0 +1,66 @@
+package pebble
+
+import (
+	"context"
+
+	"github.com/cockroachdb/pebble"
+	"github.com/go-faster/errors"
+	"go.uber.org/multierr"
+
+	"github.com/gotd/contrib/storage"
+)
+
+var _ storage.PeerDeleter = PeerStorage{}
+
+// Delete removes peer with given key and its associated keys.
+func (s PeerStorage) Delete(ctx context.Context, key storage.PeerKey) (rerr error) {
+	id := key.Bytes(nil)
+
+	snap := s.pebble.NewSnapshot()
+	defer func() {
+		multierr.AppendInto(&rerr, snap.Close())
+	}()
+
+	data, closer, err := snap.Get(s.key(id))
+	if err != nil {
+		if errors.Is(err, pebble.ErrNotFound) {
+			return storage.ErrPeerNotFound
+		}
+		return errors.Errorf("get %q: %w", id, err)
+	}
+	var value storage.Peer
+	err = s.codec.Unmarshal(data, &value)
+	if closeErr := closer.Close(); closeErr != nil {
+		return errors.Errorf("close %q: %w", id, closeErr)
+	}
+	if err != nil && !errors.Is(err, storage.ErrPeerUnmarshalMustInvalidate) {
+		return errors.Errorf("unmarshal: %w", err)
+	}
+
+	b := s.pebble.NewBatch()
+	defer func() {
+		multierr.AppendInto(&rerr, b.Close())
+	}()
+
+	if err := b.Delete(s.key(id), nil); err != nil {
+		return errors.Errorf("delete %q: %w", id, err)
+	}
+	for _, k := range value.Keys() {
+		if err := s.deleteAssociated(snap, b, []byte(k), id); err != nil {
+			return err
+		}
+	}
+	if err := s.deleteRecent(snap, b, id); err != nil {
+		return err
+	}
+	if err := s.deleteUsername(b, id, value); err != nil {
+		return err
+	}
+
+	if err := b.Commit(s.writeOpts); err != nil {
+		return errors.Errorf("commit: %w", err)
+	}
+	s.watch.Publish(storage.Event{Type: storage.EventDelete, Key: key})
+
+	return nil
+}
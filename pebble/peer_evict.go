@@ -0,0 +1,90 @@
+package pebble
+
+import (
+	"bytes"
+	"context"
+	"time"
+
+	"github.com/go-faster/errors"
+	"go.uber.org/multierr"
+
+	"github.com/gotd/contrib/storage"
+)
+
+var _ storage.PeerEvicter = PeerStorage{}
+
+// Evict removes peers which were not added, assigned or touched during
+// given duration and returns count of removed peers.
+//
+// Recency index is scanned from the oldest entry, so only evicted peers
+// are read.
+func (s PeerStorage) Evict(ctx context.Context, olderThan time.Duration) (int, error) {
+	keys, err := s.untouched(ctx, time.Now().Add(-olderThan))
+	if err != nil {
+		return 0, err
+	}
+
+	var n int
+	for _, key := range keys {
+		if err := s.Delete(ctx, key); err != nil {
+			if errors.Is(err, storage.ErrPeerNotFound) {
+				continue
+			}
+			return n, errors.Errorf("delete %s: %w", key, err)
+		}
+		n++
+	}
+	return n, nil
+}
+
+// untouched returns keys of peers last touched before cutoff.
+func (s PeerStorage) untouched(ctx context.Context, cutoff time.Time) (_ []storage.PeerKey, rerr error) {
+	snap := s.pebble.NewSnapshot()
+	defer func() {
+		multierr.AppendInto(&rerr, snap.Close())
+	}()
+
+	iter, err := snap.NewIter(s.iterOptions(recentPrefix))
+	if err != nil {
+		return nil, errors.Errorf("new iter: %w", err)
+	}
+	defer func() {
+		multierr.AppendInto(&rerr, iter.Close())
+	}()
+
+	var (
+		until = encodeTime(cutoff)
+		keys  []storage.PeerKey
+	)
+	for iter.First(); iter.Valid(); iter.Next() {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		k := iter.Key()[len(s.ns)+len(recentPrefix):]
+		if len(k) < 8 {
+			continue
+		}
+		ts, id := k[:8], k[8:]
+		if bytes.Compare(ts, until) >= 0 {
+			break
+		}
+
+		last, err := s.lastTouched(snap, id)
+		if err != nil {
+			return nil, err
+		}
+		if !bytes.Equal(ts, last) {
+			// Stale entry.
+			continue
+		}
+		var key storage.PeerKey
+		if err := key.Parse(id); err != nil {
+			continue
+		}
+		keys = append(keys, key)
+	}
+	if err := iter.Error(); err != nil {
+		return nil, errors.Errorf("iterate: %w", err)
+	}
+	return keys, nil
+}
@@ -0,0 +1,35 @@
+package pebble
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/multierr"
+
+	"github.com/gotd/contrib/storage"
+)
+
+var _ storage.PeerIDFinder = PeerStorage{}
+
+// FindByID finds peer with given ID of any kind.
+//
+// Peer keys are prefixed by kind, so FindByID does one point lookup per
+// kind using the same snapshot.
+func (s PeerStorage) FindByID(ctx context.Context, id int64) (_ storage.Peer, rerr error) {
+	snap := s.pebble.NewSnapshot()
+	defer func() {
+		multierr.AppendInto(&rerr, snap.Close())
+	}()
+
+	now := time.Now()
+	for _, kind := range storage.IDKinds {
+		p, ok, err := s.get(snap, storage.PeerKey{Kind: kind, ID: id}.Bytes(nil))
+		if err != nil {
+			return storage.Peer{}, err
+		}
+		if ok && !p.Expired(now) {
+			return p, nil
+		}
+	}
+	return storage.Peer{}, storage.ErrPeerNotFound
+}
@@ -0,0 +1,76 @@
+package pebble
+
+import (
+	"context"
+
+	"github.com/go-faster/errors"
+	"go.uber.org/multierr"
+
+	"github.com/gotd/contrib/storage"
+)
+
+var _ storage.PeerGarbageCollector = PeerStorage{}
+
+// CollectGarbage removes associated keys which point to missing peers
+// and returns count of removed keys.
+//
+// Keys are checked using a snapshot, so keys re-associated concurrently
+// may be removed too.
+func (s PeerStorage) CollectGarbage(ctx context.Context, opts storage.GCOptions) (_ int, rerr error) {
+	snap := s.pebble.NewSnapshot()
+	defer func() {
+		multierr.AppendInto(&rerr, snap.Close())
+	}()
+
+	iter, err := snap.NewIter(s.iterOptions(nil))
+	if err != nil {
+		return 0, errors.Errorf("new iter: %w", err)
+	}
+	defer func() {
+		multierr.AppendInto(&rerr, iter.Close())
+	}()
+
+	b := s.pebble.NewBatch()
+	defer func() {
+		multierr.AppendInto(&rerr, b.Close())
+	}()
+
+	var count int
+	for iter.First(); iter.Valid(); iter.Next() {
+		if err := ctx.Err(); err != nil {
+			return 0, err
+		}
+
+		key := iter.Key()[len(s.ns):]
+		target, ok := storage.AssociatedKey(key, iter.Value())
+		if !ok {
+			continue
+		}
+		p, found, err := s.get(snap, target.Bytes(nil))
+		if err != nil {
+			return 0, err
+		}
+		if !storage.Orphaned(string(key), p, found, opts) {
+			continue
+		}
+
+		count++
+		if opts.DryRun {
+			continue
+		}
+		if err := b.Delete(iter.Key(), nil); err != nil {
+			return 0, errors.Errorf("delete %q: %w", key, err)
+		}
+	}
+	if err := iter.Error(); err != nil {
+		return 0, errors.Errorf("iterate: %w", err)
+	}
+
+	if opts.DryRun || count == 0 {
+		return count, nil
+	}
+	if err := b.Commit(s.writeOpts); err != nil {
+		return 0, errors.Errorf("commit: %w", err)
+	}
+	return count, nil
+}
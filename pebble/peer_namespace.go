@@ -0,0 +1,44 @@
+package pebble
+
+import (
+	"github.com/cockroachdb/pebble"
+)
+
+// namespacePrefix is a prefix of namespaced keys.
+// Key is namespacePrefix + namespace + 0x00 + key.
+//
+// Prefix starts with underscore, so namespaced keys are never considered
+// peer or associated keys of storage without namespace.
+var namespacePrefix = []byte("_ns:")
+
+// WithNamespace sets namespace of stored peers, e.g. account ID, so multiple
+// PeerStorage instances can share one database without collisions.
+//
+// All keys of the storage, including associated keys and indexes, are
+// prefixed by the namespace. Empty namespace means no prefix, which is
+// the default.
+func (s *PeerStorage) WithNamespace(ns string) *PeerStorage {
+	s.ns = nil
+	if ns != "" {
+		s.ns = make([]byte, 0, len(namespacePrefix)+len(ns)+1)
+		s.ns = append(s.ns, namespacePrefix...)
+		s.ns = append(s.ns, ns...)
+		s.ns = append(s.ns, 0)
+	}
+	return s
+}
+
+// key returns database key of given storage key.
+func (s PeerStorage) key(k []byte) []byte {
+	if len(s.ns) == 0 {
+		return k
+	}
+	r := make([]byte, 0, len(s.ns)+len(k))
+	r = append(r, s.ns...)
+	return append(r, k...)
+}
+
+// iterOptions returns options to iterate over storage keys with given prefix.
+func (s PeerStorage) iterOptions(prefix []byte) *pebble.IterOptions {
+	return prefixIterOptions(s.key(prefix))
+}
@@ -0,0 +1,66 @@
+package pebble
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-faster/errors"
+	"go.uber.org/multierr"
+
+	"github.com/gotd/contrib/storage"
+)
+
+var _ storage.PeerPager = PeerStorage{}
+
+// IteratePage returns page of peers following given cursor.
+// Empty cursor means the first page.
+//
+// Peers are returned in key order, cursor is the last returned key,
+// so pagination is stable even if storage is modified between calls.
+func (s PeerStorage) IteratePage(ctx context.Context, cursor string, limit int) (_ storage.PeerPage, rerr error) {
+	if limit <= 0 {
+		return storage.PeerPage{}, errors.Errorf("invalid limit %d", limit)
+	}
+
+	iter, err := s.pebble.NewIter(s.iterOptions(storage.PeerKeyPrefix))
+	if err != nil {
+		return storage.PeerPage{}, errors.Errorf("new iter: %w", err)
+	}
+	defer func() {
+		multierr.AppendInto(&rerr, iter.Close())
+	}()
+
+	if cursor == "" {
+		iter.First()
+	} else if iter.SeekGE(s.key([]byte(cursor))) && string(iter.Key()[len(s.ns):]) == cursor {
+		iter.Next()
+	}
+
+	var (
+		page storage.PeerPage
+		now  = time.Now()
+	)
+	for ; iter.Valid(); iter.Next() {
+		if len(page.Peers) == limit {
+			page.Next = string(storage.KeyFromPeer(page.Peers[len(page.Peers)-1]).Bytes(nil))
+			break
+		}
+
+		var value storage.Peer
+		if err := s.codec.Unmarshal(iter.Value(), &value); err != nil {
+			if errors.Is(err, storage.ErrPeerUnmarshalMustInvalidate) {
+				continue
+			}
+			return storage.PeerPage{}, errors.Errorf("unmarshal: %w", err)
+		}
+		if value.Expired(now) {
+			continue
+		}
+		page.Peers = append(page.Peers, value)
+	}
+	if err := iter.Error(); err != nil {
+		return storage.PeerPage{}, errors.Errorf("iterate: %w", err)
+	}
+
+	return page, nil
+}
@@ -0,0 +1,235 @@
+package pebble
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"time"
+
+	"github.com/cockroachdb/pebble"
+	"github.com/go-faster/errors"
+	"go.uber.org/multierr"
+
+	"github.com/gotd/contrib/storage"
+)
+
+var _ storage.PeerToucher = PeerStorage{}
+
+var (
+	// recentPrefix is a prefix of recency index keys.
+	// Key is recentPrefix + big-endian touch time + peer key.
+	recentPrefix = []byte("_recent:")
+	// touchedPrefix is a prefix of last touch time keys.
+	// Key is touchedPrefix + peer key, value is big-endian touch time.
+	touchedPrefix = []byte("_touched:")
+)
+
+func recentKey(ts, id []byte) []byte {
+	k := make([]byte, 0, len(recentPrefix)+len(ts)+len(id))
+	k = append(k, recentPrefix...)
+	k = append(k, ts...)
+	return append(k, id...)
+}
+
+func touchedKey(id []byte) []byte {
+	return append(append([]byte(nil), touchedPrefix...), id...)
+}
+
+func encodeTime(t time.Time) []byte {
+	return binary.BigEndian.AppendUint64(nil, uint64(t.UnixNano()))
+}
+
+// lastTouched returns last touch time of given peer, or nil.
+func (s PeerStorage) lastTouched(r pebble.Reader, id []byte) ([]byte, error) {
+	key := s.key(touchedKey(id))
+	v, closer, err := r.Get(key)
+	if err != nil {
+		if errors.Is(err, pebble.ErrNotFound) {
+			return nil, nil
+		}
+		return nil, errors.Errorf("get %q: %w", key, err)
+	}
+	ts := append([]byte(nil), v...)
+	if err := closer.Close(); err != nil {
+		return nil, errors.Errorf("close %q: %w", key, err)
+	}
+	return ts, nil
+}
+
+// touch writes recency index entries for given peer to the batch.
+func (s PeerStorage) touch(r pebble.Reader, b *pebble.Batch, id []byte, now time.Time) error {
+	if err := s.deleteRecent(r, b, id); err != nil {
+		return err
+	}
+
+	ts := encodeTime(now)
+	if err := b.Set(s.key(recentKey(ts, id)), nil, nil); err != nil {
+		return errors.Errorf("set recent: %w", err)
+	}
+	if err := b.Set(s.key(touchedKey(id)), ts, nil); err != nil {
+		return errors.Errorf("set touched: %w", err)
+	}
+	return nil
+}
+
+// deleteRecent writes deletion of recency index entries for given peer to the batch.
+func (s PeerStorage) deleteRecent(r pebble.Reader, b *pebble.Batch, id []byte) error {
+	ts, err := s.lastTouched(r, id)
+	if err != nil || ts == nil {
+		return err
+	}
+	if err := b.Delete(s.key(recentKey(ts, id)), nil); err != nil {
+		return errors.Errorf("delete recent: %w", err)
+	}
+	if err := b.Delete(s.key(touchedKey(id)), nil); err != nil {
+		return errors.Errorf("delete touched: %w", err)
+	}
+	return nil
+}
+
+// Touch marks peer with given key as recently used.
+func (s PeerStorage) Touch(ctx context.Context, key storage.PeerKey) (rerr error) {
+	id := key.Bytes(nil)
+
+	_, closer, err := s.pebble.Get(s.key(id))
+	if err != nil {
+		if errors.Is(err, pebble.ErrNotFound) {
+			return storage.ErrPeerNotFound
+		}
+		return errors.Errorf("get %q: %w", id, err)
+	}
+	if err := closer.Close(); err != nil {
+		return errors.Errorf("close %q: %w", id, err)
+	}
+
+	b := s.pebble.NewBatch()
+	defer func() {
+		multierr.AppendInto(&rerr, b.Close())
+	}()
+
+	if err := s.touch(s.pebble, b, id, time.Now()); err != nil {
+		return err
+	}
+	if err := b.Commit(s.writeOpts); err != nil {
+		return errors.Errorf("commit: %w", err)
+	}
+	return nil
+}
+
+type recentIterator struct {
+	s       PeerStorage
+	snap    *pebble.Snapshot
+	iter    *pebble.Iterator
+	reverse bool
+	started bool
+	lastErr error
+	value   storage.Peer
+	opts    storage.IterateOptions
+}
+
+func (p *recentIterator) Close() error {
+	return multierr.Append(p.iter.Close(), p.snap.Close())
+}
+
+func (p *recentIterator) step() bool {
+	switch {
+	case !p.started:
+		p.started = true
+		if p.reverse {
+			return p.iter.First()
+		}
+		return p.iter.Last()
+	case p.reverse:
+		return p.iter.Next()
+	default:
+		return p.iter.Prev()
+	}
+}
+
+// load loads peer referenced by current recency index entry.
+//
+// It returns false if entry is stale, or peer is not found or does not match.
+func (p *recentIterator) load(now time.Time) (bool, error) {
+	k := p.iter.Key()[len(p.s.ns)+len(recentPrefix):]
+	if len(k) < 8 {
+		return false, nil
+	}
+	ts, id := k[:8], k[8:]
+
+	last, err := p.s.lastTouched(p.snap, id)
+	if err != nil {
+		return false, err
+	}
+	if !bytes.Equal(ts, last) {
+		// Peer was touched again or deleted.
+		return false, nil
+	}
+
+	data, closer, err := p.snap.Get(p.s.key(id))
+	if err != nil {
+		if errors.Is(err, pebble.ErrNotFound) {
+			return false, nil
+		}
+		return false, errors.Errorf("get %q: %w", id, err)
+	}
+	p.value = storage.Peer{}
+	err = p.s.codec.Unmarshal(data, &p.value)
+	if closeErr := closer.Close(); closeErr != nil {
+		return false, errors.Errorf("close %q: %w", id, closeErr)
+	}
+	if err != nil {
+		if errors.Is(err, storage.ErrPeerUnmarshalMustInvalidate) {
+			return false, nil
+		}
+		return false, errors.Errorf("unmarshal: %w", err)
+	}
+
+	return !p.value.Expired(now) && p.opts.Match(p.value), nil
+}
+
+func (p *recentIterator) Next(ctx context.Context) bool {
+	now := time.Now()
+	for p.step() {
+		if err := ctx.Err(); err != nil {
+			p.lastErr = err
+			return false
+		}
+		ok, err := p.load(now)
+		if err != nil {
+			p.lastErr = err
+			return false
+		}
+		if ok {
+			return true
+		}
+	}
+	if err := p.iter.Error(); err != nil {
+		p.lastErr = errors.Errorf("iterate: %w", err)
+	}
+	return false
+}
+
+func (p *recentIterator) Err() error {
+	return p.lastErr
+}
+
+func (p *recentIterator) Value() storage.Peer {
+	return p.value
+}
+
+func (s PeerStorage) iterateRecent(opts storage.IterateOptions) (storage.PeerIterator, error) {
+	snap := s.pebble.NewSnapshot()
+	iter, err := snap.NewIter(s.iterOptions(recentPrefix))
+	if err != nil {
+		_ = snap.Close()
+		return nil, errors.Errorf("new iter: %w", err)
+	}
+
+	return &recentIterator{
+		s:       s,
+		snap:    snap,
+		iter:    iter,
+		reverse: opts.Order == storage.OrderRecentReverse,
+		opts:    opts,
+	}, nil
+}
@@ -0,0 +1,55 @@
+package pebble
+
+import (
+	"context"
+	"time"
+
+	"github.com/cockroachdb/pebble"
+	"github.com/go-faster/errors"
+	"go.uber.org/multierr"
+
+	"github.com/gotd/contrib/storage"
+)
+
+var _ storage.PeerBatchResolver = PeerStorage{}
+
+// ResolveBatch finds peers using given associated keys.
+// Keys not found are omitted from result.
+//
+// All keys are resolved using the same snapshot.
+func (s PeerStorage) ResolveBatch(ctx context.Context, keys []string) (_ map[string]storage.Peer, rerr error) {
+	snap := s.pebble.NewSnapshot()
+	defer func() {
+		multierr.AppendInto(&rerr, snap.Close())
+	}()
+
+	var (
+		now = time.Now()
+		r   = make(map[string]storage.Peer, len(keys))
+	)
+	for _, key := range keys {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if _, ok := r[key]; ok {
+			continue
+		}
+
+		id, closer, err := snap.Get(s.key([]byte(key)))
+		if err != nil {
+			if errors.Is(err, pebble.ErrNotFound) {
+				continue
+			}
+			return nil, errors.Errorf("get %q: %w", key, err)
+		}
+		p, ok, err := s.get(snap, id)
+		if err := multierr.Append(err, closer.Close()); err != nil {
+			return nil, err
+		}
+		if !ok || p.Expired(now) {
+			continue
+		}
+		r[key] = p
+	}
+	return r, nil
+}
@@ -0,0 +1,40 @@
+package pebble
+
+import (
+	"context"
+
+	"github.com/go-faster/errors"
+	"go.uber.org/multierr"
+
+	"github.com/gotd/contrib/storage"
+)
+
+var _ storage.RawScanner = PeerStorage{}
+
+// ScanRaw calls f for every key-value pair of the database, including
+// associated keys, indexes and data of other storages in the database.
+//
+// If namespace is set, only keys of the namespace are scanned and
+// passed to f without namespace prefix.
+func (s PeerStorage) ScanRaw(ctx context.Context, f func(key, value []byte) error) (rerr error) {
+	iter, err := s.pebble.NewIter(s.iterOptions(nil))
+	if err != nil {
+		return errors.Errorf("new iter: %w", err)
+	}
+	defer func() {
+		multierr.AppendInto(&rerr, iter.Close())
+	}()
+
+	for iter.First(); iter.Valid(); iter.Next() {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := f(iter.Key()[len(s.ns):], iter.Value()); err != nil {
+			return err
+		}
+	}
+	if err := iter.Error(); err != nil {
+		return errors.Errorf("iterate: %w", err)
+	}
+	return nil
+}
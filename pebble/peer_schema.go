@@ -0,0 +1,42 @@
+package pebble
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/cockroachdb/pebble"
+	"github.com/go-faster/errors"
+	"go.uber.org/multierr"
+
+	"github.com/gotd/contrib/storage"
+)
+
+var _ storage.SchemaVersioner = PeerStorage{}
+
+// SchemaVersion returns stored schema version.
+func (s PeerStorage) SchemaVersion(ctx context.Context) (_ int, rerr error) {
+	data, closer, err := s.pebble.Get(s.key([]byte(storage.SchemaKey)))
+	if err != nil {
+		if errors.Is(err, pebble.ErrNotFound) {
+			return 0, nil
+		}
+		return 0, errors.Errorf("get: %w", err)
+	}
+	defer func() {
+		multierr.AppendInto(&rerr, closer.Close())
+	}()
+
+	v, err := strconv.Atoi(string(data))
+	if err != nil {
+		return 0, errors.Errorf("parse %q: %w", data, err)
+	}
+	return v, nil
+}
+
+// SetSchemaVersion stores schema version.
+func (s PeerStorage) SetSchemaVersion(ctx context.Context, version int) error {
+	if err := s.pebble.Set(s.key([]byte(storage.SchemaKey)), strconv.AppendInt(nil, int64(version), 10), s.writeOpts); err != nil {
+		return errors.Errorf("set: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,160 @@
+package pebble
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"time"
+
+	"github.com/cockroachdb/pebble"
+	"github.com/go-faster/errors"
+	"go.uber.org/multierr"
+
+	"github.com/gotd/contrib/storage"
+)
+
+var _ storage.PeerSearcher = PeerStorage{}
+
+// usernamePrefix is a prefix of username index keys.
+// Key is usernamePrefix + lowercase username + 0x00 + peer key.
+var usernamePrefix = []byte("_username:")
+
+func usernameKey(username string, id []byte) []byte {
+	k := make([]byte, 0, len(usernamePrefix)+len(username)+1+len(id))
+	k = append(k, usernamePrefix...)
+	k = append(k, username...)
+	k = append(k, 0)
+	return append(k, id...)
+}
+
+// indexUsername writes username index entry for given peer to the batch.
+func (s PeerStorage) indexUsername(b *pebble.Batch, id []byte, value storage.Peer) error {
+	username := storage.UsernameIndex(value)
+	if username == "" {
+		return nil
+	}
+	if err := b.Set(s.key(usernameKey(username, id)), nil, nil); err != nil {
+		return errors.Errorf("set username: %w", err)
+	}
+	return nil
+}
+
+// deleteUsername writes deletion of username index entry for given peer to the batch.
+func (s PeerStorage) deleteUsername(b *pebble.Batch, id []byte, value storage.Peer) error {
+	username := storage.UsernameIndex(value)
+	if username == "" {
+		return nil
+	}
+	if err := b.Delete(s.key(usernameKey(username, id)), nil); err != nil {
+		return errors.Errorf("delete username: %w", err)
+	}
+	return nil
+}
+
+type searchIterator struct {
+	s       PeerStorage
+	snap    *pebble.Snapshot
+	iter    *pebble.Iterator
+	started bool
+	lastErr error
+	value   storage.Peer
+}
+
+func (p *searchIterator) Close() error {
+	return multierr.Append(p.iter.Close(), p.snap.Close())
+}
+
+// load loads peer referenced by current index entry.
+//
+// It returns false if entry is stale or peer is not found.
+func (p *searchIterator) load(now time.Time) (bool, error) {
+	k := p.iter.Key()[len(p.s.ns)+len(usernamePrefix):]
+	idx := bytes.IndexByte(k, 0)
+	if idx < 0 {
+		return false, nil
+	}
+	username, id := k[:idx], k[idx+1:]
+
+	data, closer, err := p.snap.Get(p.s.key(id))
+	if err != nil {
+		if errors.Is(err, pebble.ErrNotFound) {
+			return false, nil
+		}
+		return false, errors.Errorf("get %q: %w", id, err)
+	}
+	p.value = storage.Peer{}
+	err = p.s.codec.Unmarshal(data, &p.value)
+	if closeErr := closer.Close(); closeErr != nil {
+		return false, errors.Errorf("close %q: %w", id, closeErr)
+	}
+	if err != nil {
+		if errors.Is(err, storage.ErrPeerUnmarshalMustInvalidate) {
+			return false, nil
+		}
+		return false, errors.Errorf("unmarshal: %w", err)
+	}
+
+	// Peer may change username after indexing.
+	return !p.value.Expired(now) && storage.UsernameIndex(p.value) == string(username), nil
+}
+
+func (p *searchIterator) Next(ctx context.Context) bool {
+	now := time.Now()
+	for {
+		if !p.started {
+			p.started = true
+			if !p.iter.First() {
+				break
+			}
+		} else if !p.iter.Next() {
+			break
+		}
+
+		if err := ctx.Err(); err != nil {
+			p.lastErr = err
+			return false
+		}
+		ok, err := p.load(now)
+		if err != nil {
+			p.lastErr = err
+			return false
+		}
+		if ok {
+			return true
+		}
+	}
+	if err := p.iter.Error(); err != nil {
+		p.lastErr = errors.Errorf("iterate: %w", err)
+	}
+	return false
+}
+
+func (p *searchIterator) Err() error {
+	return p.lastErr
+}
+
+func (p *searchIterator) Value() storage.Peer {
+	return p.value
+}
+
+// SearchPrefix creates and returns new PeerIterator which returns only
+// peers whose username starts with given prefix.
+//
+// Peers are returned in username order. Search uses username index
+// maintained by Add and Assign.
+func (s PeerStorage) SearchPrefix(ctx context.Context, prefix string) (storage.PeerIterator, error) {
+	snap := s.pebble.NewSnapshot()
+	iter, err := snap.NewIter(s.iterOptions(
+		append(append([]byte(nil), usernamePrefix...), strings.ToLower(prefix)...),
+	))
+	if err != nil {
+		_ = snap.Close()
+		return nil, errors.Errorf("new iter: %w", err)
+	}
+
+	return &searchIterator{
+		s:    s,
+		snap: snap,
+		iter: iter,
+	}, nil
+}
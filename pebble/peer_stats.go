@@ -0,0 +1,27 @@
+package pebble
+
+import (
+	"context"
+
+	"github.com/gotd/contrib/storage"
+)
+
+var _ storage.PeerStatsReporter = PeerStorage{}
+
+// Stats returns storage statistics. Bytes is a disk space used by
+// the whole database, including data of other storages.
+func (s PeerStorage) Stats(ctx context.Context) (storage.Stats, error) {
+	r, err := storage.RawStats(ctx, s)
+	if err != nil {
+		return storage.Stats{}, err
+	}
+
+	m := s.pebble.Metrics()
+	r.Bytes = int64(m.DiskSpaceUsage())
+	r.Compaction = &storage.CompactionStats{
+		Count:      m.Compact.Count,
+		InProgress: m.Compact.NumInProgress,
+		Duration:   m.Compact.Duration,
+	}
+	return r, nil
+}
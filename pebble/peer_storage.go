@@ -3,7 +3,7 @@ package pebble
 import (
 	"bytes"
 	"context"
-	"encoding/json"
+	"time"
 
 	"github.com/cockroachdb/pebble"
 	"github.com/go-faster/errors"
@@ -12,17 +12,45 @@ import (
 	"github.com/gotd/contrib/storage"
 )
 
-var _ storage.PeerStorage = PeerStorage{}
+var (
+	_ storage.PeerStorage         = PeerStorage{}
+	_ storage.FilteredPeerStorage = PeerStorage{}
+	_ storage.PeerWatcher         = PeerStorage{}
+)
 
 // PeerStorage is a peer storage based on pebble.
 type PeerStorage struct {
 	pebble    *pebble.DB
+	codec     storage.Codec
 	writeOpts *pebble.WriteOptions
+	watch     *storage.Broadcaster
+	// ns is a key prefix of namespace, if any.
+	ns []byte
 }
 
 // NewPeerStorage creates new peer storage using pebble.
 func NewPeerStorage(db *pebble.DB) *PeerStorage {
-	return &PeerStorage{pebble: db}
+	return &PeerStorage{
+		pebble: db,
+		codec:  storage.JSONCodec{},
+		watch:  storage.NewBroadcaster(),
+	}
+}
+
+// Watch returns channel of changes made using this PeerStorage.
+//
+// Changes made by other PeerStorage instances of the same database are
+// not observed.
+func (s PeerStorage) Watch(ctx context.Context) (<-chan storage.Event, error) {
+	return s.watch.Watch(ctx)
+}
+
+// WithCodec sets codec of persisted peers. Default is storage.JSONCodec.
+//
+// Peers stored using another codec are considered outdated.
+func (s *PeerStorage) WithCodec(c storage.Codec) *PeerStorage {
+	s.codec = c
+	return s
 }
 
 // WithWriteOptions sets pebble's write options for write operations.
@@ -32,38 +60,71 @@ func (s *PeerStorage) WithWriteOptions(writeOpts *pebble.WriteOptions) *PeerStor
 }
 
 type pebbleIterator struct {
+	ns      int
+	codec   storage.Codec
 	snap    *pebble.Snapshot
 	iter    *pebble.Iterator
 	lastErr error
 	value   storage.Peer
+
+	// prefixes are remaining key prefixes to scan after current one.
+	prefixes [][]byte
+	opts     storage.IterateOptions
 }
 
 func (p *pebbleIterator) Close() error {
 	return multierr.Append(p.iter.Close(), p.snap.Close())
 }
 
-func (p *pebbleIterator) Next(ctx context.Context) bool {
-	if !p.iter.Valid() {
+// nextPrefix moves iterator to the next prefix, if any.
+func (p *pebbleIterator) nextPrefix() bool {
+	if len(p.prefixes) == 0 {
 		return false
 	}
+	prefix := p.prefixes[0]
+	p.prefixes = p.prefixes[1:]
+	p.iter.SetBounds(prefix, keyUpperBound(prefix))
+	// Prefix may be empty, Next checks validity and moves on.
+	p.iter.First()
+	return true
+}
 
+func (p *pebbleIterator) Next(ctx context.Context) bool {
+	now := time.Now()
 	for {
-		if bytes.HasPrefix(p.iter.Key(), storage.PeerKeyPrefix) {
-			break
+		for ; p.iter.Valid(); p.iter.Next() {
+			if err := ctx.Err(); err != nil {
+				p.lastErr = err
+				return false
+			}
+			if !bytes.HasPrefix(p.iter.Key()[p.ns:], storage.PeerKeyPrefix) {
+				continue
+			}
+
+			p.value = storage.Peer{}
+			if err := p.codec.Unmarshal(p.iter.Value(), &p.value); err != nil {
+				if errors.Is(err, storage.ErrPeerUnmarshalMustInvalidate) {
+					continue
+				}
+				p.lastErr = errors.Errorf("unmarshal: %w", err)
+				return false
+			}
+			if p.value.Expired(now) || !p.opts.Match(p.value) {
+				continue
+			}
+
+			p.iter.Next()
+			return true
 		}
 
-		if !p.iter.Next() {
+		if err := p.iter.Error(); err != nil {
+			p.lastErr = errors.Errorf("iterate: %w", err)
+			return false
+		}
+		if !p.nextPrefix() {
 			return false
 		}
 	}
-
-	if err := json.Unmarshal(p.iter.Value(), &p.value); err != nil {
-		p.lastErr = errors.Errorf("unmarshal: %w", err)
-		return false
-	}
-
-	p.iter.Next()
-	return true
 }
 
 func (p *pebbleIterator) Err() error {
@@ -95,8 +156,31 @@ func prefixIterOptions(prefix []byte) *pebble.IterOptions {
 
 // Iterate creates and returns new PeerIterator.
 func (s PeerStorage) Iterate(ctx context.Context) (storage.PeerIterator, error) {
+	return s.IterateFiltered(ctx, storage.IterateOptions{})
+}
+
+// IterateFiltered creates and returns new PeerIterator which returns only
+// peers matching given options.
+//
+// Peer kinds are filtered by key prefix without decoding other peers.
+// Recency order uses last-touched index maintained by Add, Assign and Touch.
+func (s PeerStorage) IterateFiltered(ctx context.Context, opts storage.IterateOptions) (storage.PeerIterator, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	var prefixes [][]byte
+	for _, prefix := range opts.Prefixes() {
+		prefixes = append(prefixes, s.key(prefix))
+	}
+	if len(prefixes) == 0 {
+		return storage.EmptyIterator(), nil
+	}
+	if opts.Order != storage.OrderKey {
+		return s.iterateRecent(opts)
+	}
+
 	snap := s.pebble.NewSnapshot()
-	iter, err := snap.NewIter(prefixIterOptions(storage.PeerKeyPrefix))
+	iter, err := snap.NewIter(prefixIterOptions(prefixes[0]))
 	if err != nil {
 		_ = snap.Close()
 		return nil, errors.Errorf("new iter: %w", err)
@@ -104,52 +188,124 @@ func (s PeerStorage) Iterate(ctx context.Context) (storage.PeerIterator, error)
 	iter.First()
 
 	return &pebbleIterator{
-		snap: snap,
-		iter: iter,
+		ns:       len(s.ns),
+		codec:    s.codec,
+		snap:     snap,
+		iter:     iter,
+		prefixes: prefixes[1:],
+		opts:     opts,
 	}, nil
 }
 
-func (s PeerStorage) add(associated []string, value storage.Peer) (rerr error) {
-	data, err := json.Marshal(value)
+// get finds and decodes peer by id. Outdated peers are considered not found.
+func (s PeerStorage) get(r pebble.Reader, id []byte) (_ storage.Peer, ok bool, rerr error) {
+	data, closer, err := r.Get(s.key(id))
 	if err != nil {
-		return errors.Errorf("marshal: %w", err)
+		if errors.Is(err, pebble.ErrNotFound) {
+			return storage.Peer{}, false, nil
+		}
+		return storage.Peer{}, false, errors.Errorf("get %q: %w", id, err)
+	}
+	defer func() {
+		multierr.AppendInto(&rerr, closer.Close())
+	}()
+
+	var p storage.Peer
+	if err := s.codec.Unmarshal(data, &p); err != nil {
+		if errors.Is(err, storage.ErrPeerUnmarshalMustInvalidate) {
+			return storage.Peer{}, false, nil
+		}
+		return storage.Peer{}, false, errors.Errorf("unmarshal: %w", err)
+	}
+	return p, true, nil
+}
+
+func (s PeerStorage) add(ctx context.Context, associated []string, value storage.Peer) (rerr error) {
+	if err := ctx.Err(); err != nil {
+		return err
 	}
 	id := storage.KeyFromPeer(value).Bytes(nil)
+	stored, ok, err := s.get(s.pebble, id)
+	if err != nil {
+		return err
+	}
+	event := storage.EventAdd
+	if ok {
+		value = storage.Merge(stored, value)
+		event = storage.EventUpdate
+	}
+
+	data, err := s.codec.Marshal(value)
+	if err != nil {
+		return errors.Errorf("marshal: %w", err)
+	}
 
 	b := s.pebble.NewBatch()
 	defer func() {
 		multierr.AppendInto(&rerr, b.Close())
 	}()
 
-	set := b.SetDeferred(len(id), len(data))
-	copy(set.Key, id)
+	set := b.SetDeferred(len(s.ns)+len(id), len(data))
+	copy(set.Key, s.ns)
+	copy(set.Key[len(s.ns):], id)
 	copy(set.Value, data)
 	_ = set.Finish()
 
 	for _, key := range associated {
-		deferred := b.SetDeferred(len(key), len(id))
-		copy(deferred.Key, key)
+		deferred := b.SetDeferred(len(s.ns)+len(key), len(id))
+		copy(deferred.Key, s.ns)
+		copy(deferred.Key[len(s.ns):], key)
 		copy(deferred.Value, id)
 		_ = deferred.Finish()
 	}
 
-	if err := b.Commit(nil); err != nil {
+	if ok {
+		// Remove keys of previous value, like old username.
+		for _, key := range storage.StaleKeys(stored, append(value.Keys(), associated...)) {
+			if err := s.deleteAssociated(s.pebble, b, []byte(key), id); err != nil {
+				return err
+			}
+		}
+		if err := s.deleteUsername(b, id, stored); err != nil {
+			return err
+		}
+	}
+	if err := s.touch(s.pebble, b, id, time.Now()); err != nil {
+		return err
+	}
+	if err := s.indexUsername(b, id, value); err != nil {
+		return err
+	}
+
+	// Do not commit if context is done while building batch.
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := b.Commit(s.writeOpts); err != nil {
 		return errors.Errorf("commit: %w", err)
 	}
+	s.watch.Publish(storage.Event{
+		Type: event,
+		Key:  storage.KeyFromPeer(value),
+		Peer: value,
+	})
 
 	return nil
 }
 
 // Add adds given peer to the storage.
 func (s PeerStorage) Add(ctx context.Context, value storage.Peer) (rerr error) {
-	return s.add(value.Keys(), value)
+	return s.add(ctx, value.Keys(), value)
 }
 
 // Find finds peer using given key.
 func (s PeerStorage) Find(ctx context.Context, key storage.PeerKey) (_ storage.Peer, rerr error) {
+	if err := ctx.Err(); err != nil {
+		return storage.Peer{}, err
+	}
 	id := key.Bytes(nil)
 
-	data, closer, err := s.pebble.Get(id)
+	data, closer, err := s.pebble.Get(s.key(id))
 	if err != nil {
 		if errors.Is(err, pebble.ErrNotFound) {
 			return storage.Peer{}, storage.ErrPeerNotFound
@@ -161,23 +317,30 @@ func (s PeerStorage) Find(ctx context.Context, key storage.PeerKey) (_ storage.P
 	}()
 
 	var b storage.Peer
-	if err := json.Unmarshal(data, &b); err != nil {
+	if err := s.codec.Unmarshal(data, &b); err != nil {
 		if errors.Is(err, storage.ErrPeerUnmarshalMustInvalidate) {
 			return storage.Peer{}, storage.ErrPeerNotFound
 		}
 		return storage.Peer{}, errors.Errorf("unmarshal: %w", err)
 	}
+	if b.Expired(time.Now()) {
+		return storage.Peer{}, storage.ErrPeerNotFound
+	}
 
 	return b, nil
 }
 
 // Assign adds given peer to the storage and associate it to the given key.
 func (s PeerStorage) Assign(ctx context.Context, key string, value storage.Peer) (rerr error) {
-	return s.add(append(value.Keys(), key), value)
+	return s.add(ctx, append(value.Keys(), key), value)
 }
 
 // Resolve finds peer using associated key.
 func (s PeerStorage) Resolve(ctx context.Context, key string) (_ storage.Peer, rerr error) {
+	if err := ctx.Err(); err != nil {
+		return storage.Peer{}, err
+	}
+
 	// Create database snapshot.
 	snap := s.pebble.NewSnapshot()
 	defer func() {
@@ -185,7 +348,7 @@ func (s PeerStorage) Resolve(ctx context.Context, key string) (_ storage.Peer, r
 	}()
 
 	// Find id by key.
-	id, idCloser, err := snap.Get([]byte(key))
+	id, idCloser, err := snap.Get(s.key([]byte(key)))
 	if err != nil {
 		if errors.Is(err, pebble.ErrNotFound) {
 			return storage.Peer{}, storage.ErrPeerNotFound
@@ -197,7 +360,10 @@ func (s PeerStorage) Resolve(ctx context.Context, key string) (_ storage.Peer, r
 	}()
 
 	// Find object by id.
-	data, dataCloser, err := snap.Get(id)
+	if err := ctx.Err(); err != nil {
+		return storage.Peer{}, err
+	}
+	data, dataCloser, err := snap.Get(s.key(id))
 	if err != nil {
 		if errors.Is(err, pebble.ErrNotFound) {
 			return storage.Peer{}, storage.ErrPeerNotFound
@@ -209,12 +375,15 @@ func (s PeerStorage) Resolve(ctx context.Context, key string) (_ storage.Peer, r
 	}()
 
 	var b storage.Peer
-	if err := json.Unmarshal(data, &b); err != nil {
+	if err := s.codec.Unmarshal(data, &b); err != nil {
 		if errors.Is(err, storage.ErrPeerUnmarshalMustInvalidate) {
 			return storage.Peer{}, storage.ErrPeerNotFound
 		}
 		return storage.Peer{}, errors.Errorf("unmarshal: %w", err)
 	}
+	if b.Expired(time.Now()) {
+		return storage.Peer{}, storage.ErrPeerNotFound
+	}
 
 	return b, nil
 }
@@ -0,0 +1,120 @@
+package pebble
+
+import (
+	"bytes"
+	"context"
+	"time"
+
+	"github.com/cockroachdb/pebble"
+	"github.com/go-faster/errors"
+	"go.uber.org/multierr"
+
+	"github.com/gotd/contrib/storage"
+)
+
+var _ storage.ExpiringPeerStorage = PeerStorage{}
+
+// AddTTL adds given peer to the storage. Peer expires after given ttl.
+//
+// Pebble has no native expiration, so expired peers are skipped on read
+// and removed by DeleteExpired.
+func (s PeerStorage) AddTTL(ctx context.Context, value storage.Peer, ttl time.Duration) error {
+	return s.add(ctx, value.Keys(), storage.WithTTL(value, time.Now(), ttl))
+}
+
+// DeleteExpired removes expired peers and their associated keys.
+// It returns count of removed peers.
+//
+// It is intended to be called periodically to bound storage growth.
+func (s PeerStorage) DeleteExpired(ctx context.Context) (_ int, rerr error) {
+	snap := s.pebble.NewSnapshot()
+	defer func() {
+		multierr.AppendInto(&rerr, snap.Close())
+	}()
+
+	iter, err := snap.NewIter(s.iterOptions(storage.PeerKeyPrefix))
+	if err != nil {
+		return 0, errors.Errorf("new iter: %w", err)
+	}
+	defer func() {
+		multierr.AppendInto(&rerr, iter.Close())
+	}()
+
+	b := s.pebble.NewBatch()
+	defer func() {
+		multierr.AppendInto(&rerr, b.Close())
+	}()
+
+	var (
+		now     = time.Now()
+		deleted []storage.PeerKey
+		value   storage.Peer
+	)
+	for iter.First(); iter.Valid(); iter.Next() {
+		if err := ctx.Err(); err != nil {
+			return 0, err
+		}
+		if err := s.codec.Unmarshal(iter.Value(), &value); err != nil {
+			if errors.Is(err, storage.ErrPeerUnmarshalMustInvalidate) {
+				continue
+			}
+			return 0, errors.Errorf("unmarshal: %w", err)
+		}
+		if !value.Expired(now) {
+			continue
+		}
+
+		id := iter.Key()[len(s.ns):]
+		if err := b.Delete(iter.Key(), nil); err != nil {
+			return 0, errors.Errorf("delete %q: %w", id, err)
+		}
+		for _, key := range value.Keys() {
+			if err := s.deleteAssociated(snap, b, []byte(key), id); err != nil {
+				return 0, err
+			}
+		}
+		if err := s.deleteRecent(snap, b, id); err != nil {
+			return 0, err
+		}
+		if err := s.deleteUsername(b, id, value); err != nil {
+			return 0, err
+		}
+		deleted = append(deleted, storage.KeyFromPeer(value))
+	}
+	if err := iter.Error(); err != nil {
+		return 0, errors.Errorf("iterate: %w", err)
+	}
+
+	if err := b.Commit(s.writeOpts); err != nil {
+		return 0, errors.Errorf("commit: %w", err)
+	}
+	for _, key := range deleted {
+		s.watch.Publish(storage.Event{Type: storage.EventDelete, Key: key})
+	}
+
+	return len(deleted), nil
+}
+
+// deleteAssociated deletes associated key if it still points to given id.
+func (s PeerStorage) deleteAssociated(r pebble.Reader, b *pebble.Batch, key, id []byte) error {
+	key = s.key(key)
+	v, closer, err := r.Get(key)
+	if err != nil {
+		if errors.Is(err, pebble.ErrNotFound) {
+			return nil
+		}
+		return errors.Errorf("get %q: %w", key, err)
+	}
+	match := bytes.Equal(v, id)
+	if err := closer.Close(); err != nil {
+		return errors.Errorf("close %q: %w", key, err)
+	}
+	if !match {
+		return nil
+	}
+
+	if err := b.Delete(key, nil); err != nil {
+		return errors.Errorf("delete %q: %w", key, err)
+	}
+	return nil
+}
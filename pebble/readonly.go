@@ -0,0 +1,20 @@
+package pebble
+
+import (
+	"github.com/cockroachdb/pebble"
+)
+
+// ReadOnlyOptions returns pebble options to open existing database in
+// read-only mode, e.g. to inspect stored peers by external tools.
+//
+// Writes to storages of read-only database fail, use storage.NewReadOnly
+// to reject them early. Pebble locks database directory even in
+// read-only mode, so database of running process cannot be opened; open
+// its Backup instead.
+func ReadOnlyOptions() *pebble.Options {
+	opts := &pebble.Options{
+		ReadOnly:         true,
+		ErrorIfNotExists: true,
+	}
+	return opts.EnsureDefaults()
+}
@@ -0,0 +1,10 @@
+// Package peercrypt implements encryption at rest of peer storage.
+//
+// Storage encrypts peers with XChaCha20-Poly1305 before writing them to
+// underlying storage, so backend stores only peer kind and id, creation
+// and expiration time in plain text. Access hash is stored only encrypted.
+// Associated keys, like usernames and phones, are stored as is unless
+// hashed with HMAC-SHA256, see Storage.WithHashedKeys.
+//
+// Keys could be rotated by passing old keys to New and calling Rewrap.
+package peercrypt
@@ -0,0 +1,86 @@
+package peercrypt
+
+import (
+	"context"
+
+	"github.com/go-faster/errors"
+	"go.uber.org/multierr"
+
+	"github.com/gotd/contrib/storage"
+)
+
+// Rewrap re-encrypts peers stored with old keys using the current key and
+// returns count of re-encrypted peers.
+//
+// If keys are hashed, keys associated with peer entity, like username and
+// phone, are re-assigned with hashes of the current key. Other assigned keys
+// remain resolvable only while old key is configured.
+//
+// Peers remain readable during Rewrap, so key could be rotated without
+// downtime.
+func (s *Storage) Rewrap(ctx context.Context) (_ int, rerr error) {
+	if len(s.keys) < 2 {
+		return 0, nil
+	}
+
+	iter, err := s.next.Iterate(ctx)
+	if err != nil {
+		return 0, errors.Wrap(err, "iterate")
+	}
+	// Collect peers first: underlying storage may not allow writes
+	// during iteration.
+	var toWrap []storage.PeerKey
+	func() {
+		defer func() {
+			multierr.AppendInto(&rerr, iter.Close())
+		}()
+		for iter.Next(ctx) {
+			stored := iter.Value()
+			_, idx, err := s.openKey(stored)
+			if err != nil {
+				if errors.Is(err, storage.ErrPeerNotFound) {
+					continue
+				}
+				rerr = errors.Wrapf(err, "open %s", storage.KeyFromPeer(stored))
+				return
+			}
+			if idx > 0 {
+				toWrap = append(toWrap, storage.KeyFromPeer(stored))
+			}
+		}
+		multierr.AppendInto(&rerr, iter.Err())
+	}()
+	if rerr != nil {
+		return 0, rerr
+	}
+
+	var n int
+	for _, key := range toWrap {
+		if err := ctx.Err(); err != nil {
+			return n, err
+		}
+		// Find again, peer may be updated since iteration.
+		stored, err := s.next.Find(ctx, key)
+		if err != nil {
+			if errors.Is(err, storage.ErrPeerNotFound) {
+				continue
+			}
+			return n, errors.Wrapf(err, "find %s", key)
+		}
+		p, idx, err := s.openKey(stored)
+		if err != nil {
+			if errors.Is(err, storage.ErrPeerNotFound) {
+				continue
+			}
+			return n, errors.Wrapf(err, "open %s", key)
+		}
+		if idx == 0 {
+			continue
+		}
+		if err := s.add(ctx, p.Keys(), p); err != nil {
+			return n, errors.Wrapf(err, "add %s", key)
+		}
+		n++
+	}
+	return n, nil
+}
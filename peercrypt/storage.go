@@ -0,0 +1,280 @@
+package peercrypt
+
+import (
+	"context"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+
+	"github.com/go-faster/errors"
+	"golang.org/x/crypto/chacha20poly1305"
+
+	"github.com/gotd/td/telegram/query/dialogs"
+
+	"github.com/gotd/contrib/storage"
+)
+
+// metadataKey is a metadata entry of stored peer with encrypted peer.
+const metadataKey = "peercrypt"
+
+var _ storage.PeerStorage = (*Storage)(nil)
+
+// Storage is a storage.PeerStorage decorator which encrypts peers.
+//
+// Peers stored in underlying storage without encryption are considered
+// not found.
+type Storage struct {
+	next storage.PeerStorage
+	// keys are encryption keys, first one is current.
+	keys []cryptoKey
+	hash bool
+}
+
+type cryptoKey struct {
+	aead   cipher.AEAD
+	macKey []byte
+}
+
+func newCryptoKey(key []byte) (cryptoKey, error) {
+	aead, err := chacha20poly1305.NewX(key)
+	if err != nil {
+		return cryptoKey{}, errors.Wrap(err, "create cipher")
+	}
+
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte("peercrypt associated keys"))
+
+	return cryptoKey{
+		aead:   aead,
+		macKey: mac.Sum(nil),
+	}, nil
+}
+
+// New creates new Storage using given 32-byte key.
+//
+// Old keys are used only to decrypt peers stored before key rotation,
+// use Rewrap to re-encrypt them with the current key.
+func New(next storage.PeerStorage, key []byte, old ...[]byte) (*Storage, error) {
+	s := &Storage{
+		next: next,
+	}
+	for i, k := range append([][]byte{key}, old...) {
+		c, err := newCryptoKey(k)
+		if err != nil {
+			return nil, errors.Wrapf(err, "key %d", i)
+		}
+		s.keys = append(s.keys, c)
+	}
+	return s, nil
+}
+
+// WithHashedKeys enables hashing of associated keys, so usernames and
+// phones are not stored in plain text. Resolve by hashed key is still
+// possible, but keys could not be listed.
+//
+// Enabling or disabling hashing makes previously assigned keys unresolvable.
+func (s *Storage) WithHashedKeys(hash bool) *Storage {
+	s.hash = hash
+	return s
+}
+
+func (s *Storage) key(key string) string {
+	return s.keyWith(s.keys[0], key)
+}
+
+func (s *Storage) keyWith(c cryptoKey, key string) string {
+	if !s.hash {
+		return key
+	}
+	mac := hmac.New(sha256.New, c.macKey)
+	mac.Write([]byte(key))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// seal returns peer to store in underlying storage.
+func (s *Storage) seal(value storage.Peer) (storage.Peer, error) {
+	data, err := value.MarshalJSON()
+	if err != nil {
+		return storage.Peer{}, errors.Wrap(err, "marshal")
+	}
+
+	aead := s.keys[0].aead
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(data)+aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return storage.Peer{}, errors.Wrap(err, "nonce")
+	}
+	// Bind ciphertext to peer key, so it could not be swapped.
+	ad := storage.KeyFromPeer(value).Bytes(nil)
+
+	return storage.Peer{
+		Version: value.Version,
+		// Access hash is stored only encrypted.
+		Key:       dialogs.DialogKey{Kind: value.Key.Kind, ID: value.Key.ID},
+		CreatedAt: value.CreatedAt,
+		ExpiresAt: value.ExpiresAt,
+		Metadata: map[string]any{
+			metadataKey: base64.RawStdEncoding.EncodeToString(aead.Seal(nonce, nonce, data, ad)),
+		},
+	}, nil
+}
+
+// open decrypts peer stored in underlying storage.
+func (s *Storage) open(stored storage.Peer) (storage.Peer, error) {
+	p, _, err := s.openKey(stored)
+	return p, err
+}
+
+// openKey decrypts peer stored in underlying storage and returns index of
+// key used for decryption.
+func (s *Storage) openKey(stored storage.Peer) (storage.Peer, int, error) {
+	raw, ok := stored.Metadata[metadataKey].(string)
+	if !ok {
+		return storage.Peer{}, 0, storage.ErrPeerNotFound
+	}
+	data, err := base64.RawStdEncoding.DecodeString(raw)
+	if err != nil {
+		return storage.Peer{}, 0, errors.Wrap(err, "decode")
+	}
+
+	ad := storage.KeyFromPeer(stored).Bytes(nil)
+	for i, c := range s.keys {
+		if len(data) < c.aead.NonceSize() {
+			return storage.Peer{}, 0, errors.New("ciphertext too short")
+		}
+		nonce, ciphertext := data[:c.aead.NonceSize()], data[c.aead.NonceSize():]
+		plaintext, err := c.aead.Open(nil, nonce, ciphertext, ad)
+		if err != nil {
+			continue
+		}
+
+		// Peer, including access hash, is taken from encrypted payload.
+		var p storage.Peer
+		if err := p.UnmarshalJSON(plaintext); err != nil {
+			if errors.Is(err, storage.ErrPeerUnmarshalMustInvalidate) {
+				return storage.Peer{}, 0, storage.ErrPeerNotFound
+			}
+			return storage.Peer{}, 0, errors.Wrap(err, "unmarshal")
+		}
+		return p, i, nil
+	}
+	return storage.Peer{}, 0, errors.New("decrypt: no matching key")
+}
+
+func (s *Storage) add(ctx context.Context, associated []string, value storage.Peer) error {
+	// Underlying storage could not merge encrypted peers.
+	stored, err := s.Find(ctx, storage.KeyFromPeer(value))
+	switch {
+	case err == nil:
+		value = storage.Merge(stored, value)
+	case !errors.Is(err, storage.ErrPeerNotFound):
+		return err
+	}
+
+	sealed, err := s.seal(value)
+	if err != nil {
+		return err
+	}
+	if len(associated) == 0 {
+		return s.next.Add(ctx, sealed)
+	}
+	for _, key := range associated {
+		if err := s.next.Assign(ctx, s.key(key), sealed); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Add adds given peer to the storage.
+func (s *Storage) Add(ctx context.Context, value storage.Peer) error {
+	return s.add(ctx, value.Keys(), value)
+}
+
+// Find finds peer using given key.
+func (s *Storage) Find(ctx context.Context, key storage.PeerKey) (storage.Peer, error) {
+	stored, err := s.next.Find(ctx, key)
+	if err != nil {
+		return storage.Peer{}, err
+	}
+	return s.open(stored)
+}
+
+// Assign adds given peer to the storage and associates it to the given key.
+func (s *Storage) Assign(ctx context.Context, key string, value storage.Peer) error {
+	return s.add(ctx, append(value.Keys(), key), value)
+}
+
+// Resolve finds peer using associated key.
+//
+// If keys are hashed, keys hashed with old keys are tried too.
+func (s *Storage) Resolve(ctx context.Context, key string) (storage.Peer, error) {
+	for i, c := range s.keys {
+		if i > 0 && !s.hash {
+			break
+		}
+		stored, err := s.next.Resolve(ctx, s.keyWith(c, key))
+		if err != nil {
+			if errors.Is(err, storage.ErrPeerNotFound) {
+				continue
+			}
+			return storage.Peer{}, err
+		}
+		return s.open(stored)
+	}
+	return storage.Peer{}, storage.ErrPeerNotFound
+}
+
+// Iterate creates and returns new PeerIterator.
+//
+// Peers stored without encryption are skipped.
+func (s *Storage) Iterate(ctx context.Context) (storage.PeerIterator, error) {
+	iter, err := s.next.Iterate(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &iterator{
+		iter:    iter,
+		storage: s,
+	}, nil
+}
+
+type iterator struct {
+	iter    storage.PeerIterator
+	storage *Storage
+	value   storage.Peer
+	err     error
+}
+
+func (i *iterator) Next(ctx context.Context) bool {
+	for i.iter.Next(ctx) {
+		p, err := i.storage.open(i.iter.Value())
+		if err != nil {
+			if errors.Is(err, storage.ErrPeerNotFound) {
+				continue
+			}
+			i.err = err
+			return false
+		}
+		i.value = p
+		return true
+	}
+	return false
+}
+
+func (i *iterator) Err() error {
+	if i.err != nil {
+		return i.err
+	}
+	return i.iter.Err()
+}
+
+func (i *iterator) Value() storage.Peer {
+	return i.value
+}
+
+func (i *iterator) Close() error {
+	return i.iter.Close()
+}
@@ -0,0 +1,132 @@
+package peercrypt
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	pebbledb "github.com/cockroachdb/pebble"
+	"github.com/cockroachdb/pebble/vfs"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gotd/td/tg"
+
+	"github.com/gotd/contrib/internal/tests"
+	"github.com/gotd/contrib/pebble"
+	"github.com/gotd/contrib/storage"
+)
+
+func newPebble(t *testing.T) *pebble.PeerStorage {
+	db, err := pebbledb.Open("pebble.db", &pebbledb.Options{
+		FS: vfs.NewMem(),
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = db.Close()
+	})
+	return pebble.NewPeerStorage(db)
+}
+
+func TestStorage(t *testing.T) {
+	key := bytes.Repeat([]byte{1}, 32)
+
+	for _, hash := range []bool{false, true} {
+		s, err := New(newPebble(t), key)
+		require.NoError(t, err)
+		tests.TestPeerStorage(t, s.WithHashedKeys(hash))
+	}
+}
+
+func TestStorage_AtRest(t *testing.T) {
+	a := require.New(t)
+	ctx := context.Background()
+
+	next := newPebble(t)
+	s, err := New(next, bytes.Repeat([]byte{1}, 32))
+	a.NoError(err)
+	s.WithHashedKeys(true)
+
+	var p storage.Peer
+	a.True(p.FromUser(&tg.User{
+		ID:         10,
+		AccessHash: 10,
+		Username:   "secret",
+		Phone:      "123456",
+		FirstName:  "Real Name",
+	}))
+	a.NoError(s.Add(ctx, p))
+
+	got, err := s.Resolve(ctx, "secret")
+	a.NoError(err)
+	a.Equal("Real Name", got.User.FirstName)
+
+	// Neither usernames, names nor access hash are stored in plain text.
+	_, err = next.Resolve(ctx, "secret")
+	a.ErrorIs(err, storage.ErrPeerNotFound)
+	stored, err := next.Find(ctx, storage.KeyFromPeer(p))
+	a.NoError(err)
+	a.Nil(stored.User)
+	a.Zero(stored.Key.AccessHash)
+	a.Equal(int64(10), got.Key.AccessHash)
+	data, err := stored.MarshalJSON()
+	a.NoError(err)
+	a.NotContains(string(data), "Real Name")
+
+	// Wrong key.
+	other, err := New(next, bytes.Repeat([]byte{2}, 32))
+	a.NoError(err)
+	_, err = other.Find(ctx, storage.KeyFromPeer(p))
+	a.Error(err)
+
+	_, err = New(next, []byte("short"))
+	a.Error(err)
+}
+
+func TestStorage_Rewrap(t *testing.T) {
+	a := require.New(t)
+	ctx := context.Background()
+
+	next := newPebble(t)
+	oldKey, newKey := bytes.Repeat([]byte{1}, 32), bytes.Repeat([]byte{2}, 32)
+	old, err := New(next, oldKey)
+	a.NoError(err)
+	old.WithHashedKeys(true)
+
+	var p storage.Peer
+	a.True(p.FromUser(&tg.User{
+		ID:         10,
+		AccessHash: 10,
+		Username:   "secret",
+		FirstName:  "Real Name",
+	}))
+	a.NoError(old.Add(ctx, p))
+
+	s, err := New(next, newKey, oldKey)
+	a.NoError(err)
+	s.WithHashedKeys(true)
+
+	// Peers stored with old key are readable.
+	got, err := s.Resolve(ctx, "secret")
+	a.NoError(err)
+	a.Equal("Real Name", got.User.FirstName)
+
+	n, err := s.Rewrap(ctx)
+	a.NoError(err)
+	a.Equal(1, n)
+	n, err = s.Rewrap(ctx)
+	a.NoError(err)
+	a.Zero(n)
+
+	// Old key is not needed anymore.
+	rotated, err := New(next, newKey)
+	a.NoError(err)
+	rotated.WithHashedKeys(true)
+	got, err = rotated.Resolve(ctx, "secret")
+	a.NoError(err)
+	a.Equal("Real Name", got.User.FirstName)
+	_, err = old.Find(ctx, storage.KeyFromPeer(p))
+	a.Error(err)
+
+	_, err = New(next, newKey, []byte("short"))
+	a.Error(err)
+}
@@ -0,0 +1,200 @@
+package peerhttp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/go-faster/errors"
+
+	"github.com/gotd/contrib/storage"
+)
+
+var _ storage.PeerStorage = (*Client)(nil)
+
+// Client is a storage.PeerStorage which uses storage served by Handler.
+type Client struct {
+	base    string
+	http    *http.Client
+	headers http.Header
+}
+
+// NewClient creates new Client using given base URL of Handler.
+func NewClient(baseURL string) *Client {
+	return &Client{
+		base:    strings.TrimSuffix(baseURL, "/"),
+		http:    http.DefaultClient,
+		headers: http.Header{},
+	}
+}
+
+// WithHeader sets header of every request, e.g. "Authorization" for
+// Handler using BearerToken.
+func (c *Client) WithHeader(key, value string) *Client {
+	c.headers.Set(key, value)
+	return c
+}
+
+// WithHTTPClient sets HTTP client to use. Default is http.DefaultClient.
+func (c *Client) WithHTTPClient(client *http.Client) *Client {
+	c.http = client
+	return c
+}
+
+func (c *Client) do(ctx context.Context, method, path string, body io.Reader) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, c.base+path, body)
+	if err != nil {
+		return nil, errors.Errorf("create request: %w", err)
+	}
+	for k, v := range c.headers {
+		req.Header[k] = v
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, errors.Errorf("%s %s: %w", method, path, err)
+	}
+	if resp.StatusCode < 300 {
+		return resp, nil
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	if resp.StatusCode == http.StatusNotFound && resp.Header.Get(errorHeader) == errNotFound {
+		return nil, storage.ErrPeerNotFound
+	}
+	msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	return nil, errors.Errorf("%s %s: %s: %s", method, path, resp.Status, bytes.TrimSpace(msg))
+}
+
+func (c *Client) send(ctx context.Context, method, path string, value storage.Peer) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return errors.Errorf("marshal: %w", err)
+	}
+	resp, err := c.do(ctx, method, path, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	return resp.Body.Close()
+}
+
+func (c *Client) get(ctx context.Context, path string) (storage.Peer, error) {
+	resp, err := c.do(ctx, http.MethodGet, path, nil)
+	if err != nil {
+		return storage.Peer{}, err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return storage.Peer{}, errors.Errorf("read body: %w", err)
+	}
+	var p storage.Peer
+	if err := json.Unmarshal(data, &p); err != nil {
+		if errors.Is(err, storage.ErrPeerUnmarshalMustInvalidate) {
+			return storage.Peer{}, storage.ErrPeerNotFound
+		}
+		return storage.Peer{}, errors.Errorf("unmarshal: %w", err)
+	}
+	return p, nil
+}
+
+// Add adds given peer to the storage.
+func (c *Client) Add(ctx context.Context, value storage.Peer) error {
+	return c.send(ctx, http.MethodPost, "/peers", value)
+}
+
+// Find finds peer using given key.
+func (c *Client) Find(ctx context.Context, key storage.PeerKey) (storage.Peer, error) {
+	return c.get(ctx, "/peers/"+key.String())
+}
+
+// Assign adds given peer to the storage and associates it to the given key.
+func (c *Client) Assign(ctx context.Context, key string, value storage.Peer) error {
+	return c.send(ctx, http.MethodPut, "/keys/"+url.PathEscape(key), value)
+}
+
+// Resolve finds peer using associated key.
+func (c *Client) Resolve(ctx context.Context, key string) (storage.Peer, error) {
+	return c.get(ctx, "/keys/"+url.PathEscape(key))
+}
+
+// Iterate creates and returns new PeerIterator.
+func (c *Client) Iterate(ctx context.Context) (storage.PeerIterator, error) {
+	resp, err := c.do(ctx, http.MethodGet, "/peers", nil)
+	if err != nil {
+		return nil, err
+	}
+	return &iterator{
+		body: resp.Body,
+		dec:  json.NewDecoder(resp.Body),
+	}, nil
+}
+
+type iterator struct {
+	body    io.ReadCloser
+	dec     *json.Decoder
+	value   storage.Peer
+	done    bool
+	lastErr error
+}
+
+func (i *iterator) Next(ctx context.Context) bool {
+	for {
+		if i.done {
+			return false
+		}
+		if !i.dec.More() {
+			i.done = true
+			i.lastErr = errors.New("stream is truncated")
+			return false
+		}
+
+		var raw json.RawMessage
+		if err := i.dec.Decode(&raw); err != nil {
+			i.lastErr = errors.Errorf("decode: %w", err)
+			return false
+		}
+		var end streamEnd
+		if err := json.Unmarshal(raw, &end); err == nil && end.End {
+			i.done = true
+			if end.Error != "" {
+				i.lastErr = errors.Errorf("iterate: %s", end.Error)
+			}
+			return false
+		}
+
+		i.value = storage.Peer{}
+		if err := json.Unmarshal(raw, &i.value); err != nil {
+			if errors.Is(err, storage.ErrPeerUnmarshalMustInvalidate) {
+				continue
+			}
+			i.lastErr = errors.Errorf("decode: %w", err)
+			return false
+		}
+		return true
+	}
+}
+
+func (i *iterator) Err() error {
+	return i.lastErr
+}
+
+func (i *iterator) Value() storage.Peer {
+	return i.value
+}
+
+func (i *iterator) Close() error {
+	return i.body.Close()
+}
@@ -0,0 +1,121 @@
+package peerhttp_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-faster/errors"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gotd/td/tg"
+
+	"github.com/gotd/contrib/peerhttp"
+	"github.com/gotd/contrib/storage"
+	"github.com/gotd/contrib/storage/storagetest"
+)
+
+// brokenStorage fails iteration after first peer.
+type brokenStorage struct {
+	*storagetest.Memory
+}
+
+func (s brokenStorage) Iterate(ctx context.Context) (storage.PeerIterator, error) {
+	iter, err := s.Memory.Iterate(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &brokenIterator{PeerIterator: iter}, nil
+}
+
+type brokenIterator struct {
+	storage.PeerIterator
+	n int
+}
+
+func (i *brokenIterator) Next(ctx context.Context) bool {
+	i.n++
+	return i.n == 1 && i.PeerIterator.Next(ctx)
+}
+
+func (i *brokenIterator) Err() error {
+	return errors.New("disk failure")
+}
+
+func TestClient(t *testing.T) {
+	a := require.New(t)
+	ctx := context.Background()
+
+	s := brokenStorage{Memory: storagetest.NewMemory()}
+	var p storage.Peer
+	a.True(p.FromUser(&tg.User{ID: 10, AccessHash: 10}))
+	a.NoError(s.Add(ctx, p))
+
+	mux := http.NewServeMux()
+	mux.Handle("/storage/", http.StripPrefix("/storage", peerhttp.NewHandler(s)))
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	// Unknown route is not an unknown peer.
+	_, err := peerhttp.NewClient(srv.URL+"/wrong").Find(ctx, storage.KeyFromPeer(p))
+	a.Error(err)
+	a.NotErrorIs(err, storage.ErrPeerNotFound)
+
+	c := peerhttp.NewClient(srv.URL + "/storage")
+	_, err = c.Find(ctx, storage.PeerKey{Kind: p.Key.Kind, ID: 20})
+	a.ErrorIs(err, storage.ErrPeerNotFound)
+
+	// Interrupted stream fails iterator.
+	iter, err := c.Iterate(ctx)
+	a.NoError(err)
+	defer func() {
+		_ = iter.Close()
+	}()
+	a.True(iter.Next(ctx))
+	a.Equal(p.Key, iter.Value().Key)
+	a.False(iter.Next(ctx))
+	a.ErrorContains(iter.Err(), "iteration failed")
+	a.NotContains(iter.Err().Error(), "disk failure", "details must not leak")
+}
+
+func TestClientAuth(t *testing.T) {
+	a := require.New(t)
+	ctx := context.Background()
+
+	h := peerhttp.NewHandler(storagetest.NewMemory()).WithAuth(peerhttp.BearerToken("secret"))
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+
+	var p storage.Peer
+	a.True(p.FromUser(&tg.User{ID: 10, AccessHash: 10}))
+
+	err := peerhttp.NewClient(srv.URL).Add(ctx, p)
+	a.ErrorContains(err, "401")
+	err = peerhttp.NewClient(srv.URL).WithHeader("Authorization", "Bearer wrong").Add(ctx, p)
+	a.ErrorContains(err, "401")
+
+	c := peerhttp.NewClient(srv.URL).WithHeader("Authorization", "Bearer secret")
+	a.NoError(c.Add(ctx, p))
+	_, err = c.Find(ctx, storage.KeyFromPeer(p))
+	a.NoError(err)
+}
+
+func TestClientTruncated(t *testing.T) {
+	a := require.New(t)
+	ctx := context.Background()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"Key":{"Kind":0,"ID":10,"AccessHash":10}}` + "\n"))
+	}))
+	defer srv.Close()
+
+	iter, err := peerhttp.NewClient(srv.URL).Iterate(ctx)
+	a.NoError(err)
+	defer func() {
+		_ = iter.Close()
+	}()
+	for iter.Next(ctx) {
+	}
+	a.ErrorContains(iter.Err(), "truncated")
+}
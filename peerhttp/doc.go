@@ -0,0 +1,20 @@
+// Package peerhttp implements sharing of a single peer storage over HTTP.
+//
+// One process serves its storage using Handler, and other processes use
+// Client as storage.PeerStorage, so many small bots share a warmed cache.
+//
+// API:
+//
+//	POST /peers          adds peer from request body
+//	GET  /peers          streams all peers as newline-delimited JSON
+//	GET  /peers/{key}    finds peer by storage.PeerKey string
+//	PUT  /keys/{key}     assigns peer from request body to key
+//	GET  /keys/{key}     resolves peer by associated key
+//
+// Unknown peers are reported with 404 status code and "X-Peer-Error:
+// not_found" header. Peer stream ends with {"$end":true} record, which
+// contains "$error" field, if iteration failed.
+//
+// NB: Handler serves access hashes and phone numbers and accepts writes,
+// protect it using Handler.WithAuth or network isolation.
+package peerhttp
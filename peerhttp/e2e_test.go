@@ -0,0 +1,27 @@
+package peerhttp_test
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	pebbledb "github.com/cockroachdb/pebble"
+	"github.com/cockroachdb/pebble/vfs"
+
+	"github.com/gotd/contrib/internal/tests"
+	"github.com/gotd/contrib/pebble"
+	"github.com/gotd/contrib/peerhttp"
+)
+
+func TestE2E(t *testing.T) {
+	db, err := pebbledb.Open("pebble.db", &pebbledb.Options{
+		FS: vfs.NewMem(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	srv := httptest.NewServer(peerhttp.NewHandler(pebble.NewPeerStorage(db)))
+	defer srv.Close()
+
+	tests.TestPeerStorage(t, peerhttp.NewClient(srv.URL))
+}
@@ -0,0 +1,212 @@
+package peerhttp
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"io"
+	"net/http"
+
+	"github.com/go-faster/errors"
+	"go.uber.org/zap"
+
+	"github.com/gotd/contrib/storage"
+)
+
+// maxBodySize is a maximum size of request body.
+const maxBodySize = 1 << 20
+
+// errorHeader is a response header with error code, so client can
+// distinguish unknown peer from unknown route.
+const errorHeader = "X-Peer-Error"
+
+// errNotFound is a value of errorHeader for unknown peers.
+const errNotFound = "not_found"
+
+// streamEnd is a last record of peer stream. Stream without it is
+// truncated.
+type streamEnd struct {
+	End bool `json:"$end"`
+	// Error is an error which interrupted stream, if any.
+	Error string `json:"$error,omitempty"`
+}
+
+// Handler is a http.Handler which serves peer storage.
+//
+// NB: storage contains access hashes and phone numbers, and Handler
+// allows writes. Handler accepts any request by default, so either set
+// WithAuth or serve it only on trusted network.
+//
+// Errors are logged, clients receive only generic error messages.
+type Handler struct {
+	storage storage.PeerStorage
+	log     *zap.Logger
+	auth    func(r *http.Request) error
+	mux     *http.ServeMux
+}
+
+// NewHandler creates new Handler.
+func NewHandler(s storage.PeerStorage) *Handler {
+	h := &Handler{
+		storage: s,
+		log:     zap.NewNop(),
+		auth:    func(r *http.Request) error { return nil },
+		mux:     http.NewServeMux(),
+	}
+	h.mux.HandleFunc("POST /peers", h.add)
+	h.mux.HandleFunc("GET /peers", h.iterate)
+	h.mux.HandleFunc("GET /peers/{key}", h.find)
+	h.mux.HandleFunc("PUT /keys/{key}", h.assign)
+	h.mux.HandleFunc("GET /keys/{key}", h.resolve)
+	return h
+}
+
+// WithLog sets logger of handler.
+func (h *Handler) WithLog(log *zap.Logger) *Handler {
+	h.log = log
+	return h
+}
+
+// WithAuth sets function which authorizes every request. Requests for
+// which it returns error are rejected with 401 status code.
+//
+// See BearerToken for simple shared secret authorization.
+func (h *Handler) WithAuth(f func(r *http.Request) error) *Handler {
+	h.auth = f
+	return h
+}
+
+// BearerToken returns WithAuth function which requires given token in
+// "Authorization: Bearer <token>" header, see Client.WithHeader.
+func BearerToken(token string) func(r *http.Request) error {
+	expected := []byte("Bearer " + token)
+	return func(r *http.Request) error {
+		got := []byte(r.Header.Get("Authorization"))
+		if subtle.ConstantTimeCompare(got, expected) != 1 {
+			return errors.New("invalid bearer token")
+		}
+		return nil
+	}
+}
+
+// ServeHTTP implements http.Handler.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if err := h.auth(r); err != nil {
+		h.log.Warn("Unauthorized request",
+			zap.String("remote_addr", r.RemoteAddr),
+			zap.Error(err),
+		)
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	h.mux.ServeHTTP(w, r)
+}
+
+func (h *Handler) fail(w http.ResponseWriter, err error) {
+	if errors.Is(err, storage.ErrPeerNotFound) {
+		w.Header().Set(errorHeader, errNotFound)
+		http.Error(w, "peer not found", http.StatusNotFound)
+		return
+	}
+	h.log.Warn("Storage request failed", zap.Error(err))
+	http.Error(w, "internal error", http.StatusInternalServerError)
+}
+
+func (h *Handler) badRequest(w http.ResponseWriter, err error) {
+	h.log.Debug("Bad request", zap.Error(err))
+	http.Error(w, "bad request", http.StatusBadRequest)
+}
+
+func (h *Handler) writePeer(w http.ResponseWriter, p storage.Peer) {
+	data, err := json.Marshal(p)
+	if err != nil {
+		h.fail(w, errors.Errorf("marshal: %w", err))
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(data)
+}
+
+func readPeer(r *http.Request) (storage.Peer, error) {
+	data, err := io.ReadAll(io.LimitReader(r.Body, maxBodySize))
+	if err != nil {
+		return storage.Peer{}, errors.Errorf("read body: %w", err)
+	}
+	var p storage.Peer
+	if err := json.Unmarshal(data, &p); err != nil {
+		return storage.Peer{}, errors.Errorf("unmarshal: %w", err)
+	}
+	return p, nil
+}
+
+func (h *Handler) add(w http.ResponseWriter, r *http.Request) {
+	p, err := readPeer(r)
+	if err != nil {
+		h.badRequest(w, err)
+		return
+	}
+	if err := h.storage.Add(r.Context(), p); err != nil {
+		h.fail(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) find(w http.ResponseWriter, r *http.Request) {
+	var key storage.PeerKey
+	if err := key.Parse([]byte(r.PathValue("key"))); err != nil {
+		h.badRequest(w, err)
+		return
+	}
+	p, err := h.storage.Find(r.Context(), key)
+	if err != nil {
+		h.fail(w, err)
+		return
+	}
+	h.writePeer(w, p)
+}
+
+func (h *Handler) assign(w http.ResponseWriter, r *http.Request) {
+	p, err := readPeer(r)
+	if err != nil {
+		h.badRequest(w, err)
+		return
+	}
+	if err := h.storage.Assign(r.Context(), r.PathValue("key"), p); err != nil {
+		h.fail(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) resolve(w http.ResponseWriter, r *http.Request) {
+	p, err := h.storage.Resolve(r.Context(), r.PathValue("key"))
+	if err != nil {
+		h.fail(w, err)
+		return
+	}
+	h.writePeer(w, p)
+}
+
+func (h *Handler) iterate(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	iter, err := h.storage.Iterate(ctx)
+	if err != nil {
+		h.fail(w, err)
+		return
+	}
+	defer func() {
+		_ = iter.Close()
+	}()
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	e := json.NewEncoder(w)
+	end := streamEnd{End: true}
+	if err := storage.ForEach(ctx, iter, func(p storage.Peer) error {
+		return e.Encode(p)
+	}); err != nil {
+		// Headers are already sent, so error is reported in band.
+		h.log.Warn("Iterate failed", zap.Error(err))
+		end.Error = "iteration failed"
+	}
+	_ = e.Encode(end)
+}
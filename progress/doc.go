@@ -0,0 +1,2 @@
+// Package progress implements live-updating status messages.
+package progress
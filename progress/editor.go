@@ -0,0 +1,185 @@
+package progress
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/go-faster/errors"
+
+	"github.com/gotd/td/clock"
+	"github.com/gotd/td/tg"
+	"github.com/gotd/td/tgerr"
+)
+
+// Editor coalesces rapid successive edits of a single message into at most
+// one edit per interval.
+//
+// Edits that do not change text are skipped, flood wait errors postpone next
+// edit instead of failing.
+type Editor struct {
+	api   *tg.Client
+	peer  tg.InputPeerClass
+	msgID int
+
+	clock    clock.Clock
+	interval time.Duration
+
+	mux     sync.Mutex
+	pending string
+	sent    string
+	notify  chan struct{}
+}
+
+// NewEditor creates new Editor of message with given ID.
+func NewEditor(api *tg.Client, peer tg.InputPeerClass, msgID int) *Editor {
+	return &Editor{
+		api:      api,
+		peer:     peer,
+		msgID:    msgID,
+		clock:    clock.System,
+		interval: 3 * time.Second,
+		notify:   make(chan struct{}, 1),
+	}
+}
+
+// WithClock sets clock to use. Default is to use system clock.
+func (e *Editor) WithClock(c clock.Clock) *Editor {
+	e.clock = c
+	return e
+}
+
+// WithInterval sets minimum interval between edits. Default is 3 seconds.
+func (e *Editor) WithInterval(interval time.Duration) *Editor {
+	e.interval = interval
+	return e
+}
+
+// WithText sets current text of message, so the same text is not sent again.
+func (e *Editor) WithText(text string) *Editor {
+	e.pending = text
+	e.sent = text
+	return e
+}
+
+// Set sets new text of message. It does not block, text is sent by Run.
+//
+// Only the latest text is sent if Set is called multiple times per interval.
+func (e *Editor) Set(text string) {
+	e.mux.Lock()
+	e.pending = text
+	e.mux.Unlock()
+
+	e.wake()
+}
+
+func (e *Editor) wake() {
+	select {
+	case e.notify <- struct{}{}:
+	default:
+	}
+}
+
+// take returns pending text if it differs from sent one.
+func (e *Editor) take() (string, bool) {
+	e.mux.Lock()
+	defer e.mux.Unlock()
+
+	return e.pending, e.pending != e.sent
+}
+
+func (e *Editor) markSent(text string) {
+	e.mux.Lock()
+	e.sent = text
+	e.mux.Unlock()
+}
+
+// edit sends text. It returns flood wait duration, if any.
+func (e *Editor) edit(ctx context.Context, text string) (time.Duration, error) {
+	if _, err := e.api.MessagesEditMessage(ctx, &tg.MessagesEditMessageRequest{
+		Peer:    e.peer,
+		ID:      e.msgID,
+		Message: text,
+	}); err != nil {
+		if d, ok := tgerr.AsFloodWait(err); ok {
+			if d == 0 {
+				d = time.Second
+			}
+			return d, nil
+		}
+		if !tgerr.Is(err, "MESSAGE_NOT_MODIFIED") {
+			return 0, errors.Errorf("edit: %w", err)
+		}
+	}
+	e.markSent(text)
+	return 0, nil
+}
+
+// Run sends pending edits until given context is done.
+func (e *Editor) Run(ctx context.Context) error {
+	var next time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-e.notify:
+		}
+
+		if wait := next.Sub(e.clock.Now()); wait > 0 {
+			if err := e.sleep(ctx, wait); err != nil {
+				return err
+			}
+		}
+
+		text, ok := e.take()
+		if !ok {
+			continue
+		}
+		flood, err := e.edit(ctx, text)
+		if err != nil {
+			return err
+		}
+		if flood > 0 {
+			// Retry after flood wait.
+			next = e.clock.Now().Add(flood)
+			e.wake()
+			continue
+		}
+		next = e.clock.Now().Add(e.interval)
+	}
+}
+
+// Flush sends pending text immediately, ignoring interval, and waits on
+// flood wait errors.
+//
+// It should be used to send final state of message after Run is done.
+func (e *Editor) Flush(ctx context.Context) error {
+	for {
+		text, ok := e.take()
+		if !ok {
+			return nil
+		}
+		flood, err := e.edit(ctx, text)
+		if err != nil {
+			return err
+		}
+		if flood == 0 {
+			return nil
+		}
+		if err := e.sleep(ctx, flood); err != nil {
+			return err
+		}
+	}
+}
+
+func (e *Editor) sleep(ctx context.Context, d time.Duration) error {
+	t := e.clock.Timer(d)
+	defer clock.StopTimer(t)
+
+	select {
+	case <-t.C():
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,97 @@
+package progress
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/gotd/neo"
+	"github.com/gotd/td/bin"
+	"github.com/gotd/td/tg"
+	"github.com/gotd/td/tgerr"
+	"github.com/gotd/td/tgmock"
+)
+
+func newTestEditor(t *testing.T, edits chan string, errs map[string][]error) *Editor {
+	api := tg.NewClient(tgmock.Invoker(func(request bin.Encoder) (bin.Encoder, error) {
+		req, ok := request.(*tg.MessagesEditMessageRequest)
+		require.True(t, ok)
+		edits <- req.Message
+
+		if e := errs[req.Message]; len(e) > 0 {
+			errs[req.Message] = e[1:]
+			return nil, e[0]
+		}
+		return &tg.Updates{}, nil
+	}))
+	return NewEditor(api, &tg.InputPeerSelf{}, 1)
+}
+
+func TestEditor(t *testing.T) {
+	a := require.New(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	clock := neo.NewTime(time.Now())
+	edits := make(chan string, 10)
+	e := newTestEditor(t, edits, map[string][]error{
+		"4": {tgerr.New(420, "FLOOD_WAIT_5")},
+	}).WithClock(clock).WithInterval(time.Second)
+
+	e.Set("1")
+	e.Set("2")
+	done := make(chan error, 1)
+	go func() {
+		done <- e.Run(ctx)
+	}()
+	a.Equal("2", <-edits, "edits must be coalesced")
+
+	observe := clock.Observe()
+	e.Set("3")
+	<-observe
+	clock.Travel(time.Second)
+	a.Equal("3", <-edits)
+
+	observe = clock.Observe()
+	e.Set("4")
+	<-observe
+	observe = clock.Observe()
+	clock.Travel(time.Second)
+	a.Equal("4", <-edits)
+
+	// Flood wait.
+	<-observe
+	clock.Travel(5 * time.Second)
+	a.Equal("4", <-edits)
+
+	cancel()
+	a.ErrorIs(<-done, context.Canceled)
+	a.Empty(edits)
+}
+
+func TestEditor_Flush(t *testing.T) {
+	a := require.New(t)
+	ctx := context.Background()
+
+	edits := make(chan string, 10)
+	e := newTestEditor(t, edits, map[string][]error{
+		"same": {tgerr.New(400, "MESSAGE_NOT_MODIFIED")},
+		"fail": {tgerr.New(400, "MESSAGE_ID_INVALID")},
+	}).WithText("done")
+
+	// Text is not changed.
+	a.NoError(e.Flush(ctx))
+	a.Empty(edits)
+
+	e.Set("fail")
+	a.Error(e.Flush(ctx))
+	a.Equal("fail", <-edits)
+
+	e.Set("same")
+	a.NoError(e.Flush(ctx))
+	a.Equal("same", <-edits)
+	a.NoError(e.Flush(ctx))
+	a.Empty(edits)
+}
@@ -0,0 +1,200 @@
+// Package promtg provides Prometheus instrumentation for gotd without
+// OpenTelemetry dependency.
+package promtg
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/gotd/td/bin"
+	"github.com/gotd/td/telegram"
+	"github.com/gotd/td/tg"
+	"github.com/gotd/td/tgerr"
+)
+
+const (
+	labelMethod  = "tg_method"
+	labelErrType = "tg_err_type"
+	labelErrCode = "tg_err_code"
+	labelUpdate  = "tg_update"
+	labelGap     = "tg_gap"
+)
+
+var _ prometheus.Collector = (*Metrics)(nil)
+
+// Metrics is a prometheus.Collector of RPC and updates metrics.
+//
+// Use Handle as telegram.Middleware and UpdateHandler to wrap handler of
+// updates.
+type Metrics struct {
+	rpcCount       *prometheus.CounterVec
+	rpcFailures    *prometheus.CounterVec
+	rpcDuration    *prometheus.HistogramVec
+	floodWait      *prometheus.HistogramVec
+	updates        *prometheus.CounterVec
+	updateFailures prometheus.Counter
+	updateDuration prometheus.Histogram
+	updateInFlight prometheus.Gauge
+	gaps           *prometheus.CounterVec
+}
+
+// New initializes and returns new Metrics.
+func New() *Metrics {
+	return &Metrics{
+		rpcCount: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "tg_rpc_count_total",
+			Help: "Telegram RPC calls total count.",
+		}, []string{labelMethod}),
+		rpcFailures: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "tg_rpc_failures_total",
+			Help: "Telegram failed RPC calls total count.",
+		}, []string{labelMethod, labelErrCode, labelErrType}),
+		rpcDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name: "tg_rpc_duration_seconds",
+			Help: "Telegram RPC calls duration histogram.",
+		}, []string{labelMethod}),
+		floodWait: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "tg_rpc_flood_wait_seconds",
+			Help:    "Telegram flood wait duration histogram.",
+			Buckets: []float64{1, 5, 10, 30, 60, 300, 900, 3600},
+		}, []string{labelMethod}),
+		updates: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "tg_updates_received_total",
+			Help: "Telegram updates total count by type.",
+		}, []string{labelUpdate}),
+		updateFailures: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "tg_updates_failures_total",
+			Help: "Telegram updates handler failures total count.",
+		}),
+		updateDuration: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Name: "tg_updates_duration_seconds",
+			Help: "Telegram updates handler duration histogram.",
+		}),
+		updateInFlight: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "tg_updates_in_flight",
+			Help: "Telegram updates being handled.",
+		}),
+		gaps: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "tg_updates_gaps_total",
+			Help: "Telegram updates gaps total count by kind.",
+		}, []string{labelGap}),
+	}
+}
+
+func (m *Metrics) collectors() []prometheus.Collector {
+	return []prometheus.Collector{
+		m.rpcCount,
+		m.rpcFailures,
+		m.rpcDuration,
+		m.floodWait,
+		m.updates,
+		m.updateFailures,
+		m.updateDuration,
+		m.updateInFlight,
+		m.gaps,
+	}
+}
+
+// Describe implements prometheus.Collector.
+func (m *Metrics) Describe(ch chan<- *prometheus.Desc) {
+	for _, c := range m.collectors() {
+		c.Describe(ch)
+	}
+}
+
+// Collect implements prometheus.Collector.
+func (m *Metrics) Collect(ch chan<- prometheus.Metric) {
+	for _, c := range m.collectors() {
+		c.Collect(ch)
+	}
+}
+
+func methodOf(input bin.Encoder) string {
+	obj, ok := input.(interface{ TypeName() string })
+	if !ok {
+		return ""
+	}
+	return obj.TypeName()
+}
+
+// Handle implements telegram.Middleware.
+func (m *Metrics) Handle(next tg.Invoker) telegram.InvokeFunc {
+	return func(ctx context.Context, input bin.Encoder, output bin.Decoder) error {
+		method := methodOf(input)
+		m.rpcCount.WithLabelValues(method).Inc()
+		start := time.Now()
+
+		err := next.Invoke(ctx, input, output)
+
+		m.rpcDuration.WithLabelValues(method).Observe(time.Since(start).Seconds())
+		if err == nil {
+			return nil
+		}
+		if rpcErr, ok := tgerr.As(err); ok {
+			m.rpcFailures.WithLabelValues(method, strconv.Itoa(rpcErr.Code), rpcErr.Type).Inc()
+			if d, ok := tgerr.AsFloodWait(err); ok {
+				m.floodWait.WithLabelValues(method).Observe(d.Seconds())
+			}
+		} else {
+			m.rpcFailures.WithLabelValues(method, "", "CLIENT").Inc()
+		}
+		return err
+	}
+}
+
+// updateTypes returns TL names of updates in container.
+func updateTypes(u tg.UpdatesClass) []string {
+	var updates []tg.UpdateClass
+	switch u := u.(type) {
+	case *tg.Updates:
+		updates = u.Updates
+	case *tg.UpdatesCombined:
+		updates = u.Updates
+	case *tg.UpdateShort:
+		updates = []tg.UpdateClass{u.Update}
+	case *tg.UpdatesTooLong:
+		return nil
+	default:
+		// Short messages.
+		return []string{u.TypeName()}
+	}
+
+	types := make([]string, 0, len(updates))
+	for _, update := range updates {
+		types = append(types, update.TypeName())
+	}
+	return types
+}
+
+// UpdateHandler returns telegram.UpdateHandler which instruments next one.
+func (m *Metrics) UpdateHandler(next telegram.UpdateHandler) telegram.UpdateHandler {
+	return telegram.UpdateHandlerFunc(func(ctx context.Context, u tg.UpdatesClass) error {
+		if _, ok := u.(*tg.UpdatesTooLong); ok {
+			m.gaps.WithLabelValues("too_long").Inc()
+		}
+		for _, typ := range updateTypes(u) {
+			m.updates.WithLabelValues(typ).Inc()
+		}
+
+		m.updateInFlight.Inc()
+		defer m.updateInFlight.Dec()
+		start := time.Now()
+
+		err := next.Handle(ctx, u)
+
+		m.updateDuration.Observe(time.Since(start).Seconds())
+		if err != nil {
+			m.updateFailures.Inc()
+		}
+		return err
+	})
+}
+
+// OnChannelTooLong records gap of channel which is too long to recover
+// by difference. It can be used as updates.Config.OnChannelTooLong.
+func (m *Metrics) OnChannelTooLong(channelID int64) {
+	m.gaps.WithLabelValues("channel_too_long").Inc()
+}
@@ -0,0 +1,73 @@
+package promtg
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-faster/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gotd/td/bin"
+	"github.com/gotd/td/telegram"
+	"github.com/gotd/td/tg"
+	"github.com/gotd/td/tgerr"
+)
+
+func gather(t *testing.T, r *prometheus.Registry) map[string]*dto.MetricFamily {
+	t.Helper()
+	families, err := r.Gather()
+	require.NoError(t, err)
+	m := make(map[string]*dto.MetricFamily, len(families))
+	for _, f := range families {
+		m[f.GetName()] = f
+	}
+	return m
+}
+
+func TestMetrics(t *testing.T) {
+	a := require.New(t)
+	ctx := context.Background()
+	m := New()
+	r := prometheus.NewPedanticRegistry()
+	a.NoError(r.Register(m))
+
+	invoker := m.Handle(telegram.InvokeFunc(func(ctx context.Context, input bin.Encoder, output bin.Decoder) error {
+		if _, ok := input.(*tg.MessagesSendMessageRequest); ok {
+			return tgerr.New(420, "FLOOD_WAIT_10")
+		}
+		return nil
+	}))
+	a.NoError(invoker.Invoke(ctx, &tg.HelpGetConfigRequest{}, nil))
+	a.Error(invoker.Invoke(ctx, &tg.MessagesSendMessageRequest{}, nil))
+
+	testErr := errors.New("test")
+	h := m.UpdateHandler(telegram.UpdateHandlerFunc(func(ctx context.Context, u tg.UpdatesClass) error {
+		if _, ok := u.(*tg.UpdatesTooLong); ok {
+			return testErr
+		}
+		return nil
+	}))
+	a.NoError(h.Handle(ctx, &tg.Updates{
+		Updates: []tg.UpdateClass{&tg.UpdateNewMessage{}, &tg.UpdateNewMessage{}},
+	}))
+	a.ErrorIs(h.Handle(ctx, &tg.UpdatesTooLong{}), testErr)
+	m.OnChannelTooLong(10)
+
+	families := gather(t, r)
+	a.Len(families["tg_rpc_count_total"].Metric, 2)
+	failures := families["tg_rpc_failures_total"].Metric
+	a.Len(failures, 1)
+	a.Equal(1.0, failures[0].GetCounter().GetValue())
+	flood := families["tg_rpc_flood_wait_seconds"].Metric
+	a.Len(flood, 1)
+	a.Equal(10.0, flood[0].GetHistogram().GetSampleSum())
+
+	updates := families["tg_updates_received_total"].Metric
+	a.Len(updates, 1)
+	a.Equal(2.0, updates[0].GetCounter().GetValue())
+	a.Equal(1.0, families["tg_updates_failures_total"].Metric[0].GetCounter().GetValue())
+	a.Len(families["tg_updates_gaps_total"].Metric, 2)
+	a.Equal(0.0, families["tg_updates_in_flight"].Metric[0].GetGauge().GetValue())
+}
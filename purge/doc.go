@@ -0,0 +1,2 @@
+// Package purge implements removal of user data stored by contrib subsystems.
+package purge
@@ -0,0 +1,104 @@
+package purge
+
+import (
+	"context"
+
+	"github.com/go-faster/errors"
+
+	"github.com/gotd/td/telegram/query/dialogs"
+
+	"github.com/gotd/contrib/storage"
+)
+
+// Subsystem is a data store which is able to remove data related to user.
+type Subsystem interface {
+	// Name returns subsystem name used in Report.
+	Name() string
+	// PurgeUser removes data related to given user and returns count of removed records.
+	PurgeUser(ctx context.Context, userID int64) (int, error)
+}
+
+// Report describes data removed by Purge.
+type Report struct {
+	UserID int64
+	// Removed is a count of removed records per subsystem name.
+	Removed map[string]int
+}
+
+// Total returns total count of removed records.
+func (r Report) Total() (total int) {
+	for _, v := range r.Removed {
+		total += v
+	}
+	return total
+}
+
+// Purge removes data related to given user from all given subsystems.
+//
+// Purge stops on first error and returns report of data removed so far.
+func Purge(ctx context.Context, userID int64, subsystems ...Subsystem) (Report, error) {
+	r := Report{
+		UserID:  userID,
+		Removed: make(map[string]int, len(subsystems)),
+	}
+	for _, s := range subsystems {
+		removed, err := s.PurgeUser(ctx, userID)
+		if err != nil {
+			return r, errors.Errorf("purge %s: %w", s.Name(), err)
+		}
+		r.Removed[s.Name()] += removed
+	}
+	return r, nil
+}
+
+type funcSubsystem struct {
+	name string
+	f    func(ctx context.Context, userID int64) (int, error)
+}
+
+func (f funcSubsystem) Name() string {
+	return f.name
+}
+
+func (f funcSubsystem) PurgeUser(ctx context.Context, userID int64) (int, error) {
+	return f.f(ctx, userID)
+}
+
+// Func creates new Subsystem from given function.
+func Func(name string, f func(ctx context.Context, userID int64) (int, error)) Subsystem {
+	return funcSubsystem{name: name, f: f}
+}
+
+// Peers creates new Subsystem which removes user peer record and its associated
+// keys from given peer storage.
+//
+// If storage implements storage.PeerGarbageCollector, all associated keys
+// which point to missing peers are removed after the record, including
+// keys added by Assign, e.g. storage.PhoneKey or ResolverCache domains.
+// Otherwise only keys returned by Peer.Keys are removed.
+func Peers(name string, s storage.PeerDeleter) Subsystem {
+	return Func(name, func(ctx context.Context, userID int64) (int, error) {
+		var removed int
+		switch err := s.Delete(ctx, storage.PeerKey{
+			Kind: dialogs.User,
+			ID:   userID,
+		}); {
+		case err == nil:
+			removed++
+		case errors.Is(err, storage.ErrPeerNotFound):
+			// Keys may be left by previous attempt.
+		default:
+			return 0, err
+		}
+
+		gc, ok := s.(storage.PeerGarbageCollector)
+		if !ok {
+			return removed, nil
+		}
+		keys, err := gc.CollectGarbage(ctx, storage.GCOptions{})
+		if err != nil {
+			return removed, errors.Errorf("collect keys: %w", err)
+		}
+		return removed + keys, nil
+	})
+}
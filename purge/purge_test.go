@@ -0,0 +1,86 @@
+package purge
+
+import (
+	"context"
+	"testing"
+
+	pebbledb "github.com/cockroachdb/pebble"
+	"github.com/cockroachdb/pebble/vfs"
+	"github.com/go-faster/errors"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gotd/td/tg"
+
+	"github.com/gotd/contrib/pebble"
+	"github.com/gotd/contrib/storage"
+)
+
+type deleter map[storage.PeerKey]struct{}
+
+func (d deleter) Delete(ctx context.Context, key storage.PeerKey) error {
+	if _, ok := d[key]; !ok {
+		return storage.ErrPeerNotFound
+	}
+	delete(d, key)
+	return nil
+}
+
+func TestPurge(t *testing.T) {
+	a := require.New(t)
+	ctx := context.Background()
+
+	peers := deleter{
+		{ID: 10}: {},
+		{ID: 11}: {},
+	}
+	state := Func("state", func(ctx context.Context, userID int64) (int, error) {
+		return 2, nil
+	})
+
+	r, err := Purge(ctx, 10, Peers("peers", peers), state)
+	a.NoError(err)
+	a.Equal(int64(10), r.UserID)
+	a.Equal(map[string]int{"peers": 1, "state": 2}, r.Removed)
+	a.Equal(3, r.Total())
+	a.Len(peers, 1)
+
+	// Already removed.
+	r, err = Purge(ctx, 10, Peers("peers", peers))
+	a.NoError(err)
+	a.Zero(r.Total())
+
+	testErr := errors.New("test")
+	r, err = Purge(ctx, 11, Peers("peers", peers), Func("failing", func(ctx context.Context, userID int64) (int, error) {
+		return 0, testErr
+	}))
+	a.ErrorIs(err, testErr)
+	a.Equal(1, r.Removed["peers"])
+}
+
+func TestPeersAssociated(t *testing.T) {
+	a := require.New(t)
+	ctx := context.Background()
+
+	db, err := pebbledb.Open("pebble.db", &pebbledb.Options{FS: vfs.NewMem()})
+	a.NoError(err)
+	defer func() {
+		a.NoError(db.Close())
+	}()
+	s := pebble.NewPeerStorage(db)
+
+	var p storage.Peer
+	a.True(p.FromUser(&tg.User{ID: 10, AccessHash: 10, Username: "user", Phone: "123"}))
+	a.NoError(s.Add(ctx, p))
+	phone := storage.PhoneKey("+1 23")
+	a.NoError(s.Assign(ctx, phone, p))
+	a.NoError(s.Assign(ctx, "domain", p))
+
+	r, err := Purge(ctx, 10, Peers("peers", s))
+	a.NoError(err)
+	a.Equal(3, r.Removed["peers"], "record, phone and domain keys")
+
+	for _, key := range []string{"user", "123", phone, "domain"} {
+		_, err := s.Resolve(ctx, key)
+		a.ErrorIs(err, storage.ErrPeerNotFound, key)
+	}
+}
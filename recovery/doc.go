@@ -0,0 +1,3 @@
+// Package recovery implements update handler middleware which recovers
+// panics in handlers.
+package recovery
@@ -0,0 +1,49 @@
+package recovery
+
+import (
+	"context"
+	"fmt"
+
+	"go.uber.org/zap"
+
+	"github.com/gotd/td/telegram/message"
+	"github.com/gotd/td/tg"
+)
+
+// maxStackSize is a maximum size of stack trace sent to chat.
+const maxStackSize = 2048
+
+// MessageSink is a Sink which notifies admin chat about panics.
+type MessageSink struct {
+	sender *message.Sender
+	peer   tg.InputPeerClass
+	log    *zap.Logger
+}
+
+// NewMessageSink creates new MessageSink which sends messages to given peer.
+func NewMessageSink(api *tg.Client, peer tg.InputPeerClass) *MessageSink {
+	return &MessageSink{
+		sender: message.NewSender(api),
+		peer:   peer,
+		log:    zap.NewNop(),
+	}
+}
+
+// WithLog sets logger to log send errors.
+func (m *MessageSink) WithLog(log *zap.Logger) *MessageSink {
+	m.log = log
+	return m
+}
+
+// Report implements Sink.
+func (m *MessageSink) Report(ctx context.Context, p *Panic) {
+	stack := p.Stack
+	if len(stack) > maxStackSize {
+		stack = stack[:maxStackSize]
+	}
+	text := fmt.Sprintf("Update handler panic: %v\n\n%s", p.Value, stack)
+
+	if _, err := m.sender.To(m.peer).Text(ctx, text); err != nil {
+		m.log.Warn("Send panic notification", zap.Error(err))
+	}
+}
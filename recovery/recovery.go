@@ -0,0 +1,124 @@
+package recovery
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+
+	"github.com/gotd/td/telegram"
+	"github.com/gotd/td/tg"
+)
+
+// Panic is a recovered panic of update handler.
+type Panic struct {
+	// Value is a value passed to panic.
+	Value any
+	// Stack is a stack trace of panicked goroutine.
+	Stack []byte
+	// Update is an update which was handled.
+	Update tg.UpdatesClass
+}
+
+// Error implements error.
+func (p *Panic) Error() string {
+	return fmt.Sprintf("handler panic: %v", p.Value)
+}
+
+// Unwrap returns panic value if it is an error.
+func (p *Panic) Unwrap() error {
+	err, _ := p.Value.(error)
+	return err
+}
+
+// Sink receives recovered panics, e.g. error reporting service.
+type Sink interface {
+	Report(ctx context.Context, p *Panic)
+}
+
+// SinkFunc is a functional Sink.
+type SinkFunc func(ctx context.Context, p *Panic)
+
+// Report implements Sink.
+func (f SinkFunc) Report(ctx context.Context, p *Panic) {
+	f(ctx, p)
+}
+
+// Handler is a telegram.UpdateHandler middleware which recovers panics of
+// next handler and returns them as *Panic error.
+type Handler struct {
+	next    telegram.UpdateHandler
+	log     *zap.Logger
+	counter prometheus.Counter
+	sinks   []Sink
+}
+
+// New creates new Handler.
+func New(next telegram.UpdateHandler) *Handler {
+	return &Handler{
+		next: next,
+		log:  zap.NewNop(),
+	}
+}
+
+// WithLog sets logger to log panics with stack traces.
+func (h *Handler) WithLog(log *zap.Logger) *Handler {
+	h.log = log
+	return h
+}
+
+// WithCounter sets counter which is incremented on every panic.
+func (h *Handler) WithCounter(c prometheus.Counter) *Handler {
+	h.counter = c
+	return h
+}
+
+// WithSink adds sink to report panics to.
+func (h *Handler) WithSink(s Sink) *Handler {
+	h.sinks = append(h.sinks, s)
+	return h
+}
+
+func (h *Handler) recovered(ctx context.Context, u tg.UpdatesClass, v any) *Panic {
+	p := &Panic{
+		Value:  v,
+		Stack:  debug.Stack(),
+		Update: u,
+	}
+
+	h.log.Error("Update handler panic",
+		zap.Any("panic", v),
+		zap.ByteString("stack", p.Stack),
+	)
+	if h.counter != nil {
+		h.counter.Inc()
+	}
+	for _, s := range h.sinks {
+		h.report(ctx, s, p)
+	}
+
+	return p
+}
+
+// report calls sink, recovering its own panics.
+func (h *Handler) report(ctx context.Context, s Sink, p *Panic) {
+	defer func() {
+		if r := recover(); r != nil {
+			h.log.Error("Panic sink panic", zap.Any("panic", r))
+		}
+	}()
+	s.Report(ctx, p)
+}
+
+// Handle implements telegram.UpdateHandler.
+func (h *Handler) Handle(ctx context.Context, u tg.UpdatesClass) (rerr error) {
+	defer func() {
+		if r := recover(); r != nil {
+			rerr = h.recovered(ctx, u, r)
+		}
+	}()
+
+	return h.next.Handle(ctx, u)
+}
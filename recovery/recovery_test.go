@@ -0,0 +1,79 @@
+package recovery
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-faster/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gotd/td/bin"
+	"github.com/gotd/td/telegram"
+	"github.com/gotd/td/tg"
+	"github.com/gotd/td/tgmock"
+)
+
+func TestHandler(t *testing.T) {
+	a := require.New(t)
+	ctx := context.Background()
+
+	testErr := errors.New("test")
+	var panicValue any
+	next := telegram.UpdateHandlerFunc(func(ctx context.Context, u tg.UpdatesClass) error {
+		if panicValue != nil {
+			panic(panicValue)
+		}
+		return testErr
+	})
+
+	counter := prometheus.NewCounter(prometheus.CounterOpts{Name: "panics"})
+	var reported []*Panic
+	h := New(next).
+		WithCounter(counter).
+		WithSink(SinkFunc(func(ctx context.Context, p *Panic) {
+			reported = append(reported, p)
+		})).
+		WithSink(SinkFunc(func(ctx context.Context, p *Panic) {
+			panic("sink")
+		}))
+
+	u := &tg.Updates{}
+	a.ErrorIs(h.Handle(ctx, u), testErr)
+	a.Empty(reported)
+
+	panicValue = "boom"
+	err := h.Handle(ctx, u)
+	var p *Panic
+	a.ErrorAs(err, &p)
+	a.Equal("boom", p.Value)
+	a.Equal(u, p.Update)
+	a.NotEmpty(p.Stack)
+	a.Equal([]*Panic{p}, reported)
+
+	panicValue = testErr
+	a.ErrorIs(h.Handle(ctx, u), testErr)
+	var m dto.Metric
+	a.NoError(counter.Write(&m))
+	a.Equal(2.0, m.GetCounter().GetValue())
+}
+
+func TestMessageSink(t *testing.T) {
+	a := require.New(t)
+
+	var sent string
+	api := tg.NewClient(tgmock.Invoker(func(request bin.Encoder) (bin.Encoder, error) {
+		req, ok := request.(*tg.MessagesSendMessageRequest)
+		a.True(ok)
+		sent = req.Message
+		return &tg.Updates{}, nil
+	}))
+
+	NewMessageSink(api, &tg.InputPeerSelf{}).Report(context.Background(), &Panic{
+		Value: "boom",
+		Stack: make([]byte, maxStackSize*2),
+	})
+	a.Contains(sent, "boom")
+	a.Less(len(sent), maxStackSize+100)
+}
@@ -12,7 +12,7 @@ type Credentials struct {
 }
 
 // NewCredentials creates new Credentials.
-func NewCredentials(client *redis.Client) Credentials {
+func NewCredentials(client redis.UniversalClient) Credentials {
 	s := redisClient{
 		client: client,
 	}
@@ -10,7 +10,7 @@ import (
 )
 
 type redisClient struct {
-	client *redis.Client
+	client redis.UniversalClient
 }
 
 func (r redisClient) Set(ctx context.Context, k, v string) error {
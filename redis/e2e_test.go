@@ -4,11 +4,16 @@ import (
 	"context"
 	"os"
 	"testing"
+	"time"
 
 	redisclient "github.com/go-redis/redis/v8"
 
+	"github.com/gotd/td/tg"
+
 	"github.com/gotd/contrib/internal/tests"
 	"github.com/gotd/contrib/redis"
+	"github.com/gotd/contrib/storage"
+	"github.com/gotd/contrib/storage/storagetest"
 )
 
 func TestE2E(t *testing.T) {
@@ -24,7 +29,131 @@ func TestE2E(t *testing.T) {
 		return client.Ping(ctx).Err()
 	})
 
+	// Required by PeerStorage.Watch.
+	if err := client.ConfigSet(context.Background(), "notify-keyspace-events", "K$gx").Err(); err != nil {
+		t.Fatal(err)
+	}
+
 	tests.TestSessionStorage(t, redis.NewSessionStorage(client, "session"))
 	tests.TestCredentials(t, redis.NewCredentials(client))
 	tests.TestPeerStorage(t, redis.NewPeerStorage(client))
+
+	t.Run("ReadClient", func(t *testing.T) {
+		// Empty database emulates lagging replica.
+		replica := redisclient.NewClient(&redisclient.Options{
+			Addr: addr,
+			DB:   1,
+		})
+		ctx := context.Background()
+		if err := replica.FlushDB(ctx).Err(); err != nil {
+			t.Fatal(err)
+		}
+
+		s := redis.NewPeerStorage(client).WithReadClient(replica)
+		var p storage.Peer
+		if err := p.FromInputPeer(&tg.InputPeerUser{UserID: 42, AccessHash: 42}); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.Assign(ctx, "replica", p); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := s.Find(ctx, storage.KeyFromPeer(p)); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := s.Resolve(ctx, "replica"); err != nil {
+			t.Fatal(err)
+		}
+	})
+	t.Run("Namespace", func(t *testing.T) {
+		shared := redisclient.NewClient(&redisclient.Options{
+			Addr: addr,
+			DB:   4,
+		})
+		if err := shared.FlushDB(context.Background()).Err(); err != nil {
+			t.Fatal(err)
+		}
+
+		storagetest.TestIsolation(t,
+			redis.NewPeerStorage(shared).WithNamespace("first"),
+			redis.NewPeerStorage(shared).WithNamespace("second"),
+		)
+		storagetest.TestIsolation(t,
+			redis.NewPeerStorage(shared),
+			redis.NewPeerStorage(shared).WithNamespace("third"),
+		)
+		tests.TestPeerStorage(t, redis.NewPeerStorage(shared).WithNamespace("fourth"))
+	})
+	t.Run("StaleIndex", func(t *testing.T) {
+		ctx := context.Background()
+		db := redisclient.NewClient(&redisclient.Options{Addr: addr, DB: 6})
+		if err := db.FlushDB(ctx).Err(); err != nil {
+			t.Fatal(err)
+		}
+		s := redis.NewPeerStorage(db)
+
+		var p storage.Peer
+		p.FromUser(&tg.User{ID: 10, AccessHash: 10, Username: "expiring"})
+		if err := s.AddTTL(ctx, p, time.Second); err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(1500 * time.Millisecond)
+
+		recent, err := storage.IterateFiltered(ctx, s, storage.IterateOptions{Order: storage.OrderRecent})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if recent.Next(ctx) || recent.Err() != nil {
+			t.Fatal("unexpected peer", recent.Err())
+		}
+		search, err := s.SearchPrefix(ctx, "exp")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if search.Next(ctx) || search.Err() != nil {
+			t.Fatal("unexpected peer", search.Err())
+		}
+
+		for _, key := range []string{"_recent", "_usernames"} {
+			n, err := db.ZCard(ctx, key).Result()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if n != 0 {
+				t.Fatalf("%s: %d stale members", key, n)
+			}
+		}
+	})
+	t.Run("BinaryCodec", func(t *testing.T) {
+		db := redisclient.NewClient(&redisclient.Options{Addr: addr, DB: 5})
+		storagetest.TestPeerStorage(t, func() storage.PeerStorage {
+			if err := db.FlushDB(context.Background()).Err(); err != nil {
+				t.Fatal(err)
+			}
+			return redis.NewPeerStorage(db).WithCodec(storage.BinaryCodec{})
+		})
+	})
+	t.Run("CodecSwitch", func(t *testing.T) {
+		db := redisclient.NewClient(&redisclient.Options{Addr: addr, DB: 7})
+		if err := db.FlushDB(context.Background()).Err(); err != nil {
+			t.Fatal(err)
+		}
+		tests.TestPeerStorageCodecSwitch(t,
+			redis.NewPeerStorage(db),
+			redis.NewPeerStorage(db).WithCodec(storage.BinaryCodec{}),
+		)
+	})
+	t.Run("Sharded", func(t *testing.T) {
+		shards := []*redisclient.Client{
+			redisclient.NewClient(&redisclient.Options{Addr: addr, DB: 2}),
+			redisclient.NewClient(&redisclient.Options{Addr: addr, DB: 3}),
+		}
+		storagetest.TestPeerStorage(t, func() storage.PeerStorage {
+			for _, shard := range shards {
+				if err := shard.FlushDB(context.Background()).Err(); err != nil {
+					t.Fatal(err)
+				}
+			}
+			return redis.NewShardedPeerStorage(shards...)
+		})
+	})
 }
@@ -0,0 +1,40 @@
+package redis
+
+import (
+	"context"
+
+	"github.com/go-faster/errors"
+
+	"github.com/gotd/td/telegram/query/dialogs"
+
+	"github.com/gotd/contrib/storage"
+)
+
+var _ storage.PeerCounter = PeerStorage{}
+
+// countScanSize is a SCAN COUNT hint used by Count.
+const countScanSize = 1000
+
+// Count returns count of stored peers of given kinds.
+// If no kinds given, peers of all kinds are counted.
+//
+// Count uses SCAN to match keys and does not fetch values.
+func (s PeerStorage) Count(ctx context.Context, kinds ...dialogs.PeerKind) (int, error) {
+	node, err := s.node(ctx, s.reader())
+	if err != nil {
+		return 0, err
+	}
+
+	var count int
+	for _, prefix := range storage.KindPrefixes(kinds...) {
+		iter := node.Scan(ctx, 0, s.match(string(prefix)), countScanSize).Iterator()
+		for iter.Next(ctx) {
+			count++
+		}
+		if err := iter.Err(); err != nil {
+			return 0, errors.Errorf("scan %q: %w", prefix, err)
+		}
+	}
+
+	return count, nil
+}
@@ -0,0 +1,60 @@
+package redis
+
+import (
+	"context"
+
+	"github.com/go-faster/errors"
+	"github.com/go-redis/redis/v8"
+
+	"github.com/gotd/contrib/storage"
+)
+
+var _ storage.PeerDeleter = PeerStorage{}
+
+// Delete removes peer with given key, its associated keys and index
+// entries.
+func (s PeerStorage) Delete(ctx context.Context, key storage.PeerKey) error {
+	id := key.String()
+
+	data, err := s.redis.Get(ctx, s.key(id)).Bytes()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			// Peer may be expired by redis, remove its stale index entry.
+			if _, err := s.unindex(ctx, recentKey, []staleMember{{member: id, id: id, missing: true}}); err != nil {
+				return err
+			}
+			return storage.ErrPeerNotFound
+		}
+		return errors.Errorf("get %q: %w", id, err)
+	}
+
+	var value storage.Peer
+	if err := s.codec.Unmarshal(data, &value); err != nil &&
+		!errors.Is(err, storage.ErrPeerUnmarshalMustInvalidate) {
+		return errors.Errorf("unmarshal: %w", err)
+	}
+
+	keys := value.Keys()
+	if _, err := s.redis.TxPipelined(ctx, func(tx redis.Pipeliner) error {
+		tx.Del(ctx, s.key(id))
+		tx.ZRem(ctx, s.key(recentKey), id)
+		if username := storage.UsernameIndex(value); username != "" {
+			tx.ZRem(ctx, s.key(usernamesKey), usernameMember(username, id))
+		}
+		for _, k := range keys {
+			// Delete associated key only if it still points to this peer.
+			tx.Eval(ctx, deleteIfEqualScript, []string{s.key(k)}, id)
+		}
+		return nil
+	}); err != nil {
+		return errors.Errorf("exec: %w", err)
+	}
+
+	return nil
+}
+
+// deleteIfEqualScript deletes KEYS[1] if its value equals to ARGV[1].
+const deleteIfEqualScript = `if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0`
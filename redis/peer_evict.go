@@ -0,0 +1,46 @@
+package redis
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/go-faster/errors"
+	"github.com/go-redis/redis/v8"
+
+	"github.com/gotd/contrib/storage"
+)
+
+var _ storage.PeerEvicter = PeerStorage{}
+
+// Evict removes peers which were not added, assigned or touched during
+// given duration and returns count of removed peers.
+//
+// Peers stored with TTL are expired by redis itself.
+func (s PeerStorage) Evict(ctx context.Context, olderThan time.Duration) (int, error) {
+	cutoff := time.Now().Add(-olderThan).UnixMilli()
+	ids, err := s.redis.ZRangeByScore(ctx, s.key(recentKey), &redis.ZRangeBy{
+		Min: "-inf",
+		Max: "(" + strconv.FormatInt(cutoff, 10),
+	}).Result()
+	if err != nil {
+		return 0, errors.Errorf("zrangebyscore: %w", err)
+	}
+
+	var n int
+	for _, id := range ids {
+		var key storage.PeerKey
+		if err := key.Parse([]byte(id)); err != nil {
+			continue
+		}
+		if err := s.Delete(ctx, key); err != nil {
+			if errors.Is(err, storage.ErrPeerNotFound) {
+				// Expired, stale index entry is removed by Delete.
+				continue
+			}
+			return n, errors.Errorf("delete %s: %w", key, err)
+		}
+		n++
+	}
+	return n, nil
+}
@@ -0,0 +1,59 @@
+package redis
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-faster/errors"
+	"github.com/go-redis/redis/v8"
+
+	"github.com/gotd/contrib/storage"
+)
+
+var _ storage.PeerIDFinder = PeerStorage{}
+
+// FindByID finds peer with given ID of any kind.
+//
+// Peer keys are prefixed by kind, so FindByID fetches peers of all kinds
+// using single MGET.
+func (s PeerStorage) FindByID(ctx context.Context, id int64) (storage.Peer, error) {
+	p, err := s.findByID(ctx, s.reader(), id)
+	if errors.Is(err, storage.ErrPeerNotFound) && s.read != nil {
+		// Replica may lag behind primary.
+		return s.findByID(ctx, s.redis, id)
+	}
+	return p, err
+}
+
+func (s PeerStorage) findByID(ctx context.Context, client redis.UniversalClient, id int64) (storage.Peer, error) {
+	keys := make([]string, 0, len(storage.IDKinds))
+	for _, kind := range storage.IDKinds {
+		keys = append(keys, s.key(storage.PeerKey{Kind: kind, ID: id}.String()))
+	}
+
+	values, err := client.MGet(ctx, keys...).Result()
+	if err != nil {
+		return storage.Peer{}, errors.Errorf("mget: %w", err)
+	}
+
+	now := time.Now()
+	for _, v := range values {
+		data, ok := v.(string)
+		if !ok {
+			continue
+		}
+
+		var p storage.Peer
+		if err := s.codec.Unmarshal([]byte(data), &p); err != nil {
+			if errors.Is(err, storage.ErrPeerUnmarshalMustInvalidate) {
+				continue
+			}
+			return storage.Peer{}, errors.Errorf("unmarshal: %w", err)
+		}
+		if p.Expired(now) {
+			continue
+		}
+		return p, nil
+	}
+	return storage.Peer{}, storage.ErrPeerNotFound
+}
@@ -0,0 +1,192 @@
+package redis
+
+import (
+	"context"
+	"strings"
+
+	"github.com/go-faster/errors"
+	"github.com/go-redis/redis/v8"
+
+	"github.com/gotd/contrib/storage"
+)
+
+var _ storage.PeerGarbageCollector = PeerStorage{}
+
+// gcScanSize is a SCAN COUNT hint used by CollectGarbage.
+const gcScanSize = 1000
+
+// CollectGarbage removes associated keys which point to missing peers
+// and returns count of removed keys. Index entries of missing peers are
+// removed too, but not counted.
+//
+// CollectGarbage scans whole database or namespace, fetching scanned keys
+// and their peers using MGET. Orphaned keys are removed only if they still point
+// to the same peer.
+func (s PeerStorage) CollectGarbage(ctx context.Context, opts storage.GCOptions) (int, error) {
+	var (
+		count  int
+		cursor uint64
+	)
+	node, err := s.node(ctx, s.redis)
+	if err != nil {
+		return 0, err
+	}
+
+	var match string
+	if s.ns != "" {
+		match = s.match("")
+	}
+	for {
+		keys, next, err := node.Scan(ctx, cursor, match, gcScanSize).Result()
+		if err != nil {
+			return 0, errors.Errorf("scan: %w", err)
+		}
+
+		n, err := s.collectGarbage(ctx, keys, opts)
+		if err != nil {
+			return 0, err
+		}
+		count += n
+
+		if next == 0 {
+			break
+		}
+		cursor = next
+	}
+
+	if opts.DryRun {
+		return count, nil
+	}
+	if err := s.collectIndex(ctx, recentKey, func(member string) (string, bool) {
+		return member, true
+	}); err != nil {
+		return 0, err
+	}
+	if err := s.collectIndex(ctx, usernamesKey, func(member string) (string, bool) {
+		idx := strings.IndexByte(member, 0)
+		if idx < 0 {
+			return "", false
+		}
+		return member[idx+1:], true
+	}); err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// collectIndex removes members of given index which refer to missing
+// peers, e.g. expired by redis.
+func (s PeerStorage) collectIndex(ctx context.Context, index string, peerID func(member string) (string, bool)) error {
+	var cursor uint64
+	for {
+		// ZSCAN returns member and score pairs.
+		pairs, next, err := s.redis.ZScan(ctx, s.key(index), cursor, "", gcScanSize).Result()
+		if err != nil {
+			return errors.Errorf("zscan %s: %w", index, err)
+		}
+
+		var (
+			members []string
+			ids     []string
+		)
+		for i := 0; i < len(pairs); i += 2 {
+			id, ok := peerID(pairs[i])
+			if !ok {
+				continue
+			}
+			members = append(members, pairs[i])
+			ids = append(ids, id)
+		}
+		if len(ids) > 0 {
+			values, err := s.redis.MGet(ctx, s.keys(ids)...).Result()
+			if err != nil {
+				return errors.Errorf("mget peers: %w", err)
+			}
+			var stale []staleMember
+			for i, v := range values {
+				if v == nil {
+					stale = append(stale, staleMember{member: members[i], id: ids[i], missing: true})
+				}
+			}
+			if _, err := s.unindex(ctx, index, stale); err != nil {
+				return err
+			}
+		}
+
+		if next == 0 {
+			return nil
+		}
+		cursor = next
+	}
+}
+
+func (s PeerStorage) collectGarbage(ctx context.Context, keys []string, opts storage.GCOptions) (int, error) {
+	if len(keys) == 0 {
+		return 0, nil
+	}
+
+	// MGET returns nil for keys of other types, like indexes.
+	values, err := s.redis.MGet(ctx, keys...).Result()
+	if err != nil {
+		return 0, errors.Errorf("mget keys: %w", err)
+	}
+
+	var (
+		candidates []string
+		targets    []string
+	)
+	for i, v := range values {
+		value, ok := v.(string)
+		if !ok {
+			continue
+		}
+		if _, ok := storage.AssociatedKey([]byte(keys[i][len(s.ns):]), []byte(value)); !ok {
+			continue
+		}
+		candidates = append(candidates, keys[i])
+		targets = append(targets, value)
+	}
+	if len(candidates) == 0 {
+		return 0, nil
+	}
+
+	peers, err := s.redis.MGet(ctx, s.keys(targets)...).Result()
+	if err != nil {
+		return 0, errors.Errorf("mget peers: %w", err)
+	}
+
+	var orphaned []int
+	for i, v := range peers {
+		var (
+			p     storage.Peer
+			found bool
+		)
+		if data, ok := v.(string); ok {
+			switch err := s.codec.Unmarshal([]byte(data), &p); {
+			case err == nil:
+				found = true
+			case !errors.Is(err, storage.ErrPeerUnmarshalMustInvalidate):
+				return 0, errors.Errorf("unmarshal: %w", err)
+			}
+		}
+		if !storage.Orphaned(candidates[i][len(s.ns):], p, found, opts) {
+			continue
+		}
+
+		orphaned = append(orphaned, i)
+	}
+
+	if opts.DryRun || len(orphaned) == 0 {
+		return len(orphaned), nil
+	}
+	if _, err := s.redis.TxPipelined(ctx, func(tx redis.Pipeliner) error {
+		for _, i := range orphaned {
+			// Delete associated key only if it still points to this peer.
+			tx.Eval(ctx, deleteIfEqualScript, []string{candidates[i]}, targets[i])
+		}
+		return nil
+	}); err != nil {
+		return 0, errors.Errorf("exec: %w", err)
+	}
+	return len(orphaned), nil
+}
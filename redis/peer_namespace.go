@@ -0,0 +1,83 @@
+package redis
+
+import (
+	"context"
+	"strings"
+
+	"github.com/go-faster/errors"
+	"github.com/go-redis/redis/v8"
+)
+
+// namespacePrefix is a prefix of namespaced keys.
+// Key is namespacePrefix + "{" + namespace + "}" + 0x00 + key.
+//
+// Prefix starts with underscore, so namespaced keys are never considered
+// peer or associated keys of storage without namespace. Namespace is a
+// hash tag, so all keys of namespace are in the same Redis Cluster slot.
+const namespacePrefix = "_ns:"
+
+// WithNamespace sets namespace of stored peers, e.g. account ID, so multiple
+// PeerStorage instances can share one redis database without collisions.
+//
+// All keys of the storage, including associated keys and indexes, are
+// prefixed by the namespace. Empty namespace means no prefix, which is
+// the default.
+//
+// Namespace is used as hash tag, so multi-key operations of namespaced
+// storage stay in one Redis Cluster slot. Namespace is required to use
+// storage with cluster client.
+func (s *PeerStorage) WithNamespace(ns string) *PeerStorage {
+	s.ns = ""
+	if ns != "" {
+		s.ns = namespacePrefix + "{" + ns + "}\x00"
+	}
+	return s
+}
+
+// key returns redis key of given storage key.
+func (s PeerStorage) key(k string) string {
+	return s.ns + k
+}
+
+// keys returns redis keys of given storage keys.
+func (s PeerStorage) keys(keys []string) []string {
+	if s.ns == "" {
+		return keys
+	}
+	r := make([]string, len(keys))
+	for i, k := range keys {
+		r[i] = s.key(k)
+	}
+	return r
+}
+
+// match returns SCAN pattern of redis keys with given storage key prefix.
+func (s PeerStorage) match(prefix string) string {
+	var b strings.Builder
+	b.Grow(len(s.ns) + len(prefix) + 1)
+	for _, c := range []byte(s.ns) {
+		switch c {
+		case '*', '?', '[', ']', '\\':
+			b.WriteByte('\\')
+		}
+		b.WriteByte(c)
+	}
+	b.WriteString(prefix)
+	b.WriteByte('*')
+	return b.String()
+}
+
+// node returns client of node serving keys of the storage, e.g. to SCAN
+// them. Cluster client sends commands without keys to random node, so
+// commands are sent to master of namespace slot instead.
+func (s PeerStorage) node(ctx context.Context, client redis.UniversalClient) (redis.UniversalClient, error) {
+	cluster, ok := client.(*redis.ClusterClient)
+	if !ok || s.ns == "" {
+		return client, nil
+	}
+	node, err := cluster.MasterForKey(ctx, s.ns)
+	if err != nil {
+		return nil, errors.Errorf("get node: %w", err)
+	}
+	return node, nil
+}
@@ -0,0 +1,54 @@
+package redis
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gotd/contrib/storage"
+)
+
+// hashTag returns part of key which is hashed by Redis Cluster.
+func hashTag(key string) string {
+	start := strings.IndexByte(key, '{')
+	if start < 0 {
+		return key
+	}
+	end := strings.IndexByte(key[start+1:], '}')
+	if end <= 0 {
+		return key
+	}
+	return key[start+1 : start+1+end]
+}
+
+func TestNamespace(t *testing.T) {
+	a := require.New(t)
+	client := redis.NewClient(&redis.Options{})
+	defer client.Close()
+
+	s := NewPeerStorage(client)
+	a.Equal("users_1", s.key("users_1"))
+	a.Equal("users_*", s.match("users_"))
+
+	for _, ns := range []string{"account", "a}b", "{a*"} {
+		s := NewPeerStorage(client).WithNamespace(ns)
+		keys := []string{
+			s.key(storage.PeerKey{ID: 1}.String()),
+			s.key("username"),
+			s.key(recentKey),
+			s.key(usernamesKey),
+			s.key(storage.SchemaKey),
+		}
+		for _, k := range keys {
+			a.Equal(hashTag(keys[0]), hashTag(k), "keys of namespace %q should share slot", ns)
+		}
+	}
+	a.Equal(`_ns:{{a\*}`+"\x00users_*", NewPeerStorage(client).WithNamespace("{a*").match("users_"))
+
+	node, err := s.node(context.Background(), client)
+	a.NoError(err)
+	a.Equal(redis.UniversalClient(client), node)
+}
@@ -0,0 +1,89 @@
+package redis
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/go-faster/errors"
+
+	"github.com/gotd/contrib/storage"
+)
+
+var _ storage.PeerPager = PeerStorage{}
+
+// IteratePage returns page of peers following given cursor.
+// Empty cursor means the first page.
+//
+// Cursor is a Redis SCAN cursor, so every peer which is present during the
+// whole pagination is returned, but page may contain slightly more than limit
+// peers, since SCAN does not guarantee exact batch size.
+func (s PeerStorage) IteratePage(ctx context.Context, cursor string, limit int) (storage.PeerPage, error) {
+	if limit <= 0 {
+		return storage.PeerPage{}, errors.Errorf("invalid limit %d", limit)
+	}
+
+	var scanCursor uint64
+	if cursor != "" {
+		v, err := strconv.ParseUint(cursor, 10, 64)
+		if err != nil {
+			return storage.PeerPage{}, errors.Errorf("invalid cursor %q", cursor)
+		}
+		scanCursor = v
+	}
+
+	node, err := s.node(ctx, s.reader())
+	if err != nil {
+		return storage.PeerPage{}, err
+	}
+
+	match := s.match(string(storage.PeerKeyPrefix))
+	var keys []string
+	for {
+		batch, next, err := node.Scan(ctx, scanCursor, match, int64(limit)).Result()
+		if err != nil {
+			return storage.PeerPage{}, errors.Errorf("scan: %w", err)
+		}
+		keys = append(keys, batch...)
+		scanCursor = next
+		if scanCursor == 0 || len(keys) >= limit {
+			break
+		}
+	}
+
+	var page storage.PeerPage
+	if scanCursor != 0 {
+		page.Next = strconv.FormatUint(scanCursor, 10)
+	}
+	if len(keys) == 0 {
+		return page, nil
+	}
+
+	values, err := s.reader().MGet(ctx, keys...).Result()
+	if err != nil {
+		return storage.PeerPage{}, errors.Errorf("mget: %w", err)
+	}
+
+	now := time.Now()
+	for i, v := range values {
+		data, ok := v.(string)
+		if !ok {
+			// Key expired or deleted after scan.
+			continue
+		}
+
+		var value storage.Peer
+		if err := s.codec.Unmarshal([]byte(data), &value); err != nil {
+			if errors.Is(err, storage.ErrPeerUnmarshalMustInvalidate) {
+				continue
+			}
+			return storage.PeerPage{}, errors.Errorf("unmarshal %q: %w", keys[i], err)
+		}
+		if value.Expired(now) {
+			continue
+		}
+		page.Peers = append(page.Peers, value)
+	}
+
+	return page, nil
+}
@@ -0,0 +1,208 @@
+package redis
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-faster/errors"
+	"github.com/go-redis/redis/v8"
+
+	"github.com/gotd/contrib/storage"
+)
+
+var _ storage.PeerToucher = PeerStorage{}
+
+// recentKey is a key of sorted set with peer keys scored by last touch time.
+//
+// Usernames cannot start with underscore, so it does not collide with
+// associated keys.
+const recentKey = "_recent"
+
+// recentBatch is a count of peers fetched per request by recency iterator.
+const recentBatch = 100
+
+func touchMember(now time.Time, id string) *redis.Z {
+	return &redis.Z{Score: float64(now.UnixMilli()), Member: id}
+}
+
+// Touch marks peer with given key as recently used.
+func (s PeerStorage) Touch(ctx context.Context, key storage.PeerKey) error {
+	id := key.String()
+
+	// XX updates score only if peer is already indexed.
+	added, err := s.redis.ZAddXXCh(ctx, s.key(recentKey), touchMember(time.Now(), id)).Result()
+	if err != nil {
+		return errors.Errorf("zadd: %w", err)
+	}
+	if added > 0 {
+		return nil
+	}
+
+	n, err := s.redis.Exists(ctx, s.key(id)).Result()
+	if err != nil {
+		return errors.Errorf("exists %q: %w", id, err)
+	}
+	if n == 0 {
+		return storage.ErrPeerNotFound
+	}
+	if err := s.redis.ZAdd(ctx, s.key(recentKey), touchMember(time.Now(), id)).Err(); err != nil {
+		return errors.Errorf("zadd: %w", err)
+	}
+	return nil
+}
+
+type recentIterator struct {
+	s       PeerStorage
+	client  redis.UniversalClient
+	reverse bool
+	offset  int64
+	done    bool
+	batch   []storage.Peer
+	value   storage.Peer
+	lastErr error
+	opts    storage.IterateOptions
+}
+
+func (p *recentIterator) Close() error {
+	return nil
+}
+
+// fetch loads next batch of peers.
+func (p *recentIterator) fetch(ctx context.Context) error {
+	start, stop := p.offset, p.offset+recentBatch-1
+	var (
+		ids []string
+		err error
+	)
+	if p.reverse {
+		ids, err = p.client.ZRange(ctx, p.s.key(recentKey), start, stop).Result()
+	} else {
+		ids, err = p.client.ZRevRange(ctx, p.s.key(recentKey), start, stop).Result()
+	}
+	if err != nil {
+		return errors.Errorf("zrange: %w", err)
+	}
+	p.offset += int64(len(ids))
+	if len(ids) < recentBatch {
+		p.done = true
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+
+	values, err := p.client.MGet(ctx, p.s.keys(ids)...).Result()
+	if err != nil {
+		return errors.Errorf("mget: %w", err)
+	}
+
+	var (
+		now   = time.Now()
+		stale []staleMember
+	)
+	for i, v := range values {
+		data, ok := v.(string)
+		if !ok {
+			// Peer expired or deleted.
+			stale = append(stale, staleMember{member: ids[i], id: ids[i], missing: true})
+			continue
+		}
+		var value storage.Peer
+		if err := p.s.codec.Unmarshal([]byte(data), &value); err != nil {
+			if errors.Is(err, storage.ErrPeerUnmarshalMustInvalidate) {
+				continue
+			}
+			return errors.Errorf("unmarshal %q: %w", ids[i], err)
+		}
+		if value.Expired(now) {
+			stale = append(stale, staleMember{member: ids[i], id: ids[i]})
+			continue
+		}
+		if !p.opts.Match(value) {
+			continue
+		}
+		p.batch = append(p.batch, value)
+	}
+
+	removed, err := p.s.unindex(ctx, recentKey, stale)
+	if err != nil {
+		return err
+	}
+	// Removed members precede offset, so next batch is shifted.
+	p.offset -= removed
+	return nil
+}
+
+// staleMember is a member of index which refers to expired or deleted peer.
+type staleMember struct {
+	member string
+	id     string
+	// missing is true if peer is deleted, so member is removed only if
+	// peer is still missing on primary.
+	missing bool
+}
+
+// removeMissingScript removes member ARGV[1] of sorted set KEYS[1], if key
+// KEYS[2] does not exist.
+const removeMissingScript = `if redis.call("EXISTS", KEYS[2]) == 0 then
+	return redis.call("ZREM", KEYS[1], ARGV[1])
+end
+return 0`
+
+// unindex removes stale members of given index and returns count of
+// removed members.
+//
+// Peers stored with TTL are expired by redis itself, so their index
+// entries are removed lazily by iterators and CollectGarbage.
+func (s PeerStorage) unindex(ctx context.Context, index string, stale []staleMember) (int64, error) {
+	if len(stale) == 0 {
+		return 0, nil
+	}
+
+	results := make([]func() (int64, error), 0, len(stale))
+	if _, err := s.redis.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, m := range stale {
+			if m.missing {
+				results = append(results, pipe.Eval(ctx, removeMissingScript,
+					[]string{s.key(index), s.key(m.id)}, m.member,
+				).Int64)
+				continue
+			}
+			results = append(results, pipe.ZRem(ctx, s.key(index), m.member).Result)
+		}
+		return nil
+	}); err != nil {
+		return 0, errors.Errorf("remove stale %s: %w", index, err)
+	}
+
+	var removed int64
+	for _, result := range results {
+		n, err := result()
+		if err != nil {
+			return 0, errors.Errorf("remove stale %s: %w", index, err)
+		}
+		removed += n
+	}
+	return removed, nil
+}
+
+func (p *recentIterator) Next(ctx context.Context) bool {
+	for len(p.batch) == 0 {
+		if p.done {
+			return false
+		}
+		if err := p.fetch(ctx); err != nil {
+			p.lastErr = err
+			return false
+		}
+	}
+	p.value, p.batch = p.batch[0], p.batch[1:]
+	return true
+}
+
+func (p *recentIterator) Err() error {
+	return p.lastErr
+}
+
+func (p *recentIterator) Value() storage.Peer {
+	return p.value
+}
@@ -0,0 +1,95 @@
+package redis
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-faster/errors"
+	"github.com/go-redis/redis/v8"
+
+	"github.com/gotd/contrib/storage"
+)
+
+var _ storage.PeerBatchResolver = PeerStorage{}
+
+// ResolveBatch finds peers using given associated keys.
+// Keys not found are omitted from result.
+//
+// ResolveBatch uses two MGET round trips regardless of keys count: one
+// for peer IDs and one for peers.
+func (s PeerStorage) ResolveBatch(ctx context.Context, keys []string) (map[string]storage.Peer, error) {
+	r, err := s.resolveBatch(ctx, s.reader(), keys)
+	if err != nil || s.read == nil || len(r) == len(keys) {
+		return r, err
+	}
+
+	// Replica may lag behind primary, so retry missing keys.
+	var missing []string
+	for _, key := range keys {
+		if _, ok := r[key]; !ok {
+			missing = append(missing, key)
+		}
+	}
+	found, err := s.resolveBatch(ctx, s.redis, missing)
+	if err != nil {
+		return nil, err
+	}
+	for key, p := range found {
+		r[key] = p
+	}
+	return r, nil
+}
+
+func (s PeerStorage) resolveBatch(ctx context.Context, client redis.UniversalClient, keys []string) (map[string]storage.Peer, error) {
+	r := make(map[string]storage.Peer, len(keys))
+	if len(keys) == 0 {
+		return r, nil
+	}
+
+	ids, err := client.MGet(ctx, s.keys(keys)...).Result()
+	if err != nil {
+		return nil, errors.Errorf("mget keys: %w", err)
+	}
+
+	var (
+		found []string
+		idx   []string
+	)
+	for i, v := range ids {
+		id, ok := v.(string)
+		if !ok {
+			continue
+		}
+		found = append(found, keys[i])
+		idx = append(idx, id)
+	}
+	if len(idx) == 0 {
+		return r, nil
+	}
+
+	values, err := client.MGet(ctx, s.keys(idx)...).Result()
+	if err != nil {
+		return nil, errors.Errorf("mget peers: %w", err)
+	}
+
+	now := time.Now()
+	for i, v := range values {
+		data, ok := v.(string)
+		if !ok {
+			continue
+		}
+
+		var p storage.Peer
+		if err := s.codec.Unmarshal([]byte(data), &p); err != nil {
+			if errors.Is(err, storage.ErrPeerUnmarshalMustInvalidate) {
+				continue
+			}
+			return nil, errors.Errorf("unmarshal: %w", err)
+		}
+		if p.Expired(now) {
+			continue
+		}
+		r[found[i]] = p
+	}
+	return r, nil
+}
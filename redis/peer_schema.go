@@ -0,0 +1,32 @@
+package redis
+
+import (
+	"context"
+
+	"github.com/go-faster/errors"
+	"github.com/go-redis/redis/v8"
+
+	"github.com/gotd/contrib/storage"
+)
+
+var _ storage.SchemaVersioner = PeerStorage{}
+
+// SchemaVersion returns stored schema version.
+func (s PeerStorage) SchemaVersion(ctx context.Context) (int, error) {
+	v, err := s.redis.Get(ctx, s.key(storage.SchemaKey)).Int()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return 0, nil
+		}
+		return 0, errors.Errorf("get: %w", err)
+	}
+	return v, nil
+}
+
+// SetSchemaVersion stores schema version.
+func (s PeerStorage) SetSchemaVersion(ctx context.Context, version int) error {
+	if err := s.redis.Set(ctx, s.key(storage.SchemaKey), version, 0).Err(); err != nil {
+		return errors.Errorf("set: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,153 @@
+package redis
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/go-faster/errors"
+	"github.com/go-redis/redis/v8"
+
+	"github.com/gotd/contrib/storage"
+)
+
+var _ storage.PeerSearcher = PeerStorage{}
+
+// usernamesKey is a key of sorted set used as username index.
+//
+// All members have the same score, so set is ordered lexicographically.
+// Member is lowercase username + 0x00 + peer key.
+const usernamesKey = "_usernames"
+
+func usernameMember(username, id string) string {
+	return username + "\x00" + id
+}
+
+type searchIterator struct {
+	s       PeerStorage
+	client  redis.UniversalClient
+	prefix  string
+	offset  int64
+	done    bool
+	batch   []storage.Peer
+	value   storage.Peer
+	lastErr error
+}
+
+func (p *searchIterator) Close() error {
+	return nil
+}
+
+// fetch loads next batch of peers.
+func (p *searchIterator) fetch(ctx context.Context) error {
+	members, err := p.client.ZRangeByLex(ctx, p.s.key(usernamesKey), &redis.ZRangeBy{
+		Min:    "[" + p.prefix,
+		Max:    "[" + p.prefix + "\xff",
+		Offset: p.offset,
+		Count:  recentBatch,
+	}).Result()
+	if err != nil {
+		return errors.Errorf("zrangebylex: %w", err)
+	}
+	p.offset += int64(len(members))
+	if len(members) < recentBatch {
+		p.done = true
+	}
+	if len(members) == 0 {
+		return nil
+	}
+
+	var (
+		usernames = make([]string, 0, len(members))
+		ids       = make([]string, 0, len(members))
+	)
+	for _, m := range members {
+		idx := strings.IndexByte(m, 0)
+		if idx < 0 {
+			continue
+		}
+		usernames = append(usernames, m[:idx])
+		ids = append(ids, m[idx+1:])
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+
+	values, err := p.client.MGet(ctx, p.s.keys(ids)...).Result()
+	if err != nil {
+		return errors.Errorf("mget: %w", err)
+	}
+
+	var (
+		now   = time.Now()
+		stale []staleMember
+	)
+	for i, v := range values {
+		member := usernameMember(usernames[i], ids[i])
+		data, ok := v.(string)
+		if !ok {
+			// Peer expired or deleted.
+			stale = append(stale, staleMember{member: member, id: ids[i], missing: true})
+			continue
+		}
+		var value storage.Peer
+		if err := p.s.codec.Unmarshal([]byte(data), &value); err != nil {
+			if errors.Is(err, storage.ErrPeerUnmarshalMustInvalidate) {
+				continue
+			}
+			return errors.Errorf("unmarshal %q: %w", ids[i], err)
+		}
+		if value.Expired(now) {
+			stale = append(stale, staleMember{member: member, id: ids[i]})
+			continue
+		}
+		// Peer may change username after indexing.
+		if storage.UsernameIndex(value) != usernames[i] {
+			continue
+		}
+		p.batch = append(p.batch, value)
+	}
+
+	removed, err := p.s.unindex(ctx, usernamesKey, stale)
+	if err != nil {
+		return err
+	}
+	// Removed members precede offset, so next batch is shifted.
+	p.offset -= removed
+	return nil
+}
+
+func (p *searchIterator) Next(ctx context.Context) bool {
+	for len(p.batch) == 0 {
+		if p.done {
+			return false
+		}
+		if err := p.fetch(ctx); err != nil {
+			p.lastErr = err
+			return false
+		}
+	}
+	p.value, p.batch = p.batch[0], p.batch[1:]
+	return true
+}
+
+func (p *searchIterator) Err() error {
+	return p.lastErr
+}
+
+func (p *searchIterator) Value() storage.Peer {
+	return p.value
+}
+
+// SearchPrefix creates and returns new PeerIterator which returns only
+// peers whose username starts with given prefix.
+//
+// Peers are returned in username order. Search uses username index
+// maintained by Add and Assign.
+func (s PeerStorage) SearchPrefix(ctx context.Context, prefix string) (storage.PeerIterator, error) {
+	return &searchIterator{
+		s:      s,
+		client: s.reader(),
+		prefix: strings.ToLower(prefix),
+	}, nil
+}
@@ -2,8 +2,7 @@ package redis
 
 import (
 	"context"
-	"encoding/json"
-	"strings"
+	"time"
 
 	"github.com/go-faster/errors"
 	"github.com/go-redis/redis/v8"
@@ -12,23 +11,66 @@ import (
 	"github.com/gotd/contrib/storage"
 )
 
-var _ storage.PeerStorage = PeerStorage{}
+var (
+	_ storage.PeerStorage         = PeerStorage{}
+	_ storage.FilteredPeerStorage = PeerStorage{}
+)
 
 // PeerStorage is a peer storage based on redis.
 type PeerStorage struct {
-	redis *redis.Client
+	redis redis.UniversalClient
+	// read is an optional client for reads, e.g. connected to replica.
+	read  redis.UniversalClient
+	codec storage.Codec
+	// ns is a key prefix of namespace, if any.
+	ns string
 }
 
 // NewPeerStorage creates new peer storage using redis.
-func NewPeerStorage(client *redis.Client) *PeerStorage {
-	return &PeerStorage{redis: client}
+//
+// Client may be a Redis Cluster or Sentinel client, e.g. created using
+// redis.NewUniversalClient. Storage using cluster client must have
+// namespace, see WithNamespace.
+func NewPeerStorage(client redis.UniversalClient) *PeerStorage {
+	return &PeerStorage{redis: client, codec: storage.JSONCodec{}}
+}
+
+// WithReadClient sets client used for reads, e.g. connected to read
+// replica. Writes are always sent to the primary client.
+//
+// Since replicas may lag, Find and Resolve fall back to the primary
+// client if peer is not found by read client.
+func (s *PeerStorage) WithReadClient(client redis.UniversalClient) *PeerStorage {
+	s.read = client
+	return s
+}
+
+// reader returns client to use for reads.
+func (s PeerStorage) reader() redis.UniversalClient {
+	if s.read != nil {
+		return s.read
+	}
+	return s.redis
+}
+
+// WithCodec sets codec of persisted peers. Default is storage.JSONCodec.
+//
+// Peers stored using another codec are considered outdated.
+func (s *PeerStorage) WithCodec(c storage.Codec) *PeerStorage {
+	s.codec = c
+	return s
 }
 
 type redisIterator struct {
-	client  *redis.Client
+	codec   storage.Codec
+	client  redis.UniversalClient
 	iter    *redis.ScanIterator
 	lastErr error
 	value   storage.Peer
+
+	// patterns are remaining SCAN patterns to scan after current one.
+	patterns []string
+	opts     storage.IterateOptions
 }
 
 func (p *redisIterator) Close() error {
@@ -36,24 +78,42 @@ func (p *redisIterator) Close() error {
 }
 
 func (p *redisIterator) Next(ctx context.Context) bool {
-	if !p.iter.Next(ctx) {
-		return false
-	}
+	now := time.Now()
+	for {
+		if !p.iter.Next(ctx) {
+			if p.iter.Err() != nil || len(p.patterns) == 0 {
+				return false
+			}
+			p.iter = p.client.Scan(ctx, 0, p.patterns[0], 0).Iterator()
+			p.patterns = p.patterns[1:]
+			continue
+		}
 
-	key := p.iter.Val()
-	value, err := p.client.Get(ctx, key).Result()
-	if err != nil {
-		p.lastErr = errors.Errorf("get %q: %w", key, err)
-		return false
-	}
+		key := p.iter.Val()
+		value, err := p.client.Get(ctx, key).Result()
+		if err != nil {
+			if errors.Is(err, redis.Nil) {
+				// Key expired or deleted after scan.
+				continue
+			}
+			p.lastErr = errors.Errorf("get %q: %w", key, err)
+			return false
+		}
 
-	r := strings.NewReader(value)
-	if err := json.NewDecoder(r).Decode(&p.value); err != nil {
-		p.lastErr = errors.Errorf("unmarshal: %w", err)
-		return false
-	}
+		p.value = storage.Peer{}
+		if err := p.codec.Unmarshal([]byte(value), &p.value); err != nil {
+			if errors.Is(err, storage.ErrPeerUnmarshalMustInvalidate) {
+				continue
+			}
+			p.lastErr = errors.Errorf("unmarshal %q: %w", key, err)
+			return false
+		}
+		if p.value.Expired(now) || !p.opts.Match(p.value) {
+			continue
+		}
 
-	return true
+		return true
+	}
 }
 
 func (p *redisIterator) Err() error {
@@ -66,31 +126,66 @@ func (p *redisIterator) Value() storage.Peer {
 
 // Iterate creates and returns new PeerIterator.
 func (s PeerStorage) Iterate(ctx context.Context) (storage.PeerIterator, error) {
-	var b strings.Builder
-	b.Grow(len(storage.PeerKeyPrefix) + 1)
-	b.Write(storage.PeerKeyPrefix)
-	b.WriteByte('*')
+	return s.IterateFiltered(ctx, storage.IterateOptions{})
+}
+
+// IterateFiltered creates and returns new PeerIterator which returns only
+// peers matching given options.
+//
+// Peer kinds are filtered by SCAN pattern without fetching other peers.
+// Recency order uses sorted set maintained by Add, Assign and Touch.
+func (s PeerStorage) IterateFiltered(ctx context.Context, opts storage.IterateOptions) (storage.PeerIterator, error) {
+	var patterns []string
+	for _, prefix := range opts.Prefixes() {
+		patterns = append(patterns, s.match(string(prefix)))
+	}
+	if len(patterns) == 0 {
+		return storage.EmptyIterator(), nil
+	}
+	if opts.Order != storage.OrderKey {
+		return &recentIterator{
+			s:       s,
+			client:  s.reader(),
+			reverse: opts.Order == storage.OrderRecentReverse,
+			opts:    opts,
+		}, nil
+	}
 
-	result := s.redis.Scan(ctx, 0, b.String(), 0)
+	node, err := s.node(ctx, s.reader())
+	if err != nil {
+		return nil, err
+	}
+	result := node.Scan(ctx, 0, patterns[0], 0)
 	return &redisIterator{
-		client: s.redis,
-		iter:   result.Iterator(),
+		codec:    s.codec,
+		client:   node,
+		iter:     result.Iterator(),
+		patterns: patterns[1:],
+		opts:     opts,
 	}, result.Err()
 }
 
-func (s PeerStorage) add(ctx context.Context, associated []string, value storage.Peer) (rerr error) {
-	data, err := json.Marshal(value)
-	if err != nil {
-		return errors.Errorf("marshal: %w", err)
-	}
+func (s PeerStorage) add(ctx context.Context, associated []string, value storage.Peer, ttl time.Duration) (rerr error) {
 	id := storage.KeyFromPeer(value).String()
-
-	if len(associated) == 0 {
-		if err := s.redis.Set(ctx, id, data, 0).Err(); err != nil {
-			return errors.Errorf("set id <-> data: %w", err)
+	var stored *storage.Peer
+	data, err := s.redis.Get(ctx, s.key(id)).Bytes()
+	switch {
+	case err == nil:
+		var p storage.Peer
+		switch err := s.codec.Unmarshal(data, &p); {
+		case err == nil:
+			stored = &p
+			value = storage.Merge(p, value)
+		case !errors.Is(err, storage.ErrPeerUnmarshalMustInvalidate):
+			return errors.Errorf("unmarshal: %w", err)
 		}
+	case !errors.Is(err, redis.Nil):
+		return errors.Errorf("get %q: %w", id, err)
+	}
 
-		return nil
+	data, err = s.codec.Marshal(value)
+	if err != nil {
+		return errors.Errorf("marshal: %w", err)
 	}
 
 	tx := s.redis.TxPipeline()
@@ -98,16 +193,39 @@ func (s PeerStorage) add(ctx context.Context, associated []string, value storage
 		multierr.AppendInto(&rerr, tx.Close())
 	}()
 
-	if err := tx.Set(ctx, id, data, 0).Err(); err != nil {
+	if err := tx.Set(ctx, s.key(id), data, ttl).Err(); err != nil {
 		return errors.Errorf("set id <-> data: %w", err)
 	}
 
 	for _, key := range associated {
-		if err := tx.Set(ctx, key, id, 0).Err(); err != nil {
+		if err := tx.Set(ctx, s.key(key), id, ttl).Err(); err != nil {
 			return errors.Errorf("set key <-> id: %w", err)
 		}
 	}
 
+	if stored != nil {
+		// Remove keys of previous value, like old username.
+		for _, key := range storage.StaleKeys(*stored, append(value.Keys(), associated...)) {
+			// Delete associated key only if it still points to this peer.
+			if err := tx.Eval(ctx, deleteIfEqualScript, []string{s.key(key)}, id).Err(); err != nil {
+				return errors.Errorf("delete stale key: %w", err)
+			}
+		}
+		if username := storage.UsernameIndex(*stored); username != "" && username != storage.UsernameIndex(value) {
+			if err := tx.ZRem(ctx, s.key(usernamesKey), usernameMember(username, id)).Err(); err != nil {
+				return errors.Errorf("delete username: %w", err)
+			}
+		}
+	}
+	if err := tx.ZAdd(ctx, s.key(recentKey), touchMember(time.Now(), id)).Err(); err != nil {
+		return errors.Errorf("set recent: %w", err)
+	}
+	if username := storage.UsernameIndex(value); username != "" {
+		if err := tx.ZAdd(ctx, s.key(usernamesKey), &redis.Z{Member: usernameMember(username, id)}).Err(); err != nil {
+			return errors.Errorf("set username: %w", err)
+		}
+	}
+
 	if _, err := tx.Exec(ctx); err != nil {
 		return errors.Errorf("exec: %w", err)
 	}
@@ -117,38 +235,60 @@ func (s PeerStorage) add(ctx context.Context, associated []string, value storage
 
 // Add adds given peer to the storage.
 func (s PeerStorage) Add(ctx context.Context, value storage.Peer) error {
-	return s.add(ctx, value.Keys(), value)
+	return s.add(ctx, value.Keys(), value, 0)
 }
 
 // Find finds peer using given key.
 func (s PeerStorage) Find(ctx context.Context, key storage.PeerKey) (storage.Peer, error) {
-	id := key.String()
+	p, err := s.find(ctx, s.reader(), key.String())
+	if errors.Is(err, storage.ErrPeerNotFound) && s.read != nil {
+		// Replica may lag behind primary.
+		return s.find(ctx, s.redis, key.String())
+	}
+	return p, err
+}
 
-	data, err := s.redis.Get(ctx, id).Bytes()
+func (s PeerStorage) find(ctx context.Context, client redis.UniversalClient, id string) (storage.Peer, error) {
+	data, err := client.Get(ctx, s.key(id)).Bytes()
 	if err != nil {
 		if errors.Is(err, redis.Nil) {
 			return storage.Peer{}, storage.ErrPeerNotFound
 		}
-		return storage.Peer{}, errors.Errorf("get %q: %w", key, err)
+		return storage.Peer{}, errors.Errorf("get %q: %w", id, err)
 	}
 
 	var b storage.Peer
-	if err := json.Unmarshal(data, &b); err != nil {
+	if err := s.codec.Unmarshal(data, &b); err != nil {
+		if errors.Is(err, storage.ErrPeerUnmarshalMustInvalidate) {
+			return storage.Peer{}, storage.ErrPeerNotFound
+		}
 		return storage.Peer{}, errors.Errorf("unmarshal: %w", err)
 	}
+	if b.Expired(time.Now()) {
+		return storage.Peer{}, storage.ErrPeerNotFound
+	}
 
 	return b, nil
 }
 
 // Assign adds given peer to the storage and associate it to the given key.
 func (s PeerStorage) Assign(ctx context.Context, key string, value storage.Peer) (rerr error) {
-	return s.add(ctx, append(value.Keys(), key), value)
+	return s.add(ctx, append(value.Keys(), key), value, 0)
 }
 
 // Resolve finds peer using associated key.
 func (s PeerStorage) Resolve(ctx context.Context, key string) (storage.Peer, error) {
+	p, err := s.resolve(ctx, s.reader(), key)
+	if errors.Is(err, storage.ErrPeerNotFound) && s.read != nil {
+		// Replica may lag behind primary.
+		return s.resolve(ctx, s.redis, key)
+	}
+	return p, err
+}
+
+func (s PeerStorage) resolve(ctx context.Context, client redis.UniversalClient, key string) (storage.Peer, error) {
 	// Find id by domain.
-	id, err := s.redis.Get(ctx, key).Result()
+	id, err := client.Get(ctx, s.key(key)).Result()
 	if err != nil {
 		if errors.Is(err, redis.Nil) {
 			return storage.Peer{}, storage.ErrPeerNotFound
@@ -157,18 +297,5 @@ func (s PeerStorage) Resolve(ctx context.Context, key string) (storage.Peer, err
 	}
 
 	// Find object by id.
-	data, err := s.redis.Get(ctx, id).Bytes()
-	if err != nil {
-		if errors.Is(err, redis.Nil) {
-			return storage.Peer{}, storage.ErrPeerNotFound
-		}
-		return storage.Peer{}, errors.Errorf("get %q: %w", id, err)
-	}
-
-	var b storage.Peer
-	if err := json.Unmarshal(data, &b); err != nil {
-		return storage.Peer{}, errors.Errorf("unmarshal: %w", err)
-	}
-
-	return b, nil
+	return s.find(ctx, client, id)
 }
@@ -0,0 +1,20 @@
+package redis
+
+import (
+	"context"
+	"time"
+
+	"github.com/gotd/contrib/storage"
+)
+
+var _ storage.ExpiringPeerStorage = PeerStorage{}
+
+// AddTTL adds given peer to the storage. Peer expires after given ttl.
+//
+// Peer and its associated keys are stored with native Redis expiration.
+func (s PeerStorage) AddTTL(ctx context.Context, value storage.Peer, ttl time.Duration) error {
+	if ttl <= 0 {
+		ttl = 0
+	}
+	return s.add(ctx, value.Keys(), storage.WithTTL(value, time.Now(), ttl), ttl)
+}
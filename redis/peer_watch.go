@@ -0,0 +1,102 @@
+package redis
+
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	"github.com/go-faster/errors"
+	"github.com/go-redis/redis/v8"
+
+	"github.com/gotd/contrib/storage"
+)
+
+var _ storage.PeerWatcher = PeerStorage{}
+
+// Watch returns channel of peer changes using Redis keyspace notifications,
+// so changes made by all clients are observed.
+//
+// Keyspace notifications must be enabled on server, e.g. using
+// "CONFIG SET notify-keyspace-events K$gx". Redis does not distinguish new
+// and updated keys, so all writes are reported as storage.EventUpdate.
+//
+// Redis Cluster publishes notifications on node of changed key, so
+// namespaced storage subscribes to master of its slot.
+func (s PeerStorage) Watch(ctx context.Context) (<-chan storage.Event, error) {
+	node, err := s.node(ctx, s.redis)
+	if err != nil {
+		return nil, err
+	}
+	var db int
+	if c, ok := node.(*redis.Client); ok {
+		db = c.Options().DB
+	}
+
+	prefix := "__keyspace@" + strconv.Itoa(db) + "__:"
+	sub := node.PSubscribe(ctx, prefix+s.match(string(storage.PeerKeyPrefix)))
+	if _, err := sub.Receive(ctx); err != nil {
+		_ = sub.Close()
+		return nil, errors.Errorf("subscribe: %w", err)
+	}
+
+	ch := make(chan storage.Event, 64)
+	go func() {
+		defer close(ch)
+		defer func() {
+			_ = sub.Close()
+		}()
+
+		msgs := sub.Channel()
+		for {
+			var msg *redis.Message
+			select {
+			case <-ctx.Done():
+				return
+			case m, ok := <-msgs:
+				if !ok {
+					return
+				}
+				msg = m
+			}
+
+			e, ok := s.event(ctx, strings.TrimPrefix(msg.Channel, prefix), msg.Payload)
+			if !ok {
+				continue
+			}
+			select {
+			case ch <- e:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return ch, nil
+}
+
+// event creates event from keyspace notification.
+func (s PeerStorage) event(ctx context.Context, key, op string) (storage.Event, bool) {
+	var e storage.Event
+	if err := e.Key.Parse([]byte(strings.TrimPrefix(key, s.ns))); err != nil {
+		return storage.Event{}, false
+	}
+
+	switch op {
+	case "set":
+		data, err := s.redis.Get(ctx, key).Bytes()
+		if err != nil {
+			// Peer deleted before read, delete event follows.
+			return storage.Event{}, false
+		}
+		if err := s.codec.Unmarshal(data, &e.Peer); err != nil {
+			return storage.Event{}, false
+		}
+		e.Type = storage.EventUpdate
+	case "del", "expired":
+		e.Type = storage.EventDelete
+	default:
+		return storage.Event{}, false
+	}
+
+	return e, true
+}
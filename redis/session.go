@@ -16,7 +16,10 @@ type SessionStorage struct {
 }
 
 // NewSessionStorage creates new SessionStorage.
-func NewSessionStorage(client *redis.Client, key string) SessionStorage {
+//
+// Session is stored using single key, so any client, including Redis
+// Cluster client, can be used.
+func NewSessionStorage(client redis.UniversalClient, key string) SessionStorage {
 	s := redisClient{client: client}
 	return SessionStorage{
 		Session: kv.NewSession(s, key),
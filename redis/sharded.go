@@ -0,0 +1,224 @@
+package redis
+
+import (
+	"context"
+	"hash/crc32"
+	"sort"
+	"strconv"
+
+	"github.com/go-faster/errors"
+	"github.com/go-redis/redis/v8"
+
+	"github.com/gotd/contrib/storage"
+)
+
+// shardReplicas is a count of virtual nodes per shard on hash ring.
+const shardReplicas = 128
+
+// ring is a consistent hash ring.
+type ring struct {
+	hashes []uint32
+	shards []int
+}
+
+func newRing(names []string) ring {
+	var r ring
+	for i, name := range names {
+		for j := 0; j < shardReplicas; j++ {
+			r.hashes = append(r.hashes, crc32.ChecksumIEEE([]byte(name+"#"+strconv.Itoa(j))))
+			r.shards = append(r.shards, i)
+		}
+	}
+	sort.Sort(r)
+	return r
+}
+
+func (r ring) Len() int           { return len(r.hashes) }
+func (r ring) Less(i, j int) bool { return r.hashes[i] < r.hashes[j] }
+func (r ring) Swap(i, j int) {
+	r.hashes[i], r.hashes[j] = r.hashes[j], r.hashes[i]
+	r.shards[i], r.shards[j] = r.shards[j], r.shards[i]
+}
+
+// get returns shard index of given key.
+func (r ring) get(key string) int {
+	h := crc32.ChecksumIEEE([]byte(key))
+	idx := sort.Search(len(r.hashes), func(i int) bool {
+		return r.hashes[i] >= h
+	})
+	if idx == len(r.hashes) {
+		idx = 0
+	}
+	return r.shards[idx]
+}
+
+var _ storage.PeerStorage = (*ShardedPeerStorage)(nil)
+
+// ShardedPeerStorage is a peer storage which spreads peers across multiple
+// redis instances using consistent hashing.
+//
+// Peer is stored on shard selected by peer key, associated key is stored
+// on shard selected by associated key itself, so Resolve makes requests to
+// at most two shards. Iterate iterates over all shards in order.
+type ShardedPeerStorage struct {
+	shards []PeerStorage
+	ring   ring
+}
+
+// NewShardedPeerStorage creates new sharded peer storage using given
+// clients. Shards are identified by client address and database, so the
+// order of clients does not matter and adding a shard moves only part of
+// keys.
+func NewShardedPeerStorage(clients ...*redis.Client) *ShardedPeerStorage {
+	s := &ShardedPeerStorage{}
+	names := make([]string, 0, len(clients))
+	for _, client := range clients {
+		opts := client.Options()
+		names = append(names, opts.Addr+"/"+strconv.Itoa(opts.DB))
+		s.shards = append(s.shards, *NewPeerStorage(client))
+	}
+	s.ring = newRing(names)
+	return s
+}
+
+// WithCodec sets codec of persisted peers. Default is storage.JSONCodec.
+func (s *ShardedPeerStorage) WithCodec(c storage.Codec) *ShardedPeerStorage {
+	for i := range s.shards {
+		s.shards[i].codec = c
+	}
+	return s
+}
+
+// WithNamespace sets namespace of stored peers on all shards.
+//
+// See PeerStorage.WithNamespace.
+func (s *ShardedPeerStorage) WithNamespace(ns string) *ShardedPeerStorage {
+	for i := range s.shards {
+		s.shards[i].WithNamespace(ns)
+	}
+	return s
+}
+
+func (s *ShardedPeerStorage) shard(key string) PeerStorage {
+	return s.shards[s.ring.get(key)]
+}
+
+func (s *ShardedPeerStorage) add(ctx context.Context, associated []string, value storage.Peer) error {
+	id := storage.KeyFromPeer(value).String()
+	primary := s.shard(id)
+
+	stored, err := primary.find(ctx, primary.redis, id)
+	switch {
+	case err == nil:
+	case errors.Is(err, storage.ErrPeerNotFound), errors.Is(err, storage.ErrPeerUnmarshalMustInvalidate):
+		stored = storage.Peer{}
+	default:
+		return err
+	}
+
+	// Associated keys are stored on their own shards.
+	if err := primary.add(ctx, nil, value, 0); err != nil {
+		return err
+	}
+	for _, key := range associated {
+		shard := s.shard(key)
+		if err := shard.redis.Set(ctx, shard.key(key), id, 0).Err(); err != nil {
+			return errors.Errorf("set key <-> id: %w", err)
+		}
+	}
+	for _, key := range storage.StaleKeys(stored, append(value.Keys(), associated...)) {
+		// Delete associated key only if it still points to this peer.
+		shard := s.shard(key)
+		if err := shard.redis.Eval(ctx, deleteIfEqualScript, []string{shard.key(key)}, id).Err(); err != nil {
+			return errors.Errorf("delete stale key: %w", err)
+		}
+	}
+	return nil
+}
+
+// Add adds given peer to the storage.
+func (s *ShardedPeerStorage) Add(ctx context.Context, value storage.Peer) error {
+	return s.add(ctx, value.Keys(), value)
+}
+
+// Find finds peer using given key.
+func (s *ShardedPeerStorage) Find(ctx context.Context, key storage.PeerKey) (storage.Peer, error) {
+	id := key.String()
+	return s.shard(id).Find(ctx, key)
+}
+
+// Assign adds given peer to the storage and associate it to the given key.
+func (s *ShardedPeerStorage) Assign(ctx context.Context, key string, value storage.Peer) error {
+	return s.add(ctx, append(value.Keys(), key), value)
+}
+
+// Resolve finds peer using associated key.
+func (s *ShardedPeerStorage) Resolve(ctx context.Context, key string) (storage.Peer, error) {
+	shard := s.shard(key)
+	id, err := shard.redis.Get(ctx, shard.key(key)).Result()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return storage.Peer{}, storage.ErrPeerNotFound
+		}
+		return storage.Peer{}, errors.Errorf("get %q: %w", key, err)
+	}
+	primary := s.shard(id)
+	return primary.find(ctx, primary.redis, id)
+}
+
+// Iterate creates and returns new PeerIterator over all shards.
+func (s *ShardedPeerStorage) Iterate(ctx context.Context) (storage.PeerIterator, error) {
+	return &shardedIterator{shards: s.shards}, nil
+}
+
+type shardedIterator struct {
+	shards []PeerStorage
+	iter   storage.PeerIterator
+	err    error
+}
+
+func (i *shardedIterator) Next(ctx context.Context) bool {
+	for {
+		if i.iter == nil {
+			if len(i.shards) == 0 || i.err != nil {
+				return false
+			}
+			iter, err := i.shards[0].Iterate(ctx)
+			if err != nil {
+				i.err = errors.Errorf("iterate: %w", err)
+				return false
+			}
+			i.iter, i.shards = iter, i.shards[1:]
+		}
+		if i.iter.Next(ctx) {
+			return true
+		}
+		if err := i.iter.Err(); err != nil {
+			i.err = err
+			return false
+		}
+		if err := i.iter.Close(); err != nil {
+			i.err = err
+			return false
+		}
+		i.iter = nil
+	}
+}
+
+func (i *shardedIterator) Err() error {
+	return i.err
+}
+
+func (i *shardedIterator) Value() storage.Peer {
+	if i.iter == nil {
+		return storage.Peer{}
+	}
+	return i.iter.Value()
+}
+
+func (i *shardedIterator) Close() error {
+	if i.iter == nil {
+		return nil
+	}
+	return i.iter.Close()
+}
@@ -0,0 +1,41 @@
+package redis
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRing(t *testing.T) {
+	a := require.New(t)
+	names := []string{"a:6379/0", "b:6379/0", "c:6379/0"}
+	r := newRing(names)
+
+	const total = 3000
+	counts := make([]int, len(names))
+	placed := make([]string, total)
+	for i := 0; i < total; i++ {
+		key := "key" + strconv.Itoa(i)
+		idx := r.get(key)
+		counts[idx]++
+		placed[i] = names[idx]
+	}
+	for _, c := range counts {
+		a.Greater(c, total/6, "keys should be spread across shards")
+	}
+
+	// Adding shard moves keys only to new shard.
+	grown := newRing(append(names, "d:6379/0"))
+	grownNames := append(names, "d:6379/0")
+	var moved int
+	for i := 0; i < total; i++ {
+		name := grownNames[grown.get("key"+strconv.Itoa(i))]
+		if name == placed[i] {
+			continue
+		}
+		a.Equal("d:6379/0", name)
+		moved++
+	}
+	a.Less(moved, total/2)
+}
@@ -0,0 +1,2 @@
+// Package reload implements runtime configuration reload on signal.
+package reload
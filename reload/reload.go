@@ -0,0 +1,85 @@
+package reload
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"go.uber.org/zap"
+)
+
+// Func applies reloaded configuration, e.g. by re-reading config file and
+// calling ratelimit.RateLimiter.SetLimit.
+type Func func(ctx context.Context) error
+
+// Reloader calls Func on signal or on Trigger.
+type Reloader struct {
+	reload  Func
+	signals []os.Signal
+	log     *zap.Logger
+	trigger chan struct{}
+}
+
+// New creates new Reloader. By default, reload is done on SIGHUP.
+func New(f Func) *Reloader {
+	return &Reloader{
+		reload:  f,
+		signals: []os.Signal{syscall.SIGHUP},
+		log:     zap.NewNop(),
+		trigger: make(chan struct{}, 1),
+	}
+}
+
+// WithSignals sets signals which trigger reload.
+// No signals means reload only on Trigger.
+func (r *Reloader) WithSignals(signals ...os.Signal) *Reloader {
+	r.signals = signals
+	return r
+}
+
+// WithLog sets logger.
+func (r *Reloader) WithLog(log *zap.Logger) *Reloader {
+	r.log = log
+	return r
+}
+
+// Trigger requests reload, e.g. when config source watcher observes
+// change. It does not block: triggers received while reload is pending
+// are coalesced.
+func (r *Reloader) Trigger() {
+	select {
+	case r.trigger <- struct{}{}:
+	default:
+	}
+}
+
+// Run waits for reload requests and calls Func until context is done.
+//
+// Reload errors are logged and do not stop Run, so running client keeps
+// previous configuration.
+func (r *Reloader) Run(ctx context.Context) error {
+	var sig chan os.Signal
+	if len(r.signals) > 0 {
+		sig = make(chan os.Signal, 1)
+		signal.Notify(sig, r.signals...)
+		defer signal.Stop(sig)
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case s := <-sig:
+			r.log.Info("Reloading configuration", zap.Stringer("signal", s))
+		case <-r.trigger:
+			r.log.Info("Reloading configuration")
+		}
+
+		if err := r.reload(ctx); err != nil {
+			r.log.Error("Reload configuration", zap.Error(err))
+			continue
+		}
+		r.log.Info("Configuration reloaded")
+	}
+}
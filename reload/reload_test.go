@@ -0,0 +1,38 @@
+package reload
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-faster/errors"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReloader(t *testing.T) {
+	a := require.New(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	calls := make(chan int, 10)
+	var n int
+	r := New(func(ctx context.Context) error {
+		n++
+		calls <- n
+		if n == 1 {
+			return errors.New("bad config")
+		}
+		return nil
+	}).WithSignals()
+
+	done := make(chan error, 1)
+	go func() { done <- r.Run(ctx) }()
+
+	// Failed reload does not stop Run.
+	r.Trigger()
+	a.Equal(1, <-calls)
+	r.Trigger()
+	a.Equal(2, <-calls)
+
+	cancel()
+	a.ErrorIs(<-done, context.Canceled)
+}
@@ -0,0 +1,116 @@
+package resolver
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/go-faster/errors"
+	"go.uber.org/multierr"
+
+	"github.com/gotd/td/telegram/message/peer"
+	"github.com/gotd/td/tg"
+)
+
+var _ peer.Resolver = (*Chained)(nil)
+
+type named struct {
+	peer.Resolver
+	name string
+}
+
+// Named wraps given resolver to report it with given name.
+func Named(name string, r peer.Resolver) peer.Resolver {
+	return named{Resolver: r, name: name}
+}
+
+func nameOf(r peer.Resolver) string {
+	if n, ok := r.(named); ok {
+		return n.name
+	}
+	return fmt.Sprintf("%T", r)
+}
+
+// Result describes successful resolution.
+type Result struct {
+	// Key is resolved domain or phone.
+	Key string
+	// Source is name of resolver which answered, see Named.
+	// Default name is type of resolver.
+	Source string
+	// Index is index of resolver which answered.
+	Index int
+	// Errors are errors returned by preceding resolvers.
+	Errors []error
+}
+
+// Chained is a peer.Resolver which tries multiple resolvers in order.
+type Chained struct {
+	resolvers []peer.Resolver
+	onResolve func(ctx context.Context, r Result)
+}
+
+// Chain creates new Chained from given resolvers, ordered from the
+// cheapest one, e.g.:
+//
+//	resolver.Chain(
+//		resolver.Named("storage", storageResolver),
+//		resolver.Named("api", peer.Plain(raw)),
+//	)
+//
+// Every error, except context cancellation, makes chain try next resolver.
+// If all resolvers fail, errors are combined.
+func Chain(resolvers ...peer.Resolver) *Chained {
+	return &Chained{
+		resolvers: resolvers,
+		onResolve: func(ctx context.Context, r Result) {},
+	}
+}
+
+// OnResolve sets callback called on every successful resolution,
+// e.g. to log or count which source answered.
+func (c *Chained) OnResolve(f func(ctx context.Context, r Result)) *Chained {
+	c.onResolve = f
+	return c
+}
+
+func (c *Chained) resolve(
+	ctx context.Context, key string,
+	f func(ctx context.Context, r peer.Resolver) (tg.InputPeerClass, error),
+) (tg.InputPeerClass, error) {
+	if len(c.resolvers) == 0 {
+		return nil, errors.Errorf("resolve %q: no resolvers", key)
+	}
+
+	var errs []error
+	for i, r := range c.resolvers {
+		p, err := f(ctx, r)
+		if err == nil {
+			c.onResolve(ctx, Result{
+				Key:    key,
+				Source: nameOf(r),
+				Index:  i,
+				Errors: errs,
+			})
+			return p, nil
+		}
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return nil, ctxErr
+		}
+		errs = append(errs, errors.Errorf("%s: %w", nameOf(r), err))
+	}
+	return nil, errors.Errorf("resolve %q: %w", key, multierr.Combine(errs...))
+}
+
+// ResolveDomain implements peer.Resolver.
+func (c *Chained) ResolveDomain(ctx context.Context, domain string) (tg.InputPeerClass, error) {
+	return c.resolve(ctx, domain, func(ctx context.Context, r peer.Resolver) (tg.InputPeerClass, error) {
+		return r.ResolveDomain(ctx, domain)
+	})
+}
+
+// ResolvePhone implements peer.Resolver.
+func (c *Chained) ResolvePhone(ctx context.Context, phone string) (tg.InputPeerClass, error) {
+	return c.resolve(ctx, phone, func(ctx context.Context, r peer.Resolver) (tg.InputPeerClass, error) {
+		return r.ResolvePhone(ctx, phone)
+	})
+}
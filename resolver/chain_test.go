@@ -0,0 +1,99 @@
+package resolver
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-faster/errors"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/multierr"
+
+	"github.com/gotd/td/tg"
+)
+
+type resolverFunc func(ctx context.Context, key string) (tg.InputPeerClass, error)
+
+func (r resolverFunc) ResolveDomain(ctx context.Context, domain string) (tg.InputPeerClass, error) {
+	return r(ctx, domain)
+}
+
+func (r resolverFunc) ResolvePhone(ctx context.Context, phone string) (tg.InputPeerClass, error) {
+	return r(ctx, phone)
+}
+
+func TestChain(t *testing.T) {
+	a := require.New(t)
+	ctx := context.Background()
+	testErr := errors.New("not found")
+	expected := &tg.InputPeerUser{UserID: 10, AccessHash: 10}
+
+	var calls []string
+	failing := resolverFunc(func(ctx context.Context, key string) (tg.InputPeerClass, error) {
+		calls = append(calls, "failing")
+		return nil, testErr
+	})
+	ok := resolverFunc(func(ctx context.Context, key string) (tg.InputPeerClass, error) {
+		calls = append(calls, "ok")
+		return expected, nil
+	})
+
+	var results []Result
+	c := Chain(Named("storage", failing), ok, failing).
+		OnResolve(func(ctx context.Context, r Result) {
+			results = append(results, r)
+		})
+
+	p, err := c.ResolveDomain(ctx, "abc")
+	a.NoError(err)
+	a.Equal(expected, p)
+	p, err = c.ResolvePhone(ctx, "123")
+	a.NoError(err)
+	a.Equal(expected, p)
+	a.Equal([]string{"failing", "ok", "failing", "ok"}, calls)
+
+	a.Len(results, 2)
+	a.Equal("abc", results[0].Key)
+	a.Equal("123", results[1].Key)
+	for _, r := range results {
+		a.Equal("resolver.resolverFunc", r.Source)
+		a.Equal(1, r.Index)
+		a.Len(r.Errors, 1)
+		a.ErrorIs(r.Errors[0], testErr)
+		a.Contains(r.Errors[0].Error(), "storage")
+	}
+}
+
+func TestChainErrors(t *testing.T) {
+	ctx := context.Background()
+	first, second := errors.New("first"), errors.New("second")
+	failing := func(e error) resolverFunc {
+		return func(ctx context.Context, key string) (tg.InputPeerClass, error) {
+			return nil, e
+		}
+	}
+
+	t.Run("All", func(t *testing.T) {
+		a := require.New(t)
+		_, err := Chain(failing(first), failing(second)).ResolveDomain(ctx, "abc")
+		a.ErrorIs(err, first)
+		a.ErrorIs(err, second)
+		a.Len(multierr.Errors(errors.Unwrap(err)), 2)
+	})
+	t.Run("Empty", func(t *testing.T) {
+		_, err := Chain().ResolveDomain(ctx, "abc")
+		require.Error(t, err)
+	})
+	t.Run("Canceled", func(t *testing.T) {
+		a := require.New(t)
+		ctx, cancel := context.WithCancel(ctx)
+		calls := 0
+		r := resolverFunc(func(ctx context.Context, key string) (tg.InputPeerClass, error) {
+			calls++
+			cancel()
+			return nil, ctx.Err()
+		})
+		_, err := Chain(r, r).ResolveDomain(ctx, "abc")
+		a.ErrorIs(err, context.Canceled)
+		a.Equal(1, calls)
+	})
+}
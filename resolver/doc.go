@@ -0,0 +1,4 @@
+// Package resolver implements helpers to compose peer.Resolver,
+// e.g. to try peer storage, contacts and API in order, and to resolve
+// t.me links.
+package resolver
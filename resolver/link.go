@@ -0,0 +1,213 @@
+package resolver
+
+import (
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/go-faster/errors"
+)
+
+// LinkType is a type of Telegram link.
+type LinkType int
+
+const (
+	// LinkUsername is a public link, e.g. t.me/username.
+	LinkUsername LinkType = iota + 1
+	// LinkInvite is an invite link, e.g. t.me/+hash or t.me/joinchat/hash.
+	LinkInvite
+	// LinkPrivate is a private post link, e.g. t.me/c/1234/5.
+	LinkPrivate
+	// LinkPhone is a phone link, e.g. t.me/+79001234567.
+	LinkPhone
+)
+
+// String implements fmt.Stringer.
+func (t LinkType) String() string {
+	switch t {
+	case LinkUsername:
+		return "username"
+	case LinkInvite:
+		return "invite"
+	case LinkPrivate:
+		return "private"
+	case LinkPhone:
+		return "phone"
+	default:
+		return "LinkType(" + strconv.Itoa(int(t)) + ")"
+	}
+}
+
+// Link is a parsed Telegram link.
+type Link struct {
+	Type LinkType
+	// Username is a username of LinkUsername.
+	Username string
+	// Hash is an invite hash of LinkInvite.
+	Hash string
+	// ChannelID is a channel ID of LinkPrivate.
+	ChannelID int64
+	// Phone is a phone number of LinkPhone, without "+".
+	Phone string
+	// MessageID is an optional message ID of post link.
+	MessageID int
+}
+
+// ErrUnsupportedLink is returned by ParseLink if link is a valid Telegram
+// link, which does not point to peer, e.g. sticker set link.
+var ErrUnsupportedLink = errors.New("unsupported link")
+
+var usernameRegexp = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9_]{3,31}$`) // nolint:gochecknoglobals
+
+// reservedPaths are t.me paths which do not point to peer.
+var reservedPaths = map[string]struct{}{ // nolint:gochecknoglobals
+	"addemoji":     {},
+	"addlist":      {},
+	"addstickers":  {},
+	"addtheme":     {},
+	"boost":        {},
+	"confirmphone": {},
+	"iv":           {},
+	"login":        {},
+	"proxy":        {},
+	"setlanguage":  {},
+	"share":        {},
+	"socks":        {},
+}
+
+func parseMessageID(s string) (int, error) {
+	if s == "" {
+		return 0, nil
+	}
+	id, err := strconv.Atoi(s)
+	if err != nil || id <= 0 {
+		return 0, errors.Errorf("invalid message ID %q", s)
+	}
+	return id, nil
+}
+
+func parseUsername(username, post string) (Link, error) {
+	if !usernameRegexp.MatchString(username) {
+		return Link{}, errors.Errorf("invalid username %q", username)
+	}
+	msgID, err := parseMessageID(post)
+	if err != nil {
+		return Link{}, err
+	}
+	return Link{Type: LinkUsername, Username: username, MessageID: msgID}, nil
+}
+
+func parseInvite(hash string) (Link, error) {
+	if hash == "" {
+		return Link{}, errors.New("empty invite hash")
+	}
+	return Link{Type: LinkInvite, Hash: hash}, nil
+}
+
+func isDigits(s string) bool {
+	return s != "" && strings.Trim(s, "0123456789") == ""
+}
+
+func parsePhone(phone string) (Link, error) {
+	// Unescaped "+" in query is decoded as space.
+	phone = strings.TrimLeft(phone, "+ ")
+	if !isDigits(phone) {
+		return Link{}, errors.Errorf("invalid phone %q", phone)
+	}
+	return Link{Type: LinkPhone, Phone: phone}, nil
+}
+
+func parsePrivate(channel, post string) (Link, error) {
+	id, err := strconv.ParseInt(channel, 10, 64)
+	if err != nil || id <= 0 {
+		return Link{}, errors.Errorf("invalid channel ID %q", channel)
+	}
+	msgID, err := parseMessageID(post)
+	if err != nil {
+		return Link{}, err
+	}
+	return Link{Type: LinkPrivate, ChannelID: id, MessageID: msgID}, nil
+}
+
+// parseDeeplink parses tg:// link.
+func parseDeeplink(u *url.URL) (Link, error) {
+	q := u.Query()
+	switch u.Host {
+	case "resolve":
+		if phone := q.Get("phone"); phone != "" {
+			return parsePhone(phone)
+		}
+		return parseUsername(q.Get("domain"), q.Get("post"))
+	case "join":
+		return parseInvite(q.Get("invite"))
+	case "privatepost":
+		return parsePrivate(q.Get("channel"), q.Get("post"))
+	default:
+		return Link{}, ErrUnsupportedLink
+	}
+}
+
+// ParseLink parses Telegram link to peer:
+//
+//	t.me/username, t.me/username/123, t.me/s/username
+//	t.me/+hash, t.me/joinchat/hash
+//	t.me/+79001234567
+//	t.me/c/1234, t.me/c/1234/5
+//	tg://resolve?domain=username, tg://resolve?phone=79001234567
+//	tg://join?invite=hash
+//	tg://privatepost?channel=1234&post=5
+//
+// Scheme is optional, telegram.me and telegram.dog hosts are also accepted.
+func ParseLink(link string) (Link, error) {
+	link = strings.TrimSpace(link)
+	if !strings.Contains(link, "://") {
+		link = "https://" + link
+	}
+	u, err := url.Parse(link)
+	if err != nil {
+		return Link{}, errors.Errorf("parse url: %w", err)
+	}
+
+	switch strings.ToLower(u.Scheme) {
+	case "tg":
+		return parseDeeplink(u)
+	case "http", "https":
+	default:
+		return Link{}, errors.Errorf("invalid scheme %q", u.Scheme)
+	}
+	switch strings.ToLower(u.Hostname()) {
+	case "t.me", "telegram.me", "telegram.dog":
+	default:
+		return Link{}, errors.Errorf("invalid host %q", u.Host)
+	}
+
+	parts := strings.Split(strings.Trim(u.Path, "/"), "/")
+	first, arg := parts[0], ""
+	if len(parts) > 1 {
+		arg = parts[1]
+	}
+	switch {
+	case first == "":
+		return Link{}, errors.New("empty path")
+	case strings.HasPrefix(first, "+"):
+		if v := first[1:]; isDigits(v) {
+			return parsePhone(v)
+		}
+		return parseInvite(first[1:])
+	case first == "joinchat":
+		return parseInvite(arg)
+	case first == "c":
+		post := ""
+		if len(parts) > 2 {
+			post = parts[2]
+		}
+		return parsePrivate(arg, post)
+	case first == "s":
+		return parseUsername(arg, "")
+	}
+	if _, ok := reservedPaths[strings.ToLower(first)]; ok {
+		return Link{}, ErrUnsupportedLink
+	}
+	return parseUsername(first, arg)
+}
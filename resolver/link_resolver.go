@@ -0,0 +1,142 @@
+package resolver
+
+import (
+	"context"
+
+	"github.com/go-faster/errors"
+
+	"github.com/gotd/td/telegram/query/dialogs"
+	"github.com/gotd/td/tg"
+
+	"github.com/gotd/contrib/storage"
+)
+
+// ErrNotJoined is returned by LinkResolver, if invite link points to chat
+// which is not joined, so chat ID and access hash are not available.
+var ErrNotJoined = errors.New("chat is not joined")
+
+// LinkResolver resolves Telegram links to peers, see ParseLink.
+//
+// Peers are looked up in storage first. Resolved peers are added to
+// storage, so subsequent resolutions do not call API.
+type LinkResolver struct {
+	raw     *tg.Client
+	storage storage.PeerStorage
+}
+
+// NewLinkResolver creates new LinkResolver.
+func NewLinkResolver(raw *tg.Client, s storage.PeerStorage) *LinkResolver {
+	return &LinkResolver{raw: raw, storage: s}
+}
+
+// Resolve parses and resolves given link.
+func (r *LinkResolver) Resolve(ctx context.Context, link string) (storage.Peer, error) {
+	l, err := ParseLink(link)
+	if err != nil {
+		return storage.Peer{}, errors.Errorf("parse %q: %w", link, err)
+	}
+	return r.ResolveLink(ctx, l)
+}
+
+// ResolveLink resolves given parsed link.
+//
+// Private post links do not contain access hash, so channel must be
+// already stored, e.g. by storage.Prefetch. Otherwise, storage.ErrPeerNotFound
+// is returned.
+func (r *LinkResolver) ResolveLink(ctx context.Context, l Link) (storage.Peer, error) {
+	switch l.Type {
+	case LinkUsername:
+		return r.resolveKey(ctx, l.Username, func(ctx context.Context) (*tg.ContactsResolvedPeer, error) {
+			return r.raw.ContactsResolveUsername(ctx, l.Username)
+		})
+	case LinkPhone:
+		return r.resolveKey(ctx, storage.PhoneKey(l.Phone), func(ctx context.Context) (*tg.ContactsResolvedPeer, error) {
+			return r.raw.ContactsResolvePhone(ctx, l.Phone)
+		})
+	case LinkInvite:
+		return r.resolveInvite(ctx, l.Hash)
+	case LinkPrivate:
+		p, err := r.storage.Find(ctx, storage.PeerKey{Kind: dialogs.Channel, ID: l.ChannelID})
+		if err != nil {
+			return storage.Peer{}, errors.Errorf("find channel %d: %w", l.ChannelID, err)
+		}
+		return p, nil
+	default:
+		return storage.Peer{}, errors.Errorf("unknown link type %s", l.Type)
+	}
+}
+
+func (r *LinkResolver) resolveKey(
+	ctx context.Context, key string,
+	f func(ctx context.Context) (*tg.ContactsResolvedPeer, error),
+) (storage.Peer, error) {
+	p, err := r.storage.Resolve(ctx, key)
+	switch {
+	case err == nil:
+		return p, nil
+	case !errors.Is(err, storage.ErrPeerNotFound):
+		return storage.Peer{}, errors.Errorf("resolve %q: %w", key, err)
+	}
+
+	resolved, err := f(ctx)
+	if err != nil {
+		return storage.Peer{}, errors.Errorf("resolve %q: %w", key, err)
+	}
+	p, err = findPeer(resolved.Peer, resolved.Users, resolved.Chats)
+	if err != nil {
+		return storage.Peer{}, err
+	}
+	if err := r.storage.Assign(ctx, key, p); err != nil {
+		return storage.Peer{}, errors.Errorf("assign %q: %w", key, err)
+	}
+	return p, nil
+}
+
+func (r *LinkResolver) resolveInvite(ctx context.Context, hash string) (storage.Peer, error) {
+	invite, err := r.raw.MessagesCheckChatInvite(ctx, hash)
+	if err != nil {
+		return storage.Peer{}, errors.Errorf("check invite: %w", err)
+	}
+
+	var chat tg.ChatClass
+	switch invite := invite.(type) {
+	case *tg.ChatInviteAlready:
+		chat = invite.Chat
+	case *tg.ChatInvitePeek:
+		chat = invite.Chat
+	case *tg.ChatInvite:
+		return storage.Peer{}, ErrNotJoined
+	default:
+		return storage.Peer{}, errors.Errorf("unexpected type %T", invite)
+	}
+
+	var p storage.Peer
+	if !p.FromChat(chat) {
+		return storage.Peer{}, errors.Errorf("unexpected chat %T", chat)
+	}
+	if err := r.storage.Add(ctx, p); err != nil {
+		return storage.Peer{}, errors.Errorf("add %s: %w", p, err)
+	}
+	return p, nil
+}
+
+// findPeer finds entity of given peer.
+func findPeer(target tg.PeerClass, users []tg.UserClass, chats []tg.ChatClass) (storage.Peer, error) {
+	var key dialogs.DialogKey
+	if err := key.FromPeer(target); err != nil {
+		return storage.Peer{}, errors.Errorf("unpack peer: %w", err)
+	}
+
+	var p storage.Peer
+	for _, user := range users {
+		if p.FromUser(user) && p.Key.Kind == key.Kind && p.Key.ID == key.ID {
+			return p, nil
+		}
+	}
+	for _, chat := range chats {
+		if p.FromChat(chat) && p.Key.Kind == key.Kind && p.Key.ID == key.ID {
+			return p, nil
+		}
+	}
+	return storage.Peer{}, errors.Errorf("entity of %v not found", target)
+}
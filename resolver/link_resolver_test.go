@@ -0,0 +1,78 @@
+package resolver
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/gotd/td/telegram/query/dialogs"
+	"github.com/gotd/td/tg"
+	"github.com/gotd/td/tgmock"
+
+	"github.com/gotd/contrib/storage"
+	"github.com/gotd/contrib/storage/storagetest"
+)
+
+func TestLinkResolver(t *testing.T) {
+	a := require.New(t)
+	ctx := context.Background()
+	mock := tgmock.New(t)
+	s := storagetest.NewMemory()
+	r := NewLinkResolver(tg.NewClient(mock), s)
+
+	mock.ExpectCall(&tg.ContactsResolveUsernameRequest{
+		Username: "durov",
+	}).ThenResult(&tg.ContactsResolvedPeer{
+		Peer: &tg.PeerChannel{ChannelID: 10},
+		Chats: []tg.ChatClass{
+			&tg.Channel{ID: 10, AccessHash: 10, Username: "durov", Photo: &tg.ChatPhotoEmpty{}},
+		},
+	})
+	p, err := r.Resolve(ctx, "https://t.me/durov/1")
+	a.NoError(err)
+	a.Equal(storage.PeerKey{Kind: dialogs.Channel, ID: 10}, storage.KeyFromPeer(p))
+	a.Equal(int64(10), p.Key.AccessHash)
+
+	// Stored peer is used.
+	p, err = r.Resolve(ctx, "t.me/durov")
+	a.NoError(err)
+	a.Equal(int64(10), p.Key.ID)
+
+	// Private link of stored channel.
+	p, err = r.Resolve(ctx, "https://t.me/c/10/5")
+	a.NoError(err)
+	a.Equal(int64(10), p.Key.AccessHash)
+	_, err = r.Resolve(ctx, "https://t.me/c/11/5")
+	a.ErrorIs(err, storage.ErrPeerNotFound)
+
+	mock.ExpectCall(&tg.ContactsResolvePhoneRequest{
+		Phone: "79001234567",
+	}).ThenResult(&tg.ContactsResolvedPeer{
+		Peer:  &tg.PeerUser{UserID: 20},
+		Users: []tg.UserClass{&tg.User{ID: 20, AccessHash: 20}},
+	})
+	p, err = r.Resolve(ctx, "https://t.me/+79001234567")
+	a.NoError(err)
+	a.Equal(storage.PeerKey{Kind: dialogs.User, ID: 20}, storage.KeyFromPeer(p))
+	p, err = s.Resolve(ctx, storage.PhoneKey("79001234567"))
+	a.NoError(err)
+	a.Equal(int64(20), p.Key.ID)
+
+	mock.ExpectCall(&tg.MessagesCheckChatInviteRequest{
+		Hash: "AbCdEf",
+	}).ThenResult(&tg.ChatInviteAlready{
+		Chat: &tg.Channel{ID: 30, AccessHash: 30, Photo: &tg.ChatPhotoEmpty{}},
+	})
+	p, err = r.Resolve(ctx, "https://t.me/+AbCdEf")
+	a.NoError(err)
+	a.Equal(int64(30), p.Key.ID)
+	_, err = s.Find(ctx, storage.PeerKey{Kind: dialogs.Channel, ID: 30})
+	a.NoError(err)
+
+	mock.ExpectCall(&tg.MessagesCheckChatInviteRequest{
+		Hash: "NotJoined",
+	}).ThenResult(&tg.ChatInvite{Title: "chat", Photo: &tg.PhotoEmpty{}})
+	_, err = r.Resolve(ctx, "https://t.me/joinchat/NotJoined")
+	a.ErrorIs(err, ErrNotJoined)
+}
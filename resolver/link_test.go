@@ -0,0 +1,61 @@
+package resolver
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseLink(t *testing.T) {
+	for _, tt := range []struct {
+		Link   string
+		Result Link
+	}{
+		{"t.me/durov", Link{Type: LinkUsername, Username: "durov"}},
+		{"https://t.me/durov/10", Link{Type: LinkUsername, Username: "durov", MessageID: 10}},
+		{"http://telegram.me/s/durov", Link{Type: LinkUsername, Username: "durov"}},
+		{"  https://T.me/durov/  ", Link{Type: LinkUsername, Username: "durov"}},
+		{"https://t.me/+AbCdEf_123", Link{Type: LinkInvite, Hash: "AbCdEf_123"}},
+		{"https://t.me/joinchat/AbCdEf", Link{Type: LinkInvite, Hash: "AbCdEf"}},
+		{"https://t.me/+79001234567", Link{Type: LinkPhone, Phone: "79001234567"}},
+		{"https://t.me/c/1234", Link{Type: LinkPrivate, ChannelID: 1234}},
+		{"https://t.me/c/1234/5", Link{Type: LinkPrivate, ChannelID: 1234, MessageID: 5}},
+		{"tg://resolve?domain=durov&post=3", Link{Type: LinkUsername, Username: "durov", MessageID: 3}},
+		{"tg://resolve?phone=+79001234567", Link{Type: LinkPhone, Phone: "79001234567"}},
+		{"tg://join?invite=AbCdEf", Link{Type: LinkInvite, Hash: "AbCdEf"}},
+		{"tg://privatepost?channel=1234&post=5", Link{Type: LinkPrivate, ChannelID: 1234, MessageID: 5}},
+	} {
+		t.Run(tt.Link, func(t *testing.T) {
+			l, err := ParseLink(tt.Link)
+			require.NoError(t, err)
+			require.Equal(t, tt.Result, l)
+		})
+	}
+
+	for _, link := range []string{
+		"https://t.me/addstickers/Animals",
+		"tg://addstickers?set=Animals",
+	} {
+		t.Run(link, func(t *testing.T) {
+			_, err := ParseLink(link)
+			require.ErrorIs(t, err, ErrUnsupportedLink)
+		})
+	}
+
+	for _, link := range []string{
+		"",
+		"https://example.com/durov",
+		"ftp://t.me/durov",
+		"https://t.me/",
+		"https://t.me/a",
+		"https://t.me/durov/abc",
+		"https://t.me/joinchat/",
+		"https://t.me/c/abc/1",
+		"https://t.me/c/-1/1",
+	} {
+		t.Run(link, func(t *testing.T) {
+			_, err := ParseLink(link)
+			require.Error(t, err)
+		})
+	}
+}
@@ -0,0 +1,104 @@
+package resolver
+
+import (
+	"context"
+
+	"github.com/go-faster/errors"
+
+	"github.com/gotd/td/telegram/message/peer"
+	"github.com/gotd/td/tg"
+	"github.com/gotd/td/tgerr"
+
+	"github.com/gotd/contrib/storage"
+)
+
+var _ peer.Resolver = (*Phone)(nil)
+
+// Phone is a peer.Resolver which resolves phones using contacts.resolvePhone
+// and, optionally, contacts.importContacts. Domains are resolved using
+// peer.Plain.
+//
+// Use storage.ResolverCache on top of Phone to cache results under
+// storage.PhoneKey:
+//
+//	storage.NewResolverCache(resolver.NewPhone(raw), s)
+type Phone struct {
+	raw            *tg.Client
+	domain         peer.Resolver
+	importContacts bool
+}
+
+// NewPhone creates new Phone.
+func NewPhone(raw *tg.Client) *Phone {
+	return &Phone{
+		raw:    raw,
+		domain: peer.Plain(raw),
+	}
+}
+
+// WithImport enables fallback to contacts.importContacts, if phone is not
+// resolvable, e.g. due to privacy settings. Imported contact is deleted
+// right after, so contact list is not changed.
+//
+// Contact is imported only if contacts.resolvePhone fails, so existing
+// contacts, which are always resolvable, are not deleted. Import is
+// available only for user accounts.
+func (p *Phone) WithImport(enabled bool) *Phone {
+	p.importContacts = enabled
+	return p
+}
+
+// ResolveDomain implements peer.Resolver.
+func (p *Phone) ResolveDomain(ctx context.Context, domain string) (tg.InputPeerClass, error) {
+	return p.domain.ResolveDomain(ctx, domain)
+}
+
+// ResolvePhone implements peer.Resolver.
+func (p *Phone) ResolvePhone(ctx context.Context, phone string) (tg.InputPeerClass, error) {
+	phone = storage.NormalizePhone(phone)
+	if phone == "" {
+		return nil, errors.New("invalid phone")
+	}
+
+	r, err := p.raw.ContactsResolvePhone(ctx, phone)
+	if err == nil {
+		return peer.EntitiesFromResult(r).ExtractPeer(r.Peer)
+	}
+	if !p.importContacts || !tgerr.Is(err, "PHONE_NOT_OCCUPIED") {
+		return nil, errors.Errorf("resolve phone: %w", err)
+	}
+
+	user, ok, importErr := p.importContact(ctx, phone)
+	if importErr != nil {
+		return nil, importErr
+	}
+	if !ok {
+		return nil, errors.Errorf("resolve phone: %w", err)
+	}
+	return user.AsInputPeer(), nil
+}
+
+func (p *Phone) importContact(ctx context.Context, phone string) (*tg.User, bool, error) {
+	r, err := p.raw.ContactsImportContacts(ctx, []tg.InputPhoneContact{
+		{Phone: phone, FirstName: phone},
+	})
+	if err != nil {
+		return nil, false, errors.Errorf("import contact: %w", err)
+	}
+	if len(r.Imported) == 0 {
+		return nil, false, nil
+	}
+
+	userID := r.Imported[0].UserID
+	for _, u := range r.Users {
+		user, ok := u.AsNotEmpty()
+		if !ok || user.ID != userID {
+			continue
+		}
+		if _, err := p.raw.ContactsDeleteContacts(ctx, []tg.InputUserClass{user.AsInput()}); err != nil {
+			return nil, false, errors.Errorf("delete imported contact: %w", err)
+		}
+		return user, true, nil
+	}
+	return nil, false, errors.Errorf("imported user %d not found", userID)
+}
@@ -0,0 +1,70 @@
+package resolver
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/gotd/td/tg"
+	"github.com/gotd/td/tgerr"
+	"github.com/gotd/td/tgmock"
+)
+
+func TestPhone(t *testing.T) {
+	ctx := context.Background()
+	user := &tg.User{ID: 10, AccessHash: 10, Phone: "79001234567"}
+	expected := &tg.InputPeerUser{UserID: 10, AccessHash: 10}
+
+	t.Run("Resolve", func(t *testing.T) {
+		a := require.New(t)
+		mock := tgmock.New(t)
+		mock.ExpectCall(&tg.ContactsResolvePhoneRequest{
+			Phone: "79001234567",
+		}).ThenResult(&tg.ContactsResolvedPeer{
+			Peer:  &tg.PeerUser{UserID: 10},
+			Users: []tg.UserClass{user},
+		})
+
+		p, err := NewPhone(tg.NewClient(mock)).ResolvePhone(ctx, "+7 900 123-45-67")
+		a.NoError(err)
+		a.Equal(expected, p)
+	})
+	t.Run("Import", func(t *testing.T) {
+		a := require.New(t)
+		mock := tgmock.New(t)
+		mock.ExpectCall(&tg.ContactsResolvePhoneRequest{
+			Phone: "79001234567",
+		}).ThenRPCErr(tgerr.New(400, "PHONE_NOT_OCCUPIED"))
+		mock.ExpectCall(&tg.ContactsImportContactsRequest{
+			Contacts: []tg.InputPhoneContact{
+				{Phone: "79001234567", FirstName: "79001234567"},
+			},
+		}).ThenResult(&tg.ContactsImportedContacts{
+			Imported: []tg.ImportedContact{{UserID: 10}},
+			Users:    []tg.UserClass{user},
+		})
+		mock.ExpectCall(&tg.ContactsDeleteContactsRequest{
+			ID: []tg.InputUserClass{&tg.InputUser{UserID: 10, AccessHash: 10}},
+		}).ThenResult(&tg.Updates{})
+
+		p, err := NewPhone(tg.NewClient(mock)).WithImport(true).ResolvePhone(ctx, "+79001234567")
+		a.NoError(err)
+		a.Equal(expected, p)
+	})
+	t.Run("NotFound", func(t *testing.T) {
+		a := require.New(t)
+		mock := tgmock.New(t)
+		mock.ExpectCall(&tg.ContactsResolvePhoneRequest{
+			Phone: "79001234567",
+		}).ThenRPCErr(tgerr.New(400, "PHONE_NOT_OCCUPIED"))
+		mock.ExpectCall(&tg.ContactsImportContactsRequest{
+			Contacts: []tg.InputPhoneContact{
+				{Phone: "79001234567", FirstName: "79001234567"},
+			},
+		}).ThenResult(&tg.ContactsImportedContacts{})
+
+		_, err := NewPhone(tg.NewClient(mock)).WithImport(true).ResolvePhone(ctx, "79001234567")
+		a.True(tgerr.Is(err, "PHONE_NOT_OCCUPIED"))
+	})
+}
@@ -0,0 +1,2 @@
+// Package rules posts and keeps pinned per-chat rules or welcome message.
+package rules
@@ -0,0 +1,287 @@
+package rules
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"strings"
+	"text/template"
+
+	"github.com/go-faster/errors"
+	"go.uber.org/zap"
+
+	"github.com/gotd/td/crypto"
+	"github.com/gotd/td/tg"
+	"github.com/gotd/td/tgerr"
+
+	"github.com/gotd/contrib/storage"
+)
+
+// MetadataKey is a peer metadata key of rules message state.
+const MetadataKey = "rules"
+
+// Data is a template data of rules message.
+type Data struct {
+	Peer storage.Peer
+	// Title is a chat title or user name.
+	Title string
+	// Metadata is a peer metadata, i.e. per-chat settings.
+	Metadata map[string]any
+}
+
+// state is a stored state of rules message.
+type state struct {
+	MsgID int    `json:"msg_id"`
+	Hash  string `json:"hash"`
+}
+
+// Manager posts rules message to chats and keeps it pinned and up to date.
+//
+// Message text is rendered from template using Data of chat, so rules can
+// be customized per chat using peer metadata.
+type Manager struct {
+	api   *tg.Client
+	peers storage.PeerStorage
+	tmpl  *template.Template
+	rand  io.Reader
+	log   *zap.Logger
+
+	notify bool
+}
+
+// NewManager creates new Manager which renders rules using given template
+// and stores message state in peer storage.
+func NewManager(api *tg.Client, peers storage.PeerStorage, tmpl *template.Template) *Manager {
+	return &Manager{
+		api:   api,
+		peers: peers,
+		tmpl:  tmpl,
+		rand:  rand.Reader,
+		log:   zap.NewNop(),
+	}
+}
+
+// WithRand sets random source of message random IDs.
+func (m *Manager) WithRand(r io.Reader) *Manager {
+	m.rand = r
+	return m
+}
+
+// WithLog sets logger to use. Default is no-op logger.
+func (m *Manager) WithLog(log *zap.Logger) *Manager {
+	m.log = log
+	return m
+}
+
+// WithNotify sets whether pinning notifies chat members. Default is false.
+func (m *Manager) WithNotify(notify bool) *Manager {
+	m.notify = notify
+	return m
+}
+
+func title(p storage.Peer) string {
+	switch {
+	case p.Channel != nil:
+		return p.Channel.Title
+	case p.Chat != nil:
+		return p.Chat.Title
+	case p.User != nil:
+		return strings.TrimSpace(p.User.FirstName + " " + p.User.LastName)
+	default:
+		return ""
+	}
+}
+
+// Render renders rules message of given peer.
+func (m *Manager) Render(p storage.Peer) (string, error) {
+	var b strings.Builder
+	if err := m.tmpl.Execute(&b, Data{
+		Peer:     p,
+		Title:    title(p),
+		Metadata: p.Metadata,
+	}); err != nil {
+		return "", errors.Errorf("execute template: %w", err)
+	}
+	return b.String(), nil
+}
+
+func hash(text string) string {
+	h := sha256.Sum256([]byte(text))
+	return hex.EncodeToString(h[:])
+}
+
+func loadState(p storage.Peer) (state, error) {
+	v, ok := p.Metadata[MetadataKey]
+	if !ok || v == nil {
+		return state{}, nil
+	}
+	data, ok := v.(string)
+	if !ok {
+		return state{}, errors.Errorf("unexpected metadata type %T", v)
+	}
+	var s state
+	if err := json.Unmarshal([]byte(data), &s); err != nil {
+		return state{}, errors.Errorf("unmarshal: %w", err)
+	}
+	return s, nil
+}
+
+func (m *Manager) saveState(ctx context.Context, key storage.PeerKey, s state) error {
+	data, err := json.Marshal(s)
+	if err != nil {
+		return errors.Errorf("marshal: %w", err)
+	}
+	return storage.SetMetadata(ctx, m.peers, key, MetadataKey, string(data))
+}
+
+// message gets message with given ID. It returns false if message
+// was deleted.
+func (m *Manager) message(ctx context.Context, p storage.Peer, id int) (*tg.Message, bool, error) {
+	ids := []tg.InputMessageClass{&tg.InputMessageID{ID: id}}
+
+	var (
+		r   tg.MessagesMessagesClass
+		err error
+	)
+	if channel, ok := p.AsInputChannel(); ok {
+		r, err = m.api.ChannelsGetMessages(ctx, &tg.ChannelsGetMessagesRequest{
+			Channel: channel,
+			ID:      ids,
+		})
+	} else {
+		r, err = m.api.MessagesGetMessages(ctx, ids)
+	}
+	if err != nil {
+		return nil, false, errors.Errorf("get message: %w", err)
+	}
+
+	modified, ok := r.AsModified()
+	if !ok {
+		return nil, false, nil
+	}
+	for _, msg := range modified.GetMessages() {
+		if v, ok := msg.(*tg.Message); ok && v.ID == id {
+			return v, true, nil
+		}
+	}
+	return nil, false, nil
+}
+
+func sentMessageID(u tg.UpdatesClass, randomID int64) (int, bool) {
+	var updates []tg.UpdateClass
+	switch v := u.(type) {
+	case *tg.UpdateShortSentMessage:
+		return v.ID, true
+	case *tg.Updates:
+		updates = v.Updates
+	case *tg.UpdatesCombined:
+		updates = v.Updates
+	}
+	for _, update := range updates {
+		if v, ok := update.(*tg.UpdateMessageID); ok && v.RandomID == randomID {
+			return v.ID, true
+		}
+	}
+	return 0, false
+}
+
+func (m *Manager) send(ctx context.Context, peer tg.InputPeerClass, text string) (int, error) {
+	randomID, err := crypto.RandInt64(m.rand)
+	if err != nil {
+		return 0, errors.Errorf("generate random ID: %w", err)
+	}
+	u, err := m.api.MessagesSendMessage(ctx, &tg.MessagesSendMessageRequest{
+		Peer:     peer,
+		Message:  text,
+		RandomID: randomID,
+	})
+	if err != nil {
+		return 0, errors.Errorf("send: %w", err)
+	}
+	id, ok := sentMessageID(u, randomID)
+	if !ok {
+		return 0, errors.Errorf("no message ID in %T", u)
+	}
+	return id, nil
+}
+
+func (m *Manager) pin(ctx context.Context, peer tg.InputPeerClass, id int) error {
+	if _, err := m.api.MessagesUpdatePinnedMessage(ctx, &tg.MessagesUpdatePinnedMessageRequest{
+		Silent: !m.notify,
+		Peer:   peer,
+		ID:     id,
+	}); err != nil {
+		return errors.Errorf("pin: %w", err)
+	}
+	return nil
+}
+
+// Ensure ensures that chat with given key has pinned up-to-date rules
+// message and returns its ID.
+//
+// Message is edited in place if rendered text changed, re-pinned if it was
+// unpinned and posted again if it was deleted.
+func (m *Manager) Ensure(ctx context.Context, key storage.PeerKey) (int, error) {
+	p, err := m.peers.Find(ctx, key)
+	if err != nil {
+		return 0, errors.Errorf("find %s: %w", key, err)
+	}
+	text, err := m.Render(p)
+	if err != nil {
+		return 0, err
+	}
+	s, err := loadState(p)
+	if err != nil {
+		return 0, errors.Errorf("load state: %w", err)
+	}
+
+	peer := p.AsInputPeer()
+	current := state{MsgID: s.MsgID, Hash: hash(text)}
+	if s.MsgID != 0 {
+		msg, ok, err := m.message(ctx, p, s.MsgID)
+		if err != nil {
+			return 0, err
+		}
+		if ok {
+			if s.Hash != current.Hash {
+				if _, err := m.api.MessagesEditMessage(ctx, &tg.MessagesEditMessageRequest{
+					Peer:    peer,
+					ID:      s.MsgID,
+					Message: text,
+				}); err != nil && !tgerr.Is(err, "MESSAGE_NOT_MODIFIED") {
+					return 0, errors.Errorf("edit: %w", err)
+				}
+				m.log.Debug("Rules edited", zap.Stringer("peer", key), zap.Int("msg_id", s.MsgID))
+			}
+			if !msg.Pinned {
+				if err := m.pin(ctx, peer, s.MsgID); err != nil {
+					return 0, err
+				}
+				m.log.Debug("Rules re-pinned", zap.Stringer("peer", key), zap.Int("msg_id", s.MsgID))
+			}
+			if s != current {
+				if err := m.saveState(ctx, key, current); err != nil {
+					return 0, errors.Errorf("save state: %w", err)
+				}
+			}
+			return s.MsgID, nil
+		}
+	}
+
+	id, err := m.send(ctx, peer, text)
+	if err != nil {
+		return 0, err
+	}
+	if err := m.pin(ctx, peer, id); err != nil {
+		return 0, err
+	}
+	current.MsgID = id
+	if err := m.saveState(ctx, key, current); err != nil {
+		return 0, errors.Errorf("save state: %w", err)
+	}
+	m.log.Debug("Rules posted", zap.Stringer("peer", key), zap.Int("msg_id", id))
+	return id, nil
+}
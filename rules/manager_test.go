@@ -0,0 +1,103 @@
+package rules
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"text/template"
+
+	pebbledb "github.com/cockroachdb/pebble"
+	"github.com/cockroachdb/pebble/vfs"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gotd/td/tg"
+	"github.com/gotd/td/tgmock"
+
+	"github.com/gotd/contrib/pebble"
+	"github.com/gotd/contrib/storage"
+)
+
+func TestManager(t *testing.T) {
+	a := require.New(t)
+	ctx := context.Background()
+	mock := tgmock.New(t)
+
+	db, err := pebbledb.Open("pebble.db", &pebbledb.Options{
+		FS: vfs.NewMem(),
+	})
+	a.NoError(err)
+	t.Cleanup(func() {
+		_ = db.Close()
+	})
+	peers := pebble.NewPeerStorage(db)
+
+	var p storage.Peer
+	a.True(p.FromChat(&tg.Channel{ID: 10, AccessHash: 20, Title: "gotd", Photo: &tg.ChatPhotoEmpty{}}))
+	a.NoError(peers.Add(ctx, p))
+	key := storage.KeyFromPeer(p)
+	a.NoError(storage.SetMetadata(ctx, peers, key, "extra", "No spam."))
+
+	tmpl := template.Must(template.New("rules").Parse(`Welcome to {{.Title}}! {{index .Metadata "extra"}}`))
+	m := NewManager(tg.NewClient(mock), peers, tmpl).
+		WithRand(bytes.NewReader(make([]byte, 1024)))
+
+	peer := &tg.InputPeerChannel{ChannelID: 10, AccessHash: 20}
+	channel := &tg.InputChannel{ChannelID: 10, AccessHash: 20}
+	expectGet := func(msg tg.MessageClass) {
+		mock.ExpectCall(&tg.ChannelsGetMessagesRequest{
+			Channel: channel,
+			ID:      []tg.InputMessageClass{&tg.InputMessageID{ID: 5}},
+		}).ThenResult(&tg.MessagesChannelMessages{
+			Messages: []tg.MessageClass{msg},
+		})
+	}
+	expectPin := func(id int) {
+		mock.ExpectCall(&tg.MessagesUpdatePinnedMessageRequest{
+			Silent: true,
+			Peer:   peer,
+			ID:     id,
+		}).ThenResult(&tg.Updates{})
+	}
+
+	// Post and pin.
+	mock.ExpectCall(&tg.MessagesSendMessageRequest{
+		Peer:    peer,
+		Message: "Welcome to gotd! No spam.",
+	}).ThenResult(&tg.UpdateShortSentMessage{ID: 5})
+	expectPin(5)
+	id, err := m.Ensure(ctx, key)
+	a.NoError(err)
+	a.Equal(5, id)
+
+	// Up to date.
+	expectGet(&tg.Message{ID: 5, Pinned: true, PeerID: &tg.PeerChannel{ChannelID: 10}})
+	id, err = m.Ensure(ctx, key)
+	a.NoError(err)
+	a.Equal(5, id)
+
+	// Changed and unpinned.
+	a.NoError(storage.SetMetadata(ctx, peers, key, "extra", "Be nice."))
+	expectGet(&tg.Message{ID: 5, PeerID: &tg.PeerChannel{ChannelID: 10}})
+	mock.ExpectCall(&tg.MessagesEditMessageRequest{
+		Peer:    peer,
+		ID:      5,
+		Message: "Welcome to gotd! Be nice.",
+	}).ThenResult(&tg.Updates{})
+	expectPin(5)
+	id, err = m.Ensure(ctx, key)
+	a.NoError(err)
+	a.Equal(5, id)
+
+	// Deleted.
+	expectGet(&tg.MessageEmpty{ID: 5})
+	mock.ExpectCall(&tg.MessagesSendMessageRequest{
+		Peer:    peer,
+		Message: "Welcome to gotd! Be nice.",
+	}).ThenResult(&tg.Updates{
+		Updates: []tg.UpdateClass{&tg.UpdateMessageID{ID: 6}},
+	})
+	expectPin(6)
+	id, err = m.Ensure(ctx, key)
+	a.NoError(err)
+	a.Equal(6, id)
+}
@@ -0,0 +1,6 @@
+// Package servicemsg decodes service messages into typed events.
+//
+// Service messages (tg.MessageService) describe chat events, like users
+// joining, title changes, pins, calls and payments. Decode converts their
+// actions into Go types, and Router routes them to typed handlers.
+package servicemsg
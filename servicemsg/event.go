@@ -0,0 +1,154 @@
+package servicemsg
+
+import (
+	"time"
+
+	"github.com/gotd/td/tg"
+)
+
+// Event is a common part of all service message events.
+type Event struct {
+	// Message is a decoded service message.
+	Message *tg.MessageService
+	// Entities are entities of update containing message.
+	Entities tg.Entities
+}
+
+// FromUserID returns ID of user which triggered the event, if any.
+func (e Event) FromUserID() (int64, bool) {
+	from := e.Message.FromID
+	if from == nil {
+		// Messages in private chats have no FromID.
+		from = e.Message.PeerID
+	}
+	user, ok := from.(*tg.PeerUser)
+	if !ok {
+		return 0, false
+	}
+	return user.UserID, true
+}
+
+// Join is an event of users joining the chat.
+type Join struct {
+	Event
+	// UserIDs are IDs of joined users.
+	UserIDs []int64
+	// InviterID is an ID of user which created used invite link, if ByLink.
+	InviterID int64
+	// ByLink is true if user joined by invite link.
+	ByLink bool
+	// ByRequest is true if user join request was approved.
+	ByRequest bool
+}
+
+// Leave is an event of user leaving or being removed from the chat.
+type Leave struct {
+	Event
+	UserID int64
+}
+
+// TitleChange is an event of chat title change.
+type TitleChange struct {
+	Event
+	Title string
+}
+
+// Pin is an event of message pin.
+type Pin struct {
+	Event
+	// MessageID is an ID of pinned message, zero if unknown.
+	MessageID int
+}
+
+// Call is an event of finished or missed phone call.
+type Call struct {
+	Event
+	CallID   int64
+	Video    bool
+	Duration time.Duration
+	// Reason is a call discard reason, may be nil.
+	Reason tg.PhoneCallDiscardReasonClass
+}
+
+// Payment is an event of payment.
+type Payment struct {
+	Event
+	// Currency is a three-letter ISO 4217 currency code, or XTR for stars.
+	Currency string
+	// TotalAmount is a price in the smallest units of the currency.
+	TotalAmount int64
+	// Incoming is true if payment is received by the bot.
+	Incoming bool
+	// Payload is a bot-specified invoice payload, if Incoming.
+	Payload []byte
+}
+
+// Other is an event of service message with action not decoded by this
+// package.
+type Other struct {
+	Event
+	Action tg.MessageActionClass
+}
+
+// Decode decodes service message into one of Join, Leave, TitleChange,
+// Pin, Call, Payment or Other.
+//
+// It returns false if given message is not a service message.
+func Decode(e tg.Entities, msg tg.MessageClass) (any, bool) {
+	m, ok := msg.(*tg.MessageService)
+	if !ok {
+		return nil, false
+	}
+	ev := Event{Message: m, Entities: e}
+
+	switch a := m.Action.(type) {
+	case *tg.MessageActionChatAddUser:
+		return Join{Event: ev, UserIDs: a.Users}, true
+	case *tg.MessageActionChatJoinedByLink:
+		j := Join{Event: ev, InviterID: a.InviterID, ByLink: true}
+		if id, ok := ev.FromUserID(); ok {
+			j.UserIDs = []int64{id}
+		}
+		return j, true
+	case *tg.MessageActionChatJoinedByRequest:
+		j := Join{Event: ev, ByRequest: true}
+		if id, ok := ev.FromUserID(); ok {
+			j.UserIDs = []int64{id}
+		}
+		return j, true
+	case *tg.MessageActionChatDeleteUser:
+		return Leave{Event: ev, UserID: a.UserID}, true
+	case *tg.MessageActionChatEditTitle:
+		return TitleChange{Event: ev, Title: a.Title}, true
+	case *tg.MessageActionPinMessage:
+		p := Pin{Event: ev}
+		if h, ok := m.ReplyTo.(*tg.MessageReplyHeader); ok {
+			p.MessageID = h.ReplyToMsgID
+		}
+		return p, true
+	case *tg.MessageActionPhoneCall:
+		return Call{
+			Event:    ev,
+			CallID:   a.CallID,
+			Video:    a.Video,
+			Duration: time.Duration(a.Duration) * time.Second,
+			Reason:   a.Reason,
+		}, true
+	case *tg.MessageActionPaymentSent:
+		return Payment{
+			Event:       ev,
+			Currency:    a.Currency,
+			TotalAmount: a.TotalAmount,
+		}, true
+	case *tg.MessageActionPaymentSentMe:
+		return Payment{
+			Event:       ev,
+			Currency:    a.Currency,
+			TotalAmount: a.TotalAmount,
+			Incoming:    true,
+			Payload:     a.Payload,
+		}, true
+	default:
+		return Other{Event: ev, Action: m.Action}, true
+	}
+}
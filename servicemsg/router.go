@@ -0,0 +1,122 @@
+package servicemsg
+
+import (
+	"context"
+
+	"github.com/gotd/td/tg"
+)
+
+// Handler handles event of type T.
+type Handler[T any] func(ctx context.Context, e T) error
+
+// Router routes service message events to typed handlers.
+//
+// Events without handler are ignored.
+type Router struct {
+	onJoin        Handler[Join]
+	onLeave       Handler[Leave]
+	onTitleChange Handler[TitleChange]
+	onPin         Handler[Pin]
+	onCall        Handler[Call]
+	onPayment     Handler[Payment]
+	onOther       Handler[Other]
+}
+
+// NewRouter creates new Router.
+func NewRouter() *Router {
+	return &Router{}
+}
+
+// OnJoin sets Join handler.
+func (r *Router) OnJoin(h Handler[Join]) *Router {
+	r.onJoin = h
+	return r
+}
+
+// OnLeave sets Leave handler.
+func (r *Router) OnLeave(h Handler[Leave]) *Router {
+	r.onLeave = h
+	return r
+}
+
+// OnTitleChange sets TitleChange handler.
+func (r *Router) OnTitleChange(h Handler[TitleChange]) *Router {
+	r.onTitleChange = h
+	return r
+}
+
+// OnPin sets Pin handler.
+func (r *Router) OnPin(h Handler[Pin]) *Router {
+	r.onPin = h
+	return r
+}
+
+// OnCall sets Call handler.
+func (r *Router) OnCall(h Handler[Call]) *Router {
+	r.onCall = h
+	return r
+}
+
+// OnPayment sets Payment handler.
+func (r *Router) OnPayment(h Handler[Payment]) *Router {
+	r.onPayment = h
+	return r
+}
+
+// OnOther sets handler of service messages not decoded by this package.
+func (r *Router) OnOther(h Handler[Other]) *Router {
+	r.onOther = h
+	return r
+}
+
+func call[T any](ctx context.Context, h Handler[T], e T) error {
+	if h == nil {
+		return nil
+	}
+	return h(ctx, e)
+}
+
+// Handle routes given message to handler. It returns false if message is
+// not a service message, so caller can handle it as regular one.
+func (r *Router) Handle(ctx context.Context, e tg.Entities, msg tg.MessageClass) (bool, error) {
+	ev, ok := Decode(e, msg)
+	if !ok {
+		return false, nil
+	}
+
+	switch ev := ev.(type) {
+	case Join:
+		return true, call(ctx, r.onJoin, ev)
+	case Leave:
+		return true, call(ctx, r.onLeave, ev)
+	case TitleChange:
+		return true, call(ctx, r.onTitleChange, ev)
+	case Pin:
+		return true, call(ctx, r.onPin, ev)
+	case Call:
+		return true, call(ctx, r.onCall, ev)
+	case Payment:
+		return true, call(ctx, r.onPayment, ev)
+	case Other:
+		return true, call(ctx, r.onOther, ev)
+	default:
+		return true, nil
+	}
+}
+
+// Register sets NewMessage and NewChannelMessage handlers of dispatcher
+// to route service messages.
+//
+// Dispatcher has only one handler per update type, so Register replaces
+// existing handlers. To handle regular messages too, call Handle from
+// own handlers instead.
+func (r *Router) Register(d tg.UpdateDispatcher) {
+	d.OnNewMessage(func(ctx context.Context, e tg.Entities, update *tg.UpdateNewMessage) error {
+		_, err := r.Handle(ctx, e, update.Message)
+		return err
+	})
+	d.OnNewChannelMessage(func(ctx context.Context, e tg.Entities, update *tg.UpdateNewChannelMessage) error {
+		_, err := r.Handle(ctx, e, update.Message)
+		return err
+	})
+}
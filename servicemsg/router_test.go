@@ -0,0 +1,112 @@
+package servicemsg
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/gotd/td/tg"
+)
+
+func service(action tg.MessageActionClass) *tg.MessageService {
+	return &tg.MessageService{
+		ID:     10,
+		PeerID: &tg.PeerChat{ChatID: 1},
+		FromID: &tg.PeerUser{UserID: 42},
+		Action: action,
+	}
+}
+
+func TestDecode(t *testing.T) {
+	a := require.New(t)
+	e := tg.Entities{}
+
+	_, ok := Decode(e, &tg.Message{Message: "text"})
+	a.False(ok)
+
+	pinned := service(&tg.MessageActionPinMessage{})
+	pinned.ReplyTo = &tg.MessageReplyHeader{ReplyToMsgID: 5}
+
+	for _, tt := range []struct {
+		msg    *tg.MessageService
+		expect func(ev Event) any
+	}{
+		{
+			service(&tg.MessageActionChatAddUser{Users: []int64{1, 2}}),
+			func(ev Event) any { return Join{Event: ev, UserIDs: []int64{1, 2}} },
+		},
+		{
+			service(&tg.MessageActionChatJoinedByLink{InviterID: 7}),
+			func(ev Event) any { return Join{Event: ev, UserIDs: []int64{42}, InviterID: 7, ByLink: true} },
+		},
+		{
+			service(&tg.MessageActionChatDeleteUser{UserID: 3}),
+			func(ev Event) any { return Leave{Event: ev, UserID: 3} },
+		},
+		{
+			service(&tg.MessageActionChatEditTitle{Title: "title"}),
+			func(ev Event) any { return TitleChange{Event: ev, Title: "title"} },
+		},
+		{
+			pinned,
+			func(ev Event) any { return Pin{Event: ev, MessageID: 5} },
+		},
+		{
+			service(&tg.MessageActionPhoneCall{CallID: 1, Duration: 60, Video: true}),
+			func(ev Event) any { return Call{Event: ev, CallID: 1, Video: true, Duration: time.Minute} },
+		},
+		{
+			service(&tg.MessageActionPaymentSentMe{Currency: "XTR", TotalAmount: 100, Payload: []byte("p")}),
+			func(ev Event) any {
+				return Payment{Event: ev, Currency: "XTR", TotalAmount: 100, Incoming: true, Payload: []byte("p")}
+			},
+		},
+		{
+			service(&tg.MessageActionScreenshotTaken{}),
+			func(ev Event) any { return Other{Event: ev, Action: &tg.MessageActionScreenshotTaken{}} },
+		},
+	} {
+		got, ok := Decode(e, tt.msg)
+		a.True(ok)
+		a.Equal(tt.expect(Event{Message: tt.msg, Entities: e}), got)
+	}
+}
+
+func TestRouter(t *testing.T) {
+	a := require.New(t)
+	ctx := context.Background()
+
+	var (
+		joined []int64
+		titles []string
+	)
+	d := tg.NewUpdateDispatcher()
+	NewRouter().
+		OnJoin(func(ctx context.Context, e Join) error {
+			joined = append(joined, e.UserIDs...)
+			return nil
+		}).
+		OnTitleChange(func(ctx context.Context, e TitleChange) error {
+			titles = append(titles, e.Title)
+			return nil
+		}).
+		Register(d)
+
+	a.NoError(d.Handle(ctx, &tg.Updates{
+		Updates: []tg.UpdateClass{
+			&tg.UpdateNewMessage{Message: service(&tg.MessageActionChatAddUser{Users: []int64{1}})},
+			&tg.UpdateNewChannelMessage{Message: service(&tg.MessageActionChatEditTitle{Title: "new"})},
+			// No handler.
+			&tg.UpdateNewMessage{Message: service(&tg.MessageActionChatDeleteUser{UserID: 1})},
+			&tg.UpdateNewMessage{Message: &tg.Message{Message: "regular"}},
+		},
+	}))
+	a.Equal([]int64{1}, joined)
+	a.Equal([]string{"new"}, titles)
+
+	handled, err := NewRouter().Handle(ctx, tg.Entities{}, &tg.Message{})
+	a.NoError(err)
+	a.False(handled)
+}
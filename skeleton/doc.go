@@ -0,0 +1,7 @@
+// Package skeleton assembles a bot from contrib packages: pebble peer and
+// session storage, flood wait and rate limit middlewares, update
+// dispatcher with panic recovery and peer collection, health server and
+// graceful shutdown.
+//
+// Bot fields could be overridden between New and Run.
+package skeleton
@@ -0,0 +1,217 @@
+package skeleton
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"path/filepath"
+	"time"
+
+	pebbledb "github.com/cockroachdb/pebble"
+	"github.com/cockroachdb/pebble/vfs"
+	"github.com/go-faster/errors"
+	"go.uber.org/atomic"
+	"go.uber.org/multierr"
+	"go.uber.org/zap"
+	"golang.org/x/sync/errgroup"
+	"golang.org/x/time/rate"
+
+	"github.com/gotd/td/telegram"
+	"github.com/gotd/td/tg"
+
+	"github.com/gotd/contrib/coalesce"
+	"github.com/gotd/contrib/middleware/floodwait"
+	"github.com/gotd/contrib/middleware/ratelimit"
+	"github.com/gotd/contrib/pebble"
+	"github.com/gotd/contrib/recovery"
+	"github.com/gotd/contrib/storage"
+)
+
+// Config of Bot.
+type Config struct {
+	AppID   int
+	AppHash string
+	// BotToken is used to authenticate bot, if not authorized yet.
+	// If empty, authentication is up to Run callback.
+	BotToken string
+
+	// DataDir is directory of pebble database with session and peers.
+	// If empty, in-memory database is used.
+	DataDir string
+
+	// RateLimit is maximum of requests per second, zero disables limiting.
+	RateLimit float64
+	// RateBurst is rate limit burst. Default is 1.
+	RateBurst int
+
+	// HealthAddr is listen address of health server, empty disables it.
+	HealthAddr string
+	// ShutdownTimeout limits graceful shutdown. Default is 10 seconds.
+	ShutdownTimeout time.Duration
+}
+
+// Bot is an assembled bot.
+type Bot struct {
+	Config Config
+	Log    *zap.Logger
+
+	DB         *pebbledb.DB
+	Peers      storage.PeerStorage
+	Session    *coalesce.Session
+	Dispatcher tg.UpdateDispatcher
+	Waiter     *floodwait.Waiter
+	// Middlewares are applied after flood wait handling.
+	Middlewares []telegram.Middleware
+	// Options are base client options. Session storage, update handler
+	// and middlewares are set by Run.
+	Options telegram.Options
+
+	ready atomic.Bool
+}
+
+// New opens database and creates Bot from given config.
+func New(cfg Config, log *zap.Logger) (*Bot, error) {
+	if cfg.ShutdownTimeout == 0 {
+		cfg.ShutdownTimeout = 10 * time.Second
+	}
+	if cfg.RateBurst == 0 {
+		cfg.RateBurst = 1
+	}
+
+	opts := &pebbledb.Options{}
+	dir := filepath.Join(cfg.DataDir, "pebble")
+	if cfg.DataDir == "" {
+		opts.FS = vfs.NewMem()
+		dir = "pebble"
+	}
+	db, err := pebbledb.Open(dir, opts)
+	if err != nil {
+		return nil, errors.Wrap(err, "open pebble")
+	}
+
+	b := &Bot{
+		Config: cfg,
+		Log:    log,
+
+		DB:         db,
+		Peers:      pebble.NewPeerStorage(db),
+		Session:    coalesce.NewSession(pebble.NewSessionStorage(db, "session")).WithLog(log.Named("session")),
+		Dispatcher: tg.NewUpdateDispatcher(),
+		Waiter: floodwait.NewWaiter().WithCallback(func(ctx context.Context, wait floodwait.FloodWait) {
+			log.Warn("Flood wait", zap.Duration("wait", wait.Duration))
+		}),
+		Options: telegram.Options{
+			Logger: log.Named("client"),
+		},
+	}
+	if cfg.RateLimit > 0 {
+		b.Middlewares = append(b.Middlewares, ratelimit.New(rate.Limit(cfg.RateLimit), cfg.RateBurst))
+	}
+	return b, nil
+}
+
+// Ready reports whether client is connected and authorized.
+func (b *Bot) Ready() bool {
+	return b.ready.Load()
+}
+
+// Health is a health check handler. It responds with 503 until client
+// is ready.
+func (b *Bot) Health() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !b.Ready() {
+			http.Error(w, "not ready", http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = w.Write([]byte("ok"))
+	})
+}
+
+// client creates telegram client.
+func (b *Bot) client() *telegram.Client {
+	opts := b.Options
+	opts.SessionStorage = b.Session
+	opts.UpdateHandler = storage.UpdateHook(
+		recovery.New(b.Dispatcher).WithLog(b.Log.Named("recovery")),
+		b.Peers,
+	)
+	opts.Middlewares = append([]telegram.Middleware{b.Waiter}, b.Middlewares...)
+	return telegram.NewClient(b.Config.AppID, b.Config.AppHash, opts)
+}
+
+// Run runs bot until given context is done or f returns, then shuts down
+// gracefully and closes database.
+func (b *Bot) Run(ctx context.Context, f func(ctx context.Context, client *telegram.Client) error) (rerr error) {
+	defer func() {
+		multierr.AppendInto(&rerr, b.DB.Close())
+	}()
+
+	client := b.client()
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	g, ctx := errgroup.WithContext(ctx)
+	if addr := b.Config.HealthAddr; addr != "" {
+		srv := &http.Server{
+			Addr:              addr,
+			Handler:           b.Health(),
+			ReadHeaderTimeout: time.Second,
+			BaseContext: func(net.Listener) context.Context {
+				return ctx
+			},
+		}
+		g.Go(func() error {
+			if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				return errors.Wrap(err, "health server")
+			}
+			return nil
+		})
+		g.Go(func() error {
+			<-ctx.Done()
+			shutdownCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), b.Config.ShutdownTimeout)
+			defer cancel()
+			return srv.Shutdown(shutdownCtx)
+		})
+	}
+
+	sessionCtx, stopSession := context.WithCancel(context.WithoutCancel(ctx))
+	sessionDone := make(chan struct{})
+	go func() {
+		defer close(sessionDone)
+		_ = b.Session.WithFlushTimeout(b.Config.ShutdownTimeout).Run(sessionCtx)
+	}()
+	defer func() {
+		// Flush session after client is stopped.
+		stopSession()
+		<-sessionDone
+	}()
+
+	g.Go(func() error {
+		// Stop health server when client exits.
+		defer cancel()
+		return b.Waiter.Run(ctx, func(ctx context.Context) error {
+			return client.Run(ctx, func(ctx context.Context) error {
+				if token := b.Config.BotToken; token != "" {
+					status, err := client.Auth().Status(ctx)
+					if err != nil {
+						return errors.Wrap(err, "auth status")
+					}
+					if !status.Authorized {
+						if _, err := client.Auth().Bot(ctx, token); err != nil {
+							return errors.Wrap(err, "auth")
+						}
+					}
+				}
+				b.ready.Store(true)
+				defer b.ready.Store(false)
+
+				b.Log.Info("Bot started")
+				return f(ctx, client)
+			})
+		})
+	})
+
+	if err := g.Wait(); err != nil && !errors.Is(err, context.Canceled) {
+		return err
+	}
+	return nil
+}
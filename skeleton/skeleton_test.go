@@ -0,0 +1,45 @@
+package skeleton
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/gotd/td/telegram"
+	"github.com/gotd/td/tg"
+
+	"github.com/gotd/contrib/storage"
+)
+
+func TestBot(t *testing.T) {
+	a := require.New(t)
+	b, err := New(Config{RateLimit: 10}, zap.NewNop())
+	a.NoError(err)
+	a.Len(b.Middlewares, 1)
+
+	rec := httptest.NewRecorder()
+	b.Health().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	a.Equal(http.StatusServiceUnavailable, rec.Code)
+	b.ready.Store(true)
+	rec = httptest.NewRecorder()
+	b.Health().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	a.Equal(http.StatusOK, rec.Code)
+	b.ready.Store(false)
+
+	ctx := context.Background()
+	var p storage.Peer
+	a.True(p.FromUser(&tg.User{ID: 10, AccessHash: 10}))
+	a.NoError(b.Peers.Add(ctx, p))
+
+	ctx, cancel := context.WithCancel(ctx)
+	cancel()
+	a.NoError(b.Run(ctx, func(ctx context.Context, client *telegram.Client) error {
+		t.Fatal("should not be called")
+		return nil
+	}))
+	a.False(b.Ready())
+}
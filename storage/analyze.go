@@ -0,0 +1,155 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"math/bits"
+	"sort"
+
+	"github.com/go-faster/errors"
+)
+
+// RawScanner is a PeerStorage which is able to scan its raw key-value pairs,
+// including associated keys and indexes.
+type RawScanner interface {
+	// ScanRaw calls f for every stored key-value pair. Slices are valid only
+	// until f returns.
+	ScanRaw(ctx context.Context, f func(key, value []byte) error) error
+}
+
+// SizeBucket is a value size histogram bucket.
+type SizeBucket struct {
+	// UpTo is an exclusive upper bound of value size, always power of two.
+	UpTo  int `json:"up_to"`
+	Count int `json:"count"`
+}
+
+// Entry is a stored entry.
+type Entry struct {
+	Key  string `json:"key"`
+	Size int    `json:"size"`
+}
+
+// Analysis is a storage size analysis.
+type Analysis struct {
+	// Count is a count of scanned entries.
+	Count int `json:"count"`
+	// Size is a total size of scanned keys and values.
+	Size int64 `json:"size"`
+	// Sizes is a histogram of value sizes, ordered by bound.
+	Sizes []SizeBucket `json:"sizes"`
+	// Prefixes is a count of entries by key prefix, see KeyPrefix.
+	Prefixes map[string]int `json:"prefixes"`
+	// Largest are largest entries, ordered by size descending.
+	Largest []Entry `json:"largest"`
+}
+
+// KeyPrefix returns prefix of raw storage key to group keys by.
+//
+// Peer keys are grouped by kind, index keys by index name, and all other
+// keys, e.g. associated usernames and phones, are grouped as empty prefix.
+func KeyPrefix(key []byte) string {
+	var k PeerKey
+	if k.Parse(key) == nil {
+		return string(KindPrefix(k.Kind))
+	}
+	if len(key) > 0 && key[0] == '_' {
+		if idx := bytes.IndexByte(key, ':'); idx > 0 {
+			return string(key[:idx+1])
+		}
+	}
+	return ""
+}
+
+// Analyze scans given storage and reports value size distribution,
+// key prefix cardinality and largest entries. At most largest entries
+// are reported.
+//
+// If storage implements RawScanner, all stored entries are scanned.
+// Otherwise, only peers are scanned, and their size is estimated using
+// JSONCodec.
+func Analyze(ctx context.Context, s PeerStorage, largest int) (Analysis, error) {
+	a := newAnalyzer(largest)
+	if r, ok := s.(RawScanner); ok {
+		if err := r.ScanRaw(ctx, func(key, value []byte) error {
+			a.add(key, len(value))
+			return nil
+		}); err != nil {
+			return Analysis{}, errors.Errorf("scan: %w", err)
+		}
+		return a.result(), nil
+	}
+
+	iter, err := s.Iterate(ctx)
+	if err != nil {
+		return Analysis{}, errors.Errorf("iterate: %w", err)
+	}
+	defer func() {
+		_ = iter.Close()
+	}()
+
+	var key []byte
+	if err := ForEach(ctx, iter, func(p Peer) error {
+		data, err := JSONCodec{}.Marshal(p)
+		if err != nil {
+			return errors.Errorf("marshal: %w", err)
+		}
+		key = KeyFromPeer(p).Bytes(key[:0])
+		a.add(key, len(data))
+		return nil
+	}); err != nil {
+		return Analysis{}, err
+	}
+
+	return a.result(), nil
+}
+
+type analyzer struct {
+	Analysis
+	buckets map[int]int
+	limit   int
+}
+
+func newAnalyzer(largest int) *analyzer {
+	return &analyzer{
+		Analysis: Analysis{
+			Prefixes: map[string]int{},
+		},
+		buckets: map[int]int{},
+		limit:   largest,
+	}
+}
+
+func (a *analyzer) add(key []byte, size int) {
+	a.Count++
+	a.Size += int64(len(key) + size)
+	a.Prefixes[KeyPrefix(key)]++
+	a.buckets[1<<bits.Len(uint(size))]++
+
+	if a.limit <= 0 {
+		return
+	}
+	if len(a.Largest) == a.limit && a.Largest[len(a.Largest)-1].Size >= size {
+		return
+	}
+	idx := sort.Search(len(a.Largest), func(i int) bool {
+		return a.Largest[i].Size < size
+	})
+	a.Largest = append(a.Largest, Entry{})
+	copy(a.Largest[idx+1:], a.Largest[idx:])
+	a.Largest[idx] = Entry{Key: string(key), Size: size}
+	if len(a.Largest) > a.limit {
+		a.Largest = a.Largest[:a.limit]
+	}
+}
+
+func (a *analyzer) result() Analysis {
+	r := a.Analysis
+	for upTo, count := range a.buckets {
+		r.Sizes = append(r.Sizes, SizeBucket{UpTo: upTo, Count: count})
+	}
+	sort.Slice(r.Sizes, func(i, j int) bool {
+		return r.Sizes[i].UpTo < r.Sizes[j].UpTo
+	})
+	return r
+}
@@ -0,0 +1,93 @@
+package storage
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/gotd/td/telegram/query/dialogs"
+	"github.com/gotd/td/tg"
+)
+
+type rawStorage struct {
+	memStorage
+	raw map[string][]byte
+}
+
+func (r rawStorage) ScanRaw(ctx context.Context, f func(key, value []byte) error) error {
+	for k, v := range r.raw {
+		if err := f([]byte(k), v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func TestKeyPrefix(t *testing.T) {
+	a := require.New(t)
+	a.Equal(string(KindPrefix(dialogs.User)), KeyPrefix(PeerKey{Kind: dialogs.User, ID: 10}.Bytes(nil)))
+	a.Equal("_recent:", KeyPrefix([]byte("_recent:abc")))
+	a.Equal("", KeyPrefix([]byte("durov")))
+	a.Equal("", KeyPrefix([]byte("_nocolon")))
+}
+
+func TestAnalyze(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Raw", func(t *testing.T) {
+		a := require.New(t)
+		s := rawStorage{
+			memStorage: newMemStorage(),
+			raw: map[string][]byte{
+				PeerKey{Kind: dialogs.User, ID: 1}.String():    make([]byte, 100),
+				PeerKey{Kind: dialogs.User, ID: 2}.String():    make([]byte, 3000),
+				PeerKey{Kind: dialogs.Channel, ID: 1}.String(): make([]byte, 10),
+				"durov":           []byte("peer0_1"),
+				"_recent:1234567": {},
+			},
+		}
+
+		r, err := Analyze(ctx, s, 2)
+		a.NoError(err)
+		a.Equal(5, r.Count)
+		a.Equal(map[string]int{
+			string(KindPrefix(dialogs.User)):    2,
+			string(KindPrefix(dialogs.Channel)): 1,
+			"":                                  1,
+			"_recent:":                          1,
+		}, r.Prefixes)
+		a.Equal([]Entry{
+			{Key: PeerKey{Kind: dialogs.User, ID: 2}.String(), Size: 3000},
+			{Key: PeerKey{Kind: dialogs.User, ID: 1}.String(), Size: 100},
+		}, r.Largest)
+		a.Equal([]SizeBucket{
+			{UpTo: 1, Count: 1},
+			{UpTo: 8, Count: 1},
+			{UpTo: 16, Count: 1},
+			{UpTo: 128, Count: 1},
+			{UpTo: 4096, Count: 1},
+		}, r.Sizes)
+	})
+	t.Run("Peers", func(t *testing.T) {
+		a := require.New(t)
+		s := newMemStorage()
+		for i := range [3]struct{}{} {
+			var p Peer
+			a.True(p.FromUser(&tg.User{
+				ID:         int64(i) + 1,
+				AccessHash: 1,
+				FirstName:  strings.Repeat("a", 100*i),
+			}))
+			a.NoError(s.Add(ctx, p))
+		}
+
+		r, err := Analyze(ctx, s, 1)
+		a.NoError(err)
+		a.Equal(3, r.Count)
+		a.Equal(map[string]int{string(KindPrefix(dialogs.User)): 3}, r.Prefixes)
+		a.Len(r.Largest, 1)
+		a.Equal(PeerKey{Kind: dialogs.User, ID: 3}.String(), r.Largest[0].Key)
+	})
+}
@@ -0,0 +1,236 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/go-faster/errors"
+)
+
+// WriterMetadataKey is a peer metadata key of last write record.
+const WriterMetadataKey = "writer"
+
+// UnknownWriter is a writer name used if context has no writer.
+const UnknownWriter = "unknown"
+
+type writerKey struct{}
+
+// WithWriter returns new context with given writer name, e.g. "harvester",
+// "resolver" or "refresher". Audited records it as last writer of peers.
+func WithWriter(ctx context.Context, name string) context.Context {
+	return context.WithValue(ctx, writerKey{}, name)
+}
+
+// WriterFromContext returns writer name from context.
+func WriterFromContext(ctx context.Context) (string, bool) {
+	name, ok := ctx.Value(writerKey{}).(string)
+	return name, ok
+}
+
+// LastWrite is a record of last peer write.
+type LastWrite struct {
+	Writer string
+	At     time.Time
+}
+
+// LastWriter returns last write record of peer stored by Audited.
+func LastWriter(p Peer) (LastWrite, bool) {
+	m, ok := p.Metadata[WriterMetadataKey].(map[string]any)
+	if !ok {
+		return LastWrite{}, false
+	}
+	w := LastWrite{}
+	w.Writer, _ = m["name"].(string)
+	if at, ok := m["at"].(string); ok {
+		w.At, _ = time.Parse(time.RFC3339Nano, at)
+	}
+	return w, w.Writer != ""
+}
+
+// ConflictKind is a kind of write conflict.
+type ConflictKind int
+
+const (
+	// ConflictMinOverFull is a write of min peer over full one.
+	//
+	// Merge keeps full entity, but frequent conflicts of this kind mean
+	// that some writer keeps storing min constructors.
+	ConflictMinOverFull ConflictKind = iota
+	// ConflictAccessHash is a write which changes non-zero access hash.
+	ConflictAccessHash
+)
+
+// String implements fmt.Stringer.
+func (k ConflictKind) String() string {
+	switch k {
+	case ConflictMinOverFull:
+		return "min_over_full"
+	case ConflictAccessHash:
+		return "access_hash"
+	default:
+		return fmt.Sprintf("ConflictKind(%d)", int(k))
+	}
+}
+
+// Conflict describes write conflict.
+type Conflict struct {
+	Kind ConflictKind
+	Key  PeerKey
+	// Writer is a name of current writer.
+	Writer string
+	// Previous is a last write record of stored peer, if any.
+	Previous LastWrite
+}
+
+// AuditStats is a write statistics of Audited.
+type AuditStats struct {
+	// Writes is a count of writes per writer.
+	Writes map[string]int
+	// Conflicts is a count of conflicts per kind.
+	Conflicts map[ConflictKind]int
+	// ConflictWriters is a count of conflicts per writer.
+	ConflictWriters map[string]int
+}
+
+var _ PeerStorage = (*Audited)(nil)
+
+// Audited is a PeerStorage decorator which records last writer of every peer
+// in peer metadata and counts write conflicts.
+//
+// Writer is taken from context, see WithWriter. Audited finds stored peer
+// before every write, so it is intended for debugging.
+type Audited struct {
+	next       PeerStorage
+	now        func() time.Time
+	onConflict func(ctx context.Context, c Conflict)
+
+	mux   sync.Mutex
+	stats AuditStats
+}
+
+// NewAudited creates new Audited storage.
+func NewAudited(next PeerStorage) *Audited {
+	return &Audited{
+		next:       next,
+		now:        time.Now,
+		onConflict: func(ctx context.Context, c Conflict) {},
+		stats: AuditStats{
+			Writes:          map[string]int{},
+			Conflicts:       map[ConflictKind]int{},
+			ConflictWriters: map[string]int{},
+		},
+	}
+}
+
+// OnConflict sets conflict callback, e.g. to log conflicts.
+func (a *Audited) OnConflict(f func(ctx context.Context, c Conflict)) *Audited {
+	a.onConflict = f
+	return a
+}
+
+// Stats returns copy of current write statistics.
+func (a *Audited) Stats() AuditStats {
+	a.mux.Lock()
+	defer a.mux.Unlock()
+
+	r := AuditStats{
+		Writes:          make(map[string]int, len(a.stats.Writes)),
+		Conflicts:       make(map[ConflictKind]int, len(a.stats.Conflicts)),
+		ConflictWriters: make(map[string]int, len(a.stats.ConflictWriters)),
+	}
+	for k, v := range a.stats.Writes {
+		r.Writes[k] = v
+	}
+	for k, v := range a.stats.Conflicts {
+		r.Conflicts[k] = v
+	}
+	for k, v := range a.stats.ConflictWriters {
+		r.ConflictWriters[k] = v
+	}
+	return r
+}
+
+func (a *Audited) record(ctx context.Context, value Peer) (Peer, error) {
+	writer, ok := WriterFromContext(ctx)
+	if !ok {
+		writer = UnknownWriter
+	}
+	key := KeyFromPeer(value)
+
+	stored, err := a.next.Find(ctx, key)
+	switch {
+	case err == nil:
+	case errors.Is(err, ErrPeerNotFound):
+		stored = Peer{}
+	default:
+		return Peer{}, errors.Errorf("find %s: %w", key, err)
+	}
+
+	var conflicts []Conflict
+	if err == nil {
+		previous, _ := LastWriter(stored)
+		if value.Min() && !stored.Min() && (stored.User != nil || stored.Channel != nil) {
+			conflicts = append(conflicts, Conflict{Kind: ConflictMinOverFull, Key: key, Writer: writer, Previous: previous})
+		}
+		if stored.Key.AccessHash != 0 && value.Key.AccessHash != 0 && stored.Key.AccessHash != value.Key.AccessHash {
+			conflicts = append(conflicts, Conflict{Kind: ConflictAccessHash, Key: key, Writer: writer, Previous: previous})
+		}
+	}
+
+	a.mux.Lock()
+	a.stats.Writes[writer]++
+	for _, c := range conflicts {
+		a.stats.Conflicts[c.Kind]++
+		a.stats.ConflictWriters[writer]++
+	}
+	a.mux.Unlock()
+	for _, c := range conflicts {
+		a.onConflict(ctx, c)
+	}
+
+	metadata := make(map[string]any, len(value.Metadata)+1)
+	for k, v := range value.Metadata {
+		metadata[k] = v
+	}
+	metadata[WriterMetadataKey] = map[string]any{
+		"name": writer,
+		"at":   a.now().UTC().Format(time.RFC3339Nano),
+	}
+	value.Metadata = metadata
+	return value, nil
+}
+
+// Add implements PeerStorage.
+func (a *Audited) Add(ctx context.Context, value Peer) error {
+	value, err := a.record(ctx, value)
+	if err != nil {
+		return err
+	}
+	return a.next.Add(ctx, value)
+}
+
+// Find implements PeerStorage.
+func (a *Audited) Find(ctx context.Context, key PeerKey) (Peer, error) {
+	return a.next.Find(ctx, key)
+}
+
+// Assign implements PeerStorage.
+func (a *Audited) Assign(ctx context.Context, key string, value Peer) error {
+	value, err := a.record(ctx, value)
+	if err != nil {
+		return err
+	}
+	return a.next.Assign(ctx, key, value)
+}
+
+// Resolve implements PeerStorage.
+func (a *Audited) Resolve(ctx context.Context, key string) (Peer, error) {
+	return a.next.Resolve(ctx, key)
+}
+
+// Iterate implements PeerStorage.
+func (a *Audited) Iterate(ctx context.Context) (PeerIterator, error) {
+	return a.next.Iterate(ctx)
+}
@@ -0,0 +1,59 @@
+package storage
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/gotd/td/tg"
+)
+
+func TestAudited(t *testing.T) {
+	a := require.New(t)
+	ctx := context.Background()
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	s := NewAudited(newMemStorage())
+	s.now = func() time.Time { return now }
+
+	var conflicts []Conflict
+	s.OnConflict(func(ctx context.Context, c Conflict) {
+		conflicts = append(conflicts, c)
+	})
+
+	var full, min Peer
+	a.True(full.FromUser(&tg.User{ID: 10, AccessHash: 10, Username: "user"}))
+	a.True(min.FromUser(&tg.User{ID: 10, AccessHash: 20, Min: true}))
+
+	a.NoError(s.Add(WithWriter(ctx, "harvester"), full))
+	p, err := s.Find(ctx, KeyFromPeer(full))
+	a.NoError(err)
+	w, ok := LastWriter(p)
+	a.True(ok)
+	a.Equal(LastWrite{Writer: "harvester", At: now}, w)
+	a.Empty(conflicts)
+
+	a.NoError(s.Assign(WithWriter(ctx, "resolver"), "user", min))
+	a.Len(conflicts, 2)
+	a.Equal(ConflictMinOverFull, conflicts[0].Kind)
+	a.Equal(ConflictAccessHash, conflicts[1].Kind)
+	a.Equal("resolver", conflicts[0].Writer)
+	a.Equal("harvester", conflicts[0].Previous.Writer)
+
+	a.NoError(s.Add(ctx, full))
+	p, err = s.Resolve(ctx, "user")
+	a.NoError(err)
+	w, ok = LastWriter(p)
+	a.True(ok)
+	a.Equal(UnknownWriter, w.Writer)
+
+	a.Equal(AuditStats{
+		Writes:          map[string]int{"harvester": 1, "resolver": 1, UnknownWriter: 1},
+		Conflicts:       map[ConflictKind]int{ConflictMinOverFull: 1, ConflictAccessHash: 2},
+		ConflictWriters: map[string]int{"resolver": 2, UnknownWriter: 1},
+	}, s.Stats())
+
+	_, ok = LastWriter(Peer{})
+	a.False(ok)
+}
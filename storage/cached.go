@@ -0,0 +1,223 @@
+package storage
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+)
+
+var _ PeerStorage = (*Cached)(nil)
+
+// Cached is a PeerStorage decorator which caches results of Find and
+// Resolve in LRU cache.
+//
+// Add and Assign write through to the underlying storage and invalidate
+// cached entries of written peer. Writes made bypassing Cached are not
+// observed, use Invalidate or Purge in that case.
+//
+// Returned peers are shared with cache and must not be modified.
+type Cached struct {
+	next PeerStorage
+	size int
+
+	mux   sync.Mutex
+	lru   *list.List
+	items map[cacheKey]*list.Element
+	// refs are cache keys referencing peer.
+	refs map[PeerKey]map[cacheKey]struct{}
+	// gen is incremented on every write, so value read concurrently
+	// with write is not cached.
+	gen uint64
+}
+
+type cacheKey struct {
+	// resolve is true for Resolve entries.
+	resolve bool
+	peer    PeerKey
+	key     string
+}
+
+type cacheEntry struct {
+	key   cacheKey
+	value Peer
+}
+
+// NewCached creates new Cached storage with given maximum count of cached
+// entries.
+func NewCached(next PeerStorage, size int) *Cached {
+	if size < 1 {
+		size = 1
+	}
+	return &Cached{
+		next:  next,
+		size:  size,
+		lru:   list.New(),
+		items: map[cacheKey]*list.Element{},
+		refs:  map[PeerKey]map[cacheKey]struct{}{},
+	}
+}
+
+// Len returns count of cached entries.
+func (c *Cached) Len() int {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	return c.lru.Len()
+}
+
+// get returns cached value or current generation if not found.
+func (c *Cached) get(k cacheKey) (Peer, uint64, bool) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	e, ok := c.items[k]
+	if !ok {
+		return Peer{}, c.gen, false
+	}
+	entry := e.Value.(*cacheEntry)
+	if entry.value.Expired(time.Now()) {
+		c.remove(e)
+		return Peer{}, c.gen, false
+	}
+	c.lru.MoveToFront(e)
+	return entry.value, 0, true
+}
+
+// put caches value read at given generation.
+func (c *Cached) put(k cacheKey, value Peer, gen uint64) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	if gen != c.gen {
+		return
+	}
+
+	if e, ok := c.items[k]; ok {
+		c.remove(e)
+	}
+	c.items[k] = c.lru.PushFront(&cacheEntry{key: k, value: value})
+
+	peer := KeyFromPeer(value)
+	refs, ok := c.refs[peer]
+	if !ok {
+		refs = map[cacheKey]struct{}{}
+		c.refs[peer] = refs
+	}
+	refs[k] = struct{}{}
+
+	for c.lru.Len() > c.size {
+		c.remove(c.lru.Back())
+	}
+}
+
+// remove removes element from cache. Caller must hold lock.
+func (c *Cached) remove(e *list.Element) {
+	entry := e.Value.(*cacheEntry)
+	c.lru.Remove(e)
+	delete(c.items, entry.key)
+
+	peer := KeyFromPeer(entry.value)
+	if refs, ok := c.refs[peer]; ok {
+		delete(refs, entry.key)
+		if len(refs) == 0 {
+			delete(c.refs, peer)
+		}
+	}
+}
+
+// Invalidate removes cached entries of peer with given key.
+func (c *Cached) Invalidate(key PeerKey) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	c.gen++
+	c.invalidate(key)
+}
+
+func (c *Cached) invalidate(key PeerKey) {
+	for k := range c.refs[key] {
+		if e, ok := c.items[k]; ok {
+			c.remove(e)
+		}
+	}
+}
+
+// invalidateWrite removes cached entries affected by write of given peer
+// associated with given keys.
+func (c *Cached) invalidateWrite(value Peer, keys []string) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	c.gen++
+	c.invalidate(KeyFromPeer(value))
+	// Keys may be re-associated from another peer.
+	for _, key := range keys {
+		if e, ok := c.items[cacheKey{resolve: true, key: key}]; ok {
+			c.remove(e)
+		}
+	}
+}
+
+// Purge removes all cached entries.
+func (c *Cached) Purge() {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	c.gen++
+	c.lru.Init()
+	c.items = map[cacheKey]*list.Element{}
+	c.refs = map[PeerKey]map[cacheKey]struct{}{}
+}
+
+// Add implements PeerStorage.
+func (c *Cached) Add(ctx context.Context, value Peer) error {
+	err := c.next.Add(ctx, value)
+	// Invalidate even on error: write may be partially applied.
+	c.invalidateWrite(value, value.Keys())
+	return err
+}
+
+// Find implements PeerStorage.
+func (c *Cached) Find(ctx context.Context, key PeerKey) (Peer, error) {
+	k := cacheKey{peer: key}
+	p, gen, ok := c.get(k)
+	if ok {
+		return p, nil
+	}
+
+	p, err := c.next.Find(ctx, key)
+	if err != nil {
+		return Peer{}, err
+	}
+	c.put(k, p, gen)
+	return p, nil
+}
+
+// Assign implements PeerStorage.
+func (c *Cached) Assign(ctx context.Context, key string, value Peer) error {
+	err := c.next.Assign(ctx, key, value)
+	c.invalidateWrite(value, append(value.Keys(), key))
+	return err
+}
+
+// Resolve implements PeerStorage.
+func (c *Cached) Resolve(ctx context.Context, key string) (Peer, error) {
+	k := cacheKey{resolve: true, key: key}
+	p, gen, ok := c.get(k)
+	if ok {
+		return p, nil
+	}
+
+	p, err := c.next.Resolve(ctx, key)
+	if err != nil {
+		return Peer{}, err
+	}
+	c.put(k, p, gen)
+	return p, nil
+}
+
+// Iterate implements PeerStorage. Iteration is not cached.
+func (c *Cached) Iterate(ctx context.Context) (PeerIterator, error) {
+	return c.next.Iterate(ctx)
+}
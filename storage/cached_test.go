@@ -0,0 +1,110 @@
+package storage
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/gotd/td/tg"
+)
+
+type countingStorage struct {
+	memStorage
+	finds    int
+	resolves int
+}
+
+func (c *countingStorage) Find(ctx context.Context, key PeerKey) (Peer, error) {
+	c.finds++
+	return c.memStorage.Find(ctx, key)
+}
+
+func (c *countingStorage) Resolve(ctx context.Context, key string) (Peer, error) {
+	c.resolves++
+	return c.memStorage.Resolve(ctx, key)
+}
+
+func TestCached(t *testing.T) {
+	a := require.New(t)
+	ctx := context.Background()
+	inner := &countingStorage{memStorage: newMemStorage()}
+	c := NewCached(inner, 3)
+
+	user := &tg.User{ID: 10, AccessHash: 10, Username: "old"}
+	var p Peer
+	a.True(p.FromUser(user))
+	key := KeyFromPeer(p)
+	a.NoError(c.Add(ctx, p))
+
+	for i := 0; i < 3; i++ {
+		v, err := c.Find(ctx, key)
+		a.NoError(err)
+		a.Equal("old", v.User.Username)
+		v, err = c.Resolve(ctx, "old")
+		a.NoError(err)
+		a.Equal(key, KeyFromPeer(v))
+	}
+	a.Equal(1, inner.finds)
+	a.Equal(1, inner.resolves)
+	a.Equal(2, c.Len())
+
+	// Not found results are not cached.
+	_, err := c.Resolve(ctx, "missing")
+	a.ErrorIs(err, ErrPeerNotFound)
+	_, err = c.Resolve(ctx, "missing")
+	a.ErrorIs(err, ErrPeerNotFound)
+	a.Equal(3, inner.resolves)
+
+	// Write invalidates cached entries of peer.
+	user.Username = "new"
+	a.True(p.FromUser(user))
+	a.NoError(c.Add(ctx, p))
+	a.Zero(c.Len())
+	v, err := c.Find(ctx, key)
+	a.NoError(err)
+	a.Equal("new", v.User.Username)
+	a.Equal(2, inner.finds)
+
+	// Assign invalidates re-associated key.
+	_, err = c.Resolve(ctx, "new")
+	a.NoError(err)
+	var other Peer
+	a.True(other.FromUser(&tg.User{ID: 11, AccessHash: 11}))
+	a.NoError(c.Assign(ctx, "new", other))
+	v, err = c.Resolve(ctx, "new")
+	a.NoError(err)
+	a.Equal(KeyFromPeer(other), KeyFromPeer(v))
+
+	// Least recently used entries are evicted.
+	for id := int64(20); id < 25; id++ {
+		var p Peer
+		a.True(p.FromUser(&tg.User{ID: id, AccessHash: id}))
+		a.NoError(inner.Add(ctx, p))
+		_, err := c.Find(ctx, KeyFromPeer(p))
+		a.NoError(err)
+	}
+	a.Equal(3, c.Len())
+
+	c.Purge()
+	a.Zero(c.Len())
+}
+
+func TestCached_Expired(t *testing.T) {
+	a := require.New(t)
+	ctx := context.Background()
+	inner := &countingStorage{memStorage: newMemStorage()}
+	c := NewCached(inner, 10)
+
+	var p Peer
+	a.True(p.FromUser(&tg.User{ID: 10, AccessHash: 10}))
+	p.ExpiresAt = time.Now().Add(-time.Second)
+	a.NoError(inner.Add(ctx, p))
+
+	_, err := c.Find(ctx, KeyFromPeer(p))
+	a.NoError(err)
+	_, err = c.Find(ctx, KeyFromPeer(p))
+	a.NoError(err)
+	a.Equal(2, inner.finds, "expired value is not served from cache")
+}
@@ -0,0 +1,192 @@
+package storage
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/go-faster/errors"
+
+	"github.com/gotd/td/bin"
+	"github.com/gotd/td/telegram/query/dialogs"
+	"github.com/gotd/td/tg"
+)
+
+// Codec encodes and decodes persisted peers.
+//
+// Unmarshal must return ErrPeerUnmarshalMustInvalidate if data is
+// outdated or was produced by another codec, so switching codec of
+// existing storage results in cache misses instead of errors.
+type Codec interface {
+	Marshal(p Peer) ([]byte, error)
+	Unmarshal(data []byte, p *Peer) error
+}
+
+// JSONCodec is a Codec using Peer.MarshalJSON. It is the default codec.
+type JSONCodec struct{}
+
+// Marshal implements Codec.
+func (JSONCodec) Marshal(p Peer) ([]byte, error) {
+	return p.MarshalJSON()
+}
+
+// Unmarshal implements Codec.
+func (JSONCodec) Unmarshal(data []byte, p *Peer) error {
+	if len(data) == 0 || data[0] != '{' {
+		return ErrPeerUnmarshalMustInvalidate
+	}
+	return p.UnmarshalJSON(data)
+}
+
+// binaryCodecID is a BinaryCodec format marker.
+const binaryCodecID = 0x70656572
+
+const (
+	binaryUser = 1 << iota
+	binaryChat
+	binaryChannel
+)
+
+// BinaryCodec is a compact Codec using Telegram binary serialization.
+//
+// Metadata is still encoded as JSON.
+type BinaryCodec struct{}
+
+// Marshal implements Codec.
+func (BinaryCodec) Marshal(p Peer) ([]byte, error) {
+	var flags int
+	if p.User != nil {
+		flags |= binaryUser
+	}
+	if p.Chat != nil {
+		flags |= binaryChat
+	}
+	if p.Channel != nil {
+		flags |= binaryChannel
+	}
+
+	b := new(bin.Buffer)
+	b.PutID(binaryCodecID)
+	b.PutInt(p.Version)
+	b.PutInt(int(p.Key.Kind))
+	b.PutLong(p.Key.ID)
+	b.PutLong(p.Key.AccessHash)
+	b.PutLong(p.CreatedAt.Unix())
+	var expiresAt int64
+	if !p.ExpiresAt.IsZero() {
+		expiresAt = p.ExpiresAt.UnixMilli()
+	}
+	b.PutLong(expiresAt)
+	b.PutInt(flags)
+	var entities []bin.Encoder
+	if p.User != nil {
+		entities = append(entities, p.User)
+	}
+	if p.Chat != nil {
+		entities = append(entities, p.Chat)
+	}
+	if p.Channel != nil {
+		entities = append(entities, p.Channel)
+	}
+	for _, v := range entities {
+		if err := v.Encode(b); err != nil {
+			return nil, errors.Wrap(err, "encode")
+		}
+	}
+
+	var metadata []byte
+	if p.Metadata != nil {
+		raw, err := json.Marshal(p.Metadata)
+		if err != nil {
+			return nil, errors.Wrap(err, "marshal metadata")
+		}
+		metadata = raw
+	}
+	b.PutBytes(metadata)
+
+	return b.Buf, nil
+}
+
+// Unmarshal implements Codec.
+func (BinaryCodec) Unmarshal(data []byte, p *Peer) error {
+	b := &bin.Buffer{Buf: data}
+	if err := b.ConsumeID(binaryCodecID); err != nil {
+		return ErrPeerUnmarshalMustInvalidate
+	}
+	version, err := b.Int()
+	if err != nil {
+		return errors.Wrap(err, "version")
+	}
+	if version != LatestVersion {
+		return ErrPeerUnmarshalMustInvalidate
+	}
+
+	r := Peer{Version: version}
+	kind, err := b.Int()
+	if err != nil {
+		return errors.Wrap(err, "kind")
+	}
+	r.Key.Kind = dialogs.PeerKind(kind)
+	if r.Key.ID, err = b.Long(); err != nil {
+		return errors.Wrap(err, "id")
+	}
+	if r.Key.AccessHash, err = b.Long(); err != nil {
+		return errors.Wrap(err, "access_hash")
+	}
+	createdAt, err := b.Long()
+	if err != nil {
+		return errors.Wrap(err, "created_at")
+	}
+	r.CreatedAt = time.Unix(createdAt, 0)
+	expiresAt, err := b.Long()
+	if err != nil {
+		return errors.Wrap(err, "expires_at")
+	}
+	if expiresAt != 0 {
+		r.ExpiresAt = time.UnixMilli(expiresAt)
+	}
+
+	flags, err := b.Int()
+	if err != nil {
+		return errors.Wrap(err, "flags")
+	}
+	if flags&binaryUser != 0 {
+		r.User = new(tg.User)
+		if err := r.User.Decode(b); err != nil {
+			return invalidateOutdated(errors.Wrap(err, "user"))
+		}
+	}
+	if flags&binaryChat != 0 {
+		r.Chat = new(tg.Chat)
+		if err := r.Chat.Decode(b); err != nil {
+			return invalidateOutdated(errors.Wrap(err, "chat"))
+		}
+	}
+	if flags&binaryChannel != 0 {
+		r.Channel = new(tg.Channel)
+		if err := r.Channel.Decode(b); err != nil {
+			return invalidateOutdated(errors.Wrap(err, "channel"))
+		}
+	}
+
+	metadata, err := b.Bytes()
+	if err != nil {
+		return errors.Wrap(err, "metadata")
+	}
+	if len(metadata) > 0 {
+		if err := json.Unmarshal(metadata, &r.Metadata); err != nil {
+			return errors.Wrap(err, "unmarshal metadata")
+		}
+	}
+
+	*p = r
+	return nil
+}
+
+// invalidateOutdated converts error of decoding entity of unknown
+// (e.g. older layer) type into ErrPeerUnmarshalMustInvalidate.
+func invalidateOutdated(err error) error {
+	if _, ok := errors.Into[*bin.UnexpectedIDErr](err); ok {
+		return ErrPeerUnmarshalMustInvalidate
+	}
+	return err
+}
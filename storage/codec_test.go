@@ -0,0 +1,78 @@
+package storage
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/gotd/td/telegram/query/dialogs"
+	"github.com/gotd/td/tg"
+)
+
+func TestCodec(t *testing.T) {
+	user := &tg.User{
+		Username:   "foo",
+		ID:         100,
+		AccessHash: 200,
+		Photo:      &tg.UserProfilePhotoEmpty{},
+	}
+	user.SetFlags()
+
+	peers := []Peer{
+		{
+			Version: LatestVersion,
+			Key: dialogs.DialogKey{
+				ID:         100,
+				AccessHash: 200,
+				Kind:       dialogs.User,
+			},
+			User:      user,
+			Metadata:  map[string]any{"foo": "bar"},
+			CreatedAt: time.Unix(1681541743, 0),
+			ExpiresAt: time.UnixMilli(1681541743123),
+		},
+		{
+			Version:   LatestVersion,
+			Key:       dialogs.DialogKey{ID: 10, Kind: dialogs.Chat},
+			Chat:      &tg.Chat{ID: 10, Photo: &tg.ChatPhotoEmpty{}},
+			CreatedAt: time.Unix(1681541743, 0),
+		},
+	}
+
+	codecs := []Codec{JSONCodec{}, BinaryCodec{}}
+	for _, c := range codecs {
+		t.Run("", func(t *testing.T) {
+			a := require.New(t)
+			for _, p := range peers {
+				data, err := c.Marshal(p)
+				a.NoError(err)
+
+				var out Peer
+				a.NoError(c.Unmarshal(data, &out))
+				a.Equal(p.Key, out.Key)
+				a.Equal(p.Metadata, out.Metadata)
+				a.True(p.CreatedAt.Equal(out.CreatedAt))
+				a.True(p.ExpiresAt.Equal(out.ExpiresAt))
+				a.Equal(p.User, out.User)
+				a.Equal(p.Chat, out.Chat)
+
+				// Data of another codec is considered outdated.
+				for _, other := range codecs {
+					if other == c {
+						continue
+					}
+					a.ErrorIs(other.Unmarshal(data, &out), ErrPeerUnmarshalMustInvalidate)
+				}
+			}
+		})
+	}
+
+	t.Run("Outdated", func(t *testing.T) {
+		p := peers[0]
+		p.Version = 1
+		data, err := BinaryCodec{}.Marshal(p)
+		require.NoError(t, err)
+		require.ErrorIs(t, BinaryCodec{}.Unmarshal(data, &Peer{}), ErrPeerUnmarshalMustInvalidate)
+	})
+}
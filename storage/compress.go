@@ -0,0 +1,127 @@
+package storage
+
+import (
+	"sync"
+
+	"github.com/go-faster/errors"
+	"github.com/klauspost/compress/s2"
+	"github.com/klauspost/compress/zstd"
+)
+
+// Compression is a compression algorithm of CompressedCodec.
+type Compression byte
+
+const (
+	// CompressionZstd is a zstd compression.
+	CompressionZstd Compression = iota + 1
+	// CompressionSnappy is a snappy compression.
+	CompressionSnappy
+)
+
+// compressedMagic is a first byte of compressed values.
+//
+// Neither JSONCodec nor BinaryCodec values start with it.
+const compressedMagic = 0xfe
+
+// CompressedCodec is a Codec which compresses values of underlying codec.
+//
+// Compressed values are prefixed by magic byte and algorithm, so values
+// written before enabling compression are still readable.
+type CompressedCodec struct {
+	codec   Codec
+	alg     Compression
+	minSize int
+
+	zstdOnce sync.Once
+	zstdEnc  *zstd.Encoder
+	zstdDec  *zstd.Decoder
+	zstdErr  error
+}
+
+// NewCompressedCodec creates new CompressedCodec using given codec
+// and compression algorithm.
+func NewCompressedCodec(codec Codec, alg Compression) *CompressedCodec {
+	return &CompressedCodec{
+		codec:   codec,
+		alg:     alg,
+		minSize: 128,
+	}
+}
+
+// WithMinSize sets minimum size of value to compress. Smaller values are
+// stored as is. Default is 128.
+func (c *CompressedCodec) WithMinSize(n int) *CompressedCodec {
+	c.minSize = n
+	return c
+}
+
+func (c *CompressedCodec) zstd() (*zstd.Encoder, *zstd.Decoder, error) {
+	c.zstdOnce.Do(func() {
+		c.zstdEnc, c.zstdErr = zstd.NewWriter(nil)
+		if c.zstdErr != nil {
+			return
+		}
+		c.zstdDec, c.zstdErr = zstd.NewReader(nil, zstd.WithDecoderConcurrency(0))
+	})
+	return c.zstdEnc, c.zstdDec, c.zstdErr
+}
+
+// Marshal implements Codec.
+func (c *CompressedCodec) Marshal(p Peer) ([]byte, error) {
+	data, err := c.codec.Marshal(p)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < c.minSize {
+		return data, nil
+	}
+
+	r := []byte{compressedMagic, byte(c.alg)}
+	switch c.alg {
+	case CompressionZstd:
+		enc, _, err := c.zstd()
+		if err != nil {
+			return nil, errors.Wrap(err, "zstd")
+		}
+		r = enc.EncodeAll(data, r)
+	case CompressionSnappy:
+		r = append(r, s2.EncodeSnappy(nil, data)...)
+	default:
+		return nil, errors.Errorf("unknown compression %d", c.alg)
+	}
+	if len(r) >= len(data) {
+		// Not worth it.
+		return data, nil
+	}
+	return r, nil
+}
+
+// Unmarshal implements Codec.
+func (c *CompressedCodec) Unmarshal(data []byte, p *Peer) error {
+	if len(data) < 2 || data[0] != compressedMagic {
+		return c.codec.Unmarshal(data, p)
+	}
+
+	var (
+		alg = Compression(data[1])
+		raw []byte
+		err error
+	)
+	switch alg {
+	case CompressionZstd:
+		_, dec, zstdErr := c.zstd()
+		if zstdErr != nil {
+			return errors.Wrap(zstdErr, "zstd")
+		}
+		raw, err = dec.DecodeAll(data[2:], nil)
+	case CompressionSnappy:
+		raw, err = s2.Decode(nil, data[2:])
+	default:
+		return errors.Errorf("unknown compression %d", alg)
+	}
+	if err != nil {
+		return errors.Wrap(err, "decompress")
+	}
+
+	return c.codec.Unmarshal(raw, p)
+}
@@ -0,0 +1,51 @@
+package storage
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/gotd/td/tg"
+)
+
+func TestCompressedCodec(t *testing.T) {
+	var p Peer
+	require.True(t, p.FromUser(&tg.User{
+		ID:         1,
+		AccessHash: 2,
+		Username:   "foo",
+		FirstName:  strings.Repeat("long name ", 50),
+	}))
+
+	for _, alg := range []Compression{CompressionZstd, CompressionSnappy} {
+		t.Run("", func(t *testing.T) {
+			a := require.New(t)
+			c := NewCompressedCodec(JSONCodec{}, alg)
+
+			plain, err := JSONCodec{}.Marshal(p)
+			a.NoError(err)
+			data, err := c.Marshal(p)
+			a.NoError(err)
+			a.Less(len(data), len(plain))
+			a.Equal(byte(compressedMagic), data[0])
+
+			var out Peer
+			a.NoError(c.Unmarshal(data, &out))
+			a.Equal(p.User, out.User)
+
+			// Uncompressed values are still readable.
+			out = Peer{}
+			a.NoError(c.Unmarshal(plain, &out))
+			a.Equal(p.User, out.User)
+		})
+	}
+
+	t.Run("MinSize", func(t *testing.T) {
+		a := require.New(t)
+		c := NewCompressedCodec(BinaryCodec{}, CompressionZstd).WithMinSize(1 << 20)
+		data, err := c.Marshal(p)
+		a.NoError(err)
+		a.NotEqual(byte(compressedMagic), data[0])
+	})
+}
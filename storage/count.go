@@ -0,0 +1,79 @@
+package storage
+
+import (
+	"context"
+
+	"github.com/go-faster/errors"
+
+	"github.com/gotd/td/telegram/query/dialogs"
+)
+
+// PeerCounter is a PeerStorage which is able to count stored peers without
+// decoding them.
+type PeerCounter interface {
+	// Count returns count of stored peers of given kinds.
+	// If no kinds given, peers of all kinds are counted.
+	Count(ctx context.Context, kinds ...dialogs.PeerKind) (int, error)
+}
+
+// CountPeers returns count of peers of given kinds in the storage.
+// If no kinds given, peers of all kinds are counted.
+//
+// If storage implements PeerCounter, it is used. Otherwise,
+// CountPeers iterates over the whole storage.
+func CountPeers(ctx context.Context, s PeerStorage, kinds ...dialogs.PeerKind) (int, error) {
+	if c, ok := s.(PeerCounter); ok {
+		return c.Count(ctx, kinds...)
+	}
+
+	iter, err := s.Iterate(ctx)
+	if err != nil {
+		return 0, errors.Errorf("iterate: %w", err)
+	}
+	defer func() {
+		_ = iter.Close()
+	}()
+
+	var count int
+	if err := ForEach(ctx, iter, func(p Peer) error {
+		if matchKind(p.Key.Kind, kinds) {
+			count++
+		}
+		return nil
+	}); err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
+
+func matchKind(kind dialogs.PeerKind, kinds []dialogs.PeerKind) bool {
+	if len(kinds) == 0 {
+		return true
+	}
+	for _, k := range kinds {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// KindPrefixes returns key prefixes to scan for given kinds.
+// If no kinds given, it returns prefixes of all kinds, so keys of
+// other records sharing PeerKeyPrefix are not scanned.
+func KindPrefixes(kinds ...dialogs.PeerKind) [][]byte {
+	if len(kinds) == 0 {
+		kinds = []dialogs.PeerKind{dialogs.User, dialogs.Chat, dialogs.Channel}
+	}
+	r := make([][]byte, 0, len(kinds))
+	seen := make(map[dialogs.PeerKind]struct{}, len(kinds))
+	for _, kind := range kinds {
+		if _, ok := seen[kind]; ok {
+			continue
+		}
+		seen[kind] = struct{}{}
+		r = append(r, KindPrefix(kind))
+	}
+	return r
+}
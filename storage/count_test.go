@@ -0,0 +1,64 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/gotd/td/telegram/query/dialogs"
+	"github.com/gotd/td/tg"
+)
+
+func TestCountPeers(t *testing.T) {
+	a := require.New(t)
+	ctx := context.Background()
+	s := newMemStorage()
+
+	for i := range [3]struct{}{} {
+		var p Peer
+		a.NoError(p.FromInputPeer(&tg.InputPeerUser{
+			UserID:     int64(i) + 1,
+			AccessHash: int64(i) + 1,
+		}))
+		a.NoError(s.Add(ctx, p))
+	}
+	var p Peer
+	a.NoError(p.FromInputPeer(&tg.InputPeerChannel{
+		ChannelID:  10,
+		AccessHash: 10,
+	}))
+	a.NoError(s.Add(ctx, p))
+
+	count, err := CountPeers(ctx, s)
+	a.NoError(err)
+	a.Equal(4, count)
+
+	count, err = CountPeers(ctx, s, dialogs.User)
+	a.NoError(err)
+	a.Equal(3, count)
+
+	count, err = CountPeers(ctx, s, dialogs.Channel, dialogs.Chat)
+	a.NoError(err)
+	a.Equal(1, count)
+}
+
+func TestKindPrefixes(t *testing.T) {
+	a := require.New(t)
+	a.Equal([][]byte{
+		KindPrefix(dialogs.User),
+		KindPrefix(dialogs.Chat),
+		KindPrefix(dialogs.Channel),
+	}, KindPrefixes())
+	a.Len(KindPrefixes(dialogs.User, dialogs.User, dialogs.Channel), 2)
+
+	var p Peer
+	a.NoError(p.FromInputPeer(&tg.InputPeerChannel{
+		ChannelID:  10,
+		AccessHash: 10,
+	}))
+	k := KeyFromPeer(p).Bytes(nil)
+	a.True(bytes.HasPrefix(k, KindPrefix(dialogs.Channel)))
+	a.False(bytes.HasPrefix(k, KindPrefix(dialogs.User)))
+}
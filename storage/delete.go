@@ -0,0 +1,12 @@
+package storage
+
+import "context"
+
+// PeerDeleter is a PeerStorage which supports peer deletion.
+type PeerDeleter interface {
+	// Delete removes peer with given key and associated keys
+	// returned by Peer.Keys, if they still point to this peer.
+	//
+	// If peer not found, it returns ErrPeerNotFound error.
+	Delete(ctx context.Context, key PeerKey) error
+}
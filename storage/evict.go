@@ -0,0 +1,142 @@
+package storage
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-faster/errors"
+	"go.uber.org/multierr"
+)
+
+// PeerEvicter is a PeerStorage which supports eviction of unused peers.
+type PeerEvicter interface {
+	// Evict removes peers which were not added, assigned or touched
+	// during given duration and returns count of removed peers.
+	//
+	// Peers without recency index entry are left as is.
+	Evict(ctx context.Context, olderThan time.Duration) (int, error)
+}
+
+// errDeleteUnsupported is returned by eviction helpers if storage
+// does not support peer deletion.
+var errDeleteUnsupported = errors.New("storage does not implement PeerDeleter")
+
+// Evict removes peers not used during given duration and returns count of
+// removed peers.
+//
+// If storage implements PeerEvicter, it is used. Otherwise, Evict removes
+// peers created before the cutoff using PeerDeleter.
+func Evict(ctx context.Context, s PeerStorage, olderThan time.Duration) (int, error) {
+	if e, ok := s.(PeerEvicter); ok {
+		return e.Evict(ctx, olderThan)
+	}
+	d, ok := s.(PeerDeleter)
+	if !ok {
+		return 0, errDeleteUnsupported
+	}
+
+	iter, err := s.Iterate(ctx)
+	if err != nil {
+		return 0, errors.Wrap(err, "iterate")
+	}
+	cutoff := time.Now().Add(-olderThan)
+	keys, err := collectKeys(ctx, iter, func(p Peer) bool {
+		return !p.CreatedAt.IsZero() && p.CreatedAt.Before(cutoff)
+	})
+	if err != nil {
+		return 0, err
+	}
+	return deleteKeys(ctx, d, keys)
+}
+
+// EvictLRU removes least recently used peers, so at most max peers remain,
+// and returns count of removed peers.
+//
+// Storage must implement PeerDeleter. Recency is defined by
+// IterateFiltered with OrderRecent.
+func EvictLRU(ctx context.Context, s PeerStorage, max int) (int, error) {
+	d, ok := s.(PeerDeleter)
+	if !ok {
+		return 0, errDeleteUnsupported
+	}
+
+	iter, err := IterateFiltered(ctx, s, IterateOptions{Order: OrderRecent})
+	if err != nil {
+		return 0, errors.Wrap(err, "iterate")
+	}
+	var seen int
+	keys, err := collectKeys(ctx, iter, func(p Peer) bool {
+		seen++
+		return seen > max
+	})
+	if err != nil {
+		return 0, err
+	}
+	return deleteKeys(ctx, d, keys)
+}
+
+// collectKeys collects keys of matching peers and closes iterator.
+func collectKeys(ctx context.Context, iter PeerIterator, match func(p Peer) bool) (keys []PeerKey, rerr error) {
+	defer func() {
+		multierr.AppendInto(&rerr, iter.Close())
+	}()
+
+	for iter.Next(ctx) {
+		if p := iter.Value(); match(p) {
+			keys = append(keys, KeyFromPeer(p))
+		}
+	}
+	return keys, iter.Err()
+}
+
+func deleteKeys(ctx context.Context, d PeerDeleter, keys []PeerKey) (int, error) {
+	var n int
+	for _, key := range keys {
+		if err := d.Delete(ctx, key); err != nil {
+			if errors.Is(err, ErrPeerNotFound) {
+				continue
+			}
+			return n, errors.Wrapf(err, "delete %s", key)
+		}
+		n++
+	}
+	return n, nil
+}
+
+type touchOnRead struct {
+	PeerStorage
+	toucher PeerToucher
+}
+
+func (t touchOnRead) touch(ctx context.Context, p Peer, err error) (Peer, error) {
+	if err != nil {
+		return p, err
+	}
+	if err := t.toucher.Touch(ctx, KeyFromPeer(p)); err != nil && !errors.Is(err, ErrPeerNotFound) {
+		return Peer{}, errors.Wrap(err, "touch")
+	}
+	return p, nil
+}
+
+func (t touchOnRead) Find(ctx context.Context, key PeerKey) (Peer, error) {
+	p, err := t.PeerStorage.Find(ctx, key)
+	return t.touch(ctx, p, err)
+}
+
+func (t touchOnRead) Resolve(ctx context.Context, key string) (Peer, error) {
+	p, err := t.PeerStorage.Resolve(ctx, key)
+	return t.touch(ctx, p, err)
+}
+
+// TouchOnRead returns PeerStorage which touches peers returned by Find and
+// Resolve, so recency index, used by Evict and EvictLRU, tracks reads too.
+//
+// If storage does not implement PeerToucher, it is returned as is.
+// Other optional interfaces of storage are not exposed by returned one.
+func TouchOnRead(s PeerStorage) PeerStorage {
+	toucher, ok := s.(PeerToucher)
+	if !ok {
+		return s
+	}
+	return touchOnRead{PeerStorage: s, toucher: toucher}
+}
@@ -0,0 +1,58 @@
+package storage
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/gotd/td/tg"
+)
+
+type deletingStorage struct {
+	memStorage
+}
+
+func (d deletingStorage) Delete(ctx context.Context, key PeerKey) error {
+	if _, ok := d.peers[key]; !ok {
+		return ErrPeerNotFound
+	}
+	delete(d.peers, key)
+	return nil
+}
+
+func TestEvict(t *testing.T) {
+	a := require.New(t)
+	ctx := context.Background()
+
+	_, err := Evict(ctx, newMemStorage(), time.Hour)
+	a.Error(err, "deletion is not supported")
+
+	s := deletingStorage{memStorage: newMemStorage()}
+	now := time.Now()
+	for i, createdAt := range []time.Time{
+		now.Add(-2 * time.Hour),
+		now.Add(-time.Minute),
+		{},
+	} {
+		var p Peer
+		a.True(p.FromUser(&tg.User{ID: int64(i) + 10, AccessHash: 10}))
+		p.CreatedAt = createdAt
+		a.NoError(s.Add(ctx, p))
+	}
+
+	n, err := Evict(ctx, s, time.Hour)
+	a.NoError(err)
+	a.Equal(1, n)
+	a.Len(s.peers, 2)
+
+	// Recency is emulated by CreatedAt.
+	n, err = EvictLRU(ctx, s, 1)
+	a.NoError(err)
+	a.Equal(1, n)
+	a.Len(s.peers, 1)
+	for key := range s.peers {
+		a.Equal(int64(11), key.ID)
+	}
+}
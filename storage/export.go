@@ -0,0 +1,59 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+
+	"github.com/go-faster/errors"
+)
+
+// Export writes all peers from given storage to w as line-delimited JSON,
+// one peer per line.
+//
+// Only peers are exported: keys associated with Assign are not
+// enumerable and must be re-assigned after Import.
+func Export(ctx context.Context, w io.Writer, s PeerStorage) error {
+	iter, err := s.Iterate(ctx)
+	if err != nil {
+		return errors.Errorf("iterate: %w", err)
+	}
+	defer func() {
+		_ = iter.Close()
+	}()
+
+	e := json.NewEncoder(w)
+	return ForEach(ctx, iter, func(p Peer) error {
+		if err := e.Encode(p); err != nil {
+			return errors.Errorf("encode %s: %w", KeyFromPeer(p), err)
+		}
+		return nil
+	})
+}
+
+// Import reads peers written by Export from r and adds them to given storage.
+//
+// Outdated entries (see ErrPeerUnmarshalMustInvalidate) are skipped.
+func Import(ctx context.Context, r io.Reader, s PeerStorage) error {
+	d := json.NewDecoder(r)
+	for line := 1; ; line++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		var p Peer
+		if err := d.Decode(&p); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			if errors.Is(err, ErrPeerUnmarshalMustInvalidate) {
+				continue
+			}
+			return errors.Errorf("decode entry %d: %w", line, err)
+		}
+
+		if err := s.Add(ctx, p); err != nil {
+			return errors.Errorf("add %s: %w", KeyFromPeer(p), err)
+		}
+	}
+}
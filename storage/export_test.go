@@ -0,0 +1,48 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/gotd/td/tg"
+)
+
+func TestExportImport(t *testing.T) {
+	a := require.New(t)
+	ctx := context.Background()
+
+	src := newMemStorage()
+	for i := range [3]struct{}{} {
+		var p Peer
+		a.True(p.FromUser(&tg.User{
+			ID:         int64(i) + 1,
+			AccessHash: int64(i) + 10,
+			Username:   "user" + string(rune('a'+i)),
+		}))
+		p.Metadata = map[string]any{"n": float64(i)}
+		a.NoError(src.Add(ctx, p))
+	}
+
+	var buf bytes.Buffer
+	a.NoError(Export(ctx, &buf, src))
+	a.Equal(3, strings.Count(buf.String(), "\n"))
+
+	// Outdated entry is skipped.
+	buf.WriteString(`{"Version":1}` + "\n")
+
+	dst := newMemStorage()
+	a.NoError(Import(ctx, &buf, dst))
+	a.Len(dst.peers, 3)
+	for key, expected := range src.peers {
+		got, err := dst.Find(ctx, key)
+		a.NoError(err)
+		a.Equal(expected.User.AccessHash, got.User.AccessHash)
+		a.Equal(expected.Metadata, got.Metadata)
+	}
+
+	a.Error(Import(ctx, strings.NewReader("{broken\n"), dst))
+}
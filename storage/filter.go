@@ -0,0 +1,164 @@
+package storage
+
+import (
+	"context"
+	"sort"
+
+	"github.com/gotd/td/telegram/query/dialogs"
+)
+
+// IterateOrder is an order of peer iteration.
+type IterateOrder int
+
+const (
+	// OrderKey is a default, storage-defined order.
+	OrderKey IterateOrder = iota
+	// OrderRecent returns most recently touched peers first.
+	OrderRecent
+	// OrderRecentReverse returns least recently touched peers first.
+	OrderRecentReverse
+)
+
+// IterateOptions is a peer iteration filter.
+type IterateOptions struct {
+	// Kinds limits iteration to peers of given kinds.
+	// If empty, peers of all kinds are returned.
+	Kinds []dialogs.PeerKind
+	// Bots limits iteration to bot users.
+	Bots bool
+	// Order is an iteration order.
+	Order IterateOrder
+}
+
+// Prefixes returns key prefixes to scan for these options.
+//
+// Result is empty if options could not match any peer.
+func (o IterateOptions) Prefixes() [][]byte {
+	if o.Bots {
+		if !matchKind(dialogs.User, o.Kinds) {
+			return nil
+		}
+		return KindPrefixes(dialogs.User)
+	}
+	return KindPrefixes(o.Kinds...)
+}
+
+// Match reports whether given peer matches options.
+func (o IterateOptions) Match(p Peer) bool {
+	if !matchKind(p.Key.Kind, o.Kinds) {
+		return false
+	}
+	if o.Bots {
+		return p.Key.Kind == dialogs.User && p.User != nil && p.User.Bot
+	}
+	return true
+}
+
+// FilteredPeerStorage is a PeerStorage which supports filtered iteration
+// pushed down into the storage query.
+type FilteredPeerStorage interface {
+	// IterateFiltered creates and returns new PeerIterator
+	// which returns only peers matching given options in given order.
+	IterateFiltered(ctx context.Context, opts IterateOptions) (PeerIterator, error)
+}
+
+// PeerToucher is a PeerStorage which maintains last-touched index used by
+// OrderRecent iteration.
+//
+// Add and Assign touch peer implicitly.
+type PeerToucher interface {
+	// Touch marks peer with given key as recently used.
+	Touch(ctx context.Context, key PeerKey) error
+}
+
+// IterateFiltered creates and returns new PeerIterator which returns only
+// peers matching given options.
+//
+// If storage implements FilteredPeerStorage, it is used. Otherwise,
+// IterateFiltered filters result of Iterate. Recency order is emulated by
+// loading all matching peers and sorting them by Peer.CreatedAt.
+func IterateFiltered(ctx context.Context, s PeerStorage, opts IterateOptions) (PeerIterator, error) {
+	if f, ok := s.(FilteredPeerStorage); ok {
+		return f.IterateFiltered(ctx, opts)
+	}
+
+	iter, err := s.Iterate(ctx)
+	if err != nil {
+		return nil, err
+	}
+	filtered := &filterIterator{PeerIterator: iter, match: opts.Match}
+	if opts.Order == OrderKey {
+		return filtered, nil
+	}
+	defer func() {
+		_ = filtered.Close()
+	}()
+
+	var peers []Peer
+	if err := ForEach(ctx, filtered, func(p Peer) error {
+		peers = append(peers, p)
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	sort.SliceStable(peers, func(i, j int) bool {
+		if opts.Order == OrderRecentReverse {
+			return peers[i].CreatedAt.Before(peers[j].CreatedAt)
+		}
+		return peers[i].CreatedAt.After(peers[j].CreatedAt)
+	})
+	return NewSliceIterator(peers), nil
+}
+
+type filterIterator struct {
+	PeerIterator
+	match func(p Peer) bool
+}
+
+func (f *filterIterator) Next(ctx context.Context) bool {
+	for f.PeerIterator.Next(ctx) {
+		if f.match(f.PeerIterator.Value()) {
+			return true
+		}
+	}
+	return false
+}
+
+// EmptyIterator returns PeerIterator without any peers.
+func EmptyIterator() PeerIterator {
+	return emptyIterator{}
+}
+
+type emptyIterator struct{}
+
+func (emptyIterator) Next(context.Context) bool { return false }
+
+func (emptyIterator) Err() error { return nil }
+
+func (emptyIterator) Value() Peer { return Peer{} }
+
+func (emptyIterator) Close() error { return nil }
+
+// NewSliceIterator returns PeerIterator over given peers.
+func NewSliceIterator(peers []Peer) PeerIterator {
+	return &sliceIterator{peers: peers}
+}
+
+type sliceIterator struct {
+	peers []Peer
+	value Peer
+}
+
+func (s *sliceIterator) Next(context.Context) bool {
+	if len(s.peers) == 0 {
+		return false
+	}
+	s.value, s.peers = s.peers[0], s.peers[1:]
+	return true
+}
+
+func (s *sliceIterator) Err() error { return nil }
+
+func (s *sliceIterator) Value() Peer { return s.value }
+
+func (s *sliceIterator) Close() error { return nil }
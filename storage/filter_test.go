@@ -0,0 +1,88 @@
+package storage
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/gotd/td/telegram/query/dialogs"
+	"github.com/gotd/td/tg"
+)
+
+func TestIterateOptions(t *testing.T) {
+	a := require.New(t)
+
+	var user, bot, channel Peer
+	a.True(user.FromUser(&tg.User{ID: 1}))
+	a.True(bot.FromUser(&tg.User{ID: 2, Bot: true}))
+	a.True(channel.FromChat(&tg.Channel{ID: 3}))
+
+	all := IterateOptions{}
+	a.True(all.Match(user))
+	a.True(all.Match(bot))
+	a.True(all.Match(channel))
+	a.Equal(KindPrefixes(), all.Prefixes())
+
+	bots := IterateOptions{Bots: true}
+	a.False(bots.Match(user))
+	a.True(bots.Match(bot))
+	a.False(bots.Match(channel))
+	a.Equal([][]byte{KindPrefix(dialogs.User)}, bots.Prefixes())
+
+	channels := IterateOptions{Kinds: []dialogs.PeerKind{dialogs.Channel}}
+	a.False(channels.Match(user))
+	a.True(channels.Match(channel))
+	a.Empty(IterateOptions{Kinds: channels.Kinds, Bots: true}.Prefixes())
+}
+
+func TestIterateFiltered(t *testing.T) {
+	a := require.New(t)
+	ctx := context.Background()
+	s := newMemStorage()
+
+	var user, bot Peer
+	a.True(user.FromUser(&tg.User{ID: 1}))
+	a.True(bot.FromUser(&tg.User{ID: 2, Bot: true}))
+	a.NoError(s.Add(ctx, user))
+	a.NoError(s.Add(ctx, bot))
+
+	iter, err := IterateFiltered(ctx, s, IterateOptions{Bots: true})
+	a.NoError(err)
+	var r []Peer
+	a.NoError(ForEach(ctx, iter, func(p Peer) error {
+		r = append(r, p)
+		return nil
+	}))
+	a.NoError(iter.Close())
+	a.Equal([]Peer{bot}, r)
+}
+
+func TestIterateFilteredOrder(t *testing.T) {
+	a := require.New(t)
+	ctx := context.Background()
+	s := newMemStorage()
+
+	now := time.Now()
+	for i := range [3]struct{}{} {
+		var p Peer
+		a.True(p.FromUser(&tg.User{ID: int64(i) + 1}))
+		p.CreatedAt = now.Add(time.Duration(2-i) * time.Second)
+		a.NoError(s.Add(ctx, p))
+	}
+
+	collect := func(order IterateOrder) (r []int64) {
+		iter, err := IterateFiltered(ctx, s, IterateOptions{Order: order})
+		a.NoError(err)
+		a.NoError(ForEach(ctx, iter, func(p Peer) error {
+			r = append(r, p.Key.ID)
+			return nil
+		}))
+		a.NoError(iter.Close())
+		return r
+	}
+
+	a.Equal([]int64{1, 2, 3}, collect(OrderRecent))
+	a.Equal([]int64{3, 2, 1}, collect(OrderRecentReverse))
+}
@@ -0,0 +1,57 @@
+package storage
+
+import (
+	"context"
+
+	"github.com/go-faster/errors"
+
+	"github.com/gotd/td/constant"
+	"github.com/gotd/td/telegram/query/dialogs"
+)
+
+// PeerIDFinder is a PeerStorage which is able to find peer by bare ID
+// regardless of its kind.
+type PeerIDFinder interface {
+	// FindByID finds peer with given ID of any kind. If peers of several
+	// kinds have the same ID, user is preferred over chat, and chat over
+	// channel.
+	//
+	// If peer not found, it returns ErrPeerNotFound error.
+	FindByID(ctx context.Context, id int64) (Peer, error)
+}
+
+// IDKinds are peer kinds in order tried by FindByID.
+var IDKinds = []dialogs.PeerKind{dialogs.User, dialogs.Chat, dialogs.Channel} // nolint:gochecknoglobals
+
+// FindByID finds peer by ID regardless of its kind.
+//
+// Positive ID is considered as bare ID of any kind. Negative ID is
+// considered as Bot API (TDLib) ID of chat or channel, e.g. -100123 is a
+// channel 123.
+//
+// If storage implements PeerIDFinder, it is used for bare IDs. Otherwise,
+// FindByID tries to find peer of every kind in IDKinds order.
+func FindByID(ctx context.Context, s PeerStorage, id int64) (Peer, error) {
+	if id < 0 {
+		tdlib := constant.TDLibPeerID(id)
+		kind := dialogs.Chat
+		if tdlib.IsChannel() {
+			kind = dialogs.Channel
+		}
+		return s.Find(ctx, PeerKey{Kind: kind, ID: tdlib.ToPlain()})
+	}
+	if f, ok := s.(PeerIDFinder); ok {
+		return f.FindByID(ctx, id)
+	}
+
+	for _, kind := range IDKinds {
+		p, err := s.Find(ctx, PeerKey{Kind: kind, ID: id})
+		if err == nil {
+			return p, nil
+		}
+		if !errors.Is(err, ErrPeerNotFound) {
+			return Peer{}, err
+		}
+	}
+	return Peer{}, ErrPeerNotFound
+}
@@ -0,0 +1,46 @@
+package storage
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/gotd/td/telegram/query/dialogs"
+	"github.com/gotd/td/tg"
+)
+
+func TestFindByID(t *testing.T) {
+	a := require.New(t)
+	ctx := context.Background()
+	s := newMemStorage()
+
+	for _, input := range []tg.InputPeerClass{
+		&tg.InputPeerChat{ChatID: 10},
+		&tg.InputPeerChannel{ChannelID: 20, AccessHash: 20},
+		&tg.InputPeerUser{UserID: 20, AccessHash: 20},
+	} {
+		var p Peer
+		a.NoError(p.FromInputPeer(input))
+		a.NoError(s.Add(ctx, p))
+	}
+
+	for _, tt := range []struct {
+		id   int64
+		kind dialogs.PeerKind
+	}{
+		{10, dialogs.Chat},
+		{20, dialogs.User},
+		{-10, dialogs.Chat},
+		{-1000000000020, dialogs.Channel},
+	} {
+		p, err := FindByID(ctx, s, tt.id)
+		a.NoError(err, tt.id)
+		a.Equal(tt.kind, p.Key.Kind, tt.id)
+	}
+
+	_, err := FindByID(ctx, s, 30)
+	a.ErrorIs(err, ErrPeerNotFound)
+	_, err = FindByID(ctx, s, -20)
+	a.ErrorIs(err, ErrPeerNotFound)
+}
@@ -0,0 +1,95 @@
+package storage
+
+import "context"
+
+// GCOptions is a garbage collection options.
+type GCOptions struct {
+	// Mismatched enables removal of associated keys which point to a stored
+	// peer that does not have this key anymore, e.g. old username.
+	//
+	// Keys associated explicitly by Assign are not returned by Peer.Keys,
+	// so they are removed too. Do not enable it if Assign is used with
+	// custom keys.
+	Mismatched bool
+	// DryRun disables removal, so only count of orphaned keys is returned.
+	DryRun bool
+}
+
+// PeerGarbageCollector is a PeerStorage which is able to remove orphaned
+// associated keys.
+type PeerGarbageCollector interface {
+	// CollectGarbage removes associated keys which point to missing peers
+	// and returns count of removed keys.
+	CollectGarbage(ctx context.Context, opts GCOptions) (int, error)
+}
+
+// CollectGarbage removes orphaned associated keys and returns count of
+// removed keys.
+//
+// Storages which do not implement PeerGarbageCollector are left as is.
+func CollectGarbage(ctx context.Context, s PeerStorage, opts GCOptions) (int, error) {
+	c, ok := s.(PeerGarbageCollector)
+	if !ok {
+		return 0, nil
+	}
+	return c.CollectGarbage(ctx, opts)
+}
+
+// AssociatedKey reports whether given raw key-value pair looks like
+// associated key and returns key of peer it points to.
+//
+// Peer records, index entries (keys prefixed by "_") and values which are
+// not peer keys, e.g. data of other storages, are not associated keys.
+func AssociatedKey(key, value []byte) (PeerKey, bool) {
+	if len(key) == 0 || key[0] == '_' {
+		return PeerKey{}, false
+	}
+	var k PeerKey
+	if k.Parse(key) == nil {
+		// Peer record.
+		return PeerKey{}, false
+	}
+	if k.Parse(value) != nil {
+		return PeerKey{}, false
+	}
+	return k, true
+}
+
+// Orphaned reports whether associated key is orphaned. Found is false if
+// peer it points to is missing or invalidated.
+func Orphaned(key string, p Peer, found bool, opts GCOptions) bool {
+	if !found {
+		return true
+	}
+	if !opts.Mismatched {
+		return false
+	}
+	for _, k := range p.Keys() {
+		if k == key {
+			return false
+		}
+	}
+	return true
+}
+
+// StaleKeys returns keys of stored peer value which are not in given
+// associated keys of new value.
+//
+// Storage uses it on update to remove keys like old username, if they still
+// point to updated peer.
+func StaleKeys(stored Peer, associated []string) []string {
+	var r []string
+	for _, k := range stored.Keys() {
+		stale := true
+		for _, a := range associated {
+			if k == a {
+				stale = false
+				break
+			}
+		}
+		if stale {
+			r = append(r, k)
+		}
+	}
+	return r
+}
@@ -0,0 +1,59 @@
+package storage
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/gotd/td/telegram/query/dialogs"
+	"github.com/gotd/td/tg"
+)
+
+func TestAssociatedKey(t *testing.T) {
+	target := PeerKey{Kind: dialogs.Channel, ID: 10}
+	for _, tt := range []struct {
+		key, value string
+		ok         bool
+	}{
+		{"username", target.String(), true},
+		{"peerfoo", target.String(), true},
+		{"", target.String(), false},
+		{"_recent:1", target.String(), false},
+		{target.String(), "{}", false},
+		{"session", "{}", false},
+	} {
+		k, ok := AssociatedKey([]byte(tt.key), []byte(tt.value))
+		require.Equal(t, tt.ok, ok, tt.key)
+		if ok {
+			require.Equal(t, target, k)
+		}
+	}
+}
+
+func TestOrphaned(t *testing.T) {
+	a := require.New(t)
+
+	var p Peer
+	a.True(p.FromUser(&tg.User{ID: 10, Username: "user"}))
+
+	a.True(Orphaned("user", Peer{}, false, GCOptions{}))
+	a.False(Orphaned("user", p, true, GCOptions{}))
+	a.False(Orphaned("custom", p, true, GCOptions{}))
+	a.False(Orphaned("user", p, true, GCOptions{Mismatched: true}))
+	a.True(Orphaned("custom", p, true, GCOptions{Mismatched: true}))
+}
+
+func TestStaleKeys(t *testing.T) {
+	var p Peer
+	require.True(t, p.FromUser(&tg.User{ID: 10, Username: "old", Phone: "123"}))
+
+	require.Equal(t, []string{"old"}, StaleKeys(p, []string{"new", "123"}))
+	require.Empty(t, StaleKeys(p, []string{"old", "123"}))
+}
+
+func TestCollectGarbage(t *testing.T) {
+	n, err := CollectGarbage(context.Background(), newMemStorage(), GCOptions{})
+	require.NoError(t, err)
+	require.Zero(t, n)
+}
@@ -2,10 +2,12 @@ package storage
 
 import (
 	"context"
+	"time"
 
 	"go.uber.org/multierr"
 
 	"github.com/gotd/td/telegram"
+	"github.com/gotd/td/telegram/query/dialogs"
 	"github.com/gotd/td/tg"
 )
 
@@ -20,29 +22,94 @@ type updatesWithPeers interface {
 	tg.UpdatesClass
 }
 
-func (h updateHook) Handle(ctx context.Context, u tg.UpdatesClass) error {
-	updates, ok := u.(updatesWithPeers)
-	if !ok {
-		return h.next.Handle(ctx, u)
+// collect returns peers from given updates container.
+//
+// Min users and channels are collected too, so peers seen only as min are
+// stored. Merge keeps stored full entities, if storage merges peers, as
+// built-in storages do. Chats migrated to channels are collected as
+// key-only peers, if channel entity is missing.
+func (h updateHook) collect(updates updatesWithPeers) []Peer {
+	var (
+		peers    []Peer
+		idx      = map[PeerKey]int{}
+		migrated []*tg.InputChannel
+	)
+	add := func(value Peer) {
+		key := KeyFromPeer(value)
+		if i, ok := idx[key]; ok {
+			peers[i] = Merge(peers[i], value)
+			return
+		}
+		idx[key] = len(peers)
+		peers = append(peers, value)
 	}
 
-	var rerr error
 	for _, chat := range updates.GetChats() {
 		if value := (Peer{}); value.FromChat(chat) {
-			multierr.AppendInto(&rerr, h.storage.Add(ctx, value))
+			add(value)
+		} else if c, ok := chat.(*tg.Channel); ok && c.Min {
+			add(minChannel(c))
+		}
+		if c, ok := chat.(*tg.Chat); ok {
+			if ch, ok := c.MigratedTo.(*tg.InputChannel); ok {
+				migrated = append(migrated, ch)
+			}
 		}
 	}
-
 	for _, user := range updates.GetUsers() {
 		if value := (Peer{}); value.FromUser(user) {
-			multierr.AppendInto(&rerr, h.storage.Add(ctx, value))
+			add(value)
+		}
+	}
+	for _, ch := range migrated {
+		var value Peer
+		if err := value.FromInputPeer(&tg.InputPeerChannel{
+			ChannelID:  ch.ChannelID,
+			AccessHash: ch.AccessHash,
+		}); err != nil {
+			continue
 		}
+		if _, ok := idx[KeyFromPeer(value)]; !ok {
+			add(value)
+		}
+	}
+
+	return peers
+}
+
+// minChannel returns peer of min channel, which is rejected by
+// Peer.FromChat.
+func minChannel(c *tg.Channel) Peer {
+	return Peer{
+		Version:   LatestVersion,
+		CreatedAt: time.Now(),
+		Key: dialogs.DialogKey{
+			Kind:       dialogs.Channel,
+			ID:         c.ID,
+			AccessHash: c.AccessHash,
+		},
+		Channel: c,
+	}
+}
+
+func (h updateHook) Handle(ctx context.Context, u tg.UpdatesClass) error {
+	updates, ok := u.(updatesWithPeers)
+	if !ok {
+		return h.next.Handle(ctx, u)
+	}
+
+	var rerr error
+	if peers := h.collect(updates); len(peers) > 0 {
+		rerr = AddAll(ctx, h.storage, peers)
 	}
 
 	return multierr.Append(rerr, h.next.Handle(ctx, u))
 }
 
 // UpdateHook creates update hook, to collect peer data from updates.
+//
+// Peers of every updates container are written at once, using
+// PeerBatchStorage if storage implements it.
 func UpdateHook(next telegram.UpdateHandler, storage PeerStorage) telegram.UpdateHandler {
 	return updateHook{
 		next:    next,
@@ -7,6 +7,7 @@ import (
 	"github.com/go-faster/errors"
 	"github.com/stretchr/testify/require"
 
+	"github.com/gotd/td/telegram/query/dialogs"
 	"github.com/gotd/td/tg"
 )
 
@@ -75,3 +76,49 @@ func TestUpdateHook(t *testing.T) {
 		a.NotNil(p.User)
 	})
 }
+
+func TestUpdateHookCollect(t *testing.T) {
+	a := require.New(t)
+	ctx := context.Background()
+	s := &batchStorage{memStorage: newMemStorage()}
+	h := UpdateHook(testHandler{}, s)
+
+	a.NoError(h.Handle(ctx, &tg.UpdatesCombined{
+		Chats: []tg.ChatClass{
+			&tg.Chat{
+				ID:         20,
+				MigratedTo: &tg.InputChannel{ChannelID: 21, AccessHash: 21},
+			},
+			&tg.Channel{ID: 10, AccessHash: 10, Min: true},
+		},
+		Users: []tg.UserClass{
+			&tg.User{ID: 10, AccessHash: 10, Username: "full"},
+			// Min constructor of the same user does not downgrade full one.
+			&tg.User{ID: 10, AccessHash: 100, Min: true},
+			&tg.User{ID: 11, AccessHash: 11, Min: true},
+		},
+	}))
+	a.Equal([]int{5}, s.batches)
+
+	p, err := s.Resolve(ctx, "full")
+	a.NoError(err)
+	a.False(p.Min())
+	a.Equal(int64(10), p.Key.AccessHash)
+
+	a.Contains(s.peers, PeerKey{Kind: dialogs.Chat, ID: 20})
+	p, err = s.Find(ctx, PeerKey{Kind: dialogs.Channel, ID: 21})
+	a.NoError(err)
+	a.Equal(int64(21), p.Key.AccessHash)
+
+	// Peers seen only as min are stored.
+	p, err = s.Find(ctx, PeerKey{Kind: dialogs.Channel, ID: 10})
+	a.NoError(err)
+	a.True(p.Min())
+	p, err = s.Find(ctx, PeerKey{Kind: dialogs.User, ID: 11})
+	a.NoError(err)
+	a.True(p.Min())
+
+	// Updates without peers are not written.
+	a.NoError(h.Handle(ctx, &tg.Updates{}))
+	a.Len(s.batches, 1)
+}
@@ -87,3 +87,12 @@ func (k *PeerKey) Parse(r []byte) error {
 
 	return nil
 }
+
+// KindPrefix returns key prefix of all peers of given kind.
+func KindPrefix(kind dialogs.PeerKind) []byte {
+	r := make([]byte, 0, len(PeerKeyPrefix)+2)
+	r = append(r, PeerKeyPrefix...)
+	r = strconv.AppendInt(r, int64(kind), 10)
+	r = append(r, keySeparator)
+	return r
+}
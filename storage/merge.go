@@ -0,0 +1,57 @@
+package storage
+
+import "time"
+
+// Min reports whether peer contains min constructor of user or channel.
+//
+// Access hash of min constructor cannot be used in most API calls.
+//
+// See https://core.telegram.org/api/min.
+func (p Peer) Min() bool {
+	switch {
+	case p.User != nil:
+		return p.User.Min
+	case p.Channel != nil:
+		return p.Channel.Min
+	default:
+		return false
+	}
+}
+
+// Merge merges new value of the same peer into the stored one and
+// returns result to store.
+//
+// Merge does not overwrite full peer with min one, keeps stored access hash
+// and entity if new value does not have them, and merges metadata, so new
+// values override stored ones. Expired stored peer is ignored.
+func Merge(stored, value Peer) Peer {
+	if KeyFromPeer(stored) != KeyFromPeer(value) || stored.Expired(time.Now()) {
+		return value
+	}
+
+	r := value
+	if value.Min() && !stored.Min() && (stored.User != nil || stored.Channel != nil) {
+		// Keep full entity and its access hash.
+		r = stored
+		r.ExpiresAt = value.ExpiresAt
+	}
+	if r.Key.AccessHash == 0 {
+		r.Key.AccessHash = stored.Key.AccessHash
+	}
+	if r.User == nil && r.Chat == nil && r.Channel == nil {
+		r.User, r.Chat, r.Channel = stored.User, stored.Chat, stored.Channel
+	}
+
+	if len(stored.Metadata) > 0 {
+		metadata := make(map[string]any, len(stored.Metadata)+len(value.Metadata))
+		for k, v := range stored.Metadata {
+			metadata[k] = v
+		}
+		for k, v := range value.Metadata {
+			metadata[k] = v
+		}
+		r.Metadata = metadata
+	}
+
+	return r
+}
@@ -0,0 +1,59 @@
+package storage
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/gotd/td/tg"
+)
+
+func TestMerge(t *testing.T) {
+	a := require.New(t)
+
+	var full, minPeer, input Peer
+	a.True(full.FromUser(&tg.User{ID: 1, AccessHash: 10, Username: "full"}))
+	full.Metadata = map[string]any{"lang": "en", "banned": false}
+	a.True(minPeer.FromUser(&tg.User{ID: 1, AccessHash: 20, Min: true}))
+	minPeer.Metadata = map[string]any{"banned": true}
+	a.NoError(input.FromInputPeer(&tg.InputPeerUser{UserID: 1, AccessHash: 30}))
+
+	t.Run("Min", func(t *testing.T) {
+		a := require.New(t)
+		r := Merge(full, minPeer)
+		a.Equal(int64(10), r.Key.AccessHash)
+		a.Equal("full", r.User.Username)
+		a.Equal(map[string]any{"lang": "en", "banned": true}, r.Metadata)
+		a.False(r.Min())
+	})
+	t.Run("MinOverMin", func(t *testing.T) {
+		r := Merge(minPeer, minPeer)
+		require.Equal(t, int64(20), r.Key.AccessHash)
+	})
+	t.Run("NewHash", func(t *testing.T) {
+		a := require.New(t)
+		r := Merge(full, input)
+		a.Equal(int64(30), r.Key.AccessHash)
+		a.Equal(full.User, r.User, "entity should be kept")
+	})
+	t.Run("NoHash", func(t *testing.T) {
+		var forbidden Peer
+		require.True(t, forbidden.FromUser(&tg.User{ID: 1}))
+		require.Equal(t, int64(10), Merge(full, forbidden).Key.AccessHash)
+	})
+	t.Run("Expired", func(t *testing.T) {
+		a := require.New(t)
+		expired := full
+		expired.ExpiresAt = time.Now().Add(-time.Minute)
+		r := Merge(expired, minPeer)
+		a.True(r.Min())
+		a.Equal(int64(20), r.Key.AccessHash)
+		a.Equal(minPeer.Metadata, r.Metadata)
+	})
+	t.Run("OtherPeer", func(t *testing.T) {
+		var other Peer
+		require.True(t, other.FromUser(&tg.User{ID: 2}))
+		require.Equal(t, other, Merge(full, other))
+	})
+}
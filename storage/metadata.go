@@ -0,0 +1,51 @@
+package storage
+
+import (
+	"context"
+
+	"github.com/go-faster/errors"
+)
+
+// SetMetadata sets metadata value of stored peer with given key.
+// Nil value removes metadata entry.
+//
+// Metadata is persisted alongside the peer record, so value must be
+// JSON-serializable. Note that value is read back as decoded by
+// encoding/json, e.g. numbers are float64.
+//
+// SetMetadata is not atomic: concurrent updates of the same peer may be lost.
+func SetMetadata(ctx context.Context, s PeerStorage, key PeerKey, name string, value any) error {
+	p, err := s.Find(ctx, key)
+	if err != nil {
+		return errors.Errorf("find %s: %w", key, err)
+	}
+
+	metadata := make(map[string]any, len(p.Metadata)+1)
+	for k, v := range p.Metadata {
+		metadata[k] = v
+	}
+	// Keep nil entry instead of deleting it, so Add does not merge
+	// stored value back.
+	metadata[name] = value
+	p.Metadata = metadata
+
+	if err := s.Add(ctx, p); err != nil {
+		return errors.Errorf("add %s: %w", key, err)
+	}
+	return nil
+}
+
+// GetMetadata returns metadata value of stored peer with given key.
+//
+// If peer not found, it returns ErrPeerNotFound error.
+func GetMetadata(ctx context.Context, s PeerStorage, key PeerKey, name string) (any, bool, error) {
+	p, err := s.Find(ctx, key)
+	if err != nil {
+		return nil, false, err
+	}
+	v, ok := p.Metadata[name]
+	if !ok || v == nil {
+		return nil, false, nil
+	}
+	return v, true, nil
+}
@@ -0,0 +1,37 @@
+package storage
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/gotd/td/tg"
+)
+
+func TestMetadata(t *testing.T) {
+	a := require.New(t)
+	ctx := context.Background()
+	s := newMemStorage()
+
+	var p Peer
+	a.True(p.FromUser(&tg.User{ID: 1}))
+	p.Metadata = map[string]any{"lang": "en"}
+	a.NoError(s.Add(ctx, p))
+	key := KeyFromPeer(p)
+
+	a.NoError(SetMetadata(ctx, s, key, "banned", true))
+	a.Equal(map[string]any{"lang": "en"}, p.Metadata, "caller map must not be modified")
+
+	v, ok, err := GetMetadata(ctx, s, key, "banned")
+	a.NoError(err)
+	a.True(ok)
+	a.Equal(true, v)
+
+	_, ok, err = GetMetadata(ctx, s, key, "missing")
+	a.NoError(err)
+	a.False(ok)
+
+	_, _, err = GetMetadata(ctx, s, PeerKey{ID: 2}, "lang")
+	a.ErrorIs(err, ErrPeerNotFound)
+}
@@ -0,0 +1,119 @@
+package storage
+
+import (
+	"context"
+
+	"github.com/go-faster/errors"
+)
+
+// PeerBatchStorage is a PeerStorage which is able to add multiple peers
+// at once.
+type PeerBatchStorage interface {
+	// AddAll adds given peers to the storage.
+	AddAll(ctx context.Context, values []Peer) error
+}
+
+// AddAll adds given peers to the storage.
+//
+// If storage implements PeerBatchStorage, it is used. Otherwise,
+// peers are added one by one.
+func AddAll(ctx context.Context, s PeerStorage, values []Peer) error {
+	if b, ok := s.(PeerBatchStorage); ok {
+		return b.AddAll(ctx, values)
+	}
+
+	for _, p := range values {
+		if err := s.Add(ctx, p); err != nil {
+			return errors.Errorf("add %s: %w", KeyFromPeer(p), err)
+		}
+	}
+	return nil
+}
+
+// MigrateProgress is a migration progress.
+type MigrateProgress struct {
+	// Peers is a count of copied peers.
+	Peers int
+	// Keys is a count of copied associated keys.
+	Keys int
+}
+
+// MigrateOptions is a Migrate options.
+type MigrateOptions struct {
+	// BatchSize is a maximum count of peers passed to AddAll at once.
+	// Default is 100.
+	BatchSize int
+	// Keys are associated keys to copy.
+	//
+	// Keys returned by Peer.Keys are associated by Add, but keys assigned
+	// explicitly by Assign are not enumerable, so they must be listed
+	// here. Keys not found in source storage are skipped.
+	Keys []string
+	// Progress is called after every batch and copied key.
+	Progress func(MigrateProgress)
+}
+
+// Migrate copies all peers and given associated keys from one storage
+// to another.
+func Migrate(ctx context.Context, from, to PeerStorage, opts MigrateOptions) error {
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = 100
+	}
+	var progress MigrateProgress
+	report := func() {
+		if opts.Progress != nil {
+			opts.Progress(progress)
+		}
+	}
+
+	iter, err := from.Iterate(ctx)
+	if err != nil {
+		return errors.Errorf("iterate: %w", err)
+	}
+	defer func() {
+		_ = iter.Close()
+	}()
+
+	batch := make([]Peer, 0, opts.BatchSize)
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		if err := AddAll(ctx, to, batch); err != nil {
+			return errors.Errorf("add batch: %w", err)
+		}
+		progress.Peers += len(batch)
+		batch = batch[:0]
+		report()
+		return nil
+	}
+	if err := ForEach(ctx, iter, func(p Peer) error {
+		batch = append(batch, p)
+		if len(batch) < opts.BatchSize {
+			return nil
+		}
+		return flush()
+	}); err != nil {
+		return err
+	}
+	if err := flush(); err != nil {
+		return err
+	}
+
+	for _, key := range opts.Keys {
+		p, err := from.Resolve(ctx, key)
+		if err != nil {
+			if errors.Is(err, ErrPeerNotFound) {
+				continue
+			}
+			return errors.Errorf("resolve %q: %w", key, err)
+		}
+		if err := to.Assign(ctx, key, p); err != nil {
+			return errors.Errorf("assign %q: %w", key, err)
+		}
+		progress.Keys++
+		report()
+	}
+
+	return nil
+}
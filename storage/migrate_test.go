@@ -0,0 +1,73 @@
+package storage
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/gotd/td/tg"
+)
+
+type batchStorage struct {
+	memStorage
+	batches []int
+}
+
+func (b *batchStorage) AddAll(ctx context.Context, values []Peer) error {
+	b.batches = append(b.batches, len(values))
+	for _, p := range values {
+		if err := b.Add(ctx, p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func TestMigrate(t *testing.T) {
+	a := require.New(t)
+	ctx := context.Background()
+
+	from := newMemStorage()
+	for i := range [5]struct{}{} {
+		var p Peer
+		a.True(p.FromUser(&tg.User{
+			ID:         int64(i) + 1,
+			AccessHash: int64(i) + 10,
+		}))
+		a.NoError(from.Add(ctx, p))
+		if i == 0 {
+			a.NoError(from.Assign(ctx, "assigned", p))
+		}
+	}
+
+	to := &batchStorage{memStorage: newMemStorage()}
+	var progress []MigrateProgress
+	a.NoError(Migrate(ctx, from, to, MigrateOptions{
+		BatchSize: 2,
+		Keys:      []string{"assigned", "missing"},
+		Progress: func(p MigrateProgress) {
+			progress = append(progress, p)
+		},
+	}))
+
+	a.Equal([]int{2, 2, 1}, to.batches)
+	a.Len(to.peers, 5)
+	a.Equal(MigrateProgress{Peers: 5, Keys: 1}, progress[len(progress)-1])
+	a.Len(progress, 4)
+
+	p, err := to.Resolve(ctx, "assigned")
+	a.NoError(err)
+	a.Equal(int64(1), p.Key.ID)
+}
+
+func TestAddAll(t *testing.T) {
+	a := require.New(t)
+	ctx := context.Background()
+
+	s := newMemStorage()
+	var p Peer
+	a.True(p.FromUser(&tg.User{ID: 1, AccessHash: 1}))
+	a.NoError(AddAll(ctx, s, []Peer{p}))
+	a.Len(s.peers, 1)
+}
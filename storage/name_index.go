@@ -0,0 +1,190 @@
+package storage
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/go-faster/errors"
+)
+
+// PeerNameSearcher is a PeerStorage which supports search by peer names.
+type PeerNameSearcher interface {
+	// Search creates and returns new PeerIterator which returns only peers
+	// whose name, title or username contains every word of given query.
+	//
+	// Match is case-insensitive.
+	Search(ctx context.Context, query string) (PeerIterator, error)
+}
+
+// Search creates and returns new PeerIterator which returns only peers whose
+// name, title or username contains every word of given query.
+//
+// If storage implements PeerNameSearcher, it is used. Otherwise,
+// Search filters result of Iterate.
+func Search(ctx context.Context, s PeerStorage, query string) (PeerIterator, error) {
+	if f, ok := s.(PeerNameSearcher); ok {
+		return f.Search(ctx, query)
+	}
+
+	iter, err := s.Iterate(ctx)
+	if err != nil {
+		return nil, err
+	}
+	terms := searchTerms(query)
+	return &filterIterator{
+		PeerIterator: iter,
+		match: func(p Peer) bool {
+			return matchTerms(searchText(p), terms)
+		},
+	}, nil
+}
+
+// MatchName reports whether peer name, title or username contains every word
+// of given query.
+//
+// Match is case-insensitive.
+func MatchName(p Peer, query string) bool {
+	return matchTerms(searchText(p), searchTerms(query))
+}
+
+// searchText returns lowercase searchable text of peer.
+func searchText(p Peer) string {
+	var parts []string
+	switch {
+	case p.User != nil:
+		parts = append(parts, p.User.FirstName, p.User.LastName, p.User.Username)
+	case p.Chat != nil:
+		parts = append(parts, p.Chat.Title)
+	case p.Channel != nil:
+		parts = append(parts, p.Channel.Title, p.Channel.Username)
+	}
+	return strings.ToLower(strings.Join(parts, " "))
+}
+
+func searchTerms(query string) []string {
+	return strings.Fields(strings.ToLower(query))
+}
+
+func matchTerms(text string, terms []string) bool {
+	if text == "" {
+		return false
+	}
+	for _, term := range terms {
+		if !strings.Contains(text, term) {
+			return false
+		}
+	}
+	return true
+}
+
+// NameIndex is a PeerStorage decorator which maintains in-memory index of
+// peer names for storages without native text search.
+//
+// Index is updated on Add and Assign. Peers removed from underlying storage
+// are skipped by Search.
+type NameIndex struct {
+	PeerStorage
+
+	mux   sync.RWMutex
+	names map[PeerKey]string
+}
+
+var _ PeerNameSearcher = (*NameIndex)(nil)
+
+// NewNameIndex creates new NameIndex.
+//
+// Use Load to index peers which are already stored.
+func NewNameIndex(s PeerStorage) *NameIndex {
+	return &NameIndex{
+		PeerStorage: s,
+		names:       map[PeerKey]string{},
+	}
+}
+
+// Load indexes all peers from underlying storage.
+func (n *NameIndex) Load(ctx context.Context) error {
+	iter, err := n.PeerStorage.Iterate(ctx)
+	if err != nil {
+		return errors.Errorf("iterate: %w", err)
+	}
+	defer func() {
+		_ = iter.Close()
+	}()
+
+	return ForEach(ctx, iter, func(p Peer) error {
+		n.index(p)
+		return nil
+	})
+}
+
+func (n *NameIndex) index(p Peer) {
+	text := searchText(p)
+
+	n.mux.Lock()
+	defer n.mux.Unlock()
+	if text == "" {
+		delete(n.names, KeyFromPeer(p))
+		return
+	}
+	n.names[KeyFromPeer(p)] = text
+}
+
+// Add adds given peer to the storage.
+func (n *NameIndex) Add(ctx context.Context, value Peer) error {
+	if err := n.PeerStorage.Add(ctx, value); err != nil {
+		return err
+	}
+	n.index(value)
+	return nil
+}
+
+// Assign adds given peer to the storage and associates it to the given key.
+func (n *NameIndex) Assign(ctx context.Context, key string, value Peer) error {
+	if err := n.PeerStorage.Assign(ctx, key, value); err != nil {
+		return err
+	}
+	n.index(value)
+	return nil
+}
+
+// Search creates and returns new PeerIterator which returns only peers
+// whose name, title or username contains every word of given query.
+//
+// Match is case-insensitive.
+func (n *NameIndex) Search(ctx context.Context, query string) (PeerIterator, error) {
+	terms := searchTerms(query)
+
+	n.mux.RLock()
+	var keys []PeerKey
+	for key, text := range n.names {
+		if matchTerms(text, terms) {
+			keys = append(keys, key)
+		}
+	}
+	n.mux.RUnlock()
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].Kind != keys[j].Kind {
+			return keys[i].Kind < keys[j].Kind
+		}
+		return keys[i].ID < keys[j].ID
+	})
+
+	peers := make([]Peer, 0, len(keys))
+	for _, key := range keys {
+		p, err := n.PeerStorage.Find(ctx, key)
+		if err != nil {
+			if errors.Is(err, ErrPeerNotFound) {
+				continue
+			}
+			return nil, errors.Errorf("find %s: %w", key, err)
+		}
+		if !matchTerms(searchText(p), terms) {
+			continue
+		}
+		peers = append(peers, p)
+	}
+
+	return NewSliceIterator(peers), nil
+}
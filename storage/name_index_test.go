@@ -0,0 +1,65 @@
+package storage
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/gotd/td/tg"
+)
+
+func collectNames(t *testing.T, iter PeerIterator) (r []string) {
+	t.Helper()
+	a := require.New(t)
+
+	a.NoError(ForEach(context.Background(), iter, func(p Peer) error {
+		if p.User != nil {
+			r = append(r, p.User.FirstName)
+			return nil
+		}
+		r = append(r, p.Channel.Title)
+		return nil
+	}))
+	a.NoError(iter.Close())
+	return r
+}
+
+func TestNameIndex(t *testing.T) {
+	a := require.New(t)
+	ctx := context.Background()
+	s := newMemStorage()
+
+	var user, channel Peer
+	a.True(user.FromUser(&tg.User{ID: 1, FirstName: "Pavel", LastName: "Durov"}))
+	a.True(channel.FromChat(&tg.Channel{ID: 2, Title: "Go news", Username: "golang_news"}))
+	a.NoError(s.Add(ctx, user))
+
+	idx := NewNameIndex(s)
+	a.NoError(idx.Load(ctx))
+	a.NoError(idx.Add(ctx, channel))
+
+	for _, search := range []func(query string) (PeerIterator, error){
+		func(query string) (PeerIterator, error) { return idx.Search(ctx, query) },
+		func(query string) (PeerIterator, error) { return Search(ctx, s, query) },
+	} {
+		iter, err := search("durov PAV")
+		a.NoError(err)
+		a.Equal([]string{"Pavel"}, collectNames(t, iter))
+
+		iter, err = search("news")
+		a.NoError(err)
+		a.Equal([]string{"Go news"}, collectNames(t, iter))
+
+		iter, err = search("durov news")
+		a.NoError(err)
+		a.Empty(collectNames(t, iter))
+	}
+
+	delete(s.peers, KeyFromPeer(user))
+	iter, err := idx.Search(ctx, "pavel")
+	a.NoError(err)
+	a.Empty(collectNames(t, iter))
+
+	a.True(MatchName(channel, "GOLANG_"))
+}
@@ -0,0 +1,149 @@
+package storage
+
+import (
+	"context"
+	"strings"
+
+	"github.com/go-faster/errors"
+)
+
+// NormalizeKey normalizes username or phone key.
+//
+// It trims spaces, t.me link prefixes, leading "@" and "+", and converts
+// key to lower case, so "Durov", "@durov" and "https://t.me/durov" all
+// become "durov".
+func NormalizeKey(key string) string {
+	key = strings.TrimSpace(key)
+	for _, scheme := range []string{"https://", "http://"} {
+		if len(key) >= len(scheme) && strings.EqualFold(key[:len(scheme)], scheme) {
+			key = key[len(scheme):]
+			break
+		}
+	}
+	for _, host := range []string{"t.me/", "telegram.me/", "telegram.dog/"} {
+		if len(key) >= len(host) && strings.EqualFold(key[:len(host)], host) {
+			key = key[len(host):]
+			break
+		}
+	}
+	key = strings.TrimLeft(key, "@+")
+	return strings.ToLower(key)
+}
+
+// NormalizedStorage is a PeerStorage decorator which normalizes associated
+// keys using NormalizeKey on write and read.
+type NormalizedStorage struct {
+	PeerStorage
+	fuzzy bool
+}
+
+// NewNormalizedStorage creates new NormalizedStorage.
+func NewNormalizedStorage(s PeerStorage) *NormalizedStorage {
+	return &NormalizedStorage{PeerStorage: s}
+}
+
+// WithFuzzy enables fuzzy fallback of Resolve: if key is not found, Resolve
+// returns the only peer whose username is within edit distance 1 of the key.
+//
+// Fuzzy match scans all peers with usernames, so it should be used only
+// with small storages or storages implementing PeerSearcher.
+func (n *NormalizedStorage) WithFuzzy(fuzzy bool) *NormalizedStorage {
+	n.fuzzy = fuzzy
+	return n
+}
+
+// Add adds given peer to the storage.
+func (n *NormalizedStorage) Add(ctx context.Context, value Peer) error {
+	if username := value.Username(); username != "" {
+		if normalized := NormalizeKey(username); normalized != username {
+			return n.PeerStorage.Assign(ctx, normalized, value)
+		}
+	}
+	return n.PeerStorage.Add(ctx, value)
+}
+
+// Assign adds given peer to the storage and associates it to the given
+// normalized key.
+func (n *NormalizedStorage) Assign(ctx context.Context, key string, value Peer) error {
+	if username := value.Username(); username != "" {
+		if normalized := NormalizeKey(username); normalized != username {
+			if err := n.PeerStorage.Assign(ctx, normalized, value); err != nil {
+				return err
+			}
+		}
+	}
+	return n.PeerStorage.Assign(ctx, NormalizeKey(key), value)
+}
+
+// Resolve finds peer using normalized associated key.
+func (n *NormalizedStorage) Resolve(ctx context.Context, key string) (Peer, error) {
+	normalized := NormalizeKey(key)
+	p, err := n.PeerStorage.Resolve(ctx, normalized)
+	if !errors.Is(err, ErrPeerNotFound) {
+		return p, err
+	}
+	if normalized != key {
+		// Key may be stored as is by other writers.
+		p, err = n.PeerStorage.Resolve(ctx, key)
+		if !errors.Is(err, ErrPeerNotFound) {
+			return p, err
+		}
+	}
+	if !n.fuzzy || normalized == "" {
+		return Peer{}, ErrPeerNotFound
+	}
+
+	return n.resolveFuzzy(ctx, normalized)
+}
+
+func (n *NormalizedStorage) resolveFuzzy(ctx context.Context, key string) (Peer, error) {
+	iter, err := SearchPrefix(ctx, n.PeerStorage, "")
+	if err != nil {
+		return Peer{}, errors.Errorf("search: %w", err)
+	}
+	defer func() {
+		_ = iter.Close()
+	}()
+
+	var (
+		found   Peer
+		matches int
+	)
+	if err := ForEach(ctx, iter, func(p Peer) error {
+		if withinEditDistance1(key, UsernameIndex(p)) {
+			found = p
+			matches++
+		}
+		return nil
+	}); err != nil {
+		return Peer{}, err
+	}
+	if matches != 1 {
+		// Ambiguous or no match.
+		return Peer{}, ErrPeerNotFound
+	}
+
+	return found, nil
+}
+
+// withinEditDistance1 reports whether Levenshtein distance between a and b
+// is at most 1.
+func withinEditDistance1(a, b string) bool {
+	if len(a) > len(b) {
+		a, b = b, a
+	}
+	if len(b)-len(a) > 1 {
+		return false
+	}
+
+	i := 0
+	for i < len(a) && a[i] == b[i] {
+		i++
+	}
+	if len(a) == len(b) {
+		// Substitution.
+		return i == len(a) || a[i+1:] == b[i+1:]
+	}
+	// Insertion.
+	return a[i:] == b[i+1:]
+}
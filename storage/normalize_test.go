@@ -0,0 +1,68 @@
+package storage
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/gotd/td/tg"
+)
+
+func TestNormalizeKey(t *testing.T) {
+	for _, key := range []string{
+		"durov",
+		"Durov",
+		"@durov",
+		" @Durov ",
+		"t.me/durov",
+		"https://t.me/durov",
+		"HTTP://telegram.me/DUROV",
+	} {
+		require.Equal(t, "durov", NormalizeKey(key), key)
+	}
+	require.Equal(t, "79001234567", NormalizeKey("+79001234567"))
+}
+
+func TestWithinEditDistance1(t *testing.T) {
+	a := require.New(t)
+	a.True(withinEditDistance1("durov", "durov"))
+	a.True(withinEditDistance1("durov", "durow"))
+	a.True(withinEditDistance1("durov", "durv"))
+	a.True(withinEditDistance1("durov", "dufrov"))
+	a.True(withinEditDistance1("", "a"))
+	a.False(withinEditDistance1("durov", "druov"))
+	a.False(withinEditDistance1("durov", "dur"))
+}
+
+func TestNormalizedStorage(t *testing.T) {
+	a := require.New(t)
+	ctx := context.Background()
+	s := newMemStorage()
+	n := NewNormalizedStorage(s)
+
+	var durov, other Peer
+	a.True(durov.FromUser(&tg.User{ID: 1, Username: "Durov"}))
+	a.True(other.FromUser(&tg.User{ID: 2, Username: "gopher"}))
+	a.NoError(n.Add(ctx, durov))
+	a.NoError(s.Add(ctx, other))
+
+	for _, key := range []string{"Durov", "@durov", "t.me/DUROV"} {
+		p, err := n.Resolve(ctx, key)
+		a.NoError(err, key)
+		a.Equal(durov.Key, p.Key)
+	}
+
+	_, err := n.Resolve(ctx, "durow")
+	a.ErrorIs(err, ErrPeerNotFound)
+
+	n.WithFuzzy(true)
+	p, err := n.Resolve(ctx, "durow")
+	a.NoError(err)
+	a.Equal(durov.Key, p.Key)
+	p, err = n.Resolve(ctx, "@Gophers")
+	a.NoError(err)
+	a.Equal(other.Key, p.Key)
+	_, err = n.Resolve(ctx, "nobody")
+	a.ErrorIs(err, ErrPeerNotFound)
+}
@@ -0,0 +1,78 @@
+package storage
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/go-faster/errors"
+)
+
+// PeerPage is a page of peers returned by paginated iteration.
+type PeerPage struct {
+	Peers []Peer
+	// Next is a continuation cursor for the next page.
+	// Empty value means that there are no more pages.
+	Next string
+}
+
+// PeerPager is a PeerStorage which supports stable paginated iteration.
+type PeerPager interface {
+	// IteratePage returns page of peers following given cursor.
+	// Empty cursor means the first page.
+	//
+	// Cursor is an opaque value and should be taken from PeerPage.Next.
+	IteratePage(ctx context.Context, cursor string, limit int) (PeerPage, error)
+}
+
+// IteratePage returns page of peers following given cursor.
+// Empty cursor means the first page.
+//
+// If storage implements PeerPager, it is used. Otherwise, IteratePage
+// uses offset-based cursor and skips previous pages on every call, so it
+// is not stable if storage is modified concurrently.
+func IteratePage(ctx context.Context, s PeerStorage, cursor string, limit int) (PeerPage, error) {
+	if limit <= 0 {
+		return PeerPage{}, errors.Errorf("invalid limit %d", limit)
+	}
+	if p, ok := s.(PeerPager); ok {
+		return p.IteratePage(ctx, cursor, limit)
+	}
+
+	var offset int
+	if cursor != "" {
+		v, err := strconv.Atoi(cursor)
+		if err != nil || v < 0 {
+			return PeerPage{}, errors.Errorf("invalid cursor %q", cursor)
+		}
+		offset = v
+	}
+
+	iter, err := s.Iterate(ctx)
+	if err != nil {
+		return PeerPage{}, errors.Errorf("iterate: %w", err)
+	}
+	defer func() {
+		_ = iter.Close()
+	}()
+
+	var (
+		page PeerPage
+		i    int
+	)
+	for iter.Next(ctx) {
+		if i < offset {
+			i++
+			continue
+		}
+		if len(page.Peers) == limit {
+			page.Next = strconv.Itoa(offset + limit)
+			break
+		}
+		page.Peers = append(page.Peers, iter.Value())
+	}
+	if err := iter.Err(); err != nil {
+		return PeerPage{}, errors.Errorf("iterate: %w", err)
+	}
+
+	return page, nil
+}
@@ -0,0 +1,50 @@
+package storage
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/gotd/td/tg"
+)
+
+func TestIteratePage(t *testing.T) {
+	a := require.New(t)
+	ctx := context.Background()
+	s := newMemStorage()
+
+	for i := range [5]struct{}{} {
+		var p Peer
+		a.NoError(p.FromInputPeer(&tg.InputPeerUser{
+			UserID:     int64(i) + 1,
+			AccessHash: int64(i) + 1,
+		}))
+		a.NoError(s.Add(ctx, p))
+	}
+
+	var (
+		cursor string
+		pages  int
+		seen   = map[PeerKey]struct{}{}
+	)
+	for {
+		page, err := IteratePage(ctx, s, cursor, 2)
+		a.NoError(err)
+		pages++
+		for _, p := range page.Peers {
+			seen[KeyFromPeer(p)] = struct{}{}
+		}
+		if page.Next == "" {
+			break
+		}
+		cursor = page.Next
+	}
+	a.Equal(3, pages)
+	a.Len(seen, 5)
+
+	_, err := IteratePage(ctx, s, "", 0)
+	a.Error(err)
+	_, err = IteratePage(ctx, s, "foo", 1)
+	a.Error(err)
+}
@@ -30,6 +30,8 @@ type Peer struct {
 	Version   int
 	Key       dialogs.DialogKey
 	CreatedAt time.Time
+	// ExpiresAt is a peer expiration time. Zero value means that peer never expires.
+	ExpiresAt time.Time
 	User      *tg.User
 	Chat      *tg.Chat
 	Channel   *tg.Channel
@@ -67,6 +69,11 @@ func (p Peer) String() string {
 	return b.String()
 }
 
+// Expired reports whether peer is expired at given time.
+func (p Peer) Expired(now time.Time) bool {
+	return !p.ExpiresAt.IsZero() && !now.Before(p.ExpiresAt)
+}
+
 func decodeObject(d *jx.Decoder, v bin.Decoder) error {
 	data, err := d.Base64()
 	if err != nil {
@@ -113,6 +120,7 @@ func (p *Peer) Unmarshal(d *jx.Decoder) error {
 	p.Chat = nil
 	p.Channel = nil
 	p.CreatedAt = time.Time{}
+	p.ExpiresAt = time.Time{}
 
 	if err := d.Obj(func(d *jx.Decoder, key string) error {
 		switch key {
@@ -130,6 +138,13 @@ func (p *Peer) Unmarshal(d *jx.Decoder) error {
 			}
 			p.CreatedAt = time.Unix(v, 0)
 			return nil
+		case "ExpiresAt":
+			v, err := d.Int64()
+			if err != nil {
+				return errors.Wrap(err, "expires_at")
+			}
+			p.ExpiresAt = time.UnixMilli(v)
+			return nil
 		case "Key":
 			return d.Obj(func(d *jx.Decoder, key string) error {
 				switch key {
@@ -260,6 +275,11 @@ func (p Peer) Marshal(e *jx.Encoder) error {
 		e.Field("CreatedAt", func(e *jx.Encoder) {
 			e.Int64(p.CreatedAt.Unix())
 		})
+		if !p.ExpiresAt.IsZero() {
+			e.Field("ExpiresAt", func(e *jx.Encoder) {
+				e.Int64(p.ExpiresAt.UnixMilli())
+			})
+		}
 		e.Field("Metadata", func(e *jx.Encoder) {
 			e.Raw(metadataRaw)
 		})
@@ -55,6 +55,7 @@ func TestPeer_MarshalJSON(t *testing.T) {
 				Channel:   channel,
 				Metadata:  meta,
 				CreatedAt: time.Unix(1681541743, 0),
+				ExpiresAt: time.UnixMilli(1681541743123),
 			},
 			{
 				Version:   LatestVersion,
@@ -78,6 +79,7 @@ func TestPeer_MarshalJSON(t *testing.T) {
 				require.NoError(t, json.Unmarshal(data, &out))
 
 				assert.Equal(t, p.CreatedAt, out.CreatedAt, "CreatedAt")
+				assert.True(t, p.ExpiresAt.Equal(out.ExpiresAt), "ExpiresAt")
 				assert.Equal(t, p.Version, out.Version, "Version")
 				assert.Equal(t, p.Key, out.Key, "Key")
 				assert.Equal(t, p.Metadata, out.Metadata, "Metadata")
@@ -106,3 +108,18 @@ func TestPeer_MarshalJSON(t *testing.T) {
 		require.ErrorIs(t, err, ErrPeerUnmarshalMustInvalidate)
 	})
 }
+
+func TestPeer_Expired(t *testing.T) {
+	a := require.New(t)
+	now := time.Unix(1681541743, 0)
+
+	var p Peer
+	a.False(p.Expired(now), "zero ExpiresAt never expires")
+
+	p = WithTTL(p, now, time.Minute)
+	a.False(p.Expired(now))
+	a.True(p.Expired(now.Add(time.Minute)))
+
+	p = WithTTL(p, now, 0)
+	a.True(p.ExpiresAt.IsZero())
+}
@@ -0,0 +1,31 @@
+package storage
+
+import (
+	"context"
+	"time"
+)
+
+// ExpiringPeerStorage is a PeerStorage which supports peer expiration.
+//
+// Expired peers are considered as not found by Find and Resolve and are
+// skipped by iterators. Depending on implementation, expired data may be
+// removed natively (e.g. Redis) or lazily.
+type ExpiringPeerStorage interface {
+	PeerStorage
+	// AddTTL adds given peer to the storage. Peer expires after given ttl.
+	//
+	// Zero or negative ttl means that peer never expires.
+	AddTTL(ctx context.Context, value Peer, ttl time.Duration) error
+}
+
+// WithTTL sets expiration time of given peer using ttl relative to now.
+//
+// Zero or negative ttl resets expiration time.
+func WithTTL(p Peer, now time.Time, ttl time.Duration) Peer {
+	if ttl <= 0 {
+		p.ExpiresAt = time.Time{}
+		return p
+	}
+	p.ExpiresAt = now.Add(ttl)
+	return p
+}
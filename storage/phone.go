@@ -0,0 +1,29 @@
+package storage
+
+import "strings"
+
+// PhoneKeyPrefix is a prefix of associated key of phone, see PhoneKey.
+const PhoneKeyPrefix = "phone:"
+
+// NormalizePhone returns digits of given phone number, so "+7 (900) 123-45-67"
+// becomes "79001234567", like phones returned by Telegram.
+func NormalizePhone(phone string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= '0' && r <= '9' {
+			return r
+		}
+		return -1
+	}, phone)
+}
+
+// PhoneKey returns associated key of given phone number.
+//
+// Key is prefixed with PhoneKeyPrefix, so it never collides with usernames.
+// It returns empty string, if phone contains no digits.
+func PhoneKey(phone string) string {
+	digits := NormalizePhone(phone)
+	if digits == "" {
+		return ""
+	}
+	return PhoneKeyPrefix + digits
+}
@@ -0,0 +1,18 @@
+package storage
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPhoneKey(t *testing.T) {
+	for input, expected := range map[string]string{
+		"+7 (900) 123-45-67": "phone:79001234567",
+		"79001234567":        "phone:79001234567",
+		"":                   "",
+		"abc":                "",
+	} {
+		require.Equal(t, expected, PhoneKey(input), input)
+	}
+}
@@ -0,0 +1,214 @@
+package storage
+
+import (
+	"context"
+
+	"github.com/go-faster/errors"
+	"golang.org/x/time/rate"
+
+	"github.com/gotd/td/telegram/message/peer"
+	"github.com/gotd/td/telegram/query/dialogs"
+	"github.com/gotd/td/tg"
+)
+
+// PrefetchState is a state of Prefetch, used to resume interrupted prefetch.
+type PrefetchState struct {
+	// OffsetDate, OffsetID and OffsetPeer are offsets of next dialogs page.
+	OffsetDate int
+	OffsetID   int
+	OffsetPeer dialogs.DialogKey
+	// DialogsDone is true if all dialogs pages are fetched.
+	DialogsDone bool
+	// ContactsDone is true if contacts are fetched.
+	ContactsDone bool
+	// Peers is a count of added peers.
+	Peers int
+}
+
+// Prefetch is a helper to fill peer storage with all users, chats and
+// channels from dialogs and contacts, e.g. on cold start.
+//
+// Unlike PeerCollector, it adds all peers found in responses, not only
+// dialog peers. Use floodwait middleware to handle FLOOD_WAIT errors.
+type Prefetch struct {
+	raw        *tg.Client
+	storage    PeerStorage
+	limiter    *rate.Limiter
+	batchSize  int
+	contacts   bool
+	state      PrefetchState
+	onProgress func(ctx context.Context, state PrefetchState) error
+}
+
+// NewPrefetch creates new Prefetch.
+func NewPrefetch(raw *tg.Client, storage PeerStorage) *Prefetch {
+	return &Prefetch{
+		raw:        raw,
+		storage:    storage,
+		limiter:    rate.NewLimiter(1, 1),
+		batchSize:  100,
+		contacts:   true,
+		onProgress: func(ctx context.Context, state PrefetchState) error { return nil },
+	}
+}
+
+// WithRateLimit sets rate limit of requests. Default is one request per second.
+func (p *Prefetch) WithRateLimit(r rate.Limit, burst int) *Prefetch {
+	p.limiter = rate.NewLimiter(r, burst)
+	return p
+}
+
+// WithBatchSize sets dialogs page size. Default is 100.
+func (p *Prefetch) WithBatchSize(size int) *Prefetch {
+	p.batchSize = size
+	return p
+}
+
+// WithContacts sets whether to fetch contacts. Default is true.
+func (p *Prefetch) WithContacts(contacts bool) *Prefetch {
+	p.contacts = contacts
+	return p
+}
+
+// Resume sets state to resume from, e.g. saved by OnProgress.
+func (p *Prefetch) Resume(state PrefetchState) *Prefetch {
+	p.state = state
+	return p
+}
+
+// OnProgress sets callback called after every fetched page, e.g. to save
+// state to resume from. Returned error stops prefetch.
+func (p *Prefetch) OnProgress(f func(ctx context.Context, state PrefetchState) error) *Prefetch {
+	p.onProgress = f
+	return p
+}
+
+func (p *Prefetch) add(ctx context.Context, users []tg.UserClass, chats []tg.ChatClass) error {
+	for _, chat := range chats {
+		if value := (Peer{}); value.FromChat(chat) {
+			if err := p.storage.Add(ctx, value); err != nil {
+				return errors.Errorf("add %s: %w", value, err)
+			}
+			p.state.Peers++
+		}
+	}
+	for _, user := range users {
+		if value := (Peer{}); value.FromUser(user) {
+			if err := p.storage.Add(ctx, value); err != nil {
+				return errors.Errorf("add %s: %w", value, err)
+			}
+			p.state.Peers++
+		}
+	}
+	return nil
+}
+
+func (p *Prefetch) dialogs(ctx context.Context) error {
+	offsetPeer := tg.InputPeerClass(&tg.InputPeerEmpty{})
+	if p.state.OffsetPeer.ID != 0 {
+		offsetPeer = Peer{Key: p.state.OffsetPeer}.AsInputPeer()
+	}
+
+	r, err := p.raw.MessagesGetDialogs(ctx, &tg.MessagesGetDialogsRequest{
+		OffsetDate: p.state.OffsetDate,
+		OffsetID:   p.state.OffsetID,
+		OffsetPeer: offsetPeer,
+		Limit:      p.batchSize,
+	})
+	if err != nil {
+		return errors.Errorf("get dialogs: %w", err)
+	}
+
+	var (
+		last     bool
+		entities peer.Entities
+	)
+	switch r := r.(type) {
+	case *tg.MessagesDialogs:
+		last = true
+		entities = peer.EntitiesFromResult(r)
+	case *tg.MessagesDialogsSlice:
+		last = len(r.Dialogs) == 0
+		entities = peer.EntitiesFromResult(r)
+	default:
+		return errors.Errorf("unexpected type %T", r)
+	}
+	modified, _ := r.AsModified()
+
+	if err := p.add(ctx, modified.GetUsers(), modified.GetChats()); err != nil {
+		return err
+	}
+	if last {
+		p.state.DialogsDone = true
+		return nil
+	}
+
+	dlgs := modified.GetDialogs()
+	dlg := dlgs[len(dlgs)-1]
+	var key dialogs.DialogKey
+	if err := key.FromPeer(dlg.GetPeer()); err != nil {
+		return errors.Errorf("get offset peer: %w", err)
+	}
+	for _, msg := range modified.GetMessages() {
+		var msgKey dialogs.DialogKey
+		m, ok := msg.AsNotEmpty()
+		if !ok || m.GetID() != dlg.GetTopMessage() ||
+			msgKey.FromPeer(m.GetPeerID()) != nil || msgKey != key {
+			continue
+		}
+		p.state.OffsetID = m.GetID()
+		p.state.OffsetDate = m.GetDate()
+	}
+
+	input, err := entities.ExtractPeer(dlg.GetPeer())
+	if err != nil {
+		return errors.Errorf("get offset peer: %w", err)
+	}
+	if err := p.state.OffsetPeer.FromInputPeer(input); err != nil {
+		return errors.Errorf("get offset peer: %w", err)
+	}
+	return nil
+}
+
+func (p *Prefetch) fetchContacts(ctx context.Context) error {
+	r, err := p.raw.ContactsGetContacts(ctx, 0)
+	if err != nil {
+		return errors.Errorf("get contacts: %w", err)
+	}
+	if c, ok := r.AsModified(); ok {
+		if err := p.add(ctx, c.Users, nil); err != nil {
+			return err
+		}
+	}
+	p.state.ContactsDone = true
+	return nil
+}
+
+func (p *Prefetch) step(ctx context.Context, f func(ctx context.Context) error) error {
+	if err := p.limiter.Wait(ctx); err != nil {
+		return err
+	}
+	if err := f(ctx); err != nil {
+		return err
+	}
+	return p.onProgress(ctx, p.state)
+}
+
+// Run fetches dialogs and contacts until done. It returns state to resume
+// from, even if error is returned.
+func (p *Prefetch) Run(ctx context.Context) (PrefetchState, error) {
+	for !p.state.DialogsDone {
+		if err := p.step(ctx, p.dialogs); err != nil {
+			return p.state, err
+		}
+	}
+	if !p.contacts {
+		return p.state, nil
+	}
+	for !p.state.ContactsDone {
+		if err := p.step(ctx, p.fetchContacts); err != nil {
+			return p.state, err
+		}
+	}
+	return p.state, nil
+}
@@ -0,0 +1,95 @@
+package storage
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-faster/errors"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/time/rate"
+
+	"github.com/gotd/td/telegram/query/dialogs"
+	"github.com/gotd/td/tg"
+	"github.com/gotd/td/tgmock"
+)
+
+func TestPrefetch(t *testing.T) {
+	a := require.New(t)
+	ctx := context.Background()
+	mock := tgmock.New(t)
+	s := newMemStorage()
+	raw := tg.NewClient(mock)
+
+	mock.ExpectCall(&tg.MessagesGetDialogsRequest{
+		OffsetPeer: &tg.InputPeerEmpty{},
+		Limit:      2,
+	}).ThenResult(&tg.MessagesDialogsSlice{
+		Count: 3,
+		Dialogs: []tg.DialogClass{
+			&tg.Dialog{Peer: &tg.PeerChannel{ChannelID: 10}, TopMessage: 2},
+			&tg.Dialog{Peer: &tg.PeerUser{UserID: 1}, TopMessage: 5},
+		},
+		Messages: []tg.MessageClass{
+			&tg.Message{ID: 2, Date: 200, PeerID: &tg.PeerChannel{ChannelID: 10}},
+			&tg.Message{ID: 5, Date: 100, PeerID: &tg.PeerUser{UserID: 1}},
+		},
+		Chats: []tg.ChatClass{
+			&tg.Channel{ID: 10, AccessHash: 10, Photo: &tg.ChatPhotoEmpty{}},
+		},
+		Users: []tg.UserClass{
+			&tg.User{ID: 1, AccessHash: 1},
+			// Not a dialog peer, e.g. message sender.
+			&tg.User{ID: 3, AccessHash: 3},
+		},
+	})
+
+	stop := errors.New("stop")
+	var saved PrefetchState
+	state, err := NewPrefetch(raw, s).
+		WithRateLimit(rate.Inf, 1).
+		WithBatchSize(2).
+		OnProgress(func(ctx context.Context, state PrefetchState) error {
+			saved = state
+			return stop
+		}).
+		Run(ctx)
+	a.ErrorIs(err, stop)
+	a.Equal(saved, state)
+	a.Equal(PrefetchState{
+		OffsetDate: 100,
+		OffsetID:   5,
+		OffsetPeer: dialogs.DialogKey{Kind: dialogs.User, ID: 1, AccessHash: 1},
+		Peers:      3,
+	}, state)
+
+	mock.ExpectCall(&tg.MessagesGetDialogsRequest{
+		OffsetDate: 100,
+		OffsetID:   5,
+		OffsetPeer: &tg.InputPeerUser{UserID: 1, AccessHash: 1},
+		Limit:      2,
+	}).ThenResult(&tg.MessagesDialogsSlice{Count: 3})
+	mock.ExpectCall(&tg.ContactsGetContactsRequest{}).ThenResult(&tg.ContactsContacts{
+		Users: []tg.UserClass{
+			&tg.User{ID: 2, AccessHash: 2},
+		},
+	})
+
+	state, err = NewPrefetch(raw, s).
+		WithRateLimit(rate.Inf, 1).
+		WithBatchSize(2).
+		Resume(state).
+		Run(ctx)
+	a.NoError(err)
+	a.True(state.DialogsDone)
+	a.True(state.ContactsDone)
+	a.Equal(4, state.Peers)
+
+	for _, key := range []PeerKey{
+		{Kind: dialogs.User, ID: 1},
+		{Kind: dialogs.User, ID: 2},
+		{Kind: dialogs.User, ID: 3},
+		{Kind: dialogs.Channel, ID: 10},
+	} {
+		a.Contains(s.peers, key)
+	}
+}
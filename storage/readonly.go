@@ -0,0 +1,106 @@
+package storage
+
+import (
+	"context"
+
+	"github.com/go-faster/errors"
+
+	"github.com/gotd/td/telegram/query/dialogs"
+)
+
+// ErrReadOnly is returned by writes to read-only storage.
+var ErrReadOnly = errors.New("storage is read-only")
+
+var (
+	_ PeerStorage         = (*ReadOnly)(nil)
+	_ FilteredPeerStorage = (*ReadOnly)(nil)
+	_ PeerIDFinder        = (*ReadOnly)(nil)
+	_ PeerSearcher        = (*ReadOnly)(nil)
+	_ PeerCounter         = (*ReadOnly)(nil)
+	_ PeerBatchResolver   = (*ReadOnly)(nil)
+	_ PeerStatsReporter   = (*ReadOnly)(nil)
+)
+
+// ReadOnly is a PeerStorage decorator which rejects writes, e.g. to
+// inspect peers of running client by external tools.
+//
+// Add and Assign return ErrReadOnly, reads and read-only capabilities of
+// the underlying storage are passed through.
+type ReadOnly struct {
+	next    PeerStorage
+	discard bool
+}
+
+// NewReadOnly creates new ReadOnly storage.
+func NewReadOnly(next PeerStorage) *ReadOnly {
+	return &ReadOnly{next: next}
+}
+
+// Discard makes writes no-op instead of returning ErrReadOnly, e.g. to pass
+// storage to code which saves peers it sees, like UpdateHook.
+func (r *ReadOnly) Discard() *ReadOnly {
+	r.discard = true
+	return r
+}
+
+func (r *ReadOnly) write() error {
+	if r.discard {
+		return nil
+	}
+	return ErrReadOnly
+}
+
+// Add implements PeerStorage.
+func (r *ReadOnly) Add(ctx context.Context, value Peer) error {
+	return r.write()
+}
+
+// Find implements PeerStorage.
+func (r *ReadOnly) Find(ctx context.Context, key PeerKey) (Peer, error) {
+	return r.next.Find(ctx, key)
+}
+
+// Assign implements PeerStorage.
+func (r *ReadOnly) Assign(ctx context.Context, key string, value Peer) error {
+	return r.write()
+}
+
+// Resolve implements PeerStorage.
+func (r *ReadOnly) Resolve(ctx context.Context, key string) (Peer, error) {
+	return r.next.Resolve(ctx, key)
+}
+
+// Iterate implements PeerStorage.
+func (r *ReadOnly) Iterate(ctx context.Context) (PeerIterator, error) {
+	return r.next.Iterate(ctx)
+}
+
+// IterateFiltered implements FilteredPeerStorage.
+func (r *ReadOnly) IterateFiltered(ctx context.Context, opts IterateOptions) (PeerIterator, error) {
+	return IterateFiltered(ctx, r.next, opts)
+}
+
+// FindByID implements PeerIDFinder.
+func (r *ReadOnly) FindByID(ctx context.Context, id int64) (Peer, error) {
+	return FindByID(ctx, r.next, id)
+}
+
+// SearchPrefix implements PeerSearcher.
+func (r *ReadOnly) SearchPrefix(ctx context.Context, prefix string) (PeerIterator, error) {
+	return SearchPrefix(ctx, r.next, prefix)
+}
+
+// Count implements PeerCounter.
+func (r *ReadOnly) Count(ctx context.Context, kinds ...dialogs.PeerKind) (int, error) {
+	return CountPeers(ctx, r.next, kinds...)
+}
+
+// ResolveBatch implements PeerBatchResolver.
+func (r *ReadOnly) ResolveBatch(ctx context.Context, keys []string) (map[string]Peer, error) {
+	return ResolveBatch(ctx, r.next, keys)
+}
+
+// Stats implements PeerStatsReporter.
+func (r *ReadOnly) Stats(ctx context.Context) (Stats, error) {
+	return PeerStats(ctx, r.next)
+}
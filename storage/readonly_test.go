@@ -0,0 +1,46 @@
+package storage
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/gotd/td/tg"
+)
+
+func TestReadOnly(t *testing.T) {
+	a := require.New(t)
+	ctx := context.Background()
+	inner := newMemStorage()
+
+	var p, other Peer
+	a.True(p.FromUser(&tg.User{ID: 10, AccessHash: 10, Username: "user"}))
+	a.True(other.FromUser(&tg.User{ID: 11, AccessHash: 11}))
+	a.NoError(inner.Add(ctx, p))
+
+	s := NewReadOnly(inner)
+	a.ErrorIs(s.Add(ctx, other), ErrReadOnly)
+	a.ErrorIs(s.Assign(ctx, "key", other), ErrReadOnly)
+	a.NotContains(inner.peers, KeyFromPeer(other))
+
+	v, err := s.Find(ctx, KeyFromPeer(p))
+	a.NoError(err)
+	a.Equal(KeyFromPeer(p), KeyFromPeer(v))
+	v, err = s.Resolve(ctx, "user")
+	a.NoError(err)
+	a.Equal(KeyFromPeer(p), KeyFromPeer(v))
+	v, err = s.FindByID(ctx, 10)
+	a.NoError(err)
+	a.Equal(KeyFromPeer(p), KeyFromPeer(v))
+
+	n, err := s.Count(ctx)
+	a.NoError(err)
+	a.Equal(1, n)
+
+	// Discarded writes are not errors.
+	s.Discard()
+	a.NoError(s.Add(ctx, other))
+	a.NoError(s.Assign(ctx, "key", other))
+	a.NotContains(inner.peers, KeyFromPeer(other))
+}
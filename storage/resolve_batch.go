@@ -0,0 +1,45 @@
+package storage
+
+import (
+	"context"
+
+	"github.com/go-faster/errors"
+)
+
+// PeerBatchResolver is a PeerStorage which is able to resolve multiple
+// associated keys at once.
+type PeerBatchResolver interface {
+	// ResolveBatch finds peers using given associated keys.
+	// Keys not found are omitted from result.
+	ResolveBatch(ctx context.Context, keys []string) (map[string]Peer, error)
+}
+
+// ResolveBatch finds peers using given associated keys.
+// Keys not found are omitted from result.
+//
+// If storage implements PeerBatchResolver, it is used. Otherwise,
+// keys are resolved one by one.
+func ResolveBatch(ctx context.Context, s PeerStorage, keys []string) (map[string]Peer, error) {
+	if len(keys) == 0 {
+		return map[string]Peer{}, nil
+	}
+	if b, ok := s.(PeerBatchResolver); ok {
+		return b.ResolveBatch(ctx, keys)
+	}
+
+	r := make(map[string]Peer, len(keys))
+	for _, key := range keys {
+		if _, ok := r[key]; ok {
+			continue
+		}
+		p, err := s.Resolve(ctx, key)
+		if err != nil {
+			if errors.Is(err, ErrPeerNotFound) {
+				continue
+			}
+			return nil, errors.Errorf("resolve %q: %w", key, err)
+		}
+		r[key] = p
+	}
+	return r, nil
+}
@@ -0,0 +1,29 @@
+package storage
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/gotd/td/tg"
+)
+
+func TestResolveBatch(t *testing.T) {
+	a := require.New(t)
+	ctx := context.Background()
+	s := newMemStorage()
+
+	var p Peer
+	a.NoError(p.FromInputPeer(&tg.InputPeerUser{UserID: 10, AccessHash: 10}))
+	a.NoError(s.Assign(ctx, "user", p))
+
+	r, err := ResolveBatch(ctx, s, []string{"user", "missing", "user"})
+	a.NoError(err)
+	a.Len(r, 1)
+	a.Equal(KeyFromPeer(p), KeyFromPeer(r["user"]))
+
+	r, err = ResolveBatch(ctx, s, nil)
+	a.NoError(err)
+	a.Empty(r)
+}
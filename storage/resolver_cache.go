@@ -2,32 +2,84 @@ package storage
 
 import (
 	"context"
+	"time"
 
 	"github.com/go-faster/errors"
+	"golang.org/x/sync/singleflight"
 
+	"github.com/gotd/td/clock"
 	"github.com/gotd/td/telegram/message/peer"
 	"github.com/gotd/td/tg"
+	"github.com/gotd/td/tgerr"
 )
 
 // ResolverCache is a peer.Resolver cache implemented using peer storage.
+//
+// Concurrent resolutions of the same key not found in storage are
+// collapsed into one call of underlying resolver.
 type ResolverCache struct {
-	next    peer.Resolver
-	storage PeerStorage
+	next     peer.Resolver
+	storage  PeerStorage
+	ttl      time.Duration
+	negative *negativeCache
+	clock    clock.Clock
+	group    *singleflight.Group
 }
 
 // NewResolverCache creates new ResolverCache.
 func NewResolverCache(next peer.Resolver, storage PeerStorage) ResolverCache {
-	return ResolverCache{next: next, storage: storage}
+	return ResolverCache{
+		next:    next,
+		storage: storage,
+		clock:   clock.System,
+		group:   &singleflight.Group{},
+	}
+}
+
+// WithTTL sets time after which stored peer is considered stale.
+// Zero value, the default, means that stored peers are never stale.
+//
+// Stale peer is resolved again. If resolution fails, e.g. due to flood
+// wait, stale peer is returned, unless username or phone is not occupied
+// anymore.
+func (r ResolverCache) WithTTL(ttl time.Duration) ResolverCache {
+	r.ttl = ttl
+	return r
+}
+
+// WithNegativeTTL enables in-memory caching of failed resolutions, so
+// repeated resolution of e.g. deleted username does not call API.
+//
+// "Not occupied" and "invalid" errors are cached for given ttl, FLOOD_WAIT
+// errors are cached for wait duration. Cache keeps up to size entries.
+func (r ResolverCache) WithNegativeTTL(ttl time.Duration, size int) ResolverCache {
+	r.negative = newNegativeCache(ttl, size)
+	return r
+}
+
+// WithClock sets clock to use. Default is to use system clock.
+func (r ResolverCache) WithClock(c clock.Clock) ResolverCache {
+	r.clock = c
+	return r
+}
+
+func (r ResolverCache) stale(p Peer) bool {
+	return r.ttl > 0 && r.clock.Now().Sub(p.CreatedAt) > r.ttl
 }
 
 func (r ResolverCache) notFound(
 	ctx context.Context,
-	key string,
+	query, key string,
 	f func(context.Context, string) (tg.InputPeerClass, error),
 ) (_ tg.InputPeerClass, rerr error) {
+	if err := r.negative.get(key, r.clock.Now()); err != nil {
+		return nil, err
+	}
+
 	// If key not found, try to resolve.
-	resolved, err := f(ctx, key)
+	resolved, err := f(ctx, query)
 	if err != nil {
+		r.negative.put(key, err, r.clock.Now())
 		return nil, err
 	}
 
@@ -35,6 +87,7 @@ func (r ResolverCache) notFound(
 	if err := value.FromInputPeer(resolved); err != nil {
 		return nil, errors.Errorf("extract object: %w", err)
 	}
+	value.CreatedAt = r.clock.Now()
 
 	if err := r.storage.Assign(ctx, key, value); err != nil {
 		return nil, errors.Errorf("assign %q: %w", key, err)
@@ -43,27 +96,96 @@ func (r ResolverCache) notFound(
 	return resolved, nil
 }
 
+// resolve calls notFound, collapsing concurrent calls with the same key.
+// Shared call uses context of caller which started it, so if that caller
+// is canceled, other callers resolve again.
+func (r ResolverCache) resolve(
+	ctx context.Context,
+	query, key string,
+	f func(context.Context, string) (tg.InputPeerClass, error),
+) (tg.InputPeerClass, error) {
+	for {
+		ch := r.group.DoChan(key, func() (any, error) {
+			// Peer may be stored by previous call, which finished after
+			// this caller checked storage.
+			if p, err := r.storage.Resolve(ctx, key); err == nil && !r.stale(p) {
+				return p.AsInputPeer(), nil
+			}
+			return r.notFound(ctx, query, key, f)
+		})
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case res := <-ch:
+			if res.Err != nil {
+				if ctx.Err() == nil &&
+					(errors.Is(res.Err, context.Canceled) || errors.Is(res.Err, context.DeadlineExceeded)) {
+					// Call is canceled by context of another caller.
+					continue
+				}
+				return nil, res.Err
+			}
+			return res.Val.(tg.InputPeerClass), nil
+		}
+	}
+}
+
+// tryResolve finds peer in storage using given keys and resolves query
+// using f, if peer is not found or stale. Resolved peer is assigned to
+// the first key.
 func (r ResolverCache) tryResolve(
 	ctx context.Context,
-	key string,
+	query string,
+	keys []string,
 	f func(context.Context, string) (tg.InputPeerClass, error),
 ) (tg.InputPeerClass, error) {
-	b, err := r.storage.Resolve(ctx, key)
-	if err != nil {
+	var (
+		b     Peer
+		found bool
+	)
+	for _, key := range keys {
+		v, err := r.storage.Resolve(ctx, key)
 		if errors.Is(err, ErrPeerNotFound) {
-			return r.notFound(ctx, key, f)
+			continue
 		}
-		return nil, errors.Errorf("get %q: %w", key, err)
+		if err != nil {
+			return nil, errors.Errorf("get %q: %w", key, err)
+		}
+		b, found = v, true
+		break
+	}
+	if !found {
+		return r.resolve(ctx, query, keys[0], f)
 	}
-	return b.AsInputPeer(), nil
+	if !r.stale(b) {
+		return b.AsInputPeer(), nil
+	}
+
+	resolved, err := r.resolve(ctx, query, keys[0], f)
+	if err != nil {
+		if ctx.Err() != nil || tgerr.Is(err, "USERNAME_NOT_OCCUPIED", "USERNAME_INVALID", "PHONE_NOT_OCCUPIED") {
+			return nil, err
+		}
+		// Stale peer is still likely valid.
+		return b.AsInputPeer(), nil
+	}
+	return resolved, nil
 }
 
 // ResolveDomain implements peer.Resolver
 func (r ResolverCache) ResolveDomain(ctx context.Context, domain string) (tg.InputPeerClass, error) {
-	return r.tryResolve(ctx, domain, r.next.ResolveDomain)
+	return r.tryResolve(ctx, domain, []string{domain}, r.next.ResolveDomain)
 }
 
 // ResolvePhone implements peer.Resolver
+//
+// Resolved peer is stored under PhoneKey. Phones stored by Peer.Keys are
+// also looked up.
 func (r ResolverCache) ResolvePhone(ctx context.Context, phone string) (tg.InputPeerClass, error) {
-	return r.tryResolve(ctx, phone, r.next.ResolvePhone)
+	digits := NormalizePhone(phone)
+	if digits == "" {
+		return r.tryResolve(ctx, phone, []string{phone}, r.next.ResolvePhone)
+	}
+	return r.tryResolve(ctx, phone, []string{PhoneKey(digits), digits}, r.next.ResolvePhone)
 }
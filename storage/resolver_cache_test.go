@@ -2,12 +2,18 @@ package storage
 
 import (
 	"context"
+	"sort"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/go-faster/errors"
 	"github.com/stretchr/testify/require"
 
+	"github.com/gotd/neo"
 	"github.com/gotd/td/tg"
+	"github.com/gotd/td/tgerr"
 )
 
 type memStorage struct {
@@ -23,7 +29,14 @@ func newMemStorage() memStorage {
 }
 
 func (m memStorage) Iterate(ctx context.Context) (PeerIterator, error) {
-	return nil, errors.New("unimplemented")
+	buf := make([]Peer, 0, len(m.peers))
+	for _, p := range m.peers {
+		buf = append(buf, p)
+	}
+	sort.Slice(buf, func(i, j int) bool {
+		return KeyFromPeer(buf[i]).String() < KeyFromPeer(buf[j]).String()
+	})
+	return &testIterator{buf: buf}, nil
 }
 
 func (m memStorage) add(keys []string, p Peer) {
@@ -130,3 +143,240 @@ func TestResolverCache(t *testing.T) {
 		a.Equal(expected, result)
 	})
 }
+
+func TestResolverCacheTTL(t *testing.T) {
+	a := require.New(t)
+	ctx := context.Background()
+	clock := neo.NewTime(time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC))
+
+	var (
+		counter    int
+		expected   tg.InputPeerClass = &tg.InputPeerUser{UserID: 10, AccessHash: 10}
+		resolveErr error
+	)
+	r := func(ctx context.Context, k string) (tg.InputPeerClass, error) {
+		counter++
+		return expected, resolveErr
+	}
+	c := NewResolverCache(resolverFunc(r), newMemStorage()).
+		WithTTL(time.Minute).
+		WithClock(clock)
+
+	result, err := c.ResolveDomain(ctx, "abc")
+	a.NoError(err)
+	a.Equal(expected, result)
+	a.Equal(1, counter)
+
+	// Fresh.
+	clock.Travel(time.Minute)
+	_, err = c.ResolveDomain(ctx, "abc")
+	a.NoError(err)
+	a.Equal(1, counter)
+
+	// Stale, revalidated.
+	clock.Travel(time.Second)
+	expected = &tg.InputPeerUser{UserID: 10, AccessHash: 11}
+	result, err = c.ResolveDomain(ctx, "abc")
+	a.NoError(err)
+	a.Equal(expected, result)
+	a.Equal(2, counter)
+
+	_, err = c.ResolveDomain(ctx, "abc")
+	a.NoError(err)
+	a.Equal(2, counter)
+
+	// Stale, revalidation failed.
+	clock.Travel(2 * time.Minute)
+	resolveErr = errors.New("unavailable")
+	result, err = c.ResolveDomain(ctx, "abc")
+	a.NoError(err)
+	a.Equal(expected, result)
+	a.Equal(3, counter)
+
+	// Stale, username is not occupied anymore.
+	resolveErr = tgerr.New(400, "USERNAME_NOT_OCCUPIED")
+	_, err = c.ResolveDomain(ctx, "abc")
+	a.True(tgerr.Is(err, "USERNAME_NOT_OCCUPIED"))
+	a.Equal(4, counter)
+}
+
+func TestResolverCachePhone(t *testing.T) {
+	a := require.New(t)
+	ctx := context.Background()
+	s := newMemStorage()
+	expected := &tg.InputPeerUser{UserID: 10, AccessHash: 10}
+
+	var queries []string
+	r := func(ctx context.Context, k string) (tg.InputPeerClass, error) {
+		queries = append(queries, k)
+		return expected, nil
+	}
+	c := NewResolverCache(resolverFunc(r), s)
+
+	result, err := c.ResolvePhone(ctx, "+7 (900) 123-45-67")
+	a.NoError(err)
+	a.Equal(expected, result)
+	a.Equal([]string{"+7 (900) 123-45-67"}, queries)
+	a.Contains(s.keys, "phone:79001234567")
+
+	// Normalized phone is cached.
+	_, err = c.ResolvePhone(ctx, "79001234567")
+	a.NoError(err)
+	a.Len(queries, 1)
+
+	// Phone stored by Peer.Keys is found.
+	var p Peer
+	a.True(p.FromUser(&tg.User{ID: 11, AccessHash: 11, Phone: "79007654321"}))
+	a.NoError(s.Add(ctx, p))
+	result, err = c.ResolvePhone(ctx, "+79007654321")
+	a.NoError(err)
+	a.Equal(&tg.InputPeerUser{UserID: 11, AccessHash: 11}, result)
+	a.Len(queries, 1)
+}
+
+func TestResolverCacheNegative(t *testing.T) {
+	a := require.New(t)
+	ctx := context.Background()
+	clock := neo.NewTime(time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC))
+
+	var (
+		counter    int
+		resolveErr error
+	)
+	r := func(ctx context.Context, k string) (tg.InputPeerClass, error) {
+		counter++
+		return &tg.InputPeerUser{UserID: 10, AccessHash: 10}, resolveErr
+	}
+	c := NewResolverCache(resolverFunc(r), newMemStorage()).
+		WithNegativeTTL(time.Minute, 2).
+		WithClock(clock)
+
+	resolveErr = tgerr.New(400, "USERNAME_NOT_OCCUPIED")
+	for i := 0; i < 3; i++ {
+		_, err := c.ResolveDomain(ctx, "deleted")
+		a.True(tgerr.Is(err, "USERNAME_NOT_OCCUPIED"))
+	}
+	a.Equal(1, counter)
+
+	// Other errors are not cached.
+	resolveErr = errors.New("unavailable")
+	_, err := c.ResolveDomain(ctx, "other")
+	a.Error(err)
+	_, err = c.ResolveDomain(ctx, "other")
+	a.Error(err)
+	a.Equal(3, counter)
+
+	// Flood wait is cached for wait duration.
+	resolveErr = tgerr.New(420, "FLOOD_WAIT_5")
+	_, err = c.ResolveDomain(ctx, "flood")
+	a.Error(err)
+	_, err = c.ResolveDomain(ctx, "flood")
+	_, ok := tgerr.AsFloodWait(err)
+	a.True(ok)
+	a.Equal(4, counter)
+
+	clock.Travel(5 * time.Second)
+	resolveErr = nil
+	_, err = c.ResolveDomain(ctx, "flood")
+	a.NoError(err)
+	a.Equal(5, counter)
+
+	_, err = c.ResolveDomain(ctx, "deleted")
+	a.Error(err)
+	a.Equal(5, counter)
+	clock.Travel(time.Minute)
+	_, err = c.ResolveDomain(ctx, "deleted")
+	a.NoError(err)
+	a.Equal(6, counter)
+}
+
+// syncStorage is a memStorage safe for concurrent use.
+type syncStorage struct {
+	mux sync.Mutex
+	memStorage
+}
+
+func (s *syncStorage) Assign(ctx context.Context, key string, p Peer) error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	return s.memStorage.Assign(ctx, key, p)
+}
+
+func (s *syncStorage) Resolve(ctx context.Context, key string) (Peer, error) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	return s.memStorage.Resolve(ctx, key)
+}
+
+func TestResolverCacheSingleflight(t *testing.T) {
+	a := require.New(t)
+	ctx := context.Background()
+	s := &syncStorage{memStorage: newMemStorage()}
+
+	var (
+		calls   atomic.Int32
+		release = make(chan struct{})
+	)
+	r := func(ctx context.Context, k string) (tg.InputPeerClass, error) {
+		calls.Add(1)
+		<-release
+		return &tg.InputPeerUser{UserID: 10, AccessHash: 10}, nil
+	}
+	c := NewResolverCache(resolverFunc(r), s)
+
+	// Callers either share call or find result in storage.
+	const n = 10
+	errs := make(chan error, n)
+	for i := 0; i < n; i++ {
+		go func() {
+			_, err := c.ResolveDomain(ctx, "abc")
+			errs <- err
+		}()
+	}
+	close(release)
+
+	for i := 0; i < n; i++ {
+		a.NoError(<-errs)
+	}
+	a.Equal(int32(1), calls.Load())
+	_, err := s.Resolve(ctx, "abc")
+	a.NoError(err)
+}
+
+func TestResolverCacheSingleflightCanceled(t *testing.T) {
+	a := require.New(t)
+	s := &syncStorage{memStorage: newMemStorage()}
+
+	var (
+		calls   atomic.Int32
+		started = make(chan struct{})
+	)
+	r := func(ctx context.Context, k string) (tg.InputPeerClass, error) {
+		if calls.Add(1) == 1 {
+			close(started)
+			<-ctx.Done()
+			return nil, ctx.Err()
+		}
+		return &tg.InputPeerUser{UserID: 10, AccessHash: 10}, nil
+	}
+	c := NewResolverCache(resolverFunc(r), s)
+
+	leaderCtx, cancel := context.WithCancel(context.Background())
+	leaderErr := make(chan error, 1)
+	go func() {
+		_, err := c.ResolveDomain(leaderCtx, "abc")
+		leaderErr <- err
+	}()
+	<-started
+
+	followerErr := make(chan error, 1)
+	go func() {
+		_, err := c.ResolveDomain(context.Background(), "abc")
+		followerErr <- err
+	}()
+	cancel()
+
+	a.ErrorIs(<-leaderErr, context.Canceled)
+	a.NoError(<-followerErr)
+	a.Equal(int32(2), calls.Load())
+}
@@ -0,0 +1,98 @@
+package storage
+
+import (
+	"container/list"
+	"sync"
+	"time"
+
+	"github.com/gotd/td/tgerr"
+)
+
+// negativeCache is a LRU cache of failed resolutions.
+type negativeCache struct {
+	ttl  time.Duration
+	size int
+
+	mux   sync.Mutex
+	lru   *list.List
+	items map[string]*list.Element
+}
+
+type negativeEntry struct {
+	key   string
+	err   error
+	until time.Time
+}
+
+func newNegativeCache(ttl time.Duration, size int) *negativeCache {
+	if size < 1 {
+		size = 1
+	}
+	return &negativeCache{
+		ttl:   ttl,
+		size:  size,
+		lru:   list.New(),
+		items: map[string]*list.Element{},
+	}
+}
+
+// get returns cached error of given key or nil.
+func (c *negativeCache) get(key string, now time.Time) error {
+	if c == nil {
+		return nil
+	}
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	e, ok := c.items[key]
+	if !ok {
+		return nil
+	}
+	entry := e.Value.(*negativeEntry)
+	if !now.Before(entry.until) {
+		c.lru.Remove(e)
+		delete(c.items, key)
+		return nil
+	}
+	c.lru.MoveToFront(e)
+	return entry.err
+}
+
+// put caches given error, if it is a not found or flood wait error.
+//
+// Not found errors are cached for ttl, flood wait errors are cached for
+// wait duration.
+func (c *negativeCache) put(key string, err error, now time.Time) {
+	if c == nil {
+		return
+	}
+
+	var ttl time.Duration
+	if d, ok := tgerr.AsFloodWait(err); ok {
+		ttl = d
+	} else if tgerr.Is(err,
+		"USERNAME_NOT_OCCUPIED", "USERNAME_INVALID",
+		"PHONE_NOT_OCCUPIED", "PHONE_NUMBER_INVALID",
+	) {
+		ttl = c.ttl
+	}
+	if ttl <= 0 {
+		return
+	}
+
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	entry := &negativeEntry{key: key, err: err, until: now.Add(ttl)}
+	if e, ok := c.items[key]; ok {
+		e.Value = entry
+		c.lru.MoveToFront(e)
+		return
+	}
+	c.items[key] = c.lru.PushFront(entry)
+	for c.lru.Len() > c.size {
+		e := c.lru.Back()
+		c.lru.Remove(e)
+		delete(c.items, e.Value.(*negativeEntry).key)
+	}
+}
@@ -0,0 +1,139 @@
+package storage
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/go-faster/errors"
+)
+
+// SchemaVersion is a latest version of storage layout: keys, indexes and
+// peer encoding.
+//
+// History:
+//
+//	1: recency and username indexes.
+const SchemaVersion = 1
+
+// SchemaKey is a key of schema version record in backends.
+const SchemaKey = "_schema"
+
+// ErrSchemaTooNew means that storage was written by newer version and
+// could not be used safely.
+var ErrSchemaTooNew = errors.New("storage schema is newer than supported")
+
+// SchemaVersioner is a PeerStorage which persists its schema version.
+type SchemaVersioner interface {
+	// SchemaVersion returns stored schema version.
+	// Zero means that storage has no version record, e.g. it is empty or
+	// written before schema versioning.
+	SchemaVersion(ctx context.Context) (int, error)
+	// SetSchemaVersion stores schema version.
+	SetSchemaVersion(ctx context.Context, version int) error
+}
+
+// Migration upgrades storage data to given schema version.
+type Migration struct {
+	Version int
+	Name    string
+	Migrate func(ctx context.Context, s PeerStorage) error
+}
+
+// Migrations returns built-in migrations up to SchemaVersion.
+func Migrations() []Migration {
+	return []Migration{
+		{
+			Version: 1,
+			Name:    "rebuild indexes",
+			Migrate: RebuildIndexes,
+		},
+	}
+}
+
+// UpgradeSchema applies migrations newer than stored schema version, in
+// order of versions, storing new version after every migration. If no
+// migrations given, built-in Migrations are used.
+//
+// UpgradeSchema should be called on startup, before storage is used.
+// It does nothing if storage does not implement SchemaVersioner, and
+// returns ErrSchemaTooNew if storage is written by newer version.
+func UpgradeSchema(ctx context.Context, s PeerStorage, migrations ...Migration) error {
+	v, ok := s.(SchemaVersioner)
+	if !ok {
+		return nil
+	}
+	if len(migrations) == 0 {
+		migrations = Migrations()
+	}
+
+	sorted := make([]Migration, len(migrations))
+	copy(sorted, migrations)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Version < sorted[j].Version
+	})
+	for i := 1; i < len(sorted); i++ {
+		if sorted[i].Version == sorted[i-1].Version {
+			return errors.Errorf("duplicate migration %d", sorted[i].Version)
+		}
+	}
+
+	current, err := v.SchemaVersion(ctx)
+	if err != nil {
+		return errors.Errorf("get schema version: %w", err)
+	}
+	if latest := sorted[len(sorted)-1].Version; current > latest {
+		return errors.Errorf("version %d: %w", current, ErrSchemaTooNew)
+	}
+
+	for _, m := range sorted {
+		if m.Version <= current {
+			continue
+		}
+		if err := m.Migrate(ctx, s); err != nil {
+			return errors.Errorf("migrate to %d (%s): %w", m.Version, m.Name, err)
+		}
+		if err := v.SetSchemaVersion(ctx, m.Version); err != nil {
+			return errors.Errorf("set schema version %d: %w", m.Version, err)
+		}
+		current = m.Version
+	}
+
+	return nil
+}
+
+// RebuildIndexes adds all stored peers again, so backend indexes, e.g.
+// recency and usernames, are rebuilt.
+//
+// Peers are read page by page, so backend transactions are short.
+func RebuildIndexes(ctx context.Context, s PeerStorage) error {
+	expiring, _ := s.(ExpiringPeerStorage)
+
+	var cursor string
+	for {
+		page, err := IteratePage(ctx, s, cursor, 100)
+		if err != nil {
+			return errors.Errorf("iterate: %w", err)
+		}
+		for _, p := range page.Peers {
+			if err := rebuildPeer(ctx, s, expiring, p); err != nil {
+				return errors.Errorf("add %s: %w", KeyFromPeer(p), err)
+			}
+		}
+		if page.Next == "" {
+			return nil
+		}
+		cursor = page.Next
+	}
+}
+
+func rebuildPeer(ctx context.Context, s PeerStorage, expiring ExpiringPeerStorage, p Peer) error {
+	if p.ExpiresAt.IsZero() || expiring == nil {
+		return s.Add(ctx, p)
+	}
+	ttl := time.Until(p.ExpiresAt)
+	if ttl <= 0 {
+		return nil
+	}
+	return expiring.AddTTL(ctx, p, ttl)
+}
@@ -0,0 +1,80 @@
+package storage
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-faster/errors"
+	"github.com/stretchr/testify/require"
+)
+
+type versionedStorage struct {
+	memStorage
+	version *int
+}
+
+func (v versionedStorage) SchemaVersion(ctx context.Context) (int, error) {
+	return *v.version, nil
+}
+
+func (v versionedStorage) SetSchemaVersion(ctx context.Context, version int) error {
+	*v.version = version
+	return nil
+}
+
+func TestUpgradeSchema(t *testing.T) {
+	a := require.New(t)
+	ctx := context.Background()
+
+	// Storages without schema are not upgraded.
+	a.NoError(UpgradeSchema(ctx, newMemStorage(), Migration{
+		Version: 1,
+		Migrate: func(ctx context.Context, s PeerStorage) error {
+			return errors.New("must not be called")
+		},
+	}))
+
+	var version int
+	s := versionedStorage{memStorage: newMemStorage(), version: &version}
+
+	var applied []int
+	migration := func(v int) Migration {
+		return Migration{
+			Version: v,
+			Migrate: func(ctx context.Context, s PeerStorage) error {
+				applied = append(applied, v)
+				if v == 3 {
+					return errors.New("failed")
+				}
+				return nil
+			},
+		}
+	}
+
+	a.NoError(UpgradeSchema(ctx, s, migration(1), migration(2)))
+	a.Equal([]int{1, 2}, applied)
+	a.Equal(2, version)
+
+	// Failed migration does not bump version.
+	a.Error(UpgradeSchema(ctx, s, migration(1), migration(2), migration(3)))
+	a.Equal([]int{1, 2, 3}, applied)
+	a.Equal(2, version)
+
+	a.ErrorIs(UpgradeSchema(ctx, s, migration(1)), ErrSchemaTooNew)
+
+	// Migrations are applied in order of versions.
+	applied = nil
+	a.NoError(UpgradeSchema(ctx, s, migration(5), migration(4), migration(2)))
+	a.Equal([]int{4, 5}, applied)
+	a.Equal(5, version)
+
+	applied = nil
+	a.Error(UpgradeSchema(ctx, s, migration(6), migration(6)))
+	a.Empty(applied)
+	a.Equal(5, version)
+
+	// Built-in migrations.
+	version = 0
+	a.NoError(UpgradeSchema(ctx, s))
+	a.Equal(SchemaVersion, version)
+}
@@ -0,0 +1,63 @@
+package storage
+
+import (
+	"context"
+	"strings"
+)
+
+// PeerSearcher is a PeerStorage which maintains username index.
+type PeerSearcher interface {
+	// SearchPrefix creates and returns new PeerIterator which returns only
+	// peers whose username starts with given prefix.
+	//
+	// Prefix match is case-insensitive.
+	SearchPrefix(ctx context.Context, prefix string) (PeerIterator, error)
+}
+
+// SearchPrefix creates and returns new PeerIterator which returns only peers
+// whose username starts with given prefix.
+//
+// If storage implements PeerSearcher, it is used. Otherwise,
+// SearchPrefix filters result of Iterate.
+func SearchPrefix(ctx context.Context, s PeerStorage, prefix string) (PeerIterator, error) {
+	if f, ok := s.(PeerSearcher); ok {
+		return f.SearchPrefix(ctx, prefix)
+	}
+
+	iter, err := s.Iterate(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &filterIterator{
+		PeerIterator: iter,
+		match: func(p Peer) bool {
+			return MatchUsernamePrefix(p, prefix)
+		},
+	}, nil
+}
+
+// Username returns username of peer, if any.
+func (p Peer) Username() string {
+	switch {
+	case p.User != nil:
+		return p.User.Username
+	case p.Channel != nil:
+		return p.Channel.Username
+	default:
+		return ""
+	}
+}
+
+// UsernameIndex returns normalized username for username index,
+// or empty string if peer has no username.
+func UsernameIndex(p Peer) string {
+	return strings.ToLower(p.Username())
+}
+
+// MatchUsernamePrefix reports whether peer has username with given prefix.
+//
+// Prefix match is case-insensitive.
+func MatchUsernamePrefix(p Peer, prefix string) bool {
+	username := UsernameIndex(p)
+	return username != "" && strings.HasPrefix(username, strings.ToLower(prefix))
+}
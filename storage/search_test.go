@@ -0,0 +1,37 @@
+package storage
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/gotd/td/tg"
+)
+
+func TestSearchPrefix(t *testing.T) {
+	a := require.New(t)
+	ctx := context.Background()
+	s := newMemStorage()
+
+	var user, channel, chat Peer
+	a.True(user.FromUser(&tg.User{ID: 1, Username: "Gopher"}))
+	a.True(channel.FromChat(&tg.Channel{ID: 2, Username: "gophers"}))
+	a.True(chat.FromChat(&tg.Chat{ID: 3}))
+	a.NoError(s.Add(ctx, user))
+	a.NoError(s.Add(ctx, channel))
+	a.NoError(s.Add(ctx, chat))
+
+	iter, err := SearchPrefix(ctx, s, "goph")
+	a.NoError(err)
+	var r []string
+	a.NoError(ForEach(ctx, iter, func(p Peer) error {
+		r = append(r, p.Username())
+		return nil
+	}))
+	a.NoError(iter.Close())
+	a.Equal([]string{"Gopher", "gophers"}, r)
+
+	a.False(MatchUsernamePrefix(chat, ""))
+	a.True(MatchUsernamePrefix(user, ""))
+}
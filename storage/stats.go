@@ -0,0 +1,124 @@
+package storage
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-faster/errors"
+
+	"github.com/gotd/td/telegram/query/dialogs"
+)
+
+// CompactionStats is a compaction statistics of storage.
+type CompactionStats struct {
+	// Count is a count of compactions since storage was opened.
+	Count int64 `json:"count"`
+	// InProgress is a count of running compactions.
+	InProgress int64 `json:"in_progress"`
+	// Duration is a total duration of compactions.
+	Duration time.Duration `json:"duration"`
+}
+
+// Stats is a peer storage statistics.
+type Stats struct {
+	Users    int `json:"users"`
+	Chats    int `json:"chats"`
+	Channels int `json:"channels"`
+	// Keys is a count of associated keys, zero if storage could not
+	// enumerate them.
+	Keys int `json:"keys,omitempty"`
+	// Bytes is a storage size, zero if storage could not report it.
+	Bytes int64 `json:"bytes,omitempty"`
+	// Compaction is set if storage does compactions.
+	Compaction *CompactionStats `json:"compaction,omitempty"`
+}
+
+func (s *Stats) addPeer(kind dialogs.PeerKind) {
+	switch kind {
+	case dialogs.User:
+		s.Users++
+	case dialogs.Chat:
+		s.Chats++
+	case dialogs.Channel:
+		s.Channels++
+	}
+}
+
+// PeerStatsReporter is a PeerStorage which is able to report its statistics.
+type PeerStatsReporter interface {
+	// Stats returns storage statistics.
+	Stats(ctx context.Context) (Stats, error)
+}
+
+// PeerStats returns statistics of given storage.
+//
+// If storage implements PeerStatsReporter, it is used. Otherwise, raw
+// entries are scanned if storage implements RawScanner, or peers are counted
+// using CountPeers.
+func PeerStats(ctx context.Context, s PeerStorage) (Stats, error) {
+	switch s := s.(type) {
+	case PeerStatsReporter:
+		return s.Stats(ctx)
+	case RawScanner:
+		return RawStats(ctx, s)
+	}
+
+	var (
+		r     Stats
+		kinds = []struct {
+			kind dialogs.PeerKind
+			to   *int
+		}{
+			{dialogs.User, &r.Users},
+			{dialogs.Chat, &r.Chats},
+			{dialogs.Channel, &r.Channels},
+		}
+	)
+	if _, ok := s.(PeerCounter); ok {
+		for _, k := range kinds {
+			count, err := CountPeers(ctx, s, k.kind)
+			if err != nil {
+				return Stats{}, errors.Errorf("count: %w", err)
+			}
+			*k.to = count
+		}
+		return r, nil
+	}
+
+	iter, err := s.Iterate(ctx)
+	if err != nil {
+		return Stats{}, errors.Errorf("iterate: %w", err)
+	}
+	defer func() {
+		_ = iter.Close()
+	}()
+	if err := ForEach(ctx, iter, func(p Peer) error {
+		r.addPeer(p.Key.Kind)
+		return nil
+	}); err != nil {
+		return Stats{}, err
+	}
+	return r, nil
+}
+
+// RawStats returns statistics of storage by scanning its raw entries.
+// Bytes is a total size of scanned keys and values.
+func RawStats(ctx context.Context, s RawScanner) (Stats, error) {
+	var r Stats
+	if err := s.ScanRaw(ctx, func(key, value []byte) error {
+		r.Bytes += int64(len(key) + len(value))
+
+		var k PeerKey
+		if k.Parse(key) == nil {
+			r.addPeer(k.Kind)
+			return nil
+		}
+		if _, ok := AssociatedKey(key, value); ok {
+			r.Keys++
+		}
+		return nil
+	}); err != nil {
+		return Stats{}, errors.Errorf("scan: %w", err)
+	}
+	return r, nil
+}
@@ -0,0 +1,32 @@
+package storage
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/gotd/td/tg"
+)
+
+func TestPeerStats(t *testing.T) {
+	a := require.New(t)
+	ctx := context.Background()
+
+	s := newMemStorage()
+	for _, chat := range []tg.ChatClass{
+		&tg.Chat{ID: 10},
+		&tg.Channel{ID: 11, AccessHash: 11},
+	} {
+		var p Peer
+		a.True(p.FromChat(chat))
+		a.NoError(s.Add(ctx, p))
+	}
+	var p Peer
+	a.True(p.FromUser(&tg.User{ID: 12, AccessHash: 12}))
+	a.NoError(s.Add(ctx, p))
+
+	stats, err := PeerStats(ctx, s)
+	a.NoError(err)
+	a.Equal(Stats{Users: 1, Chats: 1, Channels: 1}, stats)
+}
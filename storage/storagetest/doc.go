@@ -0,0 +1,4 @@
+// Package storagetest implements conformance test suite of
+// storage.PeerStorage for custom backends and test doubles of
+// storage.PeerStorage.
+package storagetest
@@ -0,0 +1,201 @@
+package storagetest
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/gotd/td/clock"
+
+	"github.com/gotd/contrib/storage"
+)
+
+// Method is a storage.PeerStorage method name.
+type Method string
+
+// Methods of storage.PeerStorage.
+const (
+	MethodAdd     Method = "Add"
+	MethodFind    Method = "Find"
+	MethodAssign  Method = "Assign"
+	MethodResolve Method = "Resolve"
+	MethodIterate Method = "Iterate"
+)
+
+// Call is a recorded storage call.
+type Call struct {
+	Method Method
+	// Key is a peer key of Add, Find and Assign.
+	Key storage.PeerKey
+	// Associated is a key of Assign and Resolve.
+	Associated string
+	Err        error
+}
+
+var _ storage.PeerStorage = (*Faulty)(nil)
+
+// Faulty is a storage.PeerStorage test double with programmable latencies
+// and errors per method, which records calls.
+type Faulty struct {
+	next  storage.PeerStorage
+	clock clock.Clock
+
+	mux     sync.Mutex
+	latency map[Method]time.Duration
+	errs    map[Method]error
+	queued  map[Method][]error
+	calls   []Call
+}
+
+// NewFaulty creates new Faulty wrapping given storage.
+// If next is nil, Memory storage is used.
+func NewFaulty(next storage.PeerStorage) *Faulty {
+	if next == nil {
+		next = NewMemory()
+	}
+	return &Faulty{
+		next:    next,
+		clock:   clock.System,
+		latency: map[Method]time.Duration{},
+		errs:    map[Method]error{},
+		queued:  map[Method][]error{},
+	}
+}
+
+// WithClock sets clock to use. Default is to use system clock.
+func (f *Faulty) WithClock(c clock.Clock) *Faulty {
+	f.clock = c
+	return f
+}
+
+// SetLatency sets latency of given method.
+func (f *Faulty) SetLatency(m Method, d time.Duration) {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+
+	f.latency[m] = d
+}
+
+// SetError makes all calls of given method fail with given error.
+// Nil error clears it.
+func (f *Faulty) SetError(m Method, err error) {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+
+	if err == nil {
+		delete(f.errs, m)
+		return
+	}
+	f.errs[m] = err
+}
+
+// FailNext makes next calls of given method fail with given errors,
+// one error per call. Nil error means successful call.
+func (f *Faulty) FailNext(m Method, errs ...error) {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+
+	f.queued[m] = append(f.queued[m], errs...)
+}
+
+// Calls returns recorded calls.
+func (f *Faulty) Calls() []Call {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+
+	return append([]Call(nil), f.calls...)
+}
+
+// Reset clears recorded calls, latencies and errors.
+func (f *Faulty) Reset() {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+
+	f.calls = nil
+	f.latency = map[Method]time.Duration{}
+	f.errs = map[Method]error{}
+	f.queued = map[Method][]error{}
+}
+
+// before waits for latency of method and returns injected error.
+func (f *Faulty) before(ctx context.Context, m Method) error {
+	f.mux.Lock()
+	d := f.latency[m]
+	err := f.errs[m]
+	if queue := f.queued[m]; len(queue) > 0 {
+		err = queue[0]
+		f.queued[m] = queue[1:]
+	}
+	f.mux.Unlock()
+
+	if d > 0 {
+		t := f.clock.Timer(d)
+		defer clock.StopTimer(t)
+		select {
+		case <-t.C():
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return err
+}
+
+func (f *Faulty) record(c Call) {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+
+	f.calls = append(f.calls, c)
+}
+
+// Add implements storage.PeerStorage.
+func (f *Faulty) Add(ctx context.Context, value storage.Peer) error {
+	err := f.before(ctx, MethodAdd)
+	if err == nil {
+		err = f.next.Add(ctx, value)
+	}
+	f.record(Call{Method: MethodAdd, Key: storage.KeyFromPeer(value), Err: err})
+	return err
+}
+
+// Find implements storage.PeerStorage.
+func (f *Faulty) Find(ctx context.Context, key storage.PeerKey) (storage.Peer, error) {
+	var p storage.Peer
+	err := f.before(ctx, MethodFind)
+	if err == nil {
+		p, err = f.next.Find(ctx, key)
+	}
+	f.record(Call{Method: MethodFind, Key: key, Err: err})
+	return p, err
+}
+
+// Assign implements storage.PeerStorage.
+func (f *Faulty) Assign(ctx context.Context, key string, value storage.Peer) error {
+	err := f.before(ctx, MethodAssign)
+	if err == nil {
+		err = f.next.Assign(ctx, key, value)
+	}
+	f.record(Call{Method: MethodAssign, Key: storage.KeyFromPeer(value), Associated: key, Err: err})
+	return err
+}
+
+// Resolve implements storage.PeerStorage.
+func (f *Faulty) Resolve(ctx context.Context, key string) (storage.Peer, error) {
+	var p storage.Peer
+	err := f.before(ctx, MethodResolve)
+	if err == nil {
+		p, err = f.next.Resolve(ctx, key)
+	}
+	f.record(Call{Method: MethodResolve, Associated: key, Err: err})
+	return p, err
+}
+
+// Iterate implements storage.PeerStorage.
+func (f *Faulty) Iterate(ctx context.Context) (storage.PeerIterator, error) {
+	var iter storage.PeerIterator
+	err := f.before(ctx, MethodIterate)
+	if err == nil {
+		iter, err = f.next.Iterate(ctx)
+	}
+	f.record(Call{Method: MethodIterate, Err: err})
+	return iter, err
+}
@@ -0,0 +1,67 @@
+package storagetest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-faster/errors"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gotd/neo"
+
+	"github.com/gotd/contrib/storage"
+)
+
+func TestMemory(t *testing.T) {
+	TestPeerStorage(t, func() storage.PeerStorage {
+		return NewMemory()
+	})
+}
+
+func TestFaulty(t *testing.T) {
+	TestPeerStorage(t, func() storage.PeerStorage {
+		return NewFaulty(nil)
+	})
+
+	a := require.New(t)
+	ctx := context.Background()
+	clock := neo.NewTime(time.Now())
+	f := NewFaulty(nil).WithClock(clock)
+
+	testErr := errors.New("unavailable")
+	p := user(10, 10, "user")
+	f.FailNext(MethodAdd, testErr, nil)
+	a.ErrorIs(f.Add(ctx, p), testErr)
+	a.NoError(f.Add(ctx, p))
+
+	f.SetError(MethodResolve, testErr)
+	_, err := f.Resolve(ctx, "user")
+	a.ErrorIs(err, testErr)
+	f.SetError(MethodResolve, nil)
+	_, err = f.Resolve(ctx, "user")
+	a.NoError(err)
+
+	f.SetLatency(MethodFind, time.Second)
+	observe := clock.Observe()
+	done := make(chan error, 1)
+	go func() {
+		_, err := f.Find(ctx, storage.KeyFromPeer(p))
+		done <- err
+	}()
+	<-observe
+	clock.Travel(time.Second)
+	a.NoError(<-done)
+
+	key := storage.KeyFromPeer(p)
+	a.Equal([]Call{
+		{Method: MethodAdd, Key: key, Err: testErr},
+		{Method: MethodAdd, Key: key},
+		{Method: MethodResolve, Associated: "user", Err: testErr},
+		{Method: MethodResolve, Associated: "user"},
+		{Method: MethodFind, Key: key},
+	}, f.Calls())
+
+	f.Reset()
+	a.Empty(f.Calls())
+}
@@ -0,0 +1,60 @@
+package storagetest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/gotd/contrib/storage"
+)
+
+func count(ctx context.Context, a *require.Assertions, s storage.PeerStorage) int {
+	iter, err := s.Iterate(ctx)
+	a.NoError(err)
+	defer func() {
+		a.NoError(iter.Close())
+	}()
+
+	var n int
+	for iter.Next(ctx) {
+		n++
+	}
+	a.NoError(iter.Err())
+	return n
+}
+
+// TestIsolation tests that given empty storages sharing one database,
+// e.g. of different namespaces, do not observe each other.
+func TestIsolation(t *testing.T, first, second storage.PeerStorage) {
+	t.Helper()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	a := require.New(t)
+
+	// Same peer with different access hashes, like one seen by two accounts.
+	p1, p2 := user(10, 1, "first"), user(10, 2, "second")
+	a.NoError(first.Add(ctx, p1))
+	a.NoError(first.Assign(ctx, "key", p1))
+	a.NoError(second.Add(ctx, p2))
+
+	got, err := first.Find(ctx, storage.KeyFromPeer(p1))
+	a.NoError(err)
+	a.Equal(int64(1), got.Key.AccessHash)
+	got, err = second.Find(ctx, storage.KeyFromPeer(p2))
+	a.NoError(err)
+	a.Equal(int64(2), got.Key.AccessHash)
+
+	_, err = second.Resolve(ctx, "key")
+	a.ErrorIs(err, storage.ErrPeerNotFound)
+	_, err = second.Resolve(ctx, "first")
+	a.ErrorIs(err, storage.ErrPeerNotFound)
+	_, err = first.Resolve(ctx, "second")
+	a.ErrorIs(err, storage.ErrPeerNotFound)
+
+	a.NoError(second.Add(ctx, user(11, 1, "")))
+	a.Equal(1, count(ctx, a, first))
+	a.Equal(2, count(ctx, a, second))
+}
@@ -0,0 +1,96 @@
+package storagetest
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/gotd/contrib/storage"
+)
+
+var _ storage.PeerStorage = (*Memory)(nil)
+
+// Memory is an in-memory storage.PeerStorage for tests.
+type Memory struct {
+	mux   sync.Mutex
+	peers map[storage.PeerKey]storage.Peer
+	keys  map[string]storage.PeerKey
+}
+
+// NewMemory creates new empty Memory storage.
+func NewMemory() *Memory {
+	return &Memory{
+		peers: map[storage.PeerKey]storage.Peer{},
+		keys:  map[string]storage.PeerKey{},
+	}
+}
+
+func (m *Memory) add(associated []string, value storage.Peer) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	id := storage.KeyFromPeer(value)
+	m.peers[id] = value
+	for _, key := range associated {
+		m.keys[key] = id
+	}
+}
+
+// Add implements storage.PeerStorage.
+func (m *Memory) Add(ctx context.Context, value storage.Peer) error {
+	m.add(value.Keys(), value)
+	return nil
+}
+
+// Find implements storage.PeerStorage.
+func (m *Memory) Find(ctx context.Context, key storage.PeerKey) (storage.Peer, error) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	p, ok := m.peers[key]
+	if !ok {
+		return storage.Peer{}, storage.ErrPeerNotFound
+	}
+	return p, nil
+}
+
+// Assign implements storage.PeerStorage.
+func (m *Memory) Assign(ctx context.Context, key string, value storage.Peer) error {
+	m.add(append(value.Keys(), key), value)
+	return nil
+}
+
+// Resolve implements storage.PeerStorage.
+func (m *Memory) Resolve(ctx context.Context, key string) (storage.Peer, error) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	id, ok := m.keys[key]
+	if !ok {
+		return storage.Peer{}, storage.ErrPeerNotFound
+	}
+	p, ok := m.peers[id]
+	if !ok {
+		return storage.Peer{}, storage.ErrPeerNotFound
+	}
+	return p, nil
+}
+
+// Iterate implements storage.PeerStorage. Peers are iterated in key order.
+func (m *Memory) Iterate(ctx context.Context) (storage.PeerIterator, error) {
+	m.mux.Lock()
+	peers := make([]storage.Peer, 0, len(m.peers))
+	for _, p := range m.peers {
+		peers = append(peers, p)
+	}
+	m.mux.Unlock()
+
+	sort.Slice(peers, func(i, j int) bool {
+		a, b := storage.KeyFromPeer(peers[i]), storage.KeyFromPeer(peers[j])
+		if a.Kind != b.Kind {
+			return a.Kind < b.Kind
+		}
+		return a.ID < b.ID
+	})
+	return storage.NewSliceIterator(peers), nil
+}
@@ -0,0 +1,177 @@
+package storagetest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/gotd/td/tg"
+
+	"github.com/gotd/contrib/storage"
+)
+
+func user(id, accessHash int64, username string) storage.Peer {
+	var p storage.Peer
+	p.FromUser(&tg.User{
+		ID:         id,
+		AccessHash: accessHash,
+		Username:   username,
+	})
+	return p
+}
+
+// TestPeerStorage runs conformance tests of storage.PeerStorage.
+//
+// Every test gets new empty storage from given function, use t.Cleanup
+// to close it.
+func TestPeerStorage(t *testing.T, newStorage func() storage.PeerStorage) {
+	t.Helper()
+
+	run := func(name string, f func(ctx context.Context, a *require.Assertions, s storage.PeerStorage)) {
+		t.Run(name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+			defer cancel()
+
+			f(ctx, require.New(t), newStorage())
+		})
+	}
+
+	run("NotFound", func(ctx context.Context, a *require.Assertions, s storage.PeerStorage) {
+		_, err := s.Find(ctx, storage.KeyFromPeer(user(10, 10, "")))
+		a.ErrorIs(err, storage.ErrPeerNotFound)
+		_, err = s.Resolve(ctx, "missing")
+		a.ErrorIs(err, storage.ErrPeerNotFound)
+	})
+	run("AddFind", func(ctx context.Context, a *require.Assertions, s storage.PeerStorage) {
+		p := user(10, 10, "username")
+		a.NoError(s.Add(ctx, p))
+
+		got, err := s.Find(ctx, storage.KeyFromPeer(p))
+		a.NoError(err)
+		a.Equal(p.Key, got.Key)
+		a.NotNil(got.User)
+		a.Equal("username", got.User.Username)
+
+		// Add associates keys of peer entity.
+		got, err = s.Resolve(ctx, "username")
+		a.NoError(err)
+		a.Equal(p.Key, got.Key)
+	})
+	run("AssignResolve", func(ctx context.Context, a *require.Assertions, s storage.PeerStorage) {
+		p := user(10, 10, "")
+		a.NoError(s.Assign(ctx, "key", p))
+
+		got, err := s.Resolve(ctx, "key")
+		a.NoError(err)
+		a.Equal(p.Key, got.Key)
+
+		// Assign adds peer too.
+		got, err = s.Find(ctx, storage.KeyFromPeer(p))
+		a.NoError(err)
+		a.Equal(p.Key, got.Key)
+	})
+	run("Overwrite", func(ctx context.Context, a *require.Assertions, s storage.PeerStorage) {
+		a.NoError(s.Add(ctx, user(10, 10, "old")))
+		p := user(10, 20, "new")
+		a.NoError(s.Add(ctx, p))
+
+		got, err := s.Find(ctx, storage.KeyFromPeer(p))
+		a.NoError(err)
+		a.Equal(int64(20), got.Key.AccessHash)
+		a.Equal("new", got.User.Username)
+
+		got, err = s.Resolve(ctx, "new")
+		a.NoError(err)
+		a.Equal(p.Key, got.Key)
+
+		// Key is re-associated to another peer.
+		a.NoError(s.Assign(ctx, "key", p))
+		other := user(11, 11, "")
+		a.NoError(s.Assign(ctx, "key", other))
+		got, err = s.Resolve(ctx, "key")
+		a.NoError(err)
+		a.Equal(other.Key, got.Key)
+	})
+	run("Iterate", func(ctx context.Context, a *require.Assertions, s storage.PeerStorage) {
+		want := map[storage.PeerKey]struct{}{}
+		for i := int64(0); i < 5; i++ {
+			p := user(10+i, 10+i, "")
+			a.NoError(s.Add(ctx, p))
+			want[storage.KeyFromPeer(p)] = struct{}{}
+		}
+		// Overwrite must not duplicate peer.
+		a.NoError(s.Add(ctx, user(10, 10, "")))
+
+		iter, err := s.Iterate(ctx)
+		a.NoError(err)
+		got := map[storage.PeerKey]struct{}{}
+		for iter.Next(ctx) {
+			key := storage.KeyFromPeer(iter.Value())
+			a.NotContains(got, key, "duplicate peer")
+			got[key] = struct{}{}
+		}
+		a.NoError(iter.Err())
+		a.NoError(iter.Close())
+		a.Equal(want, got)
+	})
+	if _, ok := newStorage().(storage.PeerStatsReporter); ok {
+		run("Stats", testStats)
+	}
+	if _, ok := newStorage().(storage.PeerEvicter); ok {
+		run("Evict", testEvict)
+	}
+}
+
+func testEvict(ctx context.Context, a *require.Assertions, s storage.PeerStorage) {
+	const gap = 10 * time.Millisecond
+
+	old, recent := user(10, 10, "old"), user(11, 11, "recent")
+	a.NoError(s.Add(ctx, old))
+	time.Sleep(2 * gap)
+	a.NoError(s.Add(ctx, recent))
+
+	n, err := storage.Evict(ctx, s, gap)
+	a.NoError(err)
+	a.Equal(1, n)
+	_, err = s.Find(ctx, storage.KeyFromPeer(old))
+	a.ErrorIs(err, storage.ErrPeerNotFound)
+	_, err = s.Resolve(ctx, "old")
+	a.ErrorIs(err, storage.ErrPeerNotFound)
+	_, err = s.Find(ctx, storage.KeyFromPeer(recent))
+	a.NoError(err)
+
+	// Reads touch peers with TouchOnRead.
+	time.Sleep(gap)
+	newer := user(12, 12, "")
+	a.NoError(s.Add(ctx, newer))
+	time.Sleep(gap)
+	_, err = storage.TouchOnRead(s).Resolve(ctx, "recent")
+	a.NoError(err)
+
+	n, err = storage.EvictLRU(ctx, s, 1)
+	a.NoError(err)
+	a.Equal(1, n)
+	_, err = s.Find(ctx, storage.KeyFromPeer(newer))
+	a.ErrorIs(err, storage.ErrPeerNotFound)
+	_, err = s.Find(ctx, storage.KeyFromPeer(recent))
+	a.NoError(err)
+}
+
+func testStats(ctx context.Context, a *require.Assertions, s storage.PeerStorage) {
+	a.NoError(s.Add(ctx, user(10, 10, "first")))
+	a.NoError(s.Add(ctx, user(11, 11, "second")))
+	var channel storage.Peer
+	a.True(channel.FromChat(&tg.Channel{ID: 12, AccessHash: 12, Username: "channel", Photo: &tg.ChatPhotoEmpty{}}))
+	a.NoError(s.Add(ctx, channel))
+
+	stats, err := storage.PeerStats(ctx, s)
+	a.NoError(err)
+	a.Equal(2, stats.Users)
+	a.Zero(stats.Chats)
+	a.Equal(1, stats.Channels)
+	if stats.Keys != 0 {
+		a.Equal(3, stats.Keys)
+	}
+}
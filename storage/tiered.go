@@ -0,0 +1,111 @@
+package storage
+
+import (
+	"context"
+
+	"github.com/go-faster/errors"
+)
+
+var _ PeerStorage = (*Tiered)(nil)
+
+// Tiered is a PeerStorage which chains multiple storage layers, ordered
+// from the fastest to the source of truth, e.g. local pebble and shared redis.
+//
+// Writes go through all layers, starting from the last one, so source of
+// truth is written first. Reads try layers in order and promote found peer
+// to preceding layers. Iterate uses the last layer.
+//
+// Use Cached on top of Tiered to add in-memory layer:
+//
+//	NewCached(NewTiered(local, remote), 1024)
+//
+// Use storagebench.NewTiered to pick the last layer by benchmark at startup.
+type Tiered struct {
+	layers  []PeerStorage
+	onError func(ctx context.Context, layer int, err error)
+}
+
+// NewTiered creates new Tiered storage from given layers.
+func NewTiered(layers ...PeerStorage) *Tiered {
+	return &Tiered{
+		layers:  layers,
+		onError: func(ctx context.Context, layer int, err error) {},
+	}
+}
+
+// OnPromoteError sets callback called on failed promotion of peer
+// to given layer, e.g. to log errors. Promotion errors do not fail reads.
+func (t *Tiered) OnPromoteError(f func(ctx context.Context, layer int, err error)) *Tiered {
+	t.onError = f
+	return t
+}
+
+// Add implements PeerStorage.
+func (t *Tiered) Add(ctx context.Context, value Peer) error {
+	for i := len(t.layers) - 1; i >= 0; i-- {
+		if err := t.layers[i].Add(ctx, value); err != nil {
+			return errors.Errorf("add to layer %d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// Find implements PeerStorage.
+func (t *Tiered) Find(ctx context.Context, key PeerKey) (Peer, error) {
+	for i, layer := range t.layers {
+		p, err := layer.Find(ctx, key)
+		switch {
+		case err == nil:
+			for j := i - 1; j >= 0; j-- {
+				if err := t.layers[j].Add(ctx, p); err != nil {
+					t.onError(ctx, j, err)
+				}
+			}
+			return p, nil
+		case errors.Is(err, ErrPeerNotFound):
+			continue
+		default:
+			return Peer{}, errors.Errorf("find in layer %d: %w", i, err)
+		}
+	}
+	return Peer{}, ErrPeerNotFound
+}
+
+// Assign implements PeerStorage.
+func (t *Tiered) Assign(ctx context.Context, key string, value Peer) error {
+	for i := len(t.layers) - 1; i >= 0; i-- {
+		if err := t.layers[i].Assign(ctx, key, value); err != nil {
+			return errors.Errorf("assign to layer %d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// Resolve implements PeerStorage.
+func (t *Tiered) Resolve(ctx context.Context, key string) (Peer, error) {
+	for i, layer := range t.layers {
+		p, err := layer.Resolve(ctx, key)
+		switch {
+		case err == nil:
+			for j := i - 1; j >= 0; j-- {
+				if err := t.layers[j].Assign(ctx, key, p); err != nil {
+					t.onError(ctx, j, err)
+				}
+			}
+			return p, nil
+		case errors.Is(err, ErrPeerNotFound):
+			continue
+		default:
+			return Peer{}, errors.Errorf("resolve in layer %d: %w", i, err)
+		}
+	}
+	return Peer{}, ErrPeerNotFound
+}
+
+// Iterate implements PeerStorage. It iterates over the last layer.
+func (t *Tiered) Iterate(ctx context.Context) (PeerIterator, error) {
+	if len(t.layers) == 0 {
+		return NewSliceIterator(nil), nil
+	}
+	return t.layers[len(t.layers)-1].Iterate(ctx)
+}
@@ -0,0 +1,101 @@
+package storage
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-faster/errors"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gotd/td/tg"
+)
+
+type failingStorage struct {
+	memStorage
+	err error
+}
+
+func (f failingStorage) Add(ctx context.Context, p Peer) error {
+	return f.err
+}
+
+func (f failingStorage) Assign(ctx context.Context, key string, p Peer) error {
+	return f.err
+}
+
+func TestTiered(t *testing.T) {
+	a := require.New(t)
+	ctx := context.Background()
+	local, remote := newMemStorage(), newMemStorage()
+	s := NewTiered(local, remote)
+
+	var p Peer
+	a.True(p.FromUser(&tg.User{ID: 10, AccessHash: 10, Username: "user"}))
+	key := KeyFromPeer(p)
+
+	// Write-through.
+	a.NoError(s.Add(ctx, p))
+	a.Contains(local.peers, key)
+	a.Contains(remote.peers, key)
+
+	// Read-through promotion.
+	var other Peer
+	a.True(other.FromUser(&tg.User{ID: 11, AccessHash: 11}))
+	otherKey := KeyFromPeer(other)
+	a.NoError(remote.Assign(ctx, "phone", other))
+
+	v, err := s.Find(ctx, otherKey)
+	a.NoError(err)
+	a.Equal(otherKey, KeyFromPeer(v))
+	a.Contains(local.peers, otherKey)
+
+	v, err = s.Resolve(ctx, "phone")
+	a.NoError(err)
+	a.Equal(otherKey, KeyFromPeer(v))
+	a.Equal(otherKey, local.keys["phone"])
+
+	_, err = s.Find(ctx, PeerKey{Kind: key.Kind, ID: 12})
+	a.ErrorIs(err, ErrPeerNotFound)
+	_, err = s.Resolve(ctx, "missing")
+	a.ErrorIs(err, ErrPeerNotFound)
+
+	// Iterate uses source of truth.
+	iter, err := s.Iterate(ctx)
+	a.NoError(err)
+	var count int
+	for iter.Next(ctx) {
+		count++
+	}
+	a.NoError(iter.Err())
+	a.NoError(iter.Close())
+	a.Equal(2, count)
+}
+
+func TestTieredErrors(t *testing.T) {
+	a := require.New(t)
+	ctx := context.Background()
+	testErr := errors.New("unavailable")
+	broken := failingStorage{memStorage: newMemStorage(), err: testErr}
+	remote := newMemStorage()
+
+	var promoteErrs []int
+	s := NewTiered(broken, remote).OnPromoteError(func(ctx context.Context, layer int, err error) {
+		a.ErrorIs(err, testErr)
+		promoteErrs = append(promoteErrs, layer)
+	})
+
+	var p Peer
+	a.True(p.FromUser(&tg.User{ID: 10, AccessHash: 10, Username: "user"}))
+
+	// Source of truth is written before failed layer.
+	a.ErrorIs(s.Add(ctx, p), testErr)
+	a.Contains(remote.peers, KeyFromPeer(p))
+	a.ErrorIs(s.Assign(ctx, "key", p), testErr)
+
+	// Failed promotion does not fail read.
+	_, err := s.Find(ctx, KeyFromPeer(p))
+	a.NoError(err)
+	_, err = s.Resolve(ctx, "user")
+	a.NoError(err)
+	a.Equal([]int{0, 0}, promoteErrs)
+}
@@ -0,0 +1,115 @@
+package storage
+
+import (
+	"context"
+	"sync"
+
+	"github.com/go-faster/errors"
+)
+
+// ErrWatchNotSupported is returned by Watch if storage instance cannot
+// emit events, e.g. it was not created by constructor.
+var ErrWatchNotSupported = errors.New("watch not supported")
+
+// EventType is a type of peer storage change.
+type EventType int
+
+const (
+	// EventAdd means that new peer was added.
+	EventAdd EventType = iota + 1
+	// EventUpdate means that existing peer was updated.
+	EventUpdate
+	// EventDelete means that peer was deleted or expired.
+	EventDelete
+)
+
+// String implements fmt.Stringer.
+func (t EventType) String() string {
+	switch t {
+	case EventAdd:
+		return "add"
+	case EventUpdate:
+		return "update"
+	case EventDelete:
+		return "delete"
+	default:
+		return "unknown"
+	}
+}
+
+// Event is a peer storage change.
+type Event struct {
+	Type EventType
+	Key  PeerKey
+	// Peer is a new value of peer. Zero for EventDelete.
+	Peer Peer
+}
+
+// PeerWatcher is a PeerStorage which emits change events.
+type PeerWatcher interface {
+	// Watch returns channel of storage changes. Channel is closed
+	// when given context is done.
+	Watch(ctx context.Context) (<-chan Event, error)
+}
+
+// watchBuffer is a default size of watcher channel buffer.
+const watchBuffer = 64
+
+// Broadcaster delivers events to watchers. It is intended to be used by
+// PeerWatcher implementations.
+//
+// Events are delivered without blocking publisher, so watchers which
+// do not keep up with buffer size miss events.
+//
+// Nil Broadcaster is valid and drops all events.
+type Broadcaster struct {
+	mux  sync.Mutex
+	subs map[chan Event]struct{}
+}
+
+// NewBroadcaster creates new Broadcaster.
+func NewBroadcaster() *Broadcaster {
+	return &Broadcaster{
+		subs: map[chan Event]struct{}{},
+	}
+}
+
+// Watch returns channel of published events. Channel is closed
+// when given context is done.
+func (b *Broadcaster) Watch(ctx context.Context) (<-chan Event, error) {
+	if b == nil {
+		return nil, ErrWatchNotSupported
+	}
+
+	ch := make(chan Event, watchBuffer)
+	b.mux.Lock()
+	b.subs[ch] = struct{}{}
+	b.mux.Unlock()
+
+	go func() {
+		<-ctx.Done()
+
+		b.mux.Lock()
+		delete(b.subs, ch)
+		b.mux.Unlock()
+		close(ch)
+	}()
+
+	return ch, nil
+}
+
+// Publish delivers event to all watchers.
+func (b *Broadcaster) Publish(e Event) {
+	if b == nil {
+		return
+	}
+
+	b.mux.Lock()
+	defer b.mux.Unlock()
+	for ch := range b.subs {
+		select {
+		case ch <- e:
+		default:
+		}
+	}
+}
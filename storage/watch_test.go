@@ -0,0 +1,43 @@
+package storage
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBroadcaster(t *testing.T) {
+	a := require.New(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	b := NewBroadcaster()
+	first, err := b.Watch(ctx)
+	a.NoError(err)
+	second, err := b.Watch(ctx)
+	a.NoError(err)
+
+	e := Event{Type: EventAdd, Key: PeerKey{ID: 1}}
+	b.Publish(e)
+	a.Equal(e, <-first)
+	a.Equal(e, <-second)
+
+	// Slow watcher does not block publisher.
+	for i := 0; i < watchBuffer*2; i++ {
+		b.Publish(e)
+	}
+	a.Len(first, watchBuffer)
+
+	cancel()
+	for range first {
+	}
+	for range second {
+	}
+
+	var nilBroadcaster *Broadcaster
+	nilBroadcaster.Publish(e)
+	_, err = nilBroadcaster.Watch(ctx)
+	a.ErrorIs(err, ErrWatchNotSupported)
+	a.Equal("delete", EventDelete.String())
+}
@@ -0,0 +1,303 @@
+package storagebench
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"io/fs"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/go-faster/errors"
+
+	"github.com/gotd/td/tg"
+
+	"github.com/gotd/contrib/storage"
+)
+
+// Backend is a benchmarked storage.
+type Backend struct {
+	// Name of backend in report.
+	Name string
+	// Storage to benchmark. Workload writes peers to it and deletes them
+	// after benchmark, so storage must implement storage.PeerDeleter.
+	Storage storage.PeerStorage
+	// DiskUsage returns count of bytes used by storage on disk.
+	// Optional.
+	DiskUsage func() (int64, error)
+}
+
+// Workload describes benchmark workload.
+type Workload struct {
+	// Peers is a count of peers to add.
+	Peers int
+	// Reads is a count of Find and Resolve calls each.
+	Reads int
+	// Iterations is a count of full Iterate passes.
+	Iterations int
+	// IDOffset is added to IDs of generated peers, so workload does not
+	// overwrite existing peers.
+	IDOffset int64
+}
+
+// DefaultWorkload is a default benchmark workload.
+var DefaultWorkload = Workload{ // nolint:gochecknoglobals
+	Peers:      1000,
+	Reads:      5000,
+	Iterations: 10,
+	IDOffset:   1 << 40,
+}
+
+// Op is a result of single operation benchmark.
+type Op struct {
+	Count     int     `json:"count"`
+	OpsPerSec float64 `json:"ops_per_sec"`
+	P50       int64   `json:"p50_ns"`
+	P99       int64   `json:"p99_ns"`
+}
+
+func newOp(latencies []time.Duration, total time.Duration) Op {
+	if len(latencies) == 0 {
+		return Op{}
+	}
+	sort.Slice(latencies, func(i, j int) bool {
+		return latencies[i] < latencies[j]
+	})
+	quantile := func(q float64) int64 {
+		return int64(latencies[int(q*float64(len(latencies)-1))])
+	}
+	op := Op{
+		Count: len(latencies),
+		P50:   quantile(0.5),
+		P99:   quantile(0.99),
+	}
+	if total > 0 {
+		op.OpsPerSec = float64(len(latencies)) / total.Seconds()
+	}
+	return op
+}
+
+// Result is a benchmark result of single backend.
+type Result struct {
+	Backend string `json:"backend"`
+	Add     Op     `json:"add"`
+	Find    Op     `json:"find"`
+	Resolve Op     `json:"resolve"`
+	Iterate Op     `json:"iterate"`
+	// DiskBytes is a disk usage after workload, if known.
+	DiskBytes int64 `json:"disk_bytes,omitempty"`
+	// Error is a benchmark error, if any.
+	Error string `json:"error,omitempty"`
+}
+
+// score returns total p99 latency of point operations. Less is better.
+func (r Result) score() int64 {
+	return r.Add.P99 + r.Find.P99 + r.Resolve.P99
+}
+
+// Report is a benchmark report.
+type Report struct {
+	Workload Workload `json:"workload"`
+	Results  []Result `json:"results"`
+}
+
+// WriteJSON writes report as JSON.
+func (r Report) WriteJSON(w io.Writer) error {
+	e := json.NewEncoder(w)
+	e.SetIndent("", "  ")
+	return e.Encode(r)
+}
+
+// Best returns result of backend with the lowest total p99 latency of
+// Add, Find and Resolve. Failed backends are ignored.
+func (r Report) Best() (Result, bool) {
+	var (
+		best  Result
+		found bool
+	)
+	for _, result := range r.Results {
+		if result.Error != "" {
+			continue
+		}
+		if !found || result.score() < best.score() {
+			best, found = result, true
+		}
+	}
+	return best, found
+}
+
+// NewTiered runs workload against given candidates and creates
+// storage.Tiered with given fast layers on top of the best candidate,
+// see Report.Best.
+//
+// Workload writes peers to every candidate and deletes them after
+// benchmark, so use IDOffset which does not clash with real peers.
+// Candidates which do not implement storage.PeerDeleter fail.
+func NewTiered(ctx context.Context, w Workload, candidates []Backend, fast ...storage.PeerStorage) (*storage.Tiered, Report, error) {
+	r, err := Run(ctx, w, candidates...)
+	if err != nil {
+		return nil, r, err
+	}
+	best, ok := r.Best()
+	if !ok {
+		return nil, r, errors.New("no backend passed benchmark")
+	}
+	for _, c := range candidates {
+		if c.Name == best.Backend {
+			return storage.NewTiered(append(fast, c.Storage)...), r, nil
+		}
+	}
+	return nil, r, errors.Errorf("backend %q not found", best.Backend)
+}
+
+// Run runs workload against given backends sequentially. Workload peers
+// are deleted from every backend after its benchmark.
+//
+// Backend errors are recorded in report, Run returns error only if context
+// is done.
+func Run(ctx context.Context, w Workload, backends ...Backend) (Report, error) {
+	r := Report{Workload: w}
+	for _, b := range backends {
+		if err := ctx.Err(); err != nil {
+			return r, err
+		}
+		result, err := run(ctx, w, b)
+		result.Backend = b.Name
+		if err != nil {
+			result.Error = err.Error()
+		}
+		r.Results = append(r.Results, result)
+	}
+	return r, nil
+}
+
+func username(id int64) string {
+	return "bench" + strconv.FormatInt(id, 10)
+}
+
+// cleanup deletes workload peers from storage.
+func cleanup(ctx context.Context, d storage.PeerDeleter, peers []storage.Peer) error {
+	for _, p := range peers {
+		if err := d.Delete(ctx, storage.KeyFromPeer(p)); err != nil && !errors.Is(err, storage.ErrPeerNotFound) {
+			return errors.Errorf("delete %s: %w", storage.KeyFromPeer(p), err)
+		}
+	}
+	return nil
+}
+
+func run(ctx context.Context, w Workload, b Backend) (r Result, rerr error) {
+	d, ok := b.Storage.(storage.PeerDeleter)
+	if !ok {
+		return r, errors.New("storage does not implement PeerDeleter")
+	}
+
+	var (
+		latencies = make([]time.Duration, 0, w.Peers)
+		peers     = make([]storage.Peer, 0, w.Peers)
+	)
+	for i := 0; i < w.Peers; i++ {
+		id := w.IDOffset + int64(i)
+		var p storage.Peer
+		p.FromUser(&tg.User{
+			ID:         id,
+			AccessHash: id,
+			Username:   username(id),
+			FirstName:  "Bench",
+		})
+		peers = append(peers, p)
+	}
+	if len(peers) == 0 {
+		return r, errors.New("no peers in workload")
+	}
+	defer func() {
+		// Workload peers must not be left in storage, even if benchmark is
+		// canceled.
+		if err := cleanup(context.WithoutCancel(ctx), d, peers); err != nil && rerr == nil {
+			rerr = errors.Errorf("cleanup: %w", err)
+		}
+	}()
+
+	measure := func(n int, f func(i int) error) (Op, error) {
+		latencies = latencies[:0]
+		start := time.Now()
+		for i := 0; i < n; i++ {
+			opStart := time.Now()
+			if err := f(i); err != nil {
+				return Op{}, err
+			}
+			latencies = append(latencies, time.Since(opStart))
+		}
+		return newOp(latencies, time.Since(start)), nil
+	}
+
+	var err error
+	if r.Add, err = measure(len(peers), func(i int) error {
+		if err := b.Storage.Add(ctx, peers[i]); err != nil {
+			return errors.Errorf("add: %w", err)
+		}
+		return nil
+	}); err != nil {
+		return r, err
+	}
+	if r.Find, err = measure(w.Reads, func(i int) error {
+		if _, err := b.Storage.Find(ctx, storage.KeyFromPeer(peers[i%len(peers)])); err != nil {
+			return errors.Errorf("find: %w", err)
+		}
+		return nil
+	}); err != nil {
+		return r, err
+	}
+	if r.Resolve, err = measure(w.Reads, func(i int) error {
+		if _, err := b.Storage.Resolve(ctx, peers[i%len(peers)].Username()); err != nil {
+			return errors.Errorf("resolve: %w", err)
+		}
+		return nil
+	}); err != nil {
+		return r, err
+	}
+	if r.Iterate, err = measure(w.Iterations, func(int) error {
+		iter, err := b.Storage.Iterate(ctx)
+		if err != nil {
+			return errors.Errorf("iterate: %w", err)
+		}
+		defer func() {
+			_ = iter.Close()
+		}()
+		return storage.ForEach(ctx, iter, func(storage.Peer) error { return nil })
+	}); err != nil {
+		return r, err
+	}
+
+	if b.DiskUsage != nil {
+		if r.DiskBytes, err = b.DiskUsage(); err != nil {
+			return r, errors.Errorf("disk usage: %w", err)
+		}
+	}
+
+	return r, nil
+}
+
+// DirSize returns DiskUsage function which reports total size of files in
+// given directory.
+func DirSize(dir string) func() (int64, error) {
+	return func() (int64, error) {
+		var size int64
+		err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() {
+				return nil
+			}
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			size += info.Size()
+			return nil
+		})
+		return size, err
+	}
+}
@@ -0,0 +1,127 @@
+package storagebench
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	pebbledb "github.com/cockroachdb/pebble"
+	"github.com/cockroachdb/pebble/vfs"
+	"github.com/go-faster/errors"
+	"github.com/stretchr/testify/require"
+	"go.etcd.io/bbolt"
+
+	"github.com/gotd/td/tg"
+
+	"github.com/gotd/contrib/storage"
+
+	bboltstorage "github.com/gotd/contrib/bbolt"
+	"github.com/gotd/contrib/pebble"
+)
+
+type failingStorage struct {
+	storage.PeerStorage
+}
+
+func (failingStorage) Add(context.Context, storage.Peer) error {
+	return errors.New("failed")
+}
+
+func TestRun(t *testing.T) {
+	a := require.New(t)
+	ctx := context.Background()
+
+	mem, err := pebbledb.Open("pebble.db", &pebbledb.Options{FS: vfs.NewMem()})
+	a.NoError(err)
+	defer func() {
+		a.NoError(mem.Close())
+	}()
+
+	dir := t.TempDir()
+	db, err := bbolt.Open(filepath.Join(dir, "bbolt.db"), os.ModePerm, nil)
+	a.NoError(err)
+	defer func() {
+		a.NoError(db.Close())
+	}()
+
+	w := Workload{Peers: 10, Reads: 20, Iterations: 3}
+	report, err := Run(ctx, w,
+		Backend{Name: "pebble", Storage: pebble.NewPeerStorage(mem)},
+		Backend{
+			Name:      "bbolt",
+			Storage:   bboltstorage.NewPeerStorage(db, []byte("peers")),
+			DiskUsage: DirSize(dir),
+		},
+		Backend{Name: "failing", Storage: failingStorage{}},
+	)
+	a.NoError(err)
+	a.Len(report.Results, 3)
+
+	for _, r := range report.Results[:2] {
+		a.Empty(r.Error, r.Backend)
+		a.Equal(10, r.Add.Count)
+		a.Equal(20, r.Find.Count)
+		a.Equal(20, r.Resolve.Count)
+		a.Equal(3, r.Iterate.Count)
+		a.Positive(r.Find.OpsPerSec)
+		a.LessOrEqual(r.Find.P50, r.Find.P99)
+	}
+	a.Positive(report.Results[1].DiskBytes)
+	a.NotEmpty(report.Results[2].Error)
+
+	// Workload peers are deleted.
+	iter, err := pebble.NewPeerStorage(mem).Iterate(ctx)
+	a.NoError(err)
+	a.False(iter.Next(ctx))
+	a.NoError(iter.Err())
+	a.NoError(iter.Close())
+
+	best, ok := report.Best()
+	a.True(ok)
+	a.NotEqual("failing", best.Backend)
+
+	var buf bytes.Buffer
+	a.NoError(report.WriteJSON(&buf))
+	var decoded Report
+	a.NoError(json.Unmarshal(buf.Bytes(), &decoded))
+	a.Equal(report, decoded)
+}
+
+func TestNewTiered(t *testing.T) {
+	a := require.New(t)
+	ctx := context.Background()
+
+	db, err := pebbledb.Open("pebble.db", &pebbledb.Options{FS: vfs.NewMem()})
+	a.NoError(err)
+	defer func() {
+		a.NoError(db.Close())
+	}()
+	durable := pebble.NewPeerStorage(db)
+
+	fast, err := pebbledb.Open("fast.db", &pebbledb.Options{FS: vfs.NewMem()})
+	a.NoError(err)
+	defer func() {
+		a.NoError(fast.Close())
+	}()
+
+	tiered, report, err := NewTiered(ctx, Workload{Peers: 10, Reads: 10}, []Backend{
+		{Name: "failing", Storage: failingStorage{}},
+		{Name: "pebble", Storage: durable},
+	}, pebble.NewPeerStorage(fast))
+	a.NoError(err)
+	a.Len(report.Results, 2)
+
+	var p storage.Peer
+	a.NoError(p.FromInputPeer(&tg.InputPeerUser{UserID: 1, AccessHash: 1}))
+	a.NoError(tiered.Add(ctx, p))
+	_, err = durable.Find(ctx, storage.KeyFromPeer(p))
+	a.NoError(err, "best backend must be the durable tier")
+
+	_, _, err = NewTiered(ctx, Workload{Peers: 10}, []Backend{
+		{Name: "failing", Storage: failingStorage{}},
+	})
+	a.Error(err)
+}
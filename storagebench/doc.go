@@ -0,0 +1,7 @@
+// Package storagebench compares peer storage backends by running identical
+// workloads against them.
+//
+// Report is machine-readable, so it can be stored or used at startup to pick
+// a backend, see Report.Best. NewTiered uses it to pick the durable tier of
+// storage.Tiered.
+package storagebench
@@ -0,0 +1,23 @@
+package tenant
+
+import (
+	"context"
+
+	"github.com/go-faster/errors"
+)
+
+// ErrNoTenant is returned when context does not contain tenant ID.
+var ErrNoTenant = errors.New("tenant not found in context")
+
+type tenantKey struct{}
+
+// With returns new context with given tenant ID.
+func With(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, tenantKey{}, id)
+}
+
+// FromContext returns tenant ID from given context.
+func FromContext(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(tenantKey{}).(string)
+	return id, ok
+}
@@ -0,0 +1,6 @@
+// Package tenant implements tenant isolation for multi-tenant bot platforms.
+//
+// Tenant is identified by a string ID carried in context.Context. Storage,
+// rate limiting and instrumentation helpers use this ID to isolate data,
+// quotas and metrics of different tenants sharing one process.
+package tenant
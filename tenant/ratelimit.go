@@ -0,0 +1,70 @@
+package tenant
+
+import (
+	"context"
+	"sync"
+
+	"golang.org/x/time/rate"
+
+	"github.com/gotd/td/bin"
+	"github.com/gotd/td/telegram"
+	"github.com/gotd/td/tg"
+
+	"github.com/gotd/contrib/middleware/ratelimit"
+)
+
+type limit struct {
+	r rate.Limit
+	b int
+}
+
+// RateLimiter is a telegram.Middleware which limits request rate per tenant.
+//
+// Requests without tenant in context share a single limiter.
+type RateLimiter struct {
+	def       limit
+	overrides map[string]limit
+
+	limiters    map[string]*ratelimit.RateLimiter
+	limitersMux sync.Mutex
+}
+
+// NewRateLimiter creates new RateLimiter with given default per-tenant limit.
+func NewRateLimiter(r rate.Limit, b int) *RateLimiter {
+	return &RateLimiter{
+		def:       limit{r: r, b: b},
+		overrides: map[string]limit{},
+		limiters:  map[string]*ratelimit.RateLimiter{},
+	}
+}
+
+// WithLimit sets limit for given tenant.
+func (l *RateLimiter) WithLimit(id string, r rate.Limit, b int) *RateLimiter {
+	l.overrides[id] = limit{r: r, b: b}
+	return l
+}
+
+func (l *RateLimiter) limiter(ctx context.Context) *ratelimit.RateLimiter {
+	id, _ := FromContext(ctx)
+
+	l.limitersMux.Lock()
+	defer l.limitersMux.Unlock()
+
+	if lim, ok := l.limiters[id]; ok {
+		return lim
+	}
+	v, ok := l.overrides[id]
+	if !ok {
+		v = l.def
+	}
+	lim := ratelimit.New(v.r, v.b)
+	l.limiters[id] = lim
+	return lim
+}
+
+// Handle implements telegram.Middleware.
+func (l *RateLimiter) Handle(next tg.Invoker) telegram.InvokeFunc {
+	return func(ctx context.Context, input bin.Encoder, output bin.Decoder) error {
+		return l.limiter(ctx).Handle(next).Invoke(ctx, input, output)
+	}
+}
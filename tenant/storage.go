@@ -0,0 +1,215 @@
+package tenant
+
+import (
+	"context"
+	"sync"
+
+	"github.com/go-faster/errors"
+
+	"github.com/gotd/contrib/storage"
+)
+
+// ErrQuotaExceeded is returned when tenant exceeds its peer quota.
+var ErrQuotaExceeded = errors.New("tenant peer quota exceeded")
+
+// StorageFactory creates peer storage for given tenant.
+//
+// Returned storages must not share data, e.g. use separate bbolt buckets,
+// Redis databases or key namespaces.
+type StorageFactory func(ctx context.Context, id string) (storage.PeerStorage, error)
+
+var (
+	_ storage.PeerStorage = (*PeerStorage)(nil)
+	_ storage.PeerDeleter = (*PeerStorage)(nil)
+)
+
+// PeerStorage is a storage.PeerStorage which routes every call to the
+// storage of tenant from context.
+//
+// Calls with context without tenant fail with ErrNoTenant.
+type PeerStorage struct {
+	factory  StorageFactory
+	maxPeers int
+
+	tenants    map[string]*tenantStorage
+	tenantsMux sync.Mutex
+}
+
+// tenantStorage is a lazily created storage of single tenant.
+type tenantStorage struct {
+	st    storage.PeerStorage
+	stMux sync.Mutex
+
+	// Peer count, loaded once on first quota check.
+	count      int
+	counted    bool
+	counterMux sync.Mutex
+}
+
+// NewPeerStorage creates new PeerStorage using given factory.
+func NewPeerStorage(factory StorageFactory) *PeerStorage {
+	return &PeerStorage{
+		factory: factory,
+		tenants: map[string]*tenantStorage{},
+	}
+}
+
+// WithMaxPeers sets max count of peers per tenant. Add and Assign of a new
+// peer fail with ErrQuotaExceeded if tenant reached the limit.
+//
+// Peer count is loaded once per tenant and then tracked by Add, Assign
+// and Delete of this PeerStorage, so changes made directly to the tenant
+// storage, e.g. by TTL expiration, are not accounted.
+//
+// Default is zero, which means no limit.
+func (s *PeerStorage) WithMaxPeers(n int) *PeerStorage {
+	s.maxPeers = n
+	return s
+}
+
+func (s *PeerStorage) tenant(ctx context.Context, id string) (*tenantStorage, error) {
+	s.tenantsMux.Lock()
+	t, ok := s.tenants[id]
+	if !ok {
+		t = &tenantStorage{}
+		s.tenants[id] = t
+	}
+	s.tenantsMux.Unlock()
+
+	// Storage is created under tenant lock, so slow factory does not
+	// block other tenants.
+	t.stMux.Lock()
+	defer t.stMux.Unlock()
+	if t.st == nil {
+		st, err := s.factory(ctx, id)
+		if err != nil {
+			return nil, errors.Errorf("create storage for tenant %q: %w", id, err)
+		}
+		t.st = st
+	}
+	return t, nil
+}
+
+// Tenant returns peer storage of given tenant.
+func (s *PeerStorage) Tenant(ctx context.Context, id string) (storage.PeerStorage, error) {
+	t, err := s.tenant(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return t.st, nil
+}
+
+func (s *PeerStorage) current(ctx context.Context) (*tenantStorage, error) {
+	id, ok := FromContext(ctx)
+	if !ok {
+		return nil, ErrNoTenant
+	}
+	return s.tenant(ctx, id)
+}
+
+// add calls f to add given peer, checking and updating peer count.
+func (s *PeerStorage) add(ctx context.Context, value storage.Peer, f func(st storage.PeerStorage) error) error {
+	t, err := s.current(ctx)
+	if err != nil {
+		return err
+	}
+	if s.maxPeers <= 0 {
+		return f(t.st)
+	}
+
+	t.counterMux.Lock()
+	defer t.counterMux.Unlock()
+
+	if !t.counted {
+		count, err := storage.CountPeers(ctx, t.st)
+		if err != nil {
+			return errors.Errorf("count: %w", err)
+		}
+		t.count, t.counted = count, true
+	}
+
+	// Updating existing peer does not change peer count.
+	exists := true
+	if _, err := t.st.Find(ctx, storage.KeyFromPeer(value)); errors.Is(err, storage.ErrPeerNotFound) {
+		exists = false
+	} else if err != nil {
+		return errors.Errorf("find: %w", err)
+	}
+	if !exists && t.count >= s.maxPeers {
+		return ErrQuotaExceeded
+	}
+
+	if err := f(t.st); err != nil {
+		return err
+	}
+	if !exists {
+		t.count++
+	}
+	return nil
+}
+
+// Add adds given peer to the storage of current tenant.
+func (s *PeerStorage) Add(ctx context.Context, value storage.Peer) error {
+	return s.add(ctx, value, func(st storage.PeerStorage) error {
+		return st.Add(ctx, value)
+	})
+}
+
+// Find finds peer using given key in the storage of current tenant.
+func (s *PeerStorage) Find(ctx context.Context, key storage.PeerKey) (storage.Peer, error) {
+	t, err := s.current(ctx)
+	if err != nil {
+		return storage.Peer{}, err
+	}
+	return t.st.Find(ctx, key)
+}
+
+// Assign adds given peer to the storage of current tenant and associates it to the given key.
+func (s *PeerStorage) Assign(ctx context.Context, key string, value storage.Peer) error {
+	return s.add(ctx, value, func(st storage.PeerStorage) error {
+		return st.Assign(ctx, key, value)
+	})
+}
+
+// Resolve finds peer using associated key in the storage of current tenant.
+func (s *PeerStorage) Resolve(ctx context.Context, key string) (storage.Peer, error) {
+	t, err := s.current(ctx)
+	if err != nil {
+		return storage.Peer{}, err
+	}
+	return t.st.Resolve(ctx, key)
+}
+
+// Iterate creates and returns new PeerIterator over the storage of current tenant.
+func (s *PeerStorage) Iterate(ctx context.Context) (storage.PeerIterator, error) {
+	t, err := s.current(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return t.st.Iterate(ctx)
+}
+
+// Delete deletes peer using given key from the storage of current tenant.
+//
+// Tenant storage must implement storage.PeerDeleter.
+func (s *PeerStorage) Delete(ctx context.Context, key storage.PeerKey) error {
+	t, err := s.current(ctx)
+	if err != nil {
+		return err
+	}
+	d, ok := t.st.(storage.PeerDeleter)
+	if !ok {
+		return errors.New("tenant storage does not implement PeerDeleter")
+	}
+
+	t.counterMux.Lock()
+	defer t.counterMux.Unlock()
+
+	if err := d.Delete(ctx, key); err != nil {
+		return err
+	}
+	if t.counted && t.count > 0 {
+		t.count--
+	}
+	return nil
+}
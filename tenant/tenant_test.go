@@ -0,0 +1,206 @@
+package tenant
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/time/rate"
+
+	"github.com/gotd/td/bin"
+	"github.com/gotd/td/telegram/query/dialogs"
+	"github.com/gotd/td/tg"
+
+	"github.com/gotd/contrib/storage"
+)
+
+type memStorage struct {
+	mux    sync.Mutex
+	peers  map[storage.PeerKey]storage.Peer
+	keys   map[string]storage.PeerKey
+	counts int
+}
+
+func newMemStorage() *memStorage {
+	return &memStorage{
+		peers: map[storage.PeerKey]storage.Peer{},
+		keys:  map[string]storage.PeerKey{},
+	}
+}
+
+func (m *memStorage) Add(ctx context.Context, value storage.Peer) error {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	m.peers[storage.KeyFromPeer(value)] = value
+	return nil
+}
+
+func (m *memStorage) Find(ctx context.Context, key storage.PeerKey) (storage.Peer, error) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	p, ok := m.peers[key]
+	if !ok {
+		return storage.Peer{}, storage.ErrPeerNotFound
+	}
+	return p, nil
+}
+
+func (m *memStorage) Assign(ctx context.Context, key string, value storage.Peer) error {
+	m.mux.Lock()
+	m.keys[key] = storage.KeyFromPeer(value)
+	m.mux.Unlock()
+	return m.Add(ctx, value)
+}
+
+func (m *memStorage) Resolve(ctx context.Context, key string) (storage.Peer, error) {
+	m.mux.Lock()
+	k, ok := m.keys[key]
+	m.mux.Unlock()
+	if !ok {
+		return storage.Peer{}, storage.ErrPeerNotFound
+	}
+	return m.Find(ctx, k)
+}
+
+func (m *memStorage) Iterate(ctx context.Context) (storage.PeerIterator, error) {
+	return storage.EmptyIterator(), nil
+}
+
+func (m *memStorage) Count(ctx context.Context, _ ...dialogs.PeerKind) (int, error) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	m.counts++
+	return len(m.peers), nil
+}
+
+func (m *memStorage) Delete(ctx context.Context, key storage.PeerKey) error {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	if _, ok := m.peers[key]; !ok {
+		return storage.ErrPeerNotFound
+	}
+	delete(m.peers, key)
+	return nil
+}
+
+func userPeer(t *testing.T, id int64) storage.Peer {
+	var p storage.Peer
+	require.NoError(t, p.FromInputPeer(&tg.InputPeerUser{UserID: id, AccessHash: id}))
+	return p
+}
+
+func TestPeerStorage(t *testing.T) {
+	a := require.New(t)
+	ctx := context.Background()
+
+	var created []string
+	s := NewPeerStorage(func(ctx context.Context, id string) (storage.PeerStorage, error) {
+		created = append(created, id)
+		return newMemStorage(), nil
+	}).WithMaxPeers(2)
+
+	a.ErrorIs(s.Add(ctx, userPeer(t, 1)), ErrNoTenant)
+
+	foo, bar := With(ctx, "foo"), With(ctx, "bar")
+	a.NoError(s.Assign(foo, "user", userPeer(t, 1)))
+
+	_, err := s.Resolve(bar, "user")
+	a.ErrorIs(err, storage.ErrPeerNotFound, "tenants must be isolated")
+	_, err = s.Resolve(foo, "user")
+	a.NoError(err)
+
+	a.NoError(s.Add(foo, userPeer(t, 2)))
+	a.NoError(s.Add(foo, userPeer(t, 2)), "update does not change count")
+	a.ErrorIs(s.Add(foo, userPeer(t, 3)), ErrQuotaExceeded)
+	a.NoError(s.Add(bar, userPeer(t, 3)))
+
+	a.Equal([]string{"foo", "bar"}, created)
+
+	a.NoError(s.Delete(foo, storage.KeyFromPeer(userPeer(t, 2))))
+	a.ErrorIs(s.Delete(foo, storage.KeyFromPeer(userPeer(t, 2))), storage.ErrPeerNotFound)
+	a.NoError(s.Add(foo, userPeer(t, 3)), "delete frees quota")
+}
+
+func TestPeerStorageQuotaConcurrent(t *testing.T) {
+	a := require.New(t)
+	ctx := With(context.Background(), "foo")
+
+	m := newMemStorage()
+	s := NewPeerStorage(func(ctx context.Context, id string) (storage.PeerStorage, error) {
+		return m, nil
+	}).WithMaxPeers(5)
+
+	const n = 20
+	errs := make(chan error, n)
+	for i := 0; i < n; i++ {
+		go func(id int64) {
+			errs <- s.Add(ctx, userPeer(t, id))
+		}(int64(i + 1))
+	}
+	var exceeded int
+	for i := 0; i < n; i++ {
+		if err := <-errs; err != nil {
+			a.ErrorIs(err, ErrQuotaExceeded)
+			exceeded++
+		}
+	}
+	a.Equal(n-5, exceeded)
+	a.Len(m.peers, 5)
+	a.Equal(1, m.counts, "count must be loaded once")
+}
+
+func TestPeerStorageSlowFactory(t *testing.T) {
+	a := require.New(t)
+	ctx := context.Background()
+
+	started, release := make(chan struct{}), make(chan struct{})
+	s := NewPeerStorage(func(ctx context.Context, id string) (storage.PeerStorage, error) {
+		if id == "slow" {
+			close(started)
+			<-release
+		}
+		return newMemStorage(), nil
+	})
+	defer close(release)
+
+	go func() {
+		_, _ = s.Tenant(ctx, "slow")
+	}()
+	<-started
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_, _ = s.Tenant(ctx, "fast")
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("slow factory must not block other tenants")
+	}
+	a.NoError(s.Add(With(ctx, "fast"), userPeer(t, 1)))
+}
+
+type invokerFunc func(ctx context.Context, input bin.Encoder, output bin.Decoder) error
+
+func (f invokerFunc) Invoke(ctx context.Context, input bin.Encoder, output bin.Decoder) error {
+	return f(ctx, input, output)
+}
+
+func TestRateLimiter(t *testing.T) {
+	a := require.New(t)
+	ctx := context.Background()
+
+	l := NewRateLimiter(rate.Inf, 1).WithLimit("blocked", 0, 0)
+	h := l.Handle(invokerFunc(func(ctx context.Context, input bin.Encoder, output bin.Decoder) error {
+		return nil
+	}))
+
+	a.NoError(h.Invoke(With(ctx, "foo"), nil, nil))
+	a.NoError(h.Invoke(ctx, nil, nil))
+	a.Error(h.Invoke(With(ctx, "blocked"), nil, nil))
+	a.Same(l.limiter(With(ctx, "foo")), l.limiter(With(ctx, "foo")))
+	a.NotSame(l.limiter(With(ctx, "foo")), l.limiter(With(ctx, "bar")))
+}